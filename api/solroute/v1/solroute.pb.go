@@ -0,0 +1,779 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        (unknown)
+// source: solroute/v1/solroute.proto
+
+package solroutev1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type QuoteRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	InputMint     string                 `protobuf:"bytes,1,opt,name=input_mint,json=inputMint,proto3" json:"input_mint,omitempty"`
+	OutputMint    string                 `protobuf:"bytes,2,opt,name=output_mint,json=outputMint,proto3" json:"output_mint,omitempty"`
+	Amount        string                 `protobuf:"bytes,3,opt,name=amount,proto3" json:"amount,omitempty"`
+	SlippageBps   uint32                 `protobuf:"varint,4,opt,name=slippage_bps,json=slippageBps,proto3" json:"slippage_bps,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QuoteRequest) Reset() {
+	*x = QuoteRequest{}
+	mi := &file_solroute_v1_solroute_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QuoteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuoteRequest) ProtoMessage() {}
+
+func (x *QuoteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_solroute_v1_solroute_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuoteRequest.ProtoReflect.Descriptor instead.
+func (*QuoteRequest) Descriptor() ([]byte, []int) {
+	return file_solroute_v1_solroute_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *QuoteRequest) GetInputMint() string {
+	if x != nil {
+		return x.InputMint
+	}
+	return ""
+}
+
+func (x *QuoteRequest) GetOutputMint() string {
+	if x != nil {
+		return x.OutputMint
+	}
+	return ""
+}
+
+func (x *QuoteRequest) GetAmount() string {
+	if x != nil {
+		return x.Amount
+	}
+	return ""
+}
+
+func (x *QuoteRequest) GetSlippageBps() uint32 {
+	if x != nil {
+		return x.SlippageBps
+	}
+	return 0
+}
+
+type QuoteResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	InputMint      string                 `protobuf:"bytes,1,opt,name=input_mint,json=inputMint,proto3" json:"input_mint,omitempty"`
+	OutputMint     string                 `protobuf:"bytes,2,opt,name=output_mint,json=outputMint,proto3" json:"output_mint,omitempty"`
+	InAmount       string                 `protobuf:"bytes,3,opt,name=in_amount,json=inAmount,proto3" json:"in_amount,omitempty"`
+	OutAmount      string                 `protobuf:"bytes,4,opt,name=out_amount,json=outAmount,proto3" json:"out_amount,omitempty"`
+	MinOutAmount   string                 `protobuf:"bytes,5,opt,name=min_out_amount,json=minOutAmount,proto3" json:"min_out_amount,omitempty"`
+	SlippageBps    uint32                 `protobuf:"varint,6,opt,name=slippage_bps,json=slippageBps,proto3" json:"slippage_bps,omitempty"`
+	Pool           string                 `protobuf:"bytes,7,opt,name=pool,proto3" json:"pool,omitempty"`
+	Protocol       string                 `protobuf:"bytes,8,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	InUsd          float64                `protobuf:"fixed64,9,opt,name=in_usd,json=inUsd,proto3" json:"in_usd,omitempty"`
+	OutUsd         float64                `protobuf:"fixed64,10,opt,name=out_usd,json=outUsd,proto3" json:"out_usd,omitempty"`
+	QuotedAtUnixMs int64                  `protobuf:"varint,11,opt,name=quoted_at_unix_ms,json=quotedAtUnixMs,proto3" json:"quoted_at_unix_ms,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *QuoteResponse) Reset() {
+	*x = QuoteResponse{}
+	mi := &file_solroute_v1_solroute_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QuoteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuoteResponse) ProtoMessage() {}
+
+func (x *QuoteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_solroute_v1_solroute_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuoteResponse.ProtoReflect.Descriptor instead.
+func (*QuoteResponse) Descriptor() ([]byte, []int) {
+	return file_solroute_v1_solroute_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *QuoteResponse) GetInputMint() string {
+	if x != nil {
+		return x.InputMint
+	}
+	return ""
+}
+
+func (x *QuoteResponse) GetOutputMint() string {
+	if x != nil {
+		return x.OutputMint
+	}
+	return ""
+}
+
+func (x *QuoteResponse) GetInAmount() string {
+	if x != nil {
+		return x.InAmount
+	}
+	return ""
+}
+
+func (x *QuoteResponse) GetOutAmount() string {
+	if x != nil {
+		return x.OutAmount
+	}
+	return ""
+}
+
+func (x *QuoteResponse) GetMinOutAmount() string {
+	if x != nil {
+		return x.MinOutAmount
+	}
+	return ""
+}
+
+func (x *QuoteResponse) GetSlippageBps() uint32 {
+	if x != nil {
+		return x.SlippageBps
+	}
+	return 0
+}
+
+func (x *QuoteResponse) GetPool() string {
+	if x != nil {
+		return x.Pool
+	}
+	return ""
+}
+
+func (x *QuoteResponse) GetProtocol() string {
+	if x != nil {
+		return x.Protocol
+	}
+	return ""
+}
+
+func (x *QuoteResponse) GetInUsd() float64 {
+	if x != nil {
+		return x.InUsd
+	}
+	return 0
+}
+
+func (x *QuoteResponse) GetOutUsd() float64 {
+	if x != nil {
+		return x.OutUsd
+	}
+	return 0
+}
+
+func (x *QuoteResponse) GetQuotedAtUnixMs() int64 {
+	if x != nil {
+		return x.QuotedAtUnixMs
+	}
+	return 0
+}
+
+type Route struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pool          string                 `protobuf:"bytes,1,opt,name=pool,proto3" json:"pool,omitempty"`
+	Protocol      string                 `protobuf:"bytes,2,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	OutAmount     string                 `protobuf:"bytes,3,opt,name=out_amount,json=outAmount,proto3" json:"out_amount,omitempty"`
+	Error         string                 `protobuf:"bytes,4,opt,name=error,proto3" json:"error,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Route) Reset() {
+	*x = Route{}
+	mi := &file_solroute_v1_solroute_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Route) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Route) ProtoMessage() {}
+
+func (x *Route) ProtoReflect() protoreflect.Message {
+	mi := &file_solroute_v1_solroute_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Route.ProtoReflect.Descriptor instead.
+func (*Route) Descriptor() ([]byte, []int) {
+	return file_solroute_v1_solroute_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Route) GetPool() string {
+	if x != nil {
+		return x.Pool
+	}
+	return ""
+}
+
+func (x *Route) GetProtocol() string {
+	if x != nil {
+		return x.Protocol
+	}
+	return ""
+}
+
+func (x *Route) GetOutAmount() string {
+	if x != nil {
+		return x.OutAmount
+	}
+	return ""
+}
+
+func (x *Route) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+type GetRoutesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Routes        []*Route               `protobuf:"bytes,1,rep,name=routes,proto3" json:"routes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRoutesResponse) Reset() {
+	*x = GetRoutesResponse{}
+	mi := &file_solroute_v1_solroute_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRoutesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRoutesResponse) ProtoMessage() {}
+
+func (x *GetRoutesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_solroute_v1_solroute_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRoutesResponse.ProtoReflect.Descriptor instead.
+func (*GetRoutesResponse) Descriptor() ([]byte, []int) {
+	return file_solroute_v1_solroute_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetRoutesResponse) GetRoutes() []*Route {
+	if x != nil {
+		return x.Routes
+	}
+	return nil
+}
+
+type BuildSwapRequest struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	User             string                 `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	Pool             string                 `protobuf:"bytes,2,opt,name=pool,proto3" json:"pool,omitempty"`
+	InputMint        string                 `protobuf:"bytes,3,opt,name=input_mint,json=inputMint,proto3" json:"input_mint,omitempty"`
+	OutputMint       string                 `protobuf:"bytes,4,opt,name=output_mint,json=outputMint,proto3" json:"output_mint,omitempty"`
+	InAmount         string                 `protobuf:"bytes,5,opt,name=in_amount,json=inAmount,proto3" json:"in_amount,omitempty"`
+	MinOutAmount     string                 `protobuf:"bytes,6,opt,name=min_out_amount,json=minOutAmount,proto3" json:"min_out_amount,omitempty"`
+	WrapAndUnwrapSol bool                   `protobuf:"varint,7,opt,name=wrap_and_unwrap_sol,json=wrapAndUnwrapSol,proto3" json:"wrap_and_unwrap_sol,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *BuildSwapRequest) Reset() {
+	*x = BuildSwapRequest{}
+	mi := &file_solroute_v1_solroute_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BuildSwapRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BuildSwapRequest) ProtoMessage() {}
+
+func (x *BuildSwapRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_solroute_v1_solroute_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BuildSwapRequest.ProtoReflect.Descriptor instead.
+func (*BuildSwapRequest) Descriptor() ([]byte, []int) {
+	return file_solroute_v1_solroute_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *BuildSwapRequest) GetUser() string {
+	if x != nil {
+		return x.User
+	}
+	return ""
+}
+
+func (x *BuildSwapRequest) GetPool() string {
+	if x != nil {
+		return x.Pool
+	}
+	return ""
+}
+
+func (x *BuildSwapRequest) GetInputMint() string {
+	if x != nil {
+		return x.InputMint
+	}
+	return ""
+}
+
+func (x *BuildSwapRequest) GetOutputMint() string {
+	if x != nil {
+		return x.OutputMint
+	}
+	return ""
+}
+
+func (x *BuildSwapRequest) GetInAmount() string {
+	if x != nil {
+		return x.InAmount
+	}
+	return ""
+}
+
+func (x *BuildSwapRequest) GetMinOutAmount() string {
+	if x != nil {
+		return x.MinOutAmount
+	}
+	return ""
+}
+
+func (x *BuildSwapRequest) GetWrapAndUnwrapSol() bool {
+	if x != nil {
+		return x.WrapAndUnwrapSol
+	}
+	return false
+}
+
+type AccountMeta struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Pubkey        string                 `protobuf:"bytes,1,opt,name=pubkey,proto3" json:"pubkey,omitempty"`
+	IsSigner      bool                   `protobuf:"varint,2,opt,name=is_signer,json=isSigner,proto3" json:"is_signer,omitempty"`
+	IsWritable    bool                   `protobuf:"varint,3,opt,name=is_writable,json=isWritable,proto3" json:"is_writable,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AccountMeta) Reset() {
+	*x = AccountMeta{}
+	mi := &file_solroute_v1_solroute_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AccountMeta) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AccountMeta) ProtoMessage() {}
+
+func (x *AccountMeta) ProtoReflect() protoreflect.Message {
+	mi := &file_solroute_v1_solroute_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AccountMeta.ProtoReflect.Descriptor instead.
+func (*AccountMeta) Descriptor() ([]byte, []int) {
+	return file_solroute_v1_solroute_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *AccountMeta) GetPubkey() string {
+	if x != nil {
+		return x.Pubkey
+	}
+	return ""
+}
+
+func (x *AccountMeta) GetIsSigner() bool {
+	if x != nil {
+		return x.IsSigner
+	}
+	return false
+}
+
+func (x *AccountMeta) GetIsWritable() bool {
+	if x != nil {
+		return x.IsWritable
+	}
+	return false
+}
+
+type Instruction struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ProgramId     string                 `protobuf:"bytes,1,opt,name=program_id,json=programId,proto3" json:"program_id,omitempty"`
+	Accounts      []*AccountMeta         `protobuf:"bytes,2,rep,name=accounts,proto3" json:"accounts,omitempty"`
+	Data          []byte                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Instruction) Reset() {
+	*x = Instruction{}
+	mi := &file_solroute_v1_solroute_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Instruction) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Instruction) ProtoMessage() {}
+
+func (x *Instruction) ProtoReflect() protoreflect.Message {
+	mi := &file_solroute_v1_solroute_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Instruction.ProtoReflect.Descriptor instead.
+func (*Instruction) Descriptor() ([]byte, []int) {
+	return file_solroute_v1_solroute_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Instruction) GetProgramId() string {
+	if x != nil {
+		return x.ProgramId
+	}
+	return ""
+}
+
+func (x *Instruction) GetAccounts() []*AccountMeta {
+	if x != nil {
+		return x.Accounts
+	}
+	return nil
+}
+
+func (x *Instruction) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type BuildSwapResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Instructions  []*Instruction         `protobuf:"bytes,1,rep,name=instructions,proto3" json:"instructions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BuildSwapResponse) Reset() {
+	*x = BuildSwapResponse{}
+	mi := &file_solroute_v1_solroute_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BuildSwapResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BuildSwapResponse) ProtoMessage() {}
+
+func (x *BuildSwapResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_solroute_v1_solroute_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BuildSwapResponse.ProtoReflect.Descriptor instead.
+func (*BuildSwapResponse) Descriptor() ([]byte, []int) {
+	return file_solroute_v1_solroute_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *BuildSwapResponse) GetInstructions() []*Instruction {
+	if x != nil {
+		return x.Instructions
+	}
+	return nil
+}
+
+type StreamQuotesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Quote         *QuoteRequest          `protobuf:"bytes,1,opt,name=quote,proto3" json:"quote,omitempty"`
+	IntervalMs    uint32                 `protobuf:"varint,2,opt,name=interval_ms,json=intervalMs,proto3" json:"interval_ms,omitempty"`
+	ChangesOnly   bool                   `protobuf:"varint,3,opt,name=changes_only,json=changesOnly,proto3" json:"changes_only,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamQuotesRequest) Reset() {
+	*x = StreamQuotesRequest{}
+	mi := &file_solroute_v1_solroute_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamQuotesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamQuotesRequest) ProtoMessage() {}
+
+func (x *StreamQuotesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_solroute_v1_solroute_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamQuotesRequest.ProtoReflect.Descriptor instead.
+func (*StreamQuotesRequest) Descriptor() ([]byte, []int) {
+	return file_solroute_v1_solroute_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *StreamQuotesRequest) GetQuote() *QuoteRequest {
+	if x != nil {
+		return x.Quote
+	}
+	return nil
+}
+
+func (x *StreamQuotesRequest) GetIntervalMs() uint32 {
+	if x != nil {
+		return x.IntervalMs
+	}
+	return 0
+}
+
+func (x *StreamQuotesRequest) GetChangesOnly() bool {
+	if x != nil {
+		return x.ChangesOnly
+	}
+	return false
+}
+
+var File_solroute_v1_solroute_proto protoreflect.FileDescriptor
+
+const file_solroute_v1_solroute_proto_rawDesc = "" +
+	"\n" +
+	"\x1asolroute/v1/solroute.proto\x12\vsolroute.v1\"\x89\x01\n" +
+	"\fQuoteRequest\x12\x1d\n" +
+	"\n" +
+	"input_mint\x18\x01 \x01(\tR\tinputMint\x12\x1f\n" +
+	"\voutput_mint\x18\x02 \x01(\tR\n" +
+	"outputMint\x12\x16\n" +
+	"\x06amount\x18\x03 \x01(\tR\x06amount\x12!\n" +
+	"\fslippage_bps\x18\x04 \x01(\rR\vslippageBps\"\xdf\x02\n" +
+	"\rQuoteResponse\x12\x1d\n" +
+	"\n" +
+	"input_mint\x18\x01 \x01(\tR\tinputMint\x12\x1f\n" +
+	"\voutput_mint\x18\x02 \x01(\tR\n" +
+	"outputMint\x12\x1b\n" +
+	"\tin_amount\x18\x03 \x01(\tR\binAmount\x12\x1d\n" +
+	"\n" +
+	"out_amount\x18\x04 \x01(\tR\toutAmount\x12$\n" +
+	"\x0emin_out_amount\x18\x05 \x01(\tR\fminOutAmount\x12!\n" +
+	"\fslippage_bps\x18\x06 \x01(\rR\vslippageBps\x12\x12\n" +
+	"\x04pool\x18\a \x01(\tR\x04pool\x12\x1a\n" +
+	"\bprotocol\x18\b \x01(\tR\bprotocol\x12\x15\n" +
+	"\x06in_usd\x18\t \x01(\x01R\x05inUsd\x12\x17\n" +
+	"\aout_usd\x18\n" +
+	" \x01(\x01R\x06outUsd\x12)\n" +
+	"\x11quoted_at_unix_ms\x18\v \x01(\x03R\x0equotedAtUnixMs\"l\n" +
+	"\x05Route\x12\x12\n" +
+	"\x04pool\x18\x01 \x01(\tR\x04pool\x12\x1a\n" +
+	"\bprotocol\x18\x02 \x01(\tR\bprotocol\x12\x1d\n" +
+	"\n" +
+	"out_amount\x18\x03 \x01(\tR\toutAmount\x12\x14\n" +
+	"\x05error\x18\x04 \x01(\tR\x05error\"?\n" +
+	"\x11GetRoutesResponse\x12*\n" +
+	"\x06routes\x18\x01 \x03(\v2\x12.solroute.v1.RouteR\x06routes\"\xec\x01\n" +
+	"\x10BuildSwapRequest\x12\x12\n" +
+	"\x04user\x18\x01 \x01(\tR\x04user\x12\x12\n" +
+	"\x04pool\x18\x02 \x01(\tR\x04pool\x12\x1d\n" +
+	"\n" +
+	"input_mint\x18\x03 \x01(\tR\tinputMint\x12\x1f\n" +
+	"\voutput_mint\x18\x04 \x01(\tR\n" +
+	"outputMint\x12\x1b\n" +
+	"\tin_amount\x18\x05 \x01(\tR\binAmount\x12$\n" +
+	"\x0emin_out_amount\x18\x06 \x01(\tR\fminOutAmount\x12-\n" +
+	"\x13wrap_and_unwrap_sol\x18\a \x01(\bR\x10wrapAndUnwrapSol\"c\n" +
+	"\vAccountMeta\x12\x16\n" +
+	"\x06pubkey\x18\x01 \x01(\tR\x06pubkey\x12\x1b\n" +
+	"\tis_signer\x18\x02 \x01(\bR\bisSigner\x12\x1f\n" +
+	"\vis_writable\x18\x03 \x01(\bR\n" +
+	"isWritable\"v\n" +
+	"\vInstruction\x12\x1d\n" +
+	"\n" +
+	"program_id\x18\x01 \x01(\tR\tprogramId\x124\n" +
+	"\baccounts\x18\x02 \x03(\v2\x18.solroute.v1.AccountMetaR\baccounts\x12\x12\n" +
+	"\x04data\x18\x03 \x01(\fR\x04data\"Q\n" +
+	"\x11BuildSwapResponse\x12<\n" +
+	"\finstructions\x18\x01 \x03(\v2\x18.solroute.v1.InstructionR\finstructions\"\x8a\x01\n" +
+	"\x13StreamQuotesRequest\x12/\n" +
+	"\x05quote\x18\x01 \x01(\v2\x19.solroute.v1.QuoteRequestR\x05quote\x12\x1f\n" +
+	"\vinterval_ms\x18\x02 \x01(\rR\n" +
+	"intervalMs\x12!\n" +
+	"\fchanges_only\x18\x03 \x01(\bR\vchangesOnly2\xac\x02\n" +
+	"\x06Router\x12>\n" +
+	"\x05Quote\x12\x19.solroute.v1.QuoteRequest\x1a\x1a.solroute.v1.QuoteResponse\x12F\n" +
+	"\tGetRoutes\x12\x19.solroute.v1.QuoteRequest\x1a\x1e.solroute.v1.GetRoutesResponse\x12J\n" +
+	"\tBuildSwap\x12\x1d.solroute.v1.BuildSwapRequest\x1a\x1e.solroute.v1.BuildSwapResponse\x12N\n" +
+	"\fStreamQuotes\x12 .solroute.v1.StreamQuotesRequest\x1a\x1a.solroute.v1.QuoteResponse0\x01B:Z8github.com/yimingWOW/solroute/api/solroute/v1;solroutev1b\x06proto3"
+
+var (
+	file_solroute_v1_solroute_proto_rawDescOnce sync.Once
+	file_solroute_v1_solroute_proto_rawDescData []byte
+)
+
+func file_solroute_v1_solroute_proto_rawDescGZIP() []byte {
+	file_solroute_v1_solroute_proto_rawDescOnce.Do(func() {
+		file_solroute_v1_solroute_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_solroute_v1_solroute_proto_rawDesc), len(file_solroute_v1_solroute_proto_rawDesc)))
+	})
+	return file_solroute_v1_solroute_proto_rawDescData
+}
+
+var file_solroute_v1_solroute_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_solroute_v1_solroute_proto_goTypes = []any{
+	(*QuoteRequest)(nil),        // 0: solroute.v1.QuoteRequest
+	(*QuoteResponse)(nil),       // 1: solroute.v1.QuoteResponse
+	(*Route)(nil),               // 2: solroute.v1.Route
+	(*GetRoutesResponse)(nil),   // 3: solroute.v1.GetRoutesResponse
+	(*BuildSwapRequest)(nil),    // 4: solroute.v1.BuildSwapRequest
+	(*AccountMeta)(nil),         // 5: solroute.v1.AccountMeta
+	(*Instruction)(nil),         // 6: solroute.v1.Instruction
+	(*BuildSwapResponse)(nil),   // 7: solroute.v1.BuildSwapResponse
+	(*StreamQuotesRequest)(nil), // 8: solroute.v1.StreamQuotesRequest
+}
+var file_solroute_v1_solroute_proto_depIdxs = []int32{
+	2, // 0: solroute.v1.GetRoutesResponse.routes:type_name -> solroute.v1.Route
+	5, // 1: solroute.v1.Instruction.accounts:type_name -> solroute.v1.AccountMeta
+	6, // 2: solroute.v1.BuildSwapResponse.instructions:type_name -> solroute.v1.Instruction
+	0, // 3: solroute.v1.StreamQuotesRequest.quote:type_name -> solroute.v1.QuoteRequest
+	0, // 4: solroute.v1.Router.Quote:input_type -> solroute.v1.QuoteRequest
+	0, // 5: solroute.v1.Router.GetRoutes:input_type -> solroute.v1.QuoteRequest
+	4, // 6: solroute.v1.Router.BuildSwap:input_type -> solroute.v1.BuildSwapRequest
+	8, // 7: solroute.v1.Router.StreamQuotes:input_type -> solroute.v1.StreamQuotesRequest
+	1, // 8: solroute.v1.Router.Quote:output_type -> solroute.v1.QuoteResponse
+	3, // 9: solroute.v1.Router.GetRoutes:output_type -> solroute.v1.GetRoutesResponse
+	7, // 10: solroute.v1.Router.BuildSwap:output_type -> solroute.v1.BuildSwapResponse
+	1, // 11: solroute.v1.Router.StreamQuotes:output_type -> solroute.v1.QuoteResponse
+	8, // [8:12] is the sub-list for method output_type
+	4, // [4:8] is the sub-list for method input_type
+	4, // [4:4] is the sub-list for extension type_name
+	4, // [4:4] is the sub-list for extension extendee
+	0, // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_solroute_v1_solroute_proto_init() }
+func file_solroute_v1_solroute_proto_init() {
+	if File_solroute_v1_solroute_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_solroute_v1_solroute_proto_rawDesc), len(file_solroute_v1_solroute_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_solroute_v1_solroute_proto_goTypes,
+		DependencyIndexes: file_solroute_v1_solroute_proto_depIdxs,
+		MessageInfos:      file_solroute_v1_solroute_proto_msgTypes,
+	}.Build()
+	File_solroute_v1_solroute_proto = out.File
+	file_solroute_v1_solroute_proto_goTypes = nil
+	file_solroute_v1_solroute_proto_depIdxs = nil
+}
@@ -0,0 +1,98 @@
+// Command solroute-server serves solroute quotes and swap instructions over
+// HTTP, with request and response shapes following the Jupiter swap API where
+// they can:
+//
+//	GET  /quote?inputMint=...&outputMint=...&amount=...&slippageBps=50
+//	GET  /routes?inputMint=...&outputMint=...&amount=...
+//	POST /swap-instructions {"userPublicKey": "...", "quoteResponse": {...}}
+//
+// With -grpc-listen, the solroute.v1.Router gRPC service of api/solroute/v1 is
+// served as well, sharing the HTTP server's routers.
+//
+// Amounts are in base units. Only ExactIn swaps are served
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	solroutev1 "github.com/yimingWOW/solroute/api/solroute/v1"
+	"github.com/yimingWOW/solroute/pkg/grpcapi"
+	"github.com/yimingWOW/solroute/pkg/protocol"
+	"github.com/yimingWOW/solroute/pkg/router"
+	"github.com/yimingWOW/solroute/pkg/sol"
+	"google.golang.org/grpc"
+)
+
+func main() {
+	listen := flag.String("listen", ":8080", "address to listen on")
+	grpcListen := flag.String("grpc-listen", "", "address to serve the gRPC API on, disabled when empty")
+	rpcEndpoint := flag.String("rpc", os.Getenv("SOLROUTE_RPC"), "RPC endpoint, defaults to $SOLROUTE_RPC")
+	wsEndpoint := flag.String("ws", os.Getenv("SOLROUTE_WS"), "websocket endpoint, defaults to $SOLROUTE_WS")
+	poolTTL := flag.Duration("pool-ttl", 5*time.Minute, "how long the pools discovered for a pair are reused")
+	flag.Parse()
+	if *rpcEndpoint == "" || *wsEndpoint == "" {
+		log.Fatal("-rpc and -ws are required")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	client, err := sol.NewClient(ctx, *rpcEndpoint, *wsEndpoint)
+	if err != nil {
+		log.Fatalf("Failed to create solana client: %v", err)
+	}
+	defer client.Close()
+
+	routers := router.NewPairRouters(*poolTTL, nil,
+		protocol.NewPumpAmm(client),
+		protocol.NewRaydiumAmm(client),
+		protocol.NewRaydiumClmm(client),
+		protocol.NewRaydiumCpmm(client),
+		protocol.NewMeteoraDlmm(client),
+	)
+
+	var grpcServer *grpc.Server
+	if *grpcListen != "" {
+		lis, err := net.Listen("tcp", *grpcListen)
+		if err != nil {
+			log.Fatalf("Failed to listen on %s: %v", *grpcListen, err)
+		}
+		grpcServer = grpc.NewServer()
+		solroutev1.RegisterRouterServer(grpcServer, grpcapi.NewServer(client, routers))
+		go func() {
+			log.Printf("Serving gRPC on %s", *grpcListen)
+			if err := grpcServer.Serve(lis); err != nil {
+				log.Fatalf("gRPC server failed: %v", err)
+			}
+		}()
+	}
+
+	httpServer := &http.Server{
+		Addr:              *listen,
+		Handler:           newServer(client, routers).handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		if grpcServer != nil {
+			// Quote streams only end with their clients, so they are cut
+			grpcServer.Stop()
+		}
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+	log.Printf("Listening on %s", *listen)
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("Server failed: %v", err)
+	}
+}
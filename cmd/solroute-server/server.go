@@ -0,0 +1,264 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/router"
+	"github.com/yimingWOW/solroute/pkg/sol"
+)
+
+const defaultSlippageBps = 50
+
+// server answers requests with a router per pair
+type server struct {
+	client  *sol.Client
+	routers *router.PairRouters
+}
+
+func newServer(client *sol.Client, routers *router.PairRouters) *server {
+	return &server{client: client, routers: routers}
+}
+
+func (s *server) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /quote", s.handleQuote)
+	mux.HandleFunc("GET /routes", s.handleRoutes)
+	mux.HandleFunc("POST /swap-instructions", s.handleSwapInstructions)
+	return mux
+}
+
+// quoteParams are the query parameters of /quote and /routes
+type quoteParams struct {
+	inputMint, outputMint string
+	amount                math.Int
+	slippageBps           int64
+	platformFeeBps        uint16
+}
+
+func parseQuoteParams(r *http.Request) (quoteParams, error) {
+	query := r.URL.Query()
+	params := quoteParams{
+		inputMint:   query.Get("inputMint"),
+		outputMint:  query.Get("outputMint"),
+		slippageBps: defaultSlippageBps,
+	}
+	for _, mint := range []string{params.inputMint, params.outputMint} {
+		if _, err := solana.PublicKeyFromBase58(mint); err != nil {
+			return quoteParams{}, fmt.Errorf("invalid mint %q", mint)
+		}
+	}
+	amount, ok := math.NewIntFromString(query.Get("amount"))
+	if !ok || !amount.IsPositive() {
+		return quoteParams{}, fmt.Errorf("invalid amount %q", query.Get("amount"))
+	}
+	params.amount = amount
+	if mode := query.Get("swapMode"); mode != "" && mode != "ExactIn" {
+		return quoteParams{}, fmt.Errorf("unsupported swapMode %q", mode)
+	}
+	if bps := query.Get("slippageBps"); bps != "" {
+		slippage, err := strconv.ParseInt(bps, 10, 64)
+		if err != nil || slippage < 0 || slippage > 10000 {
+			return quoteParams{}, fmt.Errorf("invalid slippageBps %q", bps)
+		}
+		params.slippageBps = slippage
+	}
+	if bps := query.Get("platformFeeBps"); bps != "" {
+		fee, err := strconv.ParseUint(bps, 10, 16)
+		if err != nil || fee > 10000 {
+			return quoteParams{}, fmt.Errorf("invalid platformFeeBps %q", bps)
+		}
+		params.platformFeeBps = uint16(fee)
+	}
+	return params, nil
+}
+
+func (s *server) handleQuote(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	params, err := parseQuoteParams(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	rt, unlock := s.routers.Get(r.Context(), params.inputMint, params.outputMint)
+	defer unlock()
+	quote, err := rt.QuoteRoute(r.Context(), s.client, params.inputMint, params.outputMint, params.amount, router.QuoteOptions{})
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, newQuoteResponse(quote, params.slippageBps, params.platformFeeBps, time.Since(start)))
+}
+
+func (s *server) handleRoutes(w http.ResponseWriter, r *http.Request) {
+	params, err := parseQuoteParams(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	rt, unlock := s.routers.Get(r.Context(), params.inputMint, params.outputMint)
+	defer unlock()
+	quotes := rt.QuoteAllPools(r.Context(), s.client.RpcClient, params.inputMint, params.amount)
+	routes := make([]routeResponse, 0, len(quotes))
+	for _, quote := range quotes {
+		route := routeResponse{
+			AmmKey:     quote.Pool.GetID(),
+			Label:      string(quote.Pool.ProtocolName()),
+			InputMint:  params.inputMint,
+			OutputMint: params.outputMint,
+			InAmount:   params.amount.String(),
+		}
+		if quote.Err != nil {
+			route.Error = quote.Err.Error()
+		} else {
+			route.OutAmount = quote.AmountOut.String()
+		}
+		routes = append(routes, route)
+	}
+	writeJSON(w, http.StatusOK, routesResponse{Routes: routes})
+}
+
+func (s *server) handleSwapInstructions(w http.ResponseWriter, r *http.Request) {
+	var req swapInstructionsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+		return
+	}
+	user, err := solana.PublicKeyFromBase58(req.UserPublicKey)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid userPublicKey %q", req.UserPublicKey))
+		return
+	}
+	quote := req.QuoteResponse
+	if len(quote.RoutePlan) != 1 {
+		writeError(w, http.StatusBadRequest, errors.New("quoteResponse must have a single route step"))
+		return
+	}
+	amountIn, ok := math.NewIntFromString(quote.InAmount)
+	if !ok || !amountIn.IsPositive() {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid inAmount %q", quote.InAmount))
+		return
+	}
+	minOut, ok := math.NewIntFromString(quote.OtherAmountThreshold)
+	if !ok || minOut.IsNegative() {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid otherAmountThreshold %q", quote.OtherAmountThreshold))
+		return
+	}
+
+	var fee pkg.PlatformFee
+	if quote.PlatformFee != nil && quote.PlatformFee.FeeBps > 0 {
+		if quote.PlatformFee.FeeBps > 10000 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid platformFee feeBps %d", quote.PlatformFee.FeeBps))
+			return
+		}
+		fee.Bps = uint16(quote.PlatformFee.FeeBps)
+		if fee.Account, err = solana.PublicKeyFromBase58(req.FeeAccount); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid feeAccount %q", req.FeeAccount))
+			return
+		}
+	}
+
+	var destination solana.PublicKey
+	if req.DestinationTokenAccount != "" {
+		if destination, err = solana.PublicKeyFromBase58(req.DestinationTokenAccount); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid destinationTokenAccount %q", req.DestinationTokenAccount))
+			return
+		}
+	}
+
+	rt, unlock := s.routers.Get(r.Context(), quote.InputMint, quote.OutputMint)
+	defer unlock()
+	pool, err := rt.PoolByID(quote.RoutePlan[0].SwapInfo.AmmKey)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	wrap := req.WrapAndUnwrapSol == nil || *req.WrapAndUnwrapSol
+	insts, err := router.BuildSwapInstructions(r.Context(), s.client.RpcClient, pool, user, quote.InputMint, amountIn, minOut, router.SwapOptions{
+		SwapBuildOptions: pkg.SwapBuildOptions{WrapInput: wrap, CloseInputWsol: wrap, UnwrapOutput: wrap && destination.IsZero(),
+			OutputAccount: destination, PlatformFee: fee},
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	resp, err := newSwapInstructionsResponse(pool.GetProgramID(), insts)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// newSwapInstructionsResponse splits insts around the first instruction of
+// the pool's program into setup and cleanup instructions
+func newSwapInstructionsResponse(program solana.PublicKey, insts []solana.Instruction) (*swapInstructionsResponse, error) {
+	resp := &swapInstructionsResponse{
+		ComputeBudgetInstructions:   []instructionJSON{},
+		SetupInstructions:           []instructionJSON{},
+		AddressLookupTableAddresses: []string{},
+	}
+	var cleanup []instructionJSON
+	for _, inst := range insts {
+		encoded, err := encodeInstruction(inst)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case resp.SwapInstruction == nil && inst.ProgramID().Equals(program):
+			resp.SwapInstruction = &encoded
+		case resp.SwapInstruction == nil:
+			resp.SetupInstructions = append(resp.SetupInstructions, encoded)
+		default:
+			cleanup = append(cleanup, encoded)
+		}
+	}
+	if resp.SwapInstruction == nil {
+		return nil, errors.New("no swap instruction built")
+	}
+	// Jupiter returns a single cleanup instruction; any extra follow the swap
+	// in OtherInstructions
+	if len(cleanup) > 0 {
+		resp.CleanupInstruction = &cleanup[0]
+		resp.OtherInstructions = cleanup[1:]
+	}
+	return resp, nil
+}
+
+func encodeInstruction(inst solana.Instruction) (instructionJSON, error) {
+	data, err := inst.Data()
+	if err != nil {
+		return instructionJSON{}, fmt.Errorf("failed to encode instruction: %w", err)
+	}
+	accounts := make([]accountJSON, 0, len(inst.Accounts()))
+	for _, account := range inst.Accounts() {
+		accounts = append(accounts, accountJSON{
+			Pubkey:     account.PublicKey.String(),
+			IsSigner:   account.IsSigner,
+			IsWritable: account.IsWritable,
+		})
+	}
+	return instructionJSON{
+		ProgramID: inst.ProgramID().String(),
+		Accounts:  accounts,
+		Data:      base64.StdEncoding.EncodeToString(data),
+	}, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
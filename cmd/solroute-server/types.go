@@ -0,0 +1,134 @@
+package main
+
+import (
+	"time"
+
+	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/router"
+)
+
+// quoteResponse follows the Jupiter v6 quote response
+type quoteResponse struct {
+	InputMint            string          `json:"inputMint"`
+	InAmount             string          `json:"inAmount"`
+	OutputMint           string          `json:"outputMint"`
+	OutAmount            string          `json:"outAmount"`
+	OtherAmountThreshold string          `json:"otherAmountThreshold"`
+	SwapMode             string          `json:"swapMode"`
+	SlippageBps          int64           `json:"slippageBps"`
+	RoutePlan            []routePlanStep `json:"routePlan"`
+	// PlatformFee is set when the quote asked for platformFeeBps
+	PlatformFee *platformFee `json:"platformFee,omitempty"`
+	TimeTaken   float64      `json:"timeTaken"`
+	// InUSD and OutUSD are set when the router values routes
+	InUSD  float64 `json:"inUSD,omitempty"`
+	OutUSD float64 `json:"outUSD,omitempty"`
+}
+
+// platformFee is the fee taken at OtherAmountThreshold, the amount the swap
+// transfers to the feeAccount of the swap-instructions request
+type platformFee struct {
+	Amount string `json:"amount"`
+	FeeBps int    `json:"feeBps"`
+}
+
+type routePlanStep struct {
+	SwapInfo swapInfo `json:"swapInfo"`
+	Percent  int      `json:"percent"`
+}
+
+type swapInfo struct {
+	AmmKey     string `json:"ammKey"`
+	Label      string `json:"label"`
+	InputMint  string `json:"inputMint"`
+	OutputMint string `json:"outputMint"`
+	InAmount   string `json:"inAmount"`
+	OutAmount  string `json:"outAmount"`
+}
+
+func newQuoteResponse(quote *router.RouteQuote, slippageBps int64, platformFeeBps uint16, took time.Duration) *quoteResponse {
+	minOut := quote.AmountOut.MulRaw(10000 - slippageBps).QuoRaw(10000)
+	resp := &quoteResponse{
+		InputMint:            quote.InputMint,
+		InAmount:             quote.AmountIn.String(),
+		OutputMint:           quote.OutputMint,
+		OutAmount:            quote.AmountOut.String(),
+		OtherAmountThreshold: minOut.String(),
+		SwapMode:             "ExactIn",
+		SlippageBps:          slippageBps,
+		RoutePlan: []routePlanStep{{
+			SwapInfo: swapInfo{
+				AmmKey:     quote.Pool.GetID(),
+				Label:      string(quote.Pool.ProtocolName()),
+				InputMint:  quote.InputMint,
+				OutputMint: quote.OutputMint,
+				InAmount:   quote.AmountIn.String(),
+				OutAmount:  quote.AmountOut.String(),
+			},
+			Percent: 100,
+		}},
+		TimeTaken: took.Seconds(),
+		InUSD:     quote.AmountInUSD,
+		OutUSD:    quote.AmountOutUSD,
+	}
+	if platformFeeBps > 0 {
+		amount := pkg.PlatformFee{Bps: platformFeeBps}.Amount(minOut)
+		resp.PlatformFee = &platformFee{Amount: amount.String(), FeeBps: int(platformFeeBps)}
+	}
+	return resp
+}
+
+// routeResponse is one pool's quote for /routes. Error is set instead of
+// OutAmount when the pool failed to quote
+type routeResponse struct {
+	AmmKey     string `json:"ammKey"`
+	Label      string `json:"label"`
+	InputMint  string `json:"inputMint"`
+	OutputMint string `json:"outputMint"`
+	InAmount   string `json:"inAmount"`
+	OutAmount  string `json:"outAmount,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+type routesResponse struct {
+	Routes []routeResponse `json:"routes"`
+}
+
+// swapInstructionsRequest follows the Jupiter v6 swap-instructions request
+type swapInstructionsRequest struct {
+	UserPublicKey string        `json:"userPublicKey"`
+	QuoteResponse quoteResponse `json:"quoteResponse"`
+	// WrapAndUnwrapSol defaults to true like Jupiter's
+	WrapAndUnwrapSol *bool `json:"wrapAndUnwrapSol"`
+	// FeeAccount receives the platform fee of the quote, a token account of
+	// the output mint. Required when the quote has one
+	FeeAccount string `json:"feeAccount"`
+	// DestinationTokenAccount receives the output instead of the user's
+	// associated token account of the output mint
+	DestinationTokenAccount string `json:"destinationTokenAccount"`
+}
+
+type swapInstructionsResponse struct {
+	ComputeBudgetInstructions   []instructionJSON `json:"computeBudgetInstructions"`
+	SetupInstructions           []instructionJSON `json:"setupInstructions"`
+	SwapInstruction             *instructionJSON  `json:"swapInstruction"`
+	CleanupInstruction          *instructionJSON  `json:"cleanupInstruction,omitempty"`
+	OtherInstructions           []instructionJSON `json:"otherInstructions,omitempty"`
+	AddressLookupTableAddresses []string          `json:"addressLookupTableAddresses"`
+}
+
+type instructionJSON struct {
+	ProgramID string        `json:"programId"`
+	Accounts  []accountJSON `json:"accounts"`
+	Data      string        `json:"data"`
+}
+
+type accountJSON struct {
+	Pubkey     string `json:"pubkey"`
+	IsSigner   bool   `json:"isSigner"`
+	IsWritable bool   `json:"isWritable"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/config"
+	"github.com/yimingWOW/solroute/pkg/router"
+	"github.com/yimingWOW/solroute/pkg/sol"
+	"github.com/yimingWOW/solroute/pkg/tokens"
+)
+
+// session is what a command loaded: the client, the pair's pools and, when the
+// command swaps, the amount
+type session struct {
+	cfg      *config.Config
+	client   *sol.Client
+	router   *router.SimpleRouter
+	input    *tokens.Token
+	output   *tokens.Token
+	pools    []pkg.Pool
+	amountIn math.Int
+}
+
+// open parses args with f, connects and loads the pools of the pair. When
+// amount is not nil, it is parsed with the input mint's decimals
+func open(ctx context.Context, f *flags, args []string, amount *string) (*session, error) {
+	if err := f.Parse(args); err != nil {
+		return nil, err
+	}
+	cfg, err := f.load()
+	if err != nil {
+		return nil, err
+	}
+	inputMint, outputMint, err := f.mints()
+	if err != nil {
+		return nil, err
+	}
+	r, client, err := router.FromConfig(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	s := &session{cfg: cfg, client: client, router: r}
+
+	mints, err := tokens.NewResolver(client.RpcClient).ResolveMany(ctx, inputMint, outputMint)
+	if err != nil {
+		s.close()
+		return nil, fmt.Errorf("failed to resolve mints: %w", err)
+	}
+	s.input, s.output = mints[0], mints[1]
+	if amount != nil {
+		if *amount == "" {
+			s.close()
+			return nil, errors.New("-amount is required")
+		}
+		if s.amountIn, err = tokens.ParseAmount(*amount, s.input.Decimals); err != nil {
+			s.close()
+			return nil, fmt.Errorf("invalid amount: %w", err)
+		}
+	}
+
+	if s.pools, err = s.router.QueryAllPools(ctx, inputMint.String(), outputMint.String()); err != nil {
+		s.close()
+		return nil, fmt.Errorf("failed to query pools: %w", err)
+	}
+	return s, nil
+}
+
+func (s *session) close() {
+	s.client.Close()
+}
+
+// symbol names a token in the output, by symbol when it has one
+func symbol(t *tokens.Token) string {
+	if t.Symbol != "" {
+		return t.Symbol
+	}
+	return t.Mint.String()
+}
+
+// swapOptions wraps and unwraps SOL around the swap, so the user trades native SOL
+func swapOptions() router.SwapOptions {
+	return router.SwapOptions{
+		SwapBuildOptions: pkg.SwapBuildOptions{WrapInput: true, CloseInputWsol: true, UnwrapOutput: true},
+		CheckBalance:     true,
+	}
+}
+
+func runPools(ctx context.Context, args []string) error {
+	f := newFlags("pools")
+	s, err := open(ctx, f, args, nil)
+	if err != nil {
+		return err
+	}
+	defer s.close()
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "POOL\tPROTOCOL\tBASE\tQUOTE\tFEE (BPS)\tBASE RESERVE\tQUOTE RESERVE")
+	for _, pool := range s.pools {
+		baseMint, quoteMint := pool.GetTokens()
+		v2 := pkg.AsPoolV2(pool)
+		fee, baseReserve, quoteReserve := "-", "-", "-"
+		if bps, err := v2.GetFeeRate(); err == nil {
+			fee = strconv.FormatFloat(bps, 'f', -1, 64)
+		}
+		if err := pool.Refresh(ctx, s.client.RpcClient); err == nil {
+			if liquidity, err := v2.GetLiquidity(); err == nil {
+				baseReserve, quoteReserve = liquidity.Base.String(), liquidity.Quote.String()
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", pool.GetID(), pool.ProtocolName(), baseMint, quoteMint, fee, baseReserve, quoteReserve)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("%d pools\n", len(s.pools))
+	return nil
+}
+
+func runQuote(ctx context.Context, args []string) error {
+	f := newFlags("quote")
+	amount := f.String("amount", "", "input amount in whole tokens, e.g. 1.5")
+	s, err := open(ctx, f, args, amount)
+	if err != nil {
+		return err
+	}
+	defer s.close()
+
+	quotes := s.router.QuoteAllPools(ctx, s.client.RpcClient, s.input.Mint.String(), s.amountIn)
+	if len(quotes) == 0 {
+		return errors.New("no pool found")
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "POOL\tPROTOCOL\tOUT (%s)\n", symbol(s.output))
+	for _, quote := range quotes {
+		out := "error: " + fmt.Sprint(quote.Err)
+		if quote.Err == nil {
+			out = tokens.FormatAmount(quote.AmountOut, s.output.Decimals)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", quote.Pool.GetID(), quote.Pool.ProtocolName(), out)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	best := quotes[0]
+	if best.Err != nil {
+		return errors.New("no pool could quote the swap")
+	}
+	minOut := best.AmountOut.MulRaw(10000 - slippageBps(s.cfg)).QuoRaw(10000)
+	fmt.Printf("\nBest: %s %s -> %s %s on %s\n",
+		tokens.FormatAmount(s.amountIn, s.input.Decimals), symbol(s.input),
+		tokens.FormatAmount(best.AmountOut, s.output.Decimals), symbol(s.output), best.Pool.GetID())
+	fmt.Printf("Minimum out at %d bps slippage: %s %s\n", slippageBps(s.cfg),
+		tokens.FormatAmount(minOut, s.output.Decimals), symbol(s.output))
+	return nil
+}
+
+func runExplain(ctx context.Context, args []string) error {
+	f := newFlags("explain")
+	amount := f.String("amount", "", "input amount in whole tokens, e.g. 1.5")
+	asJSON := f.Bool("json", false, "print the report as JSON")
+	s, err := open(ctx, f, args, amount)
+	if err != nil {
+		return err
+	}
+	defer s.close()
+
+	explanation, err := s.router.ExplainRoute(ctx, s.client, s.input.Mint.String(), s.output.Mint.String(), s.amountIn)
+	if *asJSON {
+		data, jsonErr := explanation.JSON()
+		if jsonErr != nil {
+			return jsonErr
+		}
+		fmt.Println(string(data))
+	} else {
+		fmt.Print(explanation)
+	}
+	return err
+}
+
+func runSimulate(ctx context.Context, args []string) error {
+	f := newFlags("simulate")
+	amount := f.String("amount", "", "input amount in whole tokens, e.g. 1.5")
+	verbose := f.Bool("v", false, "print the program logs")
+	s, err := open(ctx, f, args, amount)
+	if err != nil {
+		return err
+	}
+	defer s.close()
+	signer, err := loadSigner(s.cfg)
+	if err != nil {
+		return err
+	}
+
+	executed, err := s.router.ExecuteRoute(ctx, s.client, []sol.Signer{signer}, s.input.Mint.String(), s.output.Mint.String(), s.amountIn, router.ExecuteOptions{
+		SlippageBps: slippageBps(s.cfg),
+		Swap:        swapOptions(),
+		Simulate:    true,
+	})
+	if executed != nil {
+		s.printQuote(executed.Quote, executed.MinAmountOut)
+		if executed.Tx != nil {
+			printSimulation(executed.Tx.Simulation, signer.Pubkey(), *verbose)
+		}
+	}
+	return err
+}
+
+// printSimulation prints the cost of a simulated swap and the token balance
+// changes of user, with the program logs when verbose
+func printSimulation(report *sol.SimulationReport, user solana.PublicKey, verbose bool) {
+	if report == nil {
+		return
+	}
+	fmt.Printf("Simulated at slot %d: %d compute units, fee %d lamports\n", report.Slot, report.ComputeUnits, report.Fee)
+	for _, change := range report.TokenBalances {
+		if change.Owner.Equals(user) {
+			fmt.Printf("  %s %s\n", change.Mint, change.Delta())
+		}
+	}
+	if verbose {
+		for _, line := range report.Logs {
+			fmt.Println("  " + line)
+		}
+	}
+}
+
+func runSwap(ctx context.Context, args []string) error {
+	f := newFlags("swap")
+	amount := f.String("amount", "", "input amount in whole tokens, e.g. 1.5")
+	s, err := open(ctx, f, args, amount)
+	if err != nil {
+		return err
+	}
+	defer s.close()
+	signer, err := loadSigner(s.cfg)
+	if err != nil {
+		return err
+	}
+
+	executed, err := s.router.ExecuteRoute(ctx, s.client, []sol.Signer{signer}, s.input.Mint.String(), s.output.Mint.String(), s.amountIn, router.ExecuteOptions{
+		SlippageBps: slippageBps(s.cfg),
+		Swap:        swapOptions(),
+	})
+	if executed != nil {
+		s.printQuote(executed.Quote, executed.MinAmountOut)
+		if executed.DryRun && executed.Tx != nil {
+			fmt.Println("Dry run, not sent")
+			printSimulation(executed.Tx.Simulation, signer.Pubkey(), false)
+		} else if executed.Tx != nil && !executed.Tx.Signature.IsZero() {
+			fmt.Printf("Transaction: https://solscan.io/tx/%s (compute units %d, fee %d lamports)\n",
+				executed.Tx.Signature, executed.Tx.ComputeUnits, executed.Tx.Fee)
+		}
+	}
+	return err
+}
+
+func (s *session) printQuote(quote *router.RouteQuote, minOut math.Int) {
+	fmt.Printf("%s %s -> %s %s (min %s) on %s %s\n",
+		tokens.FormatAmount(quote.AmountIn, s.input.Decimals), symbol(s.input),
+		tokens.FormatAmount(quote.AmountOut, s.output.Decimals), symbol(s.output),
+		tokens.FormatAmount(minOut, s.output.Decimals), quote.Pool.ProtocolName(), quote.Pool.GetID())
+}
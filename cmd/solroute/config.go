@@ -0,0 +1,131 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/yimingWOW/solroute/pkg/config"
+	"github.com/yimingWOW/solroute/pkg/price"
+	"github.com/yimingWOW/solroute/pkg/router"
+	"github.com/yimingWOW/solroute/pkg/sol"
+)
+
+// defaultKeypair is where solana-keygen writes the default keypair
+const defaultKeypair = "~/.config/solana/id.json"
+
+// symbols are the mints that can be given by symbol
+var symbols = map[string]solana.PublicKey{
+	"SOL":  sol.WSOL,
+	"WSOL": sol.WSOL,
+	"USDC": price.USDC,
+	"USDT": price.USDT,
+}
+
+// flags are the flags shared by every command
+type flags struct {
+	*flag.FlagSet
+	configPath  string
+	rpc, ws     string
+	keypair     string
+	inputMint   string
+	outputMint  string
+	slippageBps int64
+}
+
+func newFlags(name string) *flags {
+	f := &flags{FlagSet: flag.NewFlagSet("solroute "+name, flag.ContinueOnError)}
+	f.StringVar(&f.configPath, "config", os.Getenv("SOLROUTE_CONFIG"), "YAML, TOML or JSON config file, defaults to $SOLROUTE_CONFIG")
+	f.StringVar(&f.rpc, "rpc", "", "RPC endpoint, overrides the config and $SOLROUTE_RPC")
+	f.StringVar(&f.ws, "ws", "", "websocket endpoint, overrides the config and $SOLROUTE_WS")
+	f.StringVar(&f.keypair, "keypair", "", "solana-keygen keypair file, overrides the config and $SOLROUTE_KEYPAIR")
+	f.StringVar(&f.inputMint, "in", "", "input mint address or symbol")
+	f.StringVar(&f.outputMint, "out", "", "output mint address or symbol")
+	f.Int64Var(&f.slippageBps, "slippage-bps", 0, "slippage allowed below the quote, overrides the config")
+	return f
+}
+
+// load reads the config file and applies the environment and flags over it.
+// Without a config file, the environment and flags give the endpoint
+func (f *flags) load() (*config.Config, error) {
+	cfg := &config.Config{}
+	if f.configPath != "" {
+		var err error
+		if cfg, err = config.Load(expandHome(f.configPath)); err != nil {
+			return nil, err
+		}
+	} else {
+		cfg.Endpoints = []config.Endpoint{{RPC: os.Getenv("SOLROUTE_RPC"), WS: os.Getenv("SOLROUTE_WS")}}
+		cfg.Keypair = os.Getenv("SOLROUTE_KEYPAIR")
+	}
+	if f.rpc != "" || f.ws != "" {
+		cfg.Endpoints = []config.Endpoint{{RPC: f.rpc, WS: f.ws}}
+	}
+	if f.keypair != "" {
+		cfg.Keypair = f.keypair
+	}
+	if f.slippageBps != 0 {
+		cfg.SlippageBps = f.slippageBps
+	}
+	if len(cfg.Endpoints) == 0 || cfg.Endpoints[0].RPC == "" || cfg.Endpoints[0].WS == "" {
+		return nil, errors.New("an RPC and a websocket endpoint are required")
+	}
+	return cfg, cfg.Validate()
+}
+
+// mints returns the input and output mints
+func (f *flags) mints() (inputMint, outputMint solana.PublicKey, err error) {
+	if f.inputMint == "" || f.outputMint == "" {
+		return solana.PublicKey{}, solana.PublicKey{}, errors.New("-in and -out are required")
+	}
+	if inputMint, err = parseMint(f.inputMint); err != nil {
+		return
+	}
+	outputMint, err = parseMint(f.outputMint)
+	return
+}
+
+func parseMint(s string) (solana.PublicKey, error) {
+	if mint, ok := symbols[strings.ToUpper(s)]; ok {
+		return mint, nil
+	}
+	mint, err := solana.PublicKeyFromBase58(s)
+	if err != nil {
+		return solana.PublicKey{}, fmt.Errorf("invalid mint %q", s)
+	}
+	return mint, nil
+}
+
+// slippageBps returns the configured slippage or the router's default
+func slippageBps(cfg *config.Config) int64 {
+	if cfg.SlippageBps > 0 {
+		return cfg.SlippageBps
+	}
+	return router.DefaultSlippageBps
+}
+
+// loadSigner loads the configured keypair, defaulting to solana-keygen's
+func loadSigner(cfg *config.Config) (sol.Signer, error) {
+	path := cfg.Keypair
+	if path == "" {
+		path = defaultKeypair
+	}
+	key, err := solana.PrivateKeyFromSolanaKeygenFile(expandHome(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load keypair: %w", err)
+	}
+	return sol.PrivateKeySigner(key), nil
+}
+
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
@@ -0,0 +1,77 @@
+// Command solroute quotes and executes swaps from the command line:
+//
+//	solroute pools    -in SOL -out USDC
+//	solroute quote    -in SOL -out USDC -amount 1.5
+//	solroute simulate -in SOL -out USDC -amount 1.5 -slippage-bps 50
+//	solroute swap     -in SOL -out USDC -amount 1.5 -slippage-bps 50
+//
+// Mints are addresses or one of the SOL, USDC and USDT symbols, amounts are in
+// whole tokens. Endpoints, the keypair and the protocols come from the YAML,
+// TOML or JSON file given with -config, see pkg/config, or else from
+// $SOLROUTE_RPC, $SOLROUTE_WS and $SOLROUTE_KEYPAIR. Flags override both
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// command is a subcommand, run with its arguments
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, args []string) error
+}
+
+var commands = []command{
+	{"pools", "list the pools trading a pair", runPools},
+	{"quote", "quote a swap on every pool of a pair", runQuote},
+	{"explain", "report why the route of a swap picks its pool", runExplain},
+	{"simulate", "simulate the swap on the best pool", runSimulate},
+	{"swap", "send the swap on the best pool", runSwap},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	name := os.Args[1]
+	for _, cmd := range commands {
+		if cmd.name == name {
+			err := cmd.run(ctx, os.Args[2:])
+			if errors.Is(err, flag.ErrHelp) {
+				os.Exit(2)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "solroute %s: %v\n", name, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	if name != "help" && name != "-h" && name != "-help" {
+		fmt.Fprintf(os.Stderr, "solroute: unknown command %q\n", name)
+	}
+	usage()
+	os.Exit(2)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: solroute <command> [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-9s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, `Run "solroute <command> -h" for the flags of a command`)
+}
@@ -6,10 +6,11 @@ import (
 
 	"cosmossdk.io/math"
 	"github.com/gagliardetto/solana-go"
-	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/yimingWOW/solroute/pkg"
 	"github.com/yimingWOW/solroute/pkg/protocol"
 	"github.com/yimingWOW/solroute/pkg/router"
 	"github.com/yimingWOW/solroute/pkg/sol"
+	"github.com/yimingWOW/solroute/pkg/tokens"
 )
 
 const (
@@ -22,14 +23,15 @@ const (
 	usdcTokenAddr = "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"
 
 	// Swap parameters
-	defaultAmountIn = 1000000000 // 1 sol (9 decimals)
-	slippageBps     = 100        // 1% slippage
+	defaultAmountIn = "1" // in SOL, converted with the mint's decimals
+	slippageBps     = 100 // 1% slippage
 )
 
 func main() {
 	// TODO: Initialize private key from environment or config file
 	privateKey := solana.MustPrivateKeyFromBase58(privateKeyStr)
 	log.Printf("PublicKey: %v", privateKey.PublicKey())
+	signer := sol.PrivateKeySigner(privateKey)
 
 	ctx := context.Background()
 	solClient, err := sol.NewClient(ctx, mainnetRPC, mainnetWSRPC)
@@ -37,6 +39,9 @@ func main() {
 		log.Fatalf("Failed to create solana client: %v", err)
 	}
 	defer solClient.Close()
+	// Pay the 75th percentile of recent fees for the pools' writable accounts and
+	// size the compute unit limit from a simulation
+	solClient.SetPriorityFee(sol.PriorityFeeOptions{SimulateComputeUnits: true})
 
 	// check balance first
 	balance, err := solClient.GetUserTokenBalance(ctx, privateKey.PublicKey(), sol.WSOL)
@@ -45,13 +50,13 @@ func main() {
 	}
 	log.Printf("User token balance: %v", balance)
 	if balance < 10000000 {
-		err = solClient.CoverWsol(ctx, privateKey, 10000000)
+		err = solClient.CoverWsol(ctx, signer, 10000000)
 		if err != nil {
 			log.Fatalf("Failed to cover wsol: %v", err)
 		}
 	}
 
-	tokenAccount, err := solClient.SelectOrCreateSPLTokenAccount(ctx, privateKey, solana.MustPublicKeyFromBase58(usdcTokenAddr))
+	tokenAccount, err := solClient.SelectOrCreateSPLTokenAccount(ctx, signer, solana.MustPublicKeyFromBase58(usdcTokenAddr))
 	if err != nil {
 		log.Fatalf("Failed to get user token balance: %v", err)
 	}
@@ -74,37 +79,44 @@ func main() {
 		log.Printf("Found pool: %v", pool.GetID())
 	}
 
+	// Resolve the mints' decimals rather than assuming them
+	resolver := tokens.NewResolver(solClient.RpcClient)
+	mints, err := resolver.ResolveMany(ctx, sol.WSOL, solana.MustPublicKeyFromBase58(usdcTokenAddr))
+	if err != nil {
+		log.Fatalf("Failed to resolve mints: %v", err)
+	}
+	wsolToken, usdcToken := mints[0], mints[1]
+
 	// Find best pool for the swap
-	amountIn := math.NewInt(defaultAmountIn)
+	amountIn, err := tokens.ParseAmount(defaultAmountIn, wsolToken.Decimals)
+	if err != nil {
+		log.Fatalf("Invalid amount: %v", err)
+	}
 	bestPool, amountOut, err := router.GetBestPool(ctx, solClient.RpcClient, sol.WSOL.String(), usdcTokenAddr, amountIn)
 	if err != nil {
 		log.Fatalf("Failed to get best pool: %v", err)
 	}
 	log.Printf("Selected best pool: %v", bestPool.GetID())
-	log.Printf("Expected output amount: %v", amountOut)
+	log.Printf("Expected output amount: %v %s", tokens.FormatAmount(amountOut, usdcToken.Decimals), usdcToken.Symbol)
 
 	// Calculate minimum output amount with slippage
 	minAmountOut := amountOut.Mul(math.NewInt(10000 - slippageBps)).Quo(math.NewInt(10000))
 
 	// Build swap instructions
 	instructions, err := bestPool.BuildSwapInstructions(ctx, solClient.RpcClient,
-		privateKey.PublicKey(), usdcTokenAddr, amountIn, minAmountOut)
+		privateKey.PublicKey(), usdcTokenAddr, amountIn, minAmountOut, pkg.SwapBuildOptions{})
 	if err != nil {
 		log.Fatalf("Failed to build swap instructions: %v", err)
 	}
 	log.Printf("Generated swap instructions: %v", instructions)
 
 	// Prepare transaction
-	signers := []solana.PrivateKey{privateKey}
-	res, err := solClient.RpcClient.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
-	if err != nil {
-		log.Fatalf("Failed to get blockhash: %v", err)
-	}
+	signers := []sol.Signer{signer}
 
-	// Send transaction
-	sig, err := solClient.SendTx(ctx, res.Value.Blockhash, signers, instructions, true)
+	// Send transaction, SendTx attaches a recent blockhash
+	result, err := solClient.SendTx(ctx, solana.Hash{}, signers, instructions, true)
 	if err != nil {
 		log.Fatalf("Failed to send transaction: %v", err)
 	}
-	log.Printf("Transaction successful: https://solscan.io/tx/%v", sig)
+	log.Printf("Transaction successful: https://solscan.io/tx/%v (compute units %d, fee %d lamports)", result.Signature, result.ComputeUnits, result.Fee)
 }
@@ -2,12 +2,25 @@ package pkg
 
 import (
 	"context"
+	"time"
 
 	"cosmossdk.io/math"
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
 )
 
+// MaxStateAge is how long the constant product pools, e.g. Raydium AMM and
+// CPMM, quote from the state their last Refresh or ApplyAccount loaded before
+// Quote re-reads it. Pools kept current by a subscription whose accounts
+// don't change re-read it once per MaxStateAge. Set it before quoting
+var MaxStateAge = 2 * time.Second
+
+// StateStale reports whether pool state loaded at loadedAt is older than
+// MaxStateAge. State never loaded is stale
+func StateStale(loadedAt time.Time) bool {
+	return time.Since(loadedAt) > MaxStateAge
+}
+
 // ProtocolName represents the string name of AMM protocol
 type ProtocolName string
 
@@ -30,13 +43,27 @@ const (
 	ProtocolTypePumpAmm
 )
 
+// AccountFetcher reads the accounts pools quote from. *rpc.Client implements
+// it; caches, mocks and stores fed by subscriptions can stand in for it. Like
+// getMultipleAccounts, the result holds a nil account for each missing one
+type AccountFetcher interface {
+	GetMultipleAccounts(ctx context.Context, accounts ...solana.PublicKey) (*rpc.GetMultipleAccountsResult, error)
+}
+
 type Pool interface {
 	ProtocolName() ProtocolName
 	ProtocolType() ProtocolType
 	GetProgramID() solana.PublicKey
 	GetID() string
 	GetTokens() (baseMint, quoteMint string)
-	Quote(ctx context.Context, solClient *rpc.Client, inputMint string, inputAmount math.Int) (math.Int, error)
+	// Refresh reloads the on-chain state Quote works from, so a pool kept
+	// across blocks can be re-quoted without being rediscovered. Pools read at
+	// the client's default commitment, see sol.CommitmentOptions.Quoting
+	Refresh(ctx context.Context, fetcher AccountFetcher) error
+	// Quote returns the output of inputAmount of inputMint. Pools quote from
+	// their last refreshed state, fetching what they lack, e.g. tick arrays,
+	// and re-reading balances older than MaxStateAge
+	Quote(ctx context.Context, fetcher AccountFetcher, inputMint string, inputAmount math.Int) (math.Int, error)
 	BuildSwapInstructions(
 		ctx context.Context,
 		solClient *rpc.Client,
@@ -44,10 +71,81 @@ type Pool interface {
 		inputMint string,
 		inputAmount math.Int,
 		minOut math.Int,
+		opts SwapBuildOptions,
 	) ([]solana.Instruction, error)
 }
 
+// ExactOutQuoter is implemented by pools that can quote the input required to
+// receive an exact output amount
+type ExactOutQuoter interface {
+	QuoteExactOut(ctx context.Context, fetcher AccountFetcher, inputMint string, outputAmount math.Int) (math.Int, error)
+}
+
+// AccountUpdater is implemented by pools that can apply account updates pushed
+// by a subscription instead of refetching them in Refresh
+type AccountUpdater interface {
+	// WatchedAccounts returns the accounts whose changes affect quoting. The set
+	// may change after ApplyAccount, e.g. when the active price range moves
+	WatchedAccounts() []solana.PublicKey
+	// ApplyAccount updates the pool from the new data of one of WatchedAccounts
+	ApplyAccount(pubkey solana.PublicKey, data []byte) error
+}
+
+// VaultReporter is implemented by pools holding their liquidity in token
+// accounts, so it can be valued from their balances
+type VaultReporter interface {
+	// Vaults returns the token accounts of the mints GetTokens returns, in order
+	Vaults() (baseVault, quoteVault solana.PublicKey)
+}
+
 type Protocol interface {
 	FetchPoolsByPair(ctx context.Context, baseMint, quoteMint string) ([]Pool, error)
+	// FetchPoolsByMint returns every pool trading mint against any token, e.g.
+	// to build a route graph. Popular mints have many pools, which are all
+	// loaded
+	FetchPoolsByMint(ctx context.Context, mint string) ([]Pool, error)
 	FetchPoolByID(ctx context.Context, poolID string) (Pool, error)
+	// FetchPoolsByIDs loads pools in few getMultipleAccounts calls, e.g. the
+	// list of an external indexer. IDs that aren't pools of the protocol are
+	// left out
+	FetchPoolsByIDs(ctx context.Context, poolIDs []string) ([]Pool, error)
+}
+
+// PagedProtocol is implemented by protocols that can discover the pools of a
+// pair by key first and load the full accounts in pages afterwards, instead
+// of fetching every account in one getProgramAccounts call
+type PagedProtocol interface {
+	Protocol
+	// FetchPoolsByPairPaged finds the pools of the pair with a keys-only
+	// scan, or a scan of their summaries when opts has a Shortlist, then
+	// loads the candidates opts keeps a page at a time and passes each page
+	// to fn. An error from fn stops the fetch and is returned
+	FetchPoolsByPairPaged(ctx context.Context, baseMint, quoteMint string, opts PageOptions, fn func([]Pool) error) error
+}
+
+// PageOptions configures FetchPoolsByPairPaged. Zero fields keep the defaults
+type PageOptions struct {
+	// PageSize is the number of pool accounts loaded per request, at most and
+	// by default 100
+	PageSize int
+	// Skip leaves out a candidate before its account is loaded, e.g. a pool
+	// the caller already holds
+	Skip func(poolID solana.PublicKey) bool
+	// Shortlist leaves out a candidate before its account is loaded when it
+	// returns false. Discovery then reads the mints and liquidity field of
+	// every candidate with a dataSlice instead of only its key
+	Shortlist func(PoolSummary) bool
+}
+
+// PoolSummary is the part of a pool account discovery reads with a dataSlice,
+// a fraction of the account, to shortlist the pools worth loading
+type PoolSummary struct {
+	ID        solana.PublicKey
+	Protocol  ProtocolName
+	BaseMint  solana.PublicKey
+	QuoteMint solana.PublicKey
+	// Liquidity is the active liquidity of a concentrated liquidity pool or
+	// the LP token supply of a constant product pool, in raw units. Nil for
+	// pools without such a field, e.g. Meteora DLMM
+	Liquidity math.Int
 }
@@ -0,0 +1,309 @@
+// Package arbitrage scans token cycles for round trips returning more than they
+// cost: each leg of a cycle is quoted on every pool of its pair, the best legs
+// are chained, and the swap fees, transaction fees and priority fees are
+// deducted from what comes back
+package arbitrage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/logger"
+	"github.com/yimingWOW/solroute/pkg/router"
+	"github.com/yimingWOW/solroute/pkg/sol"
+	"github.com/yimingWOW/solroute/pkg/watcher"
+)
+
+// Scanner defaults
+const (
+	DefaultScanInterval = time.Second
+	// DefaultFixedCost is the base fee of a transaction with one signature
+	DefaultFixedCost = 5000
+	// DefaultBuffer is the capacity of the opportunity channel
+	DefaultBuffer = 16
+)
+
+// Cycle is a round trip through Mints, back to Mints[0]: [SOL, USDC] swaps
+// SOL to USDC and back, [SOL, USDC, BONK] goes SOL, USDC, BONK, SOL
+type Cycle struct {
+	Mints []string
+	// AmountIn of Mints[0] the cycle starts with, in base units
+	AmountIn math.Int
+}
+
+// Leg is one swap of a cycle on the pool quoting it best
+type Leg struct {
+	Pool       pkg.Pool
+	InputMint  string
+	OutputMint string
+	AmountIn   math.Int
+	AmountOut  math.Int
+}
+
+// Opportunity is a quoted cycle. Amounts are in base units of Mints[0]
+type Opportunity struct {
+	Cycle     Cycle
+	Legs      []Leg
+	AmountIn  math.Int
+	AmountOut math.Int
+	// Profit is AmountOut less AmountIn, net of the pools' swap fees
+	Profit math.Int
+	// Cost is the lamports sending the legs in one transaction is expected to
+	// cost: fees, priority fees and tips. Rent of created accounts isn't counted
+	Cost uint64
+	// CostInMint is Cost valued in Mints[0]
+	CostInMint math.Int
+	// NetProfit is Profit less CostInMint
+	NetProfit math.Int
+	At        time.Time
+}
+
+// ScannerOptions configures NewScanner. Zero fields keep the defaults
+type ScannerOptions struct {
+	Cycles []Cycle
+	// Interval between scans of all cycles. Defaults to DefaultScanInterval
+	Interval time.Duration
+	// MinNetProfit is the least NetProfit an opportunity is emitted with.
+	// Defaults to 1, any net gain
+	MinNetProfit math.Int
+	// User, when set, has the cost of profitable cycles estimated with
+	// sol.Client.EstimateCost on their swap instructions for this wallet,
+	// including the client's priority fee
+	User solana.PublicKey
+	// Swap selects the WSOL handling of the legs the cost is estimated for
+	Swap router.SwapOptions
+	// Cost configures the estimate, e.g. a Jito tip
+	Cost sol.CostOptions
+	// FixedCost is the lamports a cycle is assumed to cost when User isn't
+	// set. Defaults to DefaultFixedCost
+	FixedCost uint64
+	// Watcher, when set, keeps the pools of the legs live while the context
+	// of Run is, so scans quote from account subscriptions instead of
+	// refetching the pools
+	Watcher *watcher.PoolWatcher
+	// Buffer is the capacity of the opportunity channel. Defaults to DefaultBuffer
+	Buffer int
+}
+
+// Scanner quotes cycles at an interval and emits the profitable ones
+type Scanner struct {
+	routers *router.PairRouters
+	client  *sol.Client
+	opts    ScannerOptions
+	out     chan Opportunity
+	logger  logger.Logger
+
+	// watched are the pool instances handed to the watcher, by id
+	watchedMu sync.Mutex
+	watched   map[string]pkg.Pool
+}
+
+// NewScanner creates a Scanner quoting with routers, whose pairs it loads on
+// first use, and client
+func NewScanner(routers *router.PairRouters, client *sol.Client, opts ScannerOptions) (*Scanner, error) {
+	for i, cycle := range opts.Cycles {
+		if err := cycle.validate(); err != nil {
+			return nil, fmt.Errorf("cycle %d: %w", i, err)
+		}
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultScanInterval
+	}
+	if opts.MinNetProfit.IsNil() {
+		opts.MinNetProfit = math.OneInt()
+	}
+	if opts.FixedCost == 0 {
+		opts.FixedCost = DefaultFixedCost
+	}
+	if opts.Buffer <= 0 {
+		opts.Buffer = DefaultBuffer
+	}
+	return &Scanner{
+		routers: routers,
+		client:  client,
+		opts:    opts,
+		out:     make(chan Opportunity, opts.Buffer),
+		watched: make(map[string]pkg.Pool),
+	}, nil
+}
+
+func (c Cycle) validate() error {
+	if len(c.Mints) < 2 {
+		return errors.New("a cycle needs at least two mints")
+	}
+	for i, mint := range c.Mints {
+		if mint == c.Mints[(i+1)%len(c.Mints)] {
+			return fmt.Errorf("mint %s swaps to itself", mint)
+		}
+	}
+	if c.AmountIn.IsNil() || !c.AmountIn.IsPositive() {
+		return errors.New("cycle amount must be positive")
+	}
+	return nil
+}
+
+// SetLogger sets where failed scans are reported. nil restores logger.Default
+func (s *Scanner) SetLogger(l logger.Logger) {
+	s.logger = l
+}
+
+// Opportunities returns the channel profitable cycles are sent on. It is
+// closed when Run returns. Opportunities are dropped while it is full, as a
+// stale one isn't worth acting on
+func (s *Scanner) Opportunities() <-chan Opportunity {
+	return s.out
+}
+
+// Run scans the cycles every interval until ctx is done
+func (s *Scanner) Run(ctx context.Context) error {
+	defer close(s.out)
+	ticker := time.NewTicker(s.opts.Interval)
+	defer ticker.Stop()
+	for {
+		s.scanAll(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scanner) scanAll(ctx context.Context) {
+	for _, cycle := range s.opts.Cycles {
+		if ctx.Err() != nil {
+			return
+		}
+		opportunity, err := s.Scan(ctx, cycle)
+		if err != nil {
+			logger.Or(s.logger).Debug("failed to scan cycle", "mints", cycle.Mints, "err", err)
+			continue
+		}
+		if opportunity.NetProfit.LT(s.opts.MinNetProfit) {
+			continue
+		}
+		select {
+		case s.out <- *opportunity:
+		default:
+			logger.Or(s.logger).Warn("dropping arbitrage opportunity, channel full", "mints", cycle.Mints)
+		}
+	}
+}
+
+// Scan quotes cycle once and returns it whether it is profitable or not
+func (s *Scanner) Scan(ctx context.Context, cycle Cycle) (*Opportunity, error) {
+	if err := cycle.validate(); err != nil {
+		return nil, err
+	}
+	opportunity := &Opportunity{Cycle: cycle, AmountIn: cycle.AmountIn, At: time.Now()}
+	amount := cycle.AmountIn
+	for i, inputMint := range cycle.Mints {
+		outputMint := cycle.Mints[(i+1)%len(cycle.Mints)]
+		leg, err := s.quoteLeg(ctx, inputMint, outputMint, amount)
+		if err != nil {
+			return nil, err
+		}
+		opportunity.Legs = append(opportunity.Legs, leg)
+		amount = leg.AmountOut
+	}
+	opportunity.AmountOut = amount
+	opportunity.Profit = amount.Sub(cycle.AmountIn)
+
+	cost, err := s.cost(ctx, opportunity)
+	if err != nil {
+		return nil, err
+	}
+	opportunity.Cost = cost
+	if opportunity.CostInMint, err = s.valueLamports(ctx, cycle.Mints[0], cost); err != nil {
+		return nil, err
+	}
+	opportunity.NetProfit = opportunity.Profit.Sub(opportunity.CostInMint)
+	return opportunity, nil
+}
+
+// quoteLeg quotes amountIn on every pool of the pair and returns the best one
+func (s *Scanner) quoteLeg(ctx context.Context, inputMint, outputMint string, amountIn math.Int) (Leg, error) {
+	r, unlock := s.routers.Get(ctx, inputMint, outputMint)
+	defer unlock()
+	if s.opts.Watcher != nil {
+		r.SetWatcher(s.opts.Watcher)
+		s.watch(ctx, r)
+	}
+	// Quotes are sorted best first, failed ones last
+	quotes := r.QuoteAllPools(ctx, s.client.RpcClient, inputMint, amountIn)
+	if len(quotes) == 0 || quotes[0].Err != nil || !quotes[0].AmountOut.IsPositive() {
+		return Leg{}, fmt.Errorf("no route from %s to %s", inputMint, outputMint)
+	}
+	return Leg{
+		Pool:       quotes[0].Pool,
+		InputMint:  inputMint,
+		OutputMint: outputMint,
+		AmountIn:   amountIn,
+		AmountOut:  quotes[0].AmountOut,
+	}, nil
+}
+
+// watch hands the pools of r the watcher doesn't track yet to it. The
+// routers rediscover pairs after their TTL, so instances are compared, not ids
+func (s *Scanner) watch(ctx context.Context, r *router.SimpleRouter) {
+	s.watchedMu.Lock()
+	defer s.watchedMu.Unlock()
+	for _, pool := range r.Pools() {
+		if watched, ok := s.watched[pool.GetID()]; ok && watched == pool {
+			continue
+		}
+		s.watched[pool.GetID()] = pool
+		if err := s.opts.Watcher.Watch(ctx, pool); err != nil {
+			logger.Or(s.logger).Debug("quoting pool without watching it", "pool", pool.GetID(), "err", err)
+		}
+	}
+}
+
+// cost returns the lamports the legs of o are expected to cost in one transaction
+func (s *Scanner) cost(ctx context.Context, o *Opportunity) (uint64, error) {
+	if s.opts.User.IsZero() || !o.Profit.IsPositive() {
+		return s.opts.FixedCost, nil
+	}
+	var insts []solana.Instruction
+	for _, leg := range o.Legs {
+		legInsts, err := s.buildLeg(ctx, leg)
+		if err != nil {
+			return 0, err
+		}
+		insts = append(insts, legInsts...)
+	}
+	estimate, err := s.client.EstimateCost(ctx, s.opts.User, insts, s.opts.Cost)
+	if err != nil {
+		return 0, fmt.Errorf("failed to estimate cost: %w", err)
+	}
+	return estimate.BaseFee + estimate.PriorityFee + estimate.Tip, nil
+}
+
+func (s *Scanner) buildLeg(ctx context.Context, leg Leg) ([]solana.Instruction, error) {
+	_, unlock := s.routers.Get(ctx, leg.InputMint, leg.OutputMint)
+	defer unlock()
+	insts, err := router.BuildSwapInstructions(ctx, s.client.RpcClient, leg.Pool, s.opts.User, leg.InputMint, leg.AmountIn, leg.AmountOut, s.opts.Swap)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build swap instructions: %w", err)
+	}
+	return insts, nil
+}
+
+// valueLamports converts lamports to mint at the best WSOL pool's quote
+func (s *Scanner) valueLamports(ctx context.Context, mint string, lamports uint64) (math.Int, error) {
+	amount := math.NewIntFromUint64(lamports)
+	if mint == sol.WSOL.String() || lamports == 0 {
+		return amount, nil
+	}
+	leg, err := s.quoteLeg(ctx, sol.WSOL.String(), mint, amount)
+	if err != nil {
+		return math.Int{}, fmt.Errorf("failed to value cost: %w", err)
+	}
+	return leg.AmountOut, nil
+}
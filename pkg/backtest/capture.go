@@ -0,0 +1,157 @@
+package backtest
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+	"github.com/yimingWOW/solroute/pkg/sol/soltest"
+)
+
+// captureRPCClient implements rpc.JSONRPCClient, keeping a copy of every
+// whole account its reads return
+type captureRPCClient struct {
+	next *rpc.Client
+
+	mu       sync.Mutex
+	slot     uint64
+	accounts map[solana.PublicKey]soltest.Account
+}
+
+func newCaptureRPCClient(next *rpc.Client) *captureRPCClient {
+	return &captureRPCClient{next: next, accounts: make(map[solana.PublicKey]soltest.Account)}
+}
+
+// take returns the accounts captured since the last call and the highest slot
+// they were read at
+func (c *captureRPCClient) take() (map[solana.PublicKey]soltest.Account, uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	accounts, slot := c.accounts, c.slot
+	c.accounts, c.slot = make(map[solana.PublicKey]soltest.Account), 0
+	return accounts, slot
+}
+
+func (c *captureRPCClient) CallForInto(ctx context.Context, out interface{}, method string, params []interface{}) error {
+	var raw json.RawMessage
+	if err := c.next.RPCCallForInto(ctx, &raw, method, params); err != nil {
+		return err
+	}
+	c.capture(method, params, raw)
+	return json.Unmarshal(raw, out)
+}
+
+func (c *captureRPCClient) CallWithCallback(ctx context.Context, method string, params []interface{}, callback func(*http.Request, *http.Response) error) error {
+	return c.next.RPCCallWithCallback(ctx, method, params, callback)
+}
+
+func (c *captureRPCClient) CallBatch(ctx context.Context, requests jsonrpc.RPCRequests) (jsonrpc.RPCResponses, error) {
+	return c.next.RPCCallBatch(ctx, requests)
+}
+
+// Close closes the wrapped client; it is called by rpc.Client.Close
+func (c *captureRPCClient) Close() error {
+	return c.next.Close()
+}
+
+// encodedAccount is an account in a base64 encoded response
+type encodedAccount struct {
+	Owner      solana.PublicKey `json:"owner"`
+	Lamports   uint64           `json:"lamports"`
+	Data       []string         `json:"data"`
+	Executable bool             `json:"executable"`
+	Space      *uint64          `json:"space"`
+}
+
+// decode returns the account, or false when the response holds a slice of
+// its data or isn't base64 encoded
+func (a *encodedAccount) decode() (soltest.Account, bool) {
+	if a == nil || len(a.Data) != 2 || a.Data[1] != string(solana.EncodingBase64) {
+		return soltest.Account{}, false
+	}
+	data, err := base64.StdEncoding.DecodeString(a.Data[0])
+	if err != nil || (a.Space != nil && *a.Space != uint64(len(data))) {
+		return soltest.Account{}, false
+	}
+	return soltest.Account{Owner: a.Owner, Lamports: a.Lamports, Data: data, Executable: a.Executable}, true
+}
+
+type responseContext struct {
+	Slot uint64 `json:"slot"`
+}
+
+// capture records the accounts of a getAccountInfo, getMultipleAccounts or
+// getProgramAccounts response
+func (c *captureRPCClient) capture(method string, params []interface{}, raw json.RawMessage) {
+	captured := make(map[solana.PublicKey]soltest.Account)
+	var slot uint64
+	switch method {
+	case "getAccountInfo":
+		var key solana.PublicKey
+		var result struct {
+			Context responseContext `json:"context"`
+			Value   *encodedAccount `json:"value"`
+		}
+		if len(params) == 0 || remarshal(params[0], &key) != nil || json.Unmarshal(raw, &result) != nil {
+			return
+		}
+		if account, ok := result.Value.decode(); ok {
+			captured[key] = account
+		}
+		slot = result.Context.Slot
+
+	case "getMultipleAccounts":
+		var keys []solana.PublicKey
+		var result struct {
+			Context responseContext   `json:"context"`
+			Value   []*encodedAccount `json:"value"`
+		}
+		if len(params) == 0 || remarshal(params[0], &keys) != nil || json.Unmarshal(raw, &result) != nil {
+			return
+		}
+		for i, value := range result.Value {
+			if account, ok := value.decode(); ok && i < len(keys) {
+				captured[keys[i]] = account
+			}
+		}
+		slot = result.Context.Slot
+
+	case "getProgramAccounts":
+		var result []struct {
+			Pubkey  solana.PublicKey `json:"pubkey"`
+			Account *encodedAccount  `json:"account"`
+		}
+		if json.Unmarshal(raw, &result) != nil {
+			return
+		}
+		for _, keyed := range result {
+			if account, ok := keyed.Account.decode(); ok {
+				captured[keyed.Pubkey] = account
+			}
+		}
+
+	default:
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, account := range captured {
+		c.accounts[key] = account
+	}
+	c.slot = max(c.slot, slot)
+}
+
+// remarshal decodes a request param, as built by rpc.Client, through its JSON
+func remarshal(param interface{}, out interface{}) error {
+	data, err := json.Marshal(param)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,291 @@
+// Package backtest records the quotes and pool states of pairs over time, and
+// replays the recorded states through an in-memory RPC node so strategies can
+// be run against history with the same pools and routers they use live.
+//
+// A snapshot holds every account the pools read while being quoted, so
+// discovery and quoting work offline on the replayed node
+package backtest
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/logger"
+	"github.com/yimingWOW/solroute/pkg/router"
+	"github.com/yimingWOW/solroute/pkg/sol"
+	"github.com/yimingWOW/solroute/pkg/sol/soltest"
+)
+
+// DefaultRecordInterval is the time between snapshots
+const DefaultRecordInterval = 10 * time.Second
+
+// Columns of the CSV files
+var (
+	quoteHeader = []string{"seq", "time", "slot", "input_mint", "output_mint", "amount_in", "pool", "protocol", "amount_out", "error"}
+	stateHeader = []string{"seq", "time", "slot", "account", "owner", "lamports", "executable", "data"}
+)
+
+// Pair is quoted at every snapshot for each of Amounts of InputMint
+type Pair struct {
+	InputMint  string
+	OutputMint string
+	// Amounts are in base units of InputMint
+	Amounts []math.Int
+}
+
+// RecorderOptions configures NewRecorder. Zero fields keep the defaults
+type RecorderOptions struct {
+	Pairs []Pair
+	// Interval defaults to DefaultRecordInterval
+	Interval time.Duration
+	// Quotes receives a CSV row per pool quote. Nothing is written when nil
+	Quotes io.Writer
+	// States receives a CSV row per account read while quoting, which
+	// NewReplayer loads. Nothing is written when nil
+	States io.Writer
+}
+
+// QuoteRecord is a row of the quotes CSV
+type QuoteRecord struct {
+	Seq        int
+	Time       time.Time
+	Slot       uint64
+	InputMint  string
+	OutputMint string
+	AmountIn   math.Int
+	Pool       string
+	Protocol   pkg.ProtocolName
+	// AmountOut is zero when the pool failed to quote, with Err set
+	AmountOut math.Int
+	Err       string
+}
+
+// Recorder snapshots the quotes and pool states of pairs at an interval
+type Recorder struct {
+	routers *router.PairRouters
+	client  *sol.Client
+	opts    RecorderOptions
+	logger  logger.Logger
+
+	mu     sync.Mutex
+	seq    int
+	quotes *csv.Writer
+	states *csv.Writer
+}
+
+// NewRecorder creates a Recorder quoting with routers, whose pairs it loads on
+// first use. The routers must refresh pools before quoting them, so the pools
+// must not be kept live by a watcher or a PoolSyncService; their reads are
+// what the snapshots capture
+func NewRecorder(routers *router.PairRouters, client *sol.Client, opts RecorderOptions) (*Recorder, error) {
+	for i, pair := range opts.Pairs {
+		if pair.InputMint == "" || pair.OutputMint == "" || pair.InputMint == pair.OutputMint {
+			return nil, fmt.Errorf("pair %d: invalid mints", i)
+		}
+		if len(pair.Amounts) == 0 {
+			return nil, fmt.Errorf("pair %d: no amounts", i)
+		}
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultRecordInterval
+	}
+	r := &Recorder{routers: routers, client: client, opts: opts}
+	if opts.Quotes != nil {
+		r.quotes = csv.NewWriter(opts.Quotes)
+	}
+	if opts.States != nil {
+		r.states = csv.NewWriter(opts.States)
+	}
+	return r, nil
+}
+
+// SetLogger sets where failed snapshots are reported. nil restores logger.Default
+func (r *Recorder) SetLogger(l logger.Logger) {
+	r.logger = l
+}
+
+// Run records a snapshot every interval until ctx is done
+func (r *Recorder) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.opts.Interval)
+	defer ticker.Stop()
+	for {
+		if err := r.Record(ctx); err != nil && ctx.Err() == nil {
+			logger.Or(r.logger).Warn("failed to record snapshot", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Record quotes every pair once and writes the quotes and the accounts read
+// as one snapshot
+func (r *Recorder) Record(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	capture := newCaptureRPCClient(r.client.RpcClient)
+	rpcClient := rpc.NewWithCustomRPCClient(capture)
+
+	var records []QuoteRecord
+	for _, pair := range r.opts.Pairs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		records = append(records, r.quotePair(ctx, rpcClient, pair)...)
+	}
+	accounts, slot := capture.take()
+
+	r.seq++
+	now := time.Now().UTC()
+	for i := range records {
+		records[i].Seq, records[i].Time, records[i].Slot = r.seq, now, slot
+	}
+	return errors.Join(r.writeQuotes(records), r.writeStates(r.seq, now, slot, accounts))
+}
+
+func (r *Recorder) quotePair(ctx context.Context, rpcClient *rpc.Client, pair Pair) []QuoteRecord {
+	rt, unlock := r.routers.Get(ctx, pair.InputMint, pair.OutputMint)
+	defer unlock()
+	var records []QuoteRecord
+	for _, amount := range pair.Amounts {
+		for _, quote := range rt.QuoteAllPools(ctx, rpcClient, pair.InputMint, amount) {
+			record := QuoteRecord{
+				InputMint:  pair.InputMint,
+				OutputMint: pair.OutputMint,
+				AmountIn:   amount,
+				Pool:       quote.Pool.GetID(),
+				Protocol:   quote.Pool.ProtocolName(),
+				AmountOut:  math.ZeroInt(),
+			}
+			if quote.Err != nil {
+				record.Err = quote.Err.Error()
+			} else {
+				record.AmountOut = quote.AmountOut
+			}
+			records = append(records, record)
+		}
+	}
+	return records
+}
+
+func (r *Recorder) writeQuotes(records []QuoteRecord) error {
+	if r.quotes == nil {
+		return nil
+	}
+	if r.seq == 1 {
+		_ = r.quotes.Write(quoteHeader)
+	}
+	for _, q := range records {
+		_ = r.quotes.Write([]string{
+			strconv.Itoa(q.Seq), q.Time.Format(time.RFC3339Nano), strconv.FormatUint(q.Slot, 10),
+			q.InputMint, q.OutputMint, q.AmountIn.String(), q.Pool, string(q.Protocol), q.AmountOut.String(), q.Err,
+		})
+	}
+	r.quotes.Flush()
+	if err := r.quotes.Error(); err != nil {
+		return fmt.Errorf("failed to write quotes: %w", err)
+	}
+	return nil
+}
+
+func (r *Recorder) writeStates(seq int, at time.Time, slot uint64, accounts map[solana.PublicKey]soltest.Account) error {
+	if r.states == nil {
+		return nil
+	}
+	if seq == 1 {
+		_ = r.states.Write(stateHeader)
+	}
+	keys := make([]solana.PublicKey, 0, len(accounts))
+	for key := range accounts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+	for _, key := range keys {
+		account := accounts[key]
+		_ = r.states.Write([]string{
+			strconv.Itoa(seq), at.Format(time.RFC3339Nano), strconv.FormatUint(slot, 10),
+			key.String(), account.Owner.String(), strconv.FormatUint(account.Lamports, 10),
+			strconv.FormatBool(account.Executable), base64.StdEncoding.EncodeToString(account.Data),
+		})
+	}
+	r.states.Flush()
+	if err := r.states.Error(); err != nil {
+		return fmt.Errorf("failed to write states: %w", err)
+	}
+	return nil
+}
+
+// ReadQuotes parses a quotes CSV written by a Recorder
+func ReadQuotes(in io.Reader) ([]QuoteRecord, error) {
+	rows, err := readRows(in, quoteHeader)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]QuoteRecord, 0, len(rows))
+	for i, row := range rows {
+		record := QuoteRecord{
+			InputMint:  row[3],
+			OutputMint: row[4],
+			Pool:       row[6],
+			Protocol:   pkg.ProtocolName(row[7]),
+			Err:        row[9],
+		}
+		var ok bool
+		err := parseCommon(row, &record.Seq, &record.Time, &record.Slot)
+		if err == nil {
+			if record.AmountIn, ok = math.NewIntFromString(row[5]); !ok {
+				err = fmt.Errorf("invalid amount_in %q", row[5])
+			} else if record.AmountOut, ok = math.NewIntFromString(row[8]); !ok {
+				err = fmt.Errorf("invalid amount_out %q", row[8])
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("quote row %d: %w", i+1, err)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// readRows reads a CSV with header, dropping the header row
+func readRows(in io.Reader, header []string) ([][]string, error) {
+	reader := csv.NewReader(in)
+	reader.FieldsPerRecord = len(header)
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read csv: %w", err)
+	}
+	if len(rows) > 0 && rows[0][0] == header[0] {
+		rows = rows[1:]
+	}
+	return rows, nil
+}
+
+// parseCommon parses the seq, time and slot columns every file starts with
+func parseCommon(row []string, seq *int, at *time.Time, slot *uint64) error {
+	var err error
+	if *seq, err = strconv.Atoi(row[0]); err != nil {
+		return fmt.Errorf("invalid seq: %w", err)
+	}
+	if *at, err = time.Parse(time.RFC3339Nano, row[1]); err != nil {
+		return fmt.Errorf("invalid time: %w", err)
+	}
+	if *slot, err = strconv.ParseUint(row[2], 10, 64); err != nil {
+		return fmt.Errorf("invalid slot: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package backtest
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/yimingWOW/solroute/pkg/sol"
+	"github.com/yimingWOW/solroute/pkg/sol/soltest"
+)
+
+// Snapshot is the state a Recorder captured at one point
+type Snapshot struct {
+	Seq      int
+	Time     time.Time
+	Slot     uint64
+	Accounts map[solana.PublicKey]soltest.Account
+}
+
+// Replayer serves recorded snapshots from an in-memory RPC node. Protocols,
+// routers and pools built on Client discover and quote against the loaded
+// snapshot, refreshing from it like from a live node
+type Replayer struct {
+	node      *soltest.RPC
+	client    *sol.Client
+	snapshots []Snapshot
+}
+
+// NewReplayer reads a states CSV written by a Recorder
+func NewReplayer(states io.Reader) (*Replayer, error) {
+	snapshots, err := ReadSnapshots(states)
+	if err != nil {
+		return nil, err
+	}
+	node := soltest.NewRPC()
+	return &Replayer{node: node, client: node.SolClient(), snapshots: snapshots}, nil
+}
+
+// Client returns the client answering from the loaded snapshot
+func (r *Replayer) Client() *sol.Client {
+	return r.client
+}
+
+// Node returns the in-memory node, e.g. to add the strategy's wallet accounts
+func (r *Replayer) Node() *soltest.RPC {
+	return r.node
+}
+
+// Snapshots returns the recorded snapshots in order
+func (r *Replayer) Snapshots() []Snapshot {
+	return r.snapshots
+}
+
+// Load makes the node serve snapshot i. The accounts of earlier snapshots the
+// snapshot doesn't hold stay as they were last recorded, as on chain
+func (r *Replayer) Load(i int) error {
+	if i < 0 || i >= len(r.snapshots) {
+		return fmt.Errorf("snapshot %d out of range, have %d", i, len(r.snapshots))
+	}
+	snapshot := r.snapshots[i]
+	for key, account := range snapshot.Accounts {
+		r.node.SetAccount(key, account)
+	}
+	if snapshot.Slot > 0 {
+		r.node.SetSlot(snapshot.Slot)
+	}
+	return nil
+}
+
+// Run loads each snapshot in order and calls fn with it, stopping at the first
+// error or when ctx is done
+func (r *Replayer) Run(ctx context.Context, fn func(ctx context.Context, snapshot Snapshot) error) error {
+	for i, snapshot := range r.snapshots {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := r.Load(i); err != nil {
+			return err
+		}
+		if err := fn(ctx, snapshot); err != nil {
+			return fmt.Errorf("snapshot %d: %w", snapshot.Seq, err)
+		}
+	}
+	return nil
+}
+
+// ReadSnapshots parses a states CSV written by a Recorder, in recorded order
+func ReadSnapshots(in io.Reader) ([]Snapshot, error) {
+	rows, err := readRows(in, stateHeader)
+	if err != nil {
+		return nil, err
+	}
+	var snapshots []Snapshot
+	for i, row := range rows {
+		var seq int
+		var at time.Time
+		var slot uint64
+		if err := parseCommon(row, &seq, &at, &slot); err != nil {
+			return nil, fmt.Errorf("state row %d: %w", i+1, err)
+		}
+		key, account, err := parseAccount(row)
+		if err != nil {
+			return nil, fmt.Errorf("state row %d: %w", i+1, err)
+		}
+		if len(snapshots) == 0 || snapshots[len(snapshots)-1].Seq != seq {
+			snapshots = append(snapshots, Snapshot{Seq: seq, Time: at, Slot: slot, Accounts: make(map[solana.PublicKey]soltest.Account)})
+		}
+		snapshots[len(snapshots)-1].Accounts[key] = account
+	}
+	return snapshots, nil
+}
+
+func parseAccount(row []string) (solana.PublicKey, soltest.Account, error) {
+	key, err := solana.PublicKeyFromBase58(row[3])
+	if err != nil {
+		return solana.PublicKey{}, soltest.Account{}, fmt.Errorf("invalid account: %w", err)
+	}
+	var account soltest.Account
+	if account.Owner, err = solana.PublicKeyFromBase58(row[4]); err != nil {
+		return solana.PublicKey{}, soltest.Account{}, fmt.Errorf("invalid owner: %w", err)
+	}
+	if account.Lamports, err = strconv.ParseUint(row[5], 10, 64); err != nil {
+		return solana.PublicKey{}, soltest.Account{}, fmt.Errorf("invalid lamports: %w", err)
+	}
+	if account.Executable, err = strconv.ParseBool(row[6]); err != nil {
+		return solana.PublicKey{}, soltest.Account{}, fmt.Errorf("invalid executable: %w", err)
+	}
+	if account.Data, err = base64.StdEncoding.DecodeString(row[7]); err != nil {
+		return solana.PublicKey{}, soltest.Account{}, fmt.Errorf("invalid data: %w", err)
+	}
+	return key, account, nil
+}
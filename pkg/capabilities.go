@@ -0,0 +1,108 @@
+package pkg
+
+import (
+	"errors"
+	"strings"
+
+	"cosmossdk.io/math"
+)
+
+// ErrUnsupported is returned by the PoolV2 accessors of a pool that can't
+// provide them, see AsPoolV2
+var ErrUnsupported = errors.New("not supported by pool")
+
+// Capabilities is a set of optional features of a pool
+type Capabilities uint32
+
+const (
+	// CapExactOut quotes the input for an exact output, see ExactOutQuoter
+	CapExactOut Capabilities = 1 << iota
+	// CapToken2022 swaps Token-2022 mints
+	CapToken2022
+	// CapTickArrays quotes from the tick or bin arrays around the current
+	// price that Refresh loads; a swap crossing past them can't be quoted
+	CapTickArrays
+	// CapAccountUpdates applies pushed account updates, see AccountUpdater
+	CapAccountUpdates
+	// CapVaults holds its liquidity in token accounts, see VaultReporter
+	CapVaults
+)
+
+var capabilityNames = []string{"exact_out", "token_2022", "tick_arrays", "account_updates", "vaults"}
+
+// Has reports whether c includes all of want
+func (c Capabilities) Has(want Capabilities) bool {
+	return c&want == want
+}
+
+func (c Capabilities) String() string {
+	var names []string
+	for i, name := range capabilityNames {
+		if c&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, "|")
+}
+
+// Liquidity is what a pool holds of its mints, in base units
+type Liquidity struct {
+	// Base and Quote follow the order of GetTokens
+	Base  math.Int
+	Quote math.Int
+}
+
+// PoolV2 is a Pool describing its capabilities, fee and liquidity, so callers
+// branch on them instead of asserting optional interfaces or concrete types
+type PoolV2 interface {
+	Pool
+	Capabilities() Capabilities
+	// GetFeeRate returns the fee a swap pays, in basis points of the input
+	GetFeeRate() (float64, error)
+	// GetLiquidity returns the reserves as of the last Refresh or update
+	GetLiquidity() (Liquidity, error)
+}
+
+// AsPoolV2 returns pool as a PoolV2. A pool that doesn't implement it is
+// wrapped: its capabilities are its own Capabilities method, or else derived
+// from the optional interfaces it implements, and GetFeeRate and
+// GetLiquidity fail with ErrUnsupported. The wrapper only implements
+// PoolV2, assert optional interfaces on pool itself
+func AsPoolV2(pool Pool) PoolV2 {
+	if v2, ok := pool.(PoolV2); ok {
+		return v2
+	}
+	return poolAdapter{pool}
+}
+
+type poolAdapter struct {
+	Pool
+}
+
+func (a poolAdapter) Capabilities() Capabilities {
+	if reporter, ok := a.Pool.(interface{ Capabilities() Capabilities }); ok {
+		return reporter.Capabilities()
+	}
+	var caps Capabilities
+	if _, ok := a.Pool.(ExactOutQuoter); ok {
+		caps |= CapExactOut
+	}
+	if _, ok := a.Pool.(AccountUpdater); ok {
+		caps |= CapAccountUpdates
+	}
+	if _, ok := a.Pool.(VaultReporter); ok {
+		caps |= CapVaults
+	}
+	return caps
+}
+
+func (a poolAdapter) GetFeeRate() (float64, error) {
+	return 0, ErrUnsupported
+}
+
+func (a poolAdapter) GetLiquidity() (Liquidity, error) {
+	return Liquidity{}, ErrUnsupported
+}
@@ -0,0 +1,333 @@
+// Package config loads declarative router deployments from YAML, TOML or JSON
+// files, for router.FromConfig:
+//
+//	endpoints:
+//	  - rpc: https://...
+//	    ws: wss://...
+//	network: devnet
+//	protocols: [raydium_amm, raydium_clmm, meteora_dlmm]
+//	slippageBps: 50
+//	priorityFee:
+//	  strategy: percentile
+//	  percentile: 75
+//	watchlist:
+//	  - base: So11111111111111111111111111111111111111112
+//	    quote: EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/gagliardetto/solana-go"
+	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/sol"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is the encoding of a config file
+type Format string
+
+const (
+	FormatYAML Format = "yaml"
+	FormatTOML Format = "toml"
+	FormatJSON Format = "json"
+)
+
+// Endpoint strategies accepted in Config.Strategy
+const (
+	StrategyFailover   = "failover"
+	StrategyRoundRobin = "round_robin"
+)
+
+// Networks accepted in Config.Network
+const (
+	NetworkMainnet = "mainnet"
+	NetworkDevnet  = "devnet"
+)
+
+// Priority fee strategies accepted in PriorityFee.Strategy
+const (
+	// PriorityFeeNone sends transactions without compute budget instructions
+	PriorityFeeNone = "none"
+	// PriorityFeePercentile pays a percentile of recent prioritization fees
+	PriorityFeePercentile = "percentile"
+	// PriorityFeeFixed pays MicroLamports per compute unit
+	PriorityFeeFixed = "fixed"
+)
+
+// Config describes a router deployment. Zero fields keep the defaults
+type Config struct {
+	// Endpoints are the RPC nodes, tried in order or round robin per Strategy
+	Endpoints []Endpoint `yaml:"endpoints" toml:"endpoints" json:"endpoints"`
+	// Strategy is StrategyFailover, the default, or StrategyRoundRobin
+	Strategy string `yaml:"strategy" toml:"strategy" json:"strategy"`
+	// Network selects the program deployments: NetworkMainnet, the default, or
+	// NetworkDevnet. A local validator cloning mainnet programs uses mainnet
+	Network string `yaml:"network" toml:"network" json:"network"`
+	// ProgramIDs overrides the program of protocols by name, e.g. programs
+	// deployed to a local validator under other addresses
+	ProgramIDs map[string]string `yaml:"programIds" toml:"programIds" json:"programIds"`
+	// Protocols restricts routing to these protocol names, e.g. "raydium_amm".
+	// All protocols are used when empty
+	Protocols []string `yaml:"protocols" toml:"protocols" json:"protocols"`
+	// Intermediates are mints routes can pass through, e.g. USDC. The pools
+	// pairing them with the watchlist's mints are kept warm with the watchlist
+	Intermediates []string `yaml:"intermediates" toml:"intermediates" json:"intermediates"`
+	// SlippageBps is the slippage executed routes allow below the quote.
+	// Defaults to router.DefaultSlippageBps
+	SlippageBps int64 `yaml:"slippageBps" toml:"slippageBps" json:"slippageBps"`
+	// MinLiquidityUSD skips pools holding less. Requires a price oracle, which
+	// router.FromConfig doesn't set up
+	MinLiquidityUSD float64 `yaml:"minLiquidityUsd" toml:"minLiquidityUsd" json:"minLiquidityUsd"`
+	// MinPoolLiquidity and MinVaultBalance drop dust pools as they are
+	// discovered, in raw units, see router.DiscoveryFilter
+	MinPoolLiquidity uint64      `yaml:"minPoolLiquidity" toml:"minPoolLiquidity" json:"minPoolLiquidity"`
+	MinVaultBalance  uint64      `yaml:"minVaultBalance" toml:"minVaultBalance" json:"minVaultBalance"`
+	PriorityFee      PriorityFee `yaml:"priorityFee" toml:"priorityFee" json:"priorityFee"`
+	// DryRun simulates executed routes instead of sending them, see
+	// router.SimpleRouter.SetDryRun
+	DryRun bool `yaml:"dryRun" toml:"dryRun" json:"dryRun"`
+	// Watchlist pairs have their pools kept warm by a router.PoolSyncService
+	Watchlist []Pair   `yaml:"watchlist" toml:"watchlist" json:"watchlist"`
+	PoolSync  PoolSync `yaml:"poolSync" toml:"poolSync" json:"poolSync"`
+	// Keypair is the solana-keygen file tools like cmd/solroute sign with
+	Keypair string `yaml:"keypair" toml:"keypair" json:"keypair"`
+}
+
+// Endpoint is an RPC endpoint with its optional WebSocket counterpart
+type Endpoint struct {
+	RPC string `yaml:"rpc" toml:"rpc" json:"rpc"`
+	WS  string `yaml:"ws" toml:"ws" json:"ws"`
+}
+
+// PriorityFee selects how compute units are priced, see sol.PriorityFeeOptions
+type PriorityFee struct {
+	// Strategy is PriorityFeeNone, the default, PriorityFeePercentile or PriorityFeeFixed
+	Strategy string `yaml:"strategy" toml:"strategy" json:"strategy"`
+	// Percentile of recent fees paid by the percentile strategy
+	Percentile int `yaml:"percentile" toml:"percentile" json:"percentile"`
+	// MicroLamports per compute unit paid by the fixed strategy
+	MicroLamports uint64 `yaml:"microLamports" toml:"microLamports" json:"microLamports"`
+	// MinMicroLamports and MaxMicroLamports clamp the percentile strategy
+	MinMicroLamports uint64 `yaml:"minMicroLamports" toml:"minMicroLamports" json:"minMicroLamports"`
+	MaxMicroLamports uint64 `yaml:"maxMicroLamports" toml:"maxMicroLamports" json:"maxMicroLamports"`
+	// ComputeUnitLimit is set on transactions when non-zero, otherwise the
+	// limit is sized from a simulation
+	ComputeUnitLimit uint32 `yaml:"computeUnitLimit" toml:"computeUnitLimit" json:"computeUnitLimit"`
+}
+
+// Pair is a watched token pair, in either order
+type Pair struct {
+	Base  string `yaml:"base" toml:"base" json:"base"`
+	Quote string `yaml:"quote" toml:"quote" json:"quote"`
+}
+
+// PoolSync tunes the pool sync service of the watchlist, see router.PoolSyncOptions
+type PoolSync struct {
+	DiscoveryInterval Duration `yaml:"discoveryInterval" toml:"discoveryInterval" json:"discoveryInterval"`
+	RefreshInterval   Duration `yaml:"refreshInterval" toml:"refreshInterval" json:"refreshInterval"`
+}
+
+// Duration is a time.Duration written like "2s" or "5m" in every format
+type Duration time.Duration
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// Load reads the config file at path, in the format its extension names:
+// .yaml, .yml, .toml or .json
+func Load(path string) (*Config, error) {
+	var format Format
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		format = FormatYAML
+	case ".toml":
+		format = FormatTOML
+	case ".json":
+		format = FormatJSON
+	default:
+		return nil, fmt.Errorf("unknown config format of %s", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	cfg, err := Parse(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Parse decodes and validates a config. Unknown fields are rejected, so typos
+// don't silently keep a default
+func Parse(data []byte, format Format) (*Config, error) {
+	cfg := &Config{}
+	switch format {
+	case FormatYAML:
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("invalid config: %w", err)
+		}
+	case FormatTOML:
+		meta, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid config: %w", err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return nil, fmt.Errorf("invalid config: unknown field %q", undecoded[0].String())
+		}
+	case FormatJSON:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(cfg); err != nil {
+			return nil, fmt.Errorf("invalid config: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown config format %q", format)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate checks the fields a deployment can't start without or can't make sense of
+func (c *Config) Validate() error {
+	if len(c.Endpoints) == 0 {
+		return errors.New("invalid config: no endpoint")
+	}
+	for i, endpoint := range c.Endpoints {
+		if endpoint.RPC == "" {
+			return fmt.Errorf("invalid config: endpoint %d has no rpc url", i)
+		}
+	}
+	switch c.Strategy {
+	case "", StrategyFailover, StrategyRoundRobin:
+	default:
+		return fmt.Errorf("invalid config: unknown strategy %q", c.Strategy)
+	}
+	switch c.Network {
+	case "", NetworkMainnet, NetworkDevnet:
+	default:
+		return fmt.Errorf("invalid config: unknown network %q", c.Network)
+	}
+	for name, programID := range c.ProgramIDs {
+		if !knownProtocols[pkg.ProtocolName(name)] {
+			return fmt.Errorf("invalid config: program id of unknown protocol %q", name)
+		}
+		if _, err := solana.PublicKeyFromBase58(programID); err != nil {
+			return fmt.Errorf("invalid config: invalid program id %q of %s", programID, name)
+		}
+	}
+	for _, name := range c.Protocols {
+		if !knownProtocols[pkg.ProtocolName(name)] {
+			return fmt.Errorf("invalid config: unknown protocol %q", name)
+		}
+	}
+	for _, mint := range c.Intermediates {
+		if _, err := solana.PublicKeyFromBase58(mint); err != nil {
+			return fmt.Errorf("invalid config: invalid intermediate mint %q", mint)
+		}
+	}
+	for _, pair := range c.Watchlist {
+		for _, mint := range []string{pair.Base, pair.Quote} {
+			if _, err := solana.PublicKeyFromBase58(mint); err != nil {
+				return fmt.Errorf("invalid config: invalid watchlist mint %q", mint)
+			}
+		}
+	}
+	if c.SlippageBps < 0 || c.SlippageBps > 10000 {
+		return fmt.Errorf("invalid config: slippage %d bps out of range", c.SlippageBps)
+	}
+	switch c.PriorityFee.Strategy {
+	case "", PriorityFeeNone:
+	case PriorityFeePercentile:
+		if p := c.PriorityFee.Percentile; p < 0 || p > 100 {
+			return fmt.Errorf("invalid config: priority fee percentile %d out of range", p)
+		}
+	case PriorityFeeFixed:
+		if c.PriorityFee.MicroLamports == 0 {
+			return errors.New("invalid config: fixed priority fee requires microLamports")
+		}
+	default:
+		return fmt.Errorf("invalid config: unknown priority fee strategy %q", c.PriorityFee.Strategy)
+	}
+	return nil
+}
+
+// knownProtocols are the names Config.Protocols accepts
+var knownProtocols = map[pkg.ProtocolName]bool{
+	pkg.ProtocolNameRaydiumAmm:  true,
+	pkg.ProtocolNameRaydiumClmm: true,
+	pkg.ProtocolNameRaydiumCpmm: true,
+	pkg.ProtocolNameMeteoraDlmm: true,
+	pkg.ProtocolNamePumpAmm:     true,
+}
+
+// NewClient connects to the configured endpoints and applies the priority fee
+func (c *Config) NewClient(ctx context.Context) (*sol.Client, error) {
+	var client *sol.Client
+	var err error
+	if len(c.Endpoints) == 1 {
+		client, err = sol.NewClient(ctx, c.Endpoints[0].RPC, c.Endpoints[0].WS)
+	} else {
+		endpoints := make([]sol.Endpoint, len(c.Endpoints))
+		for i, endpoint := range c.Endpoints {
+			endpoints[i] = sol.Endpoint{RPC: endpoint.RPC, WS: endpoint.WS}
+		}
+		opts := sol.EndpointOptions{Strategy: sol.StrategyFailover}
+		if c.Strategy == StrategyRoundRobin {
+			opts.Strategy = sol.StrategyRoundRobin
+		}
+		client, err = sol.NewClientWithEndpoints(ctx, endpoints, opts)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create solana client: %w", err)
+	}
+	if opts, ok := c.PriorityFee.Options(); ok {
+		client.SetPriorityFee(opts)
+	}
+	return client, nil
+}
+
+// Options returns the sol.PriorityFeeOptions of the strategy, false for none
+func (p PriorityFee) Options() (sol.PriorityFeeOptions, bool) {
+	opts := sol.PriorityFeeOptions{
+		ComputeUnitLimit:     p.ComputeUnitLimit,
+		SimulateComputeUnits: p.ComputeUnitLimit == 0,
+	}
+	switch p.Strategy {
+	case PriorityFeePercentile:
+		opts.Percentile = p.Percentile
+		opts.MinMicroLamports = p.MinMicroLamports
+		opts.MaxMicroLamports = p.MaxMicroLamports
+	case PriorityFeeFixed:
+		opts.MinMicroLamports = p.MicroLamports
+		opts.MaxMicroLamports = p.MicroLamports
+	default:
+		return sol.PriorityFeeOptions{}, false
+	}
+	return opts, true
+}
@@ -0,0 +1,15 @@
+package pkg
+
+import "errors"
+
+// ErrZeroLiquidity is returned by Quote when the pool cannot fill the swap because
+// its reserves (or the liquidity left in range) are empty
+var ErrZeroLiquidity = errors.New("zero liquidity")
+
+// ErrNotLoaded is returned by the accessors of a pool whose state Refresh
+// hasn't loaded yet
+var ErrNotLoaded = errors.New("pool state not loaded")
+
+// ErrRouterOption is returned by the BuildSwapInstructions of pools for the
+// swap options only router.BuildSwapInstructions applies
+var ErrRouterOption = errors.New("swap option applied by router.BuildSwapInstructions")
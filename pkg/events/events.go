@@ -0,0 +1,239 @@
+// Package events publishes what the router does and sees, executed routes,
+// dropped transactions, pool liquidity moves and quotes straying from the
+// price oracle, to subscribed sinks such as webhooks and channels, so
+// operators can alert on them without polling
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/logger"
+)
+
+// Type names an event
+type Type string
+
+const (
+	// TypeRouteExecuted carries a RouteExecuted, for sent and dry-run routes
+	TypeRouteExecuted Type = "route.executed"
+	// TypeTxDropped carries a TxDropped, for transactions that expired unlanded
+	TypeTxDropped Type = "tx.dropped"
+	// TypeLiquidityChanged carries a LiquidityChanged
+	TypeLiquidityChanged Type = "pool.liquidity_changed"
+	// TypeQuoteDeviation carries a QuoteDeviation
+	TypeQuoteDeviation Type = "quote.deviation"
+)
+
+// Event is a published event. Data holds the payload of its Type
+type Event struct {
+	Type Type        `json:"type"`
+	Time time.Time   `json:"time"`
+	Data interface{} `json:"data"`
+}
+
+// RouteExecuted is a route ExecuteRoute sent or simulated
+type RouteExecuted struct {
+	Pool         string           `json:"pool"`
+	Protocol     pkg.ProtocolName `json:"protocol"`
+	InputMint    string           `json:"inputMint"`
+	OutputMint   string           `json:"outputMint"`
+	AmountIn     math.Int         `json:"amountIn"`
+	AmountOut    math.Int         `json:"amountOut"`
+	MinAmountOut math.Int         `json:"minAmountOut"`
+	Signature    string           `json:"signature,omitempty"`
+	Slot         uint64           `json:"slot,omitempty"`
+	Fee          uint64           `json:"fee,omitempty"`
+	DryRun       bool             `json:"dryRun,omitempty"`
+	// Err is why the transaction failed, when it did
+	Err string `json:"err,omitempty"`
+}
+
+// TxDropped is a sent transaction whose blockhash expired before it landed
+type TxDropped struct {
+	Pool       string   `json:"pool"`
+	InputMint  string   `json:"inputMint"`
+	OutputMint string   `json:"outputMint"`
+	AmountIn   math.Int `json:"amountIn"`
+	Signature  string   `json:"signature"`
+	Err        string   `json:"err"`
+}
+
+// LiquidityChanged is a pool whose USD liquidity moved beyond the threshold
+// of a LiquidityMonitor since the previous event, or since it was first read
+type LiquidityChanged struct {
+	Pool        string           `json:"pool"`
+	Protocol    pkg.ProtocolName `json:"protocol"`
+	PreviousUSD float64          `json:"previousUsd"`
+	USD         float64          `json:"usd"`
+	ChangeBps   int64            `json:"changeBps"`
+}
+
+// QuoteDeviation is a quote whose output is worth more or less than its input
+// at oracle prices by more than the threshold of CheckQuote
+type QuoteDeviation struct {
+	Pool         string           `json:"pool"`
+	Protocol     pkg.ProtocolName `json:"protocol"`
+	InputMint    string           `json:"inputMint"`
+	OutputMint   string           `json:"outputMint"`
+	AmountIn     math.Int         `json:"amountIn"`
+	AmountOut    math.Int         `json:"amountOut"`
+	AmountInUSD  float64          `json:"amountInUsd"`
+	AmountOutUSD float64          `json:"amountOutUsd"`
+	// DeviationBps is negative when the output is worth less than the input
+	DeviationBps int64 `json:"deviationBps"`
+}
+
+// Sink receives the events of a subscription
+type Sink interface {
+	Send(ctx context.Context, event Event) error
+}
+
+// SinkFunc adapts a function to Sink
+type SinkFunc func(ctx context.Context, event Event) error
+
+func (f SinkFunc) Send(ctx context.Context, event Event) error {
+	return f(ctx, event)
+}
+
+// DefaultBuffer is the number of events a subscription queues before dropping
+const DefaultBuffer = 64
+
+// BusOptions configures NewBus. Zero fields keep the defaults
+type BusOptions struct {
+	// Buffer is the queue of each subscription. Defaults to DefaultBuffer
+	Buffer int
+}
+
+// Bus fans published events out to its subscriptions. Each sink is sent its
+// events in order from its own goroutine, so a slow webhook doesn't hold up
+// the publisher or the other sinks
+type Bus struct {
+	buffer int
+	logger logger.Logger
+
+	mu     sync.RWMutex
+	subs   map[*subscription]struct{}
+	closed bool
+	wg     sync.WaitGroup
+}
+
+type subscription struct {
+	types map[Type]bool
+	queue chan Event
+}
+
+// NewBus creates a Bus without subscriptions
+func NewBus(opts BusOptions) *Bus {
+	if opts.Buffer <= 0 {
+		opts.Buffer = DefaultBuffer
+	}
+	return &Bus{buffer: opts.Buffer, subs: make(map[*subscription]struct{})}
+}
+
+// SetLogger sets where dropped events and failed sends are reported. nil
+// restores logger.Default
+func (b *Bus) SetLogger(l logger.Logger) {
+	b.logger = l
+}
+
+// Subscribe sends the events of types, or of every type when none are given,
+// to sink until the returned function is called or the bus is closed
+func (b *Bus) Subscribe(sink Sink, types ...Type) (unsubscribe func()) {
+	sub, unsubscribe := b.subscribe(b.buffer, types)
+	if sub == nil {
+		return unsubscribe
+	}
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		for event := range sub.queue {
+			if err := sink.Send(context.Background(), event); err != nil {
+				logger.Or(b.logger).Warn("failed to send event", "type", event.Type, "err", err)
+			}
+		}
+	}()
+	return unsubscribe
+}
+
+// Channel returns a channel receiving the events of types, or of every type
+// when none are given. It is closed by the returned function or by Close.
+// Events are dropped while it holds buffer events
+func (b *Bus) Channel(buffer int, types ...Type) (<-chan Event, func()) {
+	if buffer <= 0 {
+		buffer = b.buffer
+	}
+	sub, unsubscribe := b.subscribe(buffer, types)
+	if sub == nil {
+		ch := make(chan Event)
+		close(ch)
+		return ch, unsubscribe
+	}
+	return sub.queue, unsubscribe
+}
+
+// subscribe registers a subscription, or returns nil once the bus is closed
+func (b *Bus) subscribe(buffer int, types []Type) (*subscription, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, func() {}
+	}
+	sub := &subscription{queue: make(chan Event, buffer)}
+	if len(types) > 0 {
+		sub.types = make(map[Type]bool, len(types))
+		for _, t := range types {
+			sub.types[t] = true
+		}
+	}
+	b.subs[sub] = struct{}{}
+	var once sync.Once
+	return sub, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if _, ok := b.subs[sub]; ok {
+				delete(b.subs, sub)
+				close(sub.queue)
+			}
+		})
+	}
+}
+
+// Publish queues event for the subscriptions of its type, stamping Time when
+// it is zero. It never blocks: subscriptions whose queue is full miss it
+func (b *Bus) Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for sub := range b.subs {
+		if sub.types != nil && !sub.types[event.Type] {
+			continue
+		}
+		select {
+		case sub.queue <- event:
+		default:
+			logger.Or(b.logger).Warn("dropping event, subscriber queue full", "type", event.Type)
+		}
+	}
+}
+
+// Close ends every subscription and waits for the sinks to be sent the events
+// already queued. Later publishes are discarded
+func (b *Bus) Close() {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		for sub := range b.subs {
+			delete(b.subs, sub)
+			close(sub.queue)
+		}
+	}
+	b.mu.Unlock()
+	b.wg.Wait()
+}
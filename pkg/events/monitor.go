@@ -0,0 +1,189 @@
+package events
+
+import (
+	"context"
+	"errors"
+	stdmath "math"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/logger"
+	"github.com/yimingWOW/solroute/pkg/price"
+	"github.com/yimingWOW/solroute/pkg/router"
+	"github.com/yimingWOW/solroute/pkg/sol"
+)
+
+// PublishExecution publishes the outcome of router.ExecuteRoute: TypeTxDropped
+// when the transaction expired before landing, TypeRouteExecuted otherwise.
+// Nothing is published when the route failed before a transaction was sent
+// or simulated
+func (b *Bus) PublishExecution(executed *router.Executed, err error) {
+	if executed == nil || executed.Tx == nil {
+		return
+	}
+	quote, tx := executed.Quote, executed.Tx
+	if errors.Is(err, sol.ErrTransactionExpired) {
+		b.Publish(Event{Type: TypeTxDropped, Data: TxDropped{
+			Pool:       quote.Pool.GetID(),
+			InputMint:  quote.InputMint,
+			OutputMint: quote.OutputMint,
+			AmountIn:   quote.AmountIn,
+			Signature:  tx.Signature.String(),
+			Err:        err.Error(),
+		}})
+		return
+	}
+	data := RouteExecuted{
+		Pool:         quote.Pool.GetID(),
+		Protocol:     quote.Pool.ProtocolName(),
+		InputMint:    quote.InputMint,
+		OutputMint:   quote.OutputMint,
+		AmountIn:     quote.AmountIn,
+		AmountOut:    quote.AmountOut,
+		MinAmountOut: executed.MinAmountOut,
+		Slot:         tx.Slot,
+		Fee:          tx.Fee,
+		DryRun:       executed.DryRun,
+	}
+	if !tx.Signature.IsZero() {
+		data.Signature = tx.Signature.String()
+	}
+	if err != nil {
+		data.Err = err.Error()
+	}
+	b.Publish(Event{Type: TypeRouteExecuted, Data: data})
+}
+
+// CheckQuote publishes TypeQuoteDeviation when the USD value of the quote's
+// output differs from its input by more than thresholdBps either way, e.g.
+// on a drained pool or a stale oracle. The quote must come from a router
+// with a price oracle, see SimpleRouter.SetPriceOracle; quotes without both
+// values are skipped. It reports whether an event was published
+func (b *Bus) CheckQuote(quote *router.RouteQuote, thresholdBps int64) bool {
+	if quote == nil || quote.AmountInUSD <= 0 || quote.AmountOutUSD <= 0 {
+		return false
+	}
+	deviation := int64(stdmath.Round((quote.AmountOutUSD - quote.AmountInUSD) / quote.AmountInUSD * 10000))
+	if deviation <= thresholdBps && -deviation <= thresholdBps {
+		return false
+	}
+	b.Publish(Event{Type: TypeQuoteDeviation, Data: QuoteDeviation{
+		Pool:         quote.Pool.GetID(),
+		Protocol:     quote.Pool.ProtocolName(),
+		InputMint:    quote.InputMint,
+		OutputMint:   quote.OutputMint,
+		AmountIn:     quote.AmountIn,
+		AmountOut:    quote.AmountOut,
+		AmountInUSD:  quote.AmountInUSD,
+		AmountOutUSD: quote.AmountOutUSD,
+		DeviationBps: deviation,
+	}})
+	return true
+}
+
+// Liquidity monitor defaults
+const (
+	DefaultLiquidityInterval     = 30 * time.Second
+	DefaultLiquidityThresholdBps = 1000
+)
+
+// LiquidityMonitorOptions configures NewLiquidityMonitor. Zero fields keep the defaults
+type LiquidityMonitorOptions struct {
+	// Pools are valued every Interval; they must implement pkg.VaultReporter
+	Pools []pkg.Pool
+	// Interval defaults to DefaultLiquidityInterval
+	Interval time.Duration
+	// ThresholdBps is the move from the last reported liquidity of a pool
+	// that publishes TypeLiquidityChanged. Defaults to DefaultLiquidityThresholdBps
+	ThresholdBps int64
+}
+
+// LiquidityMonitor values pools with a price oracle and publishes the ones
+// whose liquidity moved beyond the threshold
+type LiquidityMonitor struct {
+	bus    *Bus
+	oracle *price.Oracle
+	client *rpc.Client
+	opts   LiquidityMonitorOptions
+	logger logger.Logger
+
+	mu       sync.Mutex
+	baseline map[string]float64
+}
+
+// NewLiquidityMonitor creates a LiquidityMonitor publishing to bus
+func NewLiquidityMonitor(bus *Bus, oracle *price.Oracle, client *rpc.Client, opts LiquidityMonitorOptions) *LiquidityMonitor {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultLiquidityInterval
+	}
+	if opts.ThresholdBps <= 0 {
+		opts.ThresholdBps = DefaultLiquidityThresholdBps
+	}
+	return &LiquidityMonitor{
+		bus:      bus,
+		oracle:   oracle,
+		client:   client,
+		opts:     opts,
+		baseline: make(map[string]float64),
+	}
+}
+
+// SetLogger sets where pools that fail to be valued are reported. nil
+// restores logger.Default
+func (m *LiquidityMonitor) SetLogger(l logger.Logger) {
+	m.logger = l
+}
+
+// Run checks the pools every interval until ctx is done
+func (m *LiquidityMonitor) Run(ctx context.Context) error {
+	ticker := time.NewTicker(m.opts.Interval)
+	defer ticker.Stop()
+	for {
+		m.Check(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check values every pool once. The first value of a pool is its baseline;
+// after an event the baseline moves to the reported value
+func (m *LiquidityMonitor) Check(ctx context.Context) {
+	for _, pool := range m.opts.Pools {
+		if ctx.Err() != nil {
+			return
+		}
+		usd, err := m.oracle.Liquidity(ctx, m.client, pool)
+		if err != nil {
+			logger.Or(m.logger).Debug("failed to value pool liquidity", "pool", pool.GetID(), "err", err)
+			continue
+		}
+		m.observe(pool, usd)
+	}
+}
+
+func (m *LiquidityMonitor) observe(pool pkg.Pool, usd float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	previous, ok := m.baseline[pool.GetID()]
+	if !ok || previous <= 0 {
+		m.baseline[pool.GetID()] = usd
+		return
+	}
+	change := int64(stdmath.Round((usd - previous) / previous * 10000))
+	if change < m.opts.ThresholdBps && -change < m.opts.ThresholdBps {
+		return
+	}
+	m.baseline[pool.GetID()] = usd
+	m.bus.Publish(Event{Type: TypeLiquidityChanged, Data: LiquidityChanged{
+		Pool:        pool.GetID(),
+		Protocol:    pool.ProtocolName(),
+		PreviousUSD: previous,
+		USD:         usd,
+		ChangeBps:   change,
+	}})
+}
@@ -0,0 +1,122 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Webhook defaults
+const (
+	DefaultWebhookTimeout    = 10 * time.Second
+	DefaultWebhookRetryDelay = time.Second
+)
+
+// SignatureHeader carries the hex HMAC-SHA256 of the body when
+// WebhookOptions.Secret is set
+const SignatureHeader = "X-Solroute-Signature"
+
+// WebhookOptions configures NewWebhook. Zero fields keep the defaults
+type WebhookOptions struct {
+	// Headers are set on every request, e.g. an Authorization token
+	Headers map[string]string
+	// Secret, when set, signs each body in SignatureHeader so the receiver can
+	// check the event came from this process
+	Secret string
+	// Timeout bounds each attempt. Defaults to DefaultWebhookTimeout
+	Timeout time.Duration
+	// Retries after a failed attempt: transport errors, 429 and 5xx responses.
+	// Zero sends once
+	Retries int
+	// RetryDelay is the wait before the first retry, doubling after each one.
+	// Defaults to DefaultWebhookRetryDelay
+	RetryDelay time.Duration
+	// HTTPClient defaults to http.DefaultClient
+	HTTPClient *http.Client
+}
+
+// Webhook is a Sink POSTing each event as JSON to a URL, e.g. a Slack, PagerDuty
+// or alert manager endpoint
+type Webhook struct {
+	url  string
+	opts WebhookOptions
+}
+
+// NewWebhook creates a Webhook posting to url
+func NewWebhook(url string, opts WebhookOptions) *Webhook {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultWebhookTimeout
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = DefaultWebhookRetryDelay
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+	return &Webhook{url: url, opts: opts}
+}
+
+// Send posts event, retrying failed attempts as configured
+func (w *Webhook) Send(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", event.Type, err)
+	}
+	delay := w.opts.RetryDelay
+	for attempt := 0; ; attempt++ {
+		retry, err := w.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= w.opts.Retries {
+			return err
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+// post makes one attempt, reporting whether a failure is worth retrying
+func (w *Webhook) post(ctx context.Context, body []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, w.opts.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range w.opts.Headers {
+		req.Header.Set(key, value)
+	}
+	if w.opts.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(w.opts.Secret))
+		mac.Write(body)
+		req.Header.Set(SignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := w.opts.HTTPClient.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("failed to post event: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return false, nil
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError
+	return retry, fmt.Errorf("webhook returned %s: %s", resp.Status, bytes.TrimSpace(data))
+}
@@ -0,0 +1,218 @@
+// Package geyser streams account updates and transactions from a Yellowstone
+// gRPC (Geyser plugin) endpoint. It is an alternative to WebSocket subscriptions
+// for users running their own infrastructure who need fresher pool state
+package geyser
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	pb "github.com/rpcpool/yellowstone-grpc/examples/golang/proto"
+	"github.com/yimingWOW/solroute/pkg/sol"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+)
+
+// maxMessageSize allows account updates up to the 10MB Solana account limit
+const maxMessageSize = 64 << 20
+
+// Client is a Yellowstone gRPC client
+type Client struct {
+	conn   *grpc.ClientConn
+	geyser pb.GeyserClient
+	token  string
+}
+
+// NewClient connects to endpoint, given as http(s)://host[:port]. Plain http
+// disables TLS. token is sent as the x-token header when non-empty
+func NewClient(endpoint, token string) (*Client, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint: %w", err)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid endpoint %q: expected http(s)://<host>:<port>", endpoint)
+	}
+	plaintext := u.Scheme == "http"
+	port := u.Port()
+	if port == "" {
+		port = "443"
+		if plaintext {
+			port = "80"
+		}
+	}
+
+	opts := []grpc.DialOption{
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                10 * time.Second,
+			Timeout:             time.Second,
+			PermitWithoutStream: true,
+		}),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(maxMessageSize)),
+	}
+	if plaintext {
+		opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	} else {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load system cert pool: %w", err)
+		}
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewClientTLSFromCert(pool, "")))
+	}
+
+	conn, err := grpc.NewClient(u.Hostname()+":"+port, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC client: %w", err)
+	}
+	return &Client{
+		conn:   conn,
+		geyser: pb.NewGeyserClient(conn),
+		token:  token,
+	}, nil
+}
+
+// Close closes the gRPC connection
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// SubscribeAccounts streams processed updates of pubkeys, implementing
+// sol.AccountSource so it can feed a watcher.PoolWatcher
+func (c *Client) SubscribeAccounts(ctx context.Context, pubkeys []solana.PublicKey, handler sol.AccountHandler) (sol.Subscription, error) {
+	accounts := make([]string, len(pubkeys))
+	for i, pubkey := range pubkeys {
+		accounts[i] = pubkey.String()
+	}
+	req := &pb.SubscribeRequest{
+		Accounts: map[string]*pb.SubscribeRequestFilterAccounts{
+			"accounts": {Account: accounts},
+		},
+		Commitment: pb.CommitmentLevel_PROCESSED.Enum(),
+	}
+	return c.subscribe(ctx, req, func(update *pb.SubscribeUpdate) {
+		account := update.GetAccount()
+		if account == nil || account.GetAccount() == nil {
+			return
+		}
+		info := account.GetAccount()
+		handler(solana.PublicKeyFromBytes(info.GetPubkey()), account.GetSlot(), info.GetData())
+	})
+}
+
+// Transaction is a transaction streamed by SubscribeTransactions
+type Transaction struct {
+	Slot        uint64
+	Signature   solana.Signature
+	Failed      bool
+	AccountKeys []solana.PublicKey // static keys, without address table lookups
+	Meta        *pb.TransactionStatusMeta
+}
+
+// TransactionHandler receives each streamed transaction
+type TransactionHandler func(tx *Transaction)
+
+// SubscribeTransactions streams processed, non-vote transactions mentioning any
+// of accounts, including failed ones
+func (c *Client) SubscribeTransactions(ctx context.Context, accounts []solana.PublicKey, handler TransactionHandler) (sol.Subscription, error) {
+	include := make([]string, len(accounts))
+	for i, account := range accounts {
+		include[i] = account.String()
+	}
+	vote, failed := false, true
+	req := &pb.SubscribeRequest{
+		Transactions: map[string]*pb.SubscribeRequestFilterTransactions{
+			"transactions": {
+				Vote:           &vote,
+				Failed:         &failed,
+				AccountInclude: include,
+			},
+		},
+		Commitment: pb.CommitmentLevel_PROCESSED.Enum(),
+	}
+	return c.subscribe(ctx, req, func(update *pb.SubscribeUpdate) {
+		txUpdate := update.GetTransaction()
+		if txUpdate == nil || txUpdate.GetTransaction() == nil {
+			return
+		}
+		info := txUpdate.GetTransaction()
+		tx := &Transaction{
+			Slot:      txUpdate.GetSlot(),
+			Signature: solana.SignatureFromBytes(info.GetSignature()),
+			Failed:    info.GetMeta().GetErr() != nil,
+			Meta:      info.GetMeta(),
+		}
+		for _, key := range info.GetTransaction().GetMessage().GetAccountKeys() {
+			tx.AccountKeys = append(tx.AccountKeys, solana.PublicKeyFromBytes(key))
+		}
+		handler(tx)
+	})
+}
+
+// subscribe opens a stream for req and passes every update except pings to onUpdate
+func (c *Client) subscribe(ctx context.Context, req *pb.SubscribeRequest, onUpdate func(*pb.SubscribeUpdate)) (sol.Subscription, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	if c.token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "x-token", c.token)
+	}
+	stream, err := c.geyser.Subscribe(ctx)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to open stream: %w", err)
+	}
+	if err := stream.Send(req); err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to send subscribe request: %w", err)
+	}
+
+	s := &subscription{
+		cancel: cancel,
+		errCh:  make(chan error, 1),
+		done:   make(chan struct{}),
+	}
+	go func() {
+		defer close(s.done)
+		for {
+			update, err := stream.Recv()
+			if err != nil {
+				if ctx.Err() == nil {
+					s.errCh <- fmt.Errorf("geyser stream: %w", err)
+				}
+				return
+			}
+			// Load balancers close idle streams unless pings are answered
+			if update.GetPing() != nil {
+				if err := stream.Send(&pb.SubscribeRequest{Ping: &pb.SubscribeRequestPing{Id: 1}}); err != nil && ctx.Err() == nil {
+					s.errCh <- fmt.Errorf("geyser ping: %w", err)
+					return
+				}
+				continue
+			}
+			onUpdate(update)
+		}
+	}()
+	return s, nil
+}
+
+type subscription struct {
+	cancel context.CancelFunc
+	once   sync.Once
+	errCh  chan error
+	done   chan struct{}
+}
+
+func (s *subscription) Err() <-chan error {
+	return s.errCh
+}
+
+func (s *subscription) Unsubscribe() {
+	s.once.Do(s.cancel)
+	<-s.done
+}
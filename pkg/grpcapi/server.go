@@ -0,0 +1,217 @@
+// Package grpcapi implements the solroute.v1.Router gRPC service of
+// api/solroute/v1 over a router.PairRouters:
+//
+//	grpcServer := grpc.NewServer()
+//	solroutev1.RegisterRouterServer(grpcServer, grpcapi.NewServer(client, routers))
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	solroutev1 "github.com/yimingWOW/solroute/api/solroute/v1"
+	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/router"
+	"github.com/yimingWOW/solroute/pkg/sol"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Defaults of unset request fields
+const (
+	DefaultSlippageBps    = 50
+	DefaultStreamInterval = time.Second
+	// MinStreamInterval bounds how often a stream requotes
+	MinStreamInterval = 100 * time.Millisecond
+)
+
+// Server implements solroutev1.RouterServer
+type Server struct {
+	solroutev1.UnimplementedRouterServer
+	client  *sol.Client
+	routers *router.PairRouters
+}
+
+var _ solroutev1.RouterServer = (*Server)(nil)
+
+// NewServer creates a Server quoting with routers and reading through client
+func NewServer(client *sol.Client, routers *router.PairRouters) *Server {
+	return &Server{client: client, routers: routers}
+}
+
+// swap is a validated QuoteRequest
+type swap struct {
+	inputMint, outputMint string
+	amount                math.Int
+	slippageBps           uint32
+}
+
+func parseQuoteRequest(req *solroutev1.QuoteRequest) (swap, error) {
+	if req == nil {
+		return swap{}, status.Error(codes.InvalidArgument, "missing quote request")
+	}
+	for _, mint := range []string{req.GetInputMint(), req.GetOutputMint()} {
+		if _, err := solana.PublicKeyFromBase58(mint); err != nil {
+			return swap{}, status.Errorf(codes.InvalidArgument, "invalid mint %q", mint)
+		}
+	}
+	amount, ok := math.NewIntFromString(req.GetAmount())
+	if !ok || !amount.IsPositive() {
+		return swap{}, status.Errorf(codes.InvalidArgument, "invalid amount %q", req.GetAmount())
+	}
+	slippageBps := req.GetSlippageBps()
+	if slippageBps == 0 {
+		slippageBps = DefaultSlippageBps
+	}
+	if slippageBps > 10000 {
+		return swap{}, status.Errorf(codes.InvalidArgument, "invalid slippage %d bps", slippageBps)
+	}
+	return swap{inputMint: req.GetInputMint(), outputMint: req.GetOutputMint(), amount: amount, slippageBps: slippageBps}, nil
+}
+
+func (s *Server) Quote(ctx context.Context, req *solroutev1.QuoteRequest) (*solroutev1.QuoteResponse, error) {
+	params, err := parseQuoteRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	return s.quote(ctx, params)
+}
+
+func (s *Server) quote(ctx context.Context, params swap) (*solroutev1.QuoteResponse, error) {
+	r, unlock := s.routers.Get(ctx, params.inputMint, params.outputMint)
+	defer unlock()
+	quote, err := r.QuoteRoute(ctx, s.client, params.inputMint, params.outputMint, params.amount, router.QuoteOptions{})
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	minOut := quote.AmountOut.MulRaw(int64(10000 - params.slippageBps)).QuoRaw(10000)
+	return &solroutev1.QuoteResponse{
+		InputMint:      quote.InputMint,
+		OutputMint:     quote.OutputMint,
+		InAmount:       quote.AmountIn.String(),
+		OutAmount:      quote.AmountOut.String(),
+		MinOutAmount:   minOut.String(),
+		SlippageBps:    params.slippageBps,
+		Pool:           quote.Pool.GetID(),
+		Protocol:       string(quote.Pool.ProtocolName()),
+		InUsd:          quote.AmountInUSD,
+		OutUsd:         quote.AmountOutUSD,
+		QuotedAtUnixMs: time.Now().UnixMilli(),
+	}, nil
+}
+
+func (s *Server) GetRoutes(ctx context.Context, req *solroutev1.QuoteRequest) (*solroutev1.GetRoutesResponse, error) {
+	params, err := parseQuoteRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	r, unlock := s.routers.Get(ctx, params.inputMint, params.outputMint)
+	defer unlock()
+	quotes := r.QuoteAllPools(ctx, s.client.RpcClient, params.inputMint, params.amount)
+	resp := &solroutev1.GetRoutesResponse{Routes: make([]*solroutev1.Route, 0, len(quotes))}
+	for _, quote := range quotes {
+		route := &solroutev1.Route{Pool: quote.Pool.GetID(), Protocol: string(quote.Pool.ProtocolName())}
+		if quote.Err != nil {
+			route.Error = quote.Err.Error()
+		} else {
+			route.OutAmount = quote.AmountOut.String()
+		}
+		resp.Routes = append(resp.Routes, route)
+	}
+	return resp, nil
+}
+
+func (s *Server) BuildSwap(ctx context.Context, req *solroutev1.BuildSwapRequest) (*solroutev1.BuildSwapResponse, error) {
+	user, err := solana.PublicKeyFromBase58(req.GetUser())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid user %q", req.GetUser())
+	}
+	amountIn, ok := math.NewIntFromString(req.GetInAmount())
+	if !ok || !amountIn.IsPositive() {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid in amount %q", req.GetInAmount())
+	}
+	minOut, ok := math.NewIntFromString(req.GetMinOutAmount())
+	if !ok || minOut.IsNegative() {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid min out amount %q", req.GetMinOutAmount())
+	}
+
+	r, unlock := s.routers.Get(ctx, req.GetInputMint(), req.GetOutputMint())
+	defer unlock()
+	pool, err := r.PoolByID(req.GetPool())
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	wrap := req.GetWrapAndUnwrapSol()
+	insts, err := router.BuildSwapInstructions(ctx, s.client.RpcClient, pool, user, req.GetInputMint(), amountIn, minOut, router.SwapOptions{
+		SwapBuildOptions: pkg.SwapBuildOptions{WrapInput: wrap, CloseInputWsol: wrap, UnwrapOutput: wrap},
+	})
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	resp := &solroutev1.BuildSwapResponse{Instructions: make([]*solroutev1.Instruction, 0, len(insts))}
+	for _, inst := range insts {
+		encoded, err := encodeInstruction(inst)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		resp.Instructions = append(resp.Instructions, encoded)
+	}
+	return resp, nil
+}
+
+func (s *Server) StreamQuotes(req *solroutev1.StreamQuotesRequest, stream solroutev1.Router_StreamQuotesServer) error {
+	params, err := parseQuoteRequest(req.GetQuote())
+	if err != nil {
+		return err
+	}
+	interval := time.Duration(req.GetIntervalMs()) * time.Millisecond
+	if interval == 0 {
+		interval = DefaultStreamInterval
+	}
+	interval = max(interval, MinStreamInterval)
+
+	ctx := stream.Context()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var last *solroutev1.QuoteResponse
+	for {
+		quote, err := s.quote(ctx, params)
+		switch {
+		case ctx.Err() != nil:
+			return status.FromContextError(ctx.Err()).Err()
+		case err != nil:
+			// A pair may stop quoting for a while, e.g. while its pools are
+			// rediscovered; the stream keeps going
+		case req.GetChangesOnly() && last != nil && last.OutAmount == quote.OutAmount && last.Pool == quote.Pool:
+		default:
+			if err := stream.Send(quote); err != nil {
+				return err
+			}
+			last = quote
+		}
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func encodeInstruction(inst solana.Instruction) (*solroutev1.Instruction, error) {
+	data, err := inst.Data()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode instruction: %w", err)
+	}
+	encoded := &solroutev1.Instruction{ProgramId: inst.ProgramID().String(), Data: data}
+	for _, account := range inst.Accounts() {
+		encoded.Accounts = append(encoded.Accounts, &solroutev1.AccountMeta{
+			Pubkey:     account.PublicKey.String(),
+			IsSigner:   account.IsSigner,
+			IsWritable: account.IsWritable,
+		})
+	}
+	return encoded, nil
+}
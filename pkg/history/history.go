@@ -0,0 +1,293 @@
+// Package history records executed routes in a local bbolt store: the quote a
+// swap was sent with, the amounts it realized as decoded by swapevent, its
+// fees, slot and signature. Trades are queried by time, mint, pool or
+// protocol, and summarized into a profit and loss report:
+//
+//	store, _ := history.Open("trades.db")
+//	defer store.Close()
+//	result, _ := client.SendTx(ctx, solana.Hash{}, signers, insts, true)
+//	store.RecordRoute(ctx, client.RpcClient, quote, result.Signature)
+//	trades, _ := store.Query(history.Filter{Since: time.Now().Add(-24 * time.Hour)})
+//	report := history.Summarize(trades)
+package history
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/yimingWOW/solroute/pkg/router"
+	"github.com/yimingWOW/solroute/pkg/swapevent"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	// tradesBucket maps time ordered keys, see tradeKey, to trades
+	tradesBucket = []byte("trades")
+	// signaturesBucket maps signatures to their trade keys
+	signaturesBucket = []byte("signatures")
+)
+
+// Trade is an executed route
+type Trade struct {
+	Signature  solana.Signature `json:"signature"`
+	Slot       uint64           `json:"slot"`
+	BlockTime  time.Time        `json:"blockTime"`
+	RecordedAt time.Time        `json:"recordedAt"`
+	Protocol   string           `json:"protocol"`
+	Pool       string           `json:"pool"`
+	InputMint  string           `json:"inputMint"`
+	OutputMint string           `json:"outputMint"`
+	// QuotedIn and QuotedOut are the amounts of the route's quote, AmountIn and
+	// AmountOut the ones the swap realized
+	QuotedIn  math.Int `json:"quotedIn"`
+	QuotedOut math.Int `json:"quotedOut"`
+	AmountIn  math.Int `json:"amountIn"`
+	AmountOut math.Int `json:"amountOut"`
+	// Fee and PriorityFee are the lamports the transaction paid
+	Fee         uint64 `json:"fee"`
+	PriorityFee uint64 `json:"priorityFee"`
+	// InputUSD and OutputUSD value the quote with the router's price oracle,
+	// zero without one
+	InputUSD  float64 `json:"inputUSD,omitempty"`
+	OutputUSD float64 `json:"outputUSD,omitempty"`
+	// Err is set when the transaction failed, which realizes no amounts
+	Err string `json:"err,omitempty"`
+}
+
+// Failed reports whether the trade's transaction failed
+func (t *Trade) Failed() bool {
+	return t.Err != ""
+}
+
+// SlippageBps returns how far the realized output fell short of the quote, in
+// basis points of the quote
+func (t *Trade) SlippageBps() int64 {
+	if t.Failed() || t.QuotedOut.IsNil() || !t.QuotedOut.IsPositive() || t.AmountOut.IsNil() {
+		return 0
+	}
+	return t.QuotedOut.Sub(t.AmountOut).MulRaw(10000).Quo(t.QuotedOut).Int64()
+}
+
+// NewTrade combines the quote a route was sent with and its execution. The
+// swap on the quoted pool is used, or the first decoded swap when the route
+// was rewritten, e.g. by an aggregator
+func NewTrade(quote *router.RouteQuote, exec *swapevent.Execution) *Trade {
+	trade := &Trade{
+		Signature:   exec.Signature,
+		Slot:        exec.Slot,
+		BlockTime:   exec.BlockTime,
+		RecordedAt:  time.Now(),
+		InputMint:   quote.InputMint,
+		OutputMint:  quote.OutputMint,
+		QuotedIn:    quote.AmountIn,
+		QuotedOut:   quote.AmountOut,
+		AmountIn:    math.ZeroInt(),
+		AmountOut:   math.ZeroInt(),
+		Fee:         exec.Fee,
+		PriorityFee: exec.PriorityFee,
+		InputUSD:    quote.AmountInUSD,
+		OutputUSD:   quote.AmountOutUSD,
+	}
+	if quote.Pool != nil {
+		trade.Protocol = string(quote.Pool.ProtocolName())
+		trade.Pool = quote.Pool.GetID()
+	}
+	if exec.Err != nil {
+		trade.Err = exec.Err.Error()
+		return trade
+	}
+	if swap, ok := matchSwap(trade.Pool, exec.Swaps); ok {
+		trade.AmountIn, trade.AmountOut = swap.AmountIn, swap.AmountOut
+		trade.Protocol = string(swap.Protocol)
+		trade.Pool = swap.Pool.String()
+	}
+	return trade
+}
+
+func matchSwap(pool string, swaps []swapevent.Swap) (swapevent.Swap, bool) {
+	for _, swap := range swaps {
+		if swap.Pool.String() == pool {
+			return swap, true
+		}
+	}
+	if len(swaps) > 0 {
+		return swaps[0], true
+	}
+	return swapevent.Swap{}, false
+}
+
+// Store is a persistent trade history
+type Store struct {
+	db *bolt.DB
+}
+
+// Open opens or creates the store at path
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open trade history %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{tradesBucket, signaturesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open trade history %s: %w", path, err)
+	}
+	return &Store{db: db}, nil
+}
+
+// Close closes the store
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// RecordRoute decodes the transaction of signature, which executed quote, and
+// records the trade
+func (s *Store) RecordRoute(ctx context.Context, client *rpc.Client, quote *router.RouteQuote, signature solana.Signature) (*Trade, error) {
+	exec, err := swapevent.Parse(ctx, client, signature)
+	if err != nil {
+		return nil, err
+	}
+	trade := NewTrade(quote, exec)
+	if err := s.Record(trade); err != nil {
+		return nil, err
+	}
+	return trade, nil
+}
+
+// Record stores trade, replacing a trade recorded with the same signature
+func (s *Store) Record(trade *Trade) error {
+	value, err := json.Marshal(trade)
+	if err != nil {
+		return fmt.Errorf("failed to encode trade %s: %w", trade.Signature.String(), err)
+	}
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		trades, signatures := tx.Bucket(tradesBucket), tx.Bucket(signaturesBucket)
+		if previous := signatures.Get(trade.Signature[:]); previous != nil {
+			if err := trades.Delete(previous); err != nil {
+				return err
+			}
+		}
+		key := tradeKey(trade)
+		if err := trades.Put(key, value); err != nil {
+			return err
+		}
+		return signatures.Put(trade.Signature[:], key)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record trade %s: %w", trade.Signature.String(), err)
+	}
+	return nil
+}
+
+// tradeKey orders trades by time: the block time, the recording time for
+// trades without one, then the signature
+func tradeKey(trade *Trade) []byte {
+	at := trade.BlockTime
+	if at.IsZero() {
+		at = trade.RecordedAt
+	}
+	key := binary.BigEndian.AppendUint64(nil, uint64(at.UnixNano()))
+	return append(key, trade.Signature[:]...)
+}
+
+// Filter selects trades. Zero fields match every trade
+type Filter struct {
+	// Since and Until bound the trade time, Until exclusive
+	Since time.Time
+	Until time.Time
+	// Mint matches trades with Mint as input or output
+	Mint     string
+	Pool     string
+	Protocol string
+	// IncludeFailed returns failed transactions too
+	IncludeFailed bool
+	// Limit caps the trades returned, the most recent ones are kept
+	Limit int
+}
+
+func (f Filter) match(trade *Trade) bool {
+	switch {
+	case f.Mint != "" && trade.InputMint != f.Mint && trade.OutputMint != f.Mint:
+		return false
+	case f.Pool != "" && trade.Pool != f.Pool:
+		return false
+	case f.Protocol != "" && trade.Protocol != f.Protocol:
+		return false
+	case !f.IncludeFailed && trade.Failed():
+		return false
+	}
+	return true
+}
+
+// Query returns the trades matched by filter, oldest first
+func (s *Store) Query(filter Filter) ([]*Trade, error) {
+	var trades []*Trade
+	err := s.db.View(func(tx *bolt.Tx) error {
+		cursor := tx.Bucket(tradesBucket).Cursor()
+		key, value := cursor.First()
+		if !filter.Since.IsZero() {
+			key, value = cursor.Seek(binary.BigEndian.AppendUint64(nil, uint64(filter.Since.UnixNano())))
+		}
+		var until []byte
+		if !filter.Until.IsZero() {
+			until = binary.BigEndian.AppendUint64(nil, uint64(filter.Until.UnixNano()))
+		}
+		for ; key != nil; key, value = cursor.Next() {
+			if until != nil && string(key[:8]) >= string(until) {
+				break
+			}
+			trade := new(Trade)
+			if err := json.Unmarshal(value, trade); err != nil {
+				return fmt.Errorf("failed to decode trade: %w", err)
+			}
+			if filter.match(trade) {
+				trades = append(trades, trade)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if filter.Limit > 0 && len(trades) > filter.Limit {
+		trades = trades[len(trades)-filter.Limit:]
+	}
+	return trades, nil
+}
+
+// Get returns the trade recorded for signature
+func (s *Store) Get(signature solana.Signature) (*Trade, error) {
+	var trade *Trade
+	err := s.db.View(func(tx *bolt.Tx) error {
+		key := tx.Bucket(signaturesBucket).Get(signature[:])
+		if key == nil {
+			return nil
+		}
+		trade = new(Trade)
+		return json.Unmarshal(tx.Bucket(tradesBucket).Get(key), trade)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trade %s: %w", signature.String(), err)
+	}
+	if trade == nil {
+		return nil, ErrNotFound
+	}
+	return trade, nil
+}
+
+// ErrNotFound is returned by Get for signatures without a recorded trade
+var ErrNotFound = errors.New("trade not found")
@@ -0,0 +1,63 @@
+package history
+
+import (
+	"cosmossdk.io/math"
+)
+
+// Report summarizes a set of trades
+type Report struct {
+	Trades int
+	Failed int
+	// Net is the realized change of each mint's balance: outputs received less
+	// inputs paid, in base units
+	Net map[string]math.Int
+	// Fees are the lamports paid by every transaction, failed ones included
+	Fees        uint64
+	PriorityFee uint64
+	// VolumeUSD sums the quoted input value of the successful trades, and
+	// PnLUSD the difference between their realized output and input values,
+	// each valued at the quote's prices. Trades without prices count as zero
+	VolumeUSD float64
+	PnLUSD    float64
+	// SlippageBps is the average shortfall of realized outputs against quotes
+	SlippageBps float64
+}
+
+// Summarize builds the report of trades
+func Summarize(trades []*Trade) Report {
+	report := Report{Net: make(map[string]math.Int)}
+	var slippage int64
+	for _, trade := range trades {
+		report.Fees += trade.Fee
+		report.PriorityFee += trade.PriorityFee
+		if trade.Failed() {
+			report.Failed++
+			continue
+		}
+		report.Trades++
+		report.Net[trade.InputMint] = add(report.Net[trade.InputMint], trade.AmountIn.Neg())
+		report.Net[trade.OutputMint] = add(report.Net[trade.OutputMint], trade.AmountOut)
+		slippage += trade.SlippageBps()
+
+		report.VolumeUSD += trade.InputUSD
+		if trade.InputUSD > 0 && trade.OutputUSD > 0 && trade.QuotedIn.IsPositive() && trade.QuotedOut.IsPositive() {
+			report.PnLUSD += scale(trade.OutputUSD, trade.AmountOut, trade.QuotedOut) - scale(trade.InputUSD, trade.AmountIn, trade.QuotedIn)
+		}
+	}
+	if report.Trades > 0 {
+		report.SlippageBps = float64(slippage) / float64(report.Trades)
+	}
+	return report
+}
+
+func add(total, amount math.Int) math.Int {
+	if total.IsNil() {
+		return amount
+	}
+	return total.Add(amount)
+}
+
+// scale values amount at the price of the quoted amount worth usd
+func scale(usd float64, amount, quoted math.Int) float64 {
+	return usd * math.LegacyNewDecFromInt(amount).Quo(math.LegacyNewDecFromInt(quoted)).MustFloat64()
+}
@@ -0,0 +1,338 @@
+// Package indexer keeps the pool accounts of the enabled protocols in a local
+// bbolt store, kept current by programSubscribe account notifications, and
+// serves pool discovery from memory.
+//
+// Install the Indexer as the client's discovery backend so QueryAllPools finds
+// pools without getProgramAccounts:
+//
+//	ix, _ := indexer.Open("pools.db")
+//	defer ix.Close()
+//	ix.Sync(ctx, client, indexer.DefaultPrograms()...)
+//	client.SetAccountDiscovery(ix)
+//
+// The store survives restarts: Open serves the last snapshot right away, and
+// Sync brings it up to date
+package indexer
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/ws"
+	"github.com/yimingWOW/solroute/pkg/pool/meteora"
+	"github.com/yimingWOW/solroute/pkg/pool/pump"
+	"github.com/yimingWOW/solroute/pkg/pool/raydium"
+	"github.com/yimingWOW/solroute/pkg/sol"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Program is a program whose accounts matching Filters are indexed. Filters
+// should select pool accounts broadly, e.g. by size only, since pair queries
+// are answered from the index
+type Program struct {
+	ID      solana.PublicKey
+	Filters []rpc.RPCFilter
+}
+
+// DefaultPrograms returns the pool accounts of the supported protocols
+func DefaultPrograms() []Program {
+	var (
+		amm  raydium.AMMPool
+		clmm raydium.CLMMPool
+		pool pump.PumpAMMPool
+	)
+	return []Program{
+		{ID: raydium.RAYDIUM_AMM_PROGRAM_ID, Filters: []rpc.RPCFilter{{DataSize: amm.Span()}}},
+		{ID: raydium.RAYDIUM_CLMM_PROGRAM_ID, Filters: []rpc.RPCFilter{{DataSize: uint64(clmm.Span())}}},
+		{ID: raydium.RAYDIUM_CPMM_PROGRAM_ID, Filters: []rpc.RPCFilter{{DataSize: 637}}},
+		{ID: meteora.MeteoraProgramID, Filters: []rpc.RPCFilter{{DataSize: meteora.LbPairAccountSize}}},
+		{ID: pump.PumpSwapProgramID, Filters: []rpc.RPCFilter{{DataSize: pool.Span()}}},
+	}
+}
+
+// Indexer is a persistent sol.AccountDiscovery
+type Indexer struct {
+	db    *bolt.DB
+	index *sol.LocalIndex
+
+	mu sync.Mutex
+	// dirty holds the accounts updated while a snapshot of their program runs,
+	// which the snapshot must not overwrite with older data
+	dirty map[solana.PublicKey]map[solana.PublicKey]bool
+}
+
+var _ sol.AccountDiscovery = (*Indexer)(nil)
+
+// Open opens or creates the store at path and loads the indexed accounts
+func Open(path string) (*Indexer, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open index %s: %w", path, err)
+	}
+	ix := &Indexer{
+		db:    db,
+		index: sol.NewLocalIndex(),
+		dirty: make(map[solana.PublicKey]map[solana.PublicKey]bool),
+	}
+	err = db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, bucket *bolt.Bucket) error {
+			if len(name) != solana.PublicKeyLength {
+				return nil
+			}
+			program := solana.PublicKeyFromBytes(name)
+			return bucket.ForEach(func(key, value []byte) error {
+				account, err := decodeAccount(value)
+				if err != nil {
+					return fmt.Errorf("account %s: %w", solana.PublicKeyFromBytes(key).String(), err)
+				}
+				ix.index.Put(program, solana.PublicKeyFromBytes(key), account)
+				return nil
+			})
+		})
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to load index %s: %w", path, err)
+	}
+	return ix, nil
+}
+
+// Close closes the store. Subscriptions started by Follow and Sync must be
+// stopped first
+func (ix *Indexer) Close() error {
+	return ix.db.Close()
+}
+
+// FindProgramAccounts answers from memory; programs never snapshotted fail, so
+// a sol.FallbackDiscovery moves on to the next backend
+func (ix *Indexer) FindProgramAccounts(ctx context.Context, program solana.PublicKey, filters []rpc.RPCFilter) (rpc.GetProgramAccountsResult, error) {
+	return ix.index.FindProgramAccounts(ctx, program, filters)
+}
+
+// Snapshot replaces the indexed accounts of program with the ones discovery
+// finds, e.g. sol.ProgramAccounts on the RPC node
+func (ix *Indexer) Snapshot(ctx context.Context, discovery sol.AccountDiscovery, program Program) error {
+	ix.mu.Lock()
+	ix.dirty[program.ID] = make(map[solana.PublicKey]bool)
+	ix.mu.Unlock()
+	defer func() {
+		ix.mu.Lock()
+		delete(ix.dirty, program.ID)
+		ix.mu.Unlock()
+	}()
+
+	result, err := discovery.FindProgramAccounts(ctx, program.ID, program.Filters)
+	if err != nil {
+		return fmt.Errorf("failed to snapshot program %s: %w", program.ID.String(), err)
+	}
+	fresh := make(map[solana.PublicKey]*rpc.Account, len(result))
+	for _, keyed := range result {
+		if keyed.Account != nil {
+			fresh[keyed.Pubkey] = keyed.Account
+		}
+	}
+	previous, _ := ix.index.FindProgramAccounts(ctx, program.ID, nil)
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	dirty := ix.dirty[program.ID]
+	err = ix.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(program.ID.Bytes())
+		if err != nil {
+			return err
+		}
+		for _, keyed := range previous {
+			if _, ok := fresh[keyed.Pubkey]; !ok && !dirty[keyed.Pubkey] {
+				if err := bucket.Delete(keyed.Pubkey.Bytes()); err != nil {
+					return err
+				}
+			}
+		}
+		for pubkey, account := range fresh {
+			if dirty[pubkey] {
+				continue
+			}
+			if err := bucket.Put(pubkey.Bytes(), encodeAccount(account)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store snapshot of %s: %w", program.ID.String(), err)
+	}
+
+	for _, keyed := range previous {
+		if _, ok := fresh[keyed.Pubkey]; !ok && !dirty[keyed.Pubkey] {
+			ix.index.Put(program.ID, keyed.Pubkey, nil)
+		}
+	}
+	for pubkey, account := range fresh {
+		if !dirty[pubkey] {
+			ix.index.Put(program.ID, pubkey, account)
+		}
+	}
+	return nil
+}
+
+// Follow applies programSubscribe notifications of the accounts of program
+// matching its filters to the index, until ctx is done, Unsubscribe is called
+// or the connection fails, in which case the error is delivered on Err
+func (ix *Indexer) Follow(ctx context.Context, client *sol.Client, program Program) (sol.Subscription, error) {
+	if client.WsClient == nil {
+		return nil, errors.New("client has no WebSocket connection")
+	}
+	sub, err := client.WsClient.ProgramSubscribeWithOpts(program.ID, rpc.CommitmentProcessed, solana.EncodingBase64, program.Filters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to program %s: %w", program.ID.String(), err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s := &subscription{cancel: cancel, errCh: make(chan error, 1), subs: []*ws.ProgramSubscription{sub}}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			result, err := sub.Recv(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					s.fail(fmt.Errorf("program %s subscription: %w", program.ID.String(), err))
+				}
+				return
+			}
+			if err := ix.apply(program.ID, result.Value.Pubkey, result.Value.Account); err != nil {
+				s.fail(err)
+				return
+			}
+		}
+	}()
+	return s, nil
+}
+
+// Sync follows each program, then snapshots it, so no update is missed
+// between the two. The returned subscription covers all programs
+func (ix *Indexer) Sync(ctx context.Context, client *sol.Client, programs ...Program) (sol.Subscription, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	all := &subscription{cancel: cancel, errCh: make(chan error, 1)}
+	discovery := sol.ProgramAccounts{Client: client.RpcClient}
+	for _, program := range programs {
+		sub, err := ix.Follow(ctx, client, program)
+		if err != nil {
+			all.Unsubscribe()
+			return nil, err
+		}
+		all.children = append(all.children, sub)
+		all.wg.Add(1)
+		go func() {
+			defer all.wg.Done()
+			select {
+			case err := <-sub.Err():
+				all.fail(err)
+			case <-ctx.Done():
+			}
+		}()
+		if err := ix.Snapshot(ctx, discovery, program); err != nil {
+			all.Unsubscribe()
+			return nil, err
+		}
+	}
+	return all, nil
+}
+
+// apply stores a notified account of program; accounts closed or reassigned
+// to another program are removed
+func (ix *Indexer) apply(program, pubkey solana.PublicKey, account *rpc.Account) error {
+	if account != nil && (!account.Owner.Equals(program) || account.Lamports == 0) {
+		account = nil
+	}
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if dirty, ok := ix.dirty[program]; ok {
+		dirty[pubkey] = true
+	}
+	err := ix.db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(program.Bytes())
+		if err != nil {
+			return err
+		}
+		if account == nil {
+			return bucket.Delete(pubkey.Bytes())
+		}
+		return bucket.Put(pubkey.Bytes(), encodeAccount(account))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store account %s: %w", pubkey.String(), err)
+	}
+	ix.index.Put(program, pubkey, account)
+	return nil
+}
+
+// encodeAccount stores an account as lamports, owner, executable and data
+func encodeAccount(account *rpc.Account) []byte {
+	data := account.Data.GetBinary()
+	buf := make([]byte, 0, 8+32+1+len(data))
+	buf = binary.LittleEndian.AppendUint64(buf, account.Lamports)
+	buf = append(buf, account.Owner.Bytes()...)
+	if account.Executable {
+		buf = append(buf, 1)
+	} else {
+		buf = append(buf, 0)
+	}
+	return append(buf, data...)
+}
+
+func decodeAccount(value []byte) (*rpc.Account, error) {
+	if len(value) < 8+32+1 {
+		return nil, errors.New("stored account too short")
+	}
+	// bbolt values are only valid during the transaction
+	data := append([]byte{}, value[41:]...)
+	return &rpc.Account{
+		Lamports:   binary.LittleEndian.Uint64(value[:8]),
+		Owner:      solana.PublicKeyFromBytes(value[8:40]),
+		Executable: value[40] == 1,
+		Data:       rpc.DataBytesOrJSONFromBytes(data),
+	}, nil
+}
+
+// subscription is a set of program subscriptions, or of child subscriptions
+// for Sync, failing together
+type subscription struct {
+	subs     []*ws.ProgramSubscription
+	children []sol.Subscription
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	once     sync.Once
+	errCh    chan error
+}
+
+func (s *subscription) Err() <-chan error {
+	return s.errCh
+}
+
+func (s *subscription) fail(err error) {
+	select {
+	case s.errCh <- err:
+	default:
+	}
+	go s.Unsubscribe()
+}
+
+func (s *subscription) Unsubscribe() {
+	s.once.Do(func() {
+		s.cancel()
+		for _, sub := range s.subs {
+			sub.Unsubscribe()
+		}
+		for _, child := range s.children {
+			child.Unsubscribe()
+		}
+	})
+	s.wg.Wait()
+}
@@ -0,0 +1,114 @@
+package keeper
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Phase is where a bot is in its lifecycle
+type Phase string
+
+const (
+	PhaseStarting Phase = "starting"
+	PhaseRunning  Phase = "running"
+	PhaseStopping Phase = "stopping"
+	PhaseStopped  Phase = "stopped"
+)
+
+// Health tracks a bot's liveness for the health endpoint. Bots call Beat on
+// every iteration of their loop and Fail when one goes wrong
+type Health struct {
+	maxAge  time.Duration
+	started time.Time
+
+	mu       sync.Mutex
+	phase    Phase
+	lastBeat time.Time
+	lastErr  string
+	errAt    time.Time
+}
+
+func newHealth(maxAge time.Duration) *Health {
+	return &Health{maxAge: maxAge, started: time.Now(), phase: PhaseStarting}
+}
+
+// Beat records that the bot made progress
+func (h *Health) Beat() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastBeat = time.Now()
+}
+
+// Fail records err as the bot's last error. It doesn't make the bot unhealthy
+// by itself; missing beats do
+func (h *Health) Fail(err error) {
+	if err == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.lastErr, h.errAt = err.Error(), time.Now()
+}
+
+func (h *Health) setPhase(phase Phase) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.phase = phase
+	if phase == PhaseRunning && h.lastBeat.IsZero() {
+		h.lastBeat = time.Now()
+	}
+}
+
+// HealthReport is the body of the health endpoints
+type HealthReport struct {
+	Phase     Phase     `json:"phase"`
+	Healthy   bool      `json:"healthy"`
+	Uptime    string    `json:"uptime"`
+	LastBeat  time.Time `json:"lastBeat,omitempty"`
+	LastError string    `json:"lastError,omitempty"`
+	ErrorAt   time.Time `json:"errorAt,omitempty"`
+}
+
+// Report returns the current health. The bot is healthy while running and,
+// when a maximum beat age is configured, beating within it
+func (h *Health) Report() HealthReport {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	healthy := h.phase == PhaseRunning
+	if healthy && h.maxAge > 0 && time.Since(h.lastBeat) > h.maxAge {
+		healthy = false
+	}
+	return HealthReport{
+		Phase:     h.phase,
+		Healthy:   healthy,
+		Uptime:    time.Since(h.started).Round(time.Second).String(),
+		LastBeat:  h.lastBeat,
+		LastError: h.lastErr,
+		ErrorAt:   h.errAt,
+	}
+}
+
+// Handler serves GET /healthz, failing with 503 while the bot isn't healthy,
+// and GET /readyz, failing until the bot runs
+func (h *Health) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		report := h.Report()
+		writeReport(w, report, report.Healthy)
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, r *http.Request) {
+		report := h.Report()
+		writeReport(w, report, report.Phase == PhaseRunning)
+	})
+	return mux
+}
+
+func writeReport(w http.ResponseWriter, report HealthReport, ok bool) {
+	w.Header().Set("Content-Type", "application/json")
+	if !ok {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(report)
+}
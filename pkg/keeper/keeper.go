@@ -0,0 +1,266 @@
+// Package keeper is a small runtime for swap bots: Run connects to the
+// deployment of a config, wires the router, pool subscriptions and
+// transaction confirmation, persists the bot's state, serves a health
+// endpoint and shuts the bot down gracefully on SIGINT and SIGTERM.
+//
+// A bot is a Bot, often built with Every:
+//
+//	keeper.Run(ctx, keeper.Every(time.Minute, func(ctx context.Context, env *keeper.Env) error {
+//		_, err := env.Swap(ctx, usdc, sol, amount, router.ExecuteOptions{})
+//		return err
+//	}), keeper.Options{Config: cfg, Signers: signers, StatePath: "bot.json", HealthAddr: ":8081"})
+package keeper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/yimingWOW/solroute/pkg/config"
+	"github.com/yimingWOW/solroute/pkg/logger"
+	"github.com/yimingWOW/solroute/pkg/router"
+	"github.com/yimingWOW/solroute/pkg/sol"
+	"github.com/yimingWOW/solroute/pkg/watcher"
+)
+
+// Runtime defaults
+const (
+	DefaultStateInterval   = 30 * time.Second
+	DefaultShutdownTimeout = 30 * time.Second
+	DefaultPoolTTL         = 5 * time.Minute
+)
+
+// ErrShutdownTimeout is returned by Run when the bot didn't return within the
+// shutdown timeout after being stopped
+var ErrShutdownTimeout = errors.New("bot did not stop within the shutdown timeout")
+
+// Bot is the logic Run drives. Run must return once ctx is done
+type Bot interface {
+	Run(ctx context.Context, env *Env) error
+}
+
+// BotFunc adapts a function to Bot
+type BotFunc func(ctx context.Context, env *Env) error
+
+func (f BotFunc) Run(ctx context.Context, env *Env) error {
+	return f(ctx, env)
+}
+
+// Every returns a Bot calling fn every interval, starting right away. A
+// successful call beats the health check; a failed one is logged and recorded
+// as the last error, and the bot carries on
+func Every(interval time.Duration, fn func(ctx context.Context, env *Env) error) Bot {
+	return BotFunc(func(ctx context.Context, env *Env) error {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := fn(ctx, env); err != nil && ctx.Err() == nil {
+				env.Health.Fail(err)
+				logger.Or(env.Logger).Warn("bot iteration failed", "err", err)
+			} else if err == nil {
+				env.Health.Beat()
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// Options configures Run. Zero fields keep the defaults
+type Options struct {
+	// Config is the deployment: endpoints, protocols and router defaults. Required
+	Config *config.Config
+	// Signers sign the swaps of Env.Swap; the first one is the trading wallet
+	Signers []sol.Signer
+	// StatePath persists Env.State as JSON there, loaded at start and saved
+	// every StateInterval and on shutdown. In memory only when empty
+	StatePath string
+	// StateInterval defaults to DefaultStateInterval
+	StateInterval time.Duration
+	// HealthAddr serves the health endpoints of Health.Handler, e.g. ":8081".
+	// Disabled when empty
+	HealthAddr string
+	// HealthMaxAge fails the health check when the bot hasn't beaten for this
+	// long. Zero only checks that it runs
+	HealthMaxAge time.Duration
+	// ShutdownTimeout bounds the wait for the bot to return once stopped.
+	// Defaults to DefaultShutdownTimeout
+	ShutdownTimeout time.Duration
+	// PoolTTL is how long the pools of a pair are reused before being
+	// rediscovered. Defaults to DefaultPoolTTL
+	PoolTTL time.Duration
+	// Logger receives the runtime's and the routers' logs. Defaults to logger.Default
+	Logger logger.Logger
+}
+
+// Env is what Run wires up for a bot
+type Env struct {
+	Client *sol.Client
+	// Routers load the pools of a pair on first use. With a WebSocket
+	// endpoint, pools handed to Watcher are quoted from their subscriptions
+	Routers *router.PairRouters
+	// Watcher is nil when the config has no WebSocket endpoint
+	Watcher *watcher.PoolWatcher
+	Signers []sol.Signer
+	State   *State
+	Health  *Health
+	Logger  logger.Logger
+}
+
+// Quote returns the best route for amountIn of inputMint to outputMint
+func (e *Env) Quote(ctx context.Context, inputMint, outputMint string, amountIn math.Int) (*router.RouteQuote, error) {
+	r, unlock := e.Routers.Get(ctx, inputMint, outputMint)
+	defer unlock()
+	return r.QuoteRoute(ctx, e.Client, inputMint, outputMint, amountIn, router.QuoteOptions{})
+}
+
+// Swap executes amountIn of inputMint to outputMint on the best pool with the
+// signers, and waits for the transaction to be confirmed
+func (e *Env) Swap(ctx context.Context, inputMint, outputMint string, amountIn math.Int, opts router.ExecuteOptions) (*router.Executed, error) {
+	if len(e.Signers) == 0 {
+		return nil, errors.New("no signer configured")
+	}
+	r, unlock := e.Routers.Get(ctx, inputMint, outputMint)
+	defer unlock()
+	return r.ExecuteRoute(ctx, e.Client, e.Signers, inputMint, outputMint, amountIn, opts)
+}
+
+// Watch subscribes to the pools of a pair, so they are quoted from live state
+// instead of being refreshed on every quote. It is a no-op without a Watcher
+func (e *Env) Watch(ctx context.Context, mintA, mintB string) error {
+	if e.Watcher == nil {
+		return nil
+	}
+	r, unlock := e.Routers.Get(ctx, mintA, mintB)
+	defer unlock()
+	var errs []error
+	for _, pool := range r.Pools() {
+		if err := e.Watcher.Watch(ctx, pool); err != nil {
+			errs = append(errs, fmt.Errorf("pool %s: %w", pool.GetID(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Run runs bot until it returns, ctx is done or the process receives SIGINT
+// or SIGTERM, then waits for it to return and saves its state. A bot stopped
+// this way returning its context's error counts as a clean exit
+func Run(ctx context.Context, bot Bot, opts Options) error {
+	if opts.Config == nil {
+		return errors.New("no config")
+	}
+	if err := opts.Config.Validate(); err != nil {
+		return err
+	}
+	if opts.StateInterval <= 0 {
+		opts.StateInterval = DefaultStateInterval
+	}
+	if opts.ShutdownTimeout <= 0 {
+		opts.ShutdownTimeout = DefaultShutdownTimeout
+	}
+	if opts.PoolTTL <= 0 {
+		opts.PoolTTL = DefaultPoolTTL
+	}
+	log := logger.Or(opts.Logger)
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	state, err := OpenState(opts.StatePath)
+	if err != nil {
+		return err
+	}
+	client, err := opts.Config.NewClient(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	env := &Env{
+		Client:  client,
+		Signers: opts.Signers,
+		State:   state,
+		Health:  newHealth(opts.HealthMaxAge),
+		Logger:  opts.Logger,
+	}
+	if client.WsClient != nil {
+		env.Watcher = watcher.NewPoolWatcher(client)
+		defer env.Watcher.Close()
+	}
+	env.Routers = router.PairRoutersFromConfig(client, opts.Config, opts.PoolTTL, func(r *router.SimpleRouter) {
+		r.SetLogger(opts.Logger)
+		if env.Watcher != nil {
+			r.SetWatcher(env.Watcher)
+		}
+	})
+
+	if opts.HealthAddr != "" {
+		server := &http.Server{Addr: opts.HealthAddr, Handler: env.Health.Handler(), ReadHeaderTimeout: 5 * time.Second}
+		go func() {
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Error("health endpoint failed", "addr", opts.HealthAddr, "err", err)
+			}
+		}()
+		defer func() {
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = server.Shutdown(shutdownCtx)
+		}()
+	}
+
+	saverDone := make(chan struct{})
+	saverCtx, stopSaver := context.WithCancel(context.Background())
+	go func() {
+		defer close(saverDone)
+		ticker := time.NewTicker(opts.StateInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-saverCtx.Done():
+				return
+			case <-ticker.C:
+				if err := state.Save(); err != nil {
+					log.Warn("failed to save bot state", "err", err)
+				}
+			}
+		}
+	}()
+
+	env.Health.setPhase(PhaseRunning)
+	log.Info("bot started")
+	done := make(chan error, 1)
+	go func() { done <- bot.Run(ctx, env) }()
+
+	var runErr error
+	select {
+	case runErr = <-done:
+	case <-ctx.Done():
+		env.Health.setPhase(PhaseStopping)
+		log.Info("stopping bot", "timeout", opts.ShutdownTimeout)
+		select {
+		case runErr = <-done:
+		case <-time.After(opts.ShutdownTimeout):
+			runErr = ErrShutdownTimeout
+		}
+	}
+	env.Health.setPhase(PhaseStopping)
+	stopSaver()
+	<-saverDone
+	saveErr := state.Save()
+	env.Health.setPhase(PhaseStopped)
+
+	if ctx.Err() != nil && (errors.Is(runErr, context.Canceled) || errors.Is(runErr, context.DeadlineExceeded)) {
+		runErr = nil
+	}
+	log.Info("bot stopped", "err", runErr)
+	return errors.Join(runErr, saveErr)
+}
@@ -0,0 +1,176 @@
+// Package ledger signs transactions with the Solana app of a Ledger hardware
+// wallet connected over USB HID, so private keys never leave the device
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/karalabe/hid"
+	"github.com/yimingWOW/solroute/pkg/sol"
+)
+
+// DefaultDerivationPath is the account path used by Ledger Live and most wallets
+const DefaultDerivationPath = "m/44'/501'/0'/0'"
+
+const (
+	ledgerVendorID  = 0x2c97
+	ledgerUsagePage = 0xffa0
+
+	solanaCLA       = 0xe0
+	insGetPubkey    = 0x05
+	insSignMessage  = 0x06
+	p1NonConfirm    = 0x00
+	p1Confirm       = 0x01
+	p2Extend        = 0x01
+	p2More          = 0x02
+	maxChunkPayload = 255
+
+	statusOK             = 0x9000
+	statusRejected       = 0x6985
+	statusINSUnsupported = 0x6d00
+	statusCLAUnsupported = 0x6e00
+
+	hardened = 0x80000000
+)
+
+var (
+	// ErrNoDevice is returned by Open when no Ledger is connected
+	ErrNoDevice = errors.New("no ledger device found")
+	// ErrRejected is returned when the user rejects the request on the device
+	ErrRejected = errors.New("request rejected on ledger")
+	// ErrAppNotOpen is returned when the Solana app is not open on the device
+	ErrAppNotOpen = errors.New("solana app not open on ledger")
+)
+
+func statusError(status uint16) error {
+	switch status {
+	case statusRejected:
+		return ErrRejected
+	case statusINSUnsupported, statusCLAUnsupported:
+		return ErrAppNotOpen
+	}
+	return fmt.Errorf("ledger returned status %#04x", status)
+}
+
+// Signer is a sol.Signer backed by a Ledger device
+type Signer struct {
+	mu     sync.Mutex
+	device hid.Device
+	path   []byte // serialized derivation path
+	pubkey solana.PublicKey
+}
+
+var _ sol.Signer = (*Signer)(nil)
+
+// Open connects to the first Ledger found and loads the account at path, such
+// as DefaultDerivationPath. The Solana app must be open on the device
+func Open(path string) (*Signer, error) {
+	indexes, err := ParseDerivationPath(path)
+	if err != nil {
+		return nil, err
+	}
+	if !hid.Supported() {
+		return nil, errors.New("usb hid is not supported on this platform or build (cgo disabled)")
+	}
+	devices, err := hid.Enumerate(ledgerVendorID, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enumerate usb devices: %w", err)
+	}
+	for _, info := range devices {
+		// The APDU interface is interface 0, or the vendor usage page where reported
+		if info.Interface != 0 && info.UsagePage != ledgerUsagePage {
+			continue
+		}
+		device, err := info.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open ledger: %w", err)
+		}
+		s := &Signer{device: device, path: serializePath(indexes)}
+		reply, err := exchange(device, solanaCLA, insGetPubkey, p1NonConfirm, 0, s.path)
+		if err != nil {
+			device.Close()
+			return nil, fmt.Errorf("failed to get public key: %w", err)
+		}
+		if len(reply) != solana.PublicKeyLength {
+			device.Close()
+			return nil, fmt.Errorf("unexpected public key length: %d", len(reply))
+		}
+		s.pubkey = solana.PublicKeyFromBytes(reply)
+		return s, nil
+	}
+	return nil, ErrNoDevice
+}
+
+// Pubkey returns the public key of the account at the derivation path
+func (s *Signer) Pubkey() solana.PublicKey {
+	return s.pubkey
+}
+
+// Sign asks the device to sign message, blocking until the user approves or
+// rejects it on screen
+func (s *Signer) Sign(message []byte) (solana.Signature, error) {
+	// One signer per request, followed by its path and the message
+	payload := make([]byte, 0, 1+len(s.path)+len(message))
+	payload = append(payload, 1)
+	payload = append(payload, s.path...)
+	payload = append(payload, message...)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var p2 byte
+	for len(payload) > maxChunkPayload {
+		if _, err := exchange(s.device, solanaCLA, insSignMessage, p1Confirm, p2|p2More, payload[:maxChunkPayload]); err != nil {
+			return solana.Signature{}, fmt.Errorf("ledger sign: %w", err)
+		}
+		payload = payload[maxChunkPayload:]
+		p2 |= p2Extend
+	}
+	reply, err := exchange(s.device, solanaCLA, insSignMessage, p1Confirm, p2, payload)
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("ledger sign: %w", err)
+	}
+	if len(reply) != len(solana.Signature{}) {
+		return solana.Signature{}, fmt.Errorf("unexpected signature length: %d", len(reply))
+	}
+	return solana.SignatureFromBytes(reply), nil
+}
+
+// Close releases the device
+func (s *Signer) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.device.Close()
+}
+
+// ParseDerivationPath parses a BIP-44 path such as "m/44'/501'/0'/0'". The
+// Solana app only derives hardened indexes
+func ParseDerivationPath(path string) ([]uint32, error) {
+	parts := strings.Split(strings.TrimPrefix(path, "m/"), "/")
+	if len(parts) == 0 || len(parts) > 5 {
+		return nil, fmt.Errorf("invalid derivation path %q", path)
+	}
+	indexes := make([]uint32, len(parts))
+	for i, part := range parts {
+		value, err := strconv.ParseUint(strings.TrimSuffix(part, "'"), 10, 31)
+		if err != nil || !strings.HasSuffix(part, "'") {
+			return nil, fmt.Errorf("invalid derivation path %q: index %q must be hardened", path, part)
+		}
+		indexes[i] = uint32(value) | hardened
+	}
+	return indexes, nil
+}
+
+// serializePath encodes indexes as the app expects: a count, then big endian indexes
+func serializePath(indexes []uint32) []byte {
+	buf := make([]byte, 1, 1+4*len(indexes))
+	buf[0] = byte(len(indexes))
+	for _, index := range indexes {
+		buf = append(buf, byte(index>>24), byte(index>>16), byte(index>>8), byte(index))
+	}
+	return buf
+}
@@ -0,0 +1,74 @@
+package ledger
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	hidPacketSize = 64
+	hidChannel    = 0x0101
+	hidTagAPDU    = 0x05
+)
+
+var errInvalidReply = errors.New("invalid reply from ledger")
+
+// exchange sends an APDU to the device over the Ledger HID framing and returns
+// the reply data, without the status word
+func exchange(device io.ReadWriter, cla, ins, p1, p2 byte, data []byte) ([]byte, error) {
+	if len(data) > 255 {
+		return nil, fmt.Errorf("apdu data too long: %d", len(data))
+	}
+	apdu := make([]byte, 2, 7+len(data))
+	binary.BigEndian.PutUint16(apdu, uint16(5+len(data)))
+	apdu = append(apdu, cla, ins, p1, p2, byte(len(data)))
+	apdu = append(apdu, data...)
+
+	// Each packet starts with the channel, the tag and a sequence number,
+	// the APDU length heads the first payload
+	packet := make([]byte, 0, hidPacketSize)
+	for seq := uint16(0); len(apdu) > 0; seq++ {
+		packet = binary.BigEndian.AppendUint16(packet[:0], hidChannel)
+		packet = append(packet, hidTagAPDU)
+		packet = binary.BigEndian.AppendUint16(packet, seq)
+		n := min(len(apdu), hidPacketSize-len(packet))
+		packet = append(packet, apdu[:n]...)
+		apdu = apdu[n:]
+		if _, err := device.Write(packet); err != nil {
+			return nil, fmt.Errorf("failed to write to ledger: %w", err)
+		}
+	}
+
+	var reply []byte
+	packet = packet[:hidPacketSize]
+	for seq := uint16(0); ; seq++ {
+		if _, err := io.ReadFull(device, packet); err != nil {
+			return nil, fmt.Errorf("failed to read from ledger: %w", err)
+		}
+		if binary.BigEndian.Uint16(packet[0:2]) != hidChannel || packet[2] != hidTagAPDU ||
+			binary.BigEndian.Uint16(packet[3:5]) != seq {
+			return nil, errInvalidReply
+		}
+		payload := packet[5:]
+		if seq == 0 {
+			reply = make([]byte, 0, binary.BigEndian.Uint16(payload[0:2]))
+			payload = payload[2:]
+		}
+		if left := cap(reply) - len(reply); left > len(payload) {
+			reply = append(reply, payload...)
+		} else {
+			reply = append(reply, payload[:left]...)
+			break
+		}
+	}
+
+	if len(reply) < 2 {
+		return nil, errInvalidReply
+	}
+	if status := binary.BigEndian.Uint16(reply[len(reply)-2:]); status != statusOK {
+		return nil, statusError(status)
+	}
+	return reply[:len(reply)-2], nil
+}
@@ -0,0 +1,278 @@
+// Package limitorder executes swaps conditionally: a Watcher quotes the pair
+// of each pending order at an interval and executes it once the price crosses
+// the order's threshold, until the order is cancelled or expires
+package limitorder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/yimingWOW/solroute/pkg/logger"
+	"github.com/yimingWOW/solroute/pkg/router"
+	"github.com/yimingWOW/solroute/pkg/sol"
+)
+
+// DefaultPollInterval is how often a Watcher quotes pending orders
+const DefaultPollInterval = time.Second
+
+// ErrUnknownOrder is returned by Cancel for orders that aren't pending
+var ErrUnknownOrder = errors.New("unknown order")
+
+// Condition is the side of the threshold that triggers an order
+type Condition int
+
+const (
+	// PriceAtOrAbove triggers once the quote pays at least the threshold, e.g.
+	// a take profit
+	PriceAtOrAbove Condition = iota
+	// PriceAtOrBelow triggers once the quote pays at most the threshold, e.g.
+	// a stop loss
+	PriceAtOrBelow
+)
+
+func (c Condition) String() string {
+	switch c {
+	case PriceAtOrAbove:
+		return "at or above"
+	case PriceAtOrBelow:
+		return "at or below"
+	}
+	return fmt.Sprintf("Condition(%d)", int(c))
+}
+
+// Status is the state of an order
+type Status int
+
+const (
+	StatusPending Status = iota
+	StatusExecuted
+	StatusFailed
+	StatusCancelled
+	StatusExpired
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusPending:
+		return "pending"
+	case StatusExecuted:
+		return "executed"
+	case StatusFailed:
+		return "failed"
+	case StatusCancelled:
+		return "cancelled"
+	case StatusExpired:
+		return "expired"
+	}
+	return fmt.Sprintf("Status(%d)", int(s))
+}
+
+// Order swaps AmountIn of InputMint once the quoted price, OutputMint per
+// InputMint in base units, is on the Condition side of Price
+type Order struct {
+	InputMint  string
+	OutputMint string
+	AmountIn   math.Int
+	Condition  Condition
+	Price      math.LegacyDec
+	// Expiry stops watching the order after this time. Zero never expires
+	Expiry time.Time
+	// Instructions, when set, are the pre-built route sent as is when the
+	// order triggers, or simulated when the router is in a dry run. Otherwise
+	// the route is built with ExecuteRoute
+	Instructions []solana.Instruction
+	// Execute configures ExecuteRoute. For PriceAtOrAbove orders its
+	// MinAmountOut is raised to the threshold so the swap can't fill below it
+	Execute router.ExecuteOptions
+}
+
+// Result is the final state of an order
+type Result struct {
+	ID     string
+	Order  Order
+	Status Status
+	// Quote is the quote that triggered the order
+	Quote *router.RouteQuote
+	// Tx is the sent transaction, when one was
+	Tx  *sol.TxResult
+	Err error
+}
+
+// WatcherOptions configures NewWatcher. Zero fields keep the defaults
+type WatcherOptions struct {
+	// PollInterval defaults to DefaultPollInterval
+	PollInterval time.Duration
+	// OnResult is called once per order when it leaves StatusPending
+	OnResult func(Result)
+}
+
+// Watcher watches and executes orders
+type Watcher struct {
+	router   *router.SimpleRouter
+	client   *sol.Client
+	signers  []sol.Signer
+	interval time.Duration
+	onResult func(Result)
+	logger   logger.Logger
+
+	mu     sync.Mutex
+	nextID int
+	orders map[string]Order
+}
+
+// NewWatcher creates a Watcher quoting with r, which must have loaded the
+// pools of the orders' pairs, and sending with client signed by signers
+func NewWatcher(r *router.SimpleRouter, client *sol.Client, opts WatcherOptions, signers ...sol.Signer) *Watcher {
+	interval := opts.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+	return &Watcher{
+		router:   r,
+		client:   client,
+		signers:  signers,
+		interval: interval,
+		onResult: opts.OnResult,
+		orders:   make(map[string]Order),
+	}
+}
+
+// SetLogger sets where failed quotes are reported. nil restores logger.Default
+func (w *Watcher) SetLogger(l logger.Logger) {
+	w.logger = l
+}
+
+// Submit adds order to the pending ones and returns its id
+func (w *Watcher) Submit(order Order) (string, error) {
+	if order.AmountIn.IsNil() || !order.AmountIn.IsPositive() {
+		return "", errors.New("order amount must be positive")
+	}
+	if order.Price.IsNil() || !order.Price.IsPositive() {
+		return "", errors.New("order price must be positive")
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.nextID++
+	id := strconv.Itoa(w.nextID)
+	w.orders[id] = order
+	return id, nil
+}
+
+// Cancel removes a pending order. An order already executing can't be cancelled
+func (w *Watcher) Cancel(id string) error {
+	w.mu.Lock()
+	order, ok := w.orders[id]
+	delete(w.orders, id)
+	w.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownOrder, id)
+	}
+	w.report(Result{ID: id, Order: order, Status: StatusCancelled})
+	return nil
+}
+
+// Pending returns the pending orders by id
+func (w *Watcher) Pending() map[string]Order {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	pending := make(map[string]Order, len(w.orders))
+	for id, order := range w.orders {
+		pending[id] = order
+	}
+	return pending
+}
+
+// Run checks the pending orders every poll interval until ctx is done
+func (w *Watcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		w.Check(ctx)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check expires, quotes and executes the pending orders once
+func (w *Watcher) Check(ctx context.Context) {
+	for id, order := range w.Pending() {
+		if ctx.Err() != nil {
+			return
+		}
+		if !order.Expiry.IsZero() && time.Now().After(order.Expiry) {
+			if w.take(id) {
+				w.report(Result{ID: id, Order: order, Status: StatusExpired})
+			}
+			continue
+		}
+		quote, err := w.router.QuoteRoute(ctx, w.client, order.InputMint, order.OutputMint, order.AmountIn, router.QuoteOptions{})
+		if err != nil {
+			logger.Or(w.logger).Warn("failed to quote limit order", "order", id, "err", err)
+			continue
+		}
+		if !triggered(order, quote.AmountOut) || !w.take(id) {
+			continue
+		}
+		w.report(w.execute(ctx, id, order, quote))
+	}
+}
+
+// take removes a pending order so exactly one caller executes, expires or
+// cancels it
+func (w *Watcher) take(id string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, ok := w.orders[id]
+	delete(w.orders, id)
+	return ok
+}
+
+// threshold is the output the order's price asks for AmountIn
+func threshold(order Order) math.Int {
+	return order.Price.MulInt(order.AmountIn).Ceil().TruncateInt()
+}
+
+func triggered(order Order, amountOut math.Int) bool {
+	if order.Condition == PriceAtOrBelow {
+		return amountOut.LTE(threshold(order))
+	}
+	return amountOut.GTE(threshold(order))
+}
+
+func (w *Watcher) execute(ctx context.Context, id string, order Order, quote *router.RouteQuote) Result {
+	result := Result{ID: id, Order: order, Status: StatusExecuted, Quote: quote}
+	if len(order.Instructions) > 0 {
+		result.Tx, result.Err = w.client.SendTx(ctx, solana.Hash{}, w.signers, order.Instructions, order.Execute.Simulate || w.router.DryRun())
+	} else {
+		opts := order.Execute
+		if order.Condition == PriceAtOrAbove {
+			if limit := threshold(order); opts.MinAmountOut.IsNil() || opts.MinAmountOut.LT(limit) {
+				opts.MinAmountOut = limit
+			}
+		}
+		var executed *router.Executed
+		executed, result.Err = w.router.ExecuteRoute(ctx, w.client, w.signers, order.InputMint, order.OutputMint, order.AmountIn, opts)
+		if executed != nil {
+			result.Quote, result.Tx = executed.Quote, executed.Tx
+		}
+	}
+	if result.Err != nil {
+		result.Status = StatusFailed
+	}
+	return result
+}
+
+func (w *Watcher) report(result Result) {
+	if w.onResult != nil {
+		w.onResult(result)
+	}
+}
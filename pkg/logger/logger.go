@@ -0,0 +1,101 @@
+// Package logger defines the leveled, structured Logger the router, protocols
+// and sol.Client report through, with adapters for log/slog and zap.
+//
+// Messages are short constant strings; the context goes into key-value pairs,
+// e.g. logger.Warn("failed to decode pool", "protocol", name, "pool", id, "err", err)
+package logger
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Logger is a leveled logger taking alternating key-value pairs after the message
+type Logger interface {
+	Debug(msg string, keysAndValues ...any)
+	Info(msg string, keysAndValues ...any)
+	Warn(msg string, keysAndValues ...any)
+	Error(msg string, keysAndValues ...any)
+}
+
+// Level orders log severities
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	}
+	return fmt.Sprintf("Level(%d)", int(l))
+}
+
+// Default is used by components that haven't been given a Logger. It writes
+// info and above to the standard library logger
+var Default Logger = Std(LevelInfo)
+
+// Or returns l, or Default when l is nil
+func Or(l Logger) Logger {
+	if l == nil {
+		return Default
+	}
+	return l
+}
+
+// Nop returns a Logger discarding everything
+func Nop() Logger {
+	return nop{}
+}
+
+type nop struct{}
+
+func (nop) Debug(string, ...any) {}
+func (nop) Info(string, ...any)  {}
+func (nop) Warn(string, ...any)  {}
+func (nop) Error(string, ...any) {}
+
+// Std returns a Logger writing entries at min and above to the standard
+// library logger as "LEVEL msg key=value ..."
+func Std(min Level) Logger {
+	return stdLogger{min: min}
+}
+
+type stdLogger struct {
+	min Level
+}
+
+func (s stdLogger) Debug(msg string, kv ...any) { s.log(LevelDebug, msg, kv) }
+func (s stdLogger) Info(msg string, kv ...any)  { s.log(LevelInfo, msg, kv) }
+func (s stdLogger) Warn(msg string, kv ...any)  { s.log(LevelWarn, msg, kv) }
+func (s stdLogger) Error(msg string, kv ...any) { s.log(LevelError, msg, kv) }
+
+func (s stdLogger) log(level Level, msg string, kv []any) {
+	if level < s.min {
+		return
+	}
+	var b strings.Builder
+	b.WriteString(level.String())
+	b.WriteByte(' ')
+	b.WriteString(msg)
+	for i := 0; i < len(kv); i += 2 {
+		if i+1 < len(kv) {
+			fmt.Fprintf(&b, " %v=%v", kv[i], kv[i+1])
+		} else {
+			fmt.Fprintf(&b, " %v", kv[i])
+		}
+	}
+	log.Print(b.String())
+}
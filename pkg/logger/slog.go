@@ -0,0 +1,28 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+// FromSlog adapts a *slog.Logger
+func FromSlog(l *slog.Logger) Logger {
+	return slogLogger{l}
+}
+
+type slogLogger struct {
+	l *slog.Logger
+}
+
+func (s slogLogger) Debug(msg string, kv ...any) {
+	s.l.Log(context.Background(), slog.LevelDebug, msg, kv...)
+}
+func (s slogLogger) Info(msg string, kv ...any) {
+	s.l.Log(context.Background(), slog.LevelInfo, msg, kv...)
+}
+func (s slogLogger) Warn(msg string, kv ...any) {
+	s.l.Log(context.Background(), slog.LevelWarn, msg, kv...)
+}
+func (s slogLogger) Error(msg string, kv ...any) {
+	s.l.Log(context.Background(), slog.LevelError, msg, kv...)
+}
@@ -0,0 +1,17 @@
+package logger
+
+import "go.uber.org/zap"
+
+// FromZap adapts a *zap.Logger, logging key-value pairs as loosely typed fields
+func FromZap(l *zap.Logger) Logger {
+	return zapLogger{l.Sugar()}
+}
+
+type zapLogger struct {
+	s *zap.SugaredLogger
+}
+
+func (z zapLogger) Debug(msg string, kv ...any) { z.s.Debugw(msg, kv...) }
+func (z zapLogger) Info(msg string, kv ...any)  { z.s.Infow(msg, kv...) }
+func (z zapLogger) Warn(msg string, kv ...any)  { z.s.Warnw(msg, kv...) }
+func (z zapLogger) Error(msg string, kv ...any) { z.s.Errorw(msg, kv...) }
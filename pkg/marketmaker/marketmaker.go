@@ -0,0 +1,177 @@
+// Package marketmaker derives two-sided quotes for a pair from the state of
+// its pools, for market makers quoting around the on-chain price and hedging
+// elsewhere, e.g. on a centralized exchange.
+//
+// Prices are in base units of the quote mint per base unit of the base mint;
+// UIPrice converts them to whole tokens
+package marketmaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	stdmath "math"
+	"math/big"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/router"
+	"github.com/yimingWOW/solroute/pkg/sol"
+)
+
+// DefaultSpreadBps is the width of a quote around the mid
+const DefaultSpreadBps = 20
+
+// Options configures NewQuoter. Zero fields keep the defaults
+type Options struct {
+	// Size is the base amount each side is quoted for, in base units; the pool
+	// prices are measured at it. Required
+	Size math.Int
+	// SpreadBps is the width between Bid and Ask, split evenly around the mid.
+	// Defaults to DefaultSpreadBps
+	SpreadBps int64
+	// SkewBps moves both sides up, or down when negative, e.g. to shed base
+	// inventory by quoting lower
+	SkewBps int64
+	// ProbeSize is the base amount the mid is measured at, small enough not to
+	// move the pools. Defaults to Size/1000, at least 1
+	ProbeSize math.Int
+}
+
+// Quote is a two-sided quote for Size of the base mint
+type Quote struct {
+	BaseMint  string
+	QuoteMint string
+	Size      math.Int
+	// Mid is the fee-free pool price: the geometric mean of the best pools'
+	// prices for ProbeSize in both directions, which cancels their swap fee
+	Mid float64
+	// Bid and Ask are Mid less and plus half the spread, moved by the skew
+	Bid float64
+	Ask float64
+	// PoolBid is the average price the best pool pays for Size sold into it,
+	// on BidPool, and PoolAsk the average price of buying about Size, on AskPool.
+	// A Bid below PoolBid, or an Ask above PoolAsk, can be hedged on chain at a profit
+	PoolBid float64
+	PoolAsk float64
+	BidPool pkg.Pool
+	AskPool pkg.Pool
+	At      time.Time
+}
+
+// Spread returns the width between Bid and Ask in bps of the mid
+func (q Quote) Spread() float64 {
+	return (q.Ask - q.Bid) / q.Mid * 10000
+}
+
+// UIPrice converts a price in base units to whole tokens of the pair's mints
+func UIPrice(price float64, baseDecimals, quoteDecimals uint8) float64 {
+	return price * stdmath.Pow10(int(baseDecimals)-int(quoteDecimals))
+}
+
+// Quoter quotes one pair from the pools a router loaded for it
+type Quoter struct {
+	router    *router.SimpleRouter
+	client    *sol.Client
+	baseMint  string
+	quoteMint string
+	opts      Options
+}
+
+// NewQuoter creates a Quoter for the pair, quoting on the pools r loaded with
+// QueryAllPools
+func NewQuoter(r *router.SimpleRouter, client *sol.Client, baseMint, quoteMint string, opts Options) (*Quoter, error) {
+	if opts.Size.IsNil() || !opts.Size.IsPositive() {
+		return nil, errors.New("quote size must be positive")
+	}
+	if opts.SpreadBps <= 0 {
+		opts.SpreadBps = DefaultSpreadBps
+	}
+	if opts.ProbeSize.IsNil() || !opts.ProbeSize.IsPositive() {
+		opts.ProbeSize = math.MaxInt(opts.Size.QuoRaw(1000), math.OneInt())
+	}
+	return &Quoter{
+		router:    r,
+		client:    client,
+		baseMint:  baseMint,
+		quoteMint: quoteMint,
+		opts:      opts,
+	}, nil
+}
+
+// Quote measures the pools and returns a fresh two-sided quote
+func (q *Quoter) Quote(ctx context.Context) (*Quote, error) {
+	probeBid, _, err := q.sellPrice(ctx, q.opts.ProbeSize)
+	if err != nil {
+		return nil, err
+	}
+	probeAsk, _, err := q.buyPrice(ctx, q.opts.ProbeSize, probeBid)
+	if err != nil {
+		return nil, err
+	}
+	mid := stdmath.Sqrt(probeBid * probeAsk)
+
+	quote := &Quote{
+		BaseMint:  q.baseMint,
+		QuoteMint: q.quoteMint,
+		Size:      q.opts.Size,
+		Mid:       mid,
+		At:        time.Now(),
+	}
+	if quote.PoolBid, quote.BidPool, err = q.sellPrice(ctx, q.opts.Size); err != nil {
+		return nil, err
+	}
+	if quote.PoolAsk, quote.AskPool, err = q.buyPrice(ctx, q.opts.Size, mid); err != nil {
+		return nil, err
+	}
+
+	center := mid * (1 + float64(q.opts.SkewBps)/10000)
+	half := float64(q.opts.SpreadBps) / 20000
+	quote.Bid = center * (1 - half)
+	quote.Ask = center * (1 + half)
+	return quote, nil
+}
+
+// sellPrice returns the average price of selling size of the base mint on the
+// best pool
+func (q *Quoter) sellPrice(ctx context.Context, size math.Int) (float64, pkg.Pool, error) {
+	out, pool, err := q.best(ctx, q.baseMint, size)
+	if err != nil {
+		return 0, nil, err
+	}
+	return ratio(out, size), pool, nil
+}
+
+// buyPrice returns the average price of buying about size of the base mint on
+// the best pool, spending size at price of the quote mint
+func (q *Quoter) buyPrice(ctx context.Context, size math.Int, price float64) (float64, pkg.Pool, error) {
+	spend, ok := math.NewIntFromString(fmt.Sprintf("%.0f", float64FromInt(size)*price))
+	if !ok || !spend.IsPositive() {
+		spend = math.OneInt()
+	}
+	out, pool, err := q.best(ctx, q.quoteMint, spend)
+	if err != nil {
+		return 0, nil, err
+	}
+	return ratio(spend, out), pool, nil
+}
+
+// best returns the most any pool gives for amountIn of inputMint
+func (q *Quoter) best(ctx context.Context, inputMint string, amountIn math.Int) (math.Int, pkg.Pool, error) {
+	// Quotes are sorted best first, failed ones last
+	quotes := q.router.QuoteAllPools(ctx, q.client.RpcClient, inputMint, amountIn)
+	if len(quotes) == 0 || quotes[0].Err != nil || !quotes[0].AmountOut.IsPositive() {
+		return math.Int{}, nil, fmt.Errorf("no pool quotes %s of %s", amountIn, inputMint)
+	}
+	return quotes[0].AmountOut, quotes[0].Pool, nil
+}
+
+func ratio(num, den math.Int) float64 {
+	return float64FromInt(num) / float64FromInt(den)
+}
+
+func float64FromInt(i math.Int) float64 {
+	f, _ := new(big.Float).SetInt(i.BigInt()).Float64()
+	return f
+}
@@ -0,0 +1,185 @@
+// Package metrics exports Prometheus metrics for sol.Client and the router: RPC
+// calls by method and result, quote latencies, route selections per protocol,
+// transaction outcomes and priority fees paid.
+//
+// The collectors are registered on the Metrics' own registry, which integrators
+// mount with Handler or gather alongside their own:
+//
+//	m := metrics.New(metrics.Options{})
+//	client.SetMetrics(m)
+//	router.SetMetrics(m)
+//	http.Handle("/metrics", m.Handler())
+//
+// All methods are safe to call on a nil *Metrics, which records nothing
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// DefaultNamespace prefixes the metric names unless Options.Namespace is set
+const DefaultNamespace = "solroute"
+
+// RPC call results
+const (
+	ResultOK          = "ok"
+	ResultError       = "error"
+	ResultRateLimited = "rate_limited"
+)
+
+// Transaction outcomes
+const (
+	OutcomeLanded  = "landed"
+	OutcomeFailed  = "failed"  // landed with an execution error
+	OutcomeDropped = "dropped" // expired before landing
+)
+
+// Options configures New. Zero fields keep the defaults
+type Options struct {
+	// Namespace prefixes the metric names. Defaults to DefaultNamespace
+	Namespace string
+	// Registry the collectors are registered on. Defaults to a new registry
+	// that also carries the Go runtime and process collectors
+	Registry *prometheus.Registry
+	// QuoteBuckets are the quote latency histogram buckets in seconds.
+	// Defaults to 1ms through ~4s
+	QuoteBuckets []float64
+	// PriorityFeeBuckets are the priority fee histogram buckets in lamports.
+	// Defaults to 1,000 through ~16M
+	PriorityFeeBuckets []float64
+}
+
+// Metrics holds the solroute collectors
+type Metrics struct {
+	registry *prometheus.Registry
+
+	rpcCalls        *prometheus.CounterVec
+	rpcDuration     *prometheus.HistogramVec
+	quoteDuration   *prometheus.HistogramVec
+	routeSelections *prometheus.CounterVec
+	transactions    *prometheus.CounterVec
+	priorityFees    prometheus.Histogram
+}
+
+// New creates the collectors and registers them
+func New(opts Options) *Metrics {
+	namespace := opts.Namespace
+	if namespace == "" {
+		namespace = DefaultNamespace
+	}
+	registry := opts.Registry
+	if registry == nil {
+		registry = prometheus.NewRegistry()
+		registry.MustRegister(
+			collectors.NewGoCollector(),
+			collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		)
+	}
+	quoteBuckets := opts.QuoteBuckets
+	if len(quoteBuckets) == 0 {
+		quoteBuckets = prometheus.ExponentialBuckets(0.001, 2, 13)
+	}
+	feeBuckets := opts.PriorityFeeBuckets
+	if len(feeBuckets) == 0 {
+		feeBuckets = prometheus.ExponentialBuckets(1000, 4, 13)
+	}
+
+	m := &Metrics{
+		registry: registry,
+		rpcCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rpc_calls_total",
+			Help:      "RPC calls by method and result.",
+		}, []string{"method", "result"}),
+		rpcDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "rpc_call_duration_seconds",
+			Help:      "RPC call latency by method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method"}),
+		quoteDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "quote_duration_seconds",
+			Help:      "Pool quote latency, refresh included, by protocol and result.",
+			Buckets:   quoteBuckets,
+		}, []string{"protocol", "result"}),
+		routeSelections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "route_selections_total",
+			Help:      "Best pools selected by the router, by protocol.",
+		}, []string{"protocol"}),
+		transactions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "transactions_total",
+			Help:      "Tracked transactions by outcome: landed, failed or dropped.",
+		}, []string{"outcome"}),
+		priorityFees: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "priority_fee_lamports",
+			Help:      "Priority fees paid by landed transactions, in lamports.",
+			Buckets:   feeBuckets,
+		}),
+	}
+	registry.MustRegister(m.rpcCalls, m.rpcDuration, m.quoteDuration, m.routeSelections, m.transactions, m.priorityFees)
+	return m
+}
+
+// Registry returns the registry the collectors are registered on
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler serves the registry in the Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// RPCCall records a call of method that took d and ended with result
+func (m *Metrics) RPCCall(method, result string, d time.Duration) {
+	if m == nil {
+		return
+	}
+	m.rpcCalls.WithLabelValues(method, result).Inc()
+	m.rpcDuration.WithLabelValues(method).Observe(d.Seconds())
+}
+
+// Quote records a pool quote of protocol that took d
+func (m *Metrics) Quote(protocol string, d time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	result := ResultOK
+	if err != nil {
+		result = ResultError
+	}
+	m.quoteDuration.WithLabelValues(protocol, result).Observe(d.Seconds())
+}
+
+// RouteSelected records the router picking a pool of protocol
+func (m *Metrics) RouteSelected(protocol string) {
+	if m == nil {
+		return
+	}
+	m.routeSelections.WithLabelValues(protocol).Inc()
+}
+
+// Transaction records the outcome of a tracked transaction
+func (m *Metrics) Transaction(outcome string) {
+	if m == nil {
+		return
+	}
+	m.transactions.WithLabelValues(outcome).Inc()
+}
+
+// PriorityFee records the priority fee a landed transaction paid
+func (m *Metrics) PriorityFee(lamports uint64) {
+	if m == nil {
+		return
+	}
+	m.priorityFees.Observe(float64(lamports))
+}
@@ -0,0 +1,47 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrMintNotInPool is returned for a swap of a mint the pool doesn't trade
+var ErrMintNotInPool = errors.New("mint not traded by pool")
+
+// Pair is the direction of a swap on a pool, so callers and pools don't need
+// to know whether a mint is the pool's token 0, X or base
+type Pair struct {
+	// In is sold for Out
+	In, Out string
+	// BaseIn is set when In is the first mint GetTokens returns, e.g. a
+	// zero-for-one swap on a CLMM pool or swap-for-Y on a DLMM pool
+	BaseIn bool
+}
+
+// NewPair returns the direction of a swap selling inputMint on a pool trading
+// baseMint against quoteMint, failing with ErrMintNotInPool for another mint
+func NewPair(baseMint, quoteMint, inputMint string) (Pair, error) {
+	switch inputMint {
+	case baseMint:
+		return Pair{In: baseMint, Out: quoteMint, BaseIn: true}, nil
+	case quoteMint:
+		return Pair{In: quoteMint, Out: baseMint}, nil
+	default:
+		return Pair{}, fmt.Errorf("%w: %s", ErrMintNotInPool, inputMint)
+	}
+}
+
+// PoolPair is NewPair for the mints of pool
+func PoolPair(pool Pool, inputMint string) (Pair, error) {
+	baseMint, quoteMint := pool.GetTokens()
+	pair, err := NewPair(baseMint, quoteMint, inputMint)
+	if err != nil {
+		return Pair{}, fmt.Errorf("pool %s: %w", pool.GetID(), err)
+	}
+	return pair, nil
+}
+
+// Reverse returns the swap selling Out for In
+func (p Pair) Reverse() Pair {
+	return Pair{In: p.Out, Out: p.In, BaseIn: !p.BaseIn}
+}
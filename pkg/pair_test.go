@@ -0,0 +1,63 @@
+package pkg_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/pool/raydium"
+)
+
+func TestNewPair(t *testing.T) {
+	tests := []struct {
+		name      string
+		inputMint string
+		want      pkg.Pair
+		wantErr   error
+	}{
+		{name: "base in", inputMint: "SOL", want: pkg.Pair{In: "SOL", Out: "USDC", BaseIn: true}},
+		{name: "quote in", inputMint: "USDC", want: pkg.Pair{In: "USDC", Out: "SOL"}},
+		{name: "other mint", inputMint: "BONK", wantErr: pkg.ErrMintNotInPool},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pkg.NewPair("SOL", "USDC", tt.inputMint)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			if tt.wantErr == nil && got.Reverse().Reverse() != got {
+				t.Fatalf("Reverse of %+v doesn't round-trip", got)
+			}
+		})
+	}
+}
+
+func TestPoolPair(t *testing.T) {
+	base, quote := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	pool := &raydium.CPMMPool{PoolId: solana.NewWallet().PublicKey(), Token0Mint: base, Token1Mint: quote}
+	tests := []struct {
+		name      string
+		inputMint solana.PublicKey
+		want      pkg.Pair
+		wantErr   error
+	}{
+		{name: "base in", inputMint: base, want: pkg.Pair{In: base.String(), Out: quote.String(), BaseIn: true}},
+		{name: "quote in", inputMint: quote, want: pkg.Pair{In: quote.String(), Out: base.String()}},
+		{name: "other mint", inputMint: solana.NewWallet().PublicKey(), wantErr: pkg.ErrMintNotInPool},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := pkg.PoolPair(pool, tt.inputMint.String())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
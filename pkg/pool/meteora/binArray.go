@@ -2,7 +2,6 @@ package meteora
 
 import (
 	"encoding/binary"
-	"errors"
 	"fmt"
 
 	"github.com/gagliardetto/solana-go"
@@ -73,8 +72,8 @@ func (binArray *BinArray) IsBinIDWithinRange(activeID int32) (bool, error) {
 
 // ParseBinArray deserializes binary data into a BinArray structure
 func ParseBinArray(data []byte) (BinArray, error) {
-	if len(data) < 16 {
-		return BinArray{}, errors.New("data too short")
+	if len(data) < BinArrayAccountSize {
+		return BinArray{}, fmt.Errorf("data too short: expected %d bytes, got %d", BinArrayAccountSize, len(data))
 	}
 
 	// Skip account discriminator (8 bytes)
@@ -181,3 +180,39 @@ func ParseBinArray(data []byte) (BinArray, error) {
 		bins:    bins,
 	}, nil
 }
+
+// MarshalBinary encodes the bin array in the account layout ParseBinArray
+// reads, with a zeroed discriminator
+func (binArray *BinArray) MarshalBinary() ([]byte, error) {
+	data := make([]byte, BinArrayAccountSize)
+	offset := 8
+	binary.LittleEndian.PutUint64(data[offset:], uint64(binArray.index))
+	offset += 8
+	data[offset] = binArray.version
+	offset++
+	copy(data[offset:], binArray.padding[:])
+	offset += 7
+	copy(data[offset:], binArray.LbPair[:])
+	offset += 32
+
+	putUint128 := func(v uint128.Uint128) {
+		binary.LittleEndian.PutUint64(data[offset:], v.Lo)
+		binary.LittleEndian.PutUint64(data[offset+8:], v.Hi)
+		offset += 16
+	}
+	for i := range binArray.bins {
+		bin := &binArray.bins[i]
+		binary.LittleEndian.PutUint64(data[offset:], bin.amountX)
+		binary.LittleEndian.PutUint64(data[offset+8:], bin.amountY)
+		offset += 16
+		putUint128(bin.price)
+		putUint128(bin.liquiditySupply)
+		putUint128(bin.rewardPerTokenStored[0])
+		putUint128(bin.rewardPerTokenStored[1])
+		putUint128(bin.feeAmountXPerTokenStored)
+		putUint128(bin.feeAmountYPerTokenStored)
+		putUint128(bin.amountXIn)
+		putUint128(bin.amountYIn)
+	}
+	return data, nil
+}
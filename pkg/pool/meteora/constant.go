@@ -18,6 +18,13 @@ const (
 	ExtensionBinArrayBitmapSize  = 12
 )
 
+// Account data sizes
+const (
+	LbPairAccountSize   = 904
+	BinSize             = 144
+	BinArrayAccountSize = 8 + 8 + 1 + 7 + 32 + MaxBinPerArray*BinSize
+)
+
 // Tick and bin ID range constants
 const (
 	MaxTick  = 443636
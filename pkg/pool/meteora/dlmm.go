@@ -2,10 +2,12 @@ package meteora
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"math/big"
 	"unsafe"
 
+	"cosmossdk.io/math"
 	"github.com/gagliardetto/solana-go"
 	"github.com/yimingWOW/solroute/pkg"
 	"github.com/yimingWOW/solroute/pkg/sol"
@@ -83,13 +85,15 @@ type MeteoraDlmmPool struct {
 
 	// Runtime fields (not part of on-chain data)
 	PoolId             solana.PublicKey
+	ProgramID          solana.PublicKey    // zero for MeteoraProgramID
 	BinArrays          map[string]BinArray // key: binArrayPubkey
 	BitmapExtensionKey solana.PublicKey
 	bitmapExtension    *BinArrayBitmapExtension
 	Clock              sol.Clock
 	orgActiveId        int32
-	UserBaseAccount    solana.PublicKey
-	UserQuoteAccount   solana.PublicKey
+	BaseAmount         math.Int // balance of the X reserve
+	QuoteAmount        math.Int // balance of the Y reserve
+	account            []byte   // the pair account last decoded, for MarshalBinary
 }
 
 func (pool *MeteoraDlmmPool) ProtocolName() pkg.ProtocolName {
@@ -101,9 +105,19 @@ func (pool *MeteoraDlmmPool) ProtocolType() pkg.ProtocolType {
 }
 
 func (pool *MeteoraDlmmPool) GetProgramID() solana.PublicKey {
+	if !pool.ProgramID.IsZero() {
+		return pool.ProgramID
+	}
 	return MeteoraProgramID
 }
 
+var _ pkg.PoolV2 = (*MeteoraDlmmPool)(nil)
+
+// Capabilities returns the optional features of the pool
+func (pool *MeteoraDlmmPool) Capabilities() pkg.Capabilities {
+	return pkg.CapTickArrays | pkg.CapAccountUpdates | pkg.CapVaults
+}
+
 // GetID returns the pool ID as a string
 func (pool *MeteoraDlmmPool) GetID() string {
 	return pool.PoolId.String()
@@ -114,6 +128,29 @@ func (pool *MeteoraDlmmPool) GetTokens() (string, string) {
 	return pool.TokenXMint.String(), pool.TokenYMint.String()
 }
 
+func (pool *MeteoraDlmmPool) Vaults() (baseVault, quoteVault solana.PublicKey) {
+	return pool.reserveX, pool.reserveY
+}
+
+// GetFeeRate returns the base fee plus the variable fee at the current
+// volatility, in basis points
+func (pool *MeteoraDlmmPool) GetFeeRate() (float64, error) {
+	fee, err := pool.GetTotalFee()
+	if err != nil {
+		return 0, err
+	}
+	bps, _ := new(big.Float).Quo(new(big.Float).SetInt(fee), big.NewFloat(FeePrecision/10000)).Float64()
+	return bps, nil
+}
+
+// GetLiquidity returns the balances of both reserves, across all bins
+func (pool *MeteoraDlmmPool) GetLiquidity() (pkg.Liquidity, error) {
+	if pool.BaseAmount.IsNil() || pool.QuoteAmount.IsNil() {
+		return pkg.Liquidity{}, pkg.ErrNotLoaded
+	}
+	return pkg.Liquidity{Base: pool.BaseAmount, Quote: pool.QuoteAmount}, nil
+}
+
 // Span returns the size of the pool struct in bytes
 func (pool *MeteoraDlmmPool) Span() uint64 {
 	return uint64(unsafe.Sizeof(*pool))
@@ -133,6 +170,12 @@ func (pool *MeteoraDlmmPool) Offset(field string) uint64 {
 
 // Decode deserializes binary data into the pool structure
 func (pool *MeteoraDlmmPool) Decode(data []byte) error {
+	if len(data) < LbPairAccountSize {
+		return fmt.Errorf("data too short: expected %d bytes, got %d", LbPairAccountSize, len(data))
+	}
+
+	pool.account = append([]byte(nil), data[:LbPairAccountSize]...)
+
 	// Manual parsing for first few fields
 	offset := 8 // Skip discriminator
 	pool.parameters.baseFactor = uint16(data[offset]) | uint16(data[offset+1])<<8
@@ -392,6 +435,158 @@ func (pool *MeteoraDlmmPool) UpdateClock(ctx context.Context, client *sol.Client
 
 // GetBinArrayForSwap retrieves bin arrays needed for swap operations
 func (pool *MeteoraDlmmPool) GetBinArrayForSwap(ctx context.Context, client *sol.Client) error {
+	return pool.loadBinArrays(ctx, client.RpcClient)
+}
+
+// Refresh re-reads the pair state, the clock and both reserves in a single
+// request, then reloads the bin arrays around the (possibly moved) active bin
+func (pool *MeteoraDlmmPool) Refresh(ctx context.Context, fetcher pkg.AccountFetcher) error {
+	accounts := []solana.PublicKey{pool.PoolId, solana.SysVarClockPubkey, pool.reserveX, pool.reserveY}
+	results, err := fetcher.GetMultipleAccounts(ctx, accounts...)
+	if err != nil {
+		return fmt.Errorf("batch request failed: %w", sol.ClassifyError(err))
+	}
+	if len(results.Value) != len(accounts) {
+		return fmt.Errorf("unexpected number of accounts: %d", len(results.Value))
+	}
+	for i, result := range results.Value {
+		if result == nil {
+			return fmt.Errorf("account %v: %w", accounts[i].String(), sol.ErrAccountNotFound)
+		}
+		if err := pool.ApplyAccount(accounts[i], result.Data.GetBinary()); err != nil {
+			return err
+		}
+	}
+
+	return pool.loadBinArrays(ctx, fetcher)
+}
+
+// WatchedAccounts returns the pair, the clock, both reserves and the bin
+// arrays around the active bin
+func (pool *MeteoraDlmmPool) WatchedAccounts() []solana.PublicKey {
+	accounts := []solana.PublicKey{pool.PoolId, solana.SysVarClockPubkey, pool.reserveX, pool.reserveY}
+	for _, swapForY := range []bool{true, false} {
+		binArrays, err := pool.GetBinArrayPubkeysForSwap(swapForY, 4)
+		if err != nil {
+			continue
+		}
+		accounts = append(accounts, binArrays...)
+	}
+	return accounts
+}
+
+// ApplyAccount updates the pool from new data of the pair, the clock, a
+// reserve or a bin array
+func (pool *MeteoraDlmmPool) ApplyAccount(pubkey solana.PublicKey, data []byte) error {
+	switch pubkey {
+	case pool.PoolId:
+		if err := pool.Decode(data); err != nil {
+			return fmt.Errorf("failed to decode pool data: %w", err)
+		}
+	case solana.SysVarClockPubkey:
+		clock, err := sol.ParseClock(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse clock: %w", err)
+		}
+		pool.Clock = *clock
+	case pool.reserveX:
+		amount, err := sol.ParseTokenAmount(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse reserve x: %w", err)
+		}
+		pool.BaseAmount = math.NewIntFromUint64(amount)
+	case pool.reserveY:
+		amount, err := sol.ParseTokenAmount(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse reserve y: %w", err)
+		}
+		pool.QuoteAmount = math.NewIntFromUint64(amount)
+	default:
+		binArray, err := ParseBinArray(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse bin array for account %s: %w", pubkey.String(), err)
+		}
+		if !binArray.LbPair.Equals(pool.PoolId) {
+			return fmt.Errorf("account %s is not watched by pool %s", pubkey.String(), pool.PoolId.String())
+		}
+		if pool.BinArrays == nil {
+			pool.BinArrays = make(map[string]BinArray)
+		}
+		pool.BinArrays[pubkey.String()] = binArray
+	}
+	return nil
+}
+
+// dlmmPoolSnapshot is the encoding of MeteoraDlmmPool. The pair and its bin
+// arrays are kept in their account layouts, which Decode and ParseBinArray
+// read back
+type dlmmPoolSnapshot struct {
+	Account            []byte                   `json:"account"`
+	PoolId             solana.PublicKey         `json:"poolId"`
+	ProgramID          solana.PublicKey         `json:"programId"`
+	BinArrays          map[string][]byte        `json:"binArrays"`
+	BitmapExtensionKey solana.PublicKey         `json:"bitmapExtensionKey"`
+	BitmapExtension    *BinArrayBitmapExtension `json:"bitmapExtension,omitempty"`
+	Clock              sol.Clock                `json:"clock"`
+	BaseAmount         math.Int                 `json:"baseAmount"`
+	QuoteAmount        math.Int                 `json:"quoteAmount"`
+}
+
+// MarshalBinary encodes the decoded pair, its bin arrays, the clock and the
+// reserve balances
+func (pool *MeteoraDlmmPool) MarshalBinary() ([]byte, error) {
+	if pool.account == nil {
+		return nil, fmt.Errorf("pool %s: %w", pool.PoolId.String(), pkg.ErrNotLoaded)
+	}
+	snapshot := dlmmPoolSnapshot{
+		Account:            pool.account,
+		PoolId:             pool.PoolId,
+		ProgramID:          pool.ProgramID,
+		BinArrays:          make(map[string][]byte, len(pool.BinArrays)),
+		BitmapExtensionKey: pool.BitmapExtensionKey,
+		BitmapExtension:    pool.bitmapExtension,
+		Clock:              pool.Clock,
+		BaseAmount:         pool.BaseAmount,
+		QuoteAmount:        pool.QuoteAmount,
+	}
+	for key, binArray := range pool.BinArrays {
+		data, err := binArray.MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode bin array %s: %w", key, err)
+		}
+		snapshot.BinArrays[key] = data
+	}
+	return json.Marshal(snapshot)
+}
+
+// UnmarshalBinary restores a pool encoded by MarshalBinary
+func (pool *MeteoraDlmmPool) UnmarshalBinary(data []byte) error {
+	var snapshot dlmmPoolSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("failed to decode pool snapshot: %w", err)
+	}
+	if err := pool.Decode(snapshot.Account); err != nil {
+		return fmt.Errorf("failed to decode pool data: %w", err)
+	}
+	pool.BinArrays = make(map[string]BinArray, len(snapshot.BinArrays))
+	for key, data := range snapshot.BinArrays {
+		binArray, err := ParseBinArray(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse bin array for account %s: %w", key, err)
+		}
+		pool.BinArrays[key] = binArray
+	}
+	pool.PoolId = snapshot.PoolId
+	pool.ProgramID = snapshot.ProgramID
+	pool.BitmapExtensionKey = snapshot.BitmapExtensionKey
+	pool.bitmapExtension = snapshot.BitmapExtension
+	pool.Clock = snapshot.Clock
+	pool.BaseAmount = snapshot.BaseAmount
+	pool.QuoteAmount = snapshot.QuoteAmount
+	return nil
+}
+
+func (pool *MeteoraDlmmPool) loadBinArrays(ctx context.Context, fetcher pkg.AccountFetcher) error {
 	if pool.BinArrays == nil {
 		pool.BinArrays = make(map[string]BinArray) // Initialize bin array map
 	}
@@ -412,9 +607,9 @@ func (pool *MeteoraDlmmPool) GetBinArrayForSwap(ctx context.Context, client *sol
 	activeBinArrayPubkeys = append(activeBinArrayPubkeys, negativeOrderActiveBinArrayPubkeys...)
 
 	// Fetch all bin array accounts in batch
-	results, err := client.RpcClient.GetMultipleAccounts(ctx, activeBinArrayPubkeys...)
+	results, err := fetcher.GetMultipleAccounts(ctx, activeBinArrayPubkeys...)
 	if err != nil {
-		return fmt.Errorf("batch request failed: %w", err)
+		return fmt.Errorf("batch request failed: %w", sol.ClassifyError(err))
 	}
 
 	// Parse and store bin arrays
@@ -0,0 +1,39 @@
+package meteora
+
+import (
+	"flag"
+	"strconv"
+	"testing"
+
+	"github.com/yimingWOW/solroute/pkg/sol/soltest"
+)
+
+var update = flag.Bool("update", false, "rewrite the decoded fields of the testdata fixtures")
+
+func TestLbPairFixture(t *testing.T) {
+	const path = "testdata/lb_pair.json"
+	var pool MeteoraDlmmPool
+	if err := pool.Decode(soltest.ReadFixture(t, path).Account); err != nil {
+		t.Fatal(err)
+	}
+	params := pool.parameters
+	soltest.CheckDecoded(t, path, map[string]string{
+		"BaseFactor":               strconv.Itoa(int(params.baseFactor)),
+		"FilterPeriod":             strconv.Itoa(int(params.filterPeriod)),
+		"DecayPeriod":              strconv.Itoa(int(params.decayPeriod)),
+		"ReductionFactor":          strconv.Itoa(int(params.reductionFactor)),
+		"VariableFeeControl":       strconv.Itoa(int(params.variableFeeControl)),
+		"MaxVolatilityAccumulator": strconv.Itoa(int(params.maxVolatilityAccumulator)),
+		"MinBinId":                 strconv.Itoa(int(params.minBinId)),
+		"MaxBinId":                 strconv.Itoa(int(params.maxBinId)),
+		"ProtocolShare":            strconv.Itoa(int(params.protocolShare)),
+		"ActiveId":                 strconv.Itoa(int(pool.activeId)),
+		"BinStep":                  strconv.Itoa(int(pool.binStep)),
+		"Status":                   strconv.Itoa(int(pool.status)),
+		"TokenXMint":               pool.TokenXMint.String(),
+		"TokenYMint":               pool.TokenYMint.String(),
+		"ReserveX":                 pool.reserveX.String(),
+		"ReserveY":                 pool.reserveY.String(),
+		"Oracle":                   pool.oracle.String(),
+	}, *update)
+}
@@ -0,0 +1,17 @@
+package meteora
+
+import (
+	"testing"
+
+	"github.com/yimingWOW/solroute/pkg/sol/soltest"
+)
+
+func FuzzLbPairDecode(f *testing.F) {
+	soltest.AddSeeds(f, "testdata/lb_pair.json")
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var pool MeteoraDlmmPool
+		if err := pool.Decode(data); err == nil && len(data) < LbPairAccountSize {
+			t.Fatalf("decoded %d bytes without an error", len(data))
+		}
+	})
+}
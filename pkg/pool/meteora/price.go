@@ -10,12 +10,16 @@ import (
 
 	cosmosmath "cosmossdk.io/math"
 	"github.com/gagliardetto/solana-go"
-	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/yimingWOW/solroute/pkg"
 	"lukechampine.com/uint128"
 )
 
 // Quote calculates the output amount for a given input amount and token
-func (pool *MeteoraDlmmPool) Quote(ctx context.Context, solClient *rpc.Client, inputMint string, inputAmount cosmosmath.Int) (cosmosmath.Int, error) {
+func (pool *MeteoraDlmmPool) Quote(ctx context.Context, fetcher pkg.AccountFetcher, inputMint string, inputAmount cosmosmath.Int) (cosmosmath.Int, error) {
+	pair, err := pkg.PoolPair(pool, inputMint)
+	if err != nil {
+		return cosmosmath.ZeroInt(), err
+	}
 	pool.orgActiveId = pool.activeId
 	totalAmountOut := cosmosmath.ZeroInt()
 
@@ -25,7 +29,7 @@ func (pool *MeteoraDlmmPool) Quote(ctx context.Context, solClient *rpc.Client, i
 	pool.UpdateReferences()
 
 	amountLeft := inputAmount
-	swapForY := inputMint == pool.TokenXMint.String()
+	swapForY := pair.BaseIn
 
 	// Process active bin arrays
 	for amountLeft.IsPositive() {
@@ -434,8 +438,8 @@ func (pool *MeteoraDlmmPool) AdvanceActiveBin(swapForY bool) error {
 
 	// Check if new bin ID is within valid range
 	if nextActiveBinID < MinBinID || nextActiveBinID > MaxBinID {
-		return fmt.Errorf("insufficient liquidity: bin id %d out of range [%d, %d]",
-			nextActiveBinID, MinBinID, MaxBinID)
+		return fmt.Errorf("bin id %d out of range [%d, %d]: %w",
+			nextActiveBinID, MinBinID, MaxBinID, pkg.ErrZeroLiquidity)
 	}
 
 	// Update active bin ID
@@ -466,7 +470,7 @@ func (pool *MeteoraDlmmPool) GetBinArrayPubkeysForSwap(swapForY bool, takeCount
 			}
 			if hasLiquidity {
 				binArrayIdx = append(binArrayIdx, int64(nextBinArrayIdx))
-				pda, _ := DeriveBinArrayPDA(pool.PoolId, int64(nextBinArrayIdx))
+				pda, _ := DeriveBinArrayPDA(pool.GetProgramID(), pool.PoolId, int64(nextBinArrayIdx))
 				binArrayPubkeys = append(binArrayPubkeys, pda)
 				startBinArrayIdx = int64(nextBinArrayIdx) + increment
 			} else {
@@ -480,7 +484,7 @@ func (pool *MeteoraDlmmPool) GetBinArrayPubkeysForSwap(swapForY bool, takeCount
 			}
 			if hasLiquidity {
 				binArrayIdx = append(binArrayIdx, int64(nextBinArrayIdx))
-				pda, _ := DeriveBinArrayPDA(pool.PoolId, int64(nextBinArrayIdx))
+				pda, _ := DeriveBinArrayPDA(pool.GetProgramID(), pool.PoolId, int64(nextBinArrayIdx))
 				binArrayPubkeys = append(binArrayPubkeys, pda)
 				startBinArrayIdx = int64(nextBinArrayIdx) + increment
 			} else {
@@ -533,7 +537,7 @@ func (pool *MeteoraDlmmPool) getCurrentActiveBinArray(swapForY bool) (BinArray,
 	}
 
 	// Generate PDA address for bin array
-	pda, _ := DeriveBinArrayPDA(pool.PoolId, binArrayIdx)
+	pda, _ := DeriveBinArrayPDA(pool.GetProgramID(), pool.PoolId, binArrayIdx)
 
 	binArray, exists := pool.BinArrays[pda.String()]
 	if !exists {
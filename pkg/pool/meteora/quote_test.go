@@ -0,0 +1,43 @@
+package meteora
+
+import (
+	"context"
+	"testing"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/yimingWOW/solroute/pkg/sol/soltest"
+	"lukechampine.com/uint128"
+)
+
+func TestMeteoraDlmmPoolQuote(t *testing.T) {
+	// The active bin 100 holds both tokens at a price of 2 Y per X, with a
+	// 0.1% base fee and no variable fee
+	pool := &MeteoraDlmmPool{
+		PoolId:     solana.NewWallet().PublicKey(),
+		TokenXMint: solana.NewWallet().PublicKey(),
+		TokenYMint: solana.NewWallet().PublicKey(),
+		activeId:   100,
+		binStep:    10,
+	}
+	pool.parameters.baseFactor = 10000
+	pool.binArrayBitmap[8] = 1 << 1 // bin array 1, bins 70 to 139
+	binArray := BinArray{index: 1, LbPair: pool.PoolId}
+	binArray.bins[30] = Bin{amountX: 1_000_000_000_000, amountY: 1_000_000_000_000, price: uint128.From64(2).Lsh(ScaleOffset)}
+	pda, _ := DeriveBinArrayPDA(pool.GetProgramID(), pool.PoolId, 1)
+	pool.BinArrays = map[string]BinArray{pda.String(): binArray}
+
+	fetcher := soltest.NewRPC().Client()
+	for _, tt := range []struct {
+		inputMint solana.PublicKey
+		want      int64
+	}{{pool.TokenXMint, 1_998_000}, {pool.TokenYMint, 499_500}} {
+		got, err := pool.Quote(context.Background(), fetcher, tt.inputMint.String(), math.NewInt(1_000_000))
+		if err != nil {
+			t.Fatalf("quote %s: %v", tt.inputMint, err)
+		}
+		if !got.Equal(math.NewInt(tt.want)) {
+			t.Errorf("quote %s: got %s, want %d", tt.inputMint, got, tt.want)
+		}
+	}
+}
@@ -10,9 +10,12 @@ import (
 	bin "github.com/gagliardetto/binary"
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/sol"
 )
 
-// BuildSwapInstructions creates Solana instructions for performing a swap operation
+// BuildSwapInstructions creates Solana instructions for performing a swap
+// operation. opts.ReferralAccount, when set, receives the host fee
 func (pool *MeteoraDlmmPool) BuildSwapInstructions(
 	ctx context.Context,
 	solClient *rpc.Client,
@@ -20,22 +23,51 @@ func (pool *MeteoraDlmmPool) BuildSwapInstructions(
 	inputMint string,
 	inputAmount math.Int,
 	minOut math.Int,
+	opts pkg.SwapBuildOptions,
 ) ([]solana.Instruction, error) {
+	if err := pkg.CheckSwapAmounts(inputAmount, minOut); err != nil {
+		return nil, err
+	}
+	if err := opts.CheckPoolOptions(); err != nil {
+		return nil, err
+	}
+	pair, err := pkg.PoolPair(pool, inputMint)
+	if err != nil {
+		return nil, err
+	}
 	instructions := []solana.Instruction{}
 
+	// Resolve user token accounts, creating missing ATAs on the fly unless
+	// opts.SkipCreateAccounts says the user holds them
+	xAccount, yAccount := opts.UserAccounts(pair)
+	userXAccount, createXInst, err := sol.ResolveUserTokenAccount(ctx, solClient, user, pool.TokenXMint, xAccount, solana.PublicKey{}, opts.SkipCreateAccounts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve token X account: %w", err)
+	}
+	userYAccount, createYInst, err := sol.ResolveUserTokenAccount(ctx, solClient, user, pool.TokenYMint, yAccount, solana.PublicKey{}, opts.SkipCreateAccounts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve token Y account: %w", err)
+	}
+	for _, createInst := range []solana.Instruction{createXInst, createYInst} {
+		if createInst != nil {
+			instructions = append(instructions, createInst)
+		}
+	}
+
 	var userQuoteAccount solana.PublicKey
 	var userBaseAccount solana.PublicKey
-	if inputMint == pool.TokenXMint.String() {
-		userBaseAccount = pool.UserBaseAccount
-		userQuoteAccount = pool.UserQuoteAccount
+	if pair.BaseIn {
+		userBaseAccount = userXAccount
+		userQuoteAccount = userYAccount
 	} else {
-		userBaseAccount = pool.UserQuoteAccount
-		userQuoteAccount = pool.UserBaseAccount
+		userBaseAccount = userYAccount
+		userQuoteAccount = userXAccount
 	}
 
 	instruction := SwapInstruction{
 		AmountIn:         inputAmount.Uint64(),
 		MinAmountOut:     minOut.Uint64(),
+		Program:          pool.GetProgramID(),
 		AccountMetaSlice: make(solana.AccountMetaSlice, 16+len(pool.BinArrays)),
 		RemainingAccountsInfo: RemainingAccountsInfo{
 			Slices: []RemainingAccountsSlice{
@@ -59,7 +91,7 @@ func (pool *MeteoraDlmmPool) BuildSwapInstructions(
 	if pool.bitmapExtension != nil {
 		instruction.AccountMetaSlice[1] = solana.NewAccountMeta(pool.BitmapExtensionKey, false, false)
 	} else {
-		instruction.AccountMetaSlice[1] = solana.NewAccountMeta(MeteoraProgramID, false, false)
+		instruction.AccountMetaSlice[1] = solana.NewAccountMeta(pool.GetProgramID(), false, false)
 	}
 	instruction.AccountMetaSlice[2] = solana.NewAccountMeta(pool.reserveX, true, false)
 	instruction.AccountMetaSlice[3] = solana.NewAccountMeta(pool.reserveY, true, false)
@@ -68,14 +100,19 @@ func (pool *MeteoraDlmmPool) BuildSwapInstructions(
 	instruction.AccountMetaSlice[6] = solana.NewAccountMeta(pool.TokenXMint, false, false)
 	instruction.AccountMetaSlice[7] = solana.NewAccountMeta(pool.TokenYMint, false, false)
 	instruction.AccountMetaSlice[8] = solana.NewAccountMeta(pool.oracle, true, false)
-	instruction.AccountMetaSlice[9] = solana.NewAccountMeta(MeteoraProgramID, false, false) // Host fee account - set to null in JS SDK but not in Rust SDK
+	// The program ID stands for no host fee account
+	hostFeeAccount := pool.GetProgramID()
+	if !opts.ReferralAccount.IsZero() {
+		hostFeeAccount = opts.ReferralAccount
+	}
+	instruction.AccountMetaSlice[9] = solana.NewAccountMeta(hostFeeAccount, !opts.ReferralAccount.IsZero(), false)
 	instruction.AccountMetaSlice[10] = solana.NewAccountMeta(user, true, true)
 	tokenProgramID := solana.MustPublicKeyFromBase58("TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA")
 	instruction.AccountMetaSlice[11] = solana.NewAccountMeta(tokenProgramID, false, false)
 	instruction.AccountMetaSlice[12] = solana.NewAccountMeta(tokenProgramID, false, false)
 	instruction.AccountMetaSlice[13] = solana.NewAccountMeta(MemoProgramID, false, false)
-	instruction.AccountMetaSlice[14] = solana.NewAccountMeta(DeriveEventAuthorityPDA(), false, false)
-	instruction.AccountMetaSlice[15] = solana.NewAccountMeta(MeteoraProgramID, true, false)
+	instruction.AccountMetaSlice[14] = solana.NewAccountMeta(DeriveEventAuthorityPDA(pool.GetProgramID()), false, false)
+	instruction.AccountMetaSlice[15] = solana.NewAccountMeta(pool.GetProgramID(), true, false)
 
 	index := 16
 	for binArrayKey := range pool.BinArrays {
@@ -113,12 +150,13 @@ type SwapInstruction struct {
 	AmountIn                uint64                `bin:"amount_in"`
 	MinAmountOut            uint64                `bin:"min_amount_out"`
 	RemainingAccountsInfo   RemainingAccountsInfo `bin:"remaining_accounts_info"`
+	Program                 solana.PublicKey      `bin:"-" borsh_skip:"true"`
 	solana.AccountMetaSlice `bin:"-" borsh_skip:"true"`
 }
 
-// ProgramID returns the Meteora program ID
+// ProgramID returns the program ID of the pool the swap is built for
 func (instruction *SwapInstruction) ProgramID() solana.PublicKey {
-	return MeteoraProgramID
+	return instruction.Program
 }
 
 // Accounts returns the account metadata for the instruction
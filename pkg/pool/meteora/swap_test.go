@@ -0,0 +1,22 @@
+package meteora
+
+import (
+	"context"
+	"testing"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/yimingWOW/solroute/pkg"
+)
+
+func TestBuildSwapInstructionsRejectsAmountsOverUint64(t *testing.T) {
+	overflow := math.NewIntFromUint64(^uint64(0)).AddRaw(1)
+	pool := &MeteoraDlmmPool{TokenXMint: solana.NewWallet().PublicKey(), TokenYMint: solana.NewWallet().PublicKey()}
+	for _, amounts := range [][2]math.Int{{overflow, math.ZeroInt()}, {math.NewInt(1), overflow}} {
+		_, err := pool.BuildSwapInstructions(context.Background(), nil, solana.NewWallet().PublicKey(),
+			pool.TokenXMint.String(), amounts[0], amounts[1], pkg.SwapBuildOptions{})
+		if err == nil {
+			t.Errorf("want an error for amount in %s and min out %s", amounts[0], amounts[1])
+		}
+	}
+}
@@ -88,15 +88,16 @@ func BinIDToBinArrayIndex(binID int32) int64 {
 	return int64(quotient)
 }
 
-// DeriveEventAuthorityPDA derives the event authority PDA
-func DeriveEventAuthorityPDA() solana.PublicKey {
+// DeriveEventAuthorityPDA derives the event authority PDA of the DLMM program
+// deployed at programID
+func DeriveEventAuthorityPDA(programID solana.PublicKey) solana.PublicKey {
 	seeds := [][]byte{[]byte("__event_authority")}
-	pda, _, _ := solana.FindProgramAddress(seeds, MeteoraProgramID)
+	pda, _, _ := solana.FindProgramAddress(seeds, programID)
 	return pda
 }
 
 // DeriveBinArrayPDA derives a bin array PDA for the given LB pair and bin array index
-func DeriveBinArrayPDA(lbPair solana.PublicKey, binArrayIndex int64) (solana.PublicKey, uint8) {
+func DeriveBinArrayPDA(programID, lbPair solana.PublicKey, binArrayIndex int64) (solana.PublicKey, uint8) {
 	// Convert bin_array_index to little endian bytes
 	binArrayIndexBytes := make([]byte, 8)
 	binary.LittleEndian.PutUint64(binArrayIndexBytes, uint64(binArrayIndex))
@@ -109,7 +110,7 @@ func DeriveBinArrayPDA(lbPair solana.PublicKey, binArrayIndex int64) (solana.Pub
 	}
 
 	// Find the PDA
-	pda, bump, err := solana.FindProgramAddress(seeds, MeteoraProgramID)
+	pda, bump, err := solana.FindProgramAddress(seeds, programID)
 	if err != nil {
 		return solana.PublicKey{}, 0
 	}
@@ -118,13 +119,13 @@ func DeriveBinArrayPDA(lbPair solana.PublicKey, binArrayIndex int64) (solana.Pub
 }
 
 // DeriveBinArrayBitmapExtension derives the bin array bitmap extension PDA
-func DeriveBinArrayBitmapExtension(lbPair solana.PublicKey) (solana.PublicKey, uint8) {
+func DeriveBinArrayBitmapExtension(programID, lbPair solana.PublicKey) (solana.PublicKey, uint8) {
 	pda, bump, err := solana.FindProgramAddress(
 		[][]byte{
 			[]byte(BinArrayBitmapSeed),
 			lbPair.Bytes(),
 		},
-		MeteoraProgramID, // Replace with actual program ID
+		programID,
 	)
 	if err != nil {
 		return solana.PublicKey{}, 0
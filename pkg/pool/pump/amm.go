@@ -4,13 +4,16 @@ import (
 	"bytes"
 	"context"
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
+	"time"
 
 	"cosmossdk.io/math"
 	bin "github.com/gagliardetto/binary"
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
 	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/sol"
 	"github.com/yimingWOW/solroute/utils"
 )
 
@@ -27,6 +30,9 @@ const (
 	// QuoteMintOffset represents the offset for QuoteMint in the pool data
 	QuoteMintOffset = BaseMintOffset + 32
 
+	// LpSupplyOffset represents the offset for LpSupply in the pool data
+	LpSupplyOffset = QuoteMintOffset + 32*4
+
 	// DefaultFeeRate represents the default fee rate for swaps (0.25%)
 	DefaultFeeRate = 0.00250
 )
@@ -45,11 +51,14 @@ type PumpAMMPool struct {
 	LpSupply              uint64
 	CoinCreator           solana.PublicKey
 
-	PoolId           solana.PublicKey
-	BaseAmount       math.Int
-	QuoteAmount      math.Int
-	UserBaseAccount  solana.PublicKey
-	UserQuoteAccount solana.PublicKey
+	PoolId      solana.PublicKey
+	ProgramID   solana.PublicKey // zero for PumpSwapProgramID
+	Accounts    Accounts
+	BaseAmount  math.Int
+	QuoteAmount math.Int
+	// loadedAt is when ApplyAccount last loaded an account, for Quote to
+	// re-read state older than pkg.MaxStateAge
+	loadedAt time.Time
 }
 
 func (pool *PumpAMMPool) ProtocolName() pkg.ProtocolName {
@@ -61,7 +70,23 @@ func (pool *PumpAMMPool) ProtocolType() pkg.ProtocolType {
 }
 
 func (pool *PumpAMMPool) GetProgramID() solana.PublicKey {
-	return PumpSwapProgramID
+	return orDefault(pool.ProgramID, PumpSwapProgramID)
+}
+
+var _ pkg.PoolV2 = (*PumpAMMPool)(nil)
+
+// Capabilities returns the optional features of the pool
+func (pool *PumpAMMPool) Capabilities() pkg.Capabilities {
+	return pkg.CapAccountUpdates | pkg.CapVaults
+}
+
+// eventAuthority returns the event authority PDA of the pool's program
+func (pool *PumpAMMPool) eventAuthority() solana.PublicKey {
+	if pool.ProgramID.IsZero() || pool.ProgramID.Equals(PumpSwapProgramID) {
+		return PumpEventAuthority
+	}
+	pda, _, _ := solana.FindProgramAddress([][]byte{[]byte("__event_authority")}, pool.ProgramID)
+	return pda
 }
 
 // Span returns the default span value for the pool
@@ -76,18 +101,24 @@ func (p *PumpAMMPool) Offset(value string) uint64 {
 		return BaseMintOffset
 	case "QuoteMint":
 		return QuoteMintOffset
+	case "LpSupply":
+		return LpSupplyOffset
 	default:
 		return 0
 	}
 }
 
-// Decode decodes the pool data from bytes
+// Decode decodes the pool data from bytes, keeping the runtime fields
 func (p *PumpAMMPool) Decode(data []byte) error {
-	if len(data) < PoolDataSize {
-		return fmt.Errorf("data too short: expected %d bytes, got %d", PoolDataSize, len(data))
+	layout, err := ParsePoolData(data)
+	if err != nil {
+		return err
 	}
-	dec := bin.NewBinDecoder(data)
-	return dec.Decode(p)
+	p.PoolBump, p.Index, p.Creator = layout.PoolBump, layout.Index, layout.Creator
+	p.BaseMint, p.QuoteMint, p.LpMint = layout.BaseMint, layout.QuoteMint, layout.LpMint
+	p.PoolBaseTokenAccount, p.PoolQuoteTokenAccount = layout.PoolBaseTokenAccount, layout.PoolQuoteTokenAccount
+	p.LpSupply, p.CoinCreator = layout.LpSupply, layout.CoinCreator
+	return nil
 }
 
 // ParsePoolData parses the raw pool data into a PumpAMMPool struct
@@ -135,6 +166,23 @@ func (l *PumpAMMPool) GetTokens() (string, string) {
 	return l.BaseMint.String(), l.QuoteMint.String()
 }
 
+func (l *PumpAMMPool) Vaults() (baseVault, quoteVault solana.PublicKey) {
+	return l.PoolBaseTokenAccount, l.PoolQuoteTokenAccount
+}
+
+// GetFeeRate returns the fee Quote charges, in basis points
+func (l *PumpAMMPool) GetFeeRate() (float64, error) {
+	return DefaultFeeRate * 10000, nil
+}
+
+// GetLiquidity returns the balances of both pool token accounts
+func (l *PumpAMMPool) GetLiquidity() (pkg.Liquidity, error) {
+	if l.BaseAmount.IsNil() || l.QuoteAmount.IsNil() {
+		return pkg.Liquidity{}, pkg.ErrNotLoaded
+	}
+	return pkg.Liquidity{Base: l.BaseAmount, Quote: l.QuoteAmount}, nil
+}
+
 func (s *PumpAMMPool) BuildSwapInstructions(
 	ctx context.Context,
 	solClient *rpc.Client,
@@ -142,15 +190,49 @@ func (s *PumpAMMPool) BuildSwapInstructions(
 	inputMint string,
 	inputAmount math.Int,
 	minOut math.Int,
+	opts pkg.SwapBuildOptions,
 ) ([]solana.Instruction, error) {
-	if inputMint == s.BaseMint.String() {
-		return s.buyInAMMPool(user, s, inputAmount, minOut)
+	if err := pkg.CheckSwapAmounts(inputAmount, minOut); err != nil {
+		return nil, err
+	}
+	if err := opts.CheckPoolOptions(); err != nil {
+		return nil, err
+	}
+	pair, err := pkg.PoolPair(s, inputMint)
+	if err != nil {
+		return nil, err
+	}
+	baseAccount, quoteAccount := opts.UserAccounts(pair)
+	// Resolve user token accounts, creating missing ATAs on the fly unless
+	// opts.SkipCreateAccounts says the user holds them
+	userBaseAccount, createBaseInst, err := sol.ResolveUserTokenAccount(ctx, solClient, user, s.BaseMint, baseAccount, solana.PublicKey{}, opts.SkipCreateAccounts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve base token account: %w", err)
+	}
+	userQuoteAccount, createQuoteInst, err := sol.ResolveUserTokenAccount(ctx, solClient, user, s.QuoteMint, quoteAccount, solana.PublicKey{}, opts.SkipCreateAccounts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve quote token account: %w", err)
+	}
+	instrs := []solana.Instruction{}
+	for _, createInst := range []solana.Instruction{createBaseInst, createQuoteInst} {
+		if createInst != nil {
+			instrs = append(instrs, createInst)
+		}
+	}
+
+	var swapInstrs []solana.Instruction
+	if pair.BaseIn {
+		swapInstrs, err = s.buyInAMMPool(user, userBaseAccount, userQuoteAccount, s, inputAmount, minOut)
 	} else {
-		return s.sellInAMMPool(user, s, inputAmount, minOut)
+		swapInstrs, err = s.sellInAMMPool(user, userBaseAccount, userQuoteAccount, s, inputAmount, minOut)
+	}
+	if err != nil {
+		return nil, err
 	}
+	return append(instrs, swapInstrs...), nil
 }
 
-func (s *PumpAMMPool) buyInAMMPool(userAddr solana.PublicKey, pool *PumpAMMPool,
+func (s *PumpAMMPool) buyInAMMPool(userAddr, userBaseAccount, userQuoteAccount solana.PublicKey, pool *PumpAMMPool,
 	maxInputAmountWithDecimals math.Int, outAmountWithDecimals math.Int) ([]solana.Instruction, error) {
 	// Initialize instruction array
 	instrs := []solana.Instruction{}
@@ -158,6 +240,7 @@ func (s *PumpAMMPool) buyInAMMPool(userAddr solana.PublicKey, pool *PumpAMMPool,
 	inst := BuySwapInstruction{
 		BaseAmountOut:    outAmountWithDecimals.Uint64(),
 		MaxQuoteAmountIn: maxInputAmountWithDecimals.Uint64(),
+		Program:          pool.GetProgramID(),
 	}
 	if pool.CoinCreator == solana.MustPublicKeyFromBase58("11111111111111111111111111111111") {
 		inst.AccountMetaSlice = make(solana.AccountMetaSlice, 17)
@@ -171,29 +254,29 @@ func (s *PumpAMMPool) buyInAMMPool(userAddr solana.PublicKey, pool *PumpAMMPool,
 	// Ensure correct Token Program address
 	inst.AccountMetaSlice[0] = solana.NewAccountMeta(pool.PoolId, false, false)
 	inst.AccountMetaSlice[1] = solana.NewAccountMeta(userAddr, true, true)
-	inst.AccountMetaSlice[2] = solana.NewAccountMeta(PumpGlobalConfig, false, false)
+	inst.AccountMetaSlice[2] = solana.NewAccountMeta(pool.Accounts.globalConfig(), false, false)
 	inst.AccountMetaSlice[3] = solana.NewAccountMeta(pool.BaseMint, false, false)
 	inst.AccountMetaSlice[4] = solana.NewAccountMeta(pool.QuoteMint, false, false)
-	inst.AccountMetaSlice[5] = solana.NewAccountMeta(pool.UserBaseAccount, true, false)
-	inst.AccountMetaSlice[6] = solana.NewAccountMeta(pool.UserQuoteAccount, true, false)
+	inst.AccountMetaSlice[5] = solana.NewAccountMeta(userBaseAccount, true, false)
+	inst.AccountMetaSlice[6] = solana.NewAccountMeta(userQuoteAccount, true, false)
 	inst.AccountMetaSlice[7] = solana.NewAccountMeta(pool.PoolBaseTokenAccount, true, false)
 	inst.AccountMetaSlice[8] = solana.NewAccountMeta(pool.PoolQuoteTokenAccount, true, false)
-	inst.AccountMetaSlice[9] = solana.NewAccountMeta(PumpProtocolFeeRecipient, false, false)
-	inst.AccountMetaSlice[10] = solana.NewAccountMeta(PumpProtocolFeeRecipientTokenAccount, true, false)
+	inst.AccountMetaSlice[9] = solana.NewAccountMeta(pool.Accounts.protocolFeeRecipient(), false, false)
+	inst.AccountMetaSlice[10] = solana.NewAccountMeta(pool.Accounts.protocolFeeRecipientTokenAccount(), true, false)
 	tokenProgramID := solana.MustPublicKeyFromBase58("TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA")
 	inst.AccountMetaSlice[11] = solana.NewAccountMeta(tokenProgramID, false, false)
 	inst.AccountMetaSlice[12] = solana.NewAccountMeta(tokenProgramID, false, false)
 	inst.AccountMetaSlice[13] = solana.NewAccountMeta(solana.MustPublicKeyFromBase58("11111111111111111111111111111111"), false, false)
 	inst.AccountMetaSlice[14] = solana.NewAccountMeta(solana.MustPublicKeyFromBase58("ATokenGPvbdGVxr1b2hvZbsiqW5xWH25efTNsLJA8knL"), false, false)
-	inst.AccountMetaSlice[15] = solana.NewAccountMeta(solana.MustPublicKeyFromBase58("GS4CU59F31iL7aR2Q8zVS8DRrcRnXX1yjQ66TqNVQnaR"), false, false)
-	inst.AccountMetaSlice[16] = solana.NewAccountMeta(PumpSwapProgramID, false, false)
+	inst.AccountMetaSlice[15] = solana.NewAccountMeta(pool.eventAuthority(), false, false)
+	inst.AccountMetaSlice[16] = solana.NewAccountMeta(pool.GetProgramID(), false, false)
 	if pool.CoinCreator != solana.MustPublicKeyFromBase58("11111111111111111111111111111111") {
-		ata, err := GetCoinCreatorVaultATA(pool.CoinCreator)
+		ata, err := GetCoinCreatorVaultATA(pool.GetProgramID(), pool.CoinCreator)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get coin creator vault ata: %w", err)
 		}
 		inst.AccountMetaSlice[17] = solana.NewAccountMeta(ata, true, false)
-		authority, err := GetCoinCreatorVaultAuthority(pool.CoinCreator)
+		authority, err := GetCoinCreatorVaultAuthority(pool.GetProgramID(), pool.CoinCreator)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get coin creator vault authority: %w", err)
 		}
@@ -204,13 +287,14 @@ func (s *PumpAMMPool) buyInAMMPool(userAddr solana.PublicKey, pool *PumpAMMPool,
 	return instrs, nil
 }
 
-func (s *PumpAMMPool) sellInAMMPool(userAddr solana.PublicKey,
+func (s *PumpAMMPool) sellInAMMPool(userAddr, userBaseAccount, userQuoteAccount solana.PublicKey,
 	pool *PumpAMMPool, baseAmountIn math.Int, minQuoteAmountOut math.Int) ([]solana.Instruction, error) {
 	instrs := []solana.Instruction{}
 
 	inst := SellSwapInstruction{
 		BaseAmountIn:      baseAmountIn.Uint64(),
 		MinQuoteAmountOut: minQuoteAmountOut.Uint64(),
+		Program:           pool.GetProgramID(),
 	}
 	if pool.CoinCreator == solana.MustPublicKeyFromBase58("11111111111111111111111111111111") {
 		inst.AccountMetaSlice = make(solana.AccountMetaSlice, 17)
@@ -223,29 +307,29 @@ func (s *PumpAMMPool) sellInAMMPool(userAddr solana.PublicKey,
 	// 确保使用正确的 Token Program 地址
 	inst.AccountMetaSlice[0] = solana.NewAccountMeta(pool.PoolId, false, false)
 	inst.AccountMetaSlice[1] = solana.NewAccountMeta(userAddr, true, true)
-	inst.AccountMetaSlice[2] = solana.NewAccountMeta(PumpGlobalConfig, false, false)
+	inst.AccountMetaSlice[2] = solana.NewAccountMeta(pool.Accounts.globalConfig(), false, false)
 	inst.AccountMetaSlice[3] = solana.NewAccountMeta(pool.BaseMint, false, false)
 	inst.AccountMetaSlice[4] = solana.NewAccountMeta(pool.QuoteMint, false, false)
-	inst.AccountMetaSlice[5] = solana.NewAccountMeta(pool.UserBaseAccount, true, false)
-	inst.AccountMetaSlice[6] = solana.NewAccountMeta(pool.UserQuoteAccount, true, false)
+	inst.AccountMetaSlice[5] = solana.NewAccountMeta(userBaseAccount, true, false)
+	inst.AccountMetaSlice[6] = solana.NewAccountMeta(userQuoteAccount, true, false)
 	inst.AccountMetaSlice[7] = solana.NewAccountMeta(pool.PoolBaseTokenAccount, true, false)
 	inst.AccountMetaSlice[8] = solana.NewAccountMeta(pool.PoolQuoteTokenAccount, true, false)
-	inst.AccountMetaSlice[9] = solana.NewAccountMeta(PumpProtocolFeeRecipient, false, false)
-	inst.AccountMetaSlice[10] = solana.NewAccountMeta(PumpProtocolFeeRecipientTokenAccount, true, false)
+	inst.AccountMetaSlice[9] = solana.NewAccountMeta(pool.Accounts.protocolFeeRecipient(), false, false)
+	inst.AccountMetaSlice[10] = solana.NewAccountMeta(pool.Accounts.protocolFeeRecipientTokenAccount(), true, false)
 	tokenProgramID := solana.MustPublicKeyFromBase58("TokenkegQfeZyiNwAJbNbGKPFXCWuBvf9Ss623VQ5DA")
 	inst.AccountMetaSlice[11] = solana.NewAccountMeta(tokenProgramID, false, false)
 	inst.AccountMetaSlice[12] = solana.NewAccountMeta(tokenProgramID, false, false)
 	inst.AccountMetaSlice[13] = solana.NewAccountMeta(solana.MustPublicKeyFromBase58("11111111111111111111111111111111"), false, false)
 	inst.AccountMetaSlice[14] = solana.NewAccountMeta(solana.MustPublicKeyFromBase58("ATokenGPvbdGVxr1b2hvZbsiqW5xWH25efTNsLJA8knL"), false, false)
-	inst.AccountMetaSlice[15] = solana.NewAccountMeta(solana.MustPublicKeyFromBase58("GS4CU59F31iL7aR2Q8zVS8DRrcRnXX1yjQ66TqNVQnaR"), false, false)
-	inst.AccountMetaSlice[16] = solana.NewAccountMeta(PumpSwapProgramID, false, false)
+	inst.AccountMetaSlice[15] = solana.NewAccountMeta(pool.eventAuthority(), false, false)
+	inst.AccountMetaSlice[16] = solana.NewAccountMeta(pool.GetProgramID(), false, false)
 	if pool.CoinCreator != solana.MustPublicKeyFromBase58("11111111111111111111111111111111") {
-		ata, err := GetCoinCreatorVaultATA(pool.CoinCreator)
+		ata, err := GetCoinCreatorVaultATA(pool.GetProgramID(), pool.CoinCreator)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get coin creator vault ata: %w", err)
 		}
 		inst.AccountMetaSlice[17] = solana.NewAccountMeta(ata, false, false)
-		authority, err := GetCoinCreatorVaultAuthority(pool.CoinCreator)
+		authority, err := GetCoinCreatorVaultAuthority(pool.GetProgramID(), pool.CoinCreator)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get coin creator vault authority: %w", err)
 		}
@@ -260,11 +344,12 @@ type BuySwapInstruction struct {
 	bin.BaseVariant
 	BaseAmountOut           uint64
 	MaxQuoteAmountIn        uint64
+	Program                 solana.PublicKey `bin:"-" borsh_skip:"true"`
 	solana.AccountMetaSlice `bin:"-" borsh_skip:"true"`
 }
 
 func (inst *BuySwapInstruction) ProgramID() solana.PublicKey {
-	return PumpSwapProgramID
+	return inst.Program
 }
 
 func (inst *BuySwapInstruction) Accounts() (out []*solana.AccountMeta) {
@@ -299,11 +384,12 @@ type SellSwapInstruction struct {
 	bin.BaseVariant
 	BaseAmountIn            uint64
 	MinQuoteAmountOut       uint64
+	Program                 solana.PublicKey `bin:"-" borsh_skip:"true"`
 	solana.AccountMetaSlice `bin:"-" borsh_skip:"true"`
 }
 
 func (inst *SellSwapInstruction) ProgramID() solana.PublicKey {
-	return PumpSwapProgramID
+	return inst.Program
 }
 
 func (inst *SellSwapInstruction) Accounts() (out []*solana.AccountMeta) {
@@ -336,45 +422,89 @@ func (inst *SellSwapInstruction) Data() ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
-func (pool *PumpAMMPool) Quote(ctx context.Context, solClient *rpc.Client, inputMint string, inputAmount math.Int) (math.Int, error) {
-	// update pool data first
-	accounts := make([]solana.PublicKey, 0)
-	accounts = append(accounts, pool.PoolBaseTokenAccount)
-	accounts = append(accounts, pool.PoolQuoteTokenAccount)
-	results, err := solClient.GetMultipleAccountsWithOpts(ctx,
-		accounts,
-		&rpc.GetMultipleAccountsOpts{
-			Commitment: rpc.CommitmentProcessed,
-		},
-	)
+// Refresh re-reads both pool token account balances in a single request
+func (pool *PumpAMMPool) Refresh(ctx context.Context, fetcher pkg.AccountFetcher) error {
+	accounts := pool.WatchedAccounts()
+	results, err := fetcher.GetMultipleAccounts(ctx, accounts...)
 	if err != nil {
-		return math.NewInt(0), fmt.Errorf("batch request failed: %v", err)
+		return fmt.Errorf("batch request failed: %w", sol.ClassifyError(err))
+	}
+	if len(results.Value) != len(accounts) {
+		return fmt.Errorf("unexpected number of accounts: %d", len(results.Value))
 	}
 	for i, result := range results.Value {
 		if result == nil {
-			return math.NewInt(0), fmt.Errorf("result is nil, account: %v", accounts[i].String())
+			return fmt.Errorf("account %v: %w", accounts[i].String(), sol.ErrAccountNotFound)
 		}
-		accountKey := accounts[i].String()
-		if pool.PoolBaseTokenAccount.String() == accountKey {
-			amountBytes := result.Data.GetBinary()[64:72]
-			amountUint := binary.LittleEndian.Uint64(amountBytes)
-			amount := math.NewIntFromUint64(amountUint)
-			pool.BaseAmount = amount
-		} else {
-			amountBytes := result.Data.GetBinary()[64:72]
-			amountUint := binary.LittleEndian.Uint64(amountBytes)
-			amount := math.NewIntFromUint64(amountUint)
-			pool.QuoteAmount = amount
+		if err := pool.ApplyAccount(accounts[i], result.Data.GetBinary()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WatchedAccounts returns both pool token accounts
+func (pool *PumpAMMPool) WatchedAccounts() []solana.PublicKey {
+	return []solana.PublicKey{pool.PoolBaseTokenAccount, pool.PoolQuoteTokenAccount}
+}
+
+// ApplyAccount updates the reserves from new data of one of the pool token accounts
+func (pool *PumpAMMPool) ApplyAccount(pubkey solana.PublicKey, data []byte) error {
+	amount, err := sol.ParseTokenAmount(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse pool token account: %w", err)
+	}
+	switch pubkey {
+	case pool.PoolBaseTokenAccount:
+		pool.BaseAmount = math.NewIntFromUint64(amount)
+	case pool.PoolQuoteTokenAccount:
+		pool.QuoteAmount = math.NewIntFromUint64(amount)
+	default:
+		return fmt.Errorf("account %s is not watched by pool %s", pubkey.String(), pool.PoolId.String())
+	}
+	pool.loadedAt = time.Now()
+	return nil
+}
+
+// pumpAMMPoolState is PumpAMMPool without its methods, so it encodes field by field
+type pumpAMMPoolState PumpAMMPool
+
+// MarshalBinary encodes the decoded pool state and reserves
+func (pool *PumpAMMPool) MarshalBinary() ([]byte, error) {
+	return json.Marshal((*pumpAMMPoolState)(pool))
+}
+
+// UnmarshalBinary restores a pool encoded by MarshalBinary
+func (pool *PumpAMMPool) UnmarshalBinary(data []byte) error {
+	if err := json.Unmarshal(data, (*pumpAMMPoolState)(pool)); err != nil {
+		return fmt.Errorf("failed to decode pool snapshot: %w", err)
+	}
+	pool.loadedAt = time.Now()
+	return nil
+}
+
+func (pool *PumpAMMPool) Quote(ctx context.Context, fetcher pkg.AccountFetcher, inputMint string, inputAmount math.Int) (math.Int, error) {
+	pair, err := pkg.PoolPair(pool, inputMint)
+	if err != nil {
+		return math.NewInt(0), err
+	}
+	if pool.BaseAmount.IsNil() || pool.QuoteAmount.IsNil() || pkg.StateStale(pool.loadedAt) {
+		if err := pool.Refresh(ctx, fetcher); err != nil {
+			return math.NewInt(0), err
 		}
 	}
 
+	if !pool.BaseAmount.IsPositive() || !pool.QuoteAmount.IsPositive() {
+		return math.NewInt(0), pkg.ErrZeroLiquidity
+	}
+
 	feeRate := 1 - DefaultFeeRate
 	feeMultiplier := math.NewInt(int64(feeRate * float64(BaseDecimalInt)))
 
 	// Calculate k = baseAmount * quoteAmount
 	k := pool.BaseAmount.Mul(pool.QuoteAmount)
 
-	if inputMint == pool.BaseMint.String() {
+	if pair.BaseIn {
 		// Calculate newBase = baseAmount + amountWithFee
 		newBase := pool.BaseAmount.Add(inputAmount.Mul(feeMultiplier).Quo(BaseDecimal))
 		// Calculate newQuote = k / newBase
@@ -0,0 +1,61 @@
+package pump
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/sol/soltest"
+)
+
+func TestBuildSwapInstructionsRejectsAmountsOverUint64(t *testing.T) {
+	overflow := math.NewIntFromUint64(^uint64(0)).AddRaw(1)
+	pool := &PumpAMMPool{BaseMint: solana.NewWallet().PublicKey(), QuoteMint: solana.NewWallet().PublicKey()}
+	for _, amounts := range [][2]math.Int{{overflow, math.ZeroInt()}, {math.NewInt(1), overflow}} {
+		_, err := pool.BuildSwapInstructions(context.Background(), nil, solana.NewWallet().PublicKey(),
+			pool.BaseMint.String(), amounts[0], amounts[1], pkg.SwapBuildOptions{})
+		if err == nil {
+			t.Errorf("want an error for amount in %s and min out %s", amounts[0], amounts[1])
+		}
+	}
+}
+
+func TestPumpAMMPoolQuoteReadsStaleState(t *testing.T) {
+	pool := &PumpAMMPool{
+		BaseMint:              solana.NewWallet().PublicKey(),
+		QuoteMint:             solana.NewWallet().PublicKey(),
+		PoolBaseTokenAccount:  solana.NewWallet().PublicKey(),
+		PoolQuoteTokenAccount: solana.NewWallet().PublicKey(),
+	}
+	node := soltest.NewRPC()
+	node.SetTokenAccount(pool.PoolBaseTokenAccount, pool.BaseMint, pool.PoolId, 10_000_000)
+	node.SetTokenAccount(pool.PoolQuoteTokenAccount, pool.QuoteMint, pool.PoolId, 3_000_000)
+	if err := pool.Refresh(context.Background(), node.Client()); err != nil {
+		t.Fatal(err)
+	}
+	quote := func() math.Int {
+		t.Helper()
+		out, err := pool.Quote(context.Background(), node.Client(), pool.BaseMint.String(), math.NewInt(100_000))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	before := quote()
+
+	// Quote trusts state loaded within pkg.MaxStateAge
+	node.Reset()
+	node.SetTokenAccount(pool.PoolQuoteTokenAccount, pool.QuoteMint, pool.PoolId, 1_500_000)
+	if got := quote(); !got.Equal(before) || node.CallCount("getMultipleAccounts") != 0 {
+		t.Fatalf("got %s after %d reads, want %s without reading", got, node.CallCount("getMultipleAccounts"), before)
+	}
+
+	// and re-reads older state
+	pool.loadedAt = time.Now().Add(-2 * pkg.MaxStateAge)
+	if got := quote(); got.Equal(before) || node.CallCount("getMultipleAccounts") != 1 {
+		t.Fatalf("got %s after %d reads, want a new quote after one", got, node.CallCount("getMultipleAccounts"))
+	}
+}
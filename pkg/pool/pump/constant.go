@@ -10,8 +10,37 @@ var (
 	PumpGlobalConfig                     = solana.MustPublicKeyFromBase58("ADyA8hdefvWN2dbGGWFotbzWxrAvLW83WG6QCVXvJKqw")
 	PumpProtocolFeeRecipient             = solana.MustPublicKeyFromBase58("62qc2CNXwrYqQScmEdiZFFAnJR262PxWEuNQtxfafNgV")
 	PumpProtocolFeeRecipientTokenAccount = solana.MustPublicKeyFromBase58("94qWNrtmfn42h3ZjUZwWvK1MEo9uVmmrBPd2hpNjYDjb")
+	// PumpEventAuthority is the event authority PDA of PumpSwapProgramID
+	PumpEventAuthority = solana.MustPublicKeyFromBase58("GS4CU59F31iL7aR2Q8zVS8DRrcRnXX1yjQ66TqNVQnaR")
 )
 
+// Accounts are the global accounts of a Pump AMM deployment. Zero fields keep
+// the mainnet ones
+type Accounts struct {
+	GlobalConfig                     solana.PublicKey
+	ProtocolFeeRecipient             solana.PublicKey
+	ProtocolFeeRecipientTokenAccount solana.PublicKey
+}
+
+func (a Accounts) globalConfig() solana.PublicKey {
+	return orDefault(a.GlobalConfig, PumpGlobalConfig)
+}
+
+func (a Accounts) protocolFeeRecipient() solana.PublicKey {
+	return orDefault(a.ProtocolFeeRecipient, PumpProtocolFeeRecipient)
+}
+
+func (a Accounts) protocolFeeRecipientTokenAccount() solana.PublicKey {
+	return orDefault(a.ProtocolFeeRecipientTokenAccount, PumpProtocolFeeRecipientTokenAccount)
+}
+
+func orDefault(key, fallback solana.PublicKey) solana.PublicKey {
+	if key.IsZero() {
+		return fallback
+	}
+	return key
+}
+
 var (
 	BaseDecimalInt = 1000000000                   // 1*10^9
 	BaseDecimal    = math.NewIntWithDecimal(1, 9) // 1*10^9
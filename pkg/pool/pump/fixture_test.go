@@ -0,0 +1,40 @@
+package pump
+
+import (
+	"flag"
+	"strconv"
+	"testing"
+
+	"github.com/yimingWOW/solroute/pkg/sol/soltest"
+)
+
+var update = flag.Bool("update", false, "rewrite the decoded fields of the testdata fixtures")
+
+func TestPoolFixture(t *testing.T) {
+	const path = "testdata/pool.json"
+	account := soltest.ReadFixture(t, path).Account
+	parsed, err := ParsePoolData(account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded PumpAMMPool
+	if err := decoded.Decode(account); err != nil {
+		t.Fatal(err)
+	}
+	for name, pool := range map[string]*PumpAMMPool{"ParsePoolData": parsed, "Decode": &decoded} {
+		t.Run(name, func(t *testing.T) {
+			soltest.CheckDecoded(t, path, map[string]string{
+				"PoolBump":              strconv.Itoa(int(pool.PoolBump)),
+				"Index":                 strconv.Itoa(int(pool.Index)),
+				"Creator":               pool.Creator.String(),
+				"BaseMint":              pool.BaseMint.String(),
+				"QuoteMint":             pool.QuoteMint.String(),
+				"LpMint":                pool.LpMint.String(),
+				"PoolBaseTokenAccount":  pool.PoolBaseTokenAccount.String(),
+				"PoolQuoteTokenAccount": pool.PoolQuoteTokenAccount.String(),
+				"LpSupply":              strconv.FormatUint(pool.LpSupply, 10),
+				"CoinCreator":           pool.CoinCreator.String(),
+			}, *update)
+		})
+	}
+}
@@ -0,0 +1,20 @@
+package pump
+
+import (
+	"testing"
+
+	"github.com/yimingWOW/solroute/pkg/sol/soltest"
+)
+
+func FuzzPoolDecode(f *testing.F) {
+	soltest.AddSeeds(f, "testdata/pool.json")
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if _, err := ParsePoolData(data); err == nil && len(data) < PoolDataSize {
+			t.Fatalf("parsed %d bytes without an error", len(data))
+		}
+		var pool PumpAMMPool
+		if err := pool.Decode(data); err == nil && len(data) < PoolDataSize {
+			t.Fatalf("decoded %d bytes without an error", len(data))
+		}
+	})
+}
@@ -0,0 +1,35 @@
+package pump
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/yimingWOW/solroute/pkg/sol/soltest"
+)
+
+func TestPumpAMMPoolQuote(t *testing.T) {
+	// Reserves of 1000 base and 150 quote tokens of 9 decimals, with the 0.25% fee
+	pool := &PumpAMMPool{
+		BaseMint:    solana.NewWallet().PublicKey(),
+		QuoteMint:   solana.NewWallet().PublicKey(),
+		BaseAmount:  math.NewInt(1_000_000_000_000),
+		QuoteAmount: math.NewInt(150_000_000_000),
+		loadedAt:    time.Now(),
+	}
+	fetcher := soltest.NewRPC().Client()
+	for _, tt := range []struct {
+		inputMint solana.PublicKey
+		want      int64
+	}{{pool.BaseMint, 149_475_898}, {pool.QuoteMint, 6_606_069_637}} {
+		got, err := pool.Quote(context.Background(), fetcher, tt.inputMint.String(), math.NewInt(1_000_000_000))
+		if err != nil {
+			t.Fatalf("quote %s: %v", tt.inputMint, err)
+		}
+		if !got.Equal(math.NewInt(tt.want)) {
+			t.Errorf("quote %s: got %s, want %d", tt.inputMint, got, tt.want)
+		}
+	}
+}
@@ -13,7 +13,7 @@ const (
 )
 
 // GetCoinCreatorVaultAuthority derives the Program Derived Address (PDA) for the coin creator's vault authority
-func GetCoinCreatorVaultAuthority(coinCreator solana.PublicKey) (solana.PublicKey, error) {
+func GetCoinCreatorVaultAuthority(programID, coinCreator solana.PublicKey) (solana.PublicKey, error) {
 	if coinCreator.IsZero() {
 		return solana.PublicKey{}, fmt.Errorf("invalid coin creator public key")
 	}
@@ -23,7 +23,7 @@ func GetCoinCreatorVaultAuthority(coinCreator solana.PublicKey) (solana.PublicKe
 		coinCreator.Bytes(),
 	}
 
-	pda, _, err := solana.FindProgramAddress(seeds, PumpSwapProgramID)
+	pda, _, err := solana.FindProgramAddress(seeds, programID)
 	if err != nil {
 		return solana.PublicKey{}, fmt.Errorf("failed to find program address: %w", err)
 	}
@@ -32,12 +32,12 @@ func GetCoinCreatorVaultAuthority(coinCreator solana.PublicKey) (solana.PublicKe
 }
 
 // GetCoinCreatorVaultATA derives the Associated Token Account (ATA) for the coin creator's vault authority
-func GetCoinCreatorVaultATA(coinCreator solana.PublicKey) (solana.PublicKey, error) {
+func GetCoinCreatorVaultATA(programID, coinCreator solana.PublicKey) (solana.PublicKey, error) {
 	if coinCreator.IsZero() {
 		return solana.PublicKey{}, fmt.Errorf("invalid coin creator public key")
 	}
 
-	creatorVaultAuthority, err := GetCoinCreatorVaultAuthority(coinCreator)
+	creatorVaultAuthority, err := GetCoinCreatorVaultAuthority(programID, coinCreator)
 	if err != nil {
 		return solana.PublicKey{}, fmt.Errorf("failed to get vault authority: %w", err)
 	}
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"log"
 	"reflect"
+	"time"
 	"unsafe"
 
 	"cosmossdk.io/math"
@@ -18,6 +19,7 @@ import (
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
 	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/sol"
 	"lukechampine.com/uint128"
 )
 
@@ -85,6 +87,7 @@ type AMMPool struct {
 
 	// Market related accounts
 	PoolId           solana.PublicKey
+	ProgramID        solana.PublicKey // zero for RAYDIUM_AMM_PROGRAM_ID
 	Authority        solana.PublicKey
 	MarketAuthority  solana.PublicKey
 	MarketBaseVault  solana.PublicKey
@@ -94,12 +97,18 @@ type AMMPool struct {
 	MarketEventQueue solana.PublicKey
 
 	// Pool balances
-	BaseAmount       cosmath.Int
-	QuoteAmount      cosmath.Int
-	BaseReserve      cosmath.Int
-	QuoteReserve     cosmath.Int
-	UserBaseAccount  solana.PublicKey
-	UserQuoteAccount solana.PublicKey
+	BaseAmount   cosmath.Int
+	QuoteAmount  cosmath.Int
+	BaseReserve  cosmath.Int
+	QuoteReserve cosmath.Int
+	// BaseOrderAmount and QuoteOrderAmount are the token totals of OpenOrders,
+	// the pool's tokens on the OpenBook market. Nil counts as zero
+	BaseOrderAmount  cosmath.Int
+	QuoteOrderAmount cosmath.Int
+
+	// loadedAt is when ApplyAccount last loaded an account, for Quote to
+	// re-read state older than pkg.MaxStateAge
+	loadedAt time.Time
 }
 
 func (pool *AMMPool) ProtocolName() pkg.ProtocolName {
@@ -111,9 +120,19 @@ func (pool *AMMPool) ProtocolType() pkg.ProtocolType {
 }
 
 func (pool *AMMPool) GetProgramID() solana.PublicKey {
+	if !pool.ProgramID.IsZero() {
+		return pool.ProgramID
+	}
 	return RAYDIUM_AMM_PROGRAM_ID
 }
 
+var _ pkg.PoolV2 = (*AMMPool)(nil)
+
+// Capabilities returns the optional features of the pool
+func (pool *AMMPool) Capabilities() pkg.Capabilities {
+	return pkg.CapAccountUpdates | pkg.CapVaults
+}
+
 func (l *AMMPool) Span() uint64 {
 	return 752
 }
@@ -319,6 +338,24 @@ func (l *MarketStateLayoutV3) Print() {
 	log.Printf("Pool Information:\n%s", string(poolInfo))
 }
 
+// Offsets of the token totals in an OpenBook open orders account, after the
+// "serum" padding, account flags, market, owner and the free base tokens
+const (
+	openOrdersBaseTotalOffset  = 5 + 8 + 32 + 32 + 8
+	openOrdersQuoteTotalOffset = openOrdersBaseTotalOffset + 8 + 8
+)
+
+// parseOpenOrdersTotals returns the base and quote tokens an open orders
+// account holds, free or locked in orders
+func parseOpenOrdersTotals(data []byte) (base, quote uint64, err error) {
+	if len(data) < openOrdersQuoteTotalOffset+8 {
+		return 0, 0, fmt.Errorf("data too short: expected %d bytes, got %d", openOrdersQuoteTotalOffset+8, len(data))
+	}
+	base = binary.LittleEndian.Uint64(data[openOrdersBaseTotalOffset : openOrdersBaseTotalOffset+8])
+	quote = binary.LittleEndian.Uint64(data[openOrdersQuoteTotalOffset : openOrdersQuoteTotalOffset+8])
+	return base, quote, nil
+}
+
 // GetID returns the pool ID
 func (p *AMMPool) GetID() string {
 	return p.PoolId.String()
@@ -329,61 +366,161 @@ func (p *AMMPool) GetTokens() (baseMint, quoteMint string) {
 	return p.BaseMint.String(), p.QuoteMint.String()
 }
 
+func (p *AMMPool) Vaults() (baseVault, quoteVault solana.PublicKey) {
+	return p.BaseVault, p.QuoteVault
+}
+
+// GetFeeRate returns the trade fee Quote charges, in basis points
+func (p *AMMPool) GetFeeRate() (float64, error) {
+	return feeBps(LIQUIDITY_FEES_NUMERATOR, LIQUIDITY_FEES_DENOMINATOR), nil
+}
+
+// GetLiquidity returns the reserves Quote trades against, see reserves
+func (p *AMMPool) GetLiquidity() (pkg.Liquidity, error) {
+	if p.BaseAmount.IsNil() || p.QuoteAmount.IsNil() {
+		return pkg.Liquidity{}, pkg.ErrNotLoaded
+	}
+	base, quote := p.reserves()
+	return pkg.Liquidity{Base: base, Quote: quote}, nil
+}
+
+// reserves returns the tokens of the pool the way the Raydium SDK counts
+// them: the vault balances plus the totals of its open orders, net of the
+// PnL owed to the pool owner
+func (p *AMMPool) reserves() (base, quote cosmath.Int) {
+	base = p.BaseAmount.Sub(cosmath.NewIntFromUint64(p.BaseNeedTakePnl))
+	quote = p.QuoteAmount.Sub(cosmath.NewIntFromUint64(p.QuoteNeedTakePnl))
+	if !p.BaseOrderAmount.IsNil() {
+		base = base.Add(p.BaseOrderAmount)
+	}
+	if !p.QuoteOrderAmount.IsNil() {
+		quote = quote.Add(p.QuoteOrderAmount)
+	}
+	return base, quote
+}
+
+// Refresh re-reads the pool account, both vault balances and the open orders
+// in a single request. Missing open orders, e.g. closed when the pool left
+// OpenBook, hold nothing
+func (p *AMMPool) Refresh(ctx context.Context, fetcher pkg.AccountFetcher) error {
+	accounts := p.WatchedAccounts()
+	results, err := fetcher.GetMultipleAccounts(ctx, accounts...)
+	if err != nil {
+		return fmt.Errorf("batch request failed: %w", sol.ClassifyError(err))
+	}
+	if len(results.Value) != len(accounts) {
+		return fmt.Errorf("unexpected number of accounts: %d", len(results.Value))
+	}
+	for i, result := range results.Value {
+		if result == nil && accounts[i] == p.OpenOrders {
+			p.BaseOrderAmount, p.QuoteOrderAmount = cosmath.ZeroInt(), cosmath.ZeroInt()
+			continue
+		}
+		if result == nil {
+			return fmt.Errorf("account %v: %w", accounts[i].String(), sol.ErrAccountNotFound)
+		}
+		if err := p.ApplyAccount(accounts[i], result.Data.GetBinary()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WatchedAccounts returns the pool account, both vaults and the open orders
+func (p *AMMPool) WatchedAccounts() []solana.PublicKey {
+	accounts := []solana.PublicKey{p.PoolId, p.BaseVault, p.QuoteVault}
+	if !p.OpenOrders.IsZero() {
+		accounts = append(accounts, p.OpenOrders)
+	}
+	return accounts
+}
+
+// ApplyAccount updates the pool from new data of the pool account, one of its
+// vaults or its open orders
+func (p *AMMPool) ApplyAccount(pubkey solana.PublicKey, data []byte) error {
+	switch pubkey {
+	case p.PoolId:
+		if err := p.Decode(data); err != nil {
+			return fmt.Errorf("failed to decode pool data: %w", err)
+		}
+	case p.BaseVault:
+		amount, err := sol.ParseTokenAmount(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse base vault: %w", err)
+		}
+		p.BaseAmount = math.NewIntFromUint64(amount)
+	case p.QuoteVault:
+		amount, err := sol.ParseTokenAmount(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse quote vault: %w", err)
+		}
+		p.QuoteAmount = math.NewIntFromUint64(amount)
+	case p.OpenOrders:
+		base, quote, err := parseOpenOrdersTotals(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse open orders: %w", err)
+		}
+		p.BaseOrderAmount, p.QuoteOrderAmount = math.NewIntFromUint64(base), math.NewIntFromUint64(quote)
+	default:
+		return fmt.Errorf("account %s is not watched by pool %s", pubkey.String(), p.PoolId.String())
+	}
+	p.loadedAt = time.Now()
+	return nil
+}
+
+// ammPoolState is AMMPool without its methods, so it encodes field by field
+type ammPoolState AMMPool
+
+// MarshalBinary encodes the decoded pool state, market and vault balances
+func (p *AMMPool) MarshalBinary() ([]byte, error) {
+	return json.Marshal((*ammPoolState)(p))
+}
+
+// UnmarshalBinary restores a pool encoded by MarshalBinary
+func (p *AMMPool) UnmarshalBinary(data []byte) error {
+	if err := json.Unmarshal(data, (*ammPoolState)(p)); err != nil {
+		return fmt.Errorf("failed to decode pool snapshot: %w", err)
+	}
+	p.loadedAt = time.Now()
+	return nil
+}
+
 // Quote calculates the expected output amount for a given input amount
 // It takes into account the current pool reserves and fees
 func (p *AMMPool) Quote(
 	ctx context.Context,
-	solClient *rpc.Client,
+	fetcher pkg.AccountFetcher,
 	inputMint string,
 	inputAmount cosmath.Int,
 ) (cosmath.Int, error) {
-	// update pool data first
-	accounts := make([]solana.PublicKey, 0)
-	accounts = append(accounts, p.BaseVault)
-	accounts = append(accounts, p.QuoteVault)
-	results, err := solClient.GetMultipleAccountsWithOpts(ctx,
-		accounts,
-		&rpc.GetMultipleAccountsOpts{
-			Commitment: rpc.CommitmentProcessed,
-		},
-	)
+	pair, err := pkg.PoolPair(p, inputMint)
 	if err != nil {
-		return math.NewInt(0), fmt.Errorf("batch request failed: %v", err)
+		return cosmath.ZeroInt(), err
 	}
-	for i, result := range results.Value {
-		if result == nil {
-			return math.NewInt(0), fmt.Errorf("result is nil, account: %v", accounts[i].String())
-		}
-		accountKey := accounts[i].String()
-		if p.BaseVault.String() == accountKey {
-			amountBytes := result.Data.GetBinary()[64:72]
-			amountUint := binary.LittleEndian.Uint64(amountBytes)
-			amount := math.NewIntFromUint64(amountUint)
-			p.BaseAmount = amount
-		} else {
-			amountBytes := result.Data.GetBinary()[64:72]
-			amountUint := binary.LittleEndian.Uint64(amountBytes)
-			amount := math.NewIntFromUint64(amountUint)
-			p.QuoteAmount = amount
+	if p.BaseAmount.IsNil() || p.QuoteAmount.IsNil() || pkg.StateStale(p.loadedAt) {
+		if err := p.Refresh(ctx, fetcher); err != nil {
+			return math.NewInt(0), err
 		}
 	}
 
-	// Calculate effective reserves by subtracting pending PnL
-	p.BaseReserve = p.BaseAmount.Sub(cosmath.NewInt(int64(p.BaseNeedTakePnl)))
-	p.QuoteReserve = p.QuoteAmount.Sub(cosmath.NewInt(int64(p.QuoteNeedTakePnl)))
+	// Count the tokens on the market and subtract the pending PnL
+	p.BaseReserve, p.QuoteReserve = p.reserves()
 
 	// Set reserves and decimals based on swap direction
 	reserves := []cosmath.Int{p.BaseReserve, p.QuoteReserve}
 	mintDecimals := []int{int(p.BaseDecimal), int(p.QuoteDecimal)}
 
 	// Swap reserves if input is quote token
-	if inputMint == p.QuoteMint.String() {
+	if !pair.BaseIn {
 		reserves[0], reserves[1] = reserves[1], reserves[0]
 		mintDecimals[0], mintDecimals[1] = mintDecimals[1], mintDecimals[0]
 	}
 
 	reserveIn := reserves[0]
 	reserveOut := reserves[1]
+	if !reserveIn.IsPositive() || !reserveOut.IsPositive() {
+		return cosmath.ZeroInt(), pkg.ErrZeroLiquidity
+	}
 
 	// Initialize output values
 	amountOutRaw := cosmath.ZeroInt()
@@ -413,31 +550,52 @@ func (pool *AMMPool) BuildSwapInstructions(
 	inputMint string,
 	inputAmount cosmath.Int,
 	minOut cosmath.Int,
+	opts pkg.SwapBuildOptions,
 ) ([]solana.Instruction, error) {
+	if err := pkg.CheckSwapAmounts(inputAmount, minOut); err != nil {
+		return nil, err
+	}
+	if err := opts.CheckPoolOptions(); err != nil {
+		return nil, err
+	}
+	pair, err := pkg.PoolPair(pool, inputMint)
+	if err != nil {
+		return nil, err
+	}
 	instrs := []solana.Instruction{}
 
-	// Determine input token mint
-	var inputValueMint solana.PublicKey
-	if inputMint == pool.BaseMint.String() {
-		inputValueMint = pool.BaseMint
-	} else {
-		inputValueMint = pool.QuoteMint
+	// Resolve user token accounts, creating missing ATAs on the fly unless
+	// opts.SkipCreateAccounts says the user holds them
+	baseAccount, quoteAccount := opts.UserAccounts(pair)
+	userBaseAccount, createBaseInst, err := sol.ResolveUserTokenAccount(ctx, solClient, user, pool.BaseMint, baseAccount, solana.TokenProgramID, opts.SkipCreateAccounts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve base token account: %w", err)
+	}
+	userQuoteAccount, createQuoteInst, err := sol.ResolveUserTokenAccount(ctx, solClient, user, pool.QuoteMint, quoteAccount, solana.TokenProgramID, opts.SkipCreateAccounts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve quote token account: %w", err)
+	}
+	for _, createInst := range []solana.Instruction{createBaseInst, createQuoteInst} {
+		if createInst != nil {
+			instrs = append(instrs, createInst)
+		}
 	}
 
 	// Set up source and destination accounts based on swap direction
 	var fromAccount, toAccount solana.PublicKey
-	if inputValueMint.String() == pool.BaseMint.String() {
-		fromAccount = pool.UserBaseAccount
-		toAccount = pool.UserQuoteAccount
+	if pair.BaseIn {
+		fromAccount = userBaseAccount
+		toAccount = userQuoteAccount
 	} else {
-		fromAccount = pool.UserQuoteAccount
-		toAccount = pool.UserBaseAccount
+		fromAccount = userQuoteAccount
+		toAccount = userBaseAccount
 	}
 
 	// Create swap instruction
 	inst := InSwapInstruction{
 		InAmount:         inputAmount.Uint64(),
 		MinimumOutAmount: minOut.Uint64(),
+		Program:          pool.GetProgramID(),
 		AccountMetaSlice: make(solana.AccountMetaSlice, 18),
 	}
 	inst.BaseVariant = bin.BaseVariant{
@@ -473,11 +631,12 @@ type InSwapInstruction struct {
 	bin.BaseVariant
 	InAmount                uint64
 	MinimumOutAmount        uint64
+	Program                 solana.PublicKey `bin:"-" borsh_skip:"true"`
 	solana.AccountMetaSlice `bin:"-" borsh_skip:"true"`
 }
 
 func (inst *InSwapInstruction) ProgramID() solana.PublicKey {
-	return RAYDIUM_AMM_PROGRAM_ID
+	return inst.Program
 }
 
 func (inst *InSwapInstruction) Accounts() (out []*solana.AccountMeta) {
@@ -0,0 +1,90 @@
+package raydium
+
+import (
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	cosmath "cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/sol/soltest"
+)
+
+// loadAMMPool returns the fixture pool and a node serving its account, its
+// vaults and open orders holding the given token totals
+func loadAMMPool(t *testing.T, baseTotal, quoteTotal uint64) (*AMMPool, *soltest.RPC) {
+	t.Helper()
+	account := soltest.ReadFixture(t, "testdata/amm_pool.json").Account
+	pool := &AMMPool{PoolId: solana.NewWallet().PublicKey()}
+	if err := pool.Decode(account); err != nil {
+		t.Fatal(err)
+	}
+	openOrders := make([]byte, 3228)
+	binary.LittleEndian.PutUint64(openOrders[openOrdersBaseTotalOffset:], baseTotal)
+	binary.LittleEndian.PutUint64(openOrders[openOrdersQuoteTotalOffset:], quoteTotal)
+
+	node := soltest.NewRPC()
+	node.SetAccount(pool.PoolId, soltest.Account{Owner: RAYDIUM_AMM_PROGRAM_ID, Data: account})
+	node.SetTokenAccount(pool.BaseVault, pool.BaseMint, pool.Owner, 10_000_000)
+	node.SetTokenAccount(pool.QuoteVault, pool.QuoteMint, pool.Owner, 3_000_000)
+	node.SetAccount(pool.OpenOrders, soltest.Account{Owner: pool.MarketProgramId, Data: openOrders})
+	return pool, node
+}
+
+func TestAMMPoolReserves(t *testing.T) {
+	pool, node := loadAMMPool(t, 2_000_000, 500_000)
+	if err := pool.Refresh(context.Background(), node.Client()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The vault balances plus the open orders totals, less the PnL owed:
+	// 10,000,000 + 2,000,000 - 123,456 and 3,000,000 + 500,000 - 7,890
+	base, quote := pool.reserves()
+	if !base.Equal(cosmath.NewInt(11_876_544)) || !quote.Equal(cosmath.NewInt(3_492_110)) {
+		t.Fatalf("got reserves %s and %s, want 11876544 and 3492110", base, quote)
+	}
+}
+
+func TestAMMPoolRefreshRejectsMissingResults(t *testing.T) {
+	pool, node := loadAMMPool(t, 0, 0)
+	node.Handle("getMultipleAccounts", func(context.Context, []interface{}) (interface{}, error) {
+		return map[string]interface{}{
+			"context": map[string]interface{}{"slot": 1},
+			"value":   []interface{}{},
+		}, nil
+	})
+	if err := pool.Refresh(context.Background(), node.Client()); err == nil {
+		t.Fatal("want an error for a response missing accounts")
+	}
+}
+
+func TestAMMPoolQuoteReadsStaleState(t *testing.T) {
+	pool, node := loadAMMPool(t, 0, 0)
+	if err := pool.Refresh(context.Background(), node.Client()); err != nil {
+		t.Fatal(err)
+	}
+	quote := func() cosmath.Int {
+		t.Helper()
+		out, err := pool.Quote(context.Background(), node.Client(), pool.BaseMint.String(), cosmath.NewInt(100_000))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	before := quote()
+
+	// Quote trusts state loaded within pkg.MaxStateAge
+	node.Reset()
+	node.SetTokenAccount(pool.QuoteVault, pool.QuoteMint, pool.Owner, 1_500_000)
+	if got := quote(); !got.Equal(before) || node.CallCount("getMultipleAccounts") != 0 {
+		t.Fatalf("got %s after %d reads, want %s without reading", got, node.CallCount("getMultipleAccounts"), before)
+	}
+
+	// and re-reads older state
+	pool.loadedAt = time.Now().Add(-2 * pkg.MaxStateAge)
+	if got := quote(); got.Equal(before) || node.CallCount("getMultipleAccounts") != 1 {
+		t.Fatalf("got %s after %d reads, want a new quote after one", got, node.CallCount("getMultipleAccounts"))
+	}
+}
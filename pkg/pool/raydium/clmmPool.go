@@ -4,9 +4,9 @@ import (
 	"bytes"
 	"context"
 	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"math"
 	"math/big"
 	"strconv"
@@ -16,6 +16,7 @@ import (
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
 	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/sol"
 	"lukechampine.com/uint128"
 )
 
@@ -68,12 +69,14 @@ type CLMMPool struct {
 	Padding2    [32]uint64
 
 	PoolId            solana.PublicKey
+	ProgramID         solana.PublicKey // zero for RAYDIUM_CLMM_PROGRAM_ID
 	FeeRate           uint32
 	ExBitmapAddress   solana.PublicKey
 	exTickArrayBitmap *TickArrayBitmapExtensionType
+	exBitmapExists    bool
 	TickArrayCache    map[string]TickArray
-	UserBaseAccount   solana.PublicKey
-	UserQuoteAccount  solana.PublicKey
+	BaseAmount        cosmath.Int // balance of TokenVault0
+	QuoteAmount       cosmath.Int // balance of TokenVault1
 }
 
 type RewardInfo struct {
@@ -99,10 +102,24 @@ func (pool *CLMMPool) ProtocolType() pkg.ProtocolType {
 }
 
 func (pool *CLMMPool) GetProgramID() solana.PublicKey {
+	if !pool.ProgramID.IsZero() {
+		return pool.ProgramID
+	}
 	return RAYDIUM_CLMM_PROGRAM_ID
 }
 
+var _ pkg.PoolV2 = (*CLMMPool)(nil)
+
+// Capabilities returns the optional features of the pool
+func (pool *CLMMPool) Capabilities() pkg.Capabilities {
+	return pkg.CapExactOut | pkg.CapToken2022 | pkg.CapTickArrays | pkg.CapAccountUpdates | pkg.CapVaults
+}
+
 func (l *CLMMPool) Decode(data []byte) error {
+	if len(data) < int(l.Span()) {
+		return fmt.Errorf("data too short: expected %d bytes, got %d", l.Span(), len(data))
+	}
+
 	// Skip 8 bytes discriminator if present
 	if len(data) > 8 {
 		data = data[8:]
@@ -279,6 +296,8 @@ func (l *CLMMPool) Offset(field string) uint64 {
 		return baseOffset + 1 + 32 + 32 // bump + ammConfig + owner
 	case "TokenMint1":
 		return baseOffset + 1 + 32 + 32 + 32 // bump + ammConfig + owner + tokenMint0
+	case "Liquidity":
+		return baseOffset + 1 + 32*7 + 1 + 1 + 2 // bump + 7 pubkeys + decimals + tickSpacing
 	}
 	return 0
 }
@@ -300,43 +319,66 @@ func (p *CLMMPool) BuildSwapInstructions(
 	inputMint string,
 	amountIn cosmath.Int,
 	minOutAmountWithDecimals cosmath.Int,
+	opts pkg.SwapBuildOptions,
 ) ([]solana.Instruction, error) {
+	if err := pkg.CheckSwapAmounts(amountIn, minOutAmountWithDecimals); err != nil {
+		return nil, err
+	}
+	if err := opts.CheckPoolOptions(); err != nil {
+		return nil, err
+	}
+	pair, err := pkg.PoolPair(p, inputMint)
+	if err != nil {
+		return nil, err
+	}
 
 	// 初始化指令数组和签名者
 	instrs := []solana.Instruction{}
 
-	var inputValueMint solana.PublicKey
-	var outputValueMint solana.PublicKey
-	var inputValue solana.PublicKey
-	var outputValue solana.PublicKey
-	if inputMint == p.TokenMint0.String() {
-		inputValueMint = p.TokenMint0
-		outputValueMint = p.TokenMint1
-		inputValue = p.TokenVault0
-		outputValue = p.TokenVault1
-	} else {
-		inputValueMint = p.TokenMint1
-		outputValueMint = p.TokenMint0
-		inputValue = p.TokenVault1
-		outputValue = p.TokenVault0
-	}
-
-	// Create toAccount if needed
-	var fromAccount solana.PublicKey
-	var toAccount solana.PublicKey
-	if inputValueMint.String() == p.TokenMint0.String() {
-		fromAccount = p.UserBaseAccount
-		toAccount = p.UserQuoteAccount
-	} else {
-		fromAccount = p.UserQuoteAccount
-		toAccount = p.UserBaseAccount
+	inputValueMint, outputValueMint := p.TokenMint0, p.TokenMint1
+	inputValue, outputValue := p.TokenVault0, p.TokenVault1
+	if !pair.BaseIn {
+		inputValueMint, outputValueMint = outputValueMint, inputValueMint
+		inputValue, outputValue = outputValue, inputValue
+	}
+
+	// Resolve user token accounts, creating missing ATAs on the fly unless
+	// opts.SkipCreateAccounts says the user holds them
+	baseAccount, quoteAccount := opts.UserAccounts(pair)
+	userBaseAccount, createBaseInst, err := sol.ResolveUserTokenAccount(ctx, solClient, userAddr, p.TokenMint0, baseAccount, solana.PublicKey{}, opts.SkipCreateAccounts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve token0 account: %w", err)
+	}
+	userQuoteAccount, createQuoteInst, err := sol.ResolveUserTokenAccount(ctx, solClient, userAddr, p.TokenMint1, quoteAccount, solana.PublicKey{}, opts.SkipCreateAccounts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve token1 account: %w", err)
+	}
+	for _, createInst := range []solana.Instruction{createBaseInst, createQuoteInst} {
+		if createInst != nil {
+			instrs = append(instrs, createInst)
+		}
+	}
+
+	fromAccount, toAccount := userBaseAccount, userQuoteAccount
+	if !pair.BaseIn {
+		fromAccount, toAccount = toAccount, fromAccount
+	}
+
+	// Zero lets the program swap up to its price bounds
+	sqrtPriceLimitX64 := uint128.Zero
+	if !opts.SqrtPriceLimitX64.IsNil() && opts.SqrtPriceLimitX64.IsPositive() {
+		if opts.SqrtPriceLimitX64.BigInt().BitLen() > 128 {
+			return nil, fmt.Errorf("sqrt price limit %v overflows 128 bits", opts.SqrtPriceLimitX64)
+		}
+		sqrtPriceLimitX64 = uint128.FromBig(opts.SqrtPriceLimitX64.BigInt())
 	}
 
 	inst := RayCLMMSwapInstruction{
 		Amount:               amountIn.Uint64(),
 		OtherAmountThreshold: minOutAmountWithDecimals.Uint64(),
-		SqrtPriceLimitX64:    uint128.Zero,
-		IsBaseInput:          inputValueMint == p.TokenMint0,
+		SqrtPriceLimitX64:    sqrtPriceLimitX64,
+		IsBaseInput:          pair.BaseIn,
+		Program:              p.GetProgramID(),
 		AccountMetaSlice:     make(solana.AccountMetaSlice, 0),
 	}
 	inst.BaseVariant = bin.BaseVariant{
@@ -360,18 +402,24 @@ func (p *CLMMPool) BuildSwapInstructions(
 		solana.NewAccountMeta(outputValueMint, false, false),       // inputMint
 	)
 
-	// Add bitmap extension as remaining account if it exists
-	exBitmapAddress, _, err := GetPdaExBitmapAccount(RAYDIUM_CLMM_PROGRAM_ID, p.PoolId)
-	if err != nil {
-		log.Printf("get pda address error: %v", err)
-		return nil, fmt.Errorf("get pda address error: %v", err)
+	// The program only accepts the bitmap extension if the account exists
+	if p.exTickArrayBitmap == nil {
+		if err := p.Refresh(ctx, solClient); err != nil {
+			return nil, err
+		}
+	}
+	if p.exBitmapExists {
+		inst.AccountMetaSlice = append(inst.AccountMetaSlice, solana.NewAccountMeta(p.ExBitmapAddress, true, false)) // exTickArrayBitmap (is_writable = true, is_signer = false)
 	}
-	inst.AccountMetaSlice = append(inst.AccountMetaSlice, solana.NewAccountMeta(exBitmapAddress, true, false)) // exTickArrayBitmap (is_writable = true, is_signer = false)
 
 	// Add tick arrays as remaining accounts
-	remainingAccounts, err := p.GetRemainAccounts(ctx, solClient, inputValueMint.String())
+	if p.TickArrayCache == nil {
+		if err := p.FetchPoolTickArrays(ctx, solClient); err != nil {
+			return nil, err
+		}
+	}
+	remainingAccounts, err := p.GetRemainAccounts(inputValueMint.String(), amountIn)
 	if err != nil {
-		log.Printf("GetRemainAccounts error: %v", err)
 		return nil, err
 	}
 
@@ -390,12 +438,13 @@ type RayCLMMSwapInstruction struct {
 	OtherAmountThreshold    uint64
 	SqrtPriceLimitX64       uint128.Uint128
 	IsBaseInput             bool
+	Program                 solana.PublicKey `bin:"-" borsh_skip:"true"`
 	solana.AccountMetaSlice `bin:"-" borsh_skip:"true"`
 }
 
-// ProgramID returns the program ID for the Raydium CLMM program
+// ProgramID returns the program ID of the pool the swap is built for
 func (inst *RayCLMMSwapInstruction) ProgramID() solana.PublicKey {
-	return RAYDIUM_CLMM_PROGRAM_ID
+	return inst.Program
 }
 
 // Accounts returns the account metas for the instruction
@@ -423,13 +472,13 @@ func (inst *RayCLMMSwapInstruction) Data() ([]byte, error) {
 		return nil, fmt.Errorf("failed to encode other amount threshold: %w", err)
 	}
 
-	// Write sqrt price limit x64
-	if err := bin.NewBorshEncoder(buf).WriteUint64(inst.SqrtPriceLimitX64.Hi, binary.LittleEndian); err != nil {
-		return nil, fmt.Errorf("failed to encode sqrt price limit hi: %w", err)
-	}
+	// Write sqrt price limit x64, a little endian u128: low word first
 	if err := bin.NewBorshEncoder(buf).WriteUint64(inst.SqrtPriceLimitX64.Lo, binary.LittleEndian); err != nil {
 		return nil, fmt.Errorf("failed to encode sqrt price limit lo: %w", err)
 	}
+	if err := bin.NewBorshEncoder(buf).WriteUint64(inst.SqrtPriceLimitX64.Hi, binary.LittleEndian); err != nil {
+		return nil, fmt.Errorf("failed to encode sqrt price limit hi: %w", err)
+	}
 
 	// Write is base input
 	if err := bin.NewBorshEncoder(buf).WriteBool(inst.IsBaseInput); err != nil {
@@ -449,57 +498,219 @@ func (pool *CLMMPool) GetTokens() (baseMint, quoteMint string) {
 	return pool.TokenMint0.String(), pool.TokenMint1.String()
 }
 
-func (pool *CLMMPool) Quote(ctx context.Context, solClient *rpc.Client, inputMint string, inputAmount cosmath.Int) (cosmath.Int, error) {
-	// update pool state first
-	results, err := solClient.GetMultipleAccountsWithOpts(ctx,
-		[]solana.PublicKey{pool.ExBitmapAddress},
-		&rpc.GetMultipleAccountsOpts{
-			Commitment: rpc.CommitmentProcessed,
-		},
-	)
+func (pool *CLMMPool) Vaults() (baseVault, quoteVault solana.PublicKey) {
+	return pool.TokenVault0, pool.TokenVault1
+}
+
+// Refresh re-reads the pool state (price, liquidity, current tick, bitmap), the
+// tick array bitmap extension and both vault balances in a single request
+func (pool *CLMMPool) Refresh(ctx context.Context, fetcher pkg.AccountFetcher) error {
+	accounts := pool.WatchedAccounts()
+	results, err := fetcher.GetMultipleAccounts(ctx, accounts...)
 	if err != nil {
-		return cosmath.Int{}, fmt.Errorf("batch request failed: %v", err)
+		return fmt.Errorf("batch request failed: %w", sol.ClassifyError(err))
 	}
-	for _, result := range results.Value {
-		pool.ParseExBitmapInfo(result.Data.GetBinary())
+	if len(results.Value) != len(accounts) || results.Value[0] == nil {
+		return fmt.Errorf("account %v: %w", pool.PoolId.String(), sol.ErrAccountNotFound)
 	}
 
+	if err := pool.ApplyAccount(pool.PoolId, results.Value[0].Data.GetBinary()); err != nil {
+		return err
+	}
+	for i := 2; i < len(accounts); i++ {
+		if results.Value[i] == nil {
+			return fmt.Errorf("account %v: %w", accounts[i].String(), sol.ErrAccountNotFound)
+		}
+		if err := pool.ApplyAccount(accounts[i], results.Value[i].Data.GetBinary()); err != nil {
+			return err
+		}
+	}
+	// Pools that never crossed the default bitmap range have no extension account
+	if results.Value[1] != nil {
+		return pool.ApplyAccount(pool.ExBitmapAddress, results.Value[1].Data.GetBinary())
+	}
+	pool.exBitmapExists = false
+	pool.exTickArrayBitmap = newEmptyExBitmap(pool.PoolId)
+	return nil
+}
+
+// WatchedAccounts returns the pool state, the tick array bitmap extension and
+// both vaults. Tick arrays are still fetched on every quote
+func (pool *CLMMPool) WatchedAccounts() []solana.PublicKey {
+	return []solana.PublicKey{pool.PoolId, pool.ExBitmapAddress, pool.TokenVault0, pool.TokenVault1}
+}
+
+// ApplyAccount updates the pool from new data of the pool state, bitmap
+// extension or one of its vaults
+func (pool *CLMMPool) ApplyAccount(pubkey solana.PublicKey, data []byte) error {
+	switch pubkey {
+	case pool.PoolId:
+		if err := pool.Decode(data); err != nil {
+			return fmt.Errorf("failed to decode pool data: %w", err)
+		}
+	case pool.ExBitmapAddress:
+		if err := pool.ParseExBitmapInfo(data); err != nil {
+			return fmt.Errorf("failed to parse bitmap extension: %w", err)
+		}
+		pool.exBitmapExists = true
+	case pool.TokenVault0:
+		amount, err := sol.ParseTokenAmount(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse token 0 vault: %w", err)
+		}
+		pool.BaseAmount = cosmath.NewIntFromUint64(amount)
+	case pool.TokenVault1:
+		amount, err := sol.ParseTokenAmount(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse token 1 vault: %w", err)
+		}
+		pool.QuoteAmount = cosmath.NewIntFromUint64(amount)
+	default:
+		return fmt.Errorf("account %s is not watched by pool %s", pubkey.String(), pool.PoolId.String())
+	}
+	return nil
+}
+
+// clmmPoolState is CLMMPool without its methods, so it encodes field by field
+type clmmPoolState CLMMPool
+
+// clmmPoolSnapshot adds the bitmap extension, which CLMMPool keeps unexported
+type clmmPoolSnapshot struct {
+	*clmmPoolState
+	ExTickArrayBitmap *TickArrayBitmapExtensionType `json:"exTickArrayBitmap,omitempty"`
+	ExBitmapExists    bool                          `json:"exBitmapExists"`
+}
+
+// MarshalBinary encodes the decoded pool state, bitmap extension, cached tick
+// arrays and vault balances
+func (pool *CLMMPool) MarshalBinary() ([]byte, error) {
+	return json.Marshal(clmmPoolSnapshot{
+		clmmPoolState:     (*clmmPoolState)(pool),
+		ExTickArrayBitmap: pool.exTickArrayBitmap,
+		ExBitmapExists:    pool.exBitmapExists,
+	})
+}
+
+// UnmarshalBinary restores a pool encoded by MarshalBinary
+func (pool *CLMMPool) UnmarshalBinary(data []byte) error {
+	snapshot := clmmPoolSnapshot{clmmPoolState: (*clmmPoolState)(pool)}
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("failed to decode pool snapshot: %w", err)
+	}
+	pool.exTickArrayBitmap = snapshot.ExTickArrayBitmap
+	pool.exBitmapExists = snapshot.ExBitmapExists
+	return nil
+}
+
+// GetFeeRate returns the trade fee of the pool's AMM config, in basis points
+func (pool *CLMMPool) GetFeeRate() (float64, error) {
+	return float64(pool.FeeRate) * 10000 / float64(FEE_RATE_DENOMINATOR.Int64()), nil
+}
+
+// GetLiquidity returns the vault balances, including the liquidity out of the
+// current price range and the protocol and fund fees not yet collected
+func (pool *CLMMPool) GetLiquidity() (pkg.Liquidity, error) {
+	if pool.BaseAmount.IsNil() || pool.QuoteAmount.IsNil() {
+		return pkg.Liquidity{}, pkg.ErrNotLoaded
+	}
+	return pkg.Liquidity{Base: pool.BaseAmount, Quote: pool.QuoteAmount}, nil
+}
+
+func (pool *CLMMPool) Quote(ctx context.Context, fetcher pkg.AccountFetcher, inputMint string, inputAmount cosmath.Int) (cosmath.Int, error) {
+	pair, err := pkg.PoolPair(pool, inputMint)
+	if err != nil {
+		return cosmath.Int{}, err
+	}
+	if pool.exTickArrayBitmap == nil {
+		if err := pool.Refresh(ctx, fetcher); err != nil {
+			return cosmath.Int{}, err
+		}
+	}
+
+	if err := pool.loadTickArrays(ctx, fetcher); err != nil {
+		return cosmath.Int{}, err
+	}
+
+	// The swap math reports the output as the negative change of the pool's
+	// balance; quotes are positive like the other pools'
+	amountOut, err := pool.ComputeAmountOutFormat(pair.In, inputAmount)
+	if err != nil {
+		return cosmath.Int{}, err
+	}
+	return amountOut.Neg(), nil
+}
+
+// QuoteExactOut returns the input amount of inputMint required to receive exactly
+// outputAmount of the other token
+func (pool *CLMMPool) QuoteExactOut(ctx context.Context, fetcher pkg.AccountFetcher, inputMint string, outputAmount cosmath.Int) (cosmath.Int, error) {
+	if !outputAmount.IsPositive() {
+		return cosmath.Int{}, errors.New("output amount must be positive")
+	}
+	pair, err := pkg.PoolPair(pool, inputMint)
+	if err != nil {
+		return cosmath.Int{}, err
+	}
+	if pool.exTickArrayBitmap == nil {
+		if err := pool.Refresh(ctx, fetcher); err != nil {
+			return cosmath.Int{}, err
+		}
+	}
+	if err := pool.loadTickArrays(ctx, fetcher); err != nil {
+		return cosmath.Int{}, err
+	}
+	return pool.ComputeAmountInFormat(pair.In, outputAmount)
+}
+
+// loadTickArrays fetches the initialized tick arrays around the current price
+func (pool *CLMMPool) loadTickArrays(ctx context.Context, fetcher pkg.AccountFetcher) error {
 	tickArrayAddresses, err := pool.GetTickArrayAddresses()
 	if err != nil {
-		return cosmath.Int{}, fmt.Errorf("get tick array address error: %v", err)
+		return fmt.Errorf("get tick array address error: %v", err)
 	}
-	results, err = solClient.GetMultipleAccountsWithOpts(ctx, tickArrayAddresses, &rpc.GetMultipleAccountsOpts{
-		Commitment: rpc.CommitmentProcessed,
-	})
+	results, err := fetcher.GetMultipleAccounts(ctx, tickArrayAddresses...)
 	if err != nil {
-		log.Printf("batch request failed: %v", err)
-		return cosmath.Int{}, fmt.Errorf("batch request failed: %v", err)
+		return fmt.Errorf("batch request failed: %w", sol.ClassifyError(err))
 	}
 	for _, result := range results.Value {
+		if result == nil {
+			continue
+		}
 		tickArray := &TickArray{}
 		err := tickArray.Decode(result.Data.GetBinary())
 		if err != nil {
-			return cosmath.Int{}, fmt.Errorf("failed to decode tick array: %w", err)
+			return fmt.Errorf("failed to decode tick array: %w", err)
 		}
 		if pool.TickArrayCache == nil {
 			pool.TickArrayCache = make(map[string]TickArray)
 		}
 		pool.TickArrayCache[strconv.FormatInt(int64(tickArray.StartTickIndex), 10)] = *tickArray
 	}
+	return nil
+}
 
-	if inputMint == pool.TokenMint0.String() {
-		priceBaseToQuote, err := pool.ComputeAmountOutFormat(pool.TokenMint0.String(), inputAmount)
-		if err != nil {
-			return cosmath.Int{}, err
-		}
-		return priceBaseToQuote.Neg(), nil
-	} else {
-		priceQuoteToBase, err := pool.ComputeAmountOutFormat(pool.TokenMint1.String(), inputAmount)
-		if err != nil {
-			return cosmath.Int{}, err
-		}
-		return priceQuoteToBase.Neg(), nil
+// ComputeAmountInFormat calculates the input amount required for a given output amount
+func (pool *CLMMPool) ComputeAmountInFormat(inputTokenMint string, outputAmount cosmath.Int) (cosmath.Int, error) {
+	zeroForOne := inputTokenMint == pool.TokenMint0.String()
+
+	firstTickArrayStartIndex, _, err := pool.getFirstInitializedTickArray(zeroForOne, pool.exTickArrayBitmap)
+	if err != nil {
+		return cosmath.Int{}, fmt.Errorf("failed to get first initialized tick array: %w", err)
+	}
+
+	// A negative specified amount makes swapCompute run in exact-out mode
+	expectedAmountIn, _, err := pool.swapCompute(
+		int64(pool.TickCurrent),
+		zeroForOne,
+		outputAmount.Neg(),
+		cosmath.NewIntFromUint64(uint64(pool.FeeRate)),
+		firstTickArrayStartIndex,
+		pool.exTickArrayBitmap,
+	)
+	if err != nil {
+		return cosmath.Int{}, fmt.Errorf("failed to compute swap amount: %w", err)
 	}
+
+	return expectedAmountIn, nil
 }
 
 // ComputeAmountOutFormat calculates the expected output amount for a given input amount
@@ -511,7 +722,7 @@ func (pool *CLMMPool) ComputeAmountOutFormat(inputTokenMint string, inputAmount
 		return cosmath.Int{}, fmt.Errorf("failed to get first initialized tick array: %w", err)
 	}
 
-	expectedAmountOut, err := pool.swapCompute(
+	expectedAmountOut, _, err := pool.swapCompute(
 		int64(pool.TickCurrent),
 		zeroForOne,
 		inputAmount,
@@ -534,9 +745,9 @@ func (pool *CLMMPool) swapCompute(
 	fee cosmath.Int,
 	lastSavedTickArrayStartIndex int64,
 	exTickArrayBitmap *TickArrayBitmapExtensionType,
-) (cosmath.Int, error) {
+) (cosmath.Int, []int64, error) {
 	if amountSpecified.IsZero() {
-		return cosmath.Int{}, errors.New("input amount cannot be zero")
+		return cosmath.Int{}, nil, errors.New("input amount cannot be zero")
 	}
 
 	baseInput := amountSpecified.IsPositive()
@@ -562,14 +773,14 @@ func (pool *CLMMPool) swapCompute(
 		tick = lastSavedTickArrayStartIndex
 	}
 
-	// Initialize accounts and liquidity
-	accounts := make([]*solana.PublicKey, 0)
+	// Initialize crossed tick arrays and liquidity
+	tickArrayStartIndexes := []int64{lastSavedTickArrayStartIndex}
 	liquidity := cosmath.NewIntFromBigInt(pool.Liquidity.Big())
 	tickAarrayStartIndex := lastSavedTickArrayStartIndex
 	tickArrayCurrent := pool.TickArrayCache[strconv.FormatInt(lastSavedTickArrayStartIndex, 10)]
 
 	// Set price limits based on direction
-	if baseInput {
+	if zeroForOne {
 		sqrtPriceLimitX64 = MIN_SQRT_PRICE_X64.Add(cosmath.NewInt(1))
 	} else {
 		sqrtPriceLimitX64 = MAX_SQRT_PRICE_X64.Sub(cosmath.NewInt(1))
@@ -587,7 +798,6 @@ func (pool *CLMMPool) swapCompute(
 		tickState := getNextInitTick(&tickArrayCurrent, tick, int64(pool.TickSpacing), zeroForOne, t)
 
 		nextInitTick := tickState
-		tickArrayAddress := &solana.PublicKey{}
 
 		// Handle liquidity crossing
 		if nextInitTick == nil || nextInitTick.LiquidityGross.Big().Cmp(big.NewInt(0)) <= 0 {
@@ -599,28 +809,25 @@ func (pool *CLMMPool) swapCompute(
 				zeroForOne,
 			)
 			if err != nil {
-				return cosmath.Int{}, fmt.Errorf("failed to get next initialized tick array: %w", err)
+				return cosmath.Int{}, nil, fmt.Errorf("failed to get next initialized tick array: %w", err)
 			}
 			if !isExist {
-				return cosmath.Int{}, errors.New("insufficient liquidity")
+				return cosmath.Int{}, nil, fmt.Errorf("no initialized tick array left: %w", pkg.ErrZeroLiquidity)
 			}
 
-			tickAarrayStartIndex := nextInitTickArrayIndex
-			expectedNextTickArrayAddress := getPdaTickArrayAddress(RAYDIUM_CLMM_PROGRAM_ID, pool.PoolId, tickAarrayStartIndex)
-
-			tickArrayAddress = &expectedNextTickArrayAddress
+			tickAarrayStartIndex = nextInitTickArrayIndex
 			tickArrayCurrent = pool.TickArrayCache[strconv.FormatInt(tickAarrayStartIndex, 10)]
 			nextInitTick, err = firstInitializedTick(&tickArrayCurrent, zeroForOne)
 			if err != nil {
-				return cosmath.Int{}, fmt.Errorf("failed to get first initialized tick: %w", err)
+				return cosmath.Int{}, nil, fmt.Errorf("failed to get first initialized tick: %w", err)
 			}
 		}
 
 		// Calculate next tick and price
 		tickNext := int64(nextInitTick.Tick)
 		initialized := nextInitTick.LiquidityGross.Big().Cmp(big.NewInt(0)) > 0
-		if lastSavedTickArrayStartIndex != tickAarrayStartIndex && tickArrayAddress != nil {
-			accounts = append(accounts, tickArrayAddress)
+		if lastSavedTickArrayStartIndex != tickAarrayStartIndex {
+			tickArrayStartIndexes = append(tickArrayStartIndexes, tickAarrayStartIndex)
 			lastSavedTickArrayStartIndex = tickAarrayStartIndex
 		}
 
@@ -633,7 +840,7 @@ func (pool *CLMMPool) swapCompute(
 
 		sqrtPriceNextX64, err := getSqrtPriceX64FromTick(int64(tickNext))
 		if err != nil {
-			return cosmath.Int{}, fmt.Errorf("failed to get sqrt price from tick: %w", err)
+			return cosmath.Int{}, nil, fmt.Errorf("failed to get sqrt price from tick: %w", err)
 		}
 
 		// Calculate target price
@@ -646,7 +853,7 @@ func (pool *CLMMPool) swapCompute(
 		}
 
 		// Calculate swap step
-		sqrtPriceX64, amountIn, amountOut, feeAmount = swapStepCompute(
+		sqrtPriceX64, amountIn, amountOut, feeAmount, err = swapStepCompute(
 			sqrtPriceX64.BigInt(),
 			targetPrice.BigInt(),
 			liquidity.BigInt(),
@@ -654,6 +861,9 @@ func (pool *CLMMPool) swapCompute(
 			uint32(fee.Int64()),
 			zeroForOne,
 		)
+		if err != nil {
+			return cosmath.Int{}, nil, fmt.Errorf("failed to compute swap step: %w", err)
+		}
 
 		// Update amounts
 		if baseInput {
@@ -682,7 +892,7 @@ func (pool *CLMMPool) swapCompute(
 		} else if sqrtPriceX64 != sqrtPriceStartX64 {
 			_T, err := getTickFromSqrtPriceX64(sqrtPriceX64)
 			if err != nil {
-				return cosmath.Int{}, fmt.Errorf("failed to get tick from sqrt price: %w", err)
+				return cosmath.Int{}, nil, fmt.Errorf("failed to get tick from sqrt price: %w", err)
 			}
 			t = _T != tick && !zeroForOne && int64(tickArrayCurrent.StartTickIndex) == _T
 			tick = _T
@@ -691,42 +901,63 @@ func (pool *CLMMPool) swapCompute(
 		// Safety check for infinite loops
 		loop++
 		if loop > 100 {
-			return cosmath.Int{}, errors.New("swap computation exceeded maximum iterations")
+			return cosmath.Int{}, nil, errors.New("swap computation exceeded maximum iterations")
 		}
 	}
 
-	return amountCalculated, nil
+	return amountCalculated, tickArrayStartIndexes, nil
 }
 
-// GetRemainAccounts returns the remaining accounts needed for the swap
-func (pool *CLMMPool) GetRemainAccounts(
-	ctx context.Context,
-	client *rpc.Client,
-	inputTokenMint string,
-) ([]solana.PublicKey, error) {
+// maxSwapTickArrays caps the tick arrays passed to a swap, keeping the
+// transaction within the account limit
+const maxSwapTickArrays = 5
+
+// GetRemainAccounts returns the tick arrays the swap will cross, starting with
+// the one holding the current price, followed by the next initialized array as a
+// buffer against price movement before the transaction lands
+func (pool *CLMMPool) GetRemainAccounts(inputTokenMint string, amountIn cosmath.Int) ([]solana.PublicKey, error) {
 	// Determine swap direction
 	zeroForOne := inputTokenMint == pool.TokenMint0.String()
 
 	// Get first initialized tick array
-	_, firstTickArray, err := pool.getFirstInitializedTickArray(zeroForOne, pool.exTickArrayBitmap)
+	firstTickArrayStartIndex, _, err := pool.getFirstInitializedTickArray(zeroForOne, pool.exTickArrayBitmap)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get first tick array: %w", err)
 	}
 
-	allNeededAccounts := make([]solana.PublicKey, 0)
-	allNeededAccounts = append(allNeededAccounts, firstTickArray)
-
-	// Get next tick array
-	tickAarrayStartIndex, _ := nextInitializedTickArray(
+	tickArrayStartIndexes := []int64{firstTickArrayStartIndex}
+	_, crossed, err := pool.swapCompute(
 		int64(pool.TickCurrent),
-		int64(pool.TickSpacing),
 		zeroForOne,
-		pool.TickArrayBitmap,
+		amountIn,
+		cosmath.NewIntFromUint64(uint64(pool.FeeRate)),
+		firstTickArrayStartIndex,
+		pool.exTickArrayBitmap,
+	)
+	if err == nil {
+		tickArrayStartIndexes = crossed
+	}
+
+	// Append the next initialized tick array after the last one crossed
+	last := tickArrayStartIndexes[len(tickArrayStartIndexes)-1]
+	isExist, nextStartIndex, err := nextInitializedTickArrayStartIndexUtils(
 		pool.exTickArrayBitmap,
+		last,
+		int64(pool.TickSpacing),
+		pool.TickArrayBitmap,
+		zeroForOne,
 	)
+	if err == nil && isExist && nextStartIndex != last {
+		tickArrayStartIndexes = append(tickArrayStartIndexes, nextStartIndex)
+	}
 
-	exTickArrayBitmapAddress := getPdaTickArrayAddress(RAYDIUM_CLMM_PROGRAM_ID, pool.PoolId, tickAarrayStartIndex)
-	allNeededAccounts = append(allNeededAccounts, exTickArrayBitmapAddress)
+	if len(tickArrayStartIndexes) > maxSwapTickArrays {
+		tickArrayStartIndexes = tickArrayStartIndexes[:maxSwapTickArrays]
+	}
 
+	allNeededAccounts := make([]solana.PublicKey, 0, len(tickArrayStartIndexes))
+	for _, startIndex := range tickArrayStartIndexes {
+		allNeededAccounts = append(allNeededAccounts, getPdaTickArrayAddress(pool.GetProgramID(), pool.PoolId, startIndex))
+	}
 	return allNeededAccounts, nil
 }
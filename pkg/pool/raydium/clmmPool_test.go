@@ -0,0 +1,33 @@
+package raydium
+
+import (
+	"bytes"
+	"testing"
+
+	"lukechampine.com/uint128"
+)
+
+func TestRayCLMMSwapInstructionData(t *testing.T) {
+	inst := RayCLMMSwapInstruction{
+		Amount:               1_000_000,
+		OtherAmountThreshold: 990_000,
+		SqrtPriceLimitX64:    uint128.New(0x0807060504030201, 0x100f0e0d0c0b0a09),
+		IsBaseInput:          true,
+	}
+	data, err := inst.Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 41 {
+		t.Fatalf("got %d bytes of data, want 41", len(data))
+	}
+
+	// Borsh writes a u128 little endian, low word first
+	want := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+	if got := data[24:40]; !bytes.Equal(got, want) {
+		t.Errorf("sqrt price limit encoded as %v, want %v", got, want)
+	}
+	if data[40] != 1 {
+		t.Errorf("is base input encoded as %d, want 1", data[40])
+	}
+}
@@ -12,7 +12,7 @@ import (
 	cosmath "cosmossdk.io/math"
 	bin "github.com/gagliardetto/binary"
 	"github.com/gagliardetto/solana-go"
-	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/yimingWOW/solroute/pkg"
 	"lukechampine.com/uint128"
 )
 
@@ -42,8 +42,17 @@ type TickState struct {
 	_                       [52]byte           `bin:"skip"` // padding
 }
 
+// Account data sizes checked before manual decoding
+const (
+	tickArrayDataSize = 8 + 32 + 4 + TICK_ARRAY_SIZE*TickSize + 1
+	exBitmapDataSize  = 8 + 32 + 2*EXTENSION_TICKARRAY_BITMAP_SIZE*64
+)
+
 // Decode decodes the tick array data
 func (t *TickArray) Decode(data []byte) error {
+	if len(data) < tickArrayDataSize {
+		return fmt.Errorf("data too short: expected %d bytes, got %d", tickArrayDataSize, len(data))
+	}
 	decoder := bin.NewBinDecoder(data)
 
 	// Decode initial padding
@@ -111,19 +120,19 @@ func (p *CLMMPool) GetTickArrayAddresses() ([]solana.PublicKey, error) {
 	startIndexArray := p.getInitializedTickArrayInRange(10) // Get 10 tick arrays
 	tickArrayAddresses := make([]solana.PublicKey, 0, len(startIndexArray))
 	for _, itemIndex := range startIndexArray {
-		tickArrayAddress := getPdaTickArrayAddress(RAYDIUM_CLMM_PROGRAM_ID, p.PoolId, itemIndex)
+		tickArrayAddress := getPdaTickArrayAddress(p.GetProgramID(), p.PoolId, itemIndex)
 		tickArrayAddresses = append(tickArrayAddresses, tickArrayAddress)
 	}
 	return tickArrayAddresses, nil
 }
 
 // FetchPoolTickArrays fetches tick arrays for the pool
-func (p *CLMMPool) FetchPoolTickArrays(ctx context.Context, client *rpc.Client) error {
+func (p *CLMMPool) FetchPoolTickArrays(ctx context.Context, fetcher pkg.AccountFetcher) error {
 	tickArrayAddresses, err := p.GetTickArrayAddresses()
 	if err != nil {
 		return fmt.Errorf("get tick array address error: %v", err)
 	}
-	accounts, err := client.GetMultipleAccounts(ctx, tickArrayAddresses...)
+	accounts, err := fetcher.GetMultipleAccounts(ctx, tickArrayAddresses...)
 	if err != nil {
 		return fmt.Errorf("get accounts error: %v", err)
 	}
@@ -145,7 +154,10 @@ func (p *CLMMPool) FetchPoolTickArrays(ctx context.Context, client *rpc.Client)
 }
 
 // ParseExBitmapInfo parses the extended bitmap information
-func (p *CLMMPool) ParseExBitmapInfo(data []byte) {
+func (p *CLMMPool) ParseExBitmapInfo(data []byte) error {
+	if len(data) < exBitmapDataSize {
+		return fmt.Errorf("data too short: expected %d bytes, got %d", exBitmapDataSize, len(data))
+	}
 	var bitmap TickArrayBitmapExtensionType
 
 	// Skip 8-byte discriminator
@@ -180,6 +192,21 @@ func (p *CLMMPool) ParseExBitmapInfo(data []byte) {
 	bitmap.NegativeTickArrayBitmap = negativeBitmaps
 
 	p.exTickArrayBitmap = &bitmap
+	return nil
+}
+
+// newEmptyExBitmap returns an extension with no initialized tick arrays
+func newEmptyExBitmap(poolId solana.PublicKey) *TickArrayBitmapExtensionType {
+	bitmap := &TickArrayBitmapExtensionType{
+		PoolId:                  poolId,
+		PositiveTickArrayBitmap: make([][]uint64, EXTENSION_TICKARRAY_BITMAP_SIZE),
+		NegativeTickArrayBitmap: make([][]uint64, EXTENSION_TICKARRAY_BITMAP_SIZE),
+	}
+	for i := 0; i < EXTENSION_TICKARRAY_BITMAP_SIZE; i++ {
+		bitmap.PositiveTickArrayBitmap[i] = make([]uint64, 8)
+		bitmap.NegativeTickArrayBitmap[i] = make([]uint64, 8)
+	}
+	return bitmap
 }
 
 // getInitializedTickArrayInRange returns initialized tick arrays in range
@@ -452,12 +479,15 @@ func nextInitializedTickArrayStartIndexUtils(exTickArrayBitmap *TickArrayBitmapE
 
 	// eslint-disable-next-line no-constant-condition
 	for {
-		startIsInit, startIndex := nextInitializedTickArrayStartIndex(
+		startIsInit, startIndex, err := nextInitializedTickArrayStartIndex(
 			MergeTickArrayBitmap(tickArrayBitmap[:]),
 			int64(lastTickArrayStartIndex),
 			int64(tickSpacing),
 			zeroForOne,
 		)
+		if err != nil {
+			return false, 0, err
+		}
 		if startIsInit {
 			return true, startIndex, nil
 		}
@@ -535,10 +565,10 @@ func nextInitializedTickArrayFromOneBitmap(lastTickArrayStartIndex, tickSpacing
 
 // nextInitializedTickArrayStartIndex 获取下一个初始化的 tick array 起始索引
 func nextInitializedTickArrayStartIndex(bitMap *big.Int,
-	lastTickArrayStartIndex int64, tickSpacing int64, zeroForOne bool) (bool, int64) {
+	lastTickArrayStartIndex int64, tickSpacing int64, zeroForOne bool) (bool, int64, error) {
 
 	if !checkIsValidStartIndex(lastTickArrayStartIndex, tickSpacing) {
-		panic("invalid start index")
+		return false, 0, ErrInvalidTickArrayStartIndex
 	}
 
 	tickBoundary := maxTickInTickarrayBitmap(tickSpacing)
@@ -550,7 +580,7 @@ func nextInitializedTickArrayStartIndex(bitMap *big.Int,
 	}
 
 	if nextTickArrayStartIndex < -tickBoundary || nextTickArrayStartIndex >= tickBoundary {
-		return false, lastTickArrayStartIndex
+		return false, lastTickArrayStartIndex, nil
 	}
 
 	multiplier := int64(tickSpacing) * TICK_ARRAY_SIZE
@@ -569,9 +599,9 @@ func nextInitializedTickArrayStartIndex(bitMap *big.Int,
 		nextBit := MostSignificantBit(1024, offsetBitMap)
 		if nextBit != nil {
 			nextArrayStartIndex := int64(bitPos-*nextBit-512) * multiplier
-			return true, nextArrayStartIndex
+			return true, nextArrayStartIndex, nil
 		} else {
-			return false, -tickBoundary
+			return false, -tickBoundary, nil
 		}
 	} else {
 		// 向上搜索
@@ -580,9 +610,9 @@ func nextInitializedTickArrayStartIndex(bitMap *big.Int,
 		nextBit := LeastSignificantBit(1024, offsetBitMap)
 		if nextBit != nil {
 			nextArrayStartIndex := int64(bitPos+*nextBit-512) * multiplier
-			return true, nextArrayStartIndex
+			return true, nextArrayStartIndex, nil
 		}
-		return false, tickBoundary - getTickCount(int64(tickSpacing))
+		return false, tickBoundary - getTickCount(int64(tickSpacing)), nil
 	}
 }
 
@@ -797,7 +827,7 @@ func (poolInfo *CLMMPool) getFirstInitializedTickArray(zeroForOne bool, exTickAr
 	if isInitialized {
 		// 3. 如果已初始化，获取其 PDA 地址
 		address := getPdaTickArrayAddress(
-			RAYDIUM_CLMM_PROGRAM_ID,
+			poolInfo.GetProgramID(),
 			poolInfo.PoolId,
 			startIndex,
 		)
@@ -817,7 +847,7 @@ func (poolInfo *CLMMPool) getFirstInitializedTickArray(zeroForOne bool, exTickAr
 	}
 	if isExist {
 		address := getPdaTickArrayAddress(
-			RAYDIUM_CLMM_PROGRAM_ID,
+			poolInfo.GetProgramID(),
 			poolInfo.PoolId,
 			nextStartIndex,
 		)
@@ -907,21 +937,15 @@ const (
 var (
 	MaxUint128    = new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 128), big.NewInt(1))
 	MaxUint128Int = cosmath.NewIntFromBigInt(MaxUint128)
+	pow64Int      = cosmath.NewIntFromBigInt(new(big.Int).Lsh(big.NewInt(1), 64))
 )
 
 func mulRightShift(val, mulBy cosmath.Int) cosmath.Int {
 	// 先乘法
 	result := val.Mul(mulBy)
 
-	// 然后右移 64 位
-	// 2^64 = 18446744073709551616
-	pow64Big, ok := cosmath.NewIntFromString("18446744073709551616")
-	if !ok {
-		panic("failed to create pow64Big")
-	}
-
 	// 除以 2^64 相当于右移 64 位
-	return result.Quo(pow64Big)
+	return result.Quo(pow64Int)
 }
 
 // getSqrtPriceX64FromTick calculates the sqrt price from a tick value
@@ -1128,7 +1152,7 @@ func swapStepCompute(
 	amountRemaining *big.Int,
 	feeRate uint32,
 	zeroForOne bool,
-) (cosmath.Int, cosmath.Int, cosmath.Int, cosmath.Int) {
+) (cosmath.Int, cosmath.Int, cosmath.Int, cosmath.Int, error) {
 
 	swapStep := &SwapStep{
 		SqrtPriceX64Next: new(big.Int),
@@ -1137,34 +1161,53 @@ func swapStepCompute(
 		FeeAmount:        new(big.Int),
 	}
 
+	var err error
 	zero := new(big.Int)
 	baseInput := amountRemaining.Cmp(zero) >= 0
 
 	if baseInput {
 		feeRateBig := cosmath.NewInt(int64(feeRate))
 		tmp := FEE_RATE_DENOMINATOR.Sub(feeRateBig)
-		amountRemainingSubtractFee := mulDivFloor(cosmath.NewIntFromBigInt(amountRemaining), tmp, FEE_RATE_DENOMINATOR)
+		amountRemainingSubtractFee, err := mulDivFloor(cosmath.NewIntFromBigInt(amountRemaining), tmp, FEE_RATE_DENOMINATOR)
+		if err != nil {
+			return cosmath.Int{}, cosmath.Int{}, cosmath.Int{}, cosmath.Int{}, err
+		}
 		if zeroForOne {
-			swapStep.AmountIn = getTokenAmountAFromLiquidity(sqrtPriceX64Target, sqrtPriceX64Current, liquidity, true)
+			swapStep.AmountIn, err = getTokenAmountAFromLiquidity(sqrtPriceX64Target, sqrtPriceX64Current, liquidity, true)
+			if err != nil {
+				return cosmath.Int{}, cosmath.Int{}, cosmath.Int{}, cosmath.Int{}, err
+			}
 		} else {
-			swapStep.AmountIn = getTokenAmountBFromLiquidity(sqrtPriceX64Current, sqrtPriceX64Target, liquidity, true)
+			swapStep.AmountIn, err = getTokenAmountBFromLiquidity(sqrtPriceX64Current, sqrtPriceX64Target, liquidity, true)
+			if err != nil {
+				return cosmath.Int{}, cosmath.Int{}, cosmath.Int{}, cosmath.Int{}, err
+			}
 		}
 
 		if amountRemainingSubtractFee.GTE(cosmath.NewIntFromBigInt(swapStep.AmountIn)) {
 			swapStep.SqrtPriceX64Next.Set(sqrtPriceX64Target)
 		} else {
-			swapStep.SqrtPriceX64Next = getNextSqrtPriceX64FromInput(
+			swapStep.SqrtPriceX64Next, err = getNextSqrtPriceX64FromInput(
 				sqrtPriceX64Current,
 				liquidity,
 				amountRemainingSubtractFee.BigInt(),
 				zeroForOne,
 			)
+			if err != nil {
+				return cosmath.Int{}, cosmath.Int{}, cosmath.Int{}, cosmath.Int{}, err
+			}
 		}
 	} else {
 		if zeroForOne {
-			swapStep.AmountOut = getTokenAmountBFromLiquidity(sqrtPriceX64Target, sqrtPriceX64Current, liquidity, false)
+			swapStep.AmountOut, err = getTokenAmountBFromLiquidity(sqrtPriceX64Target, sqrtPriceX64Current, liquidity, false)
+			if err != nil {
+				return cosmath.Int{}, cosmath.Int{}, cosmath.Int{}, cosmath.Int{}, err
+			}
 		} else {
-			swapStep.AmountOut = getTokenAmountAFromLiquidity(sqrtPriceX64Current, sqrtPriceX64Target, liquidity, false)
+			swapStep.AmountOut, err = getTokenAmountAFromLiquidity(sqrtPriceX64Current, sqrtPriceX64Target, liquidity, false)
+			if err != nil {
+				return cosmath.Int{}, cosmath.Int{}, cosmath.Int{}, cosmath.Int{}, err
+			}
 		}
 
 		negativeOne := new(big.Int).SetInt64(-1)
@@ -1173,12 +1216,15 @@ func swapStepCompute(
 		if amountRemainingNeg.Cmp(swapStep.AmountOut) >= 0 {
 			swapStep.SqrtPriceX64Next.Set(sqrtPriceX64Target)
 		} else {
-			swapStep.SqrtPriceX64Next = getNextSqrtPriceX64FromOutput(
+			swapStep.SqrtPriceX64Next, err = getNextSqrtPriceX64FromOutput(
 				sqrtPriceX64Current,
 				liquidity,
 				amountRemainingNeg,
 				zeroForOne,
 			)
+			if err != nil {
+				return cosmath.Int{}, cosmath.Int{}, cosmath.Int{}, cosmath.Int{}, err
+			}
 		}
 	}
 
@@ -1186,43 +1232,55 @@ func swapStepCompute(
 
 	if zeroForOne {
 		if !(reachTargetPrice && baseInput) {
-			swapStep.AmountIn = getTokenAmountAFromLiquidity(
+			swapStep.AmountIn, err = getTokenAmountAFromLiquidity(
 				swapStep.SqrtPriceX64Next,
 				sqrtPriceX64Current,
 				liquidity,
 				true,
 			)
+			if err != nil {
+				return cosmath.Int{}, cosmath.Int{}, cosmath.Int{}, cosmath.Int{}, err
+			}
 		}
 
 		if !(reachTargetPrice && !baseInput) {
-			swapStep.AmountOut = getTokenAmountBFromLiquidity(
+			swapStep.AmountOut, err = getTokenAmountBFromLiquidity(
 				swapStep.SqrtPriceX64Next,
 				sqrtPriceX64Current,
 				liquidity,
 				false,
 			)
+			if err != nil {
+				return cosmath.Int{}, cosmath.Int{}, cosmath.Int{}, cosmath.Int{}, err
+			}
 		}
 	} else {
 		if reachTargetPrice && baseInput {
 			// Keep existing amountIn
 		} else {
-			swapStep.AmountIn = getTokenAmountBFromLiquidity(
+			swapStep.AmountIn, err = getTokenAmountBFromLiquidity(
 				sqrtPriceX64Current,
 				swapStep.SqrtPriceX64Next,
 				liquidity,
 				true,
 			)
+			if err != nil {
+				return cosmath.Int{}, cosmath.Int{}, cosmath.Int{}, cosmath.Int{}, err
+			}
 		}
 
 		if reachTargetPrice && !baseInput {
 			// Keep existing amountOut
 		} else {
-			swapStep.AmountOut = getTokenAmountAFromLiquidity(
+			swapStep.AmountOut, err = getTokenAmountAFromLiquidity(
 				sqrtPriceX64Current,
 				swapStep.SqrtPriceX64Next,
 				liquidity,
 				false,
 			)
+			if err != nil {
+				return cosmath.Int{}, cosmath.Int{}, cosmath.Int{}, cosmath.Int{}, err
+			}
 		}
 	}
 
@@ -1243,7 +1301,7 @@ func swapStepCompute(
 	}
 
 	return cosmath.NewIntFromBigInt(swapStep.SqrtPriceX64Next), cosmath.NewIntFromBigInt(swapStep.AmountIn),
-		cosmath.NewIntFromBigInt(swapStep.AmountOut), cosmath.NewIntFromBigInt(swapStep.FeeAmount)
+		cosmath.NewIntFromBigInt(swapStep.AmountOut), cosmath.NewIntFromBigInt(swapStep.FeeAmount), nil
 }
 
 // Helper function for ceiling division
@@ -1265,7 +1323,7 @@ func getTokenAmountAFromLiquidity(
 	sqrtPriceX64B *big.Int,
 	liquidity *big.Int,
 	roundUp bool,
-) *big.Int {
+) (*big.Int, error) {
 	// Create copies to avoid modifying the original values
 	priceA := new(big.Int).Set(sqrtPriceX64A)
 	priceB := new(big.Int).Set(sqrtPriceX64B)
@@ -1277,7 +1335,7 @@ func getTokenAmountAFromLiquidity(
 
 	// Check if priceA > 0
 	if priceA.Cmp(big.NewInt(0)) <= 0 {
-		panic("sqrtPriceX64A must be greater than 0")
+		return nil, ErrInvalidSqrtPrice
 	}
 
 	// Calculate numerator1 = liquidity << U64Resolution
@@ -1290,12 +1348,15 @@ func getTokenAmountAFromLiquidity(
 		// First calculate mulDivCeil(numerator1, numerator2, priceB)
 		temp := mulDivCeil(cosmath.NewIntFromBigInt(numerator1), cosmath.NewIntFromBigInt(numerator2), cosmath.NewIntFromBigInt(priceB))
 		// Then calculate mulDivCeil(temp, 1, priceA)
-		return mulDivCeil(temp, cosmath.NewIntFromBigInt(big.NewInt(1)), cosmath.NewIntFromBigInt(priceA)).BigInt()
+		return mulDivCeil(temp, cosmath.NewIntFromBigInt(big.NewInt(1)), cosmath.NewIntFromBigInt(priceA)).BigInt(), nil
 	} else {
 		// Calculate mulDivFloor(numerator1, numerator2, priceB)
-		temp := mulDivFloor(cosmath.NewIntFromBigInt(numerator1), cosmath.NewIntFromBigInt(numerator2), cosmath.NewIntFromBigInt(priceB))
+		temp, err := mulDivFloor(cosmath.NewIntFromBigInt(numerator1), cosmath.NewIntFromBigInt(numerator2), cosmath.NewIntFromBigInt(priceB))
+		if err != nil {
+			return nil, err
+		}
 		// Then divide by priceA
-		return temp.Quo(cosmath.NewIntFromBigInt(priceA)).BigInt()
+		return temp.Quo(cosmath.NewIntFromBigInt(priceA)).BigInt(), nil
 	}
 }
 
@@ -1305,7 +1366,7 @@ func getTokenAmountBFromLiquidity(
 	sqrtPriceX64B *big.Int,
 	liquidity *big.Int,
 	roundUp bool,
-) *big.Int {
+) (*big.Int, error) {
 	// Create copies to avoid modifying the original values
 	priceA := new(big.Int).Set(sqrtPriceX64A)
 	priceB := new(big.Int).Set(sqrtPriceX64B)
@@ -1317,27 +1378,31 @@ func getTokenAmountBFromLiquidity(
 
 	// Check if priceA > 0
 	if priceA.Cmp(big.NewInt(0)) <= 0 {
-		panic("sqrtPriceX64A must be greater than 0")
+		return nil, ErrInvalidSqrtPrice
 	}
 
 	// Calculate price difference
 	priceDiff := new(big.Int).Sub(priceB, priceA)
 
 	if roundUp {
-		return mulDivCeil(cosmath.NewIntFromBigInt(liquidity), cosmath.NewIntFromBigInt(priceDiff), cosmath.NewIntFromBigInt(new(big.Int).Lsh(big.NewInt(1), U64Resolution))).BigInt()
+		return mulDivCeil(cosmath.NewIntFromBigInt(liquidity), cosmath.NewIntFromBigInt(priceDiff), cosmath.NewIntFromBigInt(new(big.Int).Lsh(big.NewInt(1), U64Resolution))).BigInt(), nil
 	} else {
-		return mulDivFloor(cosmath.NewIntFromBigInt(liquidity), cosmath.NewIntFromBigInt(priceDiff), cosmath.NewIntFromBigInt(new(big.Int).Lsh(big.NewInt(1), U64Resolution))).BigInt()
+		amount, err := mulDivFloor(cosmath.NewIntFromBigInt(liquidity), cosmath.NewIntFromBigInt(priceDiff), cosmath.NewIntFromBigInt(new(big.Int).Lsh(big.NewInt(1), U64Resolution)))
+		if err != nil {
+			return nil, err
+		}
+		return amount.BigInt(), nil
 	}
 }
 
 // mulDivFloor performs multiplication and division with floor rounding
-func mulDivFloor(a, b, denominator cosmath.Int) cosmath.Int {
+func mulDivFloor(a, b, denominator cosmath.Int) (cosmath.Int, error) {
 	if denominator.IsZero() {
-		panic("division by zero")
+		return cosmath.Int{}, ErrDivisionByZero
 	}
 
 	numerator := a.Mul(b)
-	return numerator.Quo(denominator)
+	return numerator.Quo(denominator), nil
 }
 
 func getNextSqrtPriceX64FromInput(
@@ -1345,17 +1410,17 @@ func getNextSqrtPriceX64FromInput(
 	liquidity *big.Int,
 	amount *big.Int,
 	zeroForOne bool,
-) *big.Int {
+) (*big.Int, error) {
 
 	if sqrtPriceX64Current.Cmp(big.NewInt(0)) <= 0 {
-		panic("sqrtPriceX64Current must be greater than 0")
+		return nil, ErrInvalidSqrtPrice
 	}
 	if liquidity.Cmp(big.NewInt(0)) <= 0 {
-		panic("liquidity must be greater than 0")
+		return nil, ErrZeroLiquidity
 	}
 
 	if amount.Cmp(big.NewInt(0)) == 0 {
-		return sqrtPriceX64Current
+		return sqrtPriceX64Current, nil
 	}
 
 	if zeroForOne {
@@ -1371,12 +1436,12 @@ func getNextSqrtPriceX64FromOutput(
 	liquidity *big.Int,
 	amount *big.Int,
 	zeroForOne bool,
-) *big.Int {
+) (*big.Int, error) {
 	if sqrtPriceX64Current.Cmp(big.NewInt(0)) <= 0 {
-		panic("sqrtPriceX64Current must be greater than 0")
+		return nil, ErrInvalidSqrtPrice
 	}
 	if liquidity.Cmp(big.NewInt(0)) <= 0 {
-		panic("liquidity must be greater than 0")
+		return nil, ErrZeroLiquidity
 	}
 
 	if zeroForOne {
@@ -1391,10 +1456,10 @@ func getNextSqrtPriceFromTokenAmountARoundingUp(
 	liquidity *big.Int,
 	amount *big.Int,
 	add bool,
-) *big.Int {
+) (*big.Int, error) {
 
 	if amount.Cmp(big.NewInt(0)) == 0 {
-		return sqrtPriceX64
+		return sqrtPriceX64, nil
 	}
 
 	liquidityLeftShift := new(big.Int).Lsh(liquidity, U64Resolution)
@@ -1403,19 +1468,19 @@ func getNextSqrtPriceFromTokenAmountARoundingUp(
 		numerator1 := liquidityLeftShift
 		denominator := new(big.Int).Add(liquidityLeftShift, new(big.Int).Mul(amount, sqrtPriceX64))
 		if denominator.Cmp(numerator1) >= 0 {
-			return mulDivCeil(cosmath.NewIntFromBigInt(numerator1), cosmath.NewIntFromBigInt(sqrtPriceX64), cosmath.NewIntFromBigInt(denominator)).BigInt()
+			return mulDivCeil(cosmath.NewIntFromBigInt(numerator1), cosmath.NewIntFromBigInt(sqrtPriceX64), cosmath.NewIntFromBigInt(denominator)).BigInt(), nil
 		}
 
 		temp := new(big.Int).Div(numerator1, sqrtPriceX64)
 		temp.Add(temp, amount)
-		return mulDivRoundingUp(numerator1, big.NewInt(1), temp)
+		return mulDivRoundingUp(numerator1, big.NewInt(1), temp), nil
 	} else {
 		amountMulSqrtPrice := new(big.Int).Mul(amount, sqrtPriceX64)
 		if liquidityLeftShift.Cmp(amountMulSqrtPrice) <= 0 {
-			panic("getNextSqrtPriceFromTokenAmountARoundingUp: liquidityLeftShift must be greater than amountMulSqrtPrice")
+			return nil, ErrPriceOutOfRange
 		}
 		denominator := new(big.Int).Sub(liquidityLeftShift, amountMulSqrtPrice)
-		return mulDivCeil(cosmath.NewIntFromBigInt(liquidityLeftShift), cosmath.NewIntFromBigInt(sqrtPriceX64), cosmath.NewIntFromBigInt(denominator)).BigInt()
+		return mulDivCeil(cosmath.NewIntFromBigInt(liquidityLeftShift), cosmath.NewIntFromBigInt(sqrtPriceX64), cosmath.NewIntFromBigInt(denominator)).BigInt(), nil
 	}
 }
 
@@ -1425,17 +1490,17 @@ func getNextSqrtPriceFromTokenAmountBRoundingDown(
 	liquidity *big.Int,
 	amount *big.Int,
 	add bool,
-) *big.Int {
+) (*big.Int, error) {
 	deltaY := new(big.Int).Lsh(amount, U64Resolution)
 
 	if add {
-		return new(big.Int).Add(sqrtPriceX64, new(big.Int).Div(deltaY, liquidity))
+		return new(big.Int).Add(sqrtPriceX64, new(big.Int).Div(deltaY, liquidity)), nil
 	} else {
 		amountDivLiquidity := mulDivRoundingUp(deltaY, big.NewInt(1), liquidity)
 		if sqrtPriceX64.Cmp(amountDivLiquidity) <= 0 {
-			panic("getNextSqrtPriceFromTokenAmountBRoundingDown: sqrtPriceX64 must be greater than amountDivLiquidity")
+			return nil, ErrPriceOutOfRange
 		}
-		return new(big.Int).Sub(sqrtPriceX64, amountDivLiquidity)
+		return new(big.Int).Sub(sqrtPriceX64, amountDivLiquidity), nil
 	}
 }
 
@@ -1443,7 +1508,7 @@ func getNextSqrtPriceFromTokenAmountBRoundingDown(
 func mulDivRoundingUp(a, b, denominator *big.Int) *big.Int {
 	numerator := new(big.Int).Mul(a, b)
 	result := new(big.Int).Div(numerator, denominator)
-	if !new(big.Int).Mod(numerator, denominator).IsInt64() {
+	if new(big.Int).Mod(numerator, denominator).Sign() != 0 {
 		result.Add(result, big.NewInt(1))
 	}
 	return result
@@ -1,10 +1,12 @@
 package raydium
 
 import (
+	"errors"
 	"math/big"
 
 	"cosmossdk.io/math"
 	"github.com/gagliardetto/solana-go"
+	"github.com/yimingWOW/solroute/pkg"
 )
 
 // Program IDs
@@ -19,6 +21,15 @@ var (
 	RAYDIUM_CLMM_PROGRAM_ID = solana.MustPublicKeyFromBase58("CAMMCzo5YL8w4VFF8KVHrK22GGUsp5VTaW7grrKgrWqK")
 )
 
+// Math errors. A pool returning one of these has inconsistent state and is skipped
+var (
+	ErrInvalidSqrtPrice           = errors.New("sqrt price must be greater than 0")
+	ErrZeroLiquidity              = pkg.ErrZeroLiquidity
+	ErrDivisionByZero             = errors.New("division by zero")
+	ErrPriceOutOfRange            = errors.New("amount moves the price out of range")
+	ErrInvalidTickArrayStartIndex = errors.New("invalid tick array start index")
+)
+
 // Tick Array Configuration
 const (
 	TICK_ARRAY_SIZE                 = 60
@@ -43,6 +54,11 @@ var (
 	LIQUIDITY_FEES_DENOMINATOR = math.NewInt(10000)
 )
 
+// feeBps converts a fee fraction to basis points
+func feeBps(numerator, denominator math.Int) float64 {
+	return float64(numerator.Int64()) * 10000 / float64(denominator.Int64())
+}
+
 // Seeds and Discriminators
 var (
 	AUTH_SEED                  = "vault_and_lp_mint_auth_seed"
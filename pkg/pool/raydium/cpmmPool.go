@@ -3,7 +3,9 @@ package raydium
 import (
 	"context"
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
+	"time"
 
 	"cosmossdk.io/math"
 	cosmath "cosmossdk.io/math"
@@ -11,6 +13,7 @@ import (
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
 	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/sol"
 )
 
 // CPMMPool represents the on-chain pool state
@@ -40,8 +43,7 @@ type CPMMPool struct {
 	_padding2          [32]uint64       // 256 bytes padding
 
 	PoolId           solana.PublicKey
-	UserBaseAccount  solana.PublicKey
-	UserQuoteAccount solana.PublicKey
+	ProgramID        solana.PublicKey // zero for RAYDIUM_CPMM_PROGRAM_ID
 	BaseAmount       cosmath.Int
 	QuoteAmount      cosmath.Int
 	BaseReserve      cosmath.Int
@@ -50,6 +52,9 @@ type CPMMPool struct {
 	QuoteDecimal     uint64
 	BaseNeedTakePnl  uint64
 	QuoteNeedTakePnl uint64
+	// loadedAt is when ApplyAccount last loaded an account, for Quote to
+	// re-read state older than pkg.MaxStateAge
+	loadedAt time.Time
 }
 
 func (pool *CPMMPool) ProtocolName() pkg.ProtocolName {
@@ -61,20 +66,31 @@ func (pool *CPMMPool) ProtocolType() pkg.ProtocolType {
 }
 
 func (pool *CPMMPool) GetProgramID() solana.PublicKey {
+	if !pool.ProgramID.IsZero() {
+		return pool.ProgramID
+	}
 	return RAYDIUM_CPMM_PROGRAM_ID
 }
 
+var _ pkg.PoolV2 = (*CPMMPool)(nil)
+
+// Capabilities returns the optional features of the pool
+func (pool *CPMMPool) Capabilities() pkg.Capabilities {
+	return pkg.CapAccountUpdates | pkg.CapVaults
+}
+
 func (p *CPMMPool) Decode(data []byte) error {
-	if len(data) > 8 {
-		data = data[8:]
+	if len(data) < int(p.Span()) {
+		return fmt.Errorf("data too short: expected %d bytes, got %d", p.Span(), len(data))
 	}
+	data = data[8:]
 
 	dec := bin.NewBinDecoder(data)
 	return dec.Decode(p)
 }
 
 func (p *CPMMPool) Span() uint64 {
-	return 584 // Total size in bytes (including discriminator)
+	return 637 // Total size in bytes (including discriminator)
 }
 
 func (p *CPMMPool) Offset(field string) uint64 {
@@ -83,6 +99,8 @@ func (p *CPMMPool) Offset(field string) uint64 {
 		return 8 + 32*5 // discriminator + 5 pubkeys
 	case "Token1Mint":
 		return 8 + 32*6 // discriminator + 6 pubkeys
+	case "LpSupply":
+		return 8 + 32*10 + 5 // discriminator + 10 pubkeys + bump, status and decimals, packed
 	default:
 		return 0
 	}
@@ -96,6 +114,26 @@ func (pool *CPMMPool) GetTokens() (string, string) {
 	return pool.Token0Mint.String(), pool.Token1Mint.String()
 }
 
+func (pool *CPMMPool) Vaults() (baseVault, quoteVault solana.PublicKey) {
+	return pool.Token0Vault, pool.Token1Vault
+}
+
+// GetFeeRate returns the trade fee Quote charges, in basis points
+func (pool *CPMMPool) GetFeeRate() (float64, error) {
+	return feeBps(LIQUIDITY_FEES_NUMERATOR, LIQUIDITY_FEES_DENOMINATOR), nil
+}
+
+// GetLiquidity returns the vault balances net of the protocol and fund fees
+func (pool *CPMMPool) GetLiquidity() (pkg.Liquidity, error) {
+	if pool.BaseAmount.IsNil() || pool.QuoteAmount.IsNil() {
+		return pkg.Liquidity{}, pkg.ErrNotLoaded
+	}
+	return pkg.Liquidity{
+		Base:  pool.BaseAmount.Sub(math.NewIntFromUint64(pool.BaseNeedTakePnl)),
+		Quote: pool.QuoteAmount.Sub(math.NewIntFromUint64(pool.QuoteNeedTakePnl)),
+	}, nil
+}
+
 func (pool *CPMMPool) BuildSwapInstructions(
 	ctx context.Context,
 	solClient *rpc.Client,
@@ -103,32 +141,56 @@ func (pool *CPMMPool) BuildSwapInstructions(
 	inputMint string,
 	amountIn math.Int,
 	minOutAmountWithDecimals math.Int,
+	opts pkg.SwapBuildOptions,
 ) ([]solana.Instruction, error) {
+	if err := pkg.CheckSwapAmounts(amountIn, minOutAmountWithDecimals); err != nil {
+		return nil, err
+	}
+	if err := opts.CheckPoolOptions(); err != nil {
+		return nil, err
+	}
+	pair, err := pkg.PoolPair(pool, inputMint)
+	if err != nil {
+		return nil, err
+	}
 
 	// 初始化指令数组
 	instrs := []solana.Instruction{}
 
-	var inputValueMint solana.PublicKey
-	if inputMint == pool.Token0Mint.String() {
-		inputValueMint = pool.Token0Mint
-	} else {
-		inputValueMint = pool.Token1Mint
+	// Resolve user token accounts, creating missing ATAs on the fly unless
+	// opts.SkipCreateAccounts says the user holds them
+	baseAccount, quoteAccount := opts.UserAccounts(pair)
+	userBaseAccount, createBaseInst, err := sol.ResolveUserTokenAccount(ctx, solClient, userAddr, pool.Token0Mint, baseAccount, pool.Token0Program, opts.SkipCreateAccounts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve token0 account: %w", err)
+	}
+	userQuoteAccount, createQuoteInst, err := sol.ResolveUserTokenAccount(ctx, solClient, userAddr, pool.Token1Mint, quoteAccount, pool.Token1Program, opts.SkipCreateAccounts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve token1 account: %w", err)
+	}
+	for _, createInst := range []solana.Instruction{createBaseInst, createQuoteInst} {
+		if createInst != nil {
+			instrs = append(instrs, createInst)
+		}
 	}
 
-	var fromAccount solana.PublicKey
-	var toAccount solana.PublicKey
-	if inputValueMint.String() == pool.Token0Mint.String() {
-		fromAccount = pool.UserBaseAccount
-		toAccount = pool.UserQuoteAccount
-	} else {
-		fromAccount = pool.UserQuoteAccount
-		toAccount = pool.UserBaseAccount
+	// Orient the accounts of the swap from the input token to the output token
+	fromAccount, toAccount := userBaseAccount, userQuoteAccount
+	inputVault, outputVault := pool.Token0Vault, pool.Token1Vault
+	inputProgram, outputProgram := pool.Token0Program, pool.Token1Program
+	inputTokenMint, outputTokenMint := pool.Token0Mint, pool.Token1Mint
+	if !pair.BaseIn {
+		fromAccount, toAccount = toAccount, fromAccount
+		inputVault, outputVault = outputVault, inputVault
+		inputProgram, outputProgram = outputProgram, inputProgram
+		inputTokenMint, outputTokenMint = outputTokenMint, inputTokenMint
 	}
 
 	// 创建 swap 指令
 	swapInst := CPMMSwapInstruction{
 		InAmount:         amountIn.Uint64(),
 		MinimumOutAmount: minOutAmountWithDecimals.Uint64(),
+		Program:          pool.GetProgramID(),
 		AccountMetaSlice: make(solana.AccountMetaSlice, 13),
 	}
 	swapInst.BaseVariant = bin.BaseVariant{
@@ -136,24 +198,24 @@ func (pool *CPMMPool) BuildSwapInstructions(
 	}
 
 	// Get the authority PDA
-	authority, _, err := getAuthorityPDA()
+	authority, _, err := getAuthorityPDA(pool.GetProgramID())
 	if err != nil {
 		return nil, fmt.Errorf("failed to get authority PDA: %v", err)
 	}
 	// 设置账户
-	swapInst.AccountMetaSlice[0] = solana.NewAccountMeta(userAddr, true, true)                // payer
-	swapInst.AccountMetaSlice[1] = solana.NewAccountMeta(authority, false, false)             // authority
-	swapInst.AccountMetaSlice[2] = solana.NewAccountMeta(pool.AmmConfig, false, false)        // amm_config
-	swapInst.AccountMetaSlice[3] = solana.NewAccountMeta(pool.PoolId, true, false)            // pool_state
-	swapInst.AccountMetaSlice[4] = solana.NewAccountMeta(fromAccount, true, false)            // input_token_account
-	swapInst.AccountMetaSlice[5] = solana.NewAccountMeta(toAccount, true, false)              // output_token_account
-	swapInst.AccountMetaSlice[6] = solana.NewAccountMeta(pool.Token0Vault, true, false)       // input_vault
-	swapInst.AccountMetaSlice[7] = solana.NewAccountMeta(pool.Token1Vault, true, false)       // output_vault
-	swapInst.AccountMetaSlice[8] = solana.NewAccountMeta(solana.TokenProgramID, false, false) // input_token_program
-	swapInst.AccountMetaSlice[9] = solana.NewAccountMeta(solana.TokenProgramID, false, false) // output_token_program
-	swapInst.AccountMetaSlice[10] = solana.NewAccountMeta(pool.Token0Mint, false, false)      // input_token_mint
-	swapInst.AccountMetaSlice[11] = solana.NewAccountMeta(pool.Token1Mint, false, false)      // output_token_mint
-	swapInst.AccountMetaSlice[12] = solana.NewAccountMeta(pool.ObservationKey, true, false)   // observation_state
+	swapInst.AccountMetaSlice[0] = solana.NewAccountMeta(userAddr, true, true)              // payer
+	swapInst.AccountMetaSlice[1] = solana.NewAccountMeta(authority, false, false)           // authority
+	swapInst.AccountMetaSlice[2] = solana.NewAccountMeta(pool.AmmConfig, false, false)      // amm_config
+	swapInst.AccountMetaSlice[3] = solana.NewAccountMeta(pool.PoolId, true, false)          // pool_state
+	swapInst.AccountMetaSlice[4] = solana.NewAccountMeta(fromAccount, true, false)          // input_token_account
+	swapInst.AccountMetaSlice[5] = solana.NewAccountMeta(toAccount, true, false)            // output_token_account
+	swapInst.AccountMetaSlice[6] = solana.NewAccountMeta(inputVault, true, false)           // input_vault
+	swapInst.AccountMetaSlice[7] = solana.NewAccountMeta(outputVault, true, false)          // output_vault
+	swapInst.AccountMetaSlice[8] = solana.NewAccountMeta(inputProgram, false, false)        // input_token_program
+	swapInst.AccountMetaSlice[9] = solana.NewAccountMeta(outputProgram, false, false)       // output_token_program
+	swapInst.AccountMetaSlice[10] = solana.NewAccountMeta(inputTokenMint, false, false)     // input_token_mint
+	swapInst.AccountMetaSlice[11] = solana.NewAccountMeta(outputTokenMint, false, false)    // output_token_mint
+	swapInst.AccountMetaSlice[12] = solana.NewAccountMeta(pool.ObservationKey, true, false) // observation_state
 	instrs = append(instrs, &swapInst)
 
 	return instrs, nil
@@ -164,11 +226,12 @@ type CPMMSwapInstruction struct {
 	bin.BaseVariant
 	InAmount                uint64
 	MinimumOutAmount        uint64
+	Program                 solana.PublicKey `bin:"-" borsh_skip:"true"`
 	solana.AccountMetaSlice `bin:"-" borsh_skip:"true"`
 }
 
 func (inst *CPMMSwapInstruction) ProgramID() solana.PublicKey {
-	return RAYDIUM_CPMM_PROGRAM_ID
+	return inst.Program
 }
 
 func (inst *CPMMSwapInstruction) Accounts() (out []*solana.AccountMeta) {
@@ -192,46 +255,106 @@ func (inst *CPMMSwapInstruction) Data() ([]byte, error) {
 }
 
 // Add a helper function to get the authority PDA
-func getAuthorityPDA() (solana.PublicKey, uint8, error) {
+func getAuthorityPDA(programID solana.PublicKey) (solana.PublicKey, uint8, error) {
 	seeds := [][]byte{
 		[]byte(AUTH_SEED),
 	}
-	authority, bump, err := solana.FindProgramAddress(seeds, RAYDIUM_CPMM_PROGRAM_ID)
+	authority, bump, err := solana.FindProgramAddress(seeds, programID)
 	if err != nil {
 		return solana.PublicKey{}, 0, fmt.Errorf("failed to find authority PDA: %v", err)
 	}
 	return authority, bump, nil
 }
 
-func (pool *CPMMPool) Quote(ctx context.Context, solClient *rpc.Client, inputMint string, inputAmount math.Int) (math.Int, error) {
-	// update pool data first
-	accounts := make([]solana.PublicKey, 0)
-	accounts = append(accounts, pool.Token0Vault)
-	accounts = append(accounts, pool.Token1Vault)
-	results, err := solClient.GetMultipleAccountsWithOpts(ctx,
-		accounts,
-		&rpc.GetMultipleAccountsOpts{
-			Commitment: rpc.CommitmentProcessed,
-		},
-	)
+// Refresh re-reads the pool account and both vault balances in a single request
+func (pool *CPMMPool) Refresh(ctx context.Context, fetcher pkg.AccountFetcher) error {
+	accounts := pool.WatchedAccounts()
+	results, err := fetcher.GetMultipleAccounts(ctx, accounts...)
 	if err != nil {
-		return math.NewInt(0), fmt.Errorf("batch request failed: %v", err)
+		return fmt.Errorf("batch request failed: %w", sol.ClassifyError(err))
+	}
+	if len(results.Value) != len(accounts) {
+		return fmt.Errorf("unexpected number of accounts: %d", len(results.Value))
 	}
 	for i, result := range results.Value {
 		if result == nil {
-			return math.NewInt(0), fmt.Errorf("result is nil, account: %v", accounts[i].String())
+			return fmt.Errorf("account %v: %w", accounts[i].String(), sol.ErrAccountNotFound)
+		}
+		if err := pool.ApplyAccount(accounts[i], result.Data.GetBinary()); err != nil {
+			return err
 		}
-		accountKey := accounts[i].String()
-		if pool.Token0Vault.String() == accountKey {
-			amountBytes := result.Data.GetBinary()[64:72]
-			amountUint := binary.LittleEndian.Uint64(amountBytes)
-			amount := math.NewIntFromUint64(amountUint)
-			pool.BaseAmount = amount
-		} else {
-			amountBytes := result.Data.GetBinary()[64:72]
-			amountUint := binary.LittleEndian.Uint64(amountBytes)
-			amount := math.NewIntFromUint64(amountUint)
-			pool.QuoteAmount = amount
+	}
+	return nil
+}
+
+// WatchedAccounts returns the pool account and both vaults
+func (pool *CPMMPool) WatchedAccounts() []solana.PublicKey {
+	return []solana.PublicKey{pool.PoolId, pool.Token0Vault, pool.Token1Vault}
+}
+
+// ApplyAccount updates the pool from new data of the pool account or one of its vaults
+func (pool *CPMMPool) ApplyAccount(pubkey solana.PublicKey, data []byte) error {
+	switch pubkey {
+	case pool.PoolId:
+		// Decode into a scratch struct, the decoder would otherwise clobber the runtime fields
+		state := &CPMMPool{}
+		if err := state.Decode(data); err != nil {
+			return fmt.Errorf("failed to decode pool data: %w", err)
+		}
+		pool.Status = state.Status
+		pool.LpSupply = state.LpSupply
+		pool.ProtocolFeesToken0 = state.ProtocolFeesToken0
+		pool.ProtocolFeesToken1 = state.ProtocolFeesToken1
+		pool.FundFeesToken0 = state.FundFeesToken0
+		pool.FundFeesToken1 = state.FundFeesToken1
+
+		// Vault balances include fees owed to the protocol and fund, which aren't swappable
+		pool.BaseNeedTakePnl = pool.ProtocolFeesToken0 + pool.FundFeesToken0
+		pool.QuoteNeedTakePnl = pool.ProtocolFeesToken1 + pool.FundFeesToken1
+	case pool.Token0Vault:
+		amount, err := sol.ParseTokenAmount(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse token 0 vault: %w", err)
+		}
+		pool.BaseAmount = math.NewIntFromUint64(amount)
+	case pool.Token1Vault:
+		amount, err := sol.ParseTokenAmount(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse token 1 vault: %w", err)
+		}
+		pool.QuoteAmount = math.NewIntFromUint64(amount)
+	default:
+		return fmt.Errorf("account %s is not watched by pool %s", pubkey.String(), pool.PoolId.String())
+	}
+	pool.loadedAt = time.Now()
+	return nil
+}
+
+// cpmmPoolState is CPMMPool without its methods, so it encodes field by field
+type cpmmPoolState CPMMPool
+
+// MarshalBinary encodes the decoded pool state and vault balances
+func (pool *CPMMPool) MarshalBinary() ([]byte, error) {
+	return json.Marshal((*cpmmPoolState)(pool))
+}
+
+// UnmarshalBinary restores a pool encoded by MarshalBinary
+func (pool *CPMMPool) UnmarshalBinary(data []byte) error {
+	if err := json.Unmarshal(data, (*cpmmPoolState)(pool)); err != nil {
+		return fmt.Errorf("failed to decode pool snapshot: %w", err)
+	}
+	pool.loadedAt = time.Now()
+	return nil
+}
+
+func (pool *CPMMPool) Quote(ctx context.Context, fetcher pkg.AccountFetcher, inputMint string, inputAmount math.Int) (math.Int, error) {
+	pair, err := pkg.PoolPair(pool, inputMint)
+	if err != nil {
+		return math.NewInt(0), err
+	}
+	if pool.BaseAmount.IsNil() || pool.QuoteAmount.IsNil() || pkg.StateStale(pool.loadedAt) {
+		if err := pool.Refresh(ctx, fetcher); err != nil {
+			return math.NewInt(0), err
 		}
 	}
 
@@ -242,20 +365,17 @@ func (pool *CPMMPool) Quote(ctx context.Context, solClient *rpc.Client, inputMin
 	reserves := []math.Int{pool.BaseReserve, pool.QuoteReserve}
 	mintDecimals := []int{int(pool.BaseDecimal), int(pool.QuoteDecimal)}
 
-	// Determine input side
-	input := "base"
-	if inputMint == pool.Token1Mint.String() {
-		input = "quote"
-	}
-
 	// If input is quote, reverse reserves and decimals
-	if input == "quote" {
+	if !pair.BaseIn {
 		reserves[0], reserves[1] = reserves[1], reserves[0]
 		mintDecimals[0], mintDecimals[1] = mintDecimals[1], mintDecimals[0]
 	}
 
 	reserveIn := reserves[0]
 	reserveOut := reserves[1]
+	if !reserveIn.IsPositive() || !reserveOut.IsPositive() {
+		return math.ZeroInt(), pkg.ErrZeroLiquidity
+	}
 
 	// Initialize output values
 	amountOutRaw := math.ZeroInt()
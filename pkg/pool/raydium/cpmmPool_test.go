@@ -0,0 +1,49 @@
+package raydium
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cosmath "cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/sol/soltest"
+)
+
+func TestCPMMPoolQuoteReadsStaleState(t *testing.T) {
+	account := soltest.ReadFixture(t, "testdata/cpmm_pool.json").Account
+	pool := &CPMMPool{PoolId: solana.NewWallet().PublicKey()}
+	if err := pool.Decode(account); err != nil {
+		t.Fatal(err)
+	}
+	node := soltest.NewRPC()
+	node.SetAccount(pool.PoolId, soltest.Account{Owner: RAYDIUM_CPMM_PROGRAM_ID, Data: account})
+	node.SetTokenAccount(pool.Token0Vault, pool.Token0Mint, pool.Token0Vault, 10_000_000)
+	node.SetTokenAccount(pool.Token1Vault, pool.Token1Mint, pool.Token1Vault, 3_000_000)
+	if err := pool.Refresh(context.Background(), node.Client()); err != nil {
+		t.Fatal(err)
+	}
+	quote := func() cosmath.Int {
+		t.Helper()
+		out, err := pool.Quote(context.Background(), node.Client(), pool.Token0Mint.String(), cosmath.NewInt(100_000))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	before := quote()
+
+	// Quote trusts state loaded within pkg.MaxStateAge
+	node.Reset()
+	node.SetTokenAccount(pool.Token1Vault, pool.Token1Mint, pool.Token1Vault, 1_500_000)
+	if got := quote(); !got.Equal(before) || node.CallCount("getMultipleAccounts") != 0 {
+		t.Fatalf("got %s after %d reads, want %s without reading", got, node.CallCount("getMultipleAccounts"), before)
+	}
+
+	// and re-reads older state
+	pool.loadedAt = time.Now().Add(-2 * pkg.MaxStateAge)
+	if got := quote(); got.Equal(before) || node.CallCount("getMultipleAccounts") != 1 {
+		t.Fatalf("got %s after %d reads, want a new quote after one", got, node.CallCount("getMultipleAccounts"))
+	}
+}
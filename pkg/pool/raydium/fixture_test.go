@@ -0,0 +1,98 @@
+package raydium
+
+import (
+	"flag"
+	"strconv"
+	"testing"
+
+	"github.com/yimingWOW/solroute/pkg/sol/soltest"
+)
+
+var update = flag.Bool("update", false, "rewrite the decoded fields of the testdata fixtures")
+
+func TestAMMPoolFixture(t *testing.T) {
+	const path = "testdata/amm_pool.json"
+	var pool AMMPool
+	if err := pool.Decode(soltest.ReadFixture(t, path).Account); err != nil {
+		t.Fatal(err)
+	}
+	soltest.CheckDecoded(t, path, map[string]string{
+		"Status":              strconv.FormatUint(pool.Status, 10),
+		"Nonce":               strconv.FormatUint(pool.Nonce, 10),
+		"BaseDecimal":         strconv.FormatUint(pool.BaseDecimal, 10),
+		"QuoteDecimal":        strconv.FormatUint(pool.QuoteDecimal, 10),
+		"TradeFeeNumerator":   strconv.FormatUint(pool.TradeFeeNumerator, 10),
+		"TradeFeeDenominator": strconv.FormatUint(pool.TradeFeeDenominator, 10),
+		"SwapFeeNumerator":    strconv.FormatUint(pool.SwapFeeNumerator, 10),
+		"SwapFeeDenominator":  strconv.FormatUint(pool.SwapFeeDenominator, 10),
+		"BaseNeedTakePnl":     strconv.FormatUint(pool.BaseNeedTakePnl, 10),
+		"QuoteNeedTakePnl":    strconv.FormatUint(pool.QuoteNeedTakePnl, 10),
+		"BaseVault":           pool.BaseVault.String(),
+		"QuoteVault":          pool.QuoteVault.String(),
+		"BaseMint":            pool.BaseMint.String(),
+		"QuoteMint":           pool.QuoteMint.String(),
+		"LpMint":              pool.LpMint.String(),
+		"OpenOrders":          pool.OpenOrders.String(),
+		"MarketId":            pool.MarketId.String(),
+		"MarketProgramId":     pool.MarketProgramId.String(),
+		"TargetOrders":        pool.TargetOrders.String(),
+		"Owner":               pool.Owner.String(),
+		"LpReserve":           strconv.FormatUint(pool.LpReserve, 10),
+	}, *update)
+}
+
+func TestCLMMPoolFixture(t *testing.T) {
+	const path = "testdata/clmm_pool.json"
+	var pool CLMMPool
+	if err := pool.Decode(soltest.ReadFixture(t, path).Account); err != nil {
+		t.Fatal(err)
+	}
+	soltest.CheckDecoded(t, path, map[string]string{
+		"Bump":           strconv.Itoa(int(pool.Bump)),
+		"AmmConfig":      pool.AmmConfig.String(),
+		"Owner":          pool.Owner.String(),
+		"TokenMint0":     pool.TokenMint0.String(),
+		"TokenMint1":     pool.TokenMint1.String(),
+		"TokenVault0":    pool.TokenVault0.String(),
+		"TokenVault1":    pool.TokenVault1.String(),
+		"ObservationKey": pool.ObservationKey.String(),
+		"MintDecimals0":  strconv.Itoa(int(pool.MintDecimals0)),
+		"MintDecimals1":  strconv.Itoa(int(pool.MintDecimals1)),
+		"TickSpacing":    strconv.Itoa(int(pool.TickSpacing)),
+		"Liquidity":      pool.Liquidity.String(),
+		"SqrtPriceX64":   pool.SqrtPriceX64.String(),
+		"TickCurrent":    strconv.Itoa(int(pool.TickCurrent)),
+		"Status":         strconv.Itoa(int(pool.Status)),
+	}, *update)
+}
+
+func TestCPMMPoolFixture(t *testing.T) {
+	const path = "testdata/cpmm_pool.json"
+	var pool CPMMPool
+	if err := pool.Decode(soltest.ReadFixture(t, path).Account); err != nil {
+		t.Fatal(err)
+	}
+	soltest.CheckDecoded(t, path, map[string]string{
+		"AmmConfig":          pool.AmmConfig.String(),
+		"PoolCreator":        pool.PoolCreator.String(),
+		"Token0Vault":        pool.Token0Vault.String(),
+		"Token1Vault":        pool.Token1Vault.String(),
+		"LpMint":             pool.LpMint.String(),
+		"Token0Mint":         pool.Token0Mint.String(),
+		"Token1Mint":         pool.Token1Mint.String(),
+		"Token0Program":      pool.Token0Program.String(),
+		"Token1Program":      pool.Token1Program.String(),
+		"ObservationKey":     pool.ObservationKey.String(),
+		"AuthBump":           strconv.Itoa(int(pool.AuthBump)),
+		"Status":             strconv.Itoa(int(pool.Status)),
+		"LpMintDecimals":     strconv.Itoa(int(pool.LpMintDecimals)),
+		"Mint0Decimals":      strconv.Itoa(int(pool.Mint0Decimals)),
+		"Mint1Decimals":      strconv.Itoa(int(pool.Mint1Decimals)),
+		"LpSupply":           strconv.FormatUint(pool.LpSupply, 10),
+		"ProtocolFeesToken0": strconv.FormatUint(pool.ProtocolFeesToken0, 10),
+		"ProtocolFeesToken1": strconv.FormatUint(pool.ProtocolFeesToken1, 10),
+		"FundFeesToken0":     strconv.FormatUint(pool.FundFeesToken0, 10),
+		"FundFeesToken1":     strconv.FormatUint(pool.FundFeesToken1, 10),
+		"OpenTime":           strconv.FormatUint(pool.OpenTime, 10),
+	}, *update)
+}
@@ -0,0 +1,37 @@
+package raydium
+
+import (
+	"testing"
+
+	"github.com/yimingWOW/solroute/pkg/sol/soltest"
+)
+
+func FuzzAMMPoolDecode(f *testing.F) {
+	soltest.AddSeeds(f, "testdata/amm_pool.json")
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var pool AMMPool
+		if err := pool.Decode(data); err == nil && len(data) < 752 {
+			t.Fatalf("decoded %d bytes without an error", len(data))
+		}
+	})
+}
+
+func FuzzCLMMPoolDecode(f *testing.F) {
+	soltest.AddSeeds(f, "testdata/clmm_pool.json")
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var pool CLMMPool
+		if err := pool.Decode(data); err == nil && len(data) < int(pool.Span()) {
+			t.Fatalf("decoded %d bytes without an error", len(data))
+		}
+	})
+}
+
+func FuzzCPMMPoolDecode(f *testing.F) {
+	soltest.AddSeeds(f, "testdata/cpmm_pool.json")
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var pool CPMMPool
+		if err := pool.Decode(data); err == nil && len(data) < 637 {
+			t.Fatalf("decoded %d bytes without an error", len(data))
+		}
+	})
+}
@@ -0,0 +1,80 @@
+package raydium
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cosmath "cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/sol/soltest"
+	"lukechampine.com/uint128"
+)
+
+// checkQuotes quotes amountIn of each mint of pool, base first, against want
+func checkQuotes(t *testing.T, pool pkg.Pool, amountIn int64, wantBaseIn, wantQuoteIn int64) {
+	t.Helper()
+	base, quote := pool.GetTokens()
+	fetcher := soltest.NewRPC().Client()
+	for _, tt := range []struct {
+		inputMint string
+		want      int64
+	}{{base, wantBaseIn}, {quote, wantQuoteIn}} {
+		got, err := pool.Quote(context.Background(), fetcher, tt.inputMint, cosmath.NewInt(amountIn))
+		if err != nil {
+			t.Fatalf("quote %s: %v", tt.inputMint, err)
+		}
+		if !got.Equal(cosmath.NewInt(tt.want)) {
+			t.Errorf("quote %s: got %s, want %d", tt.inputMint, got, tt.want)
+		}
+	}
+}
+
+func TestAMMPoolQuote(t *testing.T) {
+	// Reserves of 1000 base and 150 quote tokens of 9 decimals, with a 0.25% fee
+	pool := &AMMPool{
+		BaseMint:    solana.NewWallet().PublicKey(),
+		QuoteMint:   solana.NewWallet().PublicKey(),
+		BaseAmount:  cosmath.NewInt(1_000_000_000_000),
+		QuoteAmount: cosmath.NewInt(150_000_000_000),
+		loadedAt:    time.Now(),
+	}
+	checkQuotes(t, pool, 1_000_000_000, 149_475_897, 6_606_069_636)
+}
+
+func TestCPMMPoolQuote(t *testing.T) {
+	pool := &CPMMPool{
+		Token0Mint:  solana.NewWallet().PublicKey(),
+		Token1Mint:  solana.NewWallet().PublicKey(),
+		BaseAmount:  cosmath.NewInt(1_000_000_000_000),
+		QuoteAmount: cosmath.NewInt(150_000_000_000),
+		loadedAt:    time.Now(),
+	}
+	checkQuotes(t, pool, 1_000_000_000, 149_475_897, 6_606_069_636)
+}
+
+func TestCLMMPoolQuote(t *testing.T) {
+	// Price 4 at tick 13863, in a range from tick 10800 to 14400 holding all
+	// the liquidity, with a 0.25% fee
+	const liquidity = 1_000_000_000_000
+	pool := &CLMMPool{
+		PoolId:       solana.NewWallet().PublicKey(),
+		TokenMint0:   solana.NewWallet().PublicKey(),
+		TokenMint1:   solana.NewWallet().PublicKey(),
+		TickSpacing:  60,
+		TickCurrent:  13863,
+		SqrtPriceX64: uint128.From64(2).Lsh(64),
+		Liquidity:    uint128.From64(liquidity),
+		FeeRate:      2500,
+	}
+	pool.exTickArrayBitmap = newEmptyExBitmap(pool.PoolId)
+	pool.TickArrayBitmap[8] = 1<<3 | 1<<4 // the arrays starting at ticks 10800 and 14400
+	current := TickArray{StartTickIndex: 10800, Ticks: make([]TickState, TICK_ARRAY_SIZE)}
+	current.Ticks[0] = TickState{Tick: 10800, LiquidityNet: liquidity, LiquidityGross: uint128.From64(liquidity)}
+	next := TickArray{StartTickIndex: 14400, Ticks: make([]TickState, TICK_ARRAY_SIZE)}
+	next.Ticks[0] = TickState{Tick: 14400, LiquidityNet: -liquidity, LiquidityGross: uint128.From64(liquidity)}
+	pool.TickArrayCache = map[string]TickArray{"10800": current, "14400": next}
+
+	checkQuotes(t, pool, 1_000_000, 3_989_992, 249_374)
+}
@@ -0,0 +1,60 @@
+package raydium
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	cosmath "cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/sol/soltest"
+)
+
+func TestBuildSwapInstructionsRejectsAmountsOverUint64(t *testing.T) {
+	overflow := cosmath.NewIntFromUint64(^uint64(0)).AddRaw(1)
+	base, quote := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	for _, pool := range []pkg.Pool{
+		&AMMPool{BaseMint: base, QuoteMint: quote},
+		&CPMMPool{Token0Mint: base, Token1Mint: quote},
+		&CLMMPool{TokenMint0: base, TokenMint1: quote},
+	} {
+		for _, amounts := range [][2]cosmath.Int{{overflow, cosmath.ZeroInt()}, {cosmath.NewInt(1), overflow}} {
+			_, err := pool.BuildSwapInstructions(context.Background(), nil, solana.NewWallet().PublicKey(),
+				base.String(), amounts[0], amounts[1], pkg.SwapBuildOptions{})
+			if err == nil {
+				t.Errorf("%s: want an error for amount in %s and min out %s", pool.ProtocolName(), amounts[0], amounts[1])
+			}
+		}
+	}
+}
+
+func TestBuildSwapInstructionsRejectsRouterOptions(t *testing.T) {
+	pool := &AMMPool{BaseMint: solana.NewWallet().PublicKey(), QuoteMint: solana.NewWallet().PublicKey()}
+	_, err := pool.BuildSwapInstructions(context.Background(), nil, solana.NewWallet().PublicKey(),
+		pool.BaseMint.String(), cosmath.NewInt(1), cosmath.ZeroInt(), pkg.SwapBuildOptions{WrapInput: true})
+	if !errors.Is(err, pkg.ErrRouterOption) {
+		t.Fatalf("got error %v, want %v", err, pkg.ErrRouterOption)
+	}
+}
+
+func TestBuildSwapInstructionsSkipCreateAccountsMakesNoCalls(t *testing.T) {
+	node := soltest.NewRPC()
+	pool := &CPMMPool{
+		Token0Mint:    solana.NewWallet().PublicKey(),
+		Token1Mint:    solana.NewWallet().PublicKey(),
+		Token0Program: solana.TokenProgramID,
+		Token1Program: solana.TokenProgramID,
+	}
+	insts, err := pool.BuildSwapInstructions(context.Background(), node.Client(), solana.NewWallet().PublicKey(),
+		pool.Token0Mint.String(), cosmath.NewInt(1), cosmath.ZeroInt(), pkg.SwapBuildOptions{SkipCreateAccounts: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(insts) != 1 {
+		t.Fatalf("got %d instructions, want only the swap", len(insts))
+	}
+	if calls := node.Calls(); len(calls) != 0 {
+		t.Fatalf("got %d RPC calls, want none", len(calls))
+	}
+}
@@ -0,0 +1,349 @@
+// Package poolscore rates how safe a pool is to route through. A Scorer flags
+// freshly created pools, tiny liquidity, lopsided reserves and mints whose
+// authorities can still mint, freeze or seize tokens, and folds the flags into
+// a score from 0 to 100 the router filters on, see
+// router.SimpleRouter.SetPoolScorer
+package poolscore
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/price"
+	"github.com/yimingWOW/solroute/pkg/sol"
+	"github.com/yimingWOW/solroute/pkg/tokens"
+)
+
+// Scorer defaults
+const (
+	DefaultMinAge          = 24 * time.Hour
+	DefaultMinLiquidityUSD = 10_000
+	DefaultMaxSkew         = 5
+	DefaultTTL             = 10 * time.Minute
+)
+
+// MaxScore is the score of a pool with no flag
+const MaxScore = 100
+
+// Flag is a reason a pool looks suspicious
+type Flag string
+
+const (
+	// FlagNewPool was created less than MinAge ago
+	FlagNewPool Flag = "new_pool"
+	// FlagLowLiquidity holds less than MinLiquidityUSD
+	FlagLowLiquidity Flag = "low_liquidity"
+	// FlagSkewedReserves holds more than MaxSkew times the value of one side
+	// on the other
+	FlagSkewedReserves Flag = "skewed_reserves"
+	// FlagUnvalued couldn't be valued, so its liquidity is unknown
+	FlagUnvalued Flag = "unvalued"
+	// FlagMintAuthority trades a mint whose supply can still be inflated
+	FlagMintAuthority Flag = "mint_authority"
+	// FlagFreezeAuthority trades a mint whose accounts can be frozen, blocking sells
+	FlagFreezeAuthority Flag = "freeze_authority"
+	// FlagConfiscatable trades a Token-2022 mint with an extension that can
+	// seize tokens or block their transfers: a permanent delegate, a transfer
+	// hook, frozen default accounts, pausing or non-transferability
+	FlagConfiscatable Flag = "confiscatable"
+)
+
+// confiscatingRisks are the token risks FlagConfiscatable stands for
+var confiscatingRisks = []tokens.Risk{
+	tokens.RiskPermanentDelegate,
+	tokens.RiskTransferHook,
+	tokens.RiskDefaultFrozen,
+	tokens.RiskPausable,
+	tokens.RiskNonTransferable,
+}
+
+// DefaultPenalties is what each flag takes off MaxScore
+var DefaultPenalties = map[Flag]int{
+	FlagNewPool:         30,
+	FlagLowLiquidity:    40,
+	FlagSkewedReserves:  20,
+	FlagUnvalued:        10,
+	FlagMintAuthority:   30,
+	FlagFreezeAuthority: 40,
+	FlagConfiscatable:   50,
+}
+
+// DefaultTrustedMints are exempt from the authority checks: their issuers
+// keep mint and freeze authorities by design
+var DefaultTrustedMints = []solana.PublicKey{sol.WSOL, price.USDC, price.USDT}
+
+// Finding is a flag raised on a pool, with what triggered it
+type Finding struct {
+	Flag   Flag   `json:"flag"`
+	Detail string `json:"detail"`
+}
+
+// Score is the rating of a pool
+type Score struct {
+	Pool     string    `json:"pool"`
+	Value    int       `json:"value"`
+	Findings []Finding `json:"findings,omitempty"`
+	At       time.Time `json:"at"`
+}
+
+// Has reports whether the pool was flagged with flag
+func (s *Score) Has(flag Flag) bool {
+	for _, finding := range s.Findings {
+		if finding.Flag == flag {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Score) String() string {
+	if len(s.Findings) == 0 {
+		return fmt.Sprintf("score %d", s.Value)
+	}
+	findings := make([]string, len(s.Findings))
+	for i, finding := range s.Findings {
+		findings[i] = fmt.Sprintf("%s (%s)", finding.Flag, finding.Detail)
+	}
+	return fmt.Sprintf("score %d: %s", s.Value, strings.Join(findings, ", "))
+}
+
+// Options configures NewScorer. Zero fields keep the defaults
+type Options struct {
+	// MinAge defaults to DefaultMinAge
+	MinAge time.Duration
+	// MinLiquidityUSD defaults to DefaultMinLiquidityUSD
+	MinLiquidityUSD float64
+	// MaxSkew is the largest ratio between the values of the two sides of a
+	// pool. Defaults to DefaultMaxSkew
+	MaxSkew float64
+	// Penalties override DefaultPenalties per flag
+	Penalties map[Flag]int
+	// TrustedMints replace DefaultTrustedMints
+	TrustedMints []solana.PublicKey
+	// TTL is how long a score is reused. Defaults to DefaultTTL
+	TTL time.Duration
+}
+
+// Scorer rates pools and caches their scores
+type Scorer struct {
+	client    *rpc.Client
+	resolver  *tokens.Resolver
+	oracle    *price.Oracle
+	opts      Options
+	penalties map[Flag]int
+	trusted   map[solana.PublicKey]bool
+
+	mu     sync.Mutex
+	scores map[string]*Score
+}
+
+// NewScorer creates a Scorer reading pools through client and mints from
+// resolver. Liquidity and reserves are valued with oracle; without one, the
+// pools are only checked for their age and mints
+func NewScorer(client *rpc.Client, resolver *tokens.Resolver, oracle *price.Oracle, opts Options) *Scorer {
+	if opts.MinAge <= 0 {
+		opts.MinAge = DefaultMinAge
+	}
+	if opts.MinLiquidityUSD <= 0 {
+		opts.MinLiquidityUSD = DefaultMinLiquidityUSD
+	}
+	if opts.MaxSkew <= 0 {
+		opts.MaxSkew = DefaultMaxSkew
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultTTL
+	}
+	if opts.TrustedMints == nil {
+		opts.TrustedMints = DefaultTrustedMints
+	}
+	penalties := make(map[Flag]int, len(DefaultPenalties))
+	for flag, penalty := range DefaultPenalties {
+		penalties[flag] = penalty
+	}
+	for flag, penalty := range opts.Penalties {
+		penalties[flag] = penalty
+	}
+	trusted := make(map[solana.PublicKey]bool, len(opts.TrustedMints))
+	for _, mint := range opts.TrustedMints {
+		trusted[mint] = true
+	}
+	return &Scorer{
+		client:    client,
+		resolver:  resolver,
+		oracle:    oracle,
+		opts:      opts,
+		penalties: penalties,
+		trusted:   trusted,
+		scores:    make(map[string]*Score),
+	}
+}
+
+// Score rates pool, reusing its score for the TTL. It fails when the chain
+// can't be read; a pool that can't be valued is flagged FlagUnvalued instead
+func (s *Scorer) Score(ctx context.Context, pool pkg.Pool) (*Score, error) {
+	s.mu.Lock()
+	cached, ok := s.scores[pool.GetID()]
+	s.mu.Unlock()
+	if ok && time.Since(cached.At) < s.opts.TTL {
+		return cached, nil
+	}
+
+	score := &Score{Pool: pool.GetID(), At: time.Now()}
+	checks := []func(context.Context, pkg.Pool) ([]Finding, error){s.checkAge, s.checkReserves, s.checkMints}
+	for _, check := range checks {
+		findings, err := check(ctx, pool)
+		if err != nil {
+			return nil, err
+		}
+		score.Findings = append(score.Findings, findings...)
+	}
+	score.Value = MaxScore
+	for _, finding := range score.Findings {
+		score.Value -= s.penalties[finding.Flag]
+	}
+	score.Value = max(score.Value, 0)
+
+	s.mu.Lock()
+	s.scores[pool.GetID()] = score
+	s.mu.Unlock()
+	return score, nil
+}
+
+// Forget drops the cached score of a pool
+func (s *Scorer) Forget(poolID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.scores, poolID)
+}
+
+// maxSignaturePages bounds the history walked back to find a pool's creation
+const maxSignaturePages = 5
+
+// checkAge walks the signatures of the pool account back to the first one.
+// A pool whose history doesn't reach MinAge within maxSignaturePages pages is
+// busy enough to be assumed new
+func (s *Scorer) checkAge(ctx context.Context, pool pkg.Pool) ([]Finding, error) {
+	account, err := solana.PublicKeyFromBase58(pool.GetID())
+	if err != nil {
+		return nil, fmt.Errorf("invalid pool id %s: %w", pool.GetID(), err)
+	}
+	const limit = 1000
+	var before solana.Signature
+	for page := 0; page < maxSignaturePages; page++ {
+		signatures, err := s.client.GetSignaturesForAddressWithOpts(ctx, account, &rpc.GetSignaturesForAddressOpts{
+			Limit:  ptr(limit),
+			Before: before,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get signatures of pool %s: %w", pool.GetID(), sol.ClassifyError(err))
+		}
+		if len(signatures) == 0 {
+			return nil, nil
+		}
+		oldest := signatures[len(signatures)-1]
+		if oldest.BlockTime != nil {
+			age := time.Since(oldest.BlockTime.Time())
+			if age >= s.opts.MinAge {
+				return nil, nil
+			}
+			if len(signatures) < limit {
+				return []Finding{{Flag: FlagNewPool, Detail: "created " + age.Round(time.Minute).String() + " ago"}}, nil
+			}
+		} else if len(signatures) < limit {
+			return nil, nil
+		}
+		before = oldest.Signature
+	}
+	return []Finding{{Flag: FlagNewPool, Detail: fmt.Sprintf("over %d transactions within %s", maxSignaturePages*limit, s.opts.MinAge)}}, nil
+}
+
+// checkReserves values the vaults of the pool
+func (s *Scorer) checkReserves(ctx context.Context, pool pkg.Pool) ([]Finding, error) {
+	if s.oracle == nil {
+		return nil, nil
+	}
+	if _, ok := pool.(pkg.VaultReporter); !ok {
+		return []Finding{{Flag: FlagUnvalued, Detail: "pool doesn't report its vaults"}}, nil
+	}
+	reserves, err := s.oracle.Reserves(ctx, s.client, pool)
+	if err != nil {
+		return nil, err
+	}
+	var findings []Finding
+	var liquidity float64
+	switch {
+	case reserves.Priced[0] && reserves.Priced[1]:
+		liquidity = reserves.USD[0] + reserves.USD[1]
+		low, high := min(reserves.USD[0], reserves.USD[1]), max(reserves.USD[0], reserves.USD[1])
+		if low == 0 || high/low > s.opts.MaxSkew {
+			findings = append(findings, Finding{Flag: FlagSkewedReserves,
+				Detail: fmt.Sprintf("$%.2f against $%.2f", reserves.USD[0], reserves.USD[1])})
+		}
+	case reserves.Priced[0]:
+		liquidity = 2 * reserves.USD[0]
+	case reserves.Priced[1]:
+		liquidity = 2 * reserves.USD[1]
+	default:
+		return []Finding{{Flag: FlagUnvalued, Detail: "neither mint has a price"}}, nil
+	}
+	if liquidity < s.opts.MinLiquidityUSD {
+		findings = append(findings, Finding{Flag: FlagLowLiquidity,
+			Detail: fmt.Sprintf("$%.2f, minimum $%.2f", liquidity, s.opts.MinLiquidityUSD)})
+	}
+	return findings, nil
+}
+
+// checkMints looks up the authorities of the untrusted mints of the pool
+func (s *Scorer) checkMints(ctx context.Context, pool pkg.Pool) ([]Finding, error) {
+	baseMint, quoteMint := pool.GetTokens()
+	var mints []solana.PublicKey
+	for _, mint := range []string{baseMint, quoteMint} {
+		key, err := solana.PublicKeyFromBase58(mint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mint %s: %w", mint, err)
+		}
+		if !s.trusted[key] {
+			mints = append(mints, key)
+		}
+	}
+	if len(mints) == 0 {
+		return nil, nil
+	}
+	resolved, err := s.resolver.ResolveMany(ctx, mints...)
+	if err != nil {
+		return nil, err
+	}
+	var findings []Finding
+	for _, token := range resolved {
+		risk := token.Risk()
+		if risk.MintAuthority != nil {
+			findings = append(findings, Finding{Flag: FlagMintAuthority,
+				Detail: fmt.Sprintf("%s mintable by %s", token.Mint, risk.MintAuthority)})
+		}
+		if risk.FreezeAuthority != nil {
+			findings = append(findings, Finding{Flag: FlagFreezeAuthority,
+				Detail: fmt.Sprintf("%s freezable by %s", token.Mint, risk.FreezeAuthority)})
+		}
+		var confiscating []string
+		for _, r := range confiscatingRisks {
+			if risk.Has(r) {
+				confiscating = append(confiscating, string(r))
+			}
+		}
+		if len(confiscating) > 0 {
+			findings = append(findings, Finding{Flag: FlagConfiscatable,
+				Detail: fmt.Sprintf("%s has %s", token.Mint, strings.Join(confiscating, ", "))})
+		}
+	}
+	return findings, nil
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
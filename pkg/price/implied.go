@@ -0,0 +1,76 @@
+package price
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/yimingWOW/solroute/pkg/tokens"
+)
+
+// Quoter returns the best output for amountIn of inputMint swapped to
+// outputMint, e.g. router.SimpleRouter.PriceQuoter
+type Quoter interface {
+	QuotePrice(ctx context.Context, inputMint, outputMint string, amountIn math.Int) (math.Int, error)
+}
+
+// QuoterFunc adapts a function to Quoter
+type QuoterFunc func(ctx context.Context, inputMint, outputMint string, amountIn math.Int) (math.Int, error)
+
+func (f QuoterFunc) QuotePrice(ctx context.Context, inputMint, outputMint string, amountIn math.Int) (math.Int, error) {
+	return f(ctx, inputMint, outputMint, amountIn)
+}
+
+// Implied prices a mint by quoting one whole token of it against a quote mint
+// through the pools, for tokens without an oracle feed. The price includes the
+// pools' fees and the impact of the one token swap
+type Implied struct {
+	Quoter Quoter
+	Tokens *tokens.Resolver
+	// Quote is the mint prices are quoted in. Defaults to USDC
+	Quote solana.PublicKey
+	// QuotePrice prices the quote mint; nil values it at 1 USD
+	QuotePrice Source
+}
+
+func (i *Implied) Price(ctx context.Context, mint solana.PublicKey) (Price, error) {
+	quote := i.Quote
+	if quote.IsZero() {
+		quote = USDC
+	}
+	if mint.Equals(quote) {
+		return i.quotePrice(ctx, quote)
+	}
+	decimals, err := i.Tokens.Decimals(ctx, mint)
+	if err != nil {
+		return Price{}, err
+	}
+	quoteDecimals, err := i.Tokens.Decimals(ctx, quote)
+	if err != nil {
+		return Price{}, err
+	}
+	one := math.NewIntWithDecimal(1, int(decimals))
+	out, err := i.Quoter.QuotePrice(ctx, mint.String(), quote.String(), one)
+	if err != nil {
+		return Price{}, fmt.Errorf("%w: no pool quote for %s: %v", ErrNoPrice, mint.String(), err)
+	}
+	quotePrice, err := i.quotePrice(ctx, quote)
+	if err != nil {
+		return Price{}, err
+	}
+	return Price{
+		Mint:        mint,
+		USD:         toFloat(out, quoteDecimals) * quotePrice.USD,
+		PublishTime: time.Now(),
+		Source:      "pools",
+	}, nil
+}
+
+func (i *Implied) quotePrice(ctx context.Context, quote solana.PublicKey) (Price, error) {
+	if i.QuotePrice == nil {
+		return Price{Mint: quote, USD: 1, PublishTime: time.Now(), Source: "pools"}, nil
+	}
+	return i.QuotePrice.Price(ctx, quote)
+}
@@ -0,0 +1,80 @@
+package price
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/sol"
+)
+
+// Reserves is the content of the vaults of a pool, in the order of GetTokens
+type Reserves struct {
+	Amounts [2]math.Int
+	// USD values the amounts; Priced says which of them the oracle could value
+	USD    [2]float64
+	Priced [2]bool
+}
+
+// Reserves reads the vaults of pool and values them. A mint without a price
+// leaves its side unpriced; other pricing errors fail. Pools that don't
+// implement pkg.VaultReporter can't be valued
+func (o *Oracle) Reserves(ctx context.Context, client *rpc.Client, pool pkg.Pool) (*Reserves, error) {
+	reporter, ok := pool.(pkg.VaultReporter)
+	if !ok {
+		return nil, fmt.Errorf("%s pool %s doesn't report its vaults", pool.ProtocolName(), pool.GetID())
+	}
+	baseVault, quoteVault := reporter.Vaults()
+	results, err := client.GetMultipleAccounts(ctx, baseVault, quoteVault)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vaults of pool %s: %w", pool.GetID(), sol.ClassifyError(err))
+	}
+	baseMint, quoteMint := pool.GetTokens()
+	reserves := &Reserves{}
+	for i, mint := range []string{baseMint, quoteMint} {
+		account := results.Value[i]
+		if account == nil {
+			return nil, fmt.Errorf("vault %d of pool %s not found", i, pool.GetID())
+		}
+		data := account.Data.GetBinary()
+		if len(data) < 72 {
+			return nil, fmt.Errorf("invalid vault of pool %s", pool.GetID())
+		}
+		reserves.Amounts[i] = math.NewIntFromUint64(binary.LittleEndian.Uint64(data[64:72]))
+		value, err := o.Value(ctx, solana.MustPublicKeyFromBase58(mint), reserves.Amounts[i])
+		if errors.Is(err, ErrNoPrice) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		reserves.USD[i], reserves.Priced[i] = value, true
+	}
+	return reserves, nil
+}
+
+// Liquidity returns the USD value of the tokens held in the vaults of pool.
+// When only one of the mints has a price, the other side is valued the same,
+// as in a constant product pool. Pools that don't implement
+// pkg.VaultReporter can't be valued
+func (o *Oracle) Liquidity(ctx context.Context, client *rpc.Client, pool pkg.Pool) (float64, error) {
+	reserves, err := o.Reserves(ctx, client, pool)
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case reserves.Priced[0] && reserves.Priced[1]:
+		return reserves.USD[0] + reserves.USD[1], nil
+	case reserves.Priced[0]:
+		return 2 * reserves.USD[0], nil
+	case reserves.Priced[1]:
+		return 2 * reserves.USD[1], nil
+	}
+	baseMint, quoteMint := pool.GetTokens()
+	return 0, fmt.Errorf("%w for %s nor %s", ErrNoPrice, baseMint, quoteMint)
+}
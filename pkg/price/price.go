@@ -0,0 +1,176 @@
+// Package price values tokens in USD. Prices come from Sources tried in order:
+// Pyth and Switchboard on-chain feeds, and prices implied by the pools the
+// router quotes as a fallback for tokens without a feed. An Oracle caches
+// them and converts between token amounts and USD notionals
+package price
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	stdmath "math"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/yimingWOW/solroute/pkg/sol"
+	"github.com/yimingWOW/solroute/pkg/tokens"
+)
+
+// DefaultCacheTTL is how long an Oracle reuses a price
+const DefaultCacheTTL = 5 * time.Second
+
+// ErrNoPrice is returned by a Source that has no price for a mint
+var ErrNoPrice = errors.New("no price")
+
+// Price is the USD price of one whole token of Mint
+type Price struct {
+	Mint solana.PublicKey
+	USD  float64
+	// Confidence is the uncertainty of USD reported by the feed, zero when the
+	// source doesn't report one
+	Confidence  float64
+	PublishTime time.Time
+	Source      string
+}
+
+// Source returns USD prices
+type Source interface {
+	Price(ctx context.Context, mint solana.PublicKey) (Price, error)
+}
+
+// Fallback tries each source in order until one has a price
+type Fallback []Source
+
+func (f Fallback) Price(ctx context.Context, mint solana.PublicKey) (Price, error) {
+	var errs []string
+	for _, source := range f {
+		price, err := source.Price(ctx, mint)
+		if err == nil {
+			return price, nil
+		}
+		if ctx.Err() != nil {
+			return Price{}, ctx.Err()
+		}
+		errs = append(errs, err.Error())
+	}
+	if len(errs) == 0 {
+		return Price{}, fmt.Errorf("%w for %s: no source configured", ErrNoPrice, mint.String())
+	}
+	return Price{}, fmt.Errorf("%w for %s: %s", ErrNoPrice, mint.String(), strings.Join(errs, "; "))
+}
+
+// OracleOptions configures NewOracle. Zero fields keep the defaults
+type OracleOptions struct {
+	// CacheTTL defaults to DefaultCacheTTL; negative disables caching
+	CacheTTL time.Duration
+}
+
+// Oracle caches prices from its sources and values token amounts with the
+// decimals of their mints
+type Oracle struct {
+	source Source
+	tokens *tokens.Resolver
+	ttl    time.Duration
+
+	mu    sync.Mutex
+	cache map[solana.PublicKey]Price
+}
+
+// NewOracle creates an Oracle reading prices from sources in order, and mint
+// decimals from resolver
+func NewOracle(resolver *tokens.Resolver, opts OracleOptions, sources ...Source) *Oracle {
+	ttl := opts.CacheTTL
+	if ttl == 0 {
+		ttl = DefaultCacheTTL
+	}
+	return &Oracle{
+		source: Fallback(sources),
+		tokens: resolver,
+		ttl:    ttl,
+		cache:  make(map[solana.PublicKey]Price),
+	}
+}
+
+// Tokens returns the resolver the Oracle reads decimals from
+func (o *Oracle) Tokens() *tokens.Resolver {
+	return o.tokens
+}
+
+// Price returns the USD price of mint, cached for the TTL
+func (o *Oracle) Price(ctx context.Context, mint solana.PublicKey) (Price, error) {
+	if o.ttl > 0 {
+		o.mu.Lock()
+		cached, ok := o.cache[mint]
+		o.mu.Unlock()
+		if ok && time.Since(cached.PublishTime) < o.ttl {
+			return cached, nil
+		}
+	}
+	price, err := o.source.Price(ctx, mint)
+	if err != nil {
+		return Price{}, err
+	}
+	if o.ttl > 0 {
+		// Cache by fetch time, a feed's publish time may already be seconds old
+		cached := price
+		cached.PublishTime = time.Now()
+		o.mu.Lock()
+		o.cache[mint] = cached
+		o.mu.Unlock()
+	}
+	return price, nil
+}
+
+// Value returns the USD value of amount base units of mint
+func (o *Oracle) Value(ctx context.Context, mint solana.PublicKey, amount math.Int) (float64, error) {
+	decimals, err := o.tokens.Decimals(ctx, mint)
+	if err != nil {
+		return 0, err
+	}
+	price, err := o.Price(ctx, mint)
+	if err != nil {
+		return 0, err
+	}
+	return toFloat(amount, decimals) * price.USD, nil
+}
+
+// TokenMetadata returns the decimals, symbol and name of mint, so the Oracle
+// can value portfolios, see sol.Client.SetPortfolioValuer
+func (o *Oracle) TokenMetadata(ctx context.Context, mint solana.PublicKey) (sol.TokenMetadata, error) {
+	token, err := o.tokens.Resolve(ctx, mint)
+	if err != nil {
+		return sol.TokenMetadata{}, err
+	}
+	return sol.TokenMetadata{Decimals: token.Decimals, Symbol: token.Symbol, Name: token.Name}, nil
+}
+
+// Amount returns the base units of mint worth usd, rounded down
+func (o *Oracle) Amount(ctx context.Context, mint solana.PublicKey, usd float64) (math.Int, error) {
+	decimals, err := o.tokens.Decimals(ctx, mint)
+	if err != nil {
+		return math.Int{}, err
+	}
+	price, err := o.Price(ctx, mint)
+	if err != nil {
+		return math.Int{}, err
+	}
+	if price.USD <= 0 {
+		return math.Int{}, fmt.Errorf("invalid price of %s: %v", mint.String(), price.USD)
+	}
+	units := usd / price.USD * stdmath.Pow10(int(decimals))
+	amount, ok := math.NewIntFromString(fmt.Sprintf("%.0f", stdmath.Floor(units)))
+	if !ok {
+		return math.Int{}, fmt.Errorf("invalid amount of %s for %v USD", mint.String(), usd)
+	}
+	return amount, nil
+}
+
+// toFloat converts amount base units to whole tokens
+func toFloat(amount math.Int, decimals uint8) float64 {
+	f, _ := new(big.Float).SetInt(amount.BigInt()).Float64()
+	return f / stdmath.Pow10(int(decimals))
+}
@@ -0,0 +1,139 @@
+package price
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	stdmath "math"
+	"strings"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/yimingWOW/solroute/pkg/sol"
+)
+
+// DefaultMaxAge rejects feed prices published longer ago
+const DefaultMaxAge = time.Minute
+
+// PythPushOracleProgramID owns the sponsored Pyth price feed accounts
+var PythPushOracleProgramID = solana.MustPublicKeyFromBase58("pythWSnswVUd12oZpeFP8e9CVaEqJg25g1Vtc2biRsT")
+
+// Pyth feed ids of well-known tokens
+const (
+	PythFeedSOLUSD  = "ef0d8b6fda2ceba41da15d4095d1da392a0d2f8ed0c6c7bc0f4cfac8c280b56d"
+	PythFeedUSDCUSD = "eaa020c61cc479712813461ce153894a96a6c00b21ed0cfc2798d1f9a9e9c94a"
+	PythFeedUSDTUSD = "2b89b9dc8fdf9f34709a5b106b472f0f39bb6ca9ce04b0fd7f2e971688e2e53b"
+)
+
+// Well-known mints
+var (
+	USDC = solana.MustPublicKeyFromBase58("EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v")
+	USDT = solana.MustPublicKeyFromBase58("Es9vMFrzaCERmJfrF4H2FYD4KCoNkY11McCe8BenwNYB")
+)
+
+// PythFeedAccount returns the price update account the push oracle keeps
+// current for the hex feed id on shard
+func PythFeedAccount(feedID string, shard uint16) (solana.PublicKey, error) {
+	id, err := hex.DecodeString(strings.TrimPrefix(feedID, "0x"))
+	if err != nil || len(id) != 32 {
+		return solana.PublicKey{}, fmt.Errorf("invalid pyth feed id: %q", feedID)
+	}
+	shardSeed := binary.LittleEndian.AppendUint16(nil, shard)
+	account, _, err := solana.FindProgramAddress([][]byte{shardSeed, id}, PythPushOracleProgramID)
+	return account, err
+}
+
+// DefaultPythFeeds maps WSOL, USDC and USDT to their shard 0 feed accounts
+func DefaultPythFeeds() map[solana.PublicKey]solana.PublicKey {
+	feeds := make(map[solana.PublicKey]solana.PublicKey)
+	for mint, id := range map[solana.PublicKey]string{
+		sol.WSOL: PythFeedSOLUSD,
+		USDC:     PythFeedUSDCUSD,
+		USDT:     PythFeedUSDTUSD,
+	} {
+		// The ids are constants, derivation can't fail
+		feeds[mint], _ = PythFeedAccount(id, 0)
+	}
+	return feeds
+}
+
+// Pyth reads PriceUpdateV2 accounts of the Pyth receiver program
+type Pyth struct {
+	Client *rpc.Client
+	// Feeds maps mints to their price update accounts
+	Feeds map[solana.PublicKey]solana.PublicKey
+	// MaxAge defaults to DefaultMaxAge
+	MaxAge time.Duration
+}
+
+// NewPyth creates a Pyth source with DefaultPythFeeds
+func NewPyth(client *rpc.Client) *Pyth {
+	return &Pyth{Client: client, Feeds: DefaultPythFeeds()}
+}
+
+func (p *Pyth) Price(ctx context.Context, mint solana.PublicKey) (Price, error) {
+	feed, ok := p.Feeds[mint]
+	if !ok {
+		return Price{}, fmt.Errorf("%w: no pyth feed for %s", ErrNoPrice, mint.String())
+	}
+	account, err := p.Client.GetAccountInfo(ctx, feed)
+	if err != nil {
+		return Price{}, fmt.Errorf("failed to get pyth feed %s: %w", feed.String(), sol.ClassifyError(err))
+	}
+	price, err := parsePriceUpdateV2(account.Value.Data.GetBinary())
+	if err != nil {
+		return Price{}, fmt.Errorf("pyth feed %s: %w", feed.String(), err)
+	}
+	if err := checkAge(price.PublishTime, p.MaxAge); err != nil {
+		return Price{}, fmt.Errorf("pyth feed %s: %w", feed.String(), err)
+	}
+	price.Mint = mint
+	return price, nil
+}
+
+// parsePriceUpdateV2 decodes discriminator, write authority, verification
+// level, then the price message: feed id, price, conf, exponent, publish time
+func parsePriceUpdateV2(data []byte) (Price, error) {
+	offset := 8 + 32
+	if len(data) < offset+1 {
+		return Price{}, fmt.Errorf("invalid price update length: %d", len(data))
+	}
+	switch data[offset] {
+	case 0:
+		// Partial { num_signatures } is posted without enough guardian signatures
+		return Price{}, fmt.Errorf("price update is only partially verified")
+	case 1:
+		offset++
+	default:
+		return Price{}, fmt.Errorf("invalid verification level: %d", data[offset])
+	}
+	offset += 32 // feed id
+	if len(data) < offset+28 {
+		return Price{}, fmt.Errorf("invalid price update length: %d", len(data))
+	}
+	price := int64(binary.LittleEndian.Uint64(data[offset:]))
+	conf := binary.LittleEndian.Uint64(data[offset+8:])
+	exponent := int32(binary.LittleEndian.Uint32(data[offset+16:]))
+	publishTime := int64(binary.LittleEndian.Uint64(data[offset+20:]))
+
+	scale := stdmath.Pow10(int(exponent))
+	return Price{
+		USD:         float64(price) * scale,
+		Confidence:  float64(conf) * scale,
+		PublishTime: time.Unix(publishTime, 0),
+		Source:      "pyth",
+	}, nil
+}
+
+// checkAge fails when publishTime is older than maxAge, DefaultMaxAge when zero
+func checkAge(publishTime time.Time, maxAge time.Duration) error {
+	if maxAge == 0 {
+		maxAge = DefaultMaxAge
+	}
+	if age := time.Since(publishTime); age > maxAge {
+		return fmt.Errorf("price is stale: published %s ago", age.Truncate(time.Second))
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package price
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/yimingWOW/solroute/pkg/sol"
+)
+
+// Offsets in a Switchboard on-demand PullFeedAccountData, discriminator
+// included. Results are i128 scaled by 1e18
+const (
+	switchboardLastUpdateOffset = 8 + 2208
+	switchboardValueOffset      = 8 + 2256
+	switchboardStdDevOffset     = switchboardValueOffset + 16
+	switchboardFeedSize         = switchboardStdDevOffset + 16
+)
+
+// Switchboard reads Switchboard on-demand pull feeds
+type Switchboard struct {
+	Client *rpc.Client
+	// Feeds maps mints to their pull feed accounts
+	Feeds map[solana.PublicKey]solana.PublicKey
+	// MaxAge defaults to DefaultMaxAge
+	MaxAge time.Duration
+}
+
+func (s *Switchboard) Price(ctx context.Context, mint solana.PublicKey) (Price, error) {
+	feed, ok := s.Feeds[mint]
+	if !ok {
+		return Price{}, fmt.Errorf("%w: no switchboard feed for %s", ErrNoPrice, mint.String())
+	}
+	account, err := s.Client.GetAccountInfo(ctx, feed)
+	if err != nil {
+		return Price{}, fmt.Errorf("failed to get switchboard feed %s: %w", feed.String(), sol.ClassifyError(err))
+	}
+	price, err := parsePullFeed(account.Value.Data.GetBinary())
+	if err != nil {
+		return Price{}, fmt.Errorf("switchboard feed %s: %w", feed.String(), err)
+	}
+	if err := checkAge(price.PublishTime, s.MaxAge); err != nil {
+		return Price{}, fmt.Errorf("switchboard feed %s: %w", feed.String(), err)
+	}
+	price.Mint = mint
+	return price, nil
+}
+
+func parsePullFeed(data []byte) (Price, error) {
+	if len(data) < switchboardFeedSize {
+		return Price{}, fmt.Errorf("invalid pull feed length: %d", len(data))
+	}
+	return Price{
+		USD:         decimal18(data[switchboardValueOffset:]),
+		Confidence:  decimal18(data[switchboardStdDevOffset:]),
+		PublishTime: time.Unix(int64(binary.LittleEndian.Uint64(data[switchboardLastUpdateOffset:])), 0),
+		Source:      "switchboard",
+	}, nil
+}
+
+// decimal18 converts a little-endian i128 with 18 decimals
+func decimal18(data []byte) float64 {
+	be := make([]byte, 16)
+	for i := range be {
+		be[i] = data[15-i]
+	}
+	n := new(big.Int).SetBytes(be)
+	if be[0]&0x80 != 0 {
+		n.Sub(n, new(big.Int).Lsh(big.NewInt(1), 128))
+	}
+	f, _ := new(big.Float).Quo(new(big.Float).SetInt(n), big.NewFloat(1e18)).Float64()
+	return f
+}
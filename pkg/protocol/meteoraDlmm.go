@@ -15,12 +15,21 @@ import (
 // MeteoraDlmmProtocol handles interactions with Meteora DLMM (Dynamic Liquidity Market Maker) pools
 type MeteoraDlmmProtocol struct {
 	SolClient *sol.Client
+	ProgramID solana.PublicKey
 }
 
+var _ pkg.PagedProtocol = (*MeteoraDlmmProtocol)(nil)
+
 // NewMeteoraDlmm creates a new MeteoraDlmmProtocol instance
 func NewMeteoraDlmm(solClient *sol.Client) *MeteoraDlmmProtocol {
+	return NewMeteoraDlmmWithOptions(solClient, Options{})
+}
+
+// NewMeteoraDlmmWithOptions creates a MeteoraDlmmProtocol for the deployment in opts
+func NewMeteoraDlmmWithOptions(solClient *sol.Client, opts Options) *MeteoraDlmmProtocol {
 	return &MeteoraDlmmProtocol{
 		SolClient: solClient,
+		ProgramID: opts.programID(meteora.MeteoraProgramID),
 	}
 }
 
@@ -42,21 +51,68 @@ func (protocol *MeteoraDlmmProtocol) FetchPoolsByPair(ctx context.Context, baseM
 	}
 	programAccounts = append(programAccounts, quoteBasePools...)
 
+	return protocol.decodePools(ctx, programAccounts)
+}
+
+// FetchPoolsByIDs retrieves DLMM pools by ID with getMultipleAccounts.
+// IDs of other programs' accounts or of missing accounts are left out
+func (protocol *MeteoraDlmmProtocol) FetchPoolsByIDs(ctx context.Context, poolIDs []string) ([]pkg.Pool, error) {
+	return fetchPoolsByIDs(ctx, protocol.SolClient, protocol.ProgramID, meteora.LbPairAccountSize, poolIDs, protocol.decodePools)
+}
+
+// FetchPoolsByMint retrieves the DLMM pools trading mint on either side
+func (protocol *MeteoraDlmmProtocol) FetchPoolsByMint(ctx context.Context, mint string) ([]pkg.Pool, error) {
+	var poolLayout meteora.MeteoraDlmmPool
+	accounts, err := findPoolAccountsByMint(ctx, protocol.SolClient, protocol.ProgramID, mint, meteora.LbPairAccountSize,
+		poolLayout.Offset("TokenXMint"), poolLayout.Offset("TokenYMint"))
+	if err != nil {
+		return nil, err
+	}
+	return protocol.decodePools(ctx, accounts)
+}
+
+// dlmmSummary locates the mints of a DLMM pair, which has no liquidity field
+var dlmmSummary = summaryLayout{
+	protocol:  pkg.ProtocolNameMeteoraDlmm,
+	baseMint:  (&meteora.MeteoraDlmmPool{}).Offset("TokenXMint"),
+	quoteMint: (&meteora.MeteoraDlmmPool{}).Offset("TokenYMint"),
+}
+
+// FetchPoolsByPairPaged retrieves the pools of a token pair by key first,
+// then loads them in pages, see pkg.PagedProtocol
+func (protocol *MeteoraDlmmProtocol) FetchPoolsByPairPaged(ctx context.Context, baseMint, quoteMint string, opts pkg.PageOptions, fn func([]pkg.Pool) error) error {
+	baseQuote, err := dlmmPairFilters(baseMint, quoteMint)
+	if err != nil {
+		return err
+	}
+	quoteBase, err := dlmmPairFilters(quoteMint, baseMint)
+	if err != nil {
+		return err
+	}
+	return fetchPoolPages(ctx, protocol.SolClient, protocol.ProgramID, [][]rpc.RPCFilter{baseQuote, quoteBase}, dlmmSummary, opts, protocol.decodePools, fn)
+}
+
+// decodePools decodes DLMM pool accounts and loads their bin arrays, skipping
+// the pools that fail to
+func (protocol *MeteoraDlmmProtocol) decodePools(ctx context.Context, programAccounts rpc.GetProgramAccountsResult) ([]pkg.Pool, error) {
 	pools := make([]pkg.Pool, 0, len(programAccounts))
 	for _, account := range programAccounts {
 		poolData := &meteora.MeteoraDlmmPool{}
 		if err := poolData.Decode(account.Account.Data.GetBinary()); err != nil {
-			// Skip pools that can't be decoded
+			protocol.SolClient.Logger().Warn("skipping pool that failed to decode",
+				"protocol", "meteora_dlmm", "pool", account.Pubkey.String(), "err", err)
 			continue
 		}
 
 		poolData.PoolId = account.Pubkey
+		poolData.ProgramID = protocol.ProgramID
 		if err := poolData.GetBinArrayForSwap(ctx, protocol.SolClient); err != nil {
-			// Skip pools that can't get bin array
+			protocol.SolClient.Logger().Warn("skipping pool without bin arrays",
+				"protocol", "meteora_dlmm", "pool", account.Pubkey.String(), "err", err)
 			continue
 		}
 
-		poolData.BitmapExtensionKey, _ = meteora.DeriveBinArrayBitmapExtension(poolData.PoolId)
+		poolData.BitmapExtensionKey, _ = meteora.DeriveBinArrayBitmapExtension(protocol.ProgramID, poolData.PoolId)
 		pools = append(pools, poolData)
 	}
 	return pools, nil
@@ -64,36 +120,53 @@ func (protocol *MeteoraDlmmProtocol) FetchPoolsByPair(ctx context.Context, baseM
 
 // getMeteoraDlmmPoolAccountsByTokenPair retrieves pool accounts for a specific token pair configuration
 func (protocol *MeteoraDlmmProtocol) getMeteoraDlmmPoolAccountsByTokenPair(ctx context.Context, baseMint string, quoteMint string) (rpc.GetProgramAccountsResult, error) {
-	var poolLayout meteora.MeteoraDlmmPool
-	result, err := protocol.SolClient.RpcClient.GetProgramAccountsWithOpts(ctx, meteora.MeteoraProgramID, &rpc.GetProgramAccountsOpts{
-		Filters: []rpc.RPCFilter{
-			{
-				DataSize: 904, // Meteora DLMM pool account size
-			},
-			{
-				Memcmp: &rpc.RPCFilterMemcmp{
-					Offset: poolLayout.Offset("TokenXMint"),
-					Bytes:  solana.MustPublicKeyFromBase58(baseMint).Bytes(),
-				},
-			},
-			{
-				Memcmp: &rpc.RPCFilterMemcmp{
-					Offset: poolLayout.Offset("TokenYMint"),
-					Bytes:  solana.MustPublicKeyFromBase58(quoteMint).Bytes(),
-				},
-			},
-		},
-	})
+	filters, err := dlmmPairFilters(baseMint, quoteMint)
+	if err != nil {
+		return nil, err
+	}
+	result, err := protocol.SolClient.FindProgramAccounts(ctx, protocol.ProgramID, filters)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get program accounts: %w", err)
 	}
 	return result, nil
 }
 
+// dlmmPairFilters matches the DLMM pools with baseMint as TokenX and quoteMint as TokenY
+func dlmmPairFilters(baseMint string, quoteMint string) ([]rpc.RPCFilter, error) {
+	baseKey, err := solana.PublicKeyFromBase58(baseMint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base mint address: %w", err)
+	}
+	quoteKey, err := solana.PublicKeyFromBase58(quoteMint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid quote mint address: %w", err)
+	}
+
+	var poolLayout meteora.MeteoraDlmmPool
+	return []rpc.RPCFilter{
+		{
+			DataSize: meteora.LbPairAccountSize,
+		},
+		{
+			Memcmp: &rpc.RPCFilterMemcmp{
+				Offset: poolLayout.Offset("TokenXMint"),
+				Bytes:  baseKey.Bytes(),
+			},
+		},
+		{
+			Memcmp: &rpc.RPCFilterMemcmp{
+				Offset: poolLayout.Offset("TokenYMint"),
+				Bytes:  quoteKey.Bytes(),
+			},
+		},
+	}, nil
+}
+
 // FetchPoolByID retrieves a specific Meteora DLMM pool by its ID
 func (protocol *MeteoraDlmmProtocol) FetchPoolByID(ctx context.Context, poolID string) (pkg.Pool, error) {
 	poolData := &meteora.MeteoraDlmmPool{}
-	account, err := protocol.SolClient.RpcClient.GetAccountInfo(ctx, solana.MustPublicKeyFromBase58(poolID))
+	poolKey := solana.MustPublicKeyFromBase58(poolID)
+	account, err := protocol.SolClient.RpcClient.GetAccountInfo(ctx, poolKey)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get pool account: %w", err)
 	}
@@ -101,12 +174,14 @@ func (protocol *MeteoraDlmmProtocol) FetchPoolByID(ctx context.Context, poolID s
 	if err := poolData.Decode(account.Value.Data.GetBinary()); err != nil {
 		return nil, fmt.Errorf("failed to decode pool data: %w", err)
 	}
+	poolData.PoolId = poolKey
+	poolData.ProgramID = protocol.ProgramID
 
 	if err := poolData.GetBinArrayForSwap(ctx, protocol.SolClient); err != nil {
 		return nil, fmt.Errorf("failed to get bin array for swap: %w", err)
 	}
 
-	bitmapExtensionKey, _ := meteora.DeriveBinArrayBitmapExtension(poolData.PoolId)
+	bitmapExtensionKey, _ := meteora.DeriveBinArrayBitmapExtension(protocol.ProgramID, poolData.PoolId)
 	poolData.BitmapExtensionKey = bitmapExtensionKey
 	return poolData, nil
 }
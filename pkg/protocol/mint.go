@@ -0,0 +1,46 @@
+package protocol
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/yimingWOW/solroute/pkg/sol"
+)
+
+// findPoolAccountsByMint finds the pool accounts of program, dataSize bytes
+// long, holding mint at any of offsets, one getProgramAccounts call per
+// offset. A pool holding mint at several offsets is returned once
+func findPoolAccountsByMint(ctx context.Context, client *sol.Client, program solana.PublicKey, mint string, dataSize uint64, offsets ...uint64) (rpc.GetProgramAccountsResult, error) {
+	mintKey, err := solana.PublicKeyFromBase58(mint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mint address: %w", err)
+	}
+
+	seen := make(map[solana.PublicKey]bool)
+	var accounts rpc.GetProgramAccountsResult
+	for _, offset := range offsets {
+		result, err := client.FindProgramAccounts(ctx, program, []rpc.RPCFilter{
+			{
+				DataSize: dataSize,
+			},
+			{
+				Memcmp: &rpc.RPCFilterMemcmp{
+					Offset: offset,
+					Bytes:  mintKey.Bytes(),
+				},
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch pools with mint %s: %w", mint, err)
+		}
+		for _, account := range result {
+			if !seen[account.Pubkey] {
+				seen[account.Pubkey] = true
+				accounts = append(accounts, account)
+			}
+		}
+	}
+	return accounts, nil
+}
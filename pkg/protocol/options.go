@@ -0,0 +1,38 @@
+package protocol
+
+import (
+	"github.com/gagliardetto/solana-go"
+	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/pool/pump"
+	"github.com/yimingWOW/solroute/pkg/sol"
+)
+
+// Options points a protocol at another deployment of its program, e.g. on
+// devnet or a local validator. Zero fields keep the mainnet addresses
+type Options struct {
+	// ProgramID is the program pools are discovered under and swapped through
+	ProgramID solana.PublicKey
+	// Pump overrides the global accounts of a PumpSwap deployment
+	Pump pump.Accounts
+}
+
+// programID returns o.ProgramID, or fallback when it isn't set. Another
+// program is registered with sol.RegisterProgramAlias so its errors decode
+// like fallback's
+func (o Options) programID(fallback solana.PublicKey) solana.PublicKey {
+	if o.ProgramID.IsZero() {
+		return fallback
+	}
+	sol.RegisterProgramAlias(o.ProgramID, fallback)
+	return o.ProgramID
+}
+
+// DevnetProgramIDs are the devnet deployments of the protocols, by name.
+// Meteora DLMM uses its mainnet ID on devnet; PumpSwap has no public devnet
+// deployment and isn't listed
+var DevnetProgramIDs = map[pkg.ProtocolName]solana.PublicKey{
+	pkg.ProtocolNameRaydiumAmm:  solana.MustPublicKeyFromBase58("HWy1jotHpo6UqeQxx49dpYYdQB8wj9Qk9MdxwjLvDHB8"),
+	pkg.ProtocolNameRaydiumClmm: solana.MustPublicKeyFromBase58("devi51mZmdwUJGU9hjN27vEz64Gps7uUefqxg27EAtH"),
+	pkg.ProtocolNameRaydiumCpmm: solana.MustPublicKeyFromBase58("CPMDWBwJDtYax9qW7AyRuVC19Cc4L4Vcy4n2BHAbHkCW"),
+	pkg.ProtocolNameMeteoraDlmm: solana.MustPublicKeyFromBase58("LBUZKhRxPF3XUpBCjp4YzTKgLccjZhTSDM9YuVaPwxo"),
+}
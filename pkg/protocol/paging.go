@@ -0,0 +1,150 @@
+package protocol
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/sol"
+)
+
+// maxPageSize is the getMultipleAccounts limit enforced by RPC nodes
+const maxPageSize = 100
+
+// decodePools turns the accounts of a protocol's pools into pools, skipping
+// the ones that fail to decode
+type decodePools func(ctx context.Context, accounts rpc.GetProgramAccountsResult) ([]pkg.Pool, error)
+
+// fetchPoolPages finds the keys of the accounts of program matching any of
+// filterSets with a zero length dataSlice, or their summaries at layout when
+// opts has a Shortlist, then loads the candidates opts keeps in pages,
+// decodes them and passes each page of pools to fn
+func fetchPoolPages(
+	ctx context.Context,
+	client *sol.Client,
+	program solana.PublicKey,
+	filterSets [][]rpc.RPCFilter,
+	layout summaryLayout,
+	opts pkg.PageOptions,
+	decode decodePools,
+	fn func([]pkg.Pool) error,
+) error {
+	pageSize := opts.PageSize
+	if pageSize <= 0 || pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	var zero uint64
+	slice := rpc.DataSlice{Offset: &zero, Length: &zero}
+	if opts.Shortlist != nil {
+		slice = layout.slice()
+	}
+	seen := make(map[solana.PublicKey]bool)
+	var keys []solana.PublicKey
+	for _, filters := range filterSets {
+		result, err := client.FindProgramAccountsSliced(ctx, program, filters, slice)
+		if err != nil {
+			return fmt.Errorf("failed to get pool keys: %w", err)
+		}
+		for _, keyed := range result {
+			if seen[keyed.Pubkey] || (opts.Skip != nil && opts.Skip(keyed.Pubkey)) {
+				continue
+			}
+			seen[keyed.Pubkey] = true
+			if opts.Shortlist != nil {
+				summary, err := layout.summary(keyed.Pubkey, keyed.Account.Data.GetBinary())
+				if err != nil {
+					client.Logger().Warn("skipping pool that failed to decode",
+						"protocol", layout.protocol, "pool", keyed.Pubkey.String(), "err", err)
+					continue
+				}
+				if !opts.Shortlist(summary) {
+					continue
+				}
+			}
+			keys = append(keys, keyed.Pubkey)
+		}
+	}
+
+	return loadAccountPages(ctx, client, program, keys, pageSize, func(accounts rpc.GetProgramAccountsResult) error {
+		pools, err := decode(ctx, accounts)
+		if err != nil {
+			return err
+		}
+		if len(pools) == 0 {
+			return nil
+		}
+		return fn(pools)
+	})
+}
+
+// fetchPoolsByIDs loads the pools ids name with getMultipleAccounts, a page
+// of at most 100 per request, and decodes them. Ids without an account of
+// program dataSize bytes long, the size of its pools, are left out
+func fetchPoolsByIDs(ctx context.Context, client *sol.Client, program solana.PublicKey, dataSize uint64, ids []string, decode decodePools) ([]pkg.Pool, error) {
+	seen := make(map[solana.PublicKey]bool, len(ids))
+	keys := make([]solana.PublicKey, 0, len(ids))
+	for _, id := range ids {
+		key, err := solana.PublicKeyFromBase58(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pool ID %q: %w", id, err)
+		}
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	var pools []pkg.Pool
+	err := loadAccountPages(ctx, client, program, keys, maxPageSize, func(accounts rpc.GetProgramAccountsResult) error {
+		poolAccounts := accounts[:0]
+		for _, account := range accounts {
+			if uint64(len(account.Account.Data.GetBinary())) == dataSize {
+				poolAccounts = append(poolAccounts, account)
+			}
+		}
+		page, err := decode(ctx, poolAccounts)
+		pools = append(pools, page...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pools, nil
+}
+
+// loadAccountPages fetches the accounts of keys in pages of pageSize and
+// passes the accounts of each page owned by program to fn. Missing accounts,
+// e.g. closed since they were found, are left out
+func loadAccountPages(
+	ctx context.Context,
+	client *sol.Client,
+	program solana.PublicKey,
+	keys []solana.PublicKey,
+	pageSize int,
+	fn func(rpc.GetProgramAccountsResult) error,
+) error {
+	for start := 0; start < len(keys); start += pageSize {
+		page := keys[start:min(start+pageSize, len(keys))]
+		results, err := client.RpcClient.GetMultipleAccounts(ctx, page...)
+		if err != nil {
+			return fmt.Errorf("failed to get pool accounts: %w", sol.ClassifyError(err))
+		}
+		accounts := make(rpc.GetProgramAccountsResult, 0, len(page))
+		for i, account := range results.Value {
+			if i >= len(page) || account == nil || !account.Owner.Equals(program) {
+				continue
+			}
+			accounts = append(accounts, &rpc.KeyedAccount{Pubkey: page[i], Account: account})
+		}
+		if len(accounts) == 0 {
+			continue
+		}
+		if err := fn(accounts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
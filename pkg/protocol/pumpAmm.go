@@ -13,11 +13,22 @@ import (
 
 type PumpAmmProtocol struct {
 	SolClient *sol.Client
+	ProgramID solana.PublicKey
+	Accounts  pump.Accounts
 }
 
+var _ pkg.PagedProtocol = (*PumpAmmProtocol)(nil)
+
 func NewPumpAmm(solClient *sol.Client) *PumpAmmProtocol {
+	return NewPumpAmmWithOptions(solClient, Options{})
+}
+
+// NewPumpAmmWithOptions creates a PumpAmmProtocol for the deployment in opts
+func NewPumpAmmWithOptions(solClient *sol.Client, opts Options) *PumpAmmProtocol {
 	return &PumpAmmProtocol{
 		SolClient: solClient,
+		ProgramID: opts.programID(pump.PumpSwapProgramID),
+		Accounts:  opts.Pump,
 	}
 }
 
@@ -33,20 +44,77 @@ func (p *PumpAmmProtocol) FetchPoolsByPair(ctx context.Context, baseMint string,
 		return nil, fmt.Errorf("failed to fetch pools with base token %s: %w", quoteMint, err)
 	}
 	programAccounts = append(programAccounts, data...)
+	return p.decodePools(ctx, programAccounts)
+}
+
+// FetchPoolsByIDs retrieves PumpSwap pools by ID with getMultipleAccounts.
+// IDs of other programs' accounts or of missing accounts are left out
+func (p *PumpAmmProtocol) FetchPoolsByIDs(ctx context.Context, poolIDs []string) ([]pkg.Pool, error) {
+	return fetchPoolsByIDs(ctx, p.SolClient, p.ProgramID, new(pump.PumpAMMPool).Span(), poolIDs, p.decodePools)
+}
+
+// FetchPoolsByMint retrieves the PumpSwap pools trading mint on either side
+func (p *PumpAmmProtocol) FetchPoolsByMint(ctx context.Context, mint string) ([]pkg.Pool, error) {
+	var layout pump.PumpAMMPool
+	accounts, err := findPoolAccountsByMint(ctx, p.SolClient, p.ProgramID, mint, layout.Span(),
+		layout.Offset("BaseMint"), layout.Offset("QuoteMint"))
+	if err != nil {
+		return nil, err
+	}
+	return p.decodePools(ctx, accounts)
+}
+
+// pumpSummary locates the mints and LP supply of a PumpSwap pool
+var pumpSummary = summaryLayout{
+	protocol:      pkg.ProtocolNamePumpAmm,
+	baseMint:      pump.BaseMintOffset,
+	quoteMint:     pump.QuoteMintOffset,
+	liquidity:     pump.LpSupplyOffset,
+	liquiditySize: 8,
+}
 
-	res := make([]pkg.Pool, 0)
+// FetchPoolsByPairPaged retrieves the pools of a token pair by key first,
+// then loads them in pages, see pkg.PagedProtocol
+func (p *PumpAmmProtocol) FetchPoolsByPairPaged(ctx context.Context, baseMint, quoteMint string, opts pkg.PageOptions, fn func([]pkg.Pool) error) error {
+	baseQuote, err := pumpPairFilters(baseMint, quoteMint)
+	if err != nil {
+		return err
+	}
+	quoteBase, err := pumpPairFilters(quoteMint, baseMint)
+	if err != nil {
+		return err
+	}
+	return fetchPoolPages(ctx, p.SolClient, p.ProgramID, [][]rpc.RPCFilter{baseQuote, quoteBase}, pumpSummary, opts, p.decodePools, fn)
+}
+
+// decodePools decodes PumpSwap pool accounts, skipping the ones that fail to decode
+func (p *PumpAmmProtocol) decodePools(_ context.Context, programAccounts rpc.GetProgramAccountsResult) ([]pkg.Pool, error) {
+	res := make([]pkg.Pool, 0, len(programAccounts))
 	for _, v := range programAccounts {
 		layout, err := pump.ParsePoolData(v.Account.Data.GetBinary())
 		if err != nil {
+			p.SolClient.Logger().Warn("skipping pool that failed to decode",
+				"protocol", "pump_amm", "pool", v.Pubkey.String(), "err", err)
 			continue
 		}
 		layout.PoolId = v.Pubkey
+		layout.ProgramID = p.ProgramID
+		layout.Accounts = p.Accounts
 		res = append(res, layout)
 	}
 	return res, nil
 }
 
 func (p *PumpAmmProtocol) getPumpAMMPoolAccountsByTokenPair(ctx context.Context, baseMint string, quoteMint string) (rpc.GetProgramAccountsResult, error) {
+	filters, err := pumpPairFilters(baseMint, quoteMint)
+	if err != nil {
+		return nil, err
+	}
+	return p.SolClient.FindProgramAccounts(ctx, p.ProgramID, filters)
+}
+
+// pumpPairFilters matches the PumpSwap pools with baseMint as base and quoteMint as quote
+func pumpPairFilters(baseMint string, quoteMint string) ([]rpc.RPCFilter, error) {
 	var layout pump.PumpAMMPool
 	baseMintPubkey, err := solana.PublicKeyFromBase58(baseMint)
 	if err != nil {
@@ -57,25 +125,23 @@ func (p *PumpAmmProtocol) getPumpAMMPoolAccountsByTokenPair(ctx context.Context,
 		return nil, fmt.Errorf("invalid quote mint address: %w", err)
 	}
 
-	return p.SolClient.RpcClient.GetProgramAccountsWithOpts(ctx, pump.PumpSwapProgramID, &rpc.GetProgramAccountsOpts{
-		Filters: []rpc.RPCFilter{
-			{
-				DataSize: layout.Span(),
-			},
-			{
-				Memcmp: &rpc.RPCFilterMemcmp{
-					Offset: layout.Offset("BaseMint"),
-					Bytes:  baseMintPubkey.Bytes(),
-				},
+	return []rpc.RPCFilter{
+		{
+			DataSize: layout.Span(),
+		},
+		{
+			Memcmp: &rpc.RPCFilterMemcmp{
+				Offset: layout.Offset("BaseMint"),
+				Bytes:  baseMintPubkey.Bytes(),
 			},
-			{
-				Memcmp: &rpc.RPCFilterMemcmp{
-					Offset: layout.Offset("QuoteMint"),
-					Bytes:  quoteMintPubkey.Bytes(),
-				},
+		},
+		{
+			Memcmp: &rpc.RPCFilterMemcmp{
+				Offset: layout.Offset("QuoteMint"),
+				Bytes:  quoteMintPubkey.Bytes(),
 			},
 		},
-	})
+	}, nil
 }
 
 func (p *PumpAmmProtocol) FetchPoolByID(ctx context.Context, poolId string) (pkg.Pool, error) {
@@ -94,5 +160,7 @@ func (p *PumpAmmProtocol) FetchPoolByID(ctx context.Context, poolId string) (pkg
 		return nil, fmt.Errorf("failed to parse pool data for pool %s: %w", poolId, err)
 	}
 	layout.PoolId = poolPubkey
+	layout.ProgramID = p.ProgramID
+	layout.Accounts = p.Accounts
 	return layout, nil
 }
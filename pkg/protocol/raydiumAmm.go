@@ -6,6 +6,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
@@ -16,11 +17,35 @@ import (
 
 type RaydiumAMMProtocol struct {
 	SolClient *sol.Client
+	ProgramID solana.PublicKey
+
+	marketMu    sync.Mutex
+	marketCache map[solana.PublicKey]ammMarketAccounts
+}
+
+var _ pkg.PagedProtocol = (*RaydiumAMMProtocol)(nil)
+
+// ammMarketAccounts holds the openbook market accounts an AMM v4 swap needs.
+// They never change for a given market, so they are cached by market id
+type ammMarketAccounts struct {
+	authority  solana.PublicKey
+	bids       solana.PublicKey
+	asks       solana.PublicKey
+	eventQueue solana.PublicKey
+	baseVault  solana.PublicKey
+	quoteVault solana.PublicKey
 }
 
 func NewRaydiumAmm(solClient *sol.Client) *RaydiumAMMProtocol {
+	return NewRaydiumAmmWithOptions(solClient, Options{})
+}
+
+// NewRaydiumAmmWithOptions creates a RaydiumAMMProtocol for the deployment in opts
+func NewRaydiumAmmWithOptions(solClient *sol.Client, opts Options) *RaydiumAMMProtocol {
 	return &RaydiumAMMProtocol{
-		SolClient: solClient,
+		SolClient:   solClient,
+		ProgramID:   opts.programID(raydium.RAYDIUM_AMM_PROGRAM_ID),
+		marketCache: make(map[solana.PublicKey]ammMarketAccounts),
 	}
 }
 
@@ -36,16 +61,74 @@ func (p *RaydiumAMMProtocol) FetchPoolsByPair(ctx context.Context, baseMint, quo
 		return nil, fmt.Errorf("failed to fetch pools with base token %s: %w", quoteMint, err)
 	}
 	accounts = append(accounts, programAccounts...)
+	return p.decodePools(ctx, accounts)
+}
+
+// FetchPoolsByIDs retrieves AMM pools by ID with getMultipleAccounts.
+// IDs of other programs' accounts or of missing accounts are left out
+func (p *RaydiumAMMProtocol) FetchPoolsByIDs(ctx context.Context, poolIDs []string) ([]pkg.Pool, error) {
+	return fetchPoolsByIDs(ctx, p.SolClient, p.ProgramID, new(raydium.AMMPool).Span(), poolIDs, p.decodePools)
+}
+
+// FetchPoolsByMint retrieves the AMM pools trading mint on either side
+func (p *RaydiumAMMProtocol) FetchPoolsByMint(ctx context.Context, mint string) ([]pkg.Pool, error) {
+	var layout raydium.AMMPool
+	accounts, err := findPoolAccountsByMint(ctx, p.SolClient, p.ProgramID, mint, layout.Span(),
+		layout.Offset("BaseMint"), layout.Offset("QuoteMint"))
+	if err != nil {
+		return nil, err
+	}
+	return p.decodePools(ctx, accounts)
+}
+
+// ammSummary locates the mints and LP reserve of an AMM v4 pool
+var ammSummary = summaryLayout{
+	protocol:      pkg.ProtocolNameRaydiumAmm,
+	baseMint:      (&raydium.AMMPool{}).Offset("BaseMint"),
+	quoteMint:     (&raydium.AMMPool{}).Offset("QuoteMint"),
+	liquidity:     (&raydium.AMMPool{}).Offset("LpReserve"),
+	liquiditySize: 8,
+}
 
-	res := make([]pkg.Pool, 0)
+// FetchPoolsByPairPaged retrieves the pools of a token pair by key first,
+// then loads them in pages, see pkg.PagedProtocol
+func (p *RaydiumAMMProtocol) FetchPoolsByPairPaged(ctx context.Context, baseMint, quoteMint string, opts pkg.PageOptions, fn func([]pkg.Pool) error) error {
+	baseQuote, err := ammPairFilters(baseMint, quoteMint)
+	if err != nil {
+		return err
+	}
+	quoteBase, err := ammPairFilters(quoteMint, baseMint)
+	if err != nil {
+		return err
+	}
+	return fetchPoolPages(ctx, p.SolClient, p.ProgramID, [][]rpc.RPCFilter{baseQuote, quoteBase}, ammSummary, opts, p.decodePools, fn)
+}
+
+// decodePools decodes AMM pool accounts and resolves their markets, skipping
+// the pools that fail to decode
+func (p *RaydiumAMMProtocol) decodePools(ctx context.Context, accounts rpc.GetProgramAccountsResult) ([]pkg.Pool, error) {
+	layouts := make([]*raydium.AMMPool, 0, len(accounts))
+	marketIds := make([]solana.PublicKey, 0, len(accounts))
 	for _, v := range accounts {
 		layout := &raydium.AMMPool{}
 		if err := layout.Decode(v.Account.Data.GetBinary()); err != nil {
+			p.SolClient.Logger().Warn("skipping pool that failed to decode",
+				"protocol", "raydium_amm", "pool", v.Pubkey.String(), "err", err)
 			continue
 		}
 		layout.PoolId = v.Pubkey
+		layouts = append(layouts, layout)
+		marketIds = append(marketIds, layout.MarketId)
+	}
+	// Resolve all markets in one batch before processing the pools
+	if err := p.loadMarkets(ctx, marketIds); err != nil {
+		return nil, err
+	}
+
+	res := make([]pkg.Pool, 0, len(layouts))
+	for _, layout := range layouts {
 		if err := p.processAMMPool(ctx, layout); err != nil {
-			return nil, fmt.Errorf("failed to process AMM pool %s: %w", v.Pubkey.String(), err)
+			return nil, fmt.Errorf("failed to process AMM pool %s: %w", layout.PoolId.String(), err)
 		}
 		res = append(res, layout)
 	}
@@ -53,6 +136,15 @@ func (p *RaydiumAMMProtocol) FetchPoolsByPair(ctx context.Context, baseMint, quo
 }
 
 func (p *RaydiumAMMProtocol) getAMMPoolAccountsByTokenPair(ctx context.Context, baseMint string, quoteMint string) (rpc.GetProgramAccountsResult, error) {
+	filters, err := ammPairFilters(baseMint, quoteMint)
+	if err != nil {
+		return nil, err
+	}
+	return p.SolClient.FindProgramAccounts(ctx, p.ProgramID, filters)
+}
+
+// ammPairFilters matches the AMM pools with baseMint as base and quoteMint as quote
+func ammPairFilters(baseMint string, quoteMint string) ([]rpc.RPCFilter, error) {
 	var layout raydium.AMMPool
 	baseMintPubkey, err := solana.PublicKeyFromBase58(baseMint)
 	if err != nil {
@@ -63,25 +155,23 @@ func (p *RaydiumAMMProtocol) getAMMPoolAccountsByTokenPair(ctx context.Context,
 		return nil, fmt.Errorf("invalid quote mint address: %w", err)
 	}
 
-	return p.SolClient.RpcClient.GetProgramAccountsWithOpts(ctx, raydium.RAYDIUM_AMM_PROGRAM_ID, &rpc.GetProgramAccountsOpts{
-		Filters: []rpc.RPCFilter{
-			{
-				DataSize: layout.Span(),
-			},
-			{
-				Memcmp: &rpc.RPCFilterMemcmp{
-					Offset: layout.Offset("BaseMint"),
-					Bytes:  baseMintPubkey.Bytes(),
-				},
+	return []rpc.RPCFilter{
+		{
+			DataSize: layout.Span(),
+		},
+		{
+			Memcmp: &rpc.RPCFilterMemcmp{
+				Offset: layout.Offset("BaseMint"),
+				Bytes:  baseMintPubkey.Bytes(),
 			},
-			{
-				Memcmp: &rpc.RPCFilterMemcmp{
-					Offset: layout.Offset("QuoteMint"),
-					Bytes:  quoteMintPubkey.Bytes(),
-				},
+		},
+		{
+			Memcmp: &rpc.RPCFilterMemcmp{
+				Offset: layout.Offset("QuoteMint"),
+				Bytes:  quoteMintPubkey.Bytes(),
 			},
 		},
-	})
+	}, nil
 }
 
 // FetchPoolByID fetches a specific pool by its ID
@@ -107,6 +197,13 @@ func (r *RaydiumAMMProtocol) FetchPoolByID(ctx context.Context, poolID string) (
 	return layout, nil
 }
 
+// getVaultSigner derives the openbook vault signer from the nonce stored in the market
+func getVaultSigner(programID solana.PublicKey, marketID solana.PublicKey, nonce uint64) (solana.PublicKey, error) {
+	nonceBytes := make([]byte, 8)
+	binary.LittleEndian.PutUint64(nonceBytes, nonce)
+	return solana.CreateProgramAddress([][]byte{marketID.Bytes(), nonceBytes}, programID)
+}
+
 func getAssociatedAuthority(programID solana.PublicKey, marketID solana.PublicKey) (solana.PublicKey, uint8, error) {
 	seeds := [][]byte{marketID.Bytes()}
 	var nonce uint8 = 0
@@ -134,27 +231,98 @@ func int8ToBuf(value uint8) []byte {
 }
 
 func (p *RaydiumAMMProtocol) processAMMPool(ctx context.Context, layout *raydium.AMMPool) error {
-	marketAccount, err := p.SolClient.RpcClient.GetAccountInfo(ctx, layout.MarketId)
-	if err != nil {
-		return fmt.Errorf("failed to get market account: %w", err)
+	if err := p.loadMarkets(ctx, []solana.PublicKey{layout.MarketId}); err != nil {
+		return err
 	}
-
-	var marketLayout raydium.MarketStateLayoutV3
-	if err := marketLayout.Decode(marketAccount.Value.Data.GetBinary()); err != nil {
-		return fmt.Errorf("failed to decode market layout: %w", err)
+	p.marketMu.Lock()
+	market, ok := p.marketCache[layout.MarketId]
+	p.marketMu.Unlock()
+	if !ok {
+		return fmt.Errorf("market account %s not found", layout.MarketId.String())
 	}
 
-	authority, _, err := solana.FindProgramAddress([][]byte{{97, 109, 109, 32, 97, 117, 116, 104, 111, 114, 105, 116, 121}}, raydium.RAYDIUM_AMM_PROGRAM_ID)
+	authority, _, err := solana.FindProgramAddress([][]byte{{97, 109, 109, 32, 97, 117, 116, 104, 111, 114, 105, 116, 121}}, p.ProgramID)
 	if err != nil {
 		return fmt.Errorf("failed to find program address: %w", err)
 	}
 
-	marketAuthority, _, err := getAssociatedAuthority(marketAccount.Value.Owner, marketLayout.OwnAddress)
-	if err != nil {
-		return fmt.Errorf("failed to get associated authority: %w", err)
+	layout.ProgramID = p.ProgramID
+	layout.Authority = authority
+	layout.MarketAuthority = market.authority
+	layout.MarketBids = market.bids
+	layout.MarketAsks = market.asks
+	layout.MarketEventQueue = market.eventQueue
+	layout.MarketBaseVault = market.baseVault
+	layout.MarketQuoteVault = market.quoteVault
+	return nil
+}
+
+// loadMarkets fetches and caches the openbook market accounts not cached yet
+func (p *RaydiumAMMProtocol) loadMarkets(ctx context.Context, marketIds []solana.PublicKey) error {
+	p.marketMu.Lock()
+	if p.marketCache == nil {
+		p.marketCache = make(map[solana.PublicKey]ammMarketAccounts)
 	}
+	missing := make([]solana.PublicKey, 0, len(marketIds))
+	seen := make(map[solana.PublicKey]bool, len(marketIds))
+	for _, marketId := range marketIds {
+		if _, ok := p.marketCache[marketId]; ok || seen[marketId] {
+			continue
+		}
+		seen[marketId] = true
+		missing = append(missing, marketId)
+	}
+	p.marketMu.Unlock()
 
-	layout.Authority = authority
-	layout.MarketAuthority = marketAuthority
+	// getMultipleAccounts accepts at most 100 keys per request
+	const batchSize = 100
+	for start := 0; start < len(missing); start += batchSize {
+		end := start + batchSize
+		if end > len(missing) {
+			end = len(missing)
+		}
+		batch := missing[start:end]
+		results, err := p.SolClient.RpcClient.GetMultipleAccounts(ctx, batch...)
+		if err != nil {
+			return fmt.Errorf("failed to get market accounts: %w", err)
+		}
+		for i, result := range results.Value {
+			if result == nil {
+				continue
+			}
+			market, err := parseMarketAccounts(result.Owner, result.Data.GetBinary())
+			if err != nil {
+				return fmt.Errorf("failed to parse market %s: %w", batch[i].String(), err)
+			}
+			p.marketMu.Lock()
+			p.marketCache[batch[i]] = market
+			p.marketMu.Unlock()
+		}
+	}
 	return nil
 }
+
+func parseMarketAccounts(marketProgramId solana.PublicKey, data []byte) (ammMarketAccounts, error) {
+	var marketLayout raydium.MarketStateLayoutV3
+	if err := marketLayout.Decode(data); err != nil {
+		return ammMarketAccounts{}, fmt.Errorf("failed to decode market layout: %w", err)
+	}
+
+	marketAuthority, err := getVaultSigner(marketProgramId, marketLayout.OwnAddress, marketLayout.VaultSignerNonce)
+	if err != nil {
+		// Fall back to searching for a nonce if the stored one does not derive an address
+		marketAuthority, _, err = getAssociatedAuthority(marketProgramId, marketLayout.OwnAddress)
+		if err != nil {
+			return ammMarketAccounts{}, fmt.Errorf("failed to get associated authority: %w", err)
+		}
+	}
+
+	return ammMarketAccounts{
+		authority:  marketAuthority,
+		bids:       marketLayout.Bids,
+		asks:       marketLayout.Asks,
+		eventQueue: marketLayout.EventQueue,
+		baseVault:  marketLayout.BaseVault,
+		quoteVault: marketLayout.QuoteVault,
+	}, nil
+}
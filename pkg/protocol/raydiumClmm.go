@@ -14,11 +14,20 @@ import (
 
 type RaydiumClmmProtocol struct {
 	SolClient *sol.Client
+	ProgramID solana.PublicKey
 }
 
+var _ pkg.PagedProtocol = (*RaydiumClmmProtocol)(nil)
+
 func NewRaydiumClmm(solClient *sol.Client) *RaydiumClmmProtocol {
+	return NewRaydiumClmmWithOptions(solClient, Options{})
+}
+
+// NewRaydiumClmmWithOptions creates a RaydiumClmmProtocol for the deployment in opts
+func NewRaydiumClmmWithOptions(solClient *sol.Client, opts Options) *RaydiumClmmProtocol {
 	return &RaydiumClmmProtocol{
 		SolClient: solClient,
+		ProgramID: opts.programID(raydium.RAYDIUM_CLMM_PROGRAM_ID),
 	}
 }
 
@@ -34,38 +43,87 @@ func (p *RaydiumClmmProtocol) FetchPoolsByPair(ctx context.Context, baseMint str
 		return nil, fmt.Errorf("failed to fetch pools with base token %s: %w", quoteMint, err)
 	}
 	accounts = append(accounts, programAccounts...)
+	return p.decodePools(ctx, accounts)
+}
+
+// FetchPoolsByIDs retrieves CLMM pools by ID with getMultipleAccounts.
+// IDs of other programs' accounts or of missing accounts are left out
+func (p *RaydiumClmmProtocol) FetchPoolsByIDs(ctx context.Context, poolIDs []string) ([]pkg.Pool, error) {
+	return fetchPoolsByIDs(ctx, p.SolClient, p.ProgramID, uint64(new(raydium.CLMMPool).Span()), poolIDs, p.decodePools)
+}
+
+// FetchPoolsByMint retrieves the CLMM pools trading mint on either side
+func (p *RaydiumClmmProtocol) FetchPoolsByMint(ctx context.Context, mint string) ([]pkg.Pool, error) {
+	var layout raydium.CLMMPool
+	accounts, err := findPoolAccountsByMint(ctx, p.SolClient, p.ProgramID, mint, uint64(layout.Span()),
+		layout.Offset("TokenMint0"), layout.Offset("TokenMint1"))
+	if err != nil {
+		return nil, err
+	}
+	return p.decodePools(ctx, accounts)
+}
+
+// clmmSummary locates the mints and active liquidity of a CLMM pool
+var clmmSummary = summaryLayout{
+	protocol:      pkg.ProtocolNameRaydiumClmm,
+	baseMint:      (&raydium.CLMMPool{}).Offset("TokenMint0"),
+	quoteMint:     (&raydium.CLMMPool{}).Offset("TokenMint1"),
+	liquidity:     (&raydium.CLMMPool{}).Offset("Liquidity"),
+	liquiditySize: 16,
+}
+
+// FetchPoolsByPairPaged retrieves the pools of a token pair by key first,
+// then loads them in pages, see pkg.PagedProtocol
+func (p *RaydiumClmmProtocol) FetchPoolsByPairPaged(ctx context.Context, baseMint, quoteMint string, opts pkg.PageOptions, fn func([]pkg.Pool) error) error {
+	baseQuote, err := clmmPairFilters(baseMint, quoteMint)
+	if err != nil {
+		return err
+	}
+	quoteBase, err := clmmPairFilters(quoteMint, baseMint)
+	if err != nil {
+		return err
+	}
+	return fetchPoolPages(ctx, p.SolClient, p.ProgramID, [][]rpc.RPCFilter{baseQuote, quoteBase}, clmmSummary, opts, p.decodePools, fn)
+}
 
-	res := make([]pkg.Pool, 0)
+// decodePools decodes CLMM pool accounts and loads their configs, skipping
+// the pools that fail to
+func (p *RaydiumClmmProtocol) decodePools(ctx context.Context, accounts rpc.GetProgramAccountsResult) ([]pkg.Pool, error) {
+	res := make([]pkg.Pool, 0, len(accounts))
 	for _, v := range accounts {
 		data := v.Account.Data.GetBinary()
 		layout := &raydium.CLMMPool{}
 		if err := layout.Decode(data); err != nil {
+			p.SolClient.Logger().Warn("skipping pool that failed to decode",
+				"protocol", "raydium_clmm", "pool", v.Pubkey.String(), "err", err)
 			continue
 		}
 		layout.PoolId = v.Pubkey
-
-		ammConfigData, err := p.SolClient.RpcClient.GetAccountInfo(ctx, layout.AmmConfig)
-		if err != nil {
+		if err := p.initPool(ctx, layout); err != nil {
+			p.SolClient.Logger().Warn("skipping pool that failed to load",
+				"protocol", "raydium_clmm", "pool", v.Pubkey.String(), "err", err)
 			continue
 		}
-		feeRate, err := parseAmmConfig(ammConfigData.Value.Data.GetBinary())
-		if err != nil {
-			continue
-		}
-		layout.FeeRate = feeRate
-
-		exBitmapAddress, _, err := raydium.GetPdaExBitmapAccount(raydium.RAYDIUM_CLMM_PROGRAM_ID, layout.PoolId)
-		if err != nil {
-			continue
-		}
-		layout.ExBitmapAddress = exBitmapAddress
-
 		res = append(res, layout)
 	}
 	return res, nil
 }
 
 func (p *RaydiumClmmProtocol) getCLMMPoolAccountsByTokenPair(ctx context.Context, baseMint string, quoteMint string) (rpc.GetProgramAccountsResult, error) {
+	filters, err := clmmPairFilters(baseMint, quoteMint)
+	if err != nil {
+		return nil, err
+	}
+	result, err := p.SolClient.FindProgramAccounts(ctx, p.ProgramID, filters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pools: %w", err)
+	}
+
+	return result, nil
+}
+
+// clmmPairFilters matches the CLMM pools with baseMint as token0 and quoteMint as token1
+func clmmPairFilters(baseMint string, quoteMint string) ([]rpc.RPCFilter, error) {
 	baseKey, err := solana.PublicKeyFromBase58(baseMint)
 	if err != nil {
 		return nil, fmt.Errorf("invalid base mint address: %w", err)
@@ -76,30 +134,23 @@ func (p *RaydiumClmmProtocol) getCLMMPoolAccountsByTokenPair(ctx context.Context
 	}
 
 	var knownPoolLayout raydium.CLMMPool
-	result, err := p.SolClient.RpcClient.GetProgramAccountsWithOpts(ctx, raydium.RAYDIUM_CLMM_PROGRAM_ID, &rpc.GetProgramAccountsOpts{
-		Filters: []rpc.RPCFilter{
-			{
-				DataSize: uint64(knownPoolLayout.Span()),
-			},
-			{
-				Memcmp: &rpc.RPCFilterMemcmp{
-					Offset: knownPoolLayout.Offset("TokenMint0"),
-					Bytes:  baseKey.Bytes(),
-				},
+	return []rpc.RPCFilter{
+		{
+			DataSize: uint64(knownPoolLayout.Span()),
+		},
+		{
+			Memcmp: &rpc.RPCFilterMemcmp{
+				Offset: knownPoolLayout.Offset("TokenMint0"),
+				Bytes:  baseKey.Bytes(),
 			},
-			{
-				Memcmp: &rpc.RPCFilterMemcmp{
-					Offset: knownPoolLayout.Offset("TokenMint1"),
-					Bytes:  quoteKey.Bytes(),
-				},
+		},
+		{
+			Memcmp: &rpc.RPCFilterMemcmp{
+				Offset: knownPoolLayout.Offset("TokenMint1"),
+				Bytes:  quoteKey.Bytes(),
 			},
 		},
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to get pools: %w", err)
-	}
-
-	return result, nil
+	}, nil
 }
 
 func (r *RaydiumClmmProtocol) FetchPoolByID(ctx context.Context, poolId string) (pkg.Pool, error) {
@@ -117,9 +168,35 @@ func (r *RaydiumClmmProtocol) FetchPoolByID(ctx context.Context, poolId string)
 	if err := layout.Decode(data); err != nil {
 		return nil, fmt.Errorf("failed to decode pool data for %s: %w", poolId, err)
 	}
+	layout.PoolId = poolIdKey
+	if err := r.initPool(ctx, layout); err != nil {
+		return nil, fmt.Errorf("failed to init pool %s: %w", poolId, err)
+	}
 	return layout, nil
 }
 
+// initPool fills in the fee rate and the tick array bitmap extension, which
+// live outside the pool account
+func (p *RaydiumClmmProtocol) initPool(ctx context.Context, layout *raydium.CLMMPool) error {
+	layout.ProgramID = p.ProgramID
+	ammConfigData, err := p.SolClient.RpcClient.GetAccountInfo(ctx, layout.AmmConfig)
+	if err != nil {
+		return fmt.Errorf("failed to get amm config: %w", err)
+	}
+	feeRate, err := parseAmmConfig(ammConfigData.Value.Data.GetBinary())
+	if err != nil {
+		return err
+	}
+	layout.FeeRate = feeRate
+
+	exBitmapAddress, _, err := raydium.GetPdaExBitmapAccount(p.ProgramID, layout.PoolId)
+	if err != nil {
+		return fmt.Errorf("failed to derive bitmap extension address: %w", err)
+	}
+	layout.ExBitmapAddress = exBitmapAddress
+	return nil
+}
+
 func parseAmmConfig(data []byte) (uint32, error) {
 	var ammConfig AmmConfig
 	if err := ammConfig.Decode(data); err != nil {
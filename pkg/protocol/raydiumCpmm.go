@@ -14,12 +14,21 @@ import (
 // RaydiumCpmmProtocol represents the Raydium CPMM protocol implementation
 type RaydiumCpmmProtocol struct {
 	SolClient *sol.Client
+	ProgramID solana.PublicKey
 }
 
+var _ pkg.PagedProtocol = (*RaydiumCpmmProtocol)(nil)
+
 // NewRaydiumCpmm creates a new instance of RaydiumCpmmProtocol
 func NewRaydiumCpmm(solClient *sol.Client) *RaydiumCpmmProtocol {
+	return NewRaydiumCpmmWithOptions(solClient, Options{})
+}
+
+// NewRaydiumCpmmWithOptions creates a RaydiumCpmmProtocol for the deployment in opts
+func NewRaydiumCpmmWithOptions(solClient *sol.Client, opts Options) *RaydiumCpmmProtocol {
 	return &RaydiumCpmmProtocol{
 		SolClient: solClient,
+		ProgramID: opts.programID(raydium.RAYDIUM_CPMM_PROGRAM_ID),
 	}
 }
 
@@ -31,38 +40,91 @@ func (p *RaydiumCpmmProtocol) FetchPoolsByPair(ctx context.Context, baseMint str
 		return nil, fmt.Errorf("failed to fetch pools with base token %s: %w", baseMint, err)
 	}
 
-	pools := make([]pkg.Pool, 0)
-	for _, account := range programAccounts {
-		data := account.Account.Data.GetBinary()
-		pool := &raydium.CPMMPool{}
-		if err := pool.Decode(data); err != nil {
-			continue
-		}
-		pool.PoolId = account.Pubkey
-		pools = append(pools, pool)
-	}
-
 	// Fetch pools with quoteMint as token0
-	programAccounts, err = p.getCPMMPoolAccountsByTokenPair(ctx, quoteMint, baseMint)
+	quoteBaseAccounts, err := p.getCPMMPoolAccountsByTokenPair(ctx, quoteMint, baseMint)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch pools with base token %s: %w", quoteMint, err)
 	}
+	programAccounts = append(programAccounts, quoteBaseAccounts...)
+
+	return p.decodePools(ctx, programAccounts)
+}
+
+// FetchPoolsByIDs retrieves CPMM pools by ID with getMultipleAccounts.
+// IDs of other programs' accounts or of missing accounts are left out
+func (p *RaydiumCpmmProtocol) FetchPoolsByIDs(ctx context.Context, poolIDs []string) ([]pkg.Pool, error) {
+	return fetchPoolsByIDs(ctx, p.SolClient, p.ProgramID, 637, poolIDs, p.decodePools)
+}
+
+// FetchPoolsByMint retrieves the CPMM pools trading mint on either side
+func (p *RaydiumCpmmProtocol) FetchPoolsByMint(ctx context.Context, mint string) ([]pkg.Pool, error) {
+	var layout raydium.CPMMPool
+	accounts, err := findPoolAccountsByMint(ctx, p.SolClient, p.ProgramID, mint, 637,
+		layout.Offset("Token0Mint"), layout.Offset("Token1Mint"))
+	if err != nil {
+		return nil, err
+	}
+	return p.decodePools(ctx, accounts)
+}
+
+// cpmmSummary locates the mints and LP supply of a CPMM pool
+var cpmmSummary = summaryLayout{
+	protocol:      pkg.ProtocolNameRaydiumCpmm,
+	baseMint:      (&raydium.CPMMPool{}).Offset("Token0Mint"),
+	quoteMint:     (&raydium.CPMMPool{}).Offset("Token1Mint"),
+	liquidity:     (&raydium.CPMMPool{}).Offset("LpSupply"),
+	liquiditySize: 8,
+}
+
+// FetchPoolsByPairPaged retrieves the pools of a token pair by key first,
+// then loads them in pages, see pkg.PagedProtocol
+func (p *RaydiumCpmmProtocol) FetchPoolsByPairPaged(ctx context.Context, baseMint, quoteMint string, opts pkg.PageOptions, fn func([]pkg.Pool) error) error {
+	baseQuote, err := cpmmPairFilters(baseMint, quoteMint)
+	if err != nil {
+		return err
+	}
+	quoteBase, err := cpmmPairFilters(quoteMint, baseMint)
+	if err != nil {
+		return err
+	}
+	return fetchPoolPages(ctx, p.SolClient, p.ProgramID, [][]rpc.RPCFilter{baseQuote, quoteBase}, cpmmSummary, opts, p.decodePools, fn)
+}
 
+// decodePools decodes CPMM pool accounts, skipping the ones that fail to decode
+func (p *RaydiumCpmmProtocol) decodePools(_ context.Context, programAccounts rpc.GetProgramAccountsResult) ([]pkg.Pool, error) {
+	pools := make([]pkg.Pool, 0, len(programAccounts))
 	for _, account := range programAccounts {
 		data := account.Account.Data.GetBinary()
 		pool := &raydium.CPMMPool{}
 		if err := pool.Decode(data); err != nil {
+			p.SolClient.Logger().Warn("skipping pool that failed to decode",
+				"protocol", "raydium_cpmm", "pool", account.Pubkey.String(), "err", err)
 			continue
 		}
 		pool.PoolId = account.Pubkey
+		pool.ProgramID = p.ProgramID
 		pools = append(pools, pool)
 	}
-
 	return pools, nil
 }
 
 // getCPMMPoolAccountsByTokenPair retrieves CPMM pool accounts for a given token pair
 func (p *RaydiumCpmmProtocol) getCPMMPoolAccountsByTokenPair(ctx context.Context, baseMint string, quoteMint string) (rpc.GetProgramAccountsResult, error) {
+	filters, err := cpmmPairFilters(baseMint, quoteMint)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := p.SolClient.FindProgramAccounts(ctx, p.ProgramID, filters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pools: %w", err)
+	}
+
+	return result, nil
+}
+
+// cpmmPairFilters matches the CPMM pools with baseMint as token0 and quoteMint as token1
+func cpmmPairFilters(baseMint string, quoteMint string) ([]rpc.RPCFilter, error) {
 	baseKey, err := solana.PublicKeyFromBase58(baseMint)
 	if err != nil {
 		return nil, fmt.Errorf("invalid base mint address: %w", err)
@@ -74,7 +136,7 @@ func (p *RaydiumCpmmProtocol) getCPMMPoolAccountsByTokenPair(ctx context.Context
 	}
 
 	var layout raydium.CPMMPool
-	filters := []rpc.RPCFilter{
+	return []rpc.RPCFilter{
 		{
 			DataSize: 637,
 		},
@@ -90,16 +152,7 @@ func (p *RaydiumCpmmProtocol) getCPMMPoolAccountsByTokenPair(ctx context.Context
 				Bytes:  quoteKey.Bytes(),
 			},
 		},
-	}
-
-	result, err := p.SolClient.RpcClient.GetProgramAccountsWithOpts(ctx, raydium.RAYDIUM_CPMM_PROGRAM_ID, &rpc.GetProgramAccountsOpts{
-		Filters: filters,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to get pools: %w", err)
-	}
-
-	return result, nil
+	}, nil
 }
 
 // FetchPoolByID retrieves a CPMM pool by its ID
@@ -114,6 +167,7 @@ func (p *RaydiumCpmmProtocol) FetchPoolByID(ctx context.Context, poolID string)
 		return nil, fmt.Errorf("failed to decode pool data for %s: %w", poolID, err)
 	}
 	pool.PoolId = solana.MustPublicKeyFromBase58(poolID)
+	pool.ProgramID = p.ProgramID
 
 	return pool, nil
 }
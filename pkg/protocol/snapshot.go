@@ -0,0 +1,64 @@
+package protocol
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+
+	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/pool/meteora"
+	"github.com/yimingWOW/solroute/pkg/pool/pump"
+	"github.com/yimingWOW/solroute/pkg/pool/raydium"
+)
+
+// poolSnapshot tags the MarshalBinary encoding of a pool with its protocol
+type poolSnapshot struct {
+	Protocol pkg.ProtocolName `json:"protocol"`
+	State    json.RawMessage  `json:"state"`
+}
+
+// MarshalPool encodes the decoded state of pool, tagged with its protocol, so
+// it can be stored or sent to another process and restored by UnmarshalPool
+func MarshalPool(pool pkg.Pool) ([]byte, error) {
+	marshaler, ok := pool.(encoding.BinaryMarshaler)
+	if !ok {
+		return nil, fmt.Errorf("pool %s of protocol %s: %w", pool.GetID(), pool.ProtocolName(), pkg.ErrUnsupported)
+	}
+	state, err := marshaler.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode pool %s: %w", pool.GetID(), err)
+	}
+	return json.Marshal(poolSnapshot{Protocol: pool.ProtocolName(), State: state})
+}
+
+// UnmarshalPool restores a pool encoded by MarshalPool. The pool quotes from
+// the encoded state until it is refreshed or receives account updates, or
+// for pkg.MaxStateAge on pools re-reading older state
+func UnmarshalPool(data []byte) (pkg.Pool, error) {
+	var snapshot poolSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to decode pool snapshot: %w", err)
+	}
+	var pool interface {
+		pkg.Pool
+		encoding.BinaryUnmarshaler
+	}
+	switch snapshot.Protocol {
+	case pkg.ProtocolNameRaydiumAmm:
+		pool = &raydium.AMMPool{}
+	case pkg.ProtocolNameRaydiumClmm:
+		pool = &raydium.CLMMPool{}
+	case pkg.ProtocolNameRaydiumCpmm:
+		pool = &raydium.CPMMPool{}
+	case pkg.ProtocolNameMeteoraDlmm:
+		pool = &meteora.MeteoraDlmmPool{}
+	case pkg.ProtocolNamePumpAmm:
+		pool = &pump.PumpAMMPool{}
+	default:
+		return nil, fmt.Errorf("unknown protocol %q", snapshot.Protocol)
+	}
+	if err := pool.UnmarshalBinary(snapshot.State); err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
@@ -0,0 +1,59 @@
+package protocol
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/yimingWOW/solroute/pkg"
+	"lukechampine.com/uint128"
+)
+
+// summaryLayout locates the fields of a pool account read into a
+// pkg.PoolSummary: its mints and, when it has one, its liquidity field
+type summaryLayout struct {
+	protocol  pkg.ProtocolName
+	baseMint  uint64
+	quoteMint uint64
+	liquidity uint64
+	// liquiditySize is 8 for a u64 field, 16 for a u128 one and zero for
+	// pools without a liquidity field
+	liquiditySize uint64
+}
+
+// slice returns the smallest range of the account holding every field, as a
+// dataSlice only selects one contiguous range
+func (l summaryLayout) slice() rpc.DataSlice {
+	start, end := min(l.baseMint, l.quoteMint), max(l.baseMint, l.quoteMint)+32
+	if l.liquiditySize > 0 {
+		start, end = min(start, l.liquidity), max(end, l.liquidity+l.liquiditySize)
+	}
+	length := end - start
+	return rpc.DataSlice{Offset: &start, Length: &length}
+}
+
+// summary decodes the range of the account of pool id returned for slice
+func (l summaryLayout) summary(id solana.PublicKey, data []byte) (pkg.PoolSummary, error) {
+	slice := l.slice()
+	if uint64(len(data)) < *slice.Length {
+		return pkg.PoolSummary{}, fmt.Errorf("summary too short: expected %d bytes, got %d", *slice.Length, len(data))
+	}
+	field := func(offset, size uint64) []byte {
+		return data[offset-*slice.Offset : offset-*slice.Offset+size]
+	}
+	summary := pkg.PoolSummary{
+		ID:        id,
+		Protocol:  l.protocol,
+		BaseMint:  solana.PublicKeyFromBytes(field(l.baseMint, 32)),
+		QuoteMint: solana.PublicKeyFromBytes(field(l.quoteMint, 32)),
+	}
+	switch l.liquiditySize {
+	case 8:
+		summary.Liquidity = math.NewIntFromUint64(binary.LittleEndian.Uint64(field(l.liquidity, 8)))
+	case 16:
+		summary.Liquidity = math.NewIntFromBigInt(uint128.FromBytes(field(l.liquidity, 16)).Big())
+	}
+	return summary, nil
+}
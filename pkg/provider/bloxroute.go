@@ -0,0 +1,92 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/yimingWOW/solroute/pkg/sol"
+)
+
+// DefaultBloXrouteEndpoint is the New York Trader API region
+const DefaultBloXrouteEndpoint = "https://ny.solana.dex.blxrbdn.com"
+
+// BloXroute sends transactions through the bloXroute Trader API submit endpoint
+type BloXroute struct {
+	url        string
+	authHeader string
+	httpClient *http.Client
+	// FrontRunningProtection only forwards the transaction to validators
+	// bloXroute trusts not to sandwich it. Enabled by NewBloXroute
+	FrontRunningProtection bool
+	// UseStakedRPCs also submits through bloXroute's staked connections
+	UseStakedRPCs bool
+}
+
+var _ sol.TransactionSender = (*BloXroute)(nil)
+
+// NewBloXroute returns a sender for a Trader API endpoint, DefaultBloXrouteEndpoint
+// when empty, authenticating with the account's authorization header
+func NewBloXroute(endpoint, authHeader string) *BloXroute {
+	if endpoint == "" {
+		endpoint = DefaultBloXrouteEndpoint
+	}
+	return &BloXroute{
+		url:                    strings.TrimSuffix(endpoint, "/") + "/api/v2/submit",
+		authHeader:             authHeader,
+		httpClient:             http.DefaultClient,
+		FrontRunningProtection: true,
+	}
+}
+
+// SendTransaction submits tx, which bloXroute expects to pay it a tip
+func (b *BloXroute) SendTransaction(ctx context.Context, tx *solana.Transaction) (solana.Signature, error) {
+	content, err := tx.ToBase64()
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to encode transaction: %w", err)
+	}
+	body, err := json.Marshal(map[string]any{
+		"transaction":            map[string]string{"content": content},
+		"skipPreFlight":          true,
+		"frontRunningProtection": b.FrontRunningProtection,
+		"useStakedRPCs":          b.UseStakedRPCs,
+	})
+	if err != nil {
+		return solana.Signature{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.url, bytes.NewReader(body))
+	if err != nil {
+		return solana.Signature{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", b.authHeader)
+
+	resp, err := b.httpClient.Do(req)
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to submit transaction: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return solana.Signature{}, fmt.Errorf("bloxroute submit failed: %s: %s", resp.Status, bytes.TrimSpace(data))
+	}
+	var result struct {
+		Signature string `json:"signature"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to decode response: %w", err)
+	}
+	sig, err := solana.SignatureFromBase58(result.Signature)
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("invalid signature in response: %w", err)
+	}
+	return sig, nil
+}
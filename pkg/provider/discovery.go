@@ -0,0 +1,55 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/yimingWOW/solroute/pkg/sol"
+)
+
+// Discovery backend names accepted in DiscoveryConfig.Backends
+const (
+	DiscoveryGPA    = "gpa"    // getProgramAccounts on the client's RPC node
+	DiscoveryHelius = "helius" // Helius getProgramAccountsV2
+	DiscoveryLocal  = "local"  // a sol.LocalIndex
+)
+
+// DiscoveryConfig selects the pool discovery backends, e.g. loaded from a config file
+type DiscoveryConfig struct {
+	// Backends in the order they are tried, falling back to the next on error
+	Backends []string `json:"backends"`
+	// HeliusEndpoint is required by the helius backend
+	HeliusEndpoint string `json:"heliusEndpoint"`
+	// Index is required by the local backend
+	Index *sol.LocalIndex `json:"-"`
+}
+
+// NewDiscovery builds the backend chain described by cfg for client
+func NewDiscovery(client *sol.Client, cfg DiscoveryConfig) (sol.AccountDiscovery, error) {
+	if len(cfg.Backends) == 0 {
+		return nil, errors.New("no discovery backend configured")
+	}
+	chain := make(sol.FallbackDiscovery, 0, len(cfg.Backends))
+	for _, name := range cfg.Backends {
+		switch name {
+		case DiscoveryGPA:
+			chain = append(chain, sol.ProgramAccounts{Client: client.RpcClient})
+		case DiscoveryHelius:
+			if cfg.HeliusEndpoint == "" {
+				return nil, errors.New("helius discovery requires HeliusEndpoint")
+			}
+			chain = append(chain, NewHelius(cfg.HeliusEndpoint))
+		case DiscoveryLocal:
+			if cfg.Index == nil {
+				return nil, errors.New("local discovery requires Index")
+			}
+			chain = append(chain, cfg.Index)
+		default:
+			return nil, fmt.Errorf("unknown discovery backend: %q", name)
+		}
+	}
+	if len(chain) == 1 {
+		return chain[0], nil
+	}
+	return chain, nil
+}
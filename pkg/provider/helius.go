@@ -0,0 +1,118 @@
+// Package provider implements sol.PriorityFeeEstimator, sol.TransactionSender and
+// sol.AccountDiscovery on top of RPC provider extensions: Helius
+// getPriorityFeeEstimate and getProgramAccountsV2, Triton's percentile
+// getRecentPrioritizationFees and staked sending, the Jito block engine and the
+// bloXroute Trader API. Install them with Client.SetPriorityFeeEstimator,
+// Client.SetTransactionSender and Client.SetAccountDiscovery. PrivateRelays
+// combines senders into a send mode that never broadcasts through public RPC
+package provider
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/yimingWOW/solroute/pkg/sol"
+)
+
+// Helius priority levels, the closest is picked for a requested percentile
+var heliusLevels = []struct {
+	percentile int
+	level      string
+}{
+	{0, "Min"},
+	{25, "Low"},
+	{50, "Medium"},
+	{75, "High"},
+	{95, "VeryHigh"},
+	{100, "UnsafeMax"},
+}
+
+// heliusPageSize is the number of accounts requested per getProgramAccountsV2 page
+const heliusPageSize = 5000
+
+// Helius estimates priority fees with the Helius getPriorityFeeEstimate method
+// and discovers pools with the paginated getProgramAccountsV2
+type Helius struct {
+	client *rpc.Client
+}
+
+var (
+	_ sol.PriorityFeeEstimator = (*Helius)(nil)
+	_ sol.AccountDiscovery     = (*Helius)(nil)
+)
+
+// NewHelius returns a Helius estimator for a Helius RPC endpoint, api key included
+func NewHelius(endpoint string) *Helius {
+	return &Helius{client: rpc.New(endpoint)}
+}
+
+// EstimatePriorityFee returns the Helius estimate at the priority level closest to percentile
+func (h *Helius) EstimatePriorityFee(ctx context.Context, accounts []solana.PublicKey, percentile int) (uint64, error) {
+	if percentile <= 0 || percentile > 100 {
+		return 0, fmt.Errorf("invalid percentile: %d", percentile)
+	}
+	level := heliusLevels[0].level
+	best := percentile
+	for _, l := range heliusLevels {
+		if d := abs(l.percentile - percentile); d < best {
+			level, best = l.level, d
+		}
+	}
+
+	keys := make([]string, len(accounts))
+	for i, account := range accounts {
+		keys[i] = account.String()
+	}
+	var resp struct {
+		PriorityFeeEstimate float64 `json:"priorityFeeEstimate"`
+	}
+	params := []interface{}{rpc.M{
+		"accountKeys": keys,
+		"options":     rpc.M{"priorityLevel": level},
+	}}
+	if err := h.client.RPCCallForInto(ctx, &resp, "getPriorityFeeEstimate", params); err != nil {
+		return 0, fmt.Errorf("failed to get priority fee estimate: %w", sol.ClassifyError(err))
+	}
+	return uint64(resp.PriorityFeeEstimate), nil
+}
+
+// FindProgramAccounts pages through getProgramAccountsV2, which providers serve
+// from an index instead of scanning the program like getProgramAccounts
+func (h *Helius) FindProgramAccounts(ctx context.Context, program solana.PublicKey, filters []rpc.RPCFilter) (rpc.GetProgramAccountsResult, error) {
+	result := rpc.GetProgramAccountsResult{}
+	var paginationKey *string
+	for {
+		config := rpc.M{
+			"encoding": solana.EncodingBase64,
+			"limit":    heliusPageSize,
+		}
+		if len(filters) > 0 {
+			config["filters"] = filters
+		}
+		if paginationKey != nil {
+			config["paginationKey"] = *paginationKey
+		}
+		var page struct {
+			Accounts      rpc.GetProgramAccountsResult `json:"accounts"`
+			PaginationKey *string                      `json:"paginationKey"`
+		}
+		params := []interface{}{program, config}
+		if err := h.client.RPCCallForInto(ctx, &page, "getProgramAccountsV2", params); err != nil {
+			return nil, fmt.Errorf("failed to get program accounts: %w", sol.ClassifyError(err))
+		}
+		result = append(result, page.Accounts...)
+		if page.PaginationKey == nil || *page.PaginationKey == "" {
+			return result, nil
+		}
+		paginationKey = page.PaginationKey
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,65 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/yimingWOW/solroute/pkg/sol"
+)
+
+// DefaultJitoEndpoint is the mainnet block engine
+const DefaultJitoEndpoint = "https://mainnet.block-engine.jito.wtf"
+
+// JitoTipAccounts receive the tips that make the block engine forward a transaction
+var JitoTipAccounts = []solana.PublicKey{
+	solana.MustPublicKeyFromBase58("96gYZGLnJYVFmbjzopPSU6QiEV5fGqZNyN9nmNhvrZU5"),
+	solana.MustPublicKeyFromBase58("HFqU5x63VTqvQss8hp11i4wVV8bD44PvwucfZ2bU7gRe"),
+	solana.MustPublicKeyFromBase58("Cw8CFyM9FkoMi7K7Crf6HNQqf4uEMzpKw6QNghXLvLkY"),
+	solana.MustPublicKeyFromBase58("ADaUMid9yfUytqMBgopwjb2DTLSokTSzL1zt6iGPaS49"),
+	solana.MustPublicKeyFromBase58("DfXygSm4jCyNCybVYYK6DwvWqjKee8pbDmJGcLWNDXjh"),
+	solana.MustPublicKeyFromBase58("ADuUkR4vqLUMWXxW9gh6D6L8pMSawimctcNZ5pGwDcEt"),
+	solana.MustPublicKeyFromBase58("DttWaMuVvTiduZRnguLF7jNxTgiMBZ1hyAumKUiL2KRL"),
+	solana.MustPublicKeyFromBase58("3AVi9Tg9Uo68tJfuvoKvqKNWKc5wPdSSdeBnizKZ6jT"),
+}
+
+// JitoTipInstruction returns a transfer of lamports from payer to a random tip
+// account, spreading tips to avoid write lock contention on a single one
+func JitoTipInstruction(payer solana.PublicKey, lamports uint64) (solana.Instruction, error) {
+	tipAccount := JitoTipAccounts[rand.IntN(len(JitoTipAccounts))]
+	inst, err := system.NewTransferInstruction(lamports, payer, tipAccount).ValidateAndBuild()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build tip instruction: %w", err)
+	}
+	return inst, nil
+}
+
+// Jito sends transactions through the Jito block engine, which forwards them
+// straight to Jito validators. Transactions should carry a JitoTipInstruction
+type Jito struct {
+	client *rpc.Client
+}
+
+var _ sol.TransactionSender = (*Jito)(nil)
+
+// NewJito returns a sender for a block engine endpoint, DefaultJitoEndpoint when
+// empty. authUUID is sent as x-jito-auth when set, for higher rate limits
+func NewJito(endpoint, authUUID string) *Jito {
+	if endpoint == "" {
+		endpoint = DefaultJitoEndpoint
+	}
+	url := strings.TrimSuffix(endpoint, "/") + "/api/v1/transactions"
+	if authUUID == "" {
+		return &Jito{client: rpc.New(url)}
+	}
+	return &Jito{client: rpc.NewWithHeaders(url, map[string]string{"x-jito-auth": authUUID})}
+}
+
+// SendTransaction submits tx to the block engine, which skips preflight checks
+func (j *Jito) SendTransaction(ctx context.Context, tx *solana.Transaction) (solana.Signature, error) {
+	return j.client.SendTransactionWithOpts(ctx, tx, rpc.TransactionOpts{SkipPreflight: true})
+}
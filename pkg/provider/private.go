@@ -0,0 +1,141 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/yimingWOW/solroute/pkg/sol"
+)
+
+// RelayStrategy selects how PrivateRelays spreads a transaction over its relays
+type RelayStrategy int
+
+const (
+	// RelayFanOut submits to every relay at once, landing through whichever is
+	// fastest. Relays that take tips each need their own tip in the transaction
+	RelayFanOut RelayStrategy = iota
+	// RelayFailover submits to the relays in order, moving to the next one only
+	// when a relay rejects the transaction
+	RelayFailover
+)
+
+func (s RelayStrategy) String() string {
+	switch s {
+	case RelayFanOut:
+		return "fan-out"
+	case RelayFailover:
+		return "failover"
+	}
+	return fmt.Sprintf("RelayStrategy(%d)", int(s))
+}
+
+// Relay is a named private or paid submission endpoint, e.g. NewJito,
+// NewBloXroute or NewTriton
+type Relay struct {
+	Name   string
+	Sender sol.TransactionSender
+}
+
+// RelayResult is the outcome of submitting to one relay
+type RelayResult struct {
+	Relay     string
+	Signature solana.Signature
+	Err       error
+	Latency   time.Duration
+}
+
+// SendReport describes how PrivateRelays submitted a transaction
+type SendReport struct {
+	Strategy  RelayStrategy
+	Signature solana.Signature // zero when no relay accepted the transaction
+	Results   []RelayResult    // in relay order, for the relays tried
+}
+
+// Accepted returns the names of the relays that accepted the transaction
+func (r SendReport) Accepted() []string {
+	var names []string
+	for _, result := range r.Results {
+		if result.Err == nil {
+			names = append(names, result.Relay)
+		}
+	}
+	return names
+}
+
+// PrivateRelays is a sol.TransactionSender submitting only to its relays, never
+// through the client's RPC node or another public broadcast path. Installed
+// with Client.SetTransactionSender it carries SendTx, SendAndConfirmTx and the
+// resends of SendTxWithResend; confirmation still polls RpcClient
+type PrivateRelays struct {
+	Relays   []Relay
+	Strategy RelayStrategy
+	// OnReport, when set, is called with the report of every submission
+	OnReport func(SendReport)
+}
+
+var _ sol.TransactionSender = (*PrivateRelays)(nil)
+
+// SendTransaction submits tx according to the strategy and succeeds when at
+// least one relay accepted it. The error joins the errors of all relays otherwise
+func (p *PrivateRelays) SendTransaction(ctx context.Context, tx *solana.Transaction) (solana.Signature, error) {
+	report, err := p.Submit(ctx, tx)
+	if p.OnReport != nil {
+		p.OnReport(report)
+	}
+	return report.Signature, err
+}
+
+// Submit submits tx like SendTransaction and returns the per-relay report
+func (p *PrivateRelays) Submit(ctx context.Context, tx *solana.Transaction) (SendReport, error) {
+	report := SendReport{Strategy: p.Strategy}
+	if len(p.Relays) == 0 {
+		return report, errors.New("no private relay configured")
+	}
+
+	switch p.Strategy {
+	case RelayFanOut:
+		report.Results = make([]RelayResult, len(p.Relays))
+		var wg sync.WaitGroup
+		for i, relay := range p.Relays {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				report.Results[i] = submit(ctx, relay, tx)
+			}()
+		}
+		wg.Wait()
+	case RelayFailover:
+		for _, relay := range p.Relays {
+			result := submit(ctx, relay, tx)
+			report.Results = append(report.Results, result)
+			if result.Err == nil || ctx.Err() != nil {
+				break
+			}
+		}
+	default:
+		return report, fmt.Errorf("unknown relay strategy: %v", p.Strategy)
+	}
+
+	var errs []error
+	for _, result := range report.Results {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", result.Relay, result.Err))
+		} else if report.Signature.IsZero() {
+			report.Signature = result.Signature
+		}
+	}
+	if report.Signature.IsZero() {
+		return report, fmt.Errorf("no relay accepted the transaction: %w", errors.Join(errs...))
+	}
+	return report, nil
+}
+
+func submit(ctx context.Context, relay Relay, tx *solana.Transaction) RelayResult {
+	start := time.Now()
+	sig, err := relay.Sender.SendTransaction(ctx, tx)
+	return RelayResult{Relay: relay.Name, Signature: sig, Err: err, Latency: time.Since(start)}
+}
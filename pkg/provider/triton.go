@@ -0,0 +1,57 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/yimingWOW/solroute/pkg/sol"
+)
+
+// Triton estimates priority fees with Triton's getRecentPrioritizationFees, which
+// takes a percentile and computes it per slot over the transactions that
+// write-locked the accounts, instead of returning each slot's minimum
+type Triton struct {
+	client *rpc.Client
+}
+
+var (
+	_ sol.PriorityFeeEstimator = (*Triton)(nil)
+	_ sol.TransactionSender    = (*Triton)(nil)
+)
+
+// NewTriton returns a Triton estimator and sender for a Triton RPC endpoint
+func NewTriton(endpoint string) *Triton {
+	return &Triton{client: rpc.New(endpoint)}
+}
+
+// EstimatePriorityFee returns the median over recent slots of the per-slot fee at percentile
+func (t *Triton) EstimatePriorityFee(ctx context.Context, accounts []solana.PublicKey, percentile int) (uint64, error) {
+	if percentile <= 0 || percentile > 100 {
+		return 0, fmt.Errorf("invalid percentile: %d", percentile)
+	}
+	var results []rpc.PriorizationFeeResult
+	// Triton takes the percentile in basis points
+	params := []interface{}{accounts, rpc.M{"percentile": percentile * 100}}
+	if err := t.client.RPCCallForInto(ctx, &results, "getRecentPrioritizationFees", params); err != nil {
+		return 0, fmt.Errorf("failed to get recent prioritization fees: %w", sol.ClassifyError(err))
+	}
+	if len(results) == 0 {
+		return 0, nil
+	}
+
+	fees := make([]uint64, len(results))
+	for i, result := range results {
+		fees[i] = result.PrioritizationFee
+	}
+	sort.Slice(fees, func(i, j int) bool { return fees[i] < fees[j] })
+	return fees[len(fees)/2], nil
+}
+
+// SendTransaction submits tx to the Triton node, which forwards it to leaders
+// over its staked connections
+func (t *Triton) SendTransaction(ctx context.Context, tx *solana.Transaction) (solana.Signature, error) {
+	return t.client.SendTransactionWithOpts(ctx, tx, rpc.TransactionOpts{SkipPreflight: true})
+}
@@ -0,0 +1,173 @@
+// Package quotecheck compares pool quotes against simulated executions of the
+// swap instructions the same pools build
+package quotecheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/sol"
+)
+
+// ErrDeviationExceeded is returned by Check when at least one pool's quote is
+// further from its simulated output than the tolerance allows
+var ErrDeviationExceeded = errors.New("quote deviation exceeded tolerance")
+
+// Result holds the outcome of checking a single pool
+type Result struct {
+	PoolID       string
+	Protocol     pkg.ProtocolName
+	Quoted       math.Int
+	Simulated    math.Int
+	DeviationBps int64
+	Err          error
+}
+
+// Check quotes amountIn of inputMint on every pool, simulates the matching swap
+// from user and compares the output token balance change with the quote.
+// user must hold amountIn of inputMint. A pool fails the check when its
+// simulation fails or the deviation exceeds toleranceBps
+func Check(
+	ctx context.Context,
+	client *rpc.Client,
+	user solana.PublicKey,
+	pools []pkg.Pool,
+	inputMint string,
+	amountIn math.Int,
+	toleranceBps int64,
+) ([]Result, error) {
+	results := make([]Result, 0, len(pools))
+	var failed []error
+	for _, pool := range pools {
+		result := checkPool(ctx, client, user, pool, inputMint, amountIn)
+		if result.Err == nil && result.DeviationBps > toleranceBps {
+			result.Err = fmt.Errorf("%w: %d bps (quoted %v, simulated %v)",
+				ErrDeviationExceeded, result.DeviationBps, result.Quoted, result.Simulated)
+		}
+		if result.Err != nil {
+			failed = append(failed, fmt.Errorf("pool %s: %w", result.PoolID, result.Err))
+		}
+		results = append(results, result)
+	}
+	return results, errors.Join(failed...)
+}
+
+func checkPool(ctx context.Context, client *rpc.Client, user solana.PublicKey, pool pkg.Pool, inputMint string, amountIn math.Int) Result {
+	result := Result{PoolID: pool.GetID(), Protocol: pool.ProtocolName()}
+
+	pair, err := pkg.PoolPair(pool, inputMint)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	outputMintKey, err := solana.PublicKeyFromBase58(pair.Out)
+	if err != nil {
+		result.Err = fmt.Errorf("invalid output mint: %w", err)
+		return result
+	}
+
+	if err := pool.Refresh(ctx, client); err != nil {
+		result.Err = fmt.Errorf("failed to refresh pool: %w", err)
+		return result
+	}
+	result.Quoted, err = pool.Quote(ctx, client, inputMint, amountIn)
+	if err != nil {
+		result.Err = fmt.Errorf("failed to quote: %w", err)
+		return result
+	}
+
+	// minOut of zero keeps the program's own slippage check out of the comparison
+	insts, err := pool.BuildSwapInstructions(ctx, client, user, inputMint, amountIn, math.ZeroInt(), pkg.SwapBuildOptions{})
+	if err != nil {
+		result.Err = fmt.Errorf("failed to build swap instructions: %w", err)
+		return result
+	}
+
+	outputAccount, _, err := sol.ResolveTokenAccount(ctx, client, user, outputMintKey, solana.PublicKey{})
+	if err != nil {
+		result.Err = fmt.Errorf("failed to resolve output account: %w", err)
+		return result
+	}
+	before, err := tokenBalance(ctx, client, outputAccount)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	after, err := simulateTokenBalance(ctx, client, user, insts, outputAccount)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	result.Simulated = math.NewIntFromUint64(after).Sub(math.NewIntFromUint64(before))
+	result.DeviationBps = deviationBps(result.Quoted, result.Simulated)
+	return result
+}
+
+// tokenBalance returns the amount held by a token account, zero if it does not exist yet
+func tokenBalance(ctx context.Context, client *rpc.Client, account solana.PublicKey) (uint64, error) {
+	results, err := client.GetMultipleAccountsWithOpts(ctx, []solana.PublicKey{account}, &rpc.GetMultipleAccountsOpts{
+		Commitment: rpc.CommitmentProcessed,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get output account: %w", sol.ClassifyError(err))
+	}
+	if len(results.Value) == 0 || results.Value[0] == nil {
+		return 0, nil
+	}
+	return sol.ParseTokenAmount(results.Value[0].Data.GetBinary())
+}
+
+// simulateTokenBalance simulates insts paid by user and returns the post-state
+// amount of account
+func simulateTokenBalance(ctx context.Context, client *rpc.Client, user solana.PublicKey, insts []solana.Instruction, account solana.PublicKey) (uint64, error) {
+	// The blockhash is replaced by the node, and signatures are not verified
+	tx, err := solana.NewTransaction(insts, solana.Hash{}, solana.TransactionPayer(user))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create transaction: %w", err)
+	}
+	tx.Signatures = make([]solana.Signature, tx.Message.Header.NumRequiredSignatures)
+
+	sim, err := client.SimulateTransactionWithOpts(ctx, tx, &rpc.SimulateTransactionOpts{
+		Commitment:             rpc.CommitmentProcessed,
+		ReplaceRecentBlockhash: true,
+		Accounts: &rpc.SimulateTransactionAccountsOpts{
+			Encoding:  solana.EncodingBase64,
+			Addresses: []solana.PublicKey{account},
+		},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to simulate swap: %w", sol.ClassifyError(err))
+	}
+	if sim.Value == nil {
+		return 0, errors.New("empty simulation result")
+	}
+	if sim.Value.Err != nil {
+		return 0, fmt.Errorf("simulated swap failed: %v", sim.Value.Err)
+	}
+	if len(sim.Value.Accounts) == 0 || sim.Value.Accounts[0] == nil {
+		return 0, fmt.Errorf("output account %s: %w", account.String(), sol.ErrAccountNotFound)
+	}
+	return sol.ParseTokenAmount(sim.Value.Accounts[0].Data.GetBinary())
+}
+
+// deviationBps returns |quoted - simulated| relative to simulated, in basis points
+func deviationBps(quoted, simulated math.Int) int64 {
+	if !simulated.IsPositive() {
+		if quoted.IsZero() {
+			return 0
+		}
+		return 10000
+	}
+	diff := quoted.Sub(simulated).Abs()
+	bps := diff.MulRaw(10000).Quo(simulated)
+	if !bps.IsInt64() {
+		return 10000
+	}
+	return bps.Int64()
+}
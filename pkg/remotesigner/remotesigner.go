@@ -0,0 +1,166 @@
+// Package remotesigner forwards transaction messages to a remote signing service
+// over mutual TLS, so keys never live on the routing host.
+//
+// The service is expected to answer POST <URL>/v1/sign with a JSON body
+// {"pubkey": "<base58>", "message": "<base64>"} by {"signature": "<base58>"},
+// or a non-2xx status with {"error": "<reason>"}
+package remotesigner
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/yimingWOW/solroute/pkg/sol"
+)
+
+// DefaultTimeout is used when Config.Timeout is zero
+const DefaultTimeout = 30 * time.Second
+
+// maxResponseSize bounds what is read from the service
+const maxResponseSize = 1 << 20
+
+// Config describes the signing service
+type Config struct {
+	// URL of the service, https only
+	URL string
+	// Pubkey of the key the service signs with
+	Pubkey solana.PublicKey
+	// CertFile and KeyFile hold the PEM client certificate presented to the service
+	CertFile string
+	KeyFile  string
+	// CAFile holds the PEM CA certificates the service certificate must chain to.
+	// The system roots are used when empty
+	CAFile string
+	// TLSConfig replaces the configuration built from the files above when set
+	TLSConfig *tls.Config
+	// Timeout of a signing request, including the time an approval flow on the
+	// service side may take. Defaults to DefaultTimeout
+	Timeout time.Duration
+}
+
+// Signer is a sol.Signer backed by a remote signing service
+type Signer struct {
+	client  *http.Client
+	signURL string
+	pubkey  solana.PublicKey
+}
+
+var _ sol.Signer = (*Signer)(nil)
+
+// New returns a Signer for the service described by cfg
+func New(cfg Config) (*Signer, error) {
+	u, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("invalid url %q: expected https://<host>", cfg.URL)
+	}
+	if cfg.Pubkey.IsZero() {
+		return nil, errors.New("pubkey is required")
+	}
+
+	tlsConfig := cfg.TLSConfig
+	if tlsConfig == nil {
+		if tlsConfig, err = loadTLSConfig(cfg); err != nil {
+			return nil, err
+		}
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return &Signer{
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig, ForceAttemptHTTP2: true},
+		},
+		signURL: strings.TrimSuffix(u.String(), "/") + "/v1/sign",
+		pubkey:  cfg.Pubkey,
+	}, nil
+}
+
+func loadTLSConfig(cfg Config) (*tls.Config, error) {
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return nil, errors.New("client certificate and key are required for mutual tls")
+	}
+	cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client certificate: %w", err)
+	}
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ca file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig, nil
+}
+
+// Pubkey returns the public key the service signs with
+func (s *Signer) Pubkey() solana.PublicKey {
+	return s.pubkey
+}
+
+type signRequest struct {
+	Pubkey  string `json:"pubkey"`
+	Message string `json:"message"`
+}
+
+type signResponse struct {
+	Signature string `json:"signature"`
+	Error     string `json:"error"`
+}
+
+// Sign sends message to the service and returns its signature
+func (s *Signer) Sign(message []byte) (solana.Signature, error) {
+	body, err := json.Marshal(signRequest{
+		Pubkey:  s.pubkey.String(),
+		Message: base64.StdEncoding.EncodeToString(message),
+	})
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("failed to encode sign request: %w", err)
+	}
+	resp, err := s.client.Post(s.signURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("remote sign: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result signResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(&result); err != nil {
+		return solana.Signature{}, fmt.Errorf("remote sign: status %d: invalid response: %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode/100 != 2 {
+		return solana.Signature{}, fmt.Errorf("remote sign: status %d: %s", resp.StatusCode, result.Error)
+	}
+	sig, err := solana.SignatureFromBase58(result.Signature)
+	if err != nil {
+		return solana.Signature{}, fmt.Errorf("remote sign: invalid signature: %w", err)
+	}
+	if !sig.Verify(s.pubkey, message) {
+		return solana.Signature{}, fmt.Errorf("remote sign: signature does not verify for %s", s.pubkey.String())
+	}
+	return sig, nil
+}
@@ -0,0 +1,131 @@
+package router
+
+import (
+	"context"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/config"
+	"github.com/yimingWOW/solroute/pkg/protocol"
+	"github.com/yimingWOW/solroute/pkg/sol"
+)
+
+// FromConfig connects to the endpoints of cfg and returns a router over its
+// protocols with its slippage, liquidity and dry run defaults. When cfg has a watchlist,
+// a PoolSyncService keeps the pools of the watched pairs, and of their mints
+// paired with the intermediates, warm until ctx is done. The caller closes the
+// client
+func FromConfig(ctx context.Context, cfg *config.Config) (*SimpleRouter, *sol.Client, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+	client, err := cfg.NewClient(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	protocols := configProtocols(client, cfg)
+	r := NewSimpleRouter(protocols...)
+	applyConfig(r, client, cfg)
+
+	if pairs := watchedPairs(cfg); len(pairs) > 0 {
+		poolSync := NewPoolSyncService(client, protocols, PoolSyncOptions{
+			Pairs:             pairs,
+			DiscoveryInterval: time.Duration(cfg.PoolSync.DiscoveryInterval),
+			RefreshInterval:   time.Duration(cfg.PoolSync.RefreshInterval),
+		})
+		r.SetPoolSync(poolSync)
+		go poolSync.Run(ctx)
+	}
+	return r, client, nil
+}
+
+// PairRoutersFromConfig returns PairRouters over the protocols of cfg on
+// client, rediscovering pools after ttl. Each router gets the slippage,
+// liquidity and dry run defaults of cfg, then configure when it isn't nil
+func PairRoutersFromConfig(client *sol.Client, cfg *config.Config, ttl time.Duration, configure func(*SimpleRouter)) *PairRouters {
+	return NewPairRouters(ttl, func(r *SimpleRouter) {
+		applyConfig(r, client, cfg)
+		if configure != nil {
+			configure(r)
+		}
+	}, configProtocols(client, cfg)...)
+}
+
+// applyConfig sets the router defaults of cfg, reading vault balances from client
+func applyConfig(r *SimpleRouter, client *sol.Client, cfg *config.Config) {
+	r.SetSlippageBps(cfg.SlippageBps)
+	r.SetMinLiquidity(cfg.MinLiquidityUSD)
+	r.SetDiscoveryFilter(client.RpcClient, DiscoveryFilter{
+		MinLiquidity:    cfg.MinPoolLiquidity,
+		MinVaultBalance: cfg.MinVaultBalance,
+	})
+	r.SetDryRun(cfg.DryRun)
+}
+
+// protocolConstructors builds the protocols config.Config.Protocols can name,
+// in the order FromConfig uses them by default
+var protocolConstructors = []struct {
+	name pkg.ProtocolName
+	new  func(*sol.Client, protocol.Options) pkg.Protocol
+}{
+	{pkg.ProtocolNamePumpAmm, func(c *sol.Client, o protocol.Options) pkg.Protocol { return protocol.NewPumpAmmWithOptions(c, o) }},
+	{pkg.ProtocolNameRaydiumAmm, func(c *sol.Client, o protocol.Options) pkg.Protocol { return protocol.NewRaydiumAmmWithOptions(c, o) }},
+	{pkg.ProtocolNameRaydiumClmm, func(c *sol.Client, o protocol.Options) pkg.Protocol { return protocol.NewRaydiumClmmWithOptions(c, o) }},
+	{pkg.ProtocolNameRaydiumCpmm, func(c *sol.Client, o protocol.Options) pkg.Protocol { return protocol.NewRaydiumCpmmWithOptions(c, o) }},
+	{pkg.ProtocolNameMeteoraDlmm, func(c *sol.Client, o protocol.Options) pkg.Protocol { return protocol.NewMeteoraDlmmWithOptions(c, o) }},
+}
+
+// configProtocols returns the protocols cfg names, all of them when it names
+// none, pointed at the programs of its network
+func configProtocols(client *sol.Client, cfg *config.Config) []pkg.Protocol {
+	enabled := make(map[pkg.ProtocolName]bool, len(cfg.Protocols))
+	for _, name := range cfg.Protocols {
+		enabled[pkg.ProtocolName(name)] = true
+	}
+	var protocols []pkg.Protocol
+	for _, constructor := range protocolConstructors {
+		if len(cfg.Protocols) == 0 || enabled[constructor.name] {
+			protocols = append(protocols, constructor.new(client, protocolOptions(cfg, constructor.name)))
+		}
+	}
+	return protocols
+}
+
+// protocolOptions returns the program of name in cfg: its override, else its
+// devnet deployment on devnet, else zero for mainnet
+func protocolOptions(cfg *config.Config, name pkg.ProtocolName) protocol.Options {
+	var opts protocol.Options
+	if programID, ok := cfg.ProgramIDs[string(name)]; ok {
+		opts.ProgramID = solana.MustPublicKeyFromBase58(programID)
+	} else if cfg.Network == config.NetworkDevnet {
+		opts.ProgramID = protocol.DevnetProgramIDs[name]
+	}
+	return opts
+}
+
+// watchedPairs returns the watchlist and the pairs of its mints with the
+// intermediates, without duplicates
+func watchedPairs(cfg *config.Config) []Pair {
+	seen := make(map[Pair]bool)
+	var pairs []Pair
+	add := func(baseMint, quoteMint string) {
+		pair := Pair{BaseMint: baseMint, QuoteMint: quoteMint}
+		if baseMint == quoteMint || seen[pair.key()] {
+			return
+		}
+		seen[pair.key()] = true
+		pairs = append(pairs, pair)
+	}
+	for _, pair := range cfg.Watchlist {
+		add(pair.Base, pair.Quote)
+	}
+	for _, pair := range cfg.Watchlist {
+		for _, intermediate := range cfg.Intermediates {
+			add(pair.Base, intermediate)
+			add(pair.Quote, intermediate)
+		}
+	}
+	return pairs
+}
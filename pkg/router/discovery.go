@@ -0,0 +1,107 @@
+package router
+
+import (
+	"context"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/logger"
+	"github.com/yimingWOW/solroute/pkg/sol"
+)
+
+// DiscoveryFilter drops dust pools while the protocols discover them, before
+// they are loaded into the router and quoted. Zero fields keep the defaults
+type DiscoveryFilter struct {
+	// MinLiquidity is the least raw liquidity field of a pool, its active
+	// liquidity or LP supply, see pkg.PoolSummary. It is read before the pool
+	// is loaded from the protocols that are a pkg.PagedProtocol. Pools without
+	// a liquidity field pass
+	MinLiquidity uint64
+	// MinVaultBalance is the least raw amount both vaults of a pool must hold,
+	// read after the pools are loaded. Pools that don't implement
+	// pkg.VaultReporter pass
+	MinVaultBalance uint64
+}
+
+// SetDiscoveryFilter drops the pools f rejects from QueryAllPools, reading
+// vault balances from client. The pools of pairs a PoolSyncService watches
+// aren't filtered. A zero filter disables it
+func (r *SimpleRouter) SetDiscoveryFilter(client *rpc.Client, f DiscoveryFilter) {
+	r.discoveryClient, r.discoveryFilter = client, f
+}
+
+// shortlist returns the pkg.PageOptions Shortlist of the discovery filter,
+// nil without a minimum liquidity
+func (r *SimpleRouter) shortlist() func(pkg.PoolSummary) bool {
+	if r.discoveryFilter.MinLiquidity == 0 {
+		return nil
+	}
+	minLiquidity := math.NewIntFromUint64(r.discoveryFilter.MinLiquidity)
+	return func(summary pkg.PoolSummary) bool {
+		return summary.Liquidity.IsNil() || summary.Liquidity.GTE(minLiquidity)
+	}
+}
+
+// withVaultBalances returns the pools whose vaults hold at least the minimum
+// vault balance, reading the vaults of 50 pools per request. Pools whose
+// vaults can't be read are kept
+func (r *SimpleRouter) withVaultBalances(ctx context.Context, pools []pkg.Pool) []pkg.Pool {
+	if r.discoveryFilter.MinVaultBalance == 0 || r.discoveryClient == nil {
+		return pools
+	}
+	kept := make([]pkg.Pool, 0, len(pools))
+	var batch []pkg.Pool
+	var vaults []solana.PublicKey
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		kept = append(kept, r.checkVaults(ctx, batch, vaults)...)
+		batch, vaults = batch[:0], vaults[:0]
+	}
+	for _, pool := range pools {
+		reporter, ok := pool.(pkg.VaultReporter)
+		if !ok {
+			kept = append(kept, pool)
+			continue
+		}
+		baseVault, quoteVault := reporter.Vaults()
+		batch = append(batch, pool)
+		vaults = append(vaults, baseVault, quoteVault)
+		if len(vaults) == 100 {
+			flush()
+		}
+	}
+	flush()
+	return kept
+}
+
+// checkVaults returns the pools of batch whose vaults, two per pool in vaults,
+// hold at least the minimum vault balance
+func (r *SimpleRouter) checkVaults(ctx context.Context, batch []pkg.Pool, vaults []solana.PublicKey) []pkg.Pool {
+	results, err := r.discoveryClient.GetMultipleAccounts(ctx, vaults...)
+	if err != nil || len(results.Value) != len(vaults) {
+		logger.Or(r.logger).Warn("keeping pools whose vaults can't be read",
+			"count", len(batch), "err", sol.ClassifyError(err))
+		return batch
+	}
+	kept := make([]pkg.Pool, 0, len(batch))
+	for i, pool := range batch {
+		if r.vaultHolds(results.Value[2*i]) && r.vaultHolds(results.Value[2*i+1]) {
+			kept = append(kept, pool)
+		}
+	}
+	return kept
+}
+
+// vaultHolds reports whether vault holds at least the minimum vault balance.
+// Missing vaults hold nothing
+func (r *SimpleRouter) vaultHolds(vault *rpc.Account) bool {
+	if vault == nil {
+		return false
+	}
+	amount, err := sol.ParseTokenAmount(vault.Data.GetBinary())
+	return err == nil && amount >= r.discoveryFilter.MinVaultBalance
+}
@@ -0,0 +1,133 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/yimingWOW/solroute/pkg/sol"
+)
+
+// DefaultSlippageBps is the slippage ExecuteRoute allows below the quote
+const DefaultSlippageBps = 100
+
+// ErrPriceLimit is returned by ExecuteRoute when the best quote is below
+// ExecuteOptions.MinAmountOut
+var ErrPriceLimit = errors.New("quote below price limit")
+
+// ExecuteOptions configures ExecuteRoute. Zero fields keep the defaults
+type ExecuteOptions struct {
+	// SlippageBps bounds the output accepted below the quote. Defaults to the
+	// router's, see SetSlippageBps
+	SlippageBps int64
+	// MinAmountOut refuses to send when the quote is below it, and raises the
+	// minimum output of the swap to it so the limit also holds on chain
+	MinAmountOut math.Int
+	// Swap selects the WSOL handling and platform fee of the swap instructions
+	Swap SwapOptions
+	// SellCheck refuses to send with ErrUnsellable when a simulated round
+	// trip can't sell the output back, see QuoteOptions.SellCheck
+	SellCheck bool
+	// Simulate runs the transaction through simulateTransaction without sending
+	// it. SetDryRun turns it on for every call
+	Simulate bool
+	// OnExecuted is called with every sent transaction, e.g. to record it with
+	// history.Store.RecordRoute
+	OnExecuted func(ctx context.Context, quote *RouteQuote, result *sol.TxResult)
+}
+
+// Executed is a route ExecuteRoute sent, or simulated in a dry run
+type Executed struct {
+	Quote        *RouteQuote
+	MinAmountOut math.Int
+	// PlatformFee is the amount of the output the swap transfers to the
+	// platform fee account, zero without one
+	PlatformFee math.Int
+	// Instructions are the swap instructions the transaction was built from
+	Instructions []solana.Instruction
+	// DryRun is set when the transaction was simulated instead of sent;
+	// Tx.Simulation then holds the simulated outcome
+	DryRun bool
+	Tx     *sol.TxResult
+}
+
+// SetSlippageBps sets the slippage ExecuteRoute allows when ExecuteOptions
+// doesn't set one. Zero restores DefaultSlippageBps
+func (r *SimpleRouter) SetSlippageBps(bps int64) {
+	r.slippageBps = bps
+}
+
+// SetDryRun makes ExecuteRoute discover, quote, build and simulate routes but
+// never send them, as if every call set ExecuteOptions.Simulate, e.g. in staging
+func (r *SimpleRouter) SetDryRun(enabled bool) {
+	r.dryRun = enabled
+}
+
+// DryRun reports whether ExecuteRoute only simulates, see SetDryRun
+func (r *SimpleRouter) DryRun() bool {
+	return r.dryRun
+}
+
+// SlippageBps returns the default slippage of ExecuteRoute
+func (r *SimpleRouter) SlippageBps() int64 {
+	if r.slippageBps <= 0 {
+		return DefaultSlippageBps
+	}
+	return r.slippageBps
+}
+
+// ExecuteRoute quotes amountIn of inputMint among the pools QueryAllPools
+// loaded, then builds the swap on the best pool for the first signer, bounded
+// by the slippage, and sends it with client.SendTx
+func (r *SimpleRouter) ExecuteRoute(ctx context.Context, client *sol.Client, signers []sol.Signer, inputMint, outputMint string, amountIn math.Int, opts ExecuteOptions) (*Executed, error) {
+	if len(signers) == 0 {
+		return nil, errors.New("no signer")
+	}
+	user := signers[0].Pubkey()
+	quote, err := r.QuoteRoute(ctx, client, inputMint, outputMint, amountIn, QuoteOptions{})
+	if err != nil {
+		return nil, err
+	}
+	quote.PlatformFee = opts.Swap.PlatformFee.Amount(quote.AmountOut)
+	if !opts.MinAmountOut.IsNil() && quote.AmountOut.LT(opts.MinAmountOut) {
+		return nil, fmt.Errorf("%w: quoted %v, limit %v", ErrPriceLimit, quote.AmountOut, opts.MinAmountOut)
+	}
+	if opts.SellCheck {
+		quote.SellCheck, err = checkSellable(ctx, client, quote.Pool, user, inputMint, outputMint, amountIn, quote.AmountOut, opts.Swap)
+		if err != nil {
+			return nil, fmt.Errorf("sell check failed: %w", err)
+		}
+		if !quote.SellCheck.Sellable {
+			return nil, fmt.Errorf("%w: %w", ErrUnsellable, quote.SellCheck.Err)
+		}
+	}
+
+	slippageBps := opts.SlippageBps
+	if slippageBps <= 0 {
+		slippageBps = r.SlippageBps()
+	}
+	minOut := quote.AmountOut.MulRaw(10000 - slippageBps).QuoRaw(10000)
+	if !opts.MinAmountOut.IsNil() && minOut.LT(opts.MinAmountOut) {
+		minOut = opts.MinAmountOut
+	}
+	insts, err := BuildSwapInstructions(ctx, client.RpcClient, quote.Pool, user, inputMint, amountIn, minOut, opts.Swap)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build swap instructions: %w", err)
+	}
+	simulate := opts.Simulate || r.dryRun
+	result, err := client.SendTx(ctx, solana.Hash{}, signers, insts, simulate)
+	executed := &Executed{
+		Quote:        quote,
+		MinAmountOut: minOut,
+		PlatformFee:  opts.Swap.PlatformFee.Amount(minOut),
+		Instructions: insts,
+		DryRun:       simulate,
+		Tx:           result,
+	}
+	if result != nil && !simulate && opts.OnExecuted != nil {
+		opts.OnExecuted(ctx, quote, result)
+	}
+	return executed, err
+}
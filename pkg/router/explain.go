@@ -0,0 +1,205 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/poolscore"
+	"github.com/yimingWOW/solroute/pkg/sol"
+)
+
+// CandidateStatus is the outcome of a pool in an Explanation
+type CandidateStatus string
+
+const (
+	// CandidateSelected is the pool the route goes through
+	CandidateSelected CandidateStatus = "selected"
+	// CandidateOutquoted quoted less than the selected pool
+	CandidateOutquoted CandidateStatus = "outquoted"
+	// CandidateRejected was filtered out or failed to quote; Reason says why
+	CandidateRejected CandidateStatus = "rejected"
+)
+
+// Candidate is one pool considered for a route
+type Candidate struct {
+	Pool     string           `json:"pool"`
+	Protocol pkg.ProtocolName `json:"protocol"`
+	Status   CandidateStatus  `json:"status"`
+	// AmountOut is the pool's quote, nil when it wasn't quoted or failed
+	AmountOut *math.Int `json:"amountOut,omitempty"`
+	// ShortfallBps is how much less than the selected pool an outquoted one gives
+	ShortfallBps int64 `json:"shortfallBps,omitempty"`
+	// LiquidityUSD is set when the router has a price oracle that valued the pool
+	LiquidityUSD float64 `json:"liquidityUsd,omitempty"`
+	// Score is set when the router has a pool scorer that rated the pool
+	Score  *poolscore.Score `json:"score,omitempty"`
+	Reason string           `json:"reason,omitempty"`
+}
+
+// Explanation reports how a route was chosen: every pool QueryAllPools loaded,
+// the filters applied and each pool's quote or the reason it was rejected
+type Explanation struct {
+	InputMint  string   `json:"inputMint"`
+	OutputMint string   `json:"outputMint"`
+	AmountIn   math.Int `json:"amountIn"`
+	// MinLiquidityUSD is the SetMinLiquidity filter, zero when it is off
+	MinLiquidityUSD float64 `json:"minLiquidityUsd,omitempty"`
+	// MinScore is the SetPoolScorer filter, zero when it is off
+	MinScore int `json:"minScore,omitempty"`
+	// Selected is the pool the route goes through, empty when none could
+	Selected string `json:"selected,omitempty"`
+	// Candidates are selected first, then outquoted best first, then rejected
+	Candidates []Candidate `json:"candidates"`
+	At         time.Time   `json:"at"`
+}
+
+// ExplainRoute quotes amountIn of inputMint on every pool QueryAllPools loaded
+// as QuoteRoute does, recording why each pool was or wasn't picked. Among the
+// pools of the pair, it selects the one QuoteRoute would
+func (r *SimpleRouter) ExplainRoute(ctx context.Context, client *sol.Client, inputMint, outputMint string, amountIn math.Int) (*Explanation, error) {
+	explanation := &Explanation{
+		InputMint:  inputMint,
+		OutputMint: outputMint,
+		AmountIn:   amountIn,
+		At:         time.Now(),
+	}
+	if r.minLiquidity > 0 && r.oracle != nil {
+		explanation.MinLiquidityUSD = r.minLiquidity
+	}
+	if r.scorer != nil {
+		explanation.MinScore = r.minScore
+	}
+
+	var quoted, rejected []Candidate
+	best := -1
+	for _, pool := range r.pools {
+		candidate := Candidate{Pool: pool.GetID(), Protocol: pool.ProtocolName(), Status: CandidateRejected}
+		if baseMint, quoteMint := pool.GetTokens(); !tradesPair(baseMint, quoteMint, inputMint, outputMint) {
+			candidate.Reason = fmt.Sprintf("trades %s/%s, not the pair", baseMint, quoteMint)
+			rejected = append(rejected, candidate)
+			continue
+		}
+		if r.oracle != nil {
+			usd, err := r.checkLiquidity(ctx, client.RpcClient, pool)
+			candidate.LiquidityUSD = usd
+			if err != nil && explanation.MinLiquidityUSD > 0 {
+				candidate.Reason = err.Error()
+				rejected = append(rejected, candidate)
+				continue
+			}
+		}
+		if r.scorer != nil {
+			score, err := r.checkScore(ctx, pool)
+			candidate.Score = score
+			if err != nil {
+				candidate.Reason = err.Error()
+				rejected = append(rejected, candidate)
+				continue
+			}
+		}
+		if r.tokenFilter != nil {
+			if err := r.checkTokens(ctx, pool); err != nil {
+				candidate.Reason = err.Error()
+				rejected = append(rejected, candidate)
+				continue
+			}
+		}
+		amountOut, err := r.quote(ctx, client.RpcClient, pool, func(ctx context.Context) (math.Int, error) {
+			return pool.Quote(ctx, client.RpcClient, inputMint, amountIn)
+		})
+		switch {
+		case err != nil:
+			candidate.Reason = "quote failed: " + err.Error()
+		case !amountOut.IsPositive():
+			candidate.Reason = "quoted no output"
+		}
+		if candidate.Reason != "" {
+			rejected = append(rejected, candidate)
+			continue
+		}
+		candidate.AmountOut = &amountOut
+		// Ties keep the first pool, as GetBestPool does
+		if best < 0 || amountOut.GT(*quoted[best].AmountOut) {
+			best = len(quoted)
+		}
+		quoted = append(quoted, candidate)
+	}
+
+	if best >= 0 {
+		selected := quoted[best]
+		selected.Status = CandidateSelected
+		explanation.Selected = selected.Pool
+		explanation.Candidates = append(explanation.Candidates, selected)
+		outquoted := append(quoted[:best:best], quoted[best+1:]...)
+		sort.SliceStable(outquoted, func(i, j int) bool {
+			return outquoted[i].AmountOut.GT(*outquoted[j].AmountOut)
+		})
+		for _, candidate := range outquoted {
+			candidate.Status = CandidateOutquoted
+			candidate.ShortfallBps = selected.AmountOut.Sub(*candidate.AmountOut).MulRaw(10000).Quo(*selected.AmountOut).Int64()
+			explanation.Candidates = append(explanation.Candidates, candidate)
+		}
+	}
+	explanation.Candidates = append(explanation.Candidates, rejected...)
+	if best < 0 {
+		return explanation, fmt.Errorf("no route found")
+	}
+	return explanation, nil
+}
+
+// tradesPair reports whether a pool of baseMint and quoteMint swaps between
+// inputMint and outputMint
+func tradesPair(baseMint, quoteMint, inputMint, outputMint string) bool {
+	return (baseMint == inputMint && quoteMint == outputMint) || (baseMint == outputMint && quoteMint == inputMint)
+}
+
+// JSON returns the explanation as indented JSON
+func (e *Explanation) JSON() ([]byte, error) {
+	return json.MarshalIndent(e, "", "  ")
+}
+
+// String renders the explanation for people, one pool per line
+func (e *Explanation) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Route %s %s -> %s\n", e.AmountIn, e.InputMint, e.OutputMint)
+	if e.MinLiquidityUSD > 0 {
+		fmt.Fprintf(&b, "Minimum liquidity: $%.2f\n", e.MinLiquidityUSD)
+	}
+	if e.MinScore > 0 {
+		fmt.Fprintf(&b, "Minimum score: %d\n", e.MinScore)
+	}
+	if e.Selected != "" {
+		fmt.Fprintf(&b, "Selected: %s\n", e.Selected)
+	} else {
+		fmt.Fprintln(&b, "Selected: none, no pool could quote the swap")
+	}
+	fmt.Fprintf(&b, "%d pools considered\n\n", len(e.Candidates))
+
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STATUS\tPOOL\tPROTOCOL\tOUT\tLIQUIDITY\tSCORE\tDETAIL")
+	for _, c := range e.Candidates {
+		out, liquidity, score, detail := "-", "-", "-", c.Reason
+		if c.AmountOut != nil {
+			out = c.AmountOut.String()
+		}
+		if c.LiquidityUSD > 0 {
+			liquidity = fmt.Sprintf("$%.2f", c.LiquidityUSD)
+		}
+		if c.Score != nil {
+			score = fmt.Sprint(c.Score.Value)
+		}
+		if c.Status == CandidateOutquoted {
+			detail = fmt.Sprintf("%d bps below selected", c.ShortfallBps)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", c.Status, c.Pool, c.Protocol, out, liquidity, score, detail)
+	}
+	_ = w.Flush()
+	return b.String()
+}
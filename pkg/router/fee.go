@@ -0,0 +1,67 @@
+package router
+
+import (
+	"context"
+	"fmt"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/sol"
+)
+
+// mintDecimalsOffset is the offset of the decimals in an SPL or Token-2022 mint
+const mintDecimalsOffset = 44
+
+// platformFeeInstruction transfers fee of minOut from source, or the user's
+// associated token account of outputMint when zero, to the fee account, or
+// returns nil when the fee rounds to zero
+func platformFeeInstruction(
+	ctx context.Context,
+	solClient *rpc.Client,
+	user solana.PublicKey,
+	source solana.PublicKey,
+	outputMint string,
+	minOut math.Int,
+	fee pkg.PlatformFee,
+) (solana.Instruction, error) {
+	if fee.Bps > 10000 {
+		return nil, fmt.Errorf("platform fee of %d bps exceeds 10000", fee.Bps)
+	}
+	if fee.Account.IsZero() {
+		return nil, fmt.Errorf("platform fee of %d bps has no fee account", fee.Bps)
+	}
+	amount := fee.Amount(minOut)
+	if !amount.IsPositive() {
+		return nil, nil
+	}
+	if !amount.IsUint64() {
+		return nil, fmt.Errorf("platform fee %s overflows uint64", amount)
+	}
+	mint, err := solana.PublicKeyFromBase58(outputMint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output mint: %w", err)
+	}
+
+	results, err := solClient.GetMultipleAccounts(ctx, mint)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mint account: %w", sol.ClassifyError(err))
+	}
+	if len(results.Value) != 1 || results.Value[0] == nil {
+		return nil, fmt.Errorf("mint account %s: %w", outputMint, sol.ErrAccountNotFound)
+	}
+	data := results.Value[0].Data.GetBinary()
+	if len(data) <= mintDecimalsOffset {
+		return nil, fmt.Errorf("mint account %s too short: %d bytes", outputMint, len(data))
+	}
+	tokenProgram := results.Value[0].Owner
+	if source.IsZero() {
+		source, err = sol.FindAssociatedTokenAddress(user, mint, tokenProgram)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sol.NewTransferCheckedInstruction(tokenProgram, source, mint, fee.Account, user,
+		amount.Uint64(), data[mintDecimalsOffset]), nil
+}
@@ -0,0 +1,59 @@
+package router
+
+import (
+	"context"
+	"errors"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/yimingWOW/solroute/pkg"
+)
+
+// testPool quotes fixed amounts without touching the network
+type testPool struct {
+	id, base, quote string
+	out, in         math.Int
+}
+
+func (p *testPool) ProtocolName() pkg.ProtocolName                    { return pkg.ProtocolNameRaydiumCpmm }
+func (p *testPool) ProtocolType() pkg.ProtocolType                    { return pkg.ProtocolTypeRaydiumCpmm }
+func (p *testPool) GetProgramID() solana.PublicKey                    { return solana.PublicKey{} }
+func (p *testPool) GetID() string                                     { return p.id }
+func (p *testPool) GetTokens() (string, string)                       { return p.base, p.quote }
+func (p *testPool) Refresh(context.Context, pkg.AccountFetcher) error { return nil }
+
+func (p *testPool) Quote(context.Context, pkg.AccountFetcher, string, math.Int) (math.Int, error) {
+	return p.out, nil
+}
+
+func (p *testPool) QuoteExactOut(context.Context, pkg.AccountFetcher, string, math.Int) (math.Int, error) {
+	return p.in, nil
+}
+
+func (p *testPool) BuildSwapInstructions(context.Context, *rpc.Client, solana.PublicKey, string, math.Int, math.Int, pkg.SwapBuildOptions) ([]solana.Instruction, error) {
+	return nil, nil
+}
+
+var errNotImplemented = errors.New("not implemented by testProtocol")
+
+// testProtocol returns its pools for any pair or mint
+type testProtocol struct {
+	pools []pkg.Pool
+}
+
+func (p *testProtocol) FetchPoolsByPair(context.Context, string, string) ([]pkg.Pool, error) {
+	return p.pools, nil
+}
+
+func (p *testProtocol) FetchPoolsByMint(context.Context, string) ([]pkg.Pool, error) {
+	return p.pools, nil
+}
+
+func (p *testProtocol) FetchPoolByID(context.Context, string) (pkg.Pool, error) {
+	return nil, errNotImplemented
+}
+
+func (p *testProtocol) FetchPoolsByIDs(context.Context, []string) ([]pkg.Pool, error) {
+	return nil, errNotImplemented
+}
@@ -0,0 +1,64 @@
+package router
+
+import (
+	"context"
+	"errors"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/price"
+)
+
+// errNoOracle is returned by the USD valued methods without a price oracle
+var errNoOracle = errors.New("no price oracle set")
+
+// PriceQuoter returns a price.Quoter quoting through the pools of each pair,
+// fetched from the protocols, for a price.Implied source. The pools aren't
+// added to the ones QueryAllPools loaded, and SetMinLiquidity doesn't apply:
+// valuing them would need the price being computed
+func (r *SimpleRouter) PriceQuoter(solClient *rpc.Client) price.QuoterFunc {
+	return func(ctx context.Context, inputMint, outputMint string, amountIn math.Int) (math.Int, error) {
+		_, amountOut, err := r.bestPool(ctx, solClient, r.fetchPools(ctx, inputMint, outputMint, nil), inputMint, amountIn)
+		return amountOut, err
+	}
+}
+
+// GetBestPoolNotional is GetBestPool for the amount of tokenIn worth usd at the
+// price oracle's price, which it returns with the pool and output
+func (r *SimpleRouter) GetBestPoolNotional(ctx context.Context, solClient *rpc.Client, tokenIn, tokenOut string, usd float64) (pkg.Pool, math.Int, math.Int, error) {
+	if r.oracle == nil {
+		return nil, math.ZeroInt(), math.ZeroInt(), errNoOracle
+	}
+	mint, err := solana.PublicKeyFromBase58(tokenIn)
+	if err != nil {
+		return nil, math.ZeroInt(), math.ZeroInt(), err
+	}
+	amountIn, err := r.oracle.Amount(ctx, mint, usd)
+	if err != nil {
+		return nil, math.ZeroInt(), math.ZeroInt(), err
+	}
+	pool, amountOut, err := r.GetBestPool(ctx, solClient, tokenIn, tokenOut, amountIn)
+	if err != nil {
+		return nil, math.ZeroInt(), math.ZeroInt(), err
+	}
+	return pool, amountIn, amountOut, nil
+}
+
+// usdValue values amount of mint with the price oracle, zero when there is no
+// oracle or price
+func (r *SimpleRouter) usdValue(ctx context.Context, mint string, amount math.Int) float64 {
+	if r.oracle == nil {
+		return 0
+	}
+	key, err := solana.PublicKeyFromBase58(mint)
+	if err != nil {
+		return 0
+	}
+	usd, err := r.oracle.Value(ctx, key, amount)
+	if err != nil {
+		return 0
+	}
+	return usd
+}
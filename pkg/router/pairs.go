@@ -0,0 +1,73 @@
+package router
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/yimingWOW/solroute/pkg"
+)
+
+// DefaultPairTTL is how long PairRouters reuses the pools of a pair
+const DefaultPairTTL = 5 * time.Minute
+
+// PairRouters keeps a SimpleRouter per pair for servers answering requests
+// on many pairs: the pools of a pair are discovered on first use and again
+// after the TTL, and the requests of a pair are serialized since quoting
+// refreshes the shared pools
+type PairRouters struct {
+	protocols []pkg.Protocol
+	ttl       time.Duration
+	// configure, when set, is applied to each new router
+	configure func(*SimpleRouter)
+
+	mu      sync.Mutex
+	routers map[Pair]*pairRouter
+}
+
+type pairRouter struct {
+	ready  chan struct{} // closed once discovery finished
+	router *SimpleRouter
+	loaded time.Time
+	mu     sync.Mutex
+}
+
+// NewPairRouters creates routers over protocols, rediscovering pools after
+// ttl, DefaultPairTTL when zero. configure, when not nil, sets up each new
+// router, e.g. with a price oracle
+func NewPairRouters(ttl time.Duration, configure func(*SimpleRouter), protocols ...pkg.Protocol) *PairRouters {
+	if ttl <= 0 {
+		ttl = DefaultPairTTL
+	}
+	return &PairRouters{
+		protocols: protocols,
+		ttl:       ttl,
+		configure: configure,
+		routers:   make(map[Pair]*pairRouter),
+	}
+}
+
+// Get returns the router loaded with the pools of a pair, in either order,
+// locked until unlock is called
+func (p *PairRouters) Get(ctx context.Context, mintA, mintB string) (r *SimpleRouter, unlock func()) {
+	key := Pair{BaseMint: mintA, QuoteMint: mintB}.key()
+	p.mu.Lock()
+	entry, ok := p.routers[key]
+	if !ok || time.Since(entry.loaded) > p.ttl {
+		entry = &pairRouter{ready: make(chan struct{}), router: NewSimpleRouter(p.protocols...), loaded: time.Now()}
+		if p.configure != nil {
+			p.configure(entry.router)
+		}
+		p.routers[key] = entry
+		p.mu.Unlock()
+		// Discovery skips failing protocols and doesn't fail itself. It isn't
+		// tied to the request, other requests wait for the same pools
+		entry.router.QueryAllPools(context.WithoutCancel(ctx), mintA, mintB)
+		close(entry.ready)
+	} else {
+		p.mu.Unlock()
+		<-entry.ready
+	}
+	entry.mu.Lock()
+	return entry.router, entry.mu.Unlock
+}
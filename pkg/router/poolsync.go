@@ -0,0 +1,281 @@
+package router
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/sol"
+)
+
+// Pool sync defaults
+const (
+	DefaultDiscoveryInterval  = 5 * time.Minute
+	DefaultRefreshInterval    = 2 * time.Second
+	DefaultRefreshConcurrency = 8
+)
+
+// Pair is a token pair watched by a PoolSyncService, in either order
+type Pair struct {
+	BaseMint  string
+	QuoteMint string
+}
+
+// key returns the pair with its mints ordered, so both directions match
+func (p Pair) key() Pair {
+	if p.QuoteMint < p.BaseMint {
+		return Pair{BaseMint: p.QuoteMint, QuoteMint: p.BaseMint}
+	}
+	return p
+}
+
+// PoolSyncOptions configures a PoolSyncService. Zero fields keep the defaults
+type PoolSyncOptions struct {
+	// Pairs watched from the start; more can be added with AddPair
+	Pairs []Pair
+	// DiscoveryInterval between FetchPoolsByPair runs finding new pools of the
+	// watched pairs. Defaults to DefaultDiscoveryInterval
+	DiscoveryInterval time.Duration
+	// RefreshInterval between refreshes of each pool's state. Defaults to
+	// DefaultRefreshInterval
+	RefreshInterval time.Duration
+	// RefreshConcurrency caps the pools refreshed at once. Defaults to
+	// DefaultRefreshConcurrency
+	RefreshConcurrency int
+	// MaxStaleness is how long after its last successful refresh a pool is
+	// still quoted without refreshing it first. Defaults to twice RefreshInterval
+	MaxStaleness time.Duration
+}
+
+// PoolSyncService keeps decoded pools of a watchlist of pairs warm in the
+// background: it rediscovers them every DiscoveryInterval and refreshes their
+// state every RefreshInterval. A SimpleRouter given the service with
+// SetPoolSync finds the pools of watched pairs without getProgramAccounts and
+// quotes them without refreshing them first
+type PoolSyncService struct {
+	client    *sol.Client
+	protocols []pkg.Protocol
+	opts      PoolSyncOptions
+
+	mu    sync.RWMutex
+	pairs map[Pair]bool
+	pools map[Pair][]*syncedPool
+	byID  map[string]*syncedPool
+	// wake triggers a discovery run, e.g. after AddPair
+	wake chan struct{}
+}
+
+type syncedPool struct {
+	pool pkg.Pool
+
+	// mu guards the pool state against concurrent refreshes and quotes
+	mu          sync.Mutex
+	refreshedAt time.Time
+}
+
+// NewPoolSyncService creates a service discovering pools with protocols and
+// refreshing them through client. Run starts it
+func NewPoolSyncService(client *sol.Client, protocols []pkg.Protocol, opts PoolSyncOptions) *PoolSyncService {
+	if opts.DiscoveryInterval <= 0 {
+		opts.DiscoveryInterval = DefaultDiscoveryInterval
+	}
+	if opts.RefreshInterval <= 0 {
+		opts.RefreshInterval = DefaultRefreshInterval
+	}
+	if opts.RefreshConcurrency <= 0 {
+		opts.RefreshConcurrency = DefaultRefreshConcurrency
+	}
+	if opts.MaxStaleness <= 0 {
+		opts.MaxStaleness = 2 * opts.RefreshInterval
+	}
+	s := &PoolSyncService{
+		client:    client,
+		protocols: protocols,
+		opts:      opts,
+		pairs:     make(map[Pair]bool),
+		pools:     make(map[Pair][]*syncedPool),
+		byID:      make(map[string]*syncedPool),
+		wake:      make(chan struct{}, 1),
+	}
+	for _, pair := range opts.Pairs {
+		s.pairs[pair.key()] = true
+	}
+	return s
+}
+
+// AddPair adds pair to the watchlist; its pools are discovered right away when
+// the service runs
+func (s *PoolSyncService) AddPair(pair Pair) {
+	s.mu.Lock()
+	s.pairs[pair.key()] = true
+	s.mu.Unlock()
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// RemovePair drops pair and its pools
+func (s *PoolSyncService) RemovePair(pair Pair) {
+	key := pair.key()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pairs, key)
+	for _, sp := range s.pools[key] {
+		delete(s.byID, sp.pool.GetID())
+	}
+	delete(s.pools, key)
+}
+
+// Watched reports whether the service keeps the pools of baseMint and quoteMint
+func (s *PoolSyncService) Watched(baseMint, quoteMint string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.pairs[Pair{BaseMint: baseMint, QuoteMint: quoteMint}.key()]
+}
+
+// Pools returns the pools discovered for baseMint and quoteMint
+func (s *PoolSyncService) Pools(baseMint, quoteMint string) []pkg.Pool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	synced := s.pools[Pair{BaseMint: baseMint, QuoteMint: quoteMint}.key()]
+	pools := make([]pkg.Pool, len(synced))
+	for i, sp := range synced {
+		pools[i] = sp.pool
+	}
+	return pools
+}
+
+// Do runs fn while pool isn't being refreshed and reports whether it ran. It
+// returns false when the service doesn't keep pool or its state is older than
+// MaxStaleness, in which case the caller should Refresh the pool itself
+func (s *PoolSyncService) Do(pool pkg.Pool, fn func()) bool {
+	s.mu.RLock()
+	sp, ok := s.byID[pool.GetID()]
+	s.mu.RUnlock()
+	if !ok || sp.pool != pool {
+		return false
+	}
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	if time.Since(sp.refreshedAt) > s.opts.MaxStaleness {
+		return false
+	}
+	fn()
+	return true
+}
+
+// Run discovers and refreshes the watched pools until ctx is done
+func (s *PoolSyncService) Run(ctx context.Context) error {
+	s.Discover(ctx)
+	discover := time.NewTicker(s.opts.DiscoveryInterval)
+	defer discover.Stop()
+	refresh := time.NewTicker(s.opts.RefreshInterval)
+	defer refresh.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-discover.C:
+			s.Discover(ctx)
+		case <-s.wake:
+			s.Discover(ctx)
+		case <-refresh.C:
+			s.Refresh(ctx)
+		}
+	}
+}
+
+// Discover fetches the pools of every watched pair from each protocol. Pools
+// already kept stay in place, keeping their state; new ones are refreshed
+// before they are served. A protocol failing keeps the pools it found before
+func (s *PoolSyncService) Discover(ctx context.Context) {
+	s.mu.RLock()
+	pairs := make([]Pair, 0, len(s.pairs))
+	for pair := range s.pairs {
+		pairs = append(pairs, pair)
+	}
+	s.mu.RUnlock()
+
+	for _, pair := range pairs {
+		found := make(map[string]pkg.Pool)
+		failed := false
+		for _, proto := range s.protocols {
+			pools, err := proto.FetchPoolsByPair(ctx, pair.BaseMint, pair.QuoteMint)
+			if err != nil {
+				s.client.Logger().Warn("failed to discover pools",
+					"baseMint", pair.BaseMint, "quoteMint", pair.QuoteMint, "err", err)
+				failed = true
+				continue
+			}
+			for _, pool := range pools {
+				found[pool.GetID()] = pool
+			}
+		}
+		s.update(pair, found, failed)
+	}
+	s.Refresh(ctx)
+}
+
+// update replaces the pools of pair with found, reusing the objects already
+// kept. When a protocol failed, known pools missing from found are kept
+func (s *PoolSyncService) update(pair Pair, found map[string]pkg.Pool, failed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.pairs[pair] {
+		return
+	}
+	var pools []*syncedPool
+	for _, sp := range s.pools[pair] {
+		if _, ok := found[sp.pool.GetID()]; ok || failed {
+			pools = append(pools, sp)
+			delete(found, sp.pool.GetID())
+		} else {
+			delete(s.byID, sp.pool.GetID())
+		}
+	}
+	for id, pool := range found {
+		sp := &syncedPool{pool: pool}
+		pools = append(pools, sp)
+		s.byID[id] = sp
+	}
+	s.pools[pair] = pools
+}
+
+// Refresh reloads the state of every kept pool
+func (s *PoolSyncService) Refresh(ctx context.Context) {
+	s.mu.RLock()
+	pools := make([]*syncedPool, 0, len(s.byID))
+	for _, sp := range s.byID {
+		pools = append(pools, sp)
+	}
+	s.mu.RUnlock()
+
+	sem := make(chan struct{}, s.opts.RefreshConcurrency)
+	var wg sync.WaitGroup
+	for _, sp := range pools {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			sp.mu.Lock()
+			defer sp.mu.Unlock()
+			if err := sp.pool.Refresh(ctx, s.client.RpcClient); err != nil {
+				s.client.Logger().Warn("failed to refresh pool",
+					"protocol", string(sp.pool.ProtocolName()), "pool", sp.pool.GetID(), "err", err)
+				return
+			}
+			sp.refreshedAt = time.Now()
+		}()
+	}
+	wg.Wait()
+}
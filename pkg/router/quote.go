@@ -0,0 +1,134 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/sol"
+)
+
+// RouteQuote is the best route found for a swap of AmountIn of InputMint
+type RouteQuote struct {
+	Pool       pkg.Pool
+	InputMint  string
+	OutputMint string
+	AmountIn   math.Int
+	AmountOut  math.Int
+	// AmountInUSD and AmountOutUSD value the amounts with the router's price
+	// oracle. Zero without an oracle or a price for the mint
+	AmountInUSD  float64
+	AmountOutUSD float64
+	// PlatformFee is the share of AmountOut the platform fee of the swap
+	// options takes, zero without one. The swap takes it from its minimum
+	// output, see Executed.PlatformFee
+	PlatformFee math.Int
+	// Cost is the lamports executing the route is expected to cost on top of
+	// AmountIn. Set when QuoteOptions.User is
+	Cost *sol.CostEstimate
+	// Accounts are the accounts the swap instructions reference, e.g. to fill
+	// a lookup table with sol.LookupTableAccounts of them, and TxSize the size
+	// of a transaction of the instructions with the client's lookup tables, to
+	// check against sol.MaxTransactionSize. Set when QuoteOptions.User is
+	Accounts solana.AccountMetaSlice
+	TxSize   int
+	// SellCheck reports whether OutputMint can be sold back. Set when
+	// QuoteOptions.SellCheck is
+	SellCheck *SellCheck
+}
+
+// QuoteOptions configures QuoteRoute
+type QuoteOptions struct {
+	// User estimates Cost for the swap instructions built for this wallet
+	User solana.PublicKey
+	// Swap selects the WSOL handling of the instructions Cost is estimated for,
+	// and the platform fee reported
+	Swap SwapOptions
+	// Cost configures the estimate, e.g. the Jito tip to be added
+	Cost sol.CostOptions
+	// SellCheck simulates buying the output from User and selling it back,
+	// e.g. before routing into an unknown token. Requires User, who must hold
+	// AmountIn
+	SellCheck bool
+}
+
+// QuoteRoute finds the pool giving the most OutputMint for amountIn of inputMint
+// among the pools QueryAllPools loaded, and estimates the cost of executing the
+// swap when opts.User is set. With opts.SellCheck, the quote reports whether
+// the output can be sold back
+func (r *SimpleRouter) QuoteRoute(ctx context.Context, client *sol.Client, inputMint, outputMint string, amountIn math.Int, opts QuoteOptions) (*RouteQuote, error) {
+	pool, amountOut, err := r.GetBestPool(ctx, client.RpcClient, inputMint, outputMint, amountIn)
+	if err != nil {
+		return nil, err
+	}
+	quote := &RouteQuote{
+		Pool:       pool,
+		InputMint:  inputMint,
+		OutputMint: outputMint,
+		AmountIn:   amountIn,
+		AmountOut:  amountOut,
+	}
+	quote.AmountInUSD = r.usdValue(ctx, inputMint, amountIn)
+	quote.AmountOutUSD = r.usdValue(ctx, outputMint, amountOut)
+	quote.PlatformFee = opts.Swap.PlatformFee.Amount(amountOut)
+	if opts.User.IsZero() {
+		if opts.SellCheck {
+			return nil, errors.New("sell check requires a user")
+		}
+		return quote, nil
+	}
+	if opts.SellCheck {
+		quote.SellCheck, err = checkSellable(ctx, client, pool, opts.User, inputMint, outputMint, amountIn, amountOut, opts.Swap)
+		if err != nil {
+			return nil, fmt.Errorf("sell check failed: %w", err)
+		}
+	}
+
+	// The minimum output doesn't change the cost, quote it exactly
+	insts, err := BuildSwapInstructions(ctx, client.RpcClient, pool, opts.User, inputMint, amountIn, amountOut, opts.Swap)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build swap instructions: %w", err)
+	}
+	quote.Accounts = sol.AccountKeys(insts)
+	quote.TxSize, err = client.TransactionSize(opts.User, insts)
+	if err != nil {
+		return nil, err
+	}
+	quote.Cost, err = client.EstimateCost(ctx, opts.User, insts, opts.Cost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate cost: %w", err)
+	}
+	return quote, nil
+}
+
+// PoolQuote is the quote of one pool for a swap
+type PoolQuote struct {
+	Pool      pkg.Pool
+	AmountOut math.Int
+	Err       error
+}
+
+// QuoteAllPools quotes amountIn of inputMint on every pool QueryAllPools
+// loaded and the liquidity and score filters keep, best first, failed quotes last
+func (r *SimpleRouter) QuoteAllPools(ctx context.Context, solClient *rpc.Client, inputMint string, amountIn math.Int) []PoolQuote {
+	pools := r.eligiblePools(ctx, solClient, r.pools)
+	quotes := make([]PoolQuote, 0, len(pools))
+	for _, pool := range pools {
+		amountOut, err := r.quote(ctx, solClient, pool, func(ctx context.Context) (math.Int, error) {
+			return pool.Quote(ctx, solClient, inputMint, amountIn)
+		})
+		quotes = append(quotes, PoolQuote{Pool: pool, AmountOut: amountOut, Err: err})
+	}
+	sort.SliceStable(quotes, func(i, j int) bool {
+		if (quotes[i].Err == nil) != (quotes[j].Err == nil) {
+			return quotes[i].Err == nil
+		}
+		return quotes[i].Err == nil && quotes[i].AmountOut.GT(quotes[j].AmountOut)
+	})
+	return quotes
+}
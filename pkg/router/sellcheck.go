@@ -0,0 +1,115 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/sol"
+)
+
+// ErrUnsellable is returned by ExecuteRoute when ExecuteOptions.SellCheck
+// finds the output can't be sold back
+var ErrUnsellable = errors.New("output token can't be sold")
+
+// sellShareBps is the share of the quoted output the sell leg of a sell check
+// sells back, leaving room for transfer fees. A token taxing transfers more
+// than the rest fails the sell leg like one that can't be sold
+const sellShareBps = 9000
+
+// SellCheck is the outcome of a simulated round trip: buying the output of a
+// route, then selling most of it back on the same pool in one transaction.
+// It catches tokens whose transfer hooks, freezes or non-transferability let
+// them be bought but not sold
+type SellCheck struct {
+	// Sellable is false when the buy went through but the sell failed
+	Sellable bool
+	// AmountIn is the input the buy spent
+	AmountIn math.Int
+	// Bought is the output the buy received, Sold the part sold back and
+	// Returned the input the sell gave back. Zero when the sell failed
+	Bought   math.Int
+	Sold     math.Int
+	Returned math.Int
+	// LossBps is the round trip loss on the sold part, pool fees and price
+	// impact included. A high loss hints at a transfer tax
+	LossBps int64
+	// Err is why the sell leg failed
+	Err error
+}
+
+// checkSellable simulates buying amountIn of inputMint on pool from user and
+// selling sellShareBps of the quoted output straight back. It fails when the
+// round trip can't be simulated or the buy itself fails, e.g. because user
+// doesn't hold amountIn
+func checkSellable(ctx context.Context, client *sol.Client, pool pkg.Pool, user solana.PublicKey, inputMint, outputMint string, amountIn, quoted math.Int, swap SwapOptions) (*SellCheck, error) {
+	input, err := solana.PublicKeyFromBase58(inputMint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid input mint: %w", err)
+	}
+	output, err := solana.PublicKeyFromBase58(outputMint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output mint: %w", err)
+	}
+	// The input account must hold what the sell returns for it to be measured
+	buyOpts := SwapOptions{
+		SwapBuildOptions: pkg.SwapBuildOptions{WrapInput: swap.WrapInput, FeePayer: swap.FeePayer},
+		TracerProvider:   swap.TracerProvider,
+	}
+	buy, err := BuildSwapInstructions(ctx, client.RpcClient, pool, user, inputMint, amountIn, math.ZeroInt(), buyOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build buy instructions: %w", err)
+	}
+	sold := quoted.MulRaw(sellShareBps).QuoRaw(10000)
+	if !sold.IsPositive() {
+		return nil, fmt.Errorf("quoted output %v too small to sell back", quoted)
+	}
+	sellOpts := SwapOptions{
+		SwapBuildOptions: pkg.SwapBuildOptions{FeePayer: swap.FeePayer},
+		TracerProvider:   swap.TracerProvider,
+	}
+	sell, err := BuildSwapInstructions(ctx, client.RpcClient, pool, user, outputMint, sold, math.ZeroInt(), sellOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sell instructions: %w", err)
+	}
+
+	check := &SellCheck{AmountIn: amountIn, Bought: math.ZeroInt(), Sold: math.ZeroInt(), Returned: math.ZeroInt()}
+	report, err := client.SimulateUnsignedTx(ctx, user, append(buy, sell...))
+	if err != nil {
+		var instErr *sol.InstructionError
+		if report != nil && errors.As(err, &instErr) && instErr.Index >= len(buy) {
+			check.Err = err
+			return check, nil
+		}
+		return nil, fmt.Errorf("failed to simulate round trip: %w", err)
+	}
+
+	inputDelta, outputDelta := math.ZeroInt(), math.ZeroInt()
+	for _, change := range report.TokenBalances {
+		if !change.Owner.Equals(user) {
+			continue
+		}
+		switch {
+		case change.Mint.Equals(input):
+			inputDelta = inputDelta.Add(change.Delta())
+		case change.Mint.Equals(output):
+			outputDelta = outputDelta.Add(change.Delta())
+		}
+	}
+	check.Sellable = true
+	check.Sold = sold
+	check.Bought = outputDelta.Add(sold)
+	check.Returned = inputDelta
+	if !buyOpts.WrapInput || !input.Equals(sol.WSOL) {
+		// Without wrapping, the buy's input came out of the same account
+		check.Returned = inputDelta.Add(amountIn)
+	}
+	// Selling Sold of Bought should return that share of AmountIn
+	if expected := amountIn.Mul(sold); check.Bought.IsPositive() && expected.IsPositive() {
+		check.LossBps = expected.Sub(check.Returned.Mul(check.Bought)).MulRaw(10000).Quo(expected).Int64()
+	}
+	return check, nil
+}
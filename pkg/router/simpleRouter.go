@@ -2,44 +2,399 @@ package router
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"time"
 
 	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
 	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/logger"
+	"github.com/yimingWOW/solroute/pkg/metrics"
+	"github.com/yimingWOW/solroute/pkg/poolscore"
+	"github.com/yimingWOW/solroute/pkg/price"
+	"github.com/yimingWOW/solroute/pkg/tokens"
+	"github.com/yimingWOW/solroute/pkg/tracing"
+	"github.com/yimingWOW/solroute/pkg/watcher"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type SimpleRouter struct {
 	protocols []pkg.Protocol
 	pools     []pkg.Pool
+	watcher   *watcher.PoolWatcher
+	logger    logger.Logger
+	tracer    trace.Tracer
+	metrics   *metrics.Metrics
+	poolSync  *PoolSyncService
+	oracle    *price.Oracle
+	// minLiquidity is the USD value below which pools aren't routed through
+	minLiquidity float64
+	// scorer rates pools, the ones scoring below minScore aren't routed through
+	scorer   *poolscore.Scorer
+	minScore int
+	// tokenFilter rejects pools trading a mint it returns an error for
+	tokenFilter   func(*tokens.TokenRisk) error
+	tokenResolver *tokens.Resolver
+	// discoveryFilter drops dust pools QueryAllPools discovers, reading vault
+	// balances from discoveryClient
+	discoveryFilter DiscoveryFilter
+	discoveryClient *rpc.Client
+	// slippageBps is the default slippage of ExecuteRoute
+	slippageBps int64
+	// dryRun makes ExecuteRoute simulate instead of sending
+	dryRun bool
 }
 
 func NewSimpleRouter(protocols ...pkg.Protocol) *SimpleRouter {
 	return &SimpleRouter{
 		protocols: protocols,
 		pools:     []pkg.Pool{},
+		tracer:    tracing.Tracer(nil),
 	}
 }
 
+// SetWatcher makes the router quote pools kept live by w without refreshing them
+func (r *SimpleRouter) SetWatcher(w *watcher.PoolWatcher) {
+	r.watcher = w
+}
+
+// SetPoolSync makes QueryAllPools take the pools of pairs s watches from s
+// instead of discovering them, and quotes run on the state s keeps warm
+func (r *SimpleRouter) SetPoolSync(s *PoolSyncService) {
+	r.poolSync = s
+}
+
+// SetLogger sets the Logger skipped protocols and pools are reported to.
+// nil restores logger.Default
+func (r *SimpleRouter) SetLogger(l logger.Logger) {
+	r.logger = l
+}
+
+// SetTracerProvider sets where the discovery and quote spans go. nil restores
+// the global provider
+func (r *SimpleRouter) SetTracerProvider(tp trace.TracerProvider) {
+	r.tracer = tracing.Tracer(tp)
+}
+
+// SetMetrics records quote latencies and the selected pools on m
+func (r *SimpleRouter) SetMetrics(m *metrics.Metrics) {
+	r.metrics = m
+}
+
+// SetPriceOracle values routes in USD with o, for GetBestPoolNotional, the
+// SetMinLiquidity filter and the USD amounts of RouteQuote
+func (r *SimpleRouter) SetPriceOracle(o *price.Oracle) {
+	r.oracle = o
+}
+
+// SetMinLiquidity skips pools whose vaults hold less than usd, valued by the
+// price oracle. Pools that can't be valued are skipped too. Zero disables the filter
+func (r *SimpleRouter) SetMinLiquidity(usd float64) {
+	r.minLiquidity = usd
+}
+
+// SetPoolScorer skips pools s scores below minScore. Pools that can't be
+// scored are skipped too. nil disables the filter
+func (r *SimpleRouter) SetPoolScorer(s *poolscore.Scorer, minScore int) {
+	r.scorer, r.minScore = s, minScore
+}
+
+// SetTokenFilter skips pools trading a mint filter returns an error for, e.g.
+// tokens.DenyRisks(tokens.RiskPermanentDelegate, tokens.RiskTransferHook). The
+// mints are read from resolver. Pools whose mints can't be read are skipped
+// too. A nil filter disables it
+func (r *SimpleRouter) SetTokenFilter(resolver *tokens.Resolver, filter func(*tokens.TokenRisk) error) {
+	r.tokenResolver, r.tokenFilter = resolver, filter
+}
+
+// quote runs quoteFn against the live state of pool when the watcher keeps it
+// current, and refreshes the pool first otherwise
+func (r *SimpleRouter) quote(ctx context.Context, solClient *rpc.Client, pool pkg.Pool, quoteFn func(ctx context.Context) (math.Int, error)) (math.Int, error) {
+	ctx, span := r.tracer.Start(ctx, tracing.SpanQuote, trace.WithAttributes(
+		tracing.KeyProtocol.String(string(pool.ProtocolName())),
+		tracing.KeyPool.String(pool.GetID()),
+	))
+	start := time.Now()
+	amount, err := r.quoteLive(ctx, solClient, pool, quoteFn)
+	r.metrics.Quote(string(pool.ProtocolName()), time.Since(start), err)
+	tracing.End(span, err)
+	return amount, err
+}
+
+func (r *SimpleRouter) quoteLive(ctx context.Context, solClient *rpc.Client, pool pkg.Pool, quoteFn func(ctx context.Context) (math.Int, error)) (math.Int, error) {
+	var amount math.Int
+	var err error
+	if r.watcher != nil && r.watcher.Do(pool, func() { amount, err = quoteFn(ctx) }) {
+		return amount, err
+	}
+	if r.poolSync != nil && r.poolSync.Do(pool, func() { amount, err = quoteFn(ctx) }) {
+		return amount, err
+	}
+	if err := pool.Refresh(ctx, solClient); err != nil {
+		return math.Int{}, fmt.Errorf("error refreshing pool: %w", err)
+	}
+	return quoteFn(ctx)
+}
+
+// QueryAllPools loads the pools of a pair into the router. Protocols that are
+// a pkg.PagedProtocol only load the pools not loaded yet; the router keeps
+// the first of the pools another protocol returns again
 func (r *SimpleRouter) QueryAllPools(ctx context.Context, baseMint, quoteMint string) ([]pkg.Pool, error) {
+	loaded := make(map[solana.PublicKey]bool, len(r.pools))
+	for _, pool := range r.pools {
+		if id, err := solana.PublicKeyFromBase58(pool.GetID()); err == nil {
+			loaded[id] = true
+		}
+	}
+	skip := func(id solana.PublicKey) bool { return loaded[id] }
+	r.pools = uniquePools(append(r.pools, r.fetchPools(ctx, baseMint, quoteMint, skip)...))
+	return r.pools, nil
+}
+
+// Pools returns the pools QueryAllPools loaded
+func (r *SimpleRouter) Pools() []pkg.Pool {
+	return r.pools
+}
+
+// PoolByID returns the pool with id among the ones QueryAllPools loaded
+func (r *SimpleRouter) PoolByID(id string) (pkg.Pool, error) {
+	for _, pool := range r.pools {
+		if pool.GetID() == id {
+			return pool, nil
+		}
+	}
+	return nil, fmt.Errorf("pool %s not loaded", id)
+}
+
+// FetchPoolsByMint returns the pools of every protocol trading mint against
+// any token, once each, without loading them into the router. Protocols that
+// fail are skipped; the error is only returned when all of them do
+func (r *SimpleRouter) FetchPoolsByMint(ctx context.Context, mint string) ([]pkg.Pool, error) {
+	ctx, span := r.tracer.Start(ctx, tracing.SpanDiscovery, trace.WithAttributes(
+		tracing.KeyBaseMint.String(mint),
+	))
+	var all []pkg.Pool
+	var errs []error
 	for _, proto := range r.protocols {
-		pools, err := proto.FetchPoolsByPair(ctx, baseMint, quoteMint)
+		pools, err := proto.FetchPoolsByMint(ctx, mint)
 		if err != nil {
+			logger.Or(r.logger).Warn("skipping protocol that failed to fetch pools",
+				"mint", mint, "err", err)
+			errs = append(errs, err)
 			continue
 		}
-		r.pools = append(r.pools, pools...)
+		all = append(all, pools...)
 	}
-	return r.pools, nil
+	all = uniquePools(all)
+	var err error
+	if len(errs) > 0 && len(errs) == len(r.protocols) {
+		err = errors.Join(errs...)
+	}
+	span.SetAttributes(tracing.KeyCount.Int(len(all)))
+	tracing.End(span, err)
+	return all, err
+}
+
+// fetchPools returns the pools of a pair, from the pool sync service when it
+// watches the pair and from the protocols otherwise. When skip is set, the
+// pools it matches are left out by the protocols that are a pkg.PagedProtocol
+func (r *SimpleRouter) fetchPools(ctx context.Context, baseMint, quoteMint string, skip func(solana.PublicKey) bool) []pkg.Pool {
+	ctx, span := r.tracer.Start(ctx, tracing.SpanDiscovery, trace.WithAttributes(
+		tracing.KeyBaseMint.String(baseMint),
+		tracing.KeyQuoteMint.String(quoteMint),
+	))
+	defer span.End()
+	if r.poolSync != nil && r.poolSync.Watched(baseMint, quoteMint) {
+		pools := r.poolSync.Pools(baseMint, quoteMint)
+		span.SetAttributes(tracing.KeyCount.Int(len(pools)))
+		return pools
+	}
+	var all []pkg.Pool
+	for _, proto := range r.protocols {
+		pools, err := r.fetchProtocolPools(ctx, proto, baseMint, quoteMint, skip)
+		if err != nil {
+			logger.Or(r.logger).Warn("skipping protocol that failed to fetch pools",
+				"baseMint", baseMint, "quoteMint", quoteMint, "err", err)
+			continue
+		}
+		all = append(all, pools...)
+	}
+	all = uniquePools(all)
+	span.SetAttributes(tracing.KeyCount.Int(len(all)))
+	return all
+}
+
+// uniquePools drops the pools with the ID of an earlier one, e.g. a pool
+// found under both orientations of its pair or by two protocols sharing a
+// program
+func uniquePools(pools []pkg.Pool) []pkg.Pool {
+	seen := make(map[string]bool, len(pools))
+	unique := pools[:0]
+	for _, pool := range pools {
+		if seen[pool.GetID()] {
+			continue
+		}
+		seen[pool.GetID()] = true
+		unique = append(unique, pool)
+	}
+	return unique
+}
+
+// fetchProtocolPools fetches the pools of a pair from proto that pass the
+// discovery filter, leaving out the ones skip matches when proto pages its
+// fetches
+func (r *SimpleRouter) fetchProtocolPools(ctx context.Context, proto pkg.Protocol, baseMint, quoteMint string, skip func(solana.PublicKey) bool) ([]pkg.Pool, error) {
+	shortlist := r.shortlist()
+	paged, ok := proto.(pkg.PagedProtocol)
+	var pools []pkg.Pool
+	var err error
+	if !ok || (skip == nil && shortlist == nil) {
+		pools, err = proto.FetchPoolsByPair(ctx, baseMint, quoteMint)
+	} else {
+		err = paged.FetchPoolsByPairPaged(ctx, baseMint, quoteMint, pkg.PageOptions{Skip: skip, Shortlist: shortlist}, func(page []pkg.Pool) error {
+			pools = append(pools, page...)
+			return nil
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r.withVaultBalances(ctx, pools), nil
 }
 
+// eligiblePools returns the pools holding at least the minimum liquidity,
+// scoring at least the minimum score and trading mints the token filter allows
+func (r *SimpleRouter) eligiblePools(ctx context.Context, solClient *rpc.Client, pools []pkg.Pool) []pkg.Pool {
+	checkLiquidity := r.minLiquidity > 0 && r.oracle != nil
+	if !checkLiquidity && r.scorer == nil && r.tokenFilter == nil {
+		return pools
+	}
+	eligible := make([]pkg.Pool, 0, len(pools))
+	for _, pool := range pools {
+		if checkLiquidity {
+			if _, err := r.checkLiquidity(ctx, solClient, pool); err != nil {
+				if !errors.Is(err, errLowLiquidity) {
+					logger.Or(r.logger).Warn("skipping pool whose liquidity can't be valued",
+						"pool", pool.GetID(), "err", err)
+				}
+				continue
+			}
+		}
+		if r.scorer != nil {
+			if _, err := r.checkScore(ctx, pool); err != nil {
+				if !errors.Is(err, errLowScore) {
+					logger.Or(r.logger).Warn("skipping pool that can't be scored",
+						"pool", pool.GetID(), "err", err)
+				}
+				continue
+			}
+		}
+		if r.tokenFilter != nil {
+			if err := r.checkTokens(ctx, pool); err != nil {
+				if !errors.Is(err, errRiskyToken) {
+					logger.Or(r.logger).Warn("skipping pool whose mints can't be read",
+						"pool", pool.GetID(), "err", err)
+				}
+				continue
+			}
+		}
+		eligible = append(eligible, pool)
+	}
+	return eligible
+}
+
+// errLowLiquidity marks pools holding less than the minimum liquidity
+var errLowLiquidity = errors.New("liquidity below minimum")
+
+// checkLiquidity values pool with the price oracle. The error says why the
+// minimum liquidity filter skips it
+func (r *SimpleRouter) checkLiquidity(ctx context.Context, solClient *rpc.Client, pool pkg.Pool) (float64, error) {
+	usd, err := r.oracle.Liquidity(ctx, solClient, pool)
+	if err != nil {
+		return 0, fmt.Errorf("liquidity can't be valued: %w", err)
+	}
+	if usd < r.minLiquidity {
+		return usd, fmt.Errorf("%w: $%.2f, minimum $%.2f", errLowLiquidity, usd, r.minLiquidity)
+	}
+	return usd, nil
+}
+
+// errLowScore marks pools scoring less than the minimum score
+var errLowScore = errors.New("score below minimum")
+
+// checkScore rates pool with the scorer. The error says why the score filter
+// skips it
+func (r *SimpleRouter) checkScore(ctx context.Context, pool pkg.Pool) (*poolscore.Score, error) {
+	score, err := r.scorer.Score(ctx, pool)
+	if err != nil {
+		return nil, fmt.Errorf("pool can't be scored: %w", err)
+	}
+	if score.Value < r.minScore {
+		return score, fmt.Errorf("%w: %s, minimum %d", errLowScore, score, r.minScore)
+	}
+	return score, nil
+}
+
+// errRiskyToken marks pools trading a mint the token filter rejects
+var errRiskyToken = errors.New("token rejected")
+
+// checkTokens runs the token filter on the mints of pool. The error says why
+// the filter skips it
+func (r *SimpleRouter) checkTokens(ctx context.Context, pool pkg.Pool) error {
+	baseMint, quoteMint := pool.GetTokens()
+	mints := make([]solana.PublicKey, 0, 2)
+	for _, mint := range []string{baseMint, quoteMint} {
+		key, err := solana.PublicKeyFromBase58(mint)
+		if err != nil {
+			return fmt.Errorf("invalid mint %s: %w", mint, err)
+		}
+		mints = append(mints, key)
+	}
+	resolved, err := r.tokenResolver.ResolveMany(ctx, mints...)
+	if err != nil {
+		return fmt.Errorf("mints can't be read: %w", err)
+	}
+	for _, token := range resolved {
+		if err := r.tokenFilter(token.Risk()); err != nil {
+			return fmt.Errorf("%w: %w", errRiskyToken, err)
+		}
+	}
+	return nil
+}
+
+// GetBestPool returns the pool giving the most tokenOut for amountIn of
+// tokenIn among the pools QueryAllPools loaded
 func (r *SimpleRouter) GetBestPool(ctx context.Context, solClient *rpc.Client, tokenIn, tokenOut string, amountIn math.Int) (pkg.Pool, math.Int, error) {
+	return r.GetBestPoolFrom(ctx, solClient, r.pools, tokenIn, tokenOut, amountIn)
+}
+
+// GetBestPoolFrom is GetBestPool among pools instead of the loaded ones, e.g.
+// a pool list the caller or an indexer maintains, skipping discovery. Pools
+// not trading tokenIn against tokenOut are ignored; the liquidity, score and
+// token filters still apply
+func (r *SimpleRouter) GetBestPoolFrom(ctx context.Context, solClient *rpc.Client, pools []pkg.Pool, tokenIn, tokenOut string, amountIn math.Int) (pkg.Pool, math.Int, error) {
+	pools = poolsTrading(pools, tokenIn, tokenOut)
+	best, maxOut, err := r.bestPool(ctx, solClient, r.eligiblePools(ctx, solClient, pools), tokenIn, amountIn)
+	if err != nil {
+		return nil, math.ZeroInt(), err
+	}
+	r.metrics.RouteSelected(string(best.ProtocolName()))
+	return best, maxOut, nil
+}
+
+// bestPool returns the pool of pools giving the most for amountIn of tokenIn
+func (r *SimpleRouter) bestPool(ctx context.Context, solClient *rpc.Client, pools []pkg.Pool, tokenIn string, amountIn math.Int) (pkg.Pool, math.Int, error) {
 	var best pkg.Pool
 	maxOut := math.NewInt(0)
-	for _, pool := range r.pools {
-		outAmount, err := pool.Quote(ctx, solClient, tokenIn, amountIn)
+	for _, pool := range pools {
+		outAmount, err := r.quote(ctx, solClient, pool, func(ctx context.Context) (math.Int, error) {
+			return pool.Quote(ctx, solClient, tokenIn, amountIn)
+		})
 		if err != nil {
-			log.Printf("error quoting: %v", err)
+			logger.Or(r.logger).Warn("skipping pool that failed to quote",
+				"pool", pool.GetID(), "tokenIn", tokenIn, "err", err)
 			continue
 		}
 		if outAmount.GT(maxOut) {
@@ -52,3 +407,61 @@ func (r *SimpleRouter) GetBestPool(ctx context.Context, solClient *rpc.Client, t
 	}
 	return best, maxOut, nil
 }
+
+// poolsTrading returns the pools of pools trading mintA against mintB, in
+// either order
+func poolsTrading(pools []pkg.Pool, mintA, mintB string) []pkg.Pool {
+	trading := make([]pkg.Pool, 0, len(pools))
+	for _, pool := range pools {
+		baseMint, quoteMint := pool.GetTokens()
+		if tradesPair(baseMint, quoteMint, mintA, mintB) {
+			trading = append(trading, pool)
+		}
+	}
+	return trading
+}
+
+// poolsWith returns the pools of pools with all of caps
+func poolsWith(pools []pkg.Pool, caps pkg.Capabilities) []pkg.Pool {
+	var capable []pkg.Pool
+	for _, pool := range pools {
+		if pkg.AsPoolV2(pool).Capabilities().Has(caps) {
+			capable = append(capable, pool)
+		}
+	}
+	return capable
+}
+
+// GetBestPoolExactOut returns the pool requiring the least tokenIn to receive
+// exactly amountOut of tokenOut. Pools that cannot quote exact-out are skipped
+func (r *SimpleRouter) GetBestPoolExactOut(ctx context.Context, solClient *rpc.Client, tokenIn, tokenOut string, amountOut math.Int) (pkg.Pool, math.Int, error) {
+	var best pkg.Pool
+	minIn := math.NewInt(0)
+	pools := poolsWith(poolsTrading(r.pools, tokenIn, tokenOut), pkg.CapExactOut)
+	for _, pool := range r.eligiblePools(ctx, solClient, pools) {
+		quoter, ok := pool.(pkg.ExactOutQuoter)
+		if !ok {
+			continue
+		}
+		inAmount, err := r.quote(ctx, solClient, pool, func(ctx context.Context) (math.Int, error) {
+			return quoter.QuoteExactOut(ctx, solClient, tokenIn, amountOut)
+		})
+		if err != nil {
+			logger.Or(r.logger).Warn("skipping pool that failed to quote exact out",
+				"pool", pool.GetID(), "tokenIn", tokenIn, "err", err)
+			continue
+		}
+		if !inAmount.IsPositive() {
+			continue
+		}
+		if best == nil || inAmount.LT(minIn) {
+			minIn = inAmount
+			best = pool
+		}
+	}
+	if best == nil {
+		return nil, math.ZeroInt(), fmt.Errorf("no route found")
+	}
+	r.metrics.RouteSelected(string(best.ProtocolName()))
+	return best, minIn, nil
+}
@@ -0,0 +1,62 @@
+package router
+
+import (
+	"context"
+	"testing"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/yimingWOW/solroute/pkg"
+)
+
+func TestGetBestPoolExactOutFiltersPair(t *testing.T) {
+	// The SOL/BONK pool needs less SOL but doesn't return USDC
+	r := NewSimpleRouter()
+	r.pools = []pkg.Pool{
+		&testPool{id: "bonk", base: "SOL", quote: "BONK", in: math.NewInt(1)},
+		&testPool{id: "usdc", base: "USDC", quote: "SOL", in: math.NewInt(100)},
+	}
+
+	pool, amountIn, err := r.GetBestPoolExactOut(context.Background(), nil, "SOL", "USDC", math.NewInt(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pool.GetID() != "usdc" || !amountIn.Equal(math.NewInt(100)) {
+		t.Fatalf("got pool %s for %s, want usdc for 100", pool.GetID(), amountIn)
+	}
+
+	if _, _, err := r.GetBestPoolExactOut(context.Background(), nil, "USDC", "BONK", math.NewInt(10)); err == nil {
+		t.Fatal("want an error for a pair no pool trades")
+	}
+}
+
+func TestQueryAllPoolsKeepsPoolsOnce(t *testing.T) {
+	// The pool comes back under both orientations of its pair from the first
+	// protocol, and again from a second protocol sharing its program
+	id := solana.NewWallet().PublicKey().String()
+	first := &testPool{id: id, base: "SOL", quote: "USDC"}
+	reversed := &testPool{id: id, base: "USDC", quote: "SOL"}
+	other := &testPool{id: solana.NewWallet().PublicKey().String(), base: "SOL", quote: "USDC"}
+	r := NewSimpleRouter(
+		&testProtocol{pools: []pkg.Pool{first, reversed}},
+		&testProtocol{pools: []pkg.Pool{other, first}},
+	)
+
+	for _, pair := range [][2]string{{"SOL", "USDC"}, {"USDC", "SOL"}} {
+		pools, err := r.QueryAllPools(context.Background(), pair[0], pair[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(pools) != 2 || pools[0] != first || pools[1] != other {
+			t.Fatalf("%s/%s: got %d pools, want the first instance of each", pair[0], pair[1], len(pools))
+		}
+	}
+
+	pools, err := r.FetchPoolsByMint(context.Background(), "SOL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pools) != 2 || pools[0] != first || pools[1] != other {
+		t.Fatalf("FetchPoolsByMint: got %d pools, want the first instance of each", len(pools))
+	}
+}
@@ -0,0 +1,197 @@
+package router
+
+import (
+	"context"
+	"fmt"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/sol"
+	"github.com/yimingWOW/solroute/pkg/tracing"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SwapOptions tunes the instructions BuildSwapInstructions builds. The WSOL
+// options act on the user's WSOL associated token account
+type SwapOptions struct {
+	pkg.SwapBuildOptions
+	// CheckBalance verifies with sol.CheckBalances that the user holds the input
+	// amount and the SOL the instructions need, failing with an error matching
+	// sol.ErrInsufficientBalance otherwise. Compute budget instructions added
+	// later, e.g. by SendTx, are not accounted for
+	CheckBalance bool
+	// TracerProvider receives the instruction building span. Defaults to the
+	// global provider
+	TracerProvider trace.TracerProvider
+}
+
+// BuildSwapInstructions returns the swap instructions of pool for user, with the
+// account creation, WSOL wrapping, platform fee, closing, unwrapping, compute
+// budget and balance check selected by opts around them
+func BuildSwapInstructions(
+	ctx context.Context,
+	solClient *rpc.Client,
+	pool pkg.Pool,
+	user solana.PublicKey,
+	inputMint string,
+	amountIn math.Int,
+	minOut math.Int,
+	opts SwapOptions,
+) ([]solana.Instruction, error) {
+	ctx, span := tracing.Tracer(opts.TracerProvider).Start(ctx, tracing.SpanBuild, trace.WithAttributes(
+		tracing.KeyProtocol.String(string(pool.ProtocolName())),
+		tracing.KeyPool.String(pool.GetID()),
+		tracing.KeyInputMint.String(inputMint),
+	))
+	insts, err := buildSwapInstructions(ctx, solClient, pool, user, inputMint, amountIn, minOut, opts)
+	if err == nil {
+		span.SetAttributes(tracing.KeyCount.Int(len(insts)))
+	}
+	tracing.End(span, err)
+	return insts, err
+}
+
+func buildSwapInstructions(
+	ctx context.Context,
+	solClient *rpc.Client,
+	pool pkg.Pool,
+	user solana.PublicKey,
+	inputMint string,
+	amountIn math.Int,
+	minOut math.Int,
+	opts SwapOptions,
+) ([]solana.Instruction, error) {
+	// Wrapping, platform fees and balance checks read the amounts as u64s, as
+	// SPL token amounts are
+	if err := pkg.CheckSwapAmounts(amountIn, minOut); err != nil {
+		return nil, err
+	}
+	// The options left out are applied below, around the pool's instructions
+	swapInsts, err := pool.BuildSwapInstructions(ctx, solClient, user, inputMint, amountIn, minOut, opts.PoolOptions())
+	if err != nil {
+		return nil, err
+	}
+	// Also drop them for pkg.Pool implementations ignoring the option
+	if opts.SkipCreateAccounts {
+		swapInsts = withoutCreateAccounts(swapInsts, user)
+	}
+	pair, err := pkg.PoolPair(pool, inputMint)
+	if err != nil {
+		return nil, err
+	}
+	inputWsol := pair.In == sol.WSOL.String()
+	outputWsol := pair.Out == sol.WSOL.String()
+
+	var insts []solana.Instruction
+	if opts.ComputeUnitLimit > 0 || opts.ComputeUnitPrice > 0 {
+		budget, err := sol.ComputeBudgetInstructions(opts.ComputeUnitLimit, opts.ComputeUnitPrice)
+		if err != nil {
+			return nil, err
+		}
+		insts = append(insts, budget...)
+	}
+	if inputWsol && opts.WrapInput {
+		wrapInsts, err := sol.WrapSolInstructions(user, amountIn.Uint64())
+		if err != nil {
+			return nil, fmt.Errorf("failed to build wrap instructions: %w", err)
+		}
+		insts = append(insts, wrapInsts...)
+		// The wrap already creates the WSOL account
+		wsolAccount, err := sol.FindAssociatedTokenAddress(user, sol.WSOL, solana.TokenProgramID)
+		if err != nil {
+			return nil, err
+		}
+		swapInsts = withoutCreateAccount(swapInsts, wsolAccount)
+	}
+	insts = append(insts, swapInsts...)
+
+	if opts.PlatformFee.Bps > 0 {
+		feeInst, err := platformFeeInstruction(ctx, solClient, user, opts.OutputAccount, pair.Out, minOut, opts.PlatformFee)
+		if err != nil {
+			return nil, err
+		}
+		if feeInst != nil {
+			insts = append(insts, feeInst)
+		}
+	}
+
+	if (inputWsol && opts.CloseInputWsol) || (outputWsol && opts.UnwrapOutput) {
+		closeInst, err := sol.UnwrapSolInstruction(user)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build close instruction: %w", err)
+		}
+		insts = append(insts, closeInst)
+	}
+
+	if !opts.FeePayer.IsZero() && !opts.FeePayer.Equals(user) {
+		insts = withRentPayer(insts, user, opts.FeePayer)
+	}
+
+	if opts.CheckBalance {
+		mint, err := solana.PublicKeyFromBase58(inputMint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid input mint: %w", err)
+		}
+		if err := sol.CheckBalances(ctx, solClient, user, insts, sol.TokenRequirement{
+			Mint:   mint,
+			Amount: amountIn.Uint64(),
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return insts, nil
+}
+
+// withRentPayer makes payer fund the associated token accounts insts create for user
+func withRentPayer(insts []solana.Instruction, user, payer solana.PublicKey) []solana.Instruction {
+	out := make([]solana.Instruction, len(insts))
+	for i, inst := range insts {
+		accounts := inst.Accounts()
+		if !inst.ProgramID().Equals(solana.SPLAssociatedTokenAccountProgramID) ||
+			len(accounts) == 0 || !accounts[0].PublicKey.Equals(user) {
+			out[i] = inst
+			continue
+		}
+		data, err := inst.Data()
+		if err != nil {
+			out[i] = inst
+			continue
+		}
+		metas := make(solana.AccountMetaSlice, len(accounts))
+		copy(metas, accounts)
+		metas[0] = solana.Meta(payer).WRITE().SIGNER()
+		out[i] = solana.NewInstruction(inst.ProgramID(), metas, data)
+	}
+	return out
+}
+
+// withoutCreateAccount drops the associated token account instructions creating account
+func withoutCreateAccount(insts []solana.Instruction, account solana.PublicKey) []solana.Instruction {
+	filtered := insts[:0:0]
+	for _, inst := range insts {
+		accounts := inst.Accounts()
+		if inst.ProgramID().Equals(solana.SPLAssociatedTokenAccountProgramID) &&
+			len(accounts) > 1 && accounts[1].PublicKey.Equals(account) {
+			continue
+		}
+		filtered = append(filtered, inst)
+	}
+	return filtered
+}
+
+// withoutCreateAccounts drops the associated token account instructions creating
+// accounts of owner
+func withoutCreateAccounts(insts []solana.Instruction, owner solana.PublicKey) []solana.Instruction {
+	filtered := insts[:0:0]
+	for _, inst := range insts {
+		accounts := inst.Accounts()
+		if inst.ProgramID().Equals(solana.SPLAssociatedTokenAccountProgramID) &&
+			len(accounts) > 2 && accounts[2].PublicKey.Equals(owner) {
+			continue
+		}
+		filtered = append(filtered, inst)
+	}
+	return filtered
+}
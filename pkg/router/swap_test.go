@@ -0,0 +1,65 @@
+package router
+
+import (
+	"context"
+	"testing"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/sol"
+)
+
+func TestBuildSwapInstructionsRejectsAmountOverUint64(t *testing.T) {
+	pool := &testPool{id: "usdc", base: sol.WSOL.String(), quote: "USDC"}
+	amountIn := math.NewIntFromUint64(^uint64(0)).AddRaw(1)
+	opts := SwapOptions{SwapBuildOptions: pkg.SwapBuildOptions{WrapInput: true}}
+
+	_, err := BuildSwapInstructions(context.Background(), nil, pool, solana.NewWallet().PublicKey(),
+		sol.WSOL.String(), amountIn, math.NewInt(1), opts)
+	if err == nil {
+		t.Fatal("want an error for an amount over uint64")
+	}
+}
+
+func TestBuildSwapInstructionsRejectsMinOutOverUint64(t *testing.T) {
+	pool := &testPool{id: "usdc", base: sol.WSOL.String(), quote: "USDC"}
+	minOut := math.NewIntFromUint64(^uint64(0)).AddRaw(1)
+	opts := SwapOptions{SwapBuildOptions: pkg.SwapBuildOptions{PlatformFee: pkg.PlatformFee{Bps: 50}}}
+
+	_, err := BuildSwapInstructions(context.Background(), nil, pool, solana.NewWallet().PublicKey(),
+		sol.WSOL.String(), math.NewInt(1), minOut, opts)
+	if err == nil {
+		t.Fatal("want an error for a min out over uint64")
+	}
+}
+
+// strictPool checks the options it is built with like the protocol pools do
+type strictPool struct{ testPool }
+
+func (p *strictPool) BuildSwapInstructions(_ context.Context, _ *rpc.Client, _ solana.PublicKey, _ string, _, _ math.Int, opts pkg.SwapBuildOptions) ([]solana.Instruction, error) {
+	return nil, opts.CheckPoolOptions()
+}
+
+func TestBuildSwapInstructionsAppliesRouterOptions(t *testing.T) {
+	pool := &strictPool{testPool{id: "usdc", base: sol.WSOL.String(), quote: "USDC"}}
+	opts := SwapOptions{SwapBuildOptions: pkg.SwapBuildOptions{
+		WrapInput:        true,
+		CloseInputWsol:   true,
+		FeePayer:         solana.NewWallet().PublicKey(),
+		ComputeUnitLimit: 200_000,
+		ComputeUnitPrice: 1000,
+	}}
+
+	insts, err := BuildSwapInstructions(context.Background(), nil, pool, solana.NewWallet().PublicKey(),
+		sol.WSOL.String(), math.NewInt(1_000_000), math.ZeroInt(), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The compute unit limit and price, the WSOL account creation, transfer and
+	// sync, and the close after the swap
+	if len(insts) != 6 {
+		t.Fatalf("got %d instructions, want 6", len(insts))
+	}
+}
@@ -0,0 +1,300 @@
+// Package sandbox runs swaps against a local test validator forked from a
+// live cluster: the accounts a route touches are read at a slot, loaded into
+// solana-test-validator with the programs they belong to, and the swap is
+// sent there from a funded throwaway wallet, so its exact output can be
+// compared with the pool's quote without risking funds
+package sandbox
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/router"
+	"github.com/yimingWOW/solroute/pkg/sol"
+	"github.com/yimingWOW/solroute/pkg/sol/soltest"
+)
+
+// DefaultLamports funds the sandbox wallet
+const DefaultLamports = 10 * solana.LAMPORTS_PER_SOL
+
+// Options configures Fork. Zero fields keep the defaults
+type Options struct {
+	// Validator configures the local validator. CloneFrom must be the cluster
+	// the client reads, so the programs of the route can be cloned from it.
+	// The forked accounts are added to Accounts
+	Validator soltest.ValidatorOptions
+	// MinContextSlot refuses account reads older than this slot. The fork is
+	// taken at the slot the node reads them at, reported in Result.Slot
+	MinContextSlot uint64
+	// Lamports funds the sandbox wallet. Defaults to DefaultLamports
+	Lamports uint64
+}
+
+// Result compares a pool's quote with the swap run on the fork
+type Result struct {
+	Pool       pkg.Pool
+	InputMint  string
+	OutputMint string
+	AmountIn   math.Int
+	// Quoted is the pool's quote on the forked state
+	Quoted math.Int
+	// AmountOut is what the swap paid out on the fork
+	AmountOut math.Int
+	// DeviationBps is how far AmountOut is from Quoted, relative to Quoted
+	DeviationBps int64
+	// Slot the accounts were read at
+	Slot uint64
+	// Accounts are the forked accounts, by address
+	Accounts map[solana.PublicKey]soltest.Account
+	Tx       *sol.TxResult
+}
+
+// Programs solana-test-validator provides itself
+var builtins = map[solana.PublicKey]bool{
+	solana.SystemProgramID:                    true,
+	solana.TokenProgramID:                     true,
+	solana.Token2022ProgramID:                 true,
+	solana.SPLAssociatedTokenAccountProgramID: true,
+	solana.ComputeBudget:                      true,
+	solana.MemoProgramID:                      true,
+}
+
+// sysvarOwner owns the sysvars, which the validator keeps itself; they are
+// read for the quote but not loaded
+var sysvarOwner = solana.MustPublicKeyFromBase58("Sysvar1111111111111111111111111111111111111")
+
+// Fork reads the accounts swapping amountIn of inputMint on pool touches,
+// starts a validator loaded with them and swaps there. The input is minted
+// into the sandbox wallet's token account out of thin air; input mints whose
+// token accounts need extensions, e.g. a Token-2022 transfer fee, aren't
+// supported. The validator is stopped before Fork returns
+func Fork(ctx context.Context, client *sol.Client, pool pkg.Pool, inputMint string, amountIn math.Int, opts Options) (*Result, error) {
+	if opts.Lamports == 0 {
+		opts.Lamports = DefaultLamports
+	}
+	input, err := solana.PublicKeyFromBase58(inputMint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid input mint: %w", err)
+	}
+	pair, err := pkg.PoolPair(pool, inputMint)
+	if err != nil {
+		return nil, err
+	}
+	output, err := solana.PublicKeyFromBase58(pair.Out)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output mint: %w", err)
+	}
+
+	wallet := solana.NewWallet()
+	user := wallet.PublicKey()
+	insts, err := router.BuildSwapInstructions(ctx, client.RpcClient, pool, user, inputMint, amountIn, math.ZeroInt(), router.SwapOptions{SwapBuildOptions: pkg.SwapBuildOptions{WrapInput: true}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build swap instructions: %w", err)
+	}
+
+	result := &Result{Pool: pool, InputMint: inputMint, OutputMint: pair.Out, AmountIn: amountIn}
+	keys := touchedAccounts(insts, user, input, output)
+	accounts, slot, err := fetchAccounts(ctx, client.RpcClient, keys, opts.MinContextSlot)
+	if err != nil {
+		return nil, err
+	}
+	result.Accounts, result.Slot = accounts, slot
+
+	validatorOpts, err := forkOptions(opts, accounts)
+	if err != nil {
+		return nil, err
+	}
+	inputAccount, outputAccount, err := walletAccounts(user, input, output, accounts)
+	if err != nil {
+		return nil, err
+	}
+	validatorOpts.Accounts[user] = soltest.Account{Owner: solana.SystemProgramID, Lamports: opts.Lamports}
+	if !input.Equals(sol.WSOL) {
+		validatorOpts.Accounts[inputAccount] = tokenAccount(input, user, amountIn.Uint64(), accounts[input].Owner)
+	}
+	validatorOpts.Args = append(validatorOpts.Args, "--warp-slot", strconv.FormatUint(slot, 10))
+
+	// Quote on the same state the fork is loaded with
+	if result.Quoted, err = quoteAt(ctx, pool, accounts, inputMint, amountIn); err != nil {
+		return nil, err
+	}
+
+	validator, err := soltest.StartValidator(ctx, validatorOpts)
+	if err != nil {
+		return nil, err
+	}
+	defer validator.Stop()
+	forked, err := validator.Client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer forked.Close()
+
+	result.Tx, err = forked.SendTx(ctx, solana.Hash{}, sol.NewSigners(wallet.PrivateKey), insts, false)
+	if err != nil {
+		return result, fmt.Errorf("swap failed on the fork: %w", err)
+	}
+	balance, err := forked.RpcClient.GetTokenAccountBalance(ctx, outputAccount, rpc.CommitmentConfirmed)
+	if err != nil {
+		return result, fmt.Errorf("failed to read output balance: %w", sol.ClassifyError(err))
+	}
+	amountOut, ok := math.NewIntFromString(balance.Value.Amount)
+	if !ok {
+		return result, fmt.Errorf("invalid output balance %q", balance.Value.Amount)
+	}
+	result.AmountOut = amountOut
+	if result.Quoted.IsPositive() {
+		result.DeviationBps = amountOut.Sub(result.Quoted).Abs().MulRaw(10000).Quo(result.Quoted).Int64()
+	}
+	return result, nil
+}
+
+// touchedAccounts returns the accounts of insts to fork, and the mints, without
+// the sandbox wallet, its token accounts and the builtins
+func touchedAccounts(insts []solana.Instruction, user, inputMint, outputMint solana.PublicKey) []solana.PublicKey {
+	seen := map[solana.PublicKey]bool{user: true}
+	for _, program := range []solana.PublicKey{solana.TokenProgramID, solana.Token2022ProgramID} {
+		for _, mint := range []solana.PublicKey{inputMint, outputMint} {
+			if ata, err := sol.FindAssociatedTokenAddress(user, mint, program); err == nil {
+				seen[ata] = true
+			}
+		}
+	}
+	keys := []solana.PublicKey{inputMint, outputMint}
+	seen[inputMint], seen[outputMint] = true, true
+	add := func(key solana.PublicKey) {
+		if !seen[key] && !builtins[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	for _, inst := range insts {
+		add(inst.ProgramID())
+		for _, meta := range inst.Accounts() {
+			add(meta.PublicKey)
+		}
+	}
+	return keys
+}
+
+// fetchAccounts reads keys at one slot, at least minContextSlot. Accounts that
+// don't exist are left out
+func fetchAccounts(ctx context.Context, client *rpc.Client, keys []solana.PublicKey, minContextSlot uint64) (map[solana.PublicKey]soltest.Account, uint64, error) {
+	opts := &rpc.GetMultipleAccountsOpts{Encoding: solana.EncodingBase64, Commitment: rpc.CommitmentConfirmed}
+	accounts := make(map[solana.PublicKey]soltest.Account, len(keys))
+	var slot uint64
+	// getMultipleAccounts takes at most 100 accounts
+	for start := 0; start < len(keys); start += 100 {
+		chunk := keys[start:min(start+100, len(keys))]
+		if slot > 0 {
+			// Read the later chunks no earlier than the first
+			opts.MinContextSlot = &slot
+		} else if minContextSlot > 0 {
+			opts.MinContextSlot = &minContextSlot
+		}
+		results, err := client.GetMultipleAccountsWithOpts(ctx, chunk, opts)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to get accounts: %w", sol.ClassifyError(err))
+		}
+		if slot == 0 {
+			slot = results.Context.Slot
+		}
+		for i, account := range results.Value {
+			if account == nil || i >= len(chunk) {
+				continue
+			}
+			accounts[chunk[i]] = soltest.Account{
+				Owner:      account.Owner,
+				Lamports:   account.Lamports,
+				Data:       account.Data.GetBinary(),
+				Executable: account.Executable,
+			}
+		}
+	}
+	return accounts, slot, nil
+}
+
+// forkOptions adds the forked accounts to the validator options: data accounts
+// are loaded as fixtures, programs cloned from the cluster
+func forkOptions(opts Options, accounts map[solana.PublicKey]soltest.Account) (soltest.ValidatorOptions, error) {
+	validatorOpts := opts.Validator
+	fixtures := make(map[solana.PublicKey]soltest.Account, len(accounts)+len(validatorOpts.Accounts)+2)
+	for key, account := range validatorOpts.Accounts {
+		fixtures[key] = account
+	}
+	for key, account := range accounts {
+		if _, deployed := validatorOpts.Programs[key]; deployed {
+			continue
+		}
+		switch {
+		case account.Owner.Equals(sysvarOwner):
+		case account.Executable && account.Owner.Equals(solana.BPFLoaderUpgradeableProgramID):
+			validatorOpts.ClonePrograms = append(validatorOpts.ClonePrograms, key)
+		case account.Executable:
+			validatorOpts.Clone = append(validatorOpts.Clone, key)
+		default:
+			fixtures[key] = account
+		}
+	}
+	if (len(validatorOpts.ClonePrograms) > 0 || len(validatorOpts.Clone) > 0) && validatorOpts.CloneFrom == "" {
+		return soltest.ValidatorOptions{}, errors.New("forking the route's programs requires Validator.CloneFrom")
+	}
+	validatorOpts.Accounts = fixtures
+	return validatorOpts, nil
+}
+
+// walletAccounts returns the sandbox wallet's token accounts of the mints,
+// under the program each mint belongs to
+func walletAccounts(user, inputMint, outputMint solana.PublicKey, accounts map[solana.PublicKey]soltest.Account) (solana.PublicKey, solana.PublicKey, error) {
+	ata := func(mint solana.PublicKey) (solana.PublicKey, error) {
+		account, ok := accounts[mint]
+		if !ok {
+			return solana.PublicKey{}, fmt.Errorf("mint %s: %w", mint.String(), sol.ErrAccountNotFound)
+		}
+		return sol.FindAssociatedTokenAddress(user, mint, account.Owner)
+	}
+	input, err := ata(inputMint)
+	if err != nil {
+		return solana.PublicKey{}, solana.PublicKey{}, err
+	}
+	output, err := ata(outputMint)
+	if err != nil {
+		return solana.PublicKey{}, solana.PublicKey{}, err
+	}
+	return input, output, nil
+}
+
+// tokenAccount is an initialized token account of owner holding amount of mint
+func tokenAccount(mint, owner solana.PublicKey, amount uint64, program solana.PublicKey) soltest.Account {
+	data := make([]byte, sol.TokenAccountSize)
+	copy(data[0:32], mint[:])
+	copy(data[32:64], owner[:])
+	binary.LittleEndian.PutUint64(data[64:72], amount)
+	data[108] = 1 // initialized
+	return soltest.Account{Owner: program, Lamports: 2039280, Data: data}
+}
+
+// quoteAt quotes pool on the forked accounts, served by an in-memory node, so
+// the quote and the swap see the same state
+func quoteAt(ctx context.Context, pool pkg.Pool, accounts map[solana.PublicKey]soltest.Account, inputMint string, amountIn math.Int) (math.Int, error) {
+	node := soltest.NewRPC()
+	for key, account := range accounts {
+		node.SetAccount(key, account)
+	}
+	client := node.Client()
+	if err := pool.Refresh(ctx, client); err != nil {
+		return math.Int{}, fmt.Errorf("failed to refresh pool on the forked state: %w", err)
+	}
+	amount, err := pool.Quote(ctx, client, inputMint, amountIn)
+	if err != nil {
+		return math.Int{}, fmt.Errorf("failed to quote pool on the forked state: %w", err)
+	}
+	return amount, nil
+}
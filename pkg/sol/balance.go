@@ -2,11 +2,13 @@ package sol
 
 import (
 	"context"
-	"errors"
+	"encoding/binary"
 	"fmt"
 	"strconv"
 
 	"github.com/gagliardetto/solana-go"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+	"github.com/gagliardetto/solana-go/programs/system"
 	"github.com/gagliardetto/solana-go/rpc"
 )
 
@@ -18,15 +20,15 @@ func (t *Client) GetUserTokenBalance(ctx context.Context, userAddr solana.Public
 		},
 	)
 	if err != nil {
-		return 0, err
+		return 0, ClassifyError(err)
 	}
 	if len(acc.Value) == 0 {
-		return 0, errors.New("no token account found")
+		return 0, fmt.Errorf("token account for mint %s: %w", tokenMint.String(), ErrAccountNotFound)
 	}
 
 	tokenAccount, err := t.RpcClient.GetTokenAccountBalance(ctx, acc.Value[0].Pubkey, rpc.CommitmentConfirmed)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get token account balance: %v", err)
+		return 0, fmt.Errorf("failed to get token account balance: %w", ClassifyError(err))
 	}
 	tokenAmt, err := strconv.ParseUint(tokenAccount.Value.Amount, 10, 64)
 	if err != nil {
@@ -35,3 +37,213 @@ func (t *Client) GetUserTokenBalance(ctx context.Context, userAddr solana.Public
 
 	return tokenAmt, nil
 }
+
+// Lamports charged per transaction signature
+const lamportsPerSignature = 5000
+
+// InsufficientBalanceError reports that an account can't cover a transaction.
+// It matches ErrInsufficientBalance with errors.Is
+type InsufficientBalanceError struct {
+	Mint      solana.PublicKey // zero for native SOL
+	Required  uint64
+	Available uint64
+}
+
+func (e *InsufficientBalanceError) Error() string {
+	asset := "SOL"
+	if !e.Mint.IsZero() {
+		asset = "token " + e.Mint.String()
+	}
+	return fmt.Sprintf("%s: %s needs %d, have %d (short %d)",
+		ErrInsufficientBalance, asset, e.Required, e.Available, e.Shortfall())
+}
+
+// Shortfall returns the amount missing, in lamports or token base units
+func (e *InsufficientBalanceError) Shortfall() uint64 {
+	if e.Available >= e.Required {
+		return 0
+	}
+	return e.Required - e.Available
+}
+
+func (e *InsufficientBalanceError) Is(target error) bool {
+	return target == ErrInsufficientBalance
+}
+
+// TokenRequirement is an amount of Mint a transaction spends from the payer's
+// token account. Account defaults to the payer's associated token account
+type TokenRequirement struct {
+	Mint    solana.PublicKey
+	Account solana.PublicKey
+	Amount  uint64
+}
+
+// CheckBalances verifies that payer can cover insts before they are sent: the
+// token amounts in tokens, and SOL for the signature and priority fees, the
+// lamports insts transfer or lock in new accounts and the rent of associated
+// token accounts created on the way. Lamports insts wrap into the payer's WSOL
+// account count towards a WSOL requirement. The error is an
+// *InsufficientBalanceError for the first balance that falls short
+func CheckBalances(ctx context.Context, client *rpc.Client, payer solana.PublicKey, insts []solana.Instruction, tokens ...TokenRequirement) error {
+	wsolAccount, err := FindAssociatedTokenAddress(payer, WSOL, solana.TokenProgramID)
+	if err != nil {
+		return err
+	}
+
+	scan, err := scanInstructions(payer, insts)
+	if err != nil {
+		return err
+	}
+	spent := scan.spent + scan.baseFee() + scan.priorityFee()
+	var wrapped uint64
+	for _, transfer := range scan.transfers {
+		if transfer.to.Equals(wsolAccount) {
+			wrapped += transfer.lamports
+		}
+	}
+	created := scan.created
+
+	accounts := make([]solana.PublicKey, len(tokens))
+	for i, requirement := range tokens {
+		accounts[i], _, err = ResolveTokenAccount(ctx, client, payer, requirement.Mint, requirement.Account)
+		if err != nil {
+			return err
+		}
+	}
+	keys := append(append([]solana.PublicKey{payer}, accounts...), created...)
+	results, err := client.GetMultipleAccountsWithOpts(ctx, keys, &rpc.GetMultipleAccountsOpts{
+		Encoding:   solana.EncodingBase64,
+		Commitment: rpc.CommitmentConfirmed,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get accounts: %w", ClassifyError(err))
+	}
+	if len(results.Value) != len(keys) {
+		return fmt.Errorf("expected %d accounts, got %d", len(keys), len(results.Value))
+	}
+
+	for i, requirement := range tokens {
+		var available uint64
+		if account := results.Value[1+i]; account != nil {
+			available, err = ParseTokenAmount(account.Data.GetBinary())
+			if err != nil {
+				return fmt.Errorf("token account %s: %w", accounts[i].String(), err)
+			}
+		}
+		if accounts[i].Equals(wsolAccount) {
+			available += wrapped
+		}
+		if available < requirement.Amount {
+			return &InsufficientBalanceError{Mint: requirement.Mint, Required: requirement.Amount, Available: available}
+		}
+	}
+
+	// The payer must stay rent exempt, and pays the rent of each account created
+	minBalance, err := client.GetMinimumBalanceForRentExemption(ctx, 0, rpc.CommitmentConfirmed)
+	if err != nil {
+		return fmt.Errorf("failed to get rent exemption: %w", ClassifyError(err))
+	}
+	rent, err := createdAccountsRent(ctx, client, created, results.Value[1+len(tokens):])
+	if err != nil {
+		return err
+	}
+	spent += minBalance + rent
+
+	var lamports uint64
+	if results.Value[0] != nil {
+		lamports = results.Value[0].Lamports
+	}
+	if lamports < spent {
+		return &InsufficientBalanceError{Required: spent, Available: lamports}
+	}
+	return nil
+}
+
+// createdAccountsRent returns the rent of the token accounts in created that
+// don't exist yet, existing holding their current state
+func createdAccountsRent(ctx context.Context, client *rpc.Client, created []solana.PublicKey, existing []*rpc.Account) (uint64, error) {
+	missing := make(map[solana.PublicKey]bool)
+	for i, account := range created {
+		if existing[i] == nil {
+			missing[account] = true
+		}
+	}
+	if len(missing) == 0 {
+		return 0, nil
+	}
+	rent, err := client.GetMinimumBalanceForRentExemption(ctx, TokenAccountSize, rpc.CommitmentConfirmed)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rent exemption: %w", ClassifyError(err))
+	}
+	return uint64(len(missing)) * rent, nil
+}
+
+// instructionScan is what a payer spends on a list of instructions
+type instructionScan struct {
+	signers   int
+	spent     uint64 // lamports transferred or locked in new accounts by the payer
+	transfers []lamportTransfer
+	created   []solana.PublicKey // associated token accounts the payer creates
+	unitLimit uint64
+	unitPrice uint64 // micro-lamports
+}
+
+// lamportTransfer is a CreateAccount or Transfer funded by the payer
+type lamportTransfer struct {
+	to       solana.PublicKey
+	lamports uint64
+}
+
+// baseFee returns the signature fees
+func (s instructionScan) baseFee() uint64 {
+	return uint64(s.signers) * lamportsPerSignature
+}
+
+// priorityFee returns the fee the compute unit price adds. It is charged on
+// the requested limit, rounded up
+func (s instructionScan) priorityFee() uint64 {
+	return (s.unitPrice*s.unitLimit + 999_999) / 1_000_000
+}
+
+func scanInstructions(payer solana.PublicKey, insts []solana.Instruction) (instructionScan, error) {
+	scan := instructionScan{unitLimit: MaxComputeUnitLimit}
+	signers := map[solana.PublicKey]bool{payer: true}
+	for _, inst := range insts {
+		accounts := inst.Accounts()
+		for _, meta := range accounts {
+			if meta.IsSigner {
+				signers[meta.PublicKey] = true
+			}
+		}
+		data, err := inst.Data()
+		if err != nil {
+			return instructionScan{}, fmt.Errorf("failed to read instruction data: %w", err)
+		}
+		switch program := inst.ProgramID(); {
+		case program.Equals(solana.SystemProgramID):
+			// CreateAccount and Transfer both carry the lamports after the discriminant
+			if len(data) < 12 || len(accounts) < 2 || !accounts[0].PublicKey.Equals(payer) {
+				continue
+			}
+			switch binary.LittleEndian.Uint32(data[0:4]) {
+			case system.Instruction_CreateAccount, system.Instruction_Transfer:
+				lamports := binary.LittleEndian.Uint64(data[4:12])
+				scan.spent += lamports
+				scan.transfers = append(scan.transfers, lamportTransfer{to: accounts[1].PublicKey, lamports: lamports})
+			}
+		case program.Equals(solana.SPLAssociatedTokenAccountProgramID):
+			if len(accounts) > 1 && accounts[0].PublicKey.Equals(payer) {
+				scan.created = append(scan.created, accounts[1].PublicKey)
+			}
+		case program.Equals(solana.ComputeBudget):
+			switch {
+			case len(data) >= 5 && data[0] == computebudget.Instruction_SetComputeUnitLimit:
+				scan.unitLimit = uint64(binary.LittleEndian.Uint32(data[1:5]))
+			case len(data) >= 9 && data[0] == computebudget.Instruction_SetComputeUnitPrice:
+				scan.unitPrice = binary.LittleEndian.Uint64(data[1:9])
+			}
+		}
+	}
+	scan.signers = len(signers)
+	return scan, nil
+}
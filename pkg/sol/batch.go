@@ -0,0 +1,153 @@
+package sol
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+)
+
+// maxMultipleAccounts is the getMultipleAccounts limit enforced by RPC nodes
+const maxMultipleAccounts = 100
+
+// BatchOptions configures account fetch coalescing
+type BatchOptions struct {
+	// Window is how long a getAccountInfo call waits for others to join its batch.
+	// Defaults to 5ms
+	Window time.Duration
+	// MaxBatchSize flushes a batch early once it holds this many accounts.
+	// Defaults to and is capped at 100
+	MaxBatchSize int
+}
+
+// SetAccountBatching coalesces getAccountInfo calls with the same options issued
+// within opts.Window into getMultipleAccounts requests. Callers keep using
+// RpcClient.GetAccountInfo and see no difference except fewer RPC requests.
+// It must be called before RpcClient is shared with other goroutines
+func (c *Client) SetAccountBatching(opts BatchOptions) {
+	if opts.Window <= 0 {
+		opts.Window = 5 * time.Millisecond
+	}
+	if opts.MaxBatchSize <= 0 || opts.MaxBatchSize > maxMultipleAccounts {
+		opts.MaxBatchSize = maxMultipleAccounts
+	}
+	c.RpcClient = rpc.NewWithCustomRPCClient(&accountBatcher{
+		next:    c.RpcClient,
+		opts:    opts,
+		pending: make(map[string]*accountBatch),
+	})
+}
+
+type accountResult struct {
+	raw json.RawMessage
+	err error
+}
+
+type accountBatch struct {
+	ctx     context.Context
+	key     string
+	config  []interface{}
+	pubkeys []interface{}
+	waiters []chan accountResult
+	timer   *time.Timer
+}
+
+// accountBatcher implements rpc.JSONRPCClient, batching getAccountInfo calls
+type accountBatcher struct {
+	next *rpc.Client
+	opts BatchOptions
+
+	mu      sync.Mutex
+	pending map[string]*accountBatch // keyed by the encoded request config
+}
+
+func (b *accountBatcher) CallForInto(ctx context.Context, out interface{}, method string, params []interface{}) error {
+	if method != "getAccountInfo" || len(params) == 0 {
+		return b.next.RPCCallForInto(ctx, out, method, params)
+	}
+	key, err := json.Marshal(params[1:])
+	if err != nil {
+		return b.next.RPCCallForInto(ctx, out, method, params)
+	}
+
+	done := make(chan accountResult, 1)
+	b.mu.Lock()
+	batch, ok := b.pending[string(key)]
+	if !ok {
+		batch = &accountBatch{
+			// The batch outlives the first caller's cancellation since others share it
+			ctx:    context.WithoutCancel(ctx),
+			key:    string(key),
+			config: params[1:],
+		}
+		b.pending[batch.key] = batch
+		batch.timer = time.AfterFunc(b.opts.Window, func() { b.flush(batch) })
+	}
+	batch.pubkeys = append(batch.pubkeys, params[0])
+	batch.waiters = append(batch.waiters, done)
+	if len(batch.pubkeys) >= b.opts.MaxBatchSize {
+		// Later callers start a new batch, even while a fired timer's flush waits for the lock
+		delete(b.pending, batch.key)
+		if batch.timer.Stop() {
+			go b.flush(batch)
+		}
+	}
+	b.mu.Unlock()
+
+	select {
+	case result := <-done:
+		if result.err != nil {
+			return result.err
+		}
+		return json.Unmarshal(result.raw, out)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *accountBatcher) flush(batch *accountBatch) {
+	b.mu.Lock()
+	if b.pending[batch.key] == batch {
+		delete(b.pending, batch.key)
+	}
+	b.mu.Unlock()
+
+	var resp struct {
+		Context json.RawMessage   `json:"context"`
+		Value   []json.RawMessage `json:"value"`
+	}
+	params := append([]interface{}{batch.pubkeys}, batch.config...)
+	err := b.next.RPCCallForInto(batch.ctx, &resp, "getMultipleAccounts", params)
+	if err == nil && len(resp.Value) != len(batch.pubkeys) {
+		err = fmt.Errorf("getMultipleAccounts returned %d accounts, expected %d", len(resp.Value), len(batch.pubkeys))
+	}
+	for i, done := range batch.waiters {
+		if err != nil {
+			done <- accountResult{err: err}
+			continue
+		}
+		raw, marshalErr := json.Marshal(struct {
+			Context json.RawMessage `json:"context"`
+			Value   json.RawMessage `json:"value"`
+		}{resp.Context, resp.Value[i]})
+		done <- accountResult{raw: raw, err: marshalErr}
+	}
+}
+
+func (b *accountBatcher) CallWithCallback(ctx context.Context, method string, params []interface{}, callback func(*http.Request, *http.Response) error) error {
+	return b.next.RPCCallWithCallback(ctx, method, params, callback)
+}
+
+func (b *accountBatcher) CallBatch(ctx context.Context, requests jsonrpc.RPCRequests) (jsonrpc.RPCResponses, error) {
+	return b.next.RPCCallBatch(ctx, requests)
+}
+
+// Close closes the wrapped client; it is called by rpc.Client.Close
+func (b *accountBatcher) Close() error {
+	return b.next.Close()
+}
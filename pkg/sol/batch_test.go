@@ -0,0 +1,58 @@
+package sol_test
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/yimingWOW/solroute/pkg/sol"
+	"github.com/yimingWOW/solroute/pkg/sol/soltest"
+)
+
+func TestAccountBatchingCapsBatchSize(t *testing.T) {
+	const callers = 250
+	node := soltest.NewRPC()
+	keys := make([]solana.PublicKey, callers)
+	for i := range keys {
+		keys[i] = solana.NewWallet().PublicKey()
+		node.SetAccount(keys[i], soltest.Account{Owner: solana.SystemProgramID, Data: []byte{byte(i)}})
+	}
+	client := node.SolClient()
+	client.SetAccountBatching(sol.BatchOptions{Window: 50 * time.Millisecond, MaxBatchSize: 100})
+
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			account, err := client.RpcClient.GetAccountInfo(context.Background(), key)
+			if err != nil {
+				t.Errorf("GetAccountInfo(%s): %v", key, err)
+				return
+			}
+			if got := account.Value.Data.GetBinary(); !bytes.Equal(got, []byte{byte(i)}) {
+				t.Errorf("GetAccountInfo(%s) = %v, want [%d]", key, got, byte(i))
+			}
+		}()
+	}
+	wg.Wait()
+
+	fetched := 0
+	for _, call := range node.Calls() {
+		if call.Method != "getMultipleAccounts" {
+			t.Errorf("unexpected %s call", call.Method)
+			continue
+		}
+		pubkeys := call.Params[0].([]interface{})
+		if len(pubkeys) > 100 {
+			t.Errorf("batch of %d accounts exceeds MaxBatchSize", len(pubkeys))
+		}
+		fetched += len(pubkeys)
+	}
+	if fetched != callers {
+		t.Errorf("fetched %d accounts, want %d", fetched, callers)
+	}
+}
@@ -0,0 +1,77 @@
+package sol
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+const (
+	// DefaultBlockhashMaxAge is the age in slots after which LatestBlockhash
+	// fetches a new blockhash. A blockhash stays valid for 150 blocks, the margin
+	// leaves time to sign, send and land the transaction
+	DefaultBlockhashMaxAge = 60
+	// slotDuration is the target slot time used to estimate a blockhash's age
+	slotDuration = 400 * time.Millisecond
+)
+
+// RecentBlockhash is a blockhash and the context it was fetched in
+type RecentBlockhash struct {
+	Blockhash            solana.Hash
+	LastValidBlockHeight uint64
+	Slot                 uint64 // slot the blockhash was fetched at
+	FetchedAt            time.Time
+}
+
+// Age estimates the slots produced since b was fetched
+func (b RecentBlockhash) Age() uint64 {
+	return uint64(time.Since(b.FetchedAt) / slotDuration)
+}
+
+// SetBlockhashMaxAge sets the age in slots after which a cached blockhash is
+// replaced. Zero restores DefaultBlockhashMaxAge
+func (c *Client) SetBlockhashMaxAge(slots uint64) {
+	c.blockhashMu.Lock()
+	defer c.blockhashMu.Unlock()
+	c.blockhashMaxAge = slots
+}
+
+// LatestBlockhash returns the blockhash cached by the client, fetching a new one
+// at the sending commitment when there is none yet or the cached one is older
+// than the max age. Concurrent callers share a single fetch
+func (c *Client) LatestBlockhash(ctx context.Context) (RecentBlockhash, error) {
+	c.blockhashMu.Lock()
+	defer c.blockhashMu.Unlock()
+
+	maxAge := c.blockhashMaxAge
+	if maxAge == 0 {
+		maxAge = DefaultBlockhashMaxAge
+	}
+	if c.blockhash != nil && c.blockhash.Age() < maxAge {
+		return *c.blockhash, nil
+	}
+
+	recent, err := c.RpcClient.GetLatestBlockhash(ctx, c.sendingCommitment())
+	if err != nil {
+		return RecentBlockhash{}, fmt.Errorf("failed to get blockhash: %w", ClassifyError(err))
+	}
+	c.blockhash = &RecentBlockhash{
+		Blockhash:            recent.Value.Blockhash,
+		LastValidBlockHeight: recent.Value.LastValidBlockHeight,
+		Slot:                 recent.Context.Slot,
+		FetchedAt:            time.Now(),
+	}
+	return *c.blockhash, nil
+}
+
+// invalidateBlockhash drops the cached blockhash if it is blockhash, so the
+// next LatestBlockhash call fetches a new one
+func (c *Client) invalidateBlockhash(blockhash solana.Hash) {
+	c.blockhashMu.Lock()
+	defer c.blockhashMu.Unlock()
+	if c.blockhash != nil && c.blockhash.Blockhash.Equals(blockhash) {
+		c.blockhash = nil
+	}
+}
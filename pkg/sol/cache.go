@@ -0,0 +1,285 @@
+package sol
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+)
+
+// mintAccountSize is the length of an SPL Token mint without extensions
+const mintAccountSize = 82
+
+// AccountCacheOptions configures the account cache
+type AccountCacheOptions struct {
+	// SlotWindow is how many slots a cached account is served for. Defaults to 150
+	SlotWindow uint64
+	// MaxEntries bounds the cache size. Defaults to 10000
+	MaxEntries int
+	// Accounts are always cached, e.g. pool config accounts
+	Accounts []solana.PublicKey
+	// Filter reports whether a fetched account may be cached. Defaults to
+	// IsStaticAccount
+	Filter func(pubkey solana.PublicKey, account *rpc.Account) bool
+}
+
+// IsStaticAccount reports whether account rarely changes: executable program
+// accounts and SPL Token or Token-2022 mints
+func IsStaticAccount(_ solana.PublicKey, account *rpc.Account) bool {
+	if account.Executable {
+		return true
+	}
+	data := account.Data.GetBinary()
+	switch {
+	case account.Owner.Equals(solana.TokenProgramID):
+		return len(data) == mintAccountSize
+	case account.Owner.Equals(solana.Token2022ProgramID):
+		// Mints with extensions carry the account type after the base account length
+		return len(data) == mintAccountSize || (len(data) > tokenAccountSize && data[tokenAccountSize] == 1)
+	}
+	return false
+}
+
+// SetAccountCache serves getAccountInfo and getMultipleAccounts results for
+// accounts accepted by opts from memory for opts.SlotWindow slots after they were
+// fetched. The current slot is tracked from the context of account responses and
+// advanced with the wall clock in between. Entries are keyed by the request
+// config too, so reads with another commitment or encoding are fetched again.
+// It must be called before RpcClient is shared with other goroutines
+func (c *Client) SetAccountCache(opts AccountCacheOptions) {
+	if opts.SlotWindow == 0 {
+		opts.SlotWindow = 150
+	}
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = 10000
+	}
+	if opts.Filter == nil {
+		opts.Filter = IsStaticAccount
+	}
+	cache := &accountCache{
+		next:    c.RpcClient,
+		opts:    opts,
+		static:  make(map[solana.PublicKey]bool, len(opts.Accounts)),
+		entries: make(map[accountCacheKey]accountCacheEntry),
+	}
+	for _, account := range opts.Accounts {
+		cache.static[account] = true
+	}
+	c.RpcClient = rpc.NewWithCustomRPCClient(cache)
+}
+
+type accountCacheKey struct {
+	pubkey solana.PublicKey
+	config string // the encoded request config
+}
+
+type accountCacheEntry struct {
+	value json.RawMessage
+	slot  uint64
+}
+
+type responseContext struct {
+	Slot uint64 `json:"slot"`
+}
+
+// accountResponse is the shape of getAccountInfo and getMultipleAccounts results
+type accountResponse[T any] struct {
+	Context responseContext `json:"context"`
+	Value   T               `json:"value"`
+}
+
+// accountCache implements rpc.JSONRPCClient, caching account reads
+type accountCache struct {
+	next   *rpc.Client
+	opts   AccountCacheOptions
+	static map[solana.PublicKey]bool
+
+	mu       sync.Mutex
+	slot     uint64 // highest context slot seen
+	slotSeen time.Time
+	entries  map[accountCacheKey]accountCacheEntry
+}
+
+func (a *accountCache) CallForInto(ctx context.Context, out interface{}, method string, params []interface{}) error {
+	if (method != "getAccountInfo" && method != "getMultipleAccounts") || len(params) == 0 {
+		return a.next.RPCCallForInto(ctx, out, method, params)
+	}
+	config, err := json.Marshal(params[1:])
+	if err != nil {
+		return a.next.RPCCallForInto(ctx, out, method, params)
+	}
+	pubkeys, err := cachePubkeys(params[0])
+	if err != nil {
+		return a.next.RPCCallForInto(ctx, out, method, params)
+	}
+	if method == "getAccountInfo" {
+		if len(pubkeys) != 1 {
+			return a.next.RPCCallForInto(ctx, out, method, params)
+		}
+		return a.getAccount(ctx, out, pubkeys[0], string(config), params)
+	}
+	return a.getAccounts(ctx, out, pubkeys, string(config), params[1:])
+}
+
+func (a *accountCache) getAccount(ctx context.Context, out interface{}, pubkey solana.PublicKey, config string, params []interface{}) error {
+	key := accountCacheKey{pubkey, config}
+	if entry, ok := a.lookup(key); ok {
+		return remarshal(accountResponse[json.RawMessage]{Context: responseContext{entry.slot}, Value: entry.value}, out)
+	}
+
+	var resp accountResponse[json.RawMessage]
+	if err := a.next.RPCCallForInto(ctx, &resp, "getAccountInfo", params); err != nil {
+		return err
+	}
+	a.store(resp.Context.Slot, []accountCacheKey{key}, []json.RawMessage{resp.Value})
+	return remarshal(resp, out)
+}
+
+func (a *accountCache) getAccounts(ctx context.Context, out interface{}, pubkeys []solana.PublicKey, config string, configParams []interface{}) error {
+	values := make([]json.RawMessage, len(pubkeys))
+	var (
+		slot    uint64
+		missing []int
+	)
+	for i, pubkey := range pubkeys {
+		entry, ok := a.lookup(accountCacheKey{pubkey, config})
+		if !ok {
+			missing = append(missing, i)
+			continue
+		}
+		values[i] = entry.value
+		slot = max(slot, entry.slot)
+	}
+
+	if len(missing) > 0 {
+		fetch := make([]solana.PublicKey, len(missing))
+		keys := make([]accountCacheKey, len(missing))
+		for j, i := range missing {
+			fetch[j] = pubkeys[i]
+			keys[j] = accountCacheKey{pubkeys[i], config}
+		}
+		var resp accountResponse[[]json.RawMessage]
+		params := append([]interface{}{fetch}, configParams...)
+		if err := a.next.RPCCallForInto(ctx, &resp, "getMultipleAccounts", params); err != nil {
+			return err
+		}
+		if len(resp.Value) != len(fetch) {
+			return fmt.Errorf("getMultipleAccounts returned %d accounts, expected %d", len(resp.Value), len(fetch))
+		}
+		for j, i := range missing {
+			values[i] = resp.Value[j]
+		}
+		slot = max(slot, resp.Context.Slot)
+		a.store(resp.Context.Slot, keys, resp.Value)
+	}
+	return remarshal(accountResponse[[]json.RawMessage]{Context: responseContext{slot}, Value: values}, out)
+}
+
+// currentSlot estimates the cluster slot; the caller holds a.mu
+func (a *accountCache) currentSlot() uint64 {
+	return a.slot + uint64(time.Since(a.slotSeen)/slotDuration)
+}
+
+func (a *accountCache) lookup(key accountCacheKey) (accountCacheEntry, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	entry, ok := a.entries[key]
+	if !ok {
+		return accountCacheEntry{}, false
+	}
+	if a.currentSlot() >= entry.slot+a.opts.SlotWindow {
+		delete(a.entries, key)
+		return accountCacheEntry{}, false
+	}
+	return entry, true
+}
+
+// store records the slot of a response and caches the accepted accounts in it
+func (a *accountCache) store(slot uint64, keys []accountCacheKey, values []json.RawMessage) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if slot > a.slot {
+		a.slot, a.slotSeen = slot, time.Now()
+	}
+	for i, key := range keys {
+		if !a.cacheable(key.pubkey, values[i]) {
+			continue
+		}
+		if len(a.entries) >= a.opts.MaxEntries {
+			a.evict()
+		}
+		a.entries[key] = accountCacheEntry{value: values[i], slot: slot}
+	}
+}
+
+func (a *accountCache) cacheable(pubkey solana.PublicKey, value json.RawMessage) bool {
+	var account *rpc.Account
+	if err := json.Unmarshal(value, &account); err != nil || account == nil {
+		// Missing accounts may be created at any time
+		return false
+	}
+	return a.static[pubkey] || a.opts.Filter(pubkey, account)
+}
+
+// evict drops expired entries, or an arbitrary one when none expired; the
+// caller holds a.mu
+func (a *accountCache) evict() {
+	current := a.currentSlot()
+	for key, entry := range a.entries {
+		if current >= entry.slot+a.opts.SlotWindow {
+			delete(a.entries, key)
+		}
+	}
+	if len(a.entries) < a.opts.MaxEntries {
+		return
+	}
+	for key := range a.entries {
+		delete(a.entries, key)
+		return
+	}
+}
+
+func (a *accountCache) CallWithCallback(ctx context.Context, method string, params []interface{}, callback func(*http.Request, *http.Response) error) error {
+	return a.next.RPCCallWithCallback(ctx, method, params, callback)
+}
+
+func (a *accountCache) CallBatch(ctx context.Context, requests jsonrpc.RPCRequests) (jsonrpc.RPCResponses, error) {
+	return a.next.RPCCallBatch(ctx, requests)
+}
+
+// Close closes the wrapped client; it is called by rpc.Client.Close
+func (a *accountCache) Close() error {
+	return a.next.Close()
+}
+
+// cachePubkeys decodes the pubkey or pubkey list a request was built with
+func cachePubkeys(param interface{}) ([]solana.PublicKey, error) {
+	raw, err := json.Marshal(param)
+	if err != nil {
+		return nil, err
+	}
+	var pubkeys []solana.PublicKey
+	if err := json.Unmarshal(raw, &pubkeys); err == nil {
+		return pubkeys, nil
+	}
+	var pubkey solana.PublicKey
+	if err := json.Unmarshal(raw, &pubkey); err != nil {
+		return nil, err
+	}
+	return []solana.PublicKey{pubkey}, nil
+}
+
+// remarshal decodes in, re-encoded as JSON, into out
+func remarshal(in, out interface{}) error {
+	raw, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
@@ -3,22 +3,57 @@ package sol
 import (
 	"context"
 	"fmt"
+	"sync"
 
+	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
 	"github.com/gagliardetto/solana-go/rpc/ws"
+	"github.com/yimingWOW/solroute/pkg/logger"
+	"github.com/yimingWOW/solroute/pkg/metrics"
+	"github.com/yimingWOW/solroute/pkg/tracing"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Client represents a Solana client that handles both RPC and WebSocket connections
 type Client struct {
 	RpcClient *rpc.Client
 	WsClient  *ws.Client
+
+	priorityFee         *PriorityFeeOptions
+	lookupTables        map[solana.PublicKey]solana.PublicKeySlice
+	preflightSimulation bool
+	feeEstimator        PriorityFeeEstimator
+	txSender            TransactionSender
+	discovery           AccountDiscovery
+	portfolioValuer     PortfolioValuer
+	commitments         CommitmentOptions
+	commitmentRPC       *commitmentRPCClient
+	logger              logger.Logger
+	tracerProvider      trace.TracerProvider
+	metrics             *metrics.Metrics
+
+	blockhashMu     sync.Mutex
+	blockhash       *RecentBlockhash
+	blockhashMaxAge uint64
 }
 
 // NewClient creates a new Solana client with both RPC and WebSocket connections
 func NewClient(ctx context.Context, endpoint, wsEndpoint string) (*Client, error) {
+	return newClient(ctx, rpc.New(endpoint), wsEndpoint)
+}
+
+// NewClientFromRPC creates a client over an existing RPC client, without a
+// WebSocket connection, e.g. one built with rpc.NewWithCustomRPCClient
+func NewClientFromRPC(rpcClient *rpc.Client) *Client {
+	c, _ := newClient(context.Background(), rpcClient, "")
+	return c
+}
+
+func newClient(ctx context.Context, rpcClient *rpc.Client, wsEndpoint string) (*Client, error) {
 	c := &Client{
-		RpcClient: rpc.New(endpoint),
+		RpcClient: rpcClient,
 	}
+	c.SetCommitments(CommitmentOptions{})
 	if wsEndpoint != "" {
 		// Initialize WebSocket client
 		wsClient, err := ws.Connect(ctx, wsEndpoint)
@@ -30,10 +65,34 @@ func NewClient(ctx context.Context, endpoint, wsEndpoint string) (*Client, error
 	return c, nil
 }
 
+// SetLogger sets the Logger the client, and the protocols and watchers built on
+// it, report through. nil restores logger.Default
+func (c *Client) SetLogger(l logger.Logger) {
+	c.logger = l
+}
+
+// Logger returns the client's Logger
+func (c *Client) Logger() logger.Logger {
+	return logger.Or(c.logger)
+}
+
+// SetTracerProvider sets where the discovery, simulation, send and confirmation
+// spans go. nil restores the global provider
+func (c *Client) SetTracerProvider(tp trace.TracerProvider) {
+	c.tracerProvider = tp
+}
+
+func (c *Client) tracer() trace.Tracer {
+	return tracing.Tracer(c.tracerProvider)
+}
+
 // Close terminates all client connections
 func (c *Client) Close() error {
 	if c.WsClient != nil {
 		c.WsClient.Close()
 	}
+	if c.RpcClient != nil {
+		return c.RpcClient.Close()
+	}
 	return nil
 }
@@ -0,0 +1,39 @@
+package sol
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+func TestConstructorsSetDefaultCommitments(t *testing.T) {
+	want := CommitmentOptions{
+		Quoting:      rpc.CommitmentProcessed,
+		Sending:      rpc.CommitmentConfirmed,
+		Confirmation: rpc.CommitmentConfirmed,
+	}
+	ctx := context.Background()
+	constructors := map[string]func() (*Client, error){
+		"NewClient": func() (*Client, error) {
+			return NewClient(ctx, "http://127.0.0.1:8899", "")
+		},
+		"NewClientWithEndpoints": func() (*Client, error) {
+			return NewClientWithEndpoints(ctx, []Endpoint{{RPC: "http://127.0.0.1:8899"}}, EndpointOptions{})
+		},
+	}
+	for name, newClient := range constructors {
+		t.Run(name, func(t *testing.T) {
+			c, err := newClient()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.commitments != want {
+				t.Errorf("commitments = %+v, want %+v", c.commitments, want)
+			}
+			if c.commitmentRPC == nil {
+				t.Error("account reads don't apply the commitments")
+			}
+		})
+	}
+}
@@ -3,7 +3,6 @@ package sol
 import (
 	"context"
 	"encoding/binary"
-	"errors"
 	"fmt"
 
 	"github.com/gagliardetto/solana-go"
@@ -28,15 +27,18 @@ func (c *Client) GetClock(ctx context.Context) (*Clock, error) {
 	// Fetch the clock account
 	resp, err := c.RpcClient.GetAccountInfo(ctx, solana.SysVarClockPubkey)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch clock account: %w", err)
+		return nil, fmt.Errorf("failed to fetch clock account: %w", ClassifyError(err))
 	}
 
 	if resp.Value == nil {
-		return nil, errors.New("clock account not found in the network")
+		return nil, fmt.Errorf("clock account: %w", ErrAccountNotFound)
 	}
 
-	// Parse account data
-	data := resp.Value.Data.GetBinary()
+	return ParseClock(resp.Value.Data.GetBinary())
+}
+
+// ParseClock parses the data of the clock sysvar account
+func ParseClock(data []byte) (*Clock, error) {
 	if len(data) != ClockAccountDataSize {
 		return nil, fmt.Errorf("invalid clock account data length: expected %d bytes, got %d", ClockAccountDataSize, len(data))
 	}
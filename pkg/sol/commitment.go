@@ -0,0 +1,132 @@
+package sol
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+)
+
+// CommitmentOptions sets the commitment of each kind of operation. Zero fields
+// keep their defaults
+type CommitmentOptions struct {
+	// Discovery is used by getProgramAccounts calls that don't set one, i.e.
+	// pool discovery. Defaults to the node's default, finalized
+	Discovery rpc.CommitmentType
+	// Quoting is used by account reads that don't set one: the loading,
+	// refreshing and quoting of pools. Defaults to processed
+	Quoting rpc.CommitmentType
+	// Sending is the commitment of the blockhashes transactions are built with
+	// and of preflight checks. Defaults to confirmed
+	Sending rpc.CommitmentType
+	// Confirmation is the default ConfirmOptions.Commitment, the level SendTx
+	// waits for. Defaults to confirmed
+	Confirmation rpc.CommitmentType
+}
+
+// quotingMethods are the account reads CommitmentOptions.Quoting applies to
+var quotingMethods = map[string]bool{
+	"getAccountInfo":         true,
+	"getMultipleAccounts":    true,
+	"getTokenAccountBalance": true,
+}
+
+// SetCommitments replaces the commitment levels the client uses. The Discovery
+// and Quoting levels are applied by RpcClient itself, so they also reach pools
+// and protocols using it directly. NewClient applies the defaults.
+// It must be called before RpcClient is shared with other goroutines
+func (c *Client) SetCommitments(opts CommitmentOptions) {
+	if opts.Quoting == "" {
+		opts.Quoting = rpc.CommitmentProcessed
+	}
+	if opts.Sending == "" {
+		opts.Sending = rpc.CommitmentConfirmed
+	}
+	if opts.Confirmation == "" {
+		opts.Confirmation = rpc.CommitmentConfirmed
+	}
+	c.commitments = opts
+	if c.commitmentRPC == nil {
+		c.commitmentRPC = &commitmentRPCClient{next: c.RpcClient}
+		c.RpcClient = rpc.NewWithCustomRPCClient(c.commitmentRPC)
+	}
+	c.commitmentRPC.opts = opts
+}
+
+// sendingCommitment returns CommitmentOptions.Sending
+func (c *Client) sendingCommitment() rpc.CommitmentType {
+	if c.commitments.Sending == "" {
+		return rpc.CommitmentConfirmed
+	}
+	return c.commitments.Sending
+}
+
+// confirmationCommitment returns CommitmentOptions.Confirmation
+func (c *Client) confirmationCommitment() rpc.CommitmentType {
+	if c.commitments.Confirmation == "" {
+		return rpc.CommitmentConfirmed
+	}
+	return c.commitments.Confirmation
+}
+
+// commitmentRPCClient implements rpc.JSONRPCClient, filling in the commitment
+// of discovery and quoting calls that don't carry one
+type commitmentRPCClient struct {
+	next *rpc.Client
+	opts CommitmentOptions
+}
+
+func (c *commitmentRPCClient) CallForInto(ctx context.Context, out interface{}, method string, params []interface{}) error {
+	return c.next.RPCCallForInto(ctx, out, method, c.withCommitment(method, params))
+}
+
+func (c *commitmentRPCClient) CallWithCallback(ctx context.Context, method string, params []interface{}, callback func(*http.Request, *http.Response) error) error {
+	return c.next.RPCCallWithCallback(ctx, method, c.withCommitment(method, params), callback)
+}
+
+func (c *commitmentRPCClient) CallBatch(ctx context.Context, requests jsonrpc.RPCRequests) (jsonrpc.RPCResponses, error) {
+	for _, request := range requests {
+		if params, ok := request.Params.([]interface{}); ok {
+			request.Params = c.withCommitment(request.Method, params)
+		}
+	}
+	return c.next.RPCCallBatch(ctx, requests)
+}
+
+// Close closes the wrapped client; it is called by rpc.Client.Close
+func (c *commitmentRPCClient) Close() error {
+	return c.next.Close()
+}
+
+// withCommitment returns params with the commitment configured for method added
+// when the call doesn't set one. The first param is the pubkey or pubkey list,
+// the optional second one the config object
+func (c *commitmentRPCClient) withCommitment(method string, params []interface{}) []interface{} {
+	var commitment rpc.CommitmentType
+	switch {
+	case method == "getProgramAccounts":
+		commitment = c.opts.Discovery
+	case quotingMethods[method]:
+		commitment = c.opts.Quoting
+	}
+	if commitment == "" || len(params) == 0 || len(params) > 2 {
+		return params
+	}
+
+	config := rpc.M{}
+	if len(params) == 2 {
+		existing, ok := params[1].(rpc.M)
+		if !ok {
+			return params
+		}
+		if _, ok := existing["commitment"]; ok {
+			return params
+		}
+		for key, value := range existing {
+			config[key] = value
+		}
+	}
+	config["commitment"] = commitment
+	return []interface{}{params[0], config}
+}
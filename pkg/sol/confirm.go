@@ -0,0 +1,239 @@
+package sol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/yimingWOW/solroute/pkg/tracing"
+)
+
+// DefaultConfirmPollInterval is used when ConfirmOptions.PollInterval is zero
+const DefaultConfirmPollInterval = 2 * time.Second
+
+// TxStatus is the progress of a tracked transaction
+type TxStatus int
+
+const (
+	TxPending TxStatus = iota
+	TxProcessed
+	TxConfirmed
+	TxFinalized
+	TxFailed  // landed with an execution error
+	TxExpired // its blockhash expired before it landed
+)
+
+func (s TxStatus) String() string {
+	switch s {
+	case TxPending:
+		return "pending"
+	case TxProcessed:
+		return "processed"
+	case TxConfirmed:
+		return "confirmed"
+	case TxFinalized:
+		return "finalized"
+	case TxFailed:
+		return "failed"
+	case TxExpired:
+		return "expired"
+	}
+	return fmt.Sprintf("TxStatus(%d)", int(s))
+}
+
+// TxUpdate reports a step of a tracked transaction
+type TxUpdate struct {
+	Signature solana.Signature
+	Status    TxStatus
+	Slot      uint64
+	Err       error // set for TxFailed and TxExpired
+}
+
+// ConfirmOptions configures ConfirmTx
+type ConfirmOptions struct {
+	// Commitment the transaction must reach to count as landed. Defaults to the
+	// client's confirmation commitment, confirmed unless changed with SetCommitments
+	Commitment rpc.CommitmentType
+	// LastValidBlockHeight of the transaction's blockhash. Once the cluster is past
+	// it and the signature is still unknown, the transaction is reported expired.
+	// When zero, expiry is detected with isBlockhashValid instead, except for
+	// durable nonce transactions, which never expire
+	LastValidBlockHeight uint64
+	// PollInterval between signature status polls. Defaults to DefaultConfirmPollInterval
+	PollInterval time.Duration
+	// OnStatus, when set, is called from a single goroutine each time the
+	// transaction reaches a higher commitment level and with the final outcome
+	OnStatus func(TxUpdate)
+}
+
+// ConfirmTx tracks the first signature of tx until it lands at opts.Commitment,
+// fails or expires. Notifications from a signatureSubscribe on the WebSocket client,
+// when there is one, are combined with getSignatureStatuses polling so a dropped
+// notification can't stall the wait. The error is nil only when the transaction
+// landed; it matches ErrTransactionExpired when the blockhash expired first
+func (c *Client) ConfirmTx(ctx context.Context, tx *solana.Transaction, opts ConfirmOptions) (TxUpdate, error) {
+	ctx, span := c.tracer().Start(ctx, tracing.SpanConfirm)
+	update, err := c.confirmTx(ctx, tx, opts)
+	c.recordOutcome(update, err)
+	span.SetAttributes(
+		tracing.KeySignature.String(update.Signature.String()),
+		tracing.KeyStatus.String(update.Status.String()),
+	)
+	tracing.End(span, err)
+	return update, err
+}
+
+func (c *Client) confirmTx(ctx context.Context, tx *solana.Transaction, opts ConfirmOptions) (TxUpdate, error) {
+	if len(tx.Signatures) == 0 {
+		return TxUpdate{}, errors.New("transaction is not signed")
+	}
+	sig := tx.Signatures[0]
+	if opts.Commitment == "" {
+		opts.Commitment = c.confirmationCommitment()
+	}
+	target, err := commitmentStatus(opts.Commitment)
+	if err != nil {
+		return TxUpdate{}, err
+	}
+	interval := opts.PollInterval
+	if interval <= 0 {
+		interval = DefaultConfirmPollInterval
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	updates := make(chan TxUpdate, 2)
+	if c.WsClient != nil {
+		c.watchSignature(ctx, tx, rpc.CommitmentProcessed, updates)
+		if target != TxProcessed {
+			c.watchSignature(ctx, tx, opts.Commitment, updates)
+		}
+	}
+
+	current := TxUpdate{Signature: sig, Status: TxPending}
+	// apply records u and reports whether it settles the outcome
+	apply := func(u TxUpdate) bool {
+		if u.Status < TxFailed && u.Status <= current.Status {
+			return false
+		}
+		current = u
+		if opts.OnStatus != nil {
+			opts.OnStatus(u)
+		}
+		return u.Status >= target
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if u, ok := c.pollSignature(ctx, tx, opts.LastValidBlockHeight, rpc.CommitmentConfirmed); ok && apply(u) {
+			return current, current.Err
+		}
+		select {
+		case <-ctx.Done():
+			return current, fmt.Errorf("transaction %s not confirmed: %w", sig.String(), ctx.Err())
+		case u := <-updates:
+			if apply(u) {
+				return current, current.Err
+			}
+		case <-ticker.C:
+		}
+	}
+}
+
+// pollSignature fetches the status of tx, judging expiry by the block height at
+// heightCommitment. ok is false when the status could not be fetched or the
+// transaction is unknown but may still land
+func (c *Client) pollSignature(ctx context.Context, tx *solana.Transaction, lastValidBlockHeight uint64, heightCommitment rpc.CommitmentType) (TxUpdate, bool) {
+	sig := tx.Signatures[0]
+	// Read the block height first: a signature still unknown afterwards can no
+	// longer land once that height is past the blockhash validity
+	var expired bool
+	switch {
+	case lastValidBlockHeight > 0:
+		height, err := c.RpcClient.GetBlockHeight(ctx, heightCommitment)
+		if err != nil {
+			return TxUpdate{}, false
+		}
+		expired = height > lastValidBlockHeight
+	case !usesNonce(tx):
+		valid, err := c.RpcClient.IsBlockhashValid(ctx, tx.Message.RecentBlockhash, heightCommitment)
+		if err != nil {
+			return TxUpdate{}, false
+		}
+		expired = !valid.Value
+	}
+
+	statuses, err := c.RpcClient.GetSignatureStatuses(ctx, expired, sig)
+	if err != nil || len(statuses.Value) != 1 {
+		return TxUpdate{}, false
+	}
+	status := statuses.Value[0]
+	if status == nil {
+		if !expired {
+			return TxUpdate{}, false
+		}
+		return TxUpdate{
+			Signature: sig,
+			Status:    TxExpired,
+			Err:       fmt.Errorf("%w: %s", ErrTransactionExpired, sig.String()),
+		}, true
+	}
+
+	u := TxUpdate{Signature: sig, Slot: status.Slot}
+	switch {
+	case status.Err != nil:
+		u.Status = TxFailed
+		u.Err = transactionError(tx, status.Err)
+	case status.ConfirmationStatus == rpc.ConfirmationStatusFinalized:
+		u.Status = TxFinalized
+	case status.ConfirmationStatus == rpc.ConfirmationStatusConfirmed:
+		u.Status = TxConfirmed
+	default:
+		u.Status = TxProcessed
+	}
+	return u, true
+}
+
+// watchSignature forwards the signatureSubscribe notification for tx at
+// commitment to updates. Subscription failures are ignored, polling still
+// tracks the signature
+func (c *Client) watchSignature(ctx context.Context, tx *solana.Transaction, commitment rpc.CommitmentType, updates chan<- TxUpdate) {
+	sig := tx.Signatures[0]
+	sub, err := c.WsClient.SignatureSubscribe(sig, commitment)
+	if err != nil {
+		return
+	}
+	status, _ := commitmentStatus(commitment)
+	go func() {
+		defer sub.Unsubscribe()
+		result, err := sub.Recv(ctx)
+		if err != nil {
+			return
+		}
+		u := TxUpdate{Signature: sig, Status: status, Slot: result.Context.Slot}
+		if result.Value.Err != nil {
+			u.Status = TxFailed
+			u.Err = transactionError(tx, result.Value.Err)
+		}
+		select {
+		case updates <- u:
+		case <-ctx.Done():
+		}
+	}()
+}
+
+func commitmentStatus(commitment rpc.CommitmentType) (TxStatus, error) {
+	switch commitment {
+	case rpc.CommitmentProcessed:
+		return TxProcessed, nil
+	case rpc.CommitmentConfirmed:
+		return TxConfirmed, nil
+	case rpc.CommitmentFinalized:
+		return TxFinalized, nil
+	}
+	return TxPending, fmt.Errorf("unsupported commitment: %s", commitment)
+}
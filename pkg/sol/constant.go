@@ -7,4 +7,6 @@ var (
 	NativeSOL = solana.MustPublicKeyFromBase58("11111111111111111111111111111111")
 
 	TokenAccountSize = uint64(165)
+
+	AddressLookupTableProgramID = solana.MustPublicKeyFromBase58("AddressLookupTab1e1111111111111111111111111")
 )
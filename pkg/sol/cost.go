@@ -0,0 +1,89 @@
+package sol
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// CostEstimate itemizes the lamports a transaction is expected to cost its payer
+type CostEstimate struct {
+	BaseFee     uint64 // signature fees
+	PriorityFee uint64 // compute unit price times the requested limit
+	// Rent locked in the associated token accounts the transaction creates. It
+	// is refunded when the accounts are closed, e.g. WSOL closed after the swap
+	Rent uint64
+	Tip  uint64 // lamports paid to tip accounts, e.g. a Jito tip
+	// ComputeUnitLimit and ComputeUnitPrice (micro-lamports) the priority fee is based on
+	ComputeUnitLimit uint64
+	ComputeUnitPrice uint64
+}
+
+// Total returns the sum of the fees, rent and tip
+func (e CostEstimate) Total() uint64 {
+	return e.BaseFee + e.PriorityFee + e.Rent + e.Tip
+}
+
+// CostOptions configures EstimateCost
+type CostOptions struct {
+	// TipAccounts are the accounts lamport transfers to count as tips, e.g.
+	// provider.JitoTipAccounts
+	TipAccounts []solana.PublicKey
+	// Tip is added for a tip instruction not part of the instructions yet,
+	// e.g. one appended just before sending
+	Tip uint64
+}
+
+// EstimateCost reports what sending insts paid by payer is expected to cost.
+// When insts carry no compute budget instructions and a priority fee is set on
+// the client, the fee is estimated the way SendTx would add it
+func (c *Client) EstimateCost(ctx context.Context, payer solana.PublicKey, insts []solana.Instruction, opts CostOptions) (*CostEstimate, error) {
+	if c.priorityFee != nil && !hasComputeBudget(insts) {
+		var err error
+		insts, err = c.WithPriorityFee(ctx, payer, insts, *c.priorityFee)
+		if err != nil {
+			return nil, fmt.Errorf("failed to estimate priority fee: %w", err)
+		}
+	}
+	scan, err := scanInstructions(payer, insts)
+	if err != nil {
+		return nil, err
+	}
+	estimate := &CostEstimate{
+		BaseFee:          scan.baseFee(),
+		Tip:              opts.Tip,
+		PriorityFee:      scan.priorityFee(),
+		ComputeUnitLimit: scan.unitLimit,
+		ComputeUnitPrice: scan.unitPrice,
+	}
+
+	tipAccounts := make(map[solana.PublicKey]bool, len(opts.TipAccounts))
+	for _, account := range opts.TipAccounts {
+		tipAccounts[account] = true
+	}
+	for _, transfer := range scan.transfers {
+		if tipAccounts[transfer.to] {
+			estimate.Tip += transfer.lamports
+		}
+	}
+
+	if len(scan.created) > 0 {
+		results, err := c.RpcClient.GetMultipleAccountsWithOpts(ctx, scan.created, &rpc.GetMultipleAccountsOpts{
+			Encoding:   solana.EncodingBase64,
+			Commitment: rpc.CommitmentConfirmed,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get accounts: %w", ClassifyError(err))
+		}
+		if len(results.Value) != len(scan.created) {
+			return nil, fmt.Errorf("expected %d accounts, got %d", len(scan.created), len(results.Value))
+		}
+		estimate.Rent, err = createdAccountsRent(ctx, c.RpcClient, scan.created, results.Value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return estimate, nil
+}
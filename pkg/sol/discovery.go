@@ -0,0 +1,261 @@
+package sol
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/yimingWOW/solroute/pkg/tracing"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// AccountDiscovery finds the accounts owned by a program that match filters,
+// with the semantics of getProgramAccounts. Protocols discover pools through
+// the Client's AccountDiscovery; see SetAccountDiscovery
+type AccountDiscovery interface {
+	FindProgramAccounts(ctx context.Context, program solana.PublicKey, filters []rpc.RPCFilter) (rpc.GetProgramAccountsResult, error)
+}
+
+// SetAccountDiscovery replaces getProgramAccounts on RpcClient as the pool
+// discovery backend, e.g. with a provider indexer, a LocalIndex or a
+// FallbackDiscovery chaining several. nil restores the default
+func (c *Client) SetAccountDiscovery(discovery AccountDiscovery) {
+	c.discovery = discovery
+}
+
+// FindProgramAccounts finds the accounts of program matching filters with the
+// configured AccountDiscovery, getProgramAccounts on RpcClient by default
+func (c *Client) FindProgramAccounts(ctx context.Context, program solana.PublicKey, filters []rpc.RPCFilter) (rpc.GetProgramAccountsResult, error) {
+	ctx, span := c.tracer().Start(ctx, tracing.SpanDiscovery, trace.WithAttributes(
+		tracing.KeyProgram.String(program.String()),
+	))
+	var discovery AccountDiscovery = ProgramAccounts{Client: c.RpcClient}
+	if c.discovery != nil {
+		discovery = c.discovery
+	}
+	accounts, err := discovery.FindProgramAccounts(ctx, program, filters)
+	span.SetAttributes(tracing.KeyCount.Int(len(accounts)))
+	tracing.End(span, err)
+	return accounts, err
+}
+
+// SlicedDiscovery is implemented by discovery backends that can return only
+// part of the data of each matching account, like the dataSlice of
+// getProgramAccounts
+type SlicedDiscovery interface {
+	FindProgramAccountsSliced(ctx context.Context, program solana.PublicKey, filters []rpc.RPCFilter, slice rpc.DataSlice) (rpc.GetProgramAccountsResult, error)
+}
+
+// FindProgramAccountsSliced is FindProgramAccounts returning only slice of the
+// data of each account, e.g. a zero length to find just the keys and fetch
+// the accounts worth loading later. Backends that aren't a SlicedDiscovery
+// return full accounts, which are cut down here
+func (c *Client) FindProgramAccountsSliced(ctx context.Context, program solana.PublicKey, filters []rpc.RPCFilter, slice rpc.DataSlice) (rpc.GetProgramAccountsResult, error) {
+	ctx, span := c.tracer().Start(ctx, tracing.SpanDiscovery, trace.WithAttributes(
+		tracing.KeyProgram.String(program.String()),
+	))
+	var discovery AccountDiscovery = ProgramAccounts{Client: c.RpcClient}
+	if c.discovery != nil {
+		discovery = c.discovery
+	}
+	accounts, err := findProgramAccountsSliced(ctx, discovery, program, filters, slice)
+	span.SetAttributes(tracing.KeyCount.Int(len(accounts)))
+	tracing.End(span, err)
+	return accounts, err
+}
+
+func findProgramAccountsSliced(ctx context.Context, discovery AccountDiscovery, program solana.PublicKey, filters []rpc.RPCFilter, slice rpc.DataSlice) (rpc.GetProgramAccountsResult, error) {
+	if sliced, ok := discovery.(SlicedDiscovery); ok {
+		return sliced.FindProgramAccountsSliced(ctx, program, filters, slice)
+	}
+	result, err := discovery.FindProgramAccounts(ctx, program, filters)
+	if err != nil {
+		return nil, err
+	}
+	out := make(rpc.GetProgramAccountsResult, 0, len(result))
+	for _, keyed := range result {
+		if keyed.Account == nil {
+			continue
+		}
+		account := *keyed.Account
+		account.Data = rpc.DataBytesOrJSONFromBytes(sliceData(account.Data.GetBinary(), slice))
+		out = append(out, &rpc.KeyedAccount{Pubkey: keyed.Pubkey, Account: &account})
+	}
+	return out, nil
+}
+
+// sliceData cuts data down to slice the way getProgramAccounts does, clamped
+// to the data
+func sliceData(data []byte, slice rpc.DataSlice) []byte {
+	var offset uint64
+	if slice.Offset != nil {
+		offset = *slice.Offset
+	}
+	length := uint64(len(data))
+	if slice.Length != nil {
+		length = *slice.Length
+	}
+	if offset >= uint64(len(data)) {
+		return []byte{}
+	}
+	end := min(offset+length, uint64(len(data)))
+	return append([]byte(nil), data[offset:end]...)
+}
+
+// ProgramAccounts is the standard getProgramAccounts backend
+type ProgramAccounts struct {
+	Client *rpc.Client
+}
+
+func (p ProgramAccounts) FindProgramAccounts(ctx context.Context, program solana.PublicKey, filters []rpc.RPCFilter) (rpc.GetProgramAccountsResult, error) {
+	result, err := p.Client.GetProgramAccountsWithOpts(ctx, program, &rpc.GetProgramAccountsOpts{
+		Filters: filters,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get program accounts: %w", ClassifyError(err))
+	}
+	return result, nil
+}
+
+func (p ProgramAccounts) FindProgramAccountsSliced(ctx context.Context, program solana.PublicKey, filters []rpc.RPCFilter, slice rpc.DataSlice) (rpc.GetProgramAccountsResult, error) {
+	result, err := p.Client.GetProgramAccountsWithOpts(ctx, program, &rpc.GetProgramAccountsOpts{
+		Filters:   filters,
+		DataSlice: &slice,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get program accounts: %w", ClassifyError(err))
+	}
+	return result, nil
+}
+
+// FallbackDiscovery tries each backend in order and returns the first result
+// that isn't an error, e.g. a provider indexer before getProgramAccounts
+type FallbackDiscovery []AccountDiscovery
+
+func (f FallbackDiscovery) FindProgramAccounts(ctx context.Context, program solana.PublicKey, filters []rpc.RPCFilter) (rpc.GetProgramAccountsResult, error) {
+	var errs []error
+	for _, backend := range f {
+		result, err := backend.FindProgramAccounts(ctx, program, filters)
+		if err == nil {
+			return result, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return nil, errors.New("no discovery backend configured")
+	}
+	return nil, errors.Join(errs...)
+}
+
+func (f FallbackDiscovery) FindProgramAccountsSliced(ctx context.Context, program solana.PublicKey, filters []rpc.RPCFilter, slice rpc.DataSlice) (rpc.GetProgramAccountsResult, error) {
+	var errs []error
+	for _, backend := range f {
+		result, err := findProgramAccountsSliced(ctx, backend, program, filters, slice)
+		if err == nil {
+			return result, nil
+		}
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) == 0 {
+		return nil, errors.New("no discovery backend configured")
+	}
+	return nil, errors.Join(errs...)
+}
+
+// LocalIndex keeps program accounts in memory and answers filter queries
+// without the RPC node. Fill it with Load or from a stream of account updates
+// with Put; only loaded programs are answered, others fail so a
+// FallbackDiscovery moves on
+type LocalIndex struct {
+	mu       sync.RWMutex
+	programs map[solana.PublicKey]map[solana.PublicKey]*rpc.Account
+}
+
+var _ AccountDiscovery = (*LocalIndex)(nil)
+
+// NewLocalIndex returns an empty index
+func NewLocalIndex() *LocalIndex {
+	return &LocalIndex{programs: make(map[solana.PublicKey]map[solana.PublicKey]*rpc.Account)}
+}
+
+// Load adds the accounts of program matching filters found with discovery,
+// e.g. a broad dataSize-only scan done once at startup, and marks program indexed
+func (l *LocalIndex) Load(ctx context.Context, discovery AccountDiscovery, program solana.PublicKey, filters ...rpc.RPCFilter) error {
+	result, err := discovery.FindProgramAccounts(ctx, program, filters)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	accounts := l.program(program)
+	for _, keyed := range result {
+		if keyed.Account != nil {
+			accounts[keyed.Pubkey] = keyed.Account
+		}
+	}
+	return nil
+}
+
+// Put adds or replaces an account of program and marks program indexed.
+// A nil account removes it
+func (l *LocalIndex) Put(program, pubkey solana.PublicKey, account *rpc.Account) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if account == nil {
+		delete(l.program(program), pubkey)
+		return
+	}
+	l.program(program)[pubkey] = account
+}
+
+// program returns the accounts of program, creating the set; the caller holds l.mu
+func (l *LocalIndex) program(program solana.PublicKey) map[solana.PublicKey]*rpc.Account {
+	accounts, ok := l.programs[program]
+	if !ok {
+		accounts = make(map[solana.PublicKey]*rpc.Account)
+		l.programs[program] = accounts
+	}
+	return accounts
+}
+
+func (l *LocalIndex) FindProgramAccounts(ctx context.Context, program solana.PublicKey, filters []rpc.RPCFilter) (rpc.GetProgramAccountsResult, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	accounts, ok := l.programs[program]
+	if !ok {
+		return nil, fmt.Errorf("program %s is not indexed", program.String())
+	}
+	result := rpc.GetProgramAccountsResult{}
+	for pubkey, account := range accounts {
+		if matchFilters(account.Data.GetBinary(), filters) {
+			result = append(result, &rpc.KeyedAccount{Pubkey: pubkey, Account: account})
+		}
+	}
+	return result, nil
+}
+
+// matchFilters evaluates getProgramAccounts filters against account data
+func matchFilters(data []byte, filters []rpc.RPCFilter) bool {
+	for _, filter := range filters {
+		if filter.DataSize != 0 && uint64(len(data)) != filter.DataSize {
+			return false
+		}
+		if memcmp := filter.Memcmp; memcmp != nil {
+			end := memcmp.Offset + uint64(len(memcmp.Bytes))
+			if end > uint64(len(data)) || !bytes.Equal(data[memcmp.Offset:end], memcmp.Bytes) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,218 @@
+package sol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+	"github.com/gagliardetto/solana-go/rpc/ws"
+)
+
+// Endpoint is an RPC endpoint with its optional WebSocket counterpart
+type Endpoint struct {
+	RPC string
+	WS  string
+}
+
+// Strategy selects the order in which healthy endpoints are tried
+type Strategy int
+
+const (
+	// StrategyFailover always prefers the first healthy endpoint in the list
+	StrategyFailover Strategy = iota
+	// StrategyRoundRobin spreads requests across all healthy endpoints
+	StrategyRoundRobin
+)
+
+// EndpointOptions configures NewClientWithEndpoints
+type EndpointOptions struct {
+	Strategy Strategy
+	// Cooldown is how long an endpoint is skipped after a rate limit, timeout or
+	// server error. Defaults to 10s
+	Cooldown time.Duration
+	// HealthCheckInterval enables periodic getHealth probes when non-zero
+	HealthCheckInterval time.Duration
+	// RequestTimeout bounds a single request to one endpoint. Defaults to 30s
+	RequestTimeout time.Duration
+}
+
+// NewClientWithEndpoints creates a client that spreads RPC requests over several
+// endpoints, failing over to the next one on 429s, timeouts and server errors.
+// The WebSocket client connects to the first endpoint whose WS URL accepts
+func NewClientWithEndpoints(ctx context.Context, endpoints []Endpoint, opts EndpointOptions) (*Client, error) {
+	if len(endpoints) == 0 {
+		return nil, errors.New("at least one endpoint is required")
+	}
+	if opts.Cooldown == 0 {
+		opts.Cooldown = 10 * time.Second
+	}
+	if opts.RequestTimeout == 0 {
+		opts.RequestTimeout = 30 * time.Second
+	}
+
+	multi := newMultiRPCClient(endpoints, opts)
+	c := &Client{
+		RpcClient: rpc.NewWithCustomRPCClient(multi),
+	}
+	c.SetCommitments(CommitmentOptions{})
+
+	var wsErr error
+	for _, endpoint := range endpoints {
+		if endpoint.WS == "" {
+			continue
+		}
+		wsClient, err := ws.Connect(ctx, endpoint.WS)
+		if err != nil {
+			wsErr = err
+			continue
+		}
+		c.WsClient = wsClient
+		break
+	}
+	if c.WsClient == nil && wsErr != nil {
+		multi.Close()
+		return nil, fmt.Errorf("failed to establish WebSocket connection: %w", wsErr)
+	}
+	return c, nil
+}
+
+type endpointState struct {
+	url         string
+	client      jsonrpc.RPCClient
+	unhealthyAt atomic.Int64 // unix nanos of the last failure, 0 when healthy
+}
+
+func (e *endpointState) healthy(now time.Time, cooldown time.Duration) bool {
+	failedAt := e.unhealthyAt.Load()
+	return failedAt == 0 || now.Sub(time.Unix(0, failedAt)) >= cooldown
+}
+
+// multiRPCClient implements rpc.JSONRPCClient over several endpoints
+type multiRPCClient struct {
+	endpoints []*endpointState
+	opts      EndpointOptions
+	next      atomic.Uint64
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+func newMultiRPCClient(endpoints []Endpoint, opts EndpointOptions) *multiRPCClient {
+	httpClient := &http.Client{Timeout: opts.RequestTimeout}
+	m := &multiRPCClient{
+		opts: opts,
+		stop: make(chan struct{}),
+	}
+	for _, endpoint := range endpoints {
+		m.endpoints = append(m.endpoints, &endpointState{
+			url:    endpoint.RPC,
+			client: jsonrpc.NewClientWithOpts(endpoint.RPC, &jsonrpc.RPCClientOpts{HTTPClient: httpClient}),
+		})
+	}
+	if opts.HealthCheckInterval > 0 {
+		go m.healthLoop()
+	}
+	return m
+}
+
+// order returns the endpoints to try, healthy ones first
+func (m *multiRPCClient) order() []*endpointState {
+	n := len(m.endpoints)
+	start := 0
+	if m.opts.Strategy == StrategyRoundRobin {
+		start = int(m.next.Add(1) % uint64(n))
+	}
+	now := time.Now()
+	healthy := make([]*endpointState, 0, n)
+	var unhealthy []*endpointState
+	for i := 0; i < n; i++ {
+		e := m.endpoints[(start+i)%n]
+		if e.healthy(now, m.opts.Cooldown) {
+			healthy = append(healthy, e)
+		} else {
+			unhealthy = append(unhealthy, e)
+		}
+	}
+	// Still try endpoints in cooldown rather than failing outright
+	return append(healthy, unhealthy...)
+}
+
+func (m *multiRPCClient) do(ctx context.Context, call func(jsonrpc.RPCClient) error) error {
+	var lastErr error
+	for _, e := range m.order() {
+		err := call(e.client)
+		if err == nil || !isRetryableError(err) {
+			if err == nil {
+				e.unhealthyAt.Store(0)
+			}
+			return err
+		}
+		e.unhealthyAt.Store(time.Now().UnixNano())
+		lastErr = fmt.Errorf("%s: %w", e.url, err)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return ClassifyError(lastErr)
+}
+
+func (m *multiRPCClient) CallForInto(ctx context.Context, out interface{}, method string, params []interface{}) error {
+	return m.do(ctx, func(c jsonrpc.RPCClient) error {
+		return c.CallForInto(ctx, out, method, params)
+	})
+}
+
+func (m *multiRPCClient) CallWithCallback(ctx context.Context, method string, params []interface{}, callback func(*http.Request, *http.Response) error) error {
+	return m.do(ctx, func(c jsonrpc.RPCClient) error {
+		return c.CallWithCallback(ctx, method, params, callback)
+	})
+}
+
+func (m *multiRPCClient) CallBatch(ctx context.Context, requests jsonrpc.RPCRequests) (jsonrpc.RPCResponses, error) {
+	var responses jsonrpc.RPCResponses
+	err := m.do(ctx, func(c jsonrpc.RPCClient) error {
+		var err error
+		responses, err = c.CallBatch(ctx, requests)
+		return err
+	})
+	return responses, err
+}
+
+// Close stops the health checker; it is called by rpc.Client.Close
+func (m *multiRPCClient) Close() error {
+	m.stopOnce.Do(func() { close(m.stop) })
+	return nil
+}
+
+func (m *multiRPCClient) healthLoop() {
+	ticker := time.NewTicker(m.opts.HealthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ticker.C:
+			for _, e := range m.endpoints {
+				ctx, cancel := context.WithTimeout(context.Background(), m.opts.RequestTimeout)
+				var out string
+				if err := e.client.CallForInto(ctx, &out, "getHealth", nil); err != nil || out != "ok" {
+					e.unhealthyAt.Store(time.Now().UnixNano())
+				} else {
+					e.unhealthyAt.Store(0)
+				}
+				cancel()
+			}
+		}
+	}
+}
+
+// isRetryableError reports whether a request should be retried on another endpoint
+func isRetryableError(err error) bool {
+	return ClassifyRetry(err) != ErrorClassPermanent
+}
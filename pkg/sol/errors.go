@@ -0,0 +1,232 @@
+package sol
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+)
+
+// Errors returned by Client methods and pool implementations. Match them with errors.Is;
+// the original error stays in the chain for logging
+var (
+	ErrRateLimited      = errors.New("rpc rate limited")
+	ErrAccountNotFound  = errors.New("account not found")
+	ErrSlippageExceeded = errors.New("slippage exceeded")
+	// ErrTransactionExpired means the blockhash expired before the transaction landed
+	ErrTransactionExpired = errors.New("transaction expired")
+	// ErrInsufficientBalance is matched by *InsufficientBalanceError
+	ErrInsufficientBalance = errors.New("insufficient balance")
+)
+
+// slippageErrors maps supported DEX programs to the custom error they return
+// when the minimum output check fails
+var slippageErrors = map[solana.PublicKey]struct {
+	Code int64
+	Name string
+}{
+	solana.MustPublicKeyFromBase58("675kPX9MHTjS2zt1qfr1NYHuzeLXfQM9H24wFSUt1Mp8"): {30, "ExceededSlippage"},                  // Raydium AMM v4
+	solana.MustPublicKeyFromBase58("CPMMoo8L3F4NbTegBCKVNunggL7H1ZpdTHKxQB5qKP1C"): {6005, "ExceededSlippage"},                // Raydium CPMM
+	solana.MustPublicKeyFromBase58("CAMMCzo5YL8w4VFF8KVHrK22GGUsp5VTaW7grrKgrWqK"): {6022, "TooLittleOutputReceived"},         // Raydium CLMM
+	solana.MustPublicKeyFromBase58("LBUZKhRxPF3XUpBCjp4YzTKgLccjZhTSDM9YuVaPwxo"):  {6003, "ExceededAmountSlippageTolerance"}, // Meteora DLMM
+	solana.MustPublicKeyFromBase58("pAMMBay6oceH9fJKBRHGP5D4bD4sWpmSwMn52FMfXEA"):  {6004, "ExceededSlippage"},                // Pump AMM
+}
+
+// programAliases maps other deployments of the supported DEX programs, e.g.
+// on devnet, to the mainnet program their errors are decoded like
+var programAliases sync.Map
+
+// RegisterProgramAlias makes the custom errors of program, a deployment of
+// the DEX program mainnet, decode like mainnet's, so its slippage failures
+// match ErrSlippageExceeded. Protocols built with another program ID register
+// it themselves
+func RegisterProgramAlias(program, mainnet solana.PublicKey) {
+	if !program.IsZero() && !program.Equals(mainnet) {
+		programAliases.Store(program, mainnet)
+	}
+}
+
+// mainnetProgram returns the mainnet program registered for program, or
+// program itself
+func mainnetProgram(program solana.PublicKey) solana.PublicKey {
+	if mainnet, ok := programAliases.Load(program); ok {
+		return mainnet.(solana.PublicKey)
+	}
+	return program
+}
+
+// ClassifyError wraps an RPC error with the matching sentinel error, if any
+func ClassifyError(err error) error {
+	if err == nil || errors.Is(err, ErrRateLimited) || errors.Is(err, ErrAccountNotFound) {
+		return err
+	}
+
+	var httpErr *jsonrpc.HTTPError
+	if errors.As(err, &httpErr) && httpErr.Code == http.StatusTooManyRequests {
+		return fmt.Errorf("%w: %w", ErrRateLimited, err)
+	}
+	// Some providers answer 429 inside a JSON-RPC error body
+	var rpcErr *jsonrpc.RPCError
+	if errors.As(err, &rpcErr) && rpcErr.Code == http.StatusTooManyRequests {
+		return fmt.Errorf("%w: %w", ErrRateLimited, err)
+	}
+	if errors.Is(err, rpc.ErrNotFound) {
+		return fmt.Errorf("%w: %w", ErrAccountNotFound, err)
+	}
+	return err
+}
+
+// anchorPrograms are the supported DEX programs built with Anchor, which share
+// the framework error codes below 6000
+var anchorPrograms = map[solana.PublicKey]bool{
+	solana.MustPublicKeyFromBase58("CPMMoo8L3F4NbTegBCKVNunggL7H1ZpdTHKxQB5qKP1C"): true,
+	solana.MustPublicKeyFromBase58("CAMMCzo5YL8w4VFF8KVHrK22GGUsp5VTaW7grrKgrWqK"): true,
+	solana.MustPublicKeyFromBase58("LBUZKhRxPF3XUpBCjp4YzTKgLccjZhTSDM9YuVaPwxo"):  true,
+	solana.MustPublicKeyFromBase58("pAMMBay6oceH9fJKBRHGP5D4bD4sWpmSwMn52FMfXEA"):  true,
+}
+
+// tokenErrorNames names the SPL Token errors, shared by Token-2022
+var tokenErrorNames = map[int64]string{
+	0:  "NotRentExempt",
+	1:  "InsufficientFunds",
+	2:  "InvalidMint",
+	3:  "MintMismatch",
+	4:  "OwnerMismatch",
+	5:  "FixedSupply",
+	6:  "AlreadyInUse",
+	7:  "InvalidNumberOfProvidedSigners",
+	8:  "InvalidNumberOfRequiredSigners",
+	9:  "UninitializedState",
+	10: "NativeNotSupported",
+	11: "NonNativeHasBalance",
+	12: "InvalidInstruction",
+	13: "InvalidState",
+	14: "Overflow",
+	15: "AuthorityTypeNotSupported",
+	16: "MintCannotFreeze",
+	17: "AccountFrozen",
+	18: "MintDecimalsMismatch",
+	19: "NonNativeNotSupported",
+}
+
+var anchorErrorNames = map[int64]string{
+	100:  "InstructionMissing",
+	101:  "InstructionFallbackNotFound",
+	102:  "InstructionDidNotDeserialize",
+	2000: "ConstraintMut",
+	2001: "ConstraintHasOne",
+	2002: "ConstraintSigner",
+	2003: "ConstraintRaw",
+	2004: "ConstraintOwner",
+	2005: "ConstraintRentExempt",
+	2006: "ConstraintSeeds",
+	2012: "ConstraintAddress",
+	3001: "AccountDiscriminatorNotFound",
+	3002: "AccountDiscriminatorMismatch",
+	3003: "AccountDidNotDeserialize",
+	3005: "AccountNotEnoughKeys",
+	3006: "AccountNotMutable",
+	3007: "AccountOwnedByWrongProgram",
+	3008: "InvalidProgramId",
+	3010: "AccountNotSigner",
+	3012: "AccountNotInitialized",
+}
+
+// InstructionError is an instruction failure decoded from a transaction error
+type InstructionError struct {
+	Index     int
+	ProgramID solana.PublicKey // zero when the program could not be resolved
+	Code      *int64           // custom program error code, nil for built-in errors
+	Name      string           // e.g. "ExceededSlippage" or "InvalidAccountData", empty when unknown
+}
+
+func (e *InstructionError) Error() string {
+	msg := fmt.Sprintf("instruction %d", e.Index)
+	if !e.ProgramID.IsZero() {
+		msg += " of program " + e.ProgramID.String()
+	}
+	switch {
+	case e.Code != nil && e.Name != "":
+		return fmt.Sprintf("%s failed with %s (custom error %d)", msg, e.Name, *e.Code)
+	case e.Code != nil:
+		return fmt.Sprintf("%s failed with custom error %d", msg, *e.Code)
+	}
+	return fmt.Sprintf("%s failed with %s", msg, e.Name)
+}
+
+// Is makes errors.Is(err, ErrSlippageExceeded) hold for DEX slippage failures
+func (e *InstructionError) Is(target error) bool {
+	if target != ErrSlippageExceeded || e.Code == nil {
+		return false
+	}
+	slippage, ok := slippageErrors[mainnetProgram(e.ProgramID)]
+	return ok && slippage.Code == *e.Code
+}
+
+// transactionError converts a transaction execution error into an error wrapping
+// an *InstructionError when an instruction failed. DEX slippage failures match
+// ErrSlippageExceeded
+func transactionError(tx *solana.Transaction, txErr interface{}) error {
+	raw, err := json.Marshal(txErr)
+	if err != nil {
+		return fmt.Errorf("transaction failed: %v", txErr)
+	}
+
+	// Instruction failures look like {"InstructionError":[index,{"Custom":code}]}
+	// or {"InstructionError":[index,"InvalidAccountData"]}
+	var instErr struct {
+		InstructionError []json.RawMessage `json:"InstructionError"`
+	}
+	if err := json.Unmarshal(raw, &instErr); err != nil || len(instErr.InstructionError) != 2 {
+		return fmt.Errorf("transaction failed: %s", raw)
+	}
+	var index int
+	if json.Unmarshal(instErr.InstructionError[0], &index) != nil {
+		return fmt.Errorf("transaction failed: %s", raw)
+	}
+	decoded := &InstructionError{Index: index}
+	if tx != nil && index >= 0 && index < len(tx.Message.Instructions) {
+		if programID, err := tx.Message.Program(tx.Message.Instructions[index].ProgramIDIndex); err == nil {
+			decoded.ProgramID = programID
+		}
+	}
+
+	var name string
+	var variant map[string]json.RawMessage
+	switch {
+	case json.Unmarshal(instErr.InstructionError[1], &name) == nil:
+		decoded.Name = name
+	case json.Unmarshal(instErr.InstructionError[1], &variant) == nil && len(variant) == 1:
+		for key, value := range variant {
+			var code int64
+			if key == "Custom" && json.Unmarshal(value, &code) == nil {
+				decoded.Code = &code
+				decoded.Name = customErrorName(decoded.ProgramID, code)
+			} else {
+				decoded.Name = key
+			}
+		}
+	default:
+		return fmt.Errorf("transaction failed: %s", raw)
+	}
+	return fmt.Errorf("transaction failed: %w", decoded)
+}
+
+func customErrorName(programID solana.PublicKey, code int64) string {
+	programID = mainnetProgram(programID)
+	if slippage, ok := slippageErrors[programID]; ok && slippage.Code == code {
+		return slippage.Name
+	}
+	if programID.Equals(solana.TokenProgramID) || programID.Equals(solana.Token2022ProgramID) {
+		return tokenErrorNames[code]
+	}
+	if anchorPrograms[programID] {
+		return anchorErrorNames[code]
+	}
+	return ""
+}
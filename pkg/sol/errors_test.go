@@ -0,0 +1,26 @@
+package sol
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+func TestSlippageErrorOfRegisteredProgram(t *testing.T) {
+	mainnet := solana.MustPublicKeyFromBase58("CPMMoo8L3F4NbTegBCKVNunggL7H1ZpdTHKxQB5qKP1C")
+	devnet := solana.NewWallet().PublicKey()
+	code := int64(6005)
+
+	if err := error(&InstructionError{ProgramID: devnet, Code: &code}); errors.Is(err, ErrSlippageExceeded) {
+		t.Fatal("unregistered program matched ErrSlippageExceeded")
+	}
+	RegisterProgramAlias(devnet, mainnet)
+	err := &InstructionError{ProgramID: devnet, Code: &code, Name: customErrorName(devnet, code)}
+	if !errors.Is(err, ErrSlippageExceeded) {
+		t.Fatalf("%v doesn't match ErrSlippageExceeded", err)
+	}
+	if err.Name != "ExceededSlippage" {
+		t.Fatalf("got name %q, want ExceededSlippage", err.Name)
+	}
+}
@@ -0,0 +1,298 @@
+package sol
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	addresslookuptable "github.com/gagliardetto/solana-go/programs/address-lookup-table"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// maxExtendAddresses keeps an extend transaction, signed by an authority that
+// also pays, under the packet size limit
+const maxExtendAddresses = 20
+
+// Address lookup table program instruction discriminants
+const (
+	lookupTableCreate     uint32 = 0
+	lookupTableExtend     uint32 = 2
+	lookupTableDeactivate uint32 = 3
+	lookupTableClose      uint32 = 4
+)
+
+// FindLookupTableAddress derives the address of the lookup table created by
+// authority at recentSlot
+func FindLookupTableAddress(authority solana.PublicKey, recentSlot uint64) (solana.PublicKey, uint8, error) {
+	slot := make([]byte, 8)
+	binary.LittleEndian.PutUint64(slot, recentSlot)
+	return solana.FindProgramAddress([][]byte{authority.Bytes(), slot}, AddressLookupTableProgramID)
+}
+
+// NewCreateLookupTableInstruction returns the instruction creating a lookup table
+// owned by authority, together with the table address. recentSlot must be a
+// slot the cluster still considers recent
+func NewCreateLookupTableInstruction(authority, payer solana.PublicKey, recentSlot uint64) (solana.Instruction, solana.PublicKey, error) {
+	table, bump, err := FindLookupTableAddress(authority, recentSlot)
+	if err != nil {
+		return nil, solana.PublicKey{}, fmt.Errorf("failed to find lookup table address: %w", err)
+	}
+
+	data := make([]byte, 13)
+	binary.LittleEndian.PutUint32(data[0:4], lookupTableCreate)
+	binary.LittleEndian.PutUint64(data[4:12], recentSlot)
+	data[12] = bump
+
+	accounts := solana.AccountMetaSlice{
+		solana.Meta(table).WRITE(),
+		solana.Meta(authority).SIGNER(),
+		solana.Meta(payer).WRITE().SIGNER(),
+		solana.Meta(solana.SystemProgramID),
+	}
+	return solana.NewInstruction(AddressLookupTableProgramID, accounts, data), table, nil
+}
+
+// NewExtendLookupTableInstruction returns the instruction appending addresses to table
+func NewExtendLookupTableInstruction(table, authority, payer solana.PublicKey, addresses []solana.PublicKey) solana.Instruction {
+	buf := new(bytes.Buffer)
+	binary.Write(buf, binary.LittleEndian, lookupTableExtend)
+	binary.Write(buf, binary.LittleEndian, uint64(len(addresses)))
+	for _, address := range addresses {
+		buf.Write(address.Bytes())
+	}
+
+	accounts := solana.AccountMetaSlice{
+		solana.Meta(table).WRITE(),
+		solana.Meta(authority).SIGNER(),
+		solana.Meta(payer).WRITE().SIGNER(),
+		solana.Meta(solana.SystemProgramID),
+	}
+	return solana.NewInstruction(AddressLookupTableProgramID, accounts, buf.Bytes())
+}
+
+// NewDeactivateLookupTableInstruction returns the instruction deactivating table.
+// A deactivated table can be closed once it is no longer in the slot hashes sysvar
+func NewDeactivateLookupTableInstruction(table, authority solana.PublicKey) solana.Instruction {
+	data := make([]byte, 4)
+	binary.LittleEndian.PutUint32(data, lookupTableDeactivate)
+	accounts := solana.AccountMetaSlice{
+		solana.Meta(table).WRITE(),
+		solana.Meta(authority).SIGNER(),
+	}
+	return solana.NewInstruction(AddressLookupTableProgramID, accounts, data)
+}
+
+// NewCloseLookupTableInstruction returns the instruction closing a deactivated
+// table and sending its rent to recipient
+func NewCloseLookupTableInstruction(table, authority, recipient solana.PublicKey) solana.Instruction {
+	data := make([]byte, 4)
+	binary.LittleEndian.PutUint32(data, lookupTableClose)
+	accounts := solana.AccountMetaSlice{
+		solana.Meta(table).WRITE(),
+		solana.Meta(authority).SIGNER(),
+		solana.Meta(recipient).WRITE(),
+	}
+	return solana.NewInstruction(AddressLookupTableProgramID, accounts, data)
+}
+
+// AccountKeys returns every distinct account and program insts reference, in
+// order of first use, each signer or writable when any instruction uses it so
+func AccountKeys(insts []solana.Instruction) solana.AccountMetaSlice {
+	index := make(map[solana.PublicKey]int)
+	var keys solana.AccountMetaSlice
+	add := func(meta *solana.AccountMeta) {
+		i, ok := index[meta.PublicKey]
+		if !ok {
+			index[meta.PublicKey] = len(keys)
+			keys = append(keys, &solana.AccountMeta{PublicKey: meta.PublicKey, IsSigner: meta.IsSigner, IsWritable: meta.IsWritable})
+			return
+		}
+		keys[i].IsSigner = keys[i].IsSigner || meta.IsSigner
+		keys[i].IsWritable = keys[i].IsWritable || meta.IsWritable
+	}
+	for _, inst := range insts {
+		add(solana.Meta(inst.ProgramID()))
+		for _, meta := range inst.Accounts() {
+			add(meta)
+		}
+	}
+	return keys
+}
+
+// LookupTableAccounts returns the accounts of insts worth storing in a lookup
+// table: every distinct account and program except signers, which must stay in
+// the static account list
+func LookupTableAccounts(insts []solana.Instruction) []solana.PublicKey {
+	seen := make(map[solana.PublicKey]bool)
+	signers := make(map[solana.PublicKey]bool)
+	for _, inst := range insts {
+		for _, meta := range inst.Accounts() {
+			if meta.IsSigner {
+				signers[meta.PublicKey] = true
+			}
+		}
+	}
+
+	var accounts []solana.PublicKey
+	add := func(pubkey solana.PublicKey) {
+		if !seen[pubkey] && !signers[pubkey] {
+			seen[pubkey] = true
+			accounts = append(accounts, pubkey)
+		}
+	}
+	for _, inst := range insts {
+		add(inst.ProgramID())
+		for _, meta := range inst.Accounts() {
+			add(meta.PublicKey)
+		}
+	}
+	return accounts
+}
+
+// CreateLookupTable creates a lookup table owned and paid for by signer and
+// fills it with addresses, returning the table address
+func (c *Client) CreateLookupTable(ctx context.Context, signer Signer, addresses []solana.PublicKey) (solana.PublicKey, error) {
+	authority := signer.Pubkey()
+	slot, err := c.RpcClient.GetSlot(ctx, rpc.CommitmentFinalized)
+	if err != nil {
+		return solana.PublicKey{}, fmt.Errorf("failed to get slot: %w", ClassifyError(err))
+	}
+	createInst, table, err := NewCreateLookupTableInstruction(authority, authority, slot)
+	if err != nil {
+		return solana.PublicKey{}, err
+	}
+
+	seen := make(map[solana.PublicKey]bool, len(addresses))
+	var unique []solana.PublicKey
+	for _, address := range addresses {
+		if !seen[address] {
+			seen[address] = true
+			unique = append(unique, address)
+		}
+	}
+	addresses = unique
+
+	first := addresses[:min(len(addresses), maxExtendAddresses)]
+	insts := []solana.Instruction{createInst}
+	if len(first) > 0 {
+		insts = append(insts, NewExtendLookupTableInstruction(table, authority, authority, first))
+	}
+	if err := c.sendLookupTableTx(ctx, signer, insts); err != nil {
+		return solana.PublicKey{}, fmt.Errorf("failed to create lookup table: %w", err)
+	}
+	if err := c.extendLookupTable(ctx, signer, table, addresses[len(first):]); err != nil {
+		return table, err
+	}
+	return table, nil
+}
+
+// ExtendLookupTable appends the addresses table does not contain yet
+func (c *Client) ExtendLookupTable(ctx context.Context, signer Signer, table solana.PublicKey, addresses []solana.PublicKey) error {
+	state, err := addresslookuptable.GetAddressLookupTableStateWithOpts(ctx, c.RpcClient, table, &rpc.GetAccountInfoOpts{
+		Commitment: rpc.CommitmentProcessed,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get lookup table: %w", ClassifyError(err))
+	}
+	if !state.IsActive() {
+		return fmt.Errorf("lookup table %s is deactivated", table.String())
+	}
+
+	existing := make(map[solana.PublicKey]bool, len(state.Addresses))
+	for _, address := range state.Addresses {
+		existing[address] = true
+	}
+	var missing []solana.PublicKey
+	for _, address := range addresses {
+		if !existing[address] {
+			existing[address] = true
+			missing = append(missing, address)
+		}
+	}
+	return c.extendLookupTable(ctx, signer, table, missing)
+}
+
+func (c *Client) extendLookupTable(ctx context.Context, signer Signer, table solana.PublicKey, addresses []solana.PublicKey) error {
+	authority := signer.Pubkey()
+	for start := 0; start < len(addresses); start += maxExtendAddresses {
+		chunk := addresses[start:min(start+maxExtendAddresses, len(addresses))]
+		inst := NewExtendLookupTableInstruction(table, authority, authority, chunk)
+		if err := c.sendLookupTableTx(ctx, signer, []solana.Instruction{inst}); err != nil {
+			return fmt.Errorf("failed to extend lookup table: %w", err)
+		}
+	}
+	if _, ok := c.lookupTables[table]; ok {
+		return c.loadLookupTable(ctx, table)
+	}
+	return nil
+}
+
+// DeactivateLookupTable deactivates a table owned by signer and stops
+// referencing it in SendTx
+func (c *Client) DeactivateLookupTable(ctx context.Context, signer Signer, table solana.PublicKey) error {
+	inst := NewDeactivateLookupTableInstruction(table, signer.Pubkey())
+	if err := c.sendLookupTableTx(ctx, signer, []solana.Instruction{inst}); err != nil {
+		return fmt.Errorf("failed to deactivate lookup table: %w", err)
+	}
+	delete(c.lookupTables, table)
+	return nil
+}
+
+// UseLookupTables loads tables and makes SendTx compile versioned transactions
+// that reference them. Addresses added to a table only resolve one slot after
+// the extension lands
+func (c *Client) UseLookupTables(ctx context.Context, tables ...solana.PublicKey) error {
+	if c.lookupTables == nil {
+		c.lookupTables = make(map[solana.PublicKey]solana.PublicKeySlice)
+	}
+	for _, table := range tables {
+		if err := c.loadLookupTable(ctx, table); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Client) loadLookupTable(ctx context.Context, table solana.PublicKey) error {
+	state, err := addresslookuptable.GetAddressLookupTableStateWithOpts(ctx, c.RpcClient, table, &rpc.GetAccountInfoOpts{
+		Commitment: rpc.CommitmentProcessed,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get lookup table %s: %w", table.String(), ClassifyError(err))
+	}
+	if !state.IsActive() {
+		return fmt.Errorf("lookup table %s is deactivated", table.String())
+	}
+	c.lookupTables[table] = state.Addresses
+	return nil
+}
+
+// sendLookupTableTx sends insts without touching the lookup tables in use
+func (c *Client) sendLookupTableTx(ctx context.Context, signer Signer, insts []solana.Instruction) error {
+	if len(insts) == 0 {
+		return errors.New("no instructions")
+	}
+	recent, err := c.RpcClient.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+	if err != nil {
+		return fmt.Errorf("failed to get blockhash: %w", ClassifyError(err))
+	}
+	tx, err := signTransaction(recent.Value.Blockhash, []Signer{signer}, insts)
+	if err != nil {
+		return err
+	}
+	if _, err := c.RpcClient.SendTransactionWithOpts(ctx, tx, rpc.TransactionOpts{
+		PreflightCommitment: rpc.CommitmentProcessed,
+	}); err != nil {
+		return fmt.Errorf("failed to send transaction: %w", ClassifyError(err))
+	}
+	// Later extensions need the table to exist, so wait for each step to land
+	_, err = c.ConfirmTx(ctx, tx, ConfirmOptions{
+		LastValidBlockHeight: recent.Value.LastValidBlockHeight,
+		PollInterval:         500 * time.Millisecond,
+	})
+	return err
+}
@@ -0,0 +1,96 @@
+package sol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+	"github.com/yimingWOW/solroute/pkg/metrics"
+)
+
+// SetMetrics records RpcClient calls, transaction outcomes and the priority fees
+// paid by SendTx on m. Calls are counted as made by the caller, so with
+// SetRetryPolicy set before it a retried call counts once.
+// It must be called before RpcClient is shared with other goroutines
+func (c *Client) SetMetrics(m *metrics.Metrics) {
+	c.metrics = m
+	if m != nil {
+		c.RpcClient = rpc.NewWithCustomRPCClient(&metricsRPCClient{next: c.RpcClient, metrics: m})
+	}
+}
+
+// recordOutcome counts the outcome ConfirmTx reached for a transaction
+func (c *Client) recordOutcome(update TxUpdate, err error) {
+	switch {
+	case err == nil:
+		c.metrics.Transaction(metrics.OutcomeLanded)
+	case update.Status == TxFailed:
+		c.metrics.Transaction(metrics.OutcomeFailed)
+	case errors.Is(err, ErrTransactionExpired):
+		c.metrics.Transaction(metrics.OutcomeDropped)
+	}
+}
+
+// callResult returns the metrics result of an RPC error
+func callResult(err error) string {
+	switch {
+	case err == nil:
+		return metrics.ResultOK
+	case errors.Is(ClassifyError(err), ErrRateLimited):
+		return metrics.ResultRateLimited
+	}
+	return metrics.ResultError
+}
+
+// metricsRPCClient implements rpc.JSONRPCClient, recording every call
+type metricsRPCClient struct {
+	next    *rpc.Client
+	metrics *metrics.Metrics
+}
+
+func (m *metricsRPCClient) CallForInto(ctx context.Context, out interface{}, method string, params []interface{}) error {
+	start := time.Now()
+	err := m.next.RPCCallForInto(ctx, out, method, params)
+	m.metrics.RPCCall(method, callResult(err), time.Since(start))
+	return err
+}
+
+func (m *metricsRPCClient) CallWithCallback(ctx context.Context, method string, params []interface{}, callback func(*http.Request, *http.Response) error) error {
+	start := time.Now()
+	err := m.next.RPCCallWithCallback(ctx, method, params, callback)
+	m.metrics.RPCCall(method, callResult(err), time.Since(start))
+	return err
+}
+
+// CallBatch records each request of the batch with the batch's latency. A
+// request answered with an error counts as failed even if the batch succeeded
+func (m *metricsRPCClient) CallBatch(ctx context.Context, requests jsonrpc.RPCRequests) (jsonrpc.RPCResponses, error) {
+	start := time.Now()
+	responses, err := m.next.RPCCallBatch(ctx, requests)
+	elapsed := time.Since(start)
+
+	// IDs are compared printed, responses decode numeric IDs as float64
+	failed := make(map[string]error, len(responses))
+	for _, response := range responses {
+		if response != nil && response.Error != nil {
+			failed[fmt.Sprint(response.ID)] = response.Error
+		}
+	}
+	for _, request := range requests {
+		callErr := err
+		if callErr == nil {
+			callErr = failed[fmt.Sprint(request.ID)]
+		}
+		m.metrics.RPCCall(request.Method, callResult(callErr), elapsed)
+	}
+	return responses, err
+}
+
+// Close closes the wrapped client; it is called by rpc.Client.Close
+func (m *metricsRPCClient) Close() error {
+	return m.next.Close()
+}
@@ -0,0 +1,175 @@
+package sol
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	bin "github.com/gagliardetto/binary"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/programs/system"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// NonceAccountSize is the data length of a system nonce account
+const NonceAccountSize = 80
+
+// nonceStateInitialized is the nonce account state once InitializeNonceAccount ran
+const nonceStateInitialized = 1
+
+// NonceState is the content of an initialized nonce account
+type NonceState struct {
+	Authority            solana.PublicKey
+	Nonce                solana.Hash // used in place of a recent blockhash
+	LamportsPerSignature uint64
+}
+
+// NewAdvanceNonceInstruction returns the instruction consuming the current nonce
+// of nonceAccount. It must be the first instruction of the transaction
+func NewAdvanceNonceInstruction(nonceAccount, authority solana.PublicKey) (solana.Instruction, error) {
+	return system.NewAdvanceNonceAccountInstruction(
+		nonceAccount,
+		solana.SysVarRecentBlockHashesPubkey,
+		authority,
+	).ValidateAndBuild()
+}
+
+// SignNonceTransaction signs insts against a durable nonce without any RPC call,
+// for signing offline. The advance nonce instruction is inserted first. The
+// transaction stays valid until the nonce of nonceAccount advances
+func SignNonceTransaction(nonceAccount solana.PublicKey, nonce NonceState, signers []Signer, insts []solana.Instruction) (*solana.Transaction, error) {
+	advance, err := NewAdvanceNonceInstruction(nonceAccount, nonce.Authority)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build advance nonce instruction: %w", err)
+	}
+	return signTransaction(nonce.Nonce, signers, append([]solana.Instruction{advance}, insts...))
+}
+
+// GetNonce reads the state of nonceAccount
+func (c *Client) GetNonce(ctx context.Context, nonceAccount solana.PublicKey) (*NonceState, error) {
+	account, err := c.RpcClient.GetAccountInfoWithOpts(ctx, nonceAccount, &rpc.GetAccountInfoOpts{
+		Commitment: rpc.CommitmentConfirmed,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nonce account: %w", ClassifyError(err))
+	}
+	if !account.Value.Owner.Equals(solana.SystemProgramID) {
+		return nil, fmt.Errorf("account %s is not a nonce account", nonceAccount.String())
+	}
+	var state system.NonceAccount
+	if err := state.UnmarshalWithDecoder(bin.NewBinDecoder(account.Value.Data.GetBinary())); err != nil {
+		return nil, fmt.Errorf("failed to decode nonce account: %w", err)
+	}
+	if state.State != nonceStateInitialized {
+		return nil, fmt.Errorf("nonce account %s is not initialized", nonceAccount.String())
+	}
+	return &NonceState{
+		Authority:            state.AuthorizedPubkey,
+		Nonce:                solana.Hash(state.Nonce),
+		LamportsPerSignature: state.FeeCalculator.LamportsPerSignature,
+	}, nil
+}
+
+// CreateNonceAccount creates a nonce account paid for by payer and controlled by
+// authority, returning its address
+func (c *Client) CreateNonceAccount(ctx context.Context, payer Signer, authority solana.PublicKey) (solana.PublicKey, error) {
+	nonceKey, err := solana.NewRandomPrivateKey()
+	if err != nil {
+		return solana.PublicKey{}, fmt.Errorf("failed to generate nonce account key: %w", err)
+	}
+	nonceAccount := nonceKey.PublicKey()
+	rent, err := c.RpcClient.GetMinimumBalanceForRentExemption(ctx, NonceAccountSize, rpc.CommitmentConfirmed)
+	if err != nil {
+		return solana.PublicKey{}, fmt.Errorf("failed to get rent exemption: %w", ClassifyError(err))
+	}
+
+	createInst, err := system.NewCreateAccountInstruction(
+		rent,
+		NonceAccountSize,
+		solana.SystemProgramID,
+		payer.Pubkey(),
+		nonceAccount,
+	).ValidateAndBuild()
+	if err != nil {
+		return solana.PublicKey{}, fmt.Errorf("failed to build create account instruction: %w", err)
+	}
+	initInst, err := system.NewInitializeNonceAccountInstruction(
+		authority,
+		nonceAccount,
+		solana.SysVarRecentBlockHashesPubkey,
+		solana.SysVarRentPubkey,
+	).ValidateAndBuild()
+	if err != nil {
+		return solana.PublicKey{}, fmt.Errorf("failed to build initialize nonce instruction: %w", err)
+	}
+
+	signers := []Signer{payer, PrivateKeySigner(nonceKey)}
+	if _, err := c.SendAndConfirmTx(ctx, signers, []solana.Instruction{createInst, initInst}, ConfirmOptions{}); err != nil {
+		return solana.PublicKey{}, fmt.Errorf("failed to create nonce account: %w", err)
+	}
+	return nonceAccount, nil
+}
+
+// BuildNonceTx signs insts against the current nonce of nonceAccount, applying
+// the configured priority fee and lookup tables like SendTx. The transaction can
+// be serialized and submitted later with SubmitTx, it stays valid until the
+// nonce advances. signers must include the nonce authority
+func (c *Client) BuildNonceTx(ctx context.Context, nonceAccount solana.PublicKey, signers []Signer, insts []solana.Instruction) (*solana.Transaction, error) {
+	nonce, err := c.GetNonce(ctx, nonceAccount)
+	if err != nil {
+		return nil, err
+	}
+	advance, err := NewAdvanceNonceInstruction(nonceAccount, nonce.Authority)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build advance nonce instruction: %w", err)
+	}
+	return c.buildTx(ctx, nonce.Nonce, signers, append([]solana.Instruction{advance}, insts...))
+}
+
+// SubmitTx sends an already signed transaction, such as one from BuildNonceTx.
+// Nonce transactions don't expire, so confirm them with a zero
+// ConfirmOptions.LastValidBlockHeight
+func (c *Client) SubmitTx(ctx context.Context, tx *solana.Transaction) (solana.Signature, error) {
+	if len(tx.Signatures) == 0 {
+		return solana.Signature{}, errors.New("transaction is not signed")
+	}
+	return c.sendTx(ctx, tx)
+}
+
+// AdvanceNonce consumes the current nonce of nonceAccount, invalidating every
+// transaction signed against it that hasn't landed yet
+func (c *Client) AdvanceNonce(ctx context.Context, authority Signer, nonceAccount solana.PublicKey) error {
+	inst, err := NewAdvanceNonceInstruction(nonceAccount, authority.Pubkey())
+	if err != nil {
+		return fmt.Errorf("failed to build advance nonce instruction: %w", err)
+	}
+	if _, err := c.SendAndConfirmTx(ctx, []Signer{authority}, []solana.Instruction{inst}, ConfirmOptions{}); err != nil {
+		return fmt.Errorf("failed to advance nonce: %w", err)
+	}
+	return nil
+}
+
+// isAdvanceNonce reports whether inst is a system AdvanceNonceAccount instruction
+func isAdvanceNonce(inst solana.Instruction) bool {
+	if !inst.ProgramID().Equals(solana.SystemProgramID) {
+		return false
+	}
+	data, err := inst.Data()
+	return err == nil && isAdvanceNonceData(data)
+}
+
+// usesNonce reports whether tx starts with an advance nonce instruction, making
+// its blockhash a durable nonce
+func usesNonce(tx *solana.Transaction) bool {
+	if len(tx.Message.Instructions) == 0 {
+		return false
+	}
+	inst := tx.Message.Instructions[0]
+	programID, err := tx.Message.Program(inst.ProgramIDIndex)
+	return err == nil && programID.Equals(solana.SystemProgramID) && isAdvanceNonceData(inst.Data)
+}
+
+func isAdvanceNonceData(data []byte) bool {
+	return len(data) >= 4 && binary.LittleEndian.Uint32(data) == system.Instruction_AdvanceNonceAccount
+}
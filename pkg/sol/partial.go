@@ -0,0 +1,125 @@
+package sol
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// BuildUnsignedTx builds insts into a transaction paid by feePayer, applying the
+// priority fee and lookup tables like SendTx, without signing it. A zero
+// blockhash is replaced by the one LatestBlockhash caches. The transaction is
+// then signed by each party with PartialSign, passed between them with EncodeTx
+// and DecodeTx, and sent with SendSignedTx once MissingSigners is empty
+func (c *Client) BuildUnsignedTx(ctx context.Context, feePayer solana.PublicKey, blockhash solana.Hash, insts []solana.Instruction) (*solana.Transaction, error) {
+	if blockhash.IsZero() {
+		recent, err := c.LatestBlockhash(ctx)
+		if err != nil {
+			return nil, err
+		}
+		blockhash = recent.Blockhash
+	}
+	return c.prepareTx(ctx, blockhash, feePayer, insts)
+}
+
+// PartialSign adds the signatures of signers to tx, keeping the ones it already
+// carries. Signers that tx doesn't require are ignored
+func PartialSign(tx *solana.Transaction, signers ...Signer) error {
+	message, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+	required := int(tx.Message.Header.NumRequiredSignatures)
+	if len(tx.Signatures) != required {
+		signatures := make([]solana.Signature, required)
+		copy(signatures, tx.Signatures)
+		tx.Signatures = signatures
+	}
+	for i, key := range tx.Message.AccountKeys[:required] {
+		for _, signer := range signers {
+			if !signer.Pubkey().Equals(key) {
+				continue
+			}
+			sig, err := signer.Sign(message)
+			if err != nil {
+				return fmt.Errorf("failed to sign transaction with %s: %w", key.String(), err)
+			}
+			// Remote signers could sign something else, don't send a transaction that can't verify
+			if !sig.Verify(key, message) {
+				return fmt.Errorf("invalid signature from signer %s", key.String())
+			}
+			tx.Signatures[i] = sig
+			break
+		}
+	}
+	return nil
+}
+
+// MissingSigners returns the required signers that haven't signed tx yet
+func MissingSigners(tx *solana.Transaction) []solana.PublicKey {
+	var missing []solana.PublicKey
+	for i, key := range tx.Message.AccountKeys[:tx.Message.Header.NumRequiredSignatures] {
+		if i >= len(tx.Signatures) || tx.Signatures[i].IsZero() {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
+// EncodeTx serializes a possibly partially signed tx to base64 wire format,
+// missing signatures left zero
+func EncodeTx(tx *solana.Transaction) (string, error) {
+	data, err := tx.MarshalBinary()
+	if err != nil {
+		return "", fmt.Errorf("failed to encode transaction: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+// DecodeTx parses a transaction serialized by EncodeTx and verifies the
+// signatures it already carries against the message
+func DecodeTx(encoded string) (*solana.Transaction, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64: %w", err)
+	}
+	tx, err := solana.TransactionFromBytes(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode transaction: %w", err)
+	}
+	message, err := tx.Message.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode message: %w", err)
+	}
+	for i, sig := range tx.Signatures {
+		if i >= len(tx.Message.AccountKeys) {
+			return nil, errors.New("more signatures than account keys")
+		}
+		if !sig.IsZero() && !sig.Verify(tx.Message.AccountKeys[i], message) {
+			return nil, fmt.Errorf("invalid signature for %s", tx.Message.AccountKeys[i].String())
+		}
+	}
+	return tx, nil
+}
+
+// SendSignedTx sends a fully signed tx and waits until ConfirmTx, configured by
+// opts, reports its outcome
+func (c *Client) SendSignedTx(ctx context.Context, tx *solana.Transaction, opts ConfirmOptions) (solana.Signature, error) {
+	if missing := MissingSigners(tx); len(missing) > 0 {
+		return solana.Signature{}, fmt.Errorf("transaction is missing the signature of %s", missing[0].String())
+	}
+	sig, err := c.sendTx(ctx, tx)
+	if err != nil {
+		return solana.Signature{}, err
+	}
+	if _, err := c.ConfirmTx(ctx, tx, opts); err != nil {
+		if errors.Is(err, ErrTransactionExpired) {
+			c.invalidateBlockhash(tx.Message.RecentBlockhash)
+		}
+		return sig, err
+	}
+	return sig, nil
+}
@@ -0,0 +1,179 @@
+package sol
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+// TokenMetadata is what GetPortfolio shows of a mint
+type TokenMetadata struct {
+	Decimals uint8
+	Symbol   string
+	Name     string
+}
+
+// PortfolioValuer resolves the metadata and USD values of the holdings
+// GetPortfolio returns. *price.Oracle implements it; see SetPortfolioValuer
+type PortfolioValuer interface {
+	TokenMetadata(ctx context.Context, mint solana.PublicKey) (TokenMetadata, error)
+	Value(ctx context.Context, mint solana.PublicKey, amount math.Int) (float64, error)
+}
+
+// SetPortfolioValuer sets where GetPortfolio resolves metadata and USD values
+// from. Without one, holdings only carry their mint decimals. nil restores that
+func (c *Client) SetPortfolioValuer(valuer PortfolioValuer) {
+	c.portfolioValuer = valuer
+}
+
+// Holding is the balance of one mint across a wallet's token accounts
+type Holding struct {
+	Mint    solana.PublicKey
+	Program solana.PublicKey // solana.TokenProgramID or solana.Token2022ProgramID
+	// Accounts are the token accounts holding the mint
+	Accounts []solana.PublicKey
+	Amount   uint64
+	TokenMetadata
+	// USD is zero when the valuer has no price for the mint
+	USD float64
+}
+
+// Portfolio is the SOL and token balances of a wallet
+type Portfolio struct {
+	Owner    solana.PublicKey
+	Lamports uint64
+	// LamportsUSD values Lamports as WSOL
+	LamportsUSD float64
+	// Holdings are the mints with a non-zero balance, most valuable first,
+	// then by mint
+	Holdings []Holding
+	// TotalUSD is the value of Lamports and every priced holding
+	TotalUSD float64
+}
+
+// Holding returns the balance of mint, or false when the wallet holds none
+func (p *Portfolio) Holding(mint solana.PublicKey) (Holding, bool) {
+	for _, holding := range p.Holdings {
+		if holding.Mint.Equals(mint) {
+			return holding, true
+		}
+	}
+	return Holding{}, false
+}
+
+// GetPortfolio returns the SOL and token balances of owner under both token
+// programs, with the metadata and USD values of the portfolio valuer
+func (c *Client) GetPortfolio(ctx context.Context, owner solana.PublicKey) (*Portfolio, error) {
+	balance, err := c.RpcClient.GetBalance(ctx, owner, rpc.CommitmentConfirmed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balance: %w", ClassifyError(err))
+	}
+	portfolio := &Portfolio{Owner: owner, Lamports: balance.Value}
+
+	holdings := make(map[solana.PublicKey]*Holding)
+	for _, program := range []solana.PublicKey{solana.TokenProgramID, solana.Token2022ProgramID} {
+		accounts, err := c.RpcClient.GetTokenAccountsByOwner(ctx, owner,
+			&rpc.GetTokenAccountsConfig{ProgramId: program.ToPointer()},
+			&rpc.GetTokenAccountsOpts{
+				Commitment: rpc.CommitmentConfirmed,
+				Encoding:   solana.EncodingBase64,
+			},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get token accounts: %w", ClassifyError(err))
+		}
+		for _, account := range accounts.Value {
+			if account.Account.Data == nil {
+				continue
+			}
+			data := account.Account.Data.GetBinary()
+			amount, err := ParseTokenAmount(data)
+			if err != nil || amount == 0 {
+				continue
+			}
+			mint := solana.PublicKeyFromBytes(data[0:32])
+			holding, ok := holdings[mint]
+			if !ok {
+				holding = &Holding{Mint: mint, Program: program}
+				holdings[mint] = holding
+			}
+			holding.Accounts = append(holding.Accounts, account.Pubkey)
+			holding.Amount += amount
+		}
+	}
+
+	if err := c.resolveHoldings(ctx, holdings); err != nil {
+		return nil, err
+	}
+	for _, holding := range holdings {
+		portfolio.Holdings = append(portfolio.Holdings, *holding)
+		portfolio.TotalUSD += holding.USD
+	}
+	if c.portfolioValuer != nil && portfolio.Lamports > 0 {
+		if usd, err := c.portfolioValuer.Value(ctx, WSOL, math.NewIntFromUint64(portfolio.Lamports)); err == nil {
+			portfolio.LamportsUSD = usd
+			portfolio.TotalUSD += usd
+		}
+	}
+	sort.Slice(portfolio.Holdings, func(i, j int) bool {
+		a, b := portfolio.Holdings[i], portfolio.Holdings[j]
+		if a.USD != b.USD {
+			return a.USD > b.USD
+		}
+		return a.Mint.String() < b.Mint.String()
+	})
+	return portfolio, nil
+}
+
+// resolveHoldings fills in the metadata and values of holdings with the
+// valuer, or reads their decimals from the mints without one
+func (c *Client) resolveHoldings(ctx context.Context, holdings map[solana.PublicKey]*Holding) error {
+	if c.portfolioValuer == nil {
+		return c.readDecimals(ctx, holdings)
+	}
+	for mint, holding := range holdings {
+		metadata, err := c.portfolioValuer.TokenMetadata(ctx, mint)
+		if err != nil {
+			return fmt.Errorf("failed to resolve mint %s: %w", mint.String(), err)
+		}
+		holding.TokenMetadata = metadata
+		// Mints without a price are listed unvalued
+		if usd, err := c.portfolioValuer.Value(ctx, mint, math.NewIntFromUint64(holding.Amount)); err == nil {
+			holding.USD = usd
+		} else {
+			c.Logger().Debug("holding not valued", "mint", mint.String(), "err", err)
+		}
+	}
+	return nil
+}
+
+// Offset of the decimals byte in a mint account
+const mintDecimalsOffset = 44
+
+func (c *Client) readDecimals(ctx context.Context, holdings map[solana.PublicKey]*Holding) error {
+	mints := make([]solana.PublicKey, 0, len(holdings))
+	for mint := range holdings {
+		mints = append(mints, mint)
+	}
+	// getMultipleAccounts takes at most 100 accounts
+	for start := 0; start < len(mints); start += 100 {
+		chunk := mints[start:min(start+100, len(mints))]
+		results, err := c.RpcClient.GetMultipleAccounts(ctx, chunk...)
+		if err != nil {
+			return fmt.Errorf("failed to get mints: %w", ClassifyError(err))
+		}
+		for i, account := range results.Value {
+			if account == nil || i >= len(chunk) {
+				continue
+			}
+			if data := account.Data.GetBinary(); len(data) > mintDecimalsOffset {
+				holdings[chunk[i]].Decimals = data[mintDecimalsOffset]
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package sol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/gagliardetto/solana-go"
+	computebudget "github.com/gagliardetto/solana-go/programs/compute-budget"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+const (
+	// DefaultPriorityFeePercentile is used when PriorityFeeOptions.Percentile is zero
+	DefaultPriorityFeePercentile = 75
+	// DefaultComputeUnitMarginBps is used when PriorityFeeOptions.ComputeUnitMarginBps is zero
+	DefaultComputeUnitMarginBps = 1000
+	// MaxComputeUnitLimit is the largest compute unit limit a transaction may request
+	MaxComputeUnitLimit = 1_400_000
+)
+
+// PriorityFeeOptions configures the compute budget instructions SendTx prepends
+type PriorityFeeOptions struct {
+	// Percentile of recent prioritization fees paid for the transaction's writable
+	// accounts, 1-100. Defaults to DefaultPriorityFeePercentile
+	Percentile int
+	// MinMicroLamports and MaxMicroLamports clamp the estimated price per compute
+	// unit. A zero MaxMicroLamports means no upper bound
+	MinMicroLamports uint64
+	MaxMicroLamports uint64
+	// ComputeUnitLimit is set on the transaction when non-zero
+	ComputeUnitLimit uint32
+	// SimulateComputeUnits sets the limit from the units a simulation of the
+	// transaction consumes, plus ComputeUnitMarginBps. Ignored when
+	// ComputeUnitLimit is set
+	SimulateComputeUnits bool
+	// ComputeUnitMarginBps is the safety margin added to simulated units.
+	// Defaults to DefaultComputeUnitMarginBps
+	ComputeUnitMarginBps uint32
+}
+
+// PriorityFeeEstimator estimates the price in micro-lamports per compute unit
+// that lands a transaction writing accounts, at percentile (1-100) of recent fees.
+// Client implements it with getRecentPrioritizationFees
+type PriorityFeeEstimator interface {
+	EstimatePriorityFee(ctx context.Context, accounts []solana.PublicKey, percentile int) (uint64, error)
+}
+
+// SetPriorityFeeEstimator makes WithPriorityFee, and so SendTx, price compute
+// units with estimator, e.g. a provider-specific fee API. nil restores the default
+func (c *Client) SetPriorityFeeEstimator(estimator PriorityFeeEstimator) {
+	c.feeEstimator = estimator
+}
+
+// SetPriorityFee makes SendTx prepend SetComputeUnitLimit/SetComputeUnitPrice
+// instructions to transactions that don't already carry compute budget instructions
+func (c *Client) SetPriorityFee(opts PriorityFeeOptions) {
+	c.priorityFee = &opts
+}
+
+// EstimatePriorityFee returns the given percentile of the prioritization fees, in
+// micro-lamports per compute unit, paid by recently landed transactions that
+// write-locked any of accounts
+func (c *Client) EstimatePriorityFee(ctx context.Context, accounts []solana.PublicKey, percentile int) (uint64, error) {
+	if percentile <= 0 || percentile > 100 {
+		return 0, fmt.Errorf("invalid percentile: %d", percentile)
+	}
+	results, err := c.RpcClient.GetRecentPrioritizationFees(ctx, accounts)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get recent prioritization fees: %w", ClassifyError(err))
+	}
+	if len(results) == 0 {
+		return 0, nil
+	}
+
+	fees := make([]uint64, len(results))
+	for i, result := range results {
+		fees[i] = result.PrioritizationFee
+	}
+	sort.Slice(fees, func(i, j int) bool { return fees[i] < fees[j] })
+	return fees[(len(fees)-1)*percentile/100], nil
+}
+
+// ComputeBudgetInstructions returns the instructions setting the compute unit
+// limit (skipped when zero) and the compute unit price
+func ComputeBudgetInstructions(unitLimit uint32, microLamports uint64) ([]solana.Instruction, error) {
+	var insts []solana.Instruction
+	if unitLimit > 0 {
+		limitInst, err := computebudget.NewSetComputeUnitLimitInstruction(unitLimit).ValidateAndBuild()
+		if err != nil {
+			return nil, fmt.Errorf("failed to build compute unit limit instruction: %w", err)
+		}
+		insts = append(insts, limitInst)
+	}
+	priceInst, err := computebudget.NewSetComputeUnitPriceInstruction(microLamports).ValidateAndBuild()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build compute unit price instruction: %w", err)
+	}
+	return append(insts, priceInst), nil
+}
+
+// WithPriorityFee prepends compute budget instructions to insts, pricing compute
+// units from the recent fees paid for the accounts insts write to, as estimated
+// by the PriorityFeeEstimator set on the client. payer is
+// only used to simulate the transaction when opts.SimulateComputeUnits is set
+func (c *Client) WithPriorityFee(ctx context.Context, payer solana.PublicKey, insts []solana.Instruction, opts PriorityFeeOptions) ([]solana.Instruction, error) {
+	percentile := opts.Percentile
+	if percentile == 0 {
+		percentile = DefaultPriorityFeePercentile
+	}
+	var estimator PriorityFeeEstimator = c
+	if c.feeEstimator != nil {
+		estimator = c.feeEstimator
+	}
+	fee, err := estimator.EstimatePriorityFee(ctx, writableAccounts(insts), percentile)
+	if err != nil {
+		return nil, err
+	}
+	fee = max(fee, opts.MinMicroLamports)
+	if opts.MaxMicroLamports > 0 {
+		fee = min(fee, opts.MaxMicroLamports)
+	}
+
+	unitLimit := opts.ComputeUnitLimit
+	if unitLimit == 0 && opts.SimulateComputeUnits {
+		marginBps := opts.ComputeUnitMarginBps
+		if marginBps == 0 {
+			marginBps = DefaultComputeUnitMarginBps
+		}
+		// Simulate with the price instruction in place, its cost counts towards the limit
+		budget, err := ComputeBudgetInstructions(MaxComputeUnitLimit, fee)
+		if err != nil {
+			return nil, err
+		}
+		consumed, err := c.SimulateComputeUnits(ctx, payer, append(budget, insts...))
+		if err != nil {
+			return nil, err
+		}
+		unitLimit = uint32(min(consumed*(10000+uint64(marginBps))/10000, MaxComputeUnitLimit))
+	}
+
+	budget, err := ComputeBudgetInstructions(unitLimit, fee)
+	if err != nil {
+		return nil, err
+	}
+	return append(budget, insts...), nil
+}
+
+// SimulateComputeUnits simulates insts paid by payer and returns the compute units
+// they consume. Signatures are not verified and the blockhash is replaced by the node
+func (c *Client) SimulateComputeUnits(ctx context.Context, payer solana.PublicKey, insts []solana.Instruction) (uint64, error) {
+	tx, err := solana.NewTransaction(insts, solana.Hash{}, solana.TransactionPayer(payer))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create transaction: %w", err)
+	}
+	tx.Signatures = make([]solana.Signature, tx.Message.Header.NumRequiredSignatures)
+
+	result, err := c.RpcClient.SimulateTransactionWithOpts(ctx, tx, &rpc.SimulateTransactionOpts{
+		Commitment:             rpc.CommitmentProcessed,
+		ReplaceRecentBlockhash: true,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to simulate transaction: %w", ClassifyError(err))
+	}
+	if result.Value == nil {
+		return 0, errors.New("empty simulation result")
+	}
+	if result.Value.Err != nil {
+		return 0, fmt.Errorf("simulation failed: %w", transactionError(tx, result.Value.Err))
+	}
+	if result.Value.UnitsConsumed == nil {
+		return 0, errors.New("simulation did not report consumed compute units")
+	}
+	return *result.Value.UnitsConsumed, nil
+}
+
+// writableAccounts returns the distinct accounts write-locked by insts; the
+// node reports fees for at most 128 of them
+func writableAccounts(insts []solana.Instruction) []solana.PublicKey {
+	seen := make(map[solana.PublicKey]bool)
+	var accounts []solana.PublicKey
+	for _, inst := range insts {
+		for _, meta := range inst.Accounts() {
+			if meta.IsWritable && !seen[meta.PublicKey] && len(accounts) < 128 {
+				seen[meta.PublicKey] = true
+				accounts = append(accounts, meta.PublicKey)
+			}
+		}
+	}
+	return accounts
+}
+
+// hasComputeBudget reports whether insts already set their own compute budget
+func hasComputeBudget(insts []solana.Instruction) bool {
+	for _, inst := range insts {
+		if inst.ProgramID().Equals(solana.ComputeBudget) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package sol
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+	"golang.org/x/time/rate"
+)
+
+// RateLimitOptions configures the client-side request budget
+type RateLimitOptions struct {
+	// RequestsPerSecond is the sustained request rate; zero disables rate limiting.
+	// A batch call consumes one token per request it carries
+	RequestsPerSecond float64
+	// Burst is the token bucket size. Defaults to 1
+	Burst int
+	// MethodConcurrency caps the in-flight calls per RPC method, e.g. "getProgramAccounts"
+	MethodConcurrency map[string]int
+	// DefaultConcurrency caps in-flight calls of methods not listed in
+	// MethodConcurrency; zero means unlimited
+	DefaultConcurrency int
+}
+
+// SetRateLimit routes all RpcClient calls through a token bucket and per-method
+// concurrency caps. Calls block until they fit in the budget or ctx is done.
+// It must be called before RpcClient is shared with other goroutines
+func (c *Client) SetRateLimit(opts RateLimitOptions) {
+	c.RpcClient = rpc.NewWithCustomRPCClient(newLimitedRPCClient(c.RpcClient, opts))
+}
+
+// limitedRPCClient implements rpc.JSONRPCClient on top of an existing rpc.Client
+type limitedRPCClient struct {
+	next    *rpc.Client
+	limiter *rate.Limiter
+	methods map[string]chan struct{}
+}
+
+func newLimitedRPCClient(next *rpc.Client, opts RateLimitOptions) *limitedRPCClient {
+	l := &limitedRPCClient{
+		next:    next,
+		methods: make(map[string]chan struct{}, len(opts.MethodConcurrency)),
+	}
+	if opts.RequestsPerSecond > 0 {
+		burst := opts.Burst
+		if burst <= 0 {
+			burst = 1
+		}
+		l.limiter = rate.NewLimiter(rate.Limit(opts.RequestsPerSecond), burst)
+	}
+	for method, limit := range opts.MethodConcurrency {
+		if limit > 0 {
+			l.methods[method] = make(chan struct{}, limit)
+		}
+	}
+	if opts.DefaultConcurrency > 0 {
+		// Shared by all unlisted methods
+		l.methods[""] = make(chan struct{}, opts.DefaultConcurrency)
+	}
+	return l
+}
+
+// acquire waits for n tokens and a concurrency slot for method, returning the release func
+func (l *limitedRPCClient) acquire(ctx context.Context, method string, n int) (func(), error) {
+	if l.limiter != nil {
+		// WaitN rejects n above the burst size, so large batches wait in chunks
+		for n > 0 {
+			chunk := min(n, l.limiter.Burst())
+			if err := l.limiter.WaitN(ctx, chunk); err != nil {
+				return nil, err
+			}
+			n -= chunk
+		}
+	}
+	slots, ok := l.methods[method]
+	if !ok {
+		slots = l.methods[""]
+	}
+	if slots == nil {
+		return func() {}, nil
+	}
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (l *limitedRPCClient) CallForInto(ctx context.Context, out interface{}, method string, params []interface{}) error {
+	release, err := l.acquire(ctx, method, 1)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return l.next.RPCCallForInto(ctx, out, method, params)
+}
+
+func (l *limitedRPCClient) CallWithCallback(ctx context.Context, method string, params []interface{}, callback func(*http.Request, *http.Response) error) error {
+	release, err := l.acquire(ctx, method, 1)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return l.next.RPCCallWithCallback(ctx, method, params, callback)
+}
+
+func (l *limitedRPCClient) CallBatch(ctx context.Context, requests jsonrpc.RPCRequests) (jsonrpc.RPCResponses, error) {
+	release, err := l.acquire(ctx, "", len(requests))
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return l.next.RPCCallBatch(ctx, requests)
+}
+
+// Close closes the wrapped client; it is called by rpc.Client.Close
+func (l *limitedRPCClient) Close() error {
+	return l.next.Close()
+}
@@ -0,0 +1,136 @@
+package sol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+)
+
+const (
+	// DefaultMaxSendAttempts is used when ResendOptions.MaxAttempts is zero
+	DefaultMaxSendAttempts = 3
+	// DefaultRebroadcastInterval is used when ResendOptions.RebroadcastInterval is zero
+	DefaultRebroadcastInterval = 2 * time.Second
+)
+
+// ResendOptions configures SendTxWithResend
+type ResendOptions struct {
+	// MaxAttempts is the number of blockhashes the transaction is built against.
+	// Defaults to DefaultMaxSendAttempts
+	MaxAttempts int
+	// RebroadcastInterval between resubmissions of the same signed transaction
+	// while it is pending. Defaults to DefaultRebroadcastInterval
+	RebroadcastInterval time.Duration
+	// Confirm configures the wait for each attempt. LastValidBlockHeight is
+	// filled in from the attempt's blockhash
+	Confirm ConfirmOptions
+	// OnResend, when set, is called before each attempt after the first with
+	// the signature of the attempt that expired
+	OnResend func(attempt int, expired solana.Signature)
+}
+
+// SendTxWithResend sends insts like SendAndConfirmTx, rebroadcasting the signed
+// transaction while it is pending. When its blockhash expires before it lands,
+// the instructions are signed again against a fresh blockhash and resubmitted.
+//
+// A new attempt is only made once the finalized chain is past the previous
+// blockhash's last valid block height and the previous signature is still
+// unknown, at which point it can never execute, so the instructions run at most once
+func (c *Client) SendTxWithResend(ctx context.Context, signers []Signer, insts []solana.Instruction, opts ResendOptions) (solana.Signature, error) {
+	attempts := opts.MaxAttempts
+	if attempts <= 0 {
+		attempts = DefaultMaxSendAttempts
+	}
+	interval := opts.RebroadcastInterval
+	if interval <= 0 {
+		interval = DefaultRebroadcastInterval
+	}
+
+	var sig solana.Signature
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 && opts.OnResend != nil {
+			opts.OnResend(attempt, sig)
+		}
+		recent, err := c.LatestBlockhash(ctx)
+		if err != nil {
+			return sig, err
+		}
+		tx, err := c.buildTx(ctx, recent.Blockhash, signers, insts)
+		if err != nil {
+			return sig, err
+		}
+		sig = tx.Signatures[0]
+
+		confirmOpts := opts.Confirm
+		confirmOpts.LastValidBlockHeight = recent.LastValidBlockHeight
+		// A failed send may still have reached a leader, so the signature is
+		// tracked until it expires either way
+		_, err = c.confirmBroadcasting(ctx, tx, confirmOpts, interval)
+		if !errors.Is(err, ErrTransactionExpired) {
+			return sig, err
+		}
+
+		u, err := c.awaitExpiry(ctx, tx, confirmOpts)
+		if err != nil {
+			return sig, err
+		}
+		if u.Status != TxExpired {
+			// It landed on a fork the confirmed chain had not seen yet
+			_, err = c.ConfirmTx(ctx, tx, opts.Confirm)
+			return sig, err
+		}
+		c.invalidateBlockhash(recent.Blockhash)
+	}
+	return sig, fmt.Errorf("transaction not landed after %d attempts: %w", attempts, ErrTransactionExpired)
+}
+
+// confirmBroadcasting runs ConfirmTx while sending tx every interval
+func (c *Client) confirmBroadcasting(ctx context.Context, tx *solana.Transaction, opts ConfirmOptions, interval time.Duration) (TxUpdate, error) {
+	broadcastCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			c.sendTx(broadcastCtx, tx)
+			select {
+			case <-broadcastCtx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+	return c.ConfirmTx(ctx, tx, opts)
+}
+
+// awaitExpiry waits until the finalized block height is past the blockhash
+// validity of tx and returns its status then: TxExpired when no fork can still
+// include it, or the status it landed with
+func (c *Client) awaitExpiry(ctx context.Context, tx *solana.Transaction, opts ConfirmOptions) (TxUpdate, error) {
+	interval := opts.PollInterval
+	if interval <= 0 {
+		interval = DefaultConfirmPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		u, ok := c.pollSignature(ctx, tx, opts.LastValidBlockHeight, rpc.CommitmentFinalized)
+		if ok && (u.Status == TxExpired || u.Status == TxFailed || u.Status >= TxConfirmed) {
+			return u, nil
+		}
+		select {
+		case <-ctx.Done():
+			return TxUpdate{}, fmt.Errorf("transaction %s expiry not finalized: %w", tx.Signatures[0].String(), ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package sol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+)
+
+// ErrorClass groups errors by how a failed request should be retried
+type ErrorClass int
+
+const (
+	// ErrorClassPermanent errors are returned without retrying: invalid requests,
+	// JSON-RPC errors from a working node and cancelled contexts
+	ErrorClassPermanent ErrorClass = iota
+	// ErrorClassRateLimited errors match ErrRateLimited
+	ErrorClassRateLimited
+	// ErrorClassTransient errors are timeouts, transport failures and 5xx responses
+	ErrorClassTransient
+)
+
+func (c ErrorClass) String() string {
+	switch c {
+	case ErrorClassPermanent:
+		return "permanent"
+	case ErrorClassRateLimited:
+		return "rate limited"
+	case ErrorClassTransient:
+		return "transient"
+	}
+	return fmt.Sprintf("ErrorClass(%d)", int(c))
+}
+
+// ClassifyRetry returns the retry class of an RPC error
+func ClassifyRetry(err error) ErrorClass {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return ErrorClassPermanent
+	}
+	if errors.Is(ClassifyError(err), ErrRateLimited) {
+		return ErrorClassRateLimited
+	}
+	var httpErr *jsonrpc.HTTPError
+	if errors.As(err, &httpErr) {
+		if httpErr.Code >= http.StatusInternalServerError {
+			return ErrorClassTransient
+		}
+		return ErrorClassPermanent
+	}
+	var rpcErr *jsonrpc.RPCError
+	if errors.As(err, &rpcErr) {
+		// Errors returned in a JSON-RPC body come from a working node
+		return ErrorClassPermanent
+	}
+	// Timeouts and transport failures (connection refused, resets, EOF)
+	return ErrorClassTransient
+}
+
+// Backoff is the retry schedule of one error class: attempt n waits
+// InitialDelay*Multiplier^(n-1), capped at MaxDelay, less up to Jitter of it
+type Backoff struct {
+	// MaxRetries after the first attempt; zero disables retrying the class
+	MaxRetries   int
+	InitialDelay time.Duration
+	// MaxDelay caps a single wait; zero means no cap
+	MaxDelay time.Duration
+	// Multiplier defaults to 2
+	Multiplier float64
+	// Jitter is the fraction of each delay randomized away, 0-1, so clients
+	// retrying together spread out
+	Jitter float64
+}
+
+// delay returns the wait before retry n, starting at 1
+func (b Backoff) delay(n int) time.Duration {
+	multiplier := b.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+	d := float64(b.InitialDelay)
+	for i := 1; i < n; i++ {
+		d *= multiplier
+		if b.MaxDelay > 0 && d >= float64(b.MaxDelay) {
+			break
+		}
+	}
+	if b.MaxDelay > 0 {
+		d = min(d, float64(b.MaxDelay))
+	}
+	if jitter := min(max(b.Jitter, 0), 1); jitter > 0 {
+		d -= d * jitter * rand.Float64()
+	}
+	return time.Duration(d)
+}
+
+// RetryPolicy retries failed requests with a Backoff per error class
+type RetryPolicy struct {
+	// Classes maps error classes to their backoff; classes that aren't listed
+	// aren't retried
+	Classes map[ErrorClass]Backoff
+	// MaxElapsed stops retrying once this long has passed since the first
+	// attempt, including the wait that would follow. Zero means no limit
+	MaxElapsed time.Duration
+	// Classify defaults to ClassifyRetry
+	Classify func(error) ErrorClass
+}
+
+// DefaultRetryPolicy backs off longer on rate limits than on transient failures
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		Classes: map[ErrorClass]Backoff{
+			ErrorClassRateLimited: {MaxRetries: 5, InitialDelay: 500 * time.Millisecond, MaxDelay: 8 * time.Second, Jitter: 0.5},
+			ErrorClassTransient:   {MaxRetries: 3, InitialDelay: 100 * time.Millisecond, MaxDelay: 2 * time.Second, Jitter: 0.5},
+		},
+		MaxElapsed: 30 * time.Second,
+	}
+}
+
+// Do calls fn until it succeeds, fails with an error the policy doesn't retry,
+// runs out of retries or time, or ctx is done. The last error is returned
+func (p RetryPolicy) Do(ctx context.Context, fn func() error) error {
+	classify := p.Classify
+	if classify == nil {
+		classify = ClassifyRetry
+	}
+	start := time.Now()
+	retries := make(map[ErrorClass]int)
+	for {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+		class := classify(err)
+		backoff, ok := p.Classes[class]
+		if !ok || retries[class] >= backoff.MaxRetries || ctx.Err() != nil {
+			return err
+		}
+		retries[class]++
+		wait := backoff.delay(retries[class])
+		if p.MaxElapsed > 0 && time.Since(start)+wait > p.MaxElapsed {
+			return err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// SetRetryPolicy retries every RpcClient call failing with an error policy
+// covers. It must be called before RpcClient is shared with other goroutines
+func (c *Client) SetRetryPolicy(policy RetryPolicy) {
+	c.RpcClient = rpc.NewWithCustomRPCClient(&retryRPCClient{next: c.RpcClient, policy: policy})
+}
+
+// retryRPCClient implements rpc.JSONRPCClient, retrying calls with a RetryPolicy
+type retryRPCClient struct {
+	next   *rpc.Client
+	policy RetryPolicy
+}
+
+func (r *retryRPCClient) CallForInto(ctx context.Context, out interface{}, method string, params []interface{}) error {
+	return r.policy.Do(ctx, func() error {
+		return r.next.RPCCallForInto(ctx, out, method, params)
+	})
+}
+
+func (r *retryRPCClient) CallWithCallback(ctx context.Context, method string, params []interface{}, callback func(*http.Request, *http.Response) error) error {
+	return r.policy.Do(ctx, func() error {
+		return r.next.RPCCallWithCallback(ctx, method, params, callback)
+	})
+}
+
+func (r *retryRPCClient) CallBatch(ctx context.Context, requests jsonrpc.RPCRequests) (jsonrpc.RPCResponses, error) {
+	var responses jsonrpc.RPCResponses
+	err := r.policy.Do(ctx, func() error {
+		var err error
+		responses, err = r.next.RPCCallBatch(ctx, requests)
+		return err
+	})
+	return responses, err
+}
+
+// Close closes the wrapped client; it is called by rpc.Client.Close
+func (r *retryRPCClient) Close() error {
+	return r.next.Close()
+}
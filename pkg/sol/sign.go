@@ -2,70 +2,293 @@ package sol
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/gagliardetto/solana-go"
 	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/yimingWOW/solroute/pkg/tracing"
 )
 
-// signTransaction creates and signs a new transaction with the given instructions
-func signTransaction(blockhash solana.Hash, signers []solana.PrivateKey, instrs ...solana.Instruction) (*solana.Transaction, error) {
+// signTransaction creates and signs a new transaction with the given instructions,
+// paid by the first signer
+func signTransaction(blockhash solana.Hash, signers []Signer, instrs []solana.Instruction, opts ...solana.TransactionOption) (*solana.Transaction, error) {
 	if len(signers) == 0 {
 		return nil, fmt.Errorf("at least one signer is required")
 	}
+	tx, err := newTransaction(blockhash, signers[0].Pubkey(), instrs, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := PartialSign(tx, signers...); err != nil {
+		return nil, err
+	}
+	if missing := MissingSigners(tx); len(missing) > 0 {
+		return nil, fmt.Errorf("failed to sign transaction: no signer for %s", missing[0].String())
+	}
+	return tx, nil
+}
 
-	// Create new transaction with all instructions
-	tx, err := solana.NewTransaction(
-		instrs,
-		blockhash,
-		solana.TransactionPayer(signers[0].PublicKey()),
-	)
+// newTransaction creates an unsigned transaction paid by payer, with a zero
+// signature for each required signer
+func newTransaction(blockhash solana.Hash, payer solana.PublicKey, instrs []solana.Instruction, opts ...solana.TransactionOption) (*solana.Transaction, error) {
+	opts = append(opts, solana.TransactionPayer(payer))
+	tx, err := solana.NewTransaction(instrs, blockhash, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create transaction: %w", err)
 	}
+	tx.Signatures = make([]solana.Signature, tx.Message.Header.NumRequiredSignatures)
+	return tx, nil
+}
 
-	// Sign the transaction with all provided signers
-	_, err = tx.Sign(
-		func(key solana.PublicKey) *solana.PrivateKey {
-			for _, payer := range signers {
-				if payer.PublicKey().Equals(key) {
-					return &payer
-				}
-			}
-			return nil
-		},
-	)
+// MaxTransactionSize is the largest serialized transaction the cluster accepts
+const MaxTransactionSize = 1232
+
+// TransactionSize returns the serialized size of a transaction of insts paid
+// by payer, with its signatures, compiled against tables when set
+func TransactionSize(payer solana.PublicKey, insts []solana.Instruction, tables map[solana.PublicKey]solana.PublicKeySlice) (int, error) {
+	var opts []solana.TransactionOption
+	if len(tables) > 0 {
+		opts = append(opts, solana.TransactionAddressTables(tables))
+	}
+	tx, err := newTransaction(solana.Hash{}, payer, insts, opts...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to sign transaction: %w", err)
+		return 0, err
 	}
-	return tx, nil
+	data, err := tx.MarshalBinary()
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode transaction: %w", err)
+	}
+	return len(data), nil
+}
+
+// TransactionSize returns the size of a transaction of insts paid by payer
+// referencing the lookup tables registered with UseLookupTables. The compute
+// budget instructions of a configured priority fee are not accounted for
+func (c *Client) TransactionSize(payer solana.PublicKey, insts []solana.Instruction) (int, error) {
+	return TransactionSize(payer, insts, c.lookupTables)
+}
+
+// TxResult describes a sent or simulated transaction
+type TxResult struct {
+	Signature    solana.Signature // zero for simulations
+	Slot         uint64
+	ComputeUnits uint64
+	Fee          uint64            // lamports paid, or that would be paid when simulated
+	Err          *InstructionError // the failing instruction, when one failed
+	// Simulation is set for simulations and when preflight simulation is enabled
+	Simulation *SimulationReport
+}
+
+// SendTx sends or simulates a transaction based on the isSimulate flag. When a
+// priority fee is configured, compute budget instructions are prepended first,
+// and lookup tables registered with UseLookupTables are referenced. A zero
+// blockhash is replaced by the one LatestBlockhash caches.
+//
+// A sent transaction is tracked with ConfirmTx until it lands, and the result
+// is read back from the ledger. When the transaction fails, the error wraps the
+// decoded *InstructionError that is also set on the result
+func (c *Client) SendTx(ctx context.Context, blockhash solana.Hash, signers []Signer, insts []solana.Instruction, isSimulate bool) (*TxResult, error) {
+	var confirmOpts ConfirmOptions
+	if blockhash.IsZero() {
+		recent, err := c.LatestBlockhash(ctx)
+		if err != nil {
+			return nil, err
+		}
+		blockhash = recent.Blockhash
+		confirmOpts.LastValidBlockHeight = recent.LastValidBlockHeight
+	}
+	tx, err := c.buildTx(ctx, blockhash, signers, insts)
+	if err != nil {
+		return nil, err
+	}
+
+	var preflight *SimulationReport
+	if isSimulate || c.preflightSimulation {
+		report, err := c.simulateTx(ctx, tx, insts)
+		if report == nil {
+			return nil, err
+		}
+		if isSimulate || err != nil {
+			return &TxResult{
+				Slot:         report.Slot,
+				ComputeUnits: report.ComputeUnits,
+				Fee:          report.Fee,
+				Err:          report.Err,
+				Simulation:   report,
+			}, err
+		}
+		preflight = report
+	}
+
+	sig, err := c.sendTx(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	result := &TxResult{Signature: sig, Simulation: preflight}
+	_, confirmErr := c.ConfirmTx(ctx, tx, confirmOpts)
+	if errors.Is(confirmErr, ErrTransactionExpired) {
+		c.invalidateBlockhash(blockhash)
+	}
+	if confirmErr != nil {
+		result.Err = instructionError(confirmErr)
+		if result.Err == nil {
+			return result, confirmErr
+		}
+	}
+
+	// getTransaction serves confirmed transactions at the earliest
+	commitment := c.confirmationCommitment()
+	if commitment == rpc.CommitmentProcessed {
+		commitment = rpc.CommitmentConfirmed
+	}
+	landed, err := c.RpcClient.GetTransaction(ctx, sig, &rpc.GetTransactionOpts{
+		Commitment:                     commitment,
+		MaxSupportedTransactionVersion: &rpc.MaxSupportedTransactionVersion0,
+	})
+	if err != nil {
+		if confirmErr != nil {
+			return result, confirmErr
+		}
+		return result, fmt.Errorf("failed to get transaction %s: %w", sig.String(), ClassifyError(err))
+	}
+	result.Slot = landed.Slot
+	if landed.Meta != nil {
+		result.Fee = landed.Meta.Fee
+		if baseFee := lamportsPerSignature * uint64(len(tx.Signatures)); result.Fee >= baseFee {
+			c.metrics.PriorityFee(result.Fee - baseFee)
+		}
+		if landed.Meta.ComputeUnitsConsumed != nil {
+			result.ComputeUnits = *landed.Meta.ComputeUnitsConsumed
+		}
+		if landed.Meta.Err != nil {
+			err := transactionError(tx, landed.Meta.Err)
+			result.Err = instructionError(err)
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// instructionError returns the *InstructionError in err's chain, if any
+func instructionError(err error) *InstructionError {
+	var instErr *InstructionError
+	if errors.As(err, &instErr) {
+		return instErr
+	}
+	return nil
+}
+
+// SendAndConfirmTx signs insts against the blockhash LatestBlockhash returns, sends
+// the transaction and waits until ConfirmTx, configured by opts, reports its
+// outcome. opts.LastValidBlockHeight is filled in from the blockhash
+func (c *Client) SendAndConfirmTx(ctx context.Context, signers []Signer, insts []solana.Instruction, opts ConfirmOptions) (solana.Signature, error) {
+	recent, err := c.LatestBlockhash(ctx)
+	if err != nil {
+		return solana.Signature{}, err
+	}
+	tx, err := c.buildTx(ctx, recent.Blockhash, signers, insts)
+	if err != nil {
+		return solana.Signature{}, err
+	}
+	sig, err := c.sendTx(ctx, tx)
+	if err != nil {
+		return solana.Signature{}, err
+	}
+	opts.LastValidBlockHeight = recent.LastValidBlockHeight
+	if _, err := c.ConfirmTx(ctx, tx, opts); err != nil {
+		if errors.Is(err, ErrTransactionExpired) {
+			c.invalidateBlockhash(recent.Blockhash)
+		}
+		return sig, err
+	}
+	return sig, nil
 }
 
-// SendTx sends or simulates a transaction based on the isSimulate flag
-func (c *Client) SendTx(ctx context.Context, blockhash solana.Hash, signers []solana.PrivateKey, insts []solana.Instruction, isSimulate bool) (solana.Signature, error) {
-	tx, err := signTransaction(blockhash, signers, insts...)
+// buildTx applies the configured priority fee and lookup tables to insts and signs
+// them, paid by the first signer. A leading advance nonce instruction stays first
+func (c *Client) buildTx(ctx context.Context, blockhash solana.Hash, signers []Signer, insts []solana.Instruction) (*solana.Transaction, error) {
+	if len(signers) == 0 {
+		return nil, fmt.Errorf("failed to sign transaction: at least one signer is required")
+	}
+	tx, err := c.prepareTx(ctx, blockhash, signers[0].Pubkey(), insts)
 	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to sign transaction: %w", err)
+		return nil, err
+	}
+	if err := PartialSign(tx, signers...); err != nil {
+		return nil, fmt.Errorf("failed to sign transaction: %w", err)
+	}
+	if missing := MissingSigners(tx); len(missing) > 0 {
+		return nil, fmt.Errorf("failed to sign transaction: no signer for %s", missing[0].String())
 	}
+	return tx, nil
+}
 
-	if isSimulate {
-		if _, err := c.RpcClient.SimulateTransaction(ctx, tx); err != nil {
-			return solana.Signature{}, fmt.Errorf("failed to simulate transaction: %w", err)
+// prepareTx applies the configured priority fee and lookup tables to insts and
+// returns the unsigned transaction paid by payer
+func (c *Client) prepareTx(ctx context.Context, blockhash solana.Hash, payer solana.PublicKey, insts []solana.Instruction) (*solana.Transaction, error) {
+	if c.priorityFee != nil && !hasComputeBudget(insts) {
+		nonce := len(insts) > 0 && isAdvanceNonce(insts[0])
+		count := len(insts)
+		var err error
+		insts, err = c.WithPriorityFee(ctx, payer, insts, *c.priorityFee)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add priority fee: %w", err)
+		}
+		if nonce {
+			// Move the advance instruction back in front of the compute budget ones
+			i := len(insts) - count
+			insts = append([]solana.Instruction{insts[i]}, append(insts[:i:i], insts[i+1:]...)...)
 		}
-		// Return empty signature for simulation
-		return solana.Signature{}, nil
 	}
 
+	var opts []solana.TransactionOption
+	if len(c.lookupTables) > 0 {
+		opts = append(opts, solana.TransactionAddressTables(c.lookupTables))
+	}
+	return newTransaction(blockhash, payer, insts, opts...)
+}
+
+// TransactionSender submits signed transactions, e.g. to a provider's send
+// endpoint instead of the RPC node. Confirmation still goes through RpcClient
+type TransactionSender interface {
+	SendTransaction(ctx context.Context, tx *solana.Transaction) (solana.Signature, error)
+}
+
+// SetTransactionSender makes SendTx and the other sending methods submit
+// transactions with sender. nil restores sending through RpcClient
+func (c *Client) SetTransactionSender(sender TransactionSender) {
+	c.txSender = sender
+}
+
+func (c *Client) sendTx(ctx context.Context, tx *solana.Transaction) (solana.Signature, error) {
+	ctx, span := c.tracer().Start(ctx, tracing.SpanSend)
+	sig, err := c.submitTx(ctx, tx)
+	if err == nil {
+		span.SetAttributes(tracing.KeySignature.String(sig.String()))
+	}
+	tracing.End(span, err)
+	return sig, err
+}
+
+func (c *Client) submitTx(ctx context.Context, tx *solana.Transaction) (solana.Signature, error) {
+	if c.txSender != nil {
+		sig, err := c.txSender.SendTransaction(ctx, tx)
+		if err != nil {
+			return solana.Signature{}, fmt.Errorf("failed to send transaction: %w", ClassifyError(err))
+		}
+		return sig, nil
+	}
 	// Send transaction with optimized options
 	sig, err := c.RpcClient.SendTransactionWithOpts(
 		ctx, tx,
 		rpc.TransactionOpts{
 			SkipPreflight:       true,
-			PreflightCommitment: rpc.CommitmentProcessed,
+			PreflightCommitment: c.sendingCommitment(),
 		},
 	)
 	if err != nil {
-		return solana.Signature{}, fmt.Errorf("failed to send transaction: %w", err)
+		return solana.Signature{}, fmt.Errorf("failed to send transaction: %w", ClassifyError(err))
 	}
 	return sig, nil
 }
@@ -0,0 +1,32 @@
+package sol
+
+import (
+	"github.com/gagliardetto/solana-go"
+)
+
+// Signer signs transaction messages. Implement it to sign with hardware wallets,
+// remote signers, KMS/HSM keys or multisig flows
+type Signer interface {
+	Pubkey() solana.PublicKey
+	Sign(message []byte) (solana.Signature, error)
+}
+
+// PrivateKeySigner signs with an in-memory private key
+type PrivateKeySigner solana.PrivateKey
+
+func (k PrivateKeySigner) Pubkey() solana.PublicKey {
+	return solana.PrivateKey(k).PublicKey()
+}
+
+func (k PrivateKeySigner) Sign(message []byte) (solana.Signature, error) {
+	return solana.PrivateKey(k).Sign(message)
+}
+
+// NewSigners wraps private keys as Signers
+func NewSigners(keys ...solana.PrivateKey) []Signer {
+	signers := make([]Signer, len(keys))
+	for i, key := range keys {
+		signers[i] = PrivateKeySigner(key)
+	}
+	return signers
+}
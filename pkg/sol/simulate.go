@@ -0,0 +1,179 @@
+package sol
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/yimingWOW/solroute/pkg/tracing"
+)
+
+const (
+	// tokenAccountSize is the length of a token account without Token-2022 extensions
+	tokenAccountSize = 165
+	// token2022AccountTypeAccount marks an extended Token-2022 token account, as opposed to a mint
+	token2022AccountTypeAccount = 2
+)
+
+// SimulationReport is the expected outcome of a transaction
+type SimulationReport struct {
+	Slot          uint64
+	ComputeUnits  uint64
+	Fee           uint64 // lamports
+	Logs          []string
+	TokenBalances []TokenBalanceChange // token accounts whose balance changes
+	Err           *InstructionError    // the failing instruction, when one failed
+}
+
+// TokenBalanceChange is the effect of a transaction on a token account it writes
+type TokenBalanceChange struct {
+	Account solana.PublicKey
+	Mint    solana.PublicKey
+	Owner   solana.PublicKey
+	Before  uint64 // zero when the transaction creates the account
+	After   uint64 // zero when the transaction closes the account
+}
+
+// Delta returns After - Before
+func (c TokenBalanceChange) Delta() math.Int {
+	return math.NewIntFromUint64(c.After).Sub(math.NewIntFromUint64(c.Before))
+}
+
+// SetPreflightSimulation makes SendTx simulate every transaction before sending
+// it. A failing simulation aborts the send, and the report is returned in
+// TxResult.Simulation either way
+func (c *Client) SetPreflightSimulation(enabled bool) {
+	c.preflightSimulation = enabled
+}
+
+// SimulateTx builds insts like SendTx and simulates them, reporting logs,
+// consumed compute units, the fee and the token balance changes. When the
+// simulation fails, the error wraps report.Err if an instruction failed
+func (c *Client) SimulateTx(ctx context.Context, blockhash solana.Hash, signers []Signer, insts []solana.Instruction) (*SimulationReport, error) {
+	tx, err := c.buildTx(ctx, blockhash, signers, insts)
+	if err != nil {
+		return nil, err
+	}
+	return c.simulateTx(ctx, tx, insts)
+}
+
+// SimulateUnsignedTx simulates insts paid by payer like SimulateTx, without
+// signing them, e.g. for a wallet whose key isn't at hand. The lookup tables
+// apply but not the priority fee, so InstructionError.Index points into insts.
+// The node replaces the blockhash
+func (c *Client) SimulateUnsignedTx(ctx context.Context, payer solana.PublicKey, insts []solana.Instruction) (*SimulationReport, error) {
+	var opts []solana.TransactionOption
+	if len(c.lookupTables) > 0 {
+		opts = append(opts, solana.TransactionAddressTables(c.lookupTables))
+	}
+	tx, err := newTransaction(solana.Hash{}, payer, insts, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return c.simulateTx(ctx, tx, insts)
+}
+
+// simulateTx simulates tx, whose instructions are insts minus any compute budget ones
+func (c *Client) simulateTx(ctx context.Context, tx *solana.Transaction, insts []solana.Instruction) (*SimulationReport, error) {
+	ctx, span := c.tracer().Start(ctx, tracing.SpanSimulate)
+	report, err := c.runSimulation(ctx, tx, insts)
+	tracing.End(span, err)
+	return report, err
+}
+
+func (c *Client) runSimulation(ctx context.Context, tx *solana.Transaction, insts []solana.Instruction) (*SimulationReport, error) {
+	// Token accounts can only change if written, read those before and after
+	accounts := writableAccounts(insts)
+	accounts = accounts[:min(len(accounts), maxMultipleAccounts)]
+	var before []*rpc.Account
+	if len(accounts) > 0 {
+		pre, err := c.RpcClient.GetMultipleAccountsWithOpts(ctx, accounts, &rpc.GetMultipleAccountsOpts{
+			Commitment: rpc.CommitmentProcessed,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get accounts: %w", ClassifyError(err))
+		}
+		before = pre.Value
+	}
+
+	simulation, err := c.RpcClient.SimulateTransactionWithOpts(ctx, tx, &rpc.SimulateTransactionOpts{
+		Commitment:             rpc.CommitmentProcessed,
+		ReplaceRecentBlockhash: tx.Message.RecentBlockhash.IsZero(),
+		Accounts: &rpc.SimulateTransactionAccountsOpts{
+			Encoding:  solana.EncodingBase64,
+			Addresses: accounts,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to simulate transaction: %w", ClassifyError(err))
+	}
+	report := &SimulationReport{Slot: simulation.Context.Slot}
+	if simulation.Value == nil {
+		return report, nil
+	}
+	report.Logs = simulation.Value.Logs
+	if simulation.Value.UnitsConsumed != nil {
+		report.ComputeUnits = *simulation.Value.UnitsConsumed
+	}
+	if message, err := tx.Message.MarshalBinary(); err == nil {
+		fee, err := c.RpcClient.GetFeeForMessage(ctx, base64.StdEncoding.EncodeToString(message), rpc.CommitmentProcessed)
+		if err == nil && fee.Value != nil {
+			report.Fee = *fee.Value
+		}
+	}
+	if simulation.Value.Err != nil {
+		err := fmt.Errorf("simulation failed: %w", transactionError(tx, simulation.Value.Err))
+		report.Err = instructionError(err)
+		return report, err
+	}
+
+	if len(simulation.Value.Accounts) == len(accounts) && len(before) == len(accounts) {
+		for i, account := range accounts {
+			if change, ok := tokenBalanceChange(account, before[i], simulation.Value.Accounts[i]); ok {
+				report.TokenBalances = append(report.TokenBalances, change)
+			}
+		}
+	}
+	return report, nil
+}
+
+// tokenBalanceChange compares a token account before and after a transaction.
+// ok is false when it isn't a token account or its balance is unchanged
+func tokenBalanceChange(pubkey solana.PublicKey, before, after *rpc.Account) (TokenBalanceChange, bool) {
+	change := TokenBalanceChange{Account: pubkey}
+	var found bool
+	for _, state := range []struct {
+		account *rpc.Account
+		amount  *uint64
+	}{{before, &change.Before}, {after, &change.After}} {
+		data, ok := tokenAccountData(state.account)
+		if !ok {
+			continue
+		}
+		found = true
+		change.Mint = solana.PublicKeyFromBytes(data[0:32])
+		change.Owner = solana.PublicKeyFromBytes(data[32:64])
+		*state.amount, _ = ParseTokenAmount(data)
+	}
+	return change, found && change.Before != change.After
+}
+
+// tokenAccountData returns the data of account if it is an SPL or Token-2022
+// token account
+func tokenAccountData(account *rpc.Account) ([]byte, bool) {
+	if account == nil || account.Data == nil {
+		return nil, false
+	}
+	if !account.Owner.Equals(solana.TokenProgramID) && !account.Owner.Equals(solana.Token2022ProgramID) {
+		return nil, false
+	}
+	data := account.Data.GetBinary()
+	// Token-2022 accounts with extensions carry their account type after the base layout
+	if len(data) == tokenAccountSize || (len(data) > tokenAccountSize && data[tokenAccountSize] == token2022AccountTypeAccount) {
+		return data, true
+	}
+	return nil, false
+}
@@ -0,0 +1,69 @@
+package soltest
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+// Fixture is an account stored under testdata, with the fields its decoder
+// must produce so layout regressions show up without mainnet access
+type Fixture struct {
+	// Account is the account data, base64 in the file
+	Account []byte `json:"account"`
+	// Decoded are the golden decoded fields, formatted as strings
+	Decoded map[string]string `json:"decoded"`
+}
+
+// ReadFixture reads the fixture at path
+func ReadFixture(t testing.TB, path string) *Fixture {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fixture Fixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		t.Fatalf("failed to decode fixture %s: %v", path, err)
+	}
+	return &fixture
+}
+
+// CheckDecoded compares decoded with the golden fields of the fixture at
+// path, or rewrites them with decoded when update is set
+func CheckDecoded(t testing.TB, path string, decoded map[string]string, update bool) {
+	t.Helper()
+	fixture := ReadFixture(t, path)
+	if update {
+		fixture.Decoded = decoded
+		data, err := json.MarshalIndent(fixture, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	for field, want := range fixture.Decoded {
+		if got, ok := decoded[field]; !ok || got != want {
+			t.Errorf("%s: %s = %q, want %q", path, field, got, want)
+		}
+	}
+	for field := range decoded {
+		if _, ok := fixture.Decoded[field]; !ok {
+			t.Errorf("%s: %s isn't in the fixture, run with -update", path, field)
+		}
+	}
+}
+
+// AddSeeds seeds f with the account of the fixture at path, and with the
+// empty and truncated accounts a decoder must reject
+func AddSeeds(f *testing.F, path string) {
+	account := ReadFixture(f, path).Account
+	f.Add([]byte{})
+	f.Add(account[:min(8, len(account))])
+	f.Add(account[:len(account)/2])
+	f.Add(account[:max(len(account)-1, 0)])
+	f.Add(account)
+}
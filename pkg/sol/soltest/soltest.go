@@ -0,0 +1,556 @@
+// Package soltest provides an in-memory RPC node for testing code built on
+// sol.Client and the pools without mainnet access or a funded key:
+//
+//	node := soltest.NewRPC()
+//	node.SetAccount(poolID, soltest.Account{Owner: programID, Data: poolData})
+//	node.FailNext("getMultipleAccounts", 1, soltest.ErrRateLimit)
+//	node.SetLatency("", 20*time.Millisecond)
+//	client := node.SolClient()
+//
+// Account reads, program account scans with memcmp and dataSize filters, token
+// accounts by owner, balances, blockhashes and slots are answered from the fixtures, as the JSON
+// a node would send, so decoding runs exactly as against a real node. Other
+// methods can be answered with Handle
+package soltest
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+	"github.com/yimingWOW/solroute/pkg/sol"
+)
+
+// Errors to program with SetError and FailNext
+var (
+	// ErrRateLimit is a 429 response, classified as sol.ErrRateLimited
+	ErrRateLimit = jsonrpc.NewHTTPError(http.StatusTooManyRequests, errors.New("too many requests"))
+	// ErrUnavailable is a 503 response, retried as a transient failure
+	ErrUnavailable = jsonrpc.NewHTTPError(http.StatusServiceUnavailable, errors.New("service unavailable"))
+)
+
+// ErrMethodNotFound is returned for methods without fixtures or a handler
+var ErrMethodNotFound = &jsonrpc.RPCError{Code: -32601, Message: "Method not found"}
+
+// DefaultBlockhash is the blockhash getLatestBlockhash returns until SetBlockhash
+var DefaultBlockhash = solana.MustHashFromBase58("EkSnNWid2cvwEVnVx9aBqawnmiCNiDgp3gUdkDPTKN1N")
+
+// Account is an account fixture
+type Account struct {
+	Owner      solana.PublicKey
+	Lamports   uint64
+	Data       []byte
+	Executable bool
+}
+
+// Call is a request the node received
+type Call struct {
+	Method string
+	Params []interface{}
+}
+
+// Handler answers a method; the result is marshaled to JSON like a node's
+type Handler func(ctx context.Context, params []interface{}) (interface{}, error)
+
+// RPC is an in-memory RPC node implementing rpc.JSONRPCClient. It is safe for
+// concurrent use
+type RPC struct {
+	mu        sync.Mutex
+	accounts  map[solana.PublicKey]Account
+	slot      uint64
+	blockhash solana.Hash
+	handlers  map[string]Handler
+	errors    map[string]error
+	failNext  map[string][]error
+	latency   map[string]time.Duration
+	calls     []Call
+}
+
+var _ rpc.JSONRPCClient = (*RPC)(nil)
+
+// NewRPC creates a node without accounts at slot 1
+func NewRPC() *RPC {
+	return &RPC{
+		accounts:  make(map[solana.PublicKey]Account),
+		slot:      1,
+		blockhash: DefaultBlockhash,
+		handlers:  make(map[string]Handler),
+		errors:    make(map[string]error),
+		failNext:  make(map[string][]error),
+		latency:   make(map[string]time.Duration),
+	}
+}
+
+// Client returns an rpc.Client talking to the node
+func (m *RPC) Client() *rpc.Client {
+	return rpc.NewWithCustomRPCClient(m)
+}
+
+// SolClient returns a sol.Client talking to the node, without WebSocket
+func (m *RPC) SolClient() *sol.Client {
+	return sol.NewClientFromRPC(m.Client())
+}
+
+// SetAccount adds or replaces the account at key
+func (m *RPC) SetAccount(key solana.PublicKey, account Account) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.accounts[key] = account
+}
+
+// DeleteAccount removes the account at key, so reads of it find nothing
+func (m *RPC) DeleteAccount(key solana.PublicKey) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.accounts, key)
+}
+
+// SetMint adds an SPL Token mint account with decimals
+func (m *RPC) SetMint(mint solana.PublicKey, decimals uint8) {
+	data := make([]byte, 82)
+	data[44] = decimals
+	data[45] = 1 // initialized
+	m.SetAccount(mint, Account{Owner: solana.TokenProgramID, Lamports: 1461600, Data: data})
+}
+
+// SetTokenAccount adds an SPL Token account of owner holding amount of mint
+func (m *RPC) SetTokenAccount(key, mint, owner solana.PublicKey, amount uint64) {
+	data := make([]byte, 165)
+	copy(data[0:32], mint[:])
+	copy(data[32:64], owner[:])
+	binary.LittleEndian.PutUint64(data[64:72], amount)
+	data[108] = 1 // initialized
+	m.SetAccount(key, Account{Owner: solana.TokenProgramID, Lamports: 2039280, Data: data})
+}
+
+// SetSlot sets the slot responses are given at
+func (m *RPC) SetSlot(slot uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.slot = slot
+}
+
+// SetBlockhash sets the blockhash getLatestBlockhash returns
+func (m *RPC) SetBlockhash(blockhash solana.Hash) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.blockhash = blockhash
+}
+
+// Handle answers method with h instead of the fixtures, or adds a method the
+// node doesn't know, e.g. sendTransaction
+func (m *RPC) Handle(method string, h Handler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handlers[method] = h
+}
+
+// SetError makes every call of method fail with err until it is cleared with a
+// nil err. An empty method fails every call
+func (m *RPC) SetError(method string, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err == nil {
+		delete(m.errors, method)
+		return
+	}
+	m.errors[method] = err
+}
+
+// FailNext makes the next n calls of method fail with err, after which they
+// succeed again. An empty method matches every call
+func (m *RPC) FailNext(method string, n int, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for range n {
+		m.failNext[method] = append(m.failNext[method], err)
+	}
+}
+
+// SetLatency delays every call of method by d, or of every method when method
+// is empty. Calls return early with the context's error when it is done first
+func (m *RPC) SetLatency(method string, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.latency[method] = d
+}
+
+// Calls returns the requests received so far, in order
+func (m *RPC) Calls() []Call {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Call(nil), m.calls...)
+}
+
+// CallCount returns how many requests for method were received
+func (m *RPC) CallCount(method string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for _, call := range m.calls {
+		if call.Method == method {
+			n++
+		}
+	}
+	return n
+}
+
+// Reset forgets the recorded calls and the programmed errors and latencies,
+// keeping the fixtures and handlers
+func (m *RPC) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = nil
+	m.errors = make(map[string]error)
+	m.failNext = make(map[string][]error)
+	m.latency = make(map[string]time.Duration)
+}
+
+func (m *RPC) CallForInto(ctx context.Context, out interface{}, method string, params []interface{}) error {
+	result, err := m.call(ctx, method, params)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(result, out)
+}
+
+func (m *RPC) CallWithCallback(ctx context.Context, method string, params []interface{}, callback func(*http.Request, *http.Response) error) error {
+	result, err := m.call(ctx, method, params)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(jsonrpc.RPCResponse{JSONRPC: "2.0", Result: result, ID: 0})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://soltest", nil)
+	if err != nil {
+		return err
+	}
+	return callback(req, &http.Response{
+		StatusCode:    http.StatusOK,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	})
+}
+
+func (m *RPC) CallBatch(ctx context.Context, requests jsonrpc.RPCRequests) (jsonrpc.RPCResponses, error) {
+	responses := make(jsonrpc.RPCResponses, 0, len(requests))
+	for _, request := range requests {
+		params, _ := request.Params.([]interface{})
+		result, err := m.call(ctx, request.Method, params)
+		response := &jsonrpc.RPCResponse{JSONRPC: "2.0", ID: request.ID}
+		var rpcErr *jsonrpc.RPCError
+		switch {
+		case errors.As(err, &rpcErr):
+			response.Error = rpcErr
+		case err != nil:
+			// Transport errors fail the whole batch
+			return nil, err
+		default:
+			response.Result = result
+		}
+		responses = append(responses, response)
+	}
+	return responses, nil
+}
+
+// Close implements rpc.JSONRPCClient; the node keeps working after it
+func (m *RPC) Close() error {
+	return nil
+}
+
+// call records the request, applies the programmed latency and errors and
+// answers it
+func (m *RPC) call(ctx context.Context, method string, params []interface{}) (json.RawMessage, error) {
+	m.mu.Lock()
+	m.calls = append(m.calls, Call{Method: method, Params: params})
+	delay := m.latency[""] + m.latency[method]
+	err := m.programmedError(method)
+	handler := m.handlers[method]
+	m.mu.Unlock()
+
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var result interface{}
+	if handler != nil {
+		result, err = handler(ctx, params)
+	} else {
+		result, err = m.answer(method, params)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(result)
+}
+
+// programmedError pops the error the next call of method fails with. m.mu is held
+func (m *RPC) programmedError(method string) error {
+	for _, key := range []string{method, ""} {
+		if queued := m.failNext[key]; len(queued) > 0 {
+			m.failNext[key] = queued[1:]
+			return queued[0]
+		}
+	}
+	if err, ok := m.errors[method]; ok {
+		return err
+	}
+	return m.errors[""]
+}
+
+// config is the optional config object of a request
+type config struct {
+	Encoding  string `json:"encoding"`
+	DataSlice *struct {
+		Offset uint64 `json:"offset"`
+		Length uint64 `json:"length"`
+	} `json:"dataSlice"`
+	Filters []struct {
+		DataSize *uint64 `json:"dataSize"`
+		Memcmp   *struct {
+			Offset uint64 `json:"offset"`
+			Bytes  string `json:"bytes"`
+		} `json:"memcmp"`
+	} `json:"filters"`
+}
+
+// answer serves method from the fixtures
+func (m *RPC) answer(method string, params []interface{}) (interface{}, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ctx := map[string]uint64{"slot": m.slot}
+
+	var cfg config
+	if len(params) > 1 {
+		if err := remarshal(params[len(params)-1], &cfg); err != nil {
+			return nil, invalidParams(err)
+		}
+	}
+	switch method {
+	case "getAccountInfo":
+		key, err := pubkeyParam(params)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"context": ctx, "value": m.encodeAccount(key, cfg)}, nil
+
+	case "getMultipleAccounts":
+		if len(params) == 0 {
+			return nil, invalidParams(errors.New("missing accounts"))
+		}
+		var keys []solana.PublicKey
+		if err := remarshal(params[0], &keys); err != nil {
+			return nil, invalidParams(err)
+		}
+		values := make([]interface{}, len(keys))
+		for i, key := range keys {
+			values[i] = m.encodeAccount(key, cfg)
+		}
+		return map[string]interface{}{"context": ctx, "value": values}, nil
+
+	case "getProgramAccounts":
+		program, err := pubkeyParam(params)
+		if err != nil {
+			return nil, err
+		}
+		keyed := []interface{}{}
+		for key, account := range m.accounts {
+			if account.Owner.Equals(program) && matches(account.Data, cfg) {
+				keyed = append(keyed, map[string]interface{}{"pubkey": key, "account": m.encodeAccount(key, cfg)})
+			}
+		}
+		return keyed, nil
+
+	case "getTokenAccountsByOwner":
+		owner, err := pubkeyParam(params)
+		if err != nil {
+			return nil, err
+		}
+		var filter struct {
+			Mint      *solana.PublicKey `json:"mint"`
+			ProgramID *solana.PublicKey `json:"programId"`
+		}
+		if len(params) < 2 {
+			return nil, invalidParams(errors.New("missing mint or programId"))
+		}
+		if err := remarshal(params[1], &filter); err != nil {
+			return nil, invalidParams(err)
+		}
+		keyed := []interface{}{}
+		for key, account := range m.accounts {
+			if !isTokenProgram(account.Owner) || len(account.Data) < 165 || !owner.Equals(solana.PublicKeyFromBytes(account.Data[32:64])) {
+				continue
+			}
+			if filter.Mint != nil && !filter.Mint.Equals(solana.PublicKeyFromBytes(account.Data[0:32])) {
+				continue
+			}
+			if filter.ProgramID != nil && !filter.ProgramID.Equals(account.Owner) {
+				continue
+			}
+			keyed = append(keyed, map[string]interface{}{"pubkey": key, "account": m.encodeAccount(key, cfg)})
+		}
+		return map[string]interface{}{"context": ctx, "value": keyed}, nil
+
+	case "getBalance":
+		key, err := pubkeyParam(params)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"context": ctx, "value": m.accounts[key].Lamports}, nil
+
+	case "getTokenAccountBalance":
+		key, err := pubkeyParam(params)
+		if err != nil {
+			return nil, err
+		}
+		account, ok := m.accounts[key]
+		if !ok || len(account.Data) < 72 {
+			return nil, &jsonrpc.RPCError{Code: -32602, Message: "Invalid param: could not find account"}
+		}
+		mint := solana.PublicKeyFromBytes(account.Data[0:32])
+		var decimals uint8
+		if mintAccount, ok := m.accounts[mint]; ok && len(mintAccount.Data) > 44 {
+			decimals = mintAccount.Data[44]
+		}
+		amount := binary.LittleEndian.Uint64(account.Data[64:72])
+		ui := math.LegacyNewDecFromBigIntWithPrec(math.NewIntFromUint64(amount).BigInt(), int64(decimals))
+		uiAmount, _ := strconv.ParseFloat(ui.String(), 64)
+		return map[string]interface{}{"context": ctx, "value": map[string]interface{}{
+			"amount":         strconv.FormatUint(amount, 10),
+			"decimals":       decimals,
+			"uiAmount":       uiAmount,
+			"uiAmountString": strconv.FormatFloat(uiAmount, 'f', -1, 64),
+		}}, nil
+
+	case "getLatestBlockhash":
+		return map[string]interface{}{"context": ctx, "value": map[string]interface{}{
+			"blockhash":            m.blockhash,
+			"lastValidBlockHeight": m.slot + 150,
+		}}, nil
+
+	case "getSlot", "getBlockHeight":
+		return m.slot, nil
+
+	case "isBlockhashValid":
+		return map[string]interface{}{"context": ctx, "value": true}, nil
+
+	case "getRecentPrioritizationFees":
+		return []interface{}{}, nil
+
+	case "getMinimumBalanceForRentExemption":
+		var size uint64
+		if len(params) > 0 {
+			if err := remarshal(params[0], &size); err != nil {
+				return nil, invalidParams(err)
+			}
+		}
+		// The rent of (128 + size) bytes for two years at 3480 lamports per byte-year
+		return (128 + size) * 3480 * 2, nil
+
+	case "getHealth":
+		return "ok", nil
+	}
+	return nil, ErrMethodNotFound
+}
+
+// encodeAccount returns the JSON of the account at key, nil when there is none
+func (m *RPC) encodeAccount(key solana.PublicKey, cfg config) interface{} {
+	account, ok := m.accounts[key]
+	if !ok {
+		return nil
+	}
+	data := account.Data
+	if cfg.DataSlice != nil {
+		start := min(cfg.DataSlice.Offset, uint64(len(data)))
+		end := min(start+cfg.DataSlice.Length, uint64(len(data)))
+		data = data[start:end]
+	}
+	return map[string]interface{}{
+		"owner":      account.Owner,
+		"lamports":   account.Lamports,
+		"data":       []string{base64.StdEncoding.EncodeToString(data), "base64"},
+		"executable": account.Executable,
+		"rentEpoch":  0,
+		"space":      len(account.Data),
+	}
+}
+
+func isTokenProgram(program solana.PublicKey) bool {
+	return program.Equals(solana.TokenProgramID) || program.Equals(solana.Token2022ProgramID)
+}
+
+// matches applies the getProgramAccounts filters of cfg to data
+func matches(data []byte, cfg config) bool {
+	for _, filter := range cfg.Filters {
+		if filter.DataSize != nil && uint64(len(data)) != *filter.DataSize {
+			return false
+		}
+		if filter.Memcmp != nil {
+			want, err := decodeBase58(filter.Memcmp.Bytes)
+			if err != nil {
+				return false
+			}
+			offset := filter.Memcmp.Offset
+			if offset+uint64(len(want)) > uint64(len(data)) || !bytes.Equal(data[offset:offset+uint64(len(want))], want) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func decodeBase58(s string) (solana.Base58, error) {
+	var b solana.Base58
+	err := b.UnmarshalJSON([]byte(strconv.Quote(s)))
+	return b, err
+}
+
+func pubkeyParam(params []interface{}) (solana.PublicKey, error) {
+	if len(params) == 0 {
+		return solana.PublicKey{}, invalidParams(errors.New("missing pubkey"))
+	}
+	var key solana.PublicKey
+	if err := remarshal(params[0], &key); err != nil {
+		return solana.PublicKey{}, invalidParams(err)
+	}
+	return key, nil
+}
+
+// remarshal decodes a request param, as built by rpc.Client, through its JSON
+func remarshal(param interface{}, out interface{}) error {
+	data, err := json.Marshal(param)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func invalidParams(err error) error {
+	return &jsonrpc.RPCError{Code: -32602, Message: fmt.Sprintf("Invalid params: %v", err)}
+}
@@ -0,0 +1,273 @@
+package soltest
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/yimingWOW/solroute/pkg/sol"
+)
+
+// Validator defaults
+const (
+	DefaultValidatorBinary = "solana-test-validator"
+	DefaultRPCPort         = 8899
+	DefaultStartTimeout    = time.Minute
+)
+
+// ValidatorOptions configures StartValidator. Zero fields keep the defaults
+type ValidatorOptions struct {
+	// Binary defaults to DefaultValidatorBinary, looked up in PATH
+	Binary string
+	// LedgerDir keeps the ledger there. A temporary one, removed by Stop, is
+	// used when empty
+	LedgerDir string
+	// RPCPort defaults to DefaultRPCPort; the WebSocket listens on the next port.
+	// Validators running side by side also need distinct faucet and gossip
+	// ports, passed in Args
+	RPCPort int
+	// CloneFrom is the cluster Clone and ClonePrograms are copied from, e.g.
+	// rpc.MainNetBeta_RPC
+	CloneFrom string
+	// Clone copies these accounts, e.g. pools, vaults and mints, at startup
+	Clone []solana.PublicKey
+	// ClonePrograms copies these upgradeable programs, e.g. the protocols'
+	ClonePrograms []solana.PublicKey
+	// Programs deploys the .so file at each path under its program ID
+	Programs map[solana.PublicKey]string
+	// Accounts are loaded at startup, like fixtures of RPC.SetAccount
+	Accounts map[solana.PublicKey]Account
+	// Args are passed to the validator after the ones built from the options
+	Args []string
+	// StartTimeout bounds the wait for the node to be healthy. Defaults to
+	// DefaultStartTimeout; cloning from a slow cluster may take longer
+	StartTimeout time.Duration
+	// Output receives the validator's log. Discarded when nil
+	Output io.Writer
+}
+
+// Validator is a running solana-test-validator
+type Validator struct {
+	RPC string
+	WS  string
+
+	cmd        *exec.Cmd
+	exited     chan struct{}
+	exitErr    error
+	removeDirs []string
+}
+
+// StartValidator runs solana-test-validator with a fresh ledger and returns
+// once its RPC node reports healthy. The caller stops it with Stop
+func StartValidator(ctx context.Context, opts ValidatorOptions) (*Validator, error) {
+	binary := opts.Binary
+	if binary == "" {
+		binary = DefaultValidatorBinary
+	}
+	path, err := exec.LookPath(binary)
+	if err != nil {
+		return nil, fmt.Errorf("validator binary not found: %w", err)
+	}
+	port := opts.RPCPort
+	if port == 0 {
+		port = DefaultRPCPort
+	}
+	if (len(opts.Clone) > 0 || len(opts.ClonePrograms) > 0) && opts.CloneFrom == "" {
+		return nil, errors.New("cloning accounts requires CloneFrom")
+	}
+
+	v := &Validator{
+		RPC:    fmt.Sprintf("http://127.0.0.1:%d", port),
+		WS:     fmt.Sprintf("ws://127.0.0.1:%d", port+1),
+		exited: make(chan struct{}),
+	}
+	ledger := opts.LedgerDir
+	if ledger == "" {
+		if ledger, err = os.MkdirTemp("", "solroute-validator-"); err != nil {
+			return nil, fmt.Errorf("failed to create ledger dir: %w", err)
+		}
+		v.removeDirs = append(v.removeDirs, ledger)
+	}
+
+	args, err := v.args(opts, ledger, port)
+	if err != nil {
+		v.cleanup()
+		return nil, err
+	}
+	output := opts.Output
+	if output == nil {
+		output = io.Discard
+	}
+	v.cmd = exec.Command(path, args...)
+	v.cmd.Stdout = output
+	v.cmd.Stderr = output
+	if err := v.cmd.Start(); err != nil {
+		v.cleanup()
+		return nil, fmt.Errorf("failed to start validator: %w", err)
+	}
+	go func() {
+		v.exitErr = v.cmd.Wait()
+		close(v.exited)
+	}()
+
+	timeout := opts.StartTimeout
+	if timeout <= 0 {
+		timeout = DefaultStartTimeout
+	}
+	if err := v.waitHealthy(ctx, timeout); err != nil {
+		v.Stop()
+		return nil, err
+	}
+	return v, nil
+}
+
+// args builds the command line of opts, writing the account fixtures to a
+// temporary dir, as --reset empties the ledger
+func (v *Validator) args(opts ValidatorOptions, ledger string, port int) ([]string, error) {
+	args := []string{"--ledger", ledger, "--reset", "--quiet", "--rpc-port", strconv.Itoa(port)}
+	if opts.CloneFrom != "" {
+		args = append(args, "--url", opts.CloneFrom)
+	}
+	for _, key := range opts.Clone {
+		args = append(args, "--clone", key.String())
+	}
+	for _, program := range opts.ClonePrograms {
+		args = append(args, "--clone-upgradeable-program", program.String())
+	}
+	for program, so := range opts.Programs {
+		args = append(args, "--bpf-program", program.String(), so)
+	}
+	if len(opts.Accounts) > 0 {
+		dir, err := os.MkdirTemp("", "solroute-fixtures-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create fixture dir: %w", err)
+		}
+		v.removeDirs = append(v.removeDirs, dir)
+		for key, account := range opts.Accounts {
+			file := filepath.Join(dir, key.String()+".json")
+			if err := writeAccountFile(file, key, account); err != nil {
+				return nil, err
+			}
+			args = append(args, "--account", key.String(), file)
+		}
+	}
+	return append(args, opts.Args...), nil
+}
+
+// writeAccountFile writes account in the JSON of `solana account --output json`,
+// which --account loads
+func writeAccountFile(file string, key solana.PublicKey, account Account) error {
+	data, err := json.Marshal(map[string]interface{}{
+		"pubkey": key.String(),
+		"account": map[string]interface{}{
+			"lamports":   account.Lamports,
+			"data":       []string{base64.StdEncoding.EncodeToString(account.Data), "base64"},
+			"owner":      account.Owner.String(),
+			"executable": account.Executable,
+			"rentEpoch":  0,
+			"space":      len(account.Data),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode account %s: %w", key.String(), err)
+	}
+	if err := os.WriteFile(file, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write account %s: %w", key.String(), err)
+	}
+	return nil
+}
+
+// waitHealthy polls getHealth until it succeeds, the validator exits or timeout passes
+func (v *Validator) waitHealthy(ctx context.Context, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	client := rpc.New(v.RPC)
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if health, err := client.GetHealth(ctx); err == nil && health == rpc.HealthOk {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("validator not healthy: %w", ctx.Err())
+		case <-v.exited:
+			return fmt.Errorf("validator exited: %v", v.exitErr)
+		case <-ticker.C:
+		}
+	}
+}
+
+// Client returns a sol.Client connected to the validator
+func (v *Validator) Client(ctx context.Context) (*sol.Client, error) {
+	return sol.NewClient(ctx, v.RPC, v.WS)
+}
+
+// Airdrop funds to with lamports from the validator's faucet and waits for the
+// transfer to be confirmed
+func (v *Validator) Airdrop(ctx context.Context, to solana.PublicKey, lamports uint64) error {
+	client := rpc.New(v.RPC)
+	signature, err := client.RequestAirdrop(ctx, to, lamports, rpc.CommitmentConfirmed)
+	if err != nil {
+		return fmt.Errorf("failed to request airdrop: %w", err)
+	}
+	ticker := time.NewTicker(250 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		statuses, err := client.GetSignatureStatuses(ctx, false, signature)
+		if err == nil && len(statuses.Value) == 1 && statuses.Value[0] != nil {
+			status := statuses.Value[0]
+			if status.Err != nil {
+				return fmt.Errorf("airdrop failed: %v", status.Err)
+			}
+			if status.ConfirmationStatus != rpc.ConfirmationStatusProcessed {
+				return nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("airdrop not confirmed: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop terminates the validator, killing it if it doesn't exit within 10
+// seconds, and removes its temporary ledger and fixtures
+func (v *Validator) Stop() error {
+	select {
+	case <-v.exited:
+	default:
+		timeout := time.After(10 * time.Second)
+		if err := v.cmd.Process.Signal(syscall.SIGTERM); err != nil {
+			timeout = time.After(0)
+		}
+		select {
+		case <-v.exited:
+		case <-timeout:
+			_ = v.cmd.Process.Kill()
+			<-v.exited
+		}
+	}
+	return v.cleanup()
+}
+
+func (v *Validator) cleanup() error {
+	var errs []error
+	for _, dir := range v.removeDirs {
+		errs = append(errs, os.RemoveAll(dir))
+	}
+	v.removeDirs = nil
+	return errors.Join(errs...)
+}
@@ -0,0 +1,105 @@
+package sol
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/ws"
+)
+
+// AccountHandler receives the data of an account each time it changes. It is
+// called from one goroutine per subscribed account, so it must be safe for
+// concurrent use
+type AccountHandler func(pubkey solana.PublicKey, slot uint64, data []byte)
+
+// Subscription is a running stream of updates
+type Subscription interface {
+	// Err returns a channel receiving the first stream failure
+	Err() <-chan error
+	// Unsubscribe stops the stream and waits for in-flight handlers to return.
+	// It must not be called from a handler
+	Unsubscribe()
+}
+
+// AccountSource streams account updates. Client implements it over its WebSocket
+// connection; the geyser package implements it over Yellowstone gRPC
+type AccountSource interface {
+	SubscribeAccounts(ctx context.Context, pubkeys []solana.PublicKey, handler AccountHandler) (Subscription, error)
+}
+
+// accountSubscription is a set of WebSocket account subscriptions sharing one handler
+type accountSubscription struct {
+	subs   []*ws.AccountSubscription
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	once   sync.Once
+	errCh  chan error
+}
+
+// SubscribeAccounts subscribes to processed updates of pubkeys over the WebSocket
+// connection and calls handler with the new account data. The subscription ends
+// when ctx is done, Unsubscribe is called or the connection fails, in which case
+// the error is delivered on Err
+func (c *Client) SubscribeAccounts(ctx context.Context, pubkeys []solana.PublicKey, handler AccountHandler) (Subscription, error) {
+	if c.WsClient == nil {
+		return nil, errors.New("client has no WebSocket connection")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s := &accountSubscription{
+		cancel: cancel,
+		errCh:  make(chan error, 1),
+	}
+	for _, pubkey := range pubkeys {
+		sub, err := c.WsClient.AccountSubscribeWithOpts(pubkey, rpc.CommitmentProcessed, solana.EncodingBase64)
+		if err != nil {
+			s.Unsubscribe()
+			return nil, fmt.Errorf("failed to subscribe to account %s: %w", pubkey.String(), err)
+		}
+		s.subs = append(s.subs, sub)
+	}
+
+	for i, sub := range s.subs {
+		s.wg.Add(1)
+		go func(pubkey solana.PublicKey, sub *ws.AccountSubscription) {
+			defer s.wg.Done()
+			for {
+				result, err := sub.Recv(ctx)
+				if err != nil {
+					if ctx.Err() == nil {
+						s.fail(fmt.Errorf("account %s subscription: %w", pubkey.String(), err))
+					}
+					return
+				}
+				handler(pubkey, result.Context.Slot, result.Value.Data.GetBinary())
+			}
+		}(pubkeys[i], sub)
+	}
+	return s, nil
+}
+
+func (s *accountSubscription) Err() <-chan error {
+	return s.errCh
+}
+
+func (s *accountSubscription) fail(err error) {
+	select {
+	case s.errCh <- err:
+	default:
+	}
+	go s.Unsubscribe()
+}
+
+func (s *accountSubscription) Unsubscribe() {
+	s.once.Do(func() {
+		s.cancel()
+		for _, sub := range s.subs {
+			sub.Unsubscribe()
+		}
+	})
+	s.wg.Wait()
+}
@@ -2,60 +2,248 @@ package sol
 
 import (
 	"context"
-	"log"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
 
 	"github.com/gagliardetto/solana-go"
-	associatedtokenaccount "github.com/gagliardetto/solana-go/programs/associated-token-account"
 	"github.com/gagliardetto/solana-go/rpc"
 )
 
-func (t *Client) SelectOrCreateSPLTokenAccount(ctx context.Context, privateKey solana.PrivateKey, tokenMint solana.PublicKey) (solana.PublicKey, error) {
-	user := privateKey.PublicKey()
-	acc, err := t.RpcClient.GetTokenAccountsByOwner(ctx, user,
-		&rpc.GetTokenAccountsConfig{Mint: tokenMint.ToPointer()},
+// TokenAccountStrategy selects the token account SelectTokenAccount returns
+type TokenAccountStrategy int
+
+const (
+	// TokenAccountPreferATA uses the associated token account when it exists, then
+	// the funded auxiliary account holding the most, and creates the ATA otherwise
+	TokenAccountPreferATA TokenAccountStrategy = iota
+	// TokenAccountATAOnly always uses the associated token account, creating it if missing
+	TokenAccountATAOnly
+	// TokenAccountLargestBalance uses the account holding the most, the ATA included,
+	// and creates the ATA when the owner has no funded account for the mint
+	TokenAccountLargestBalance
+)
+
+// Token account state byte and its value for frozen accounts
+const (
+	tokenAccountStateOffset = 108
+	tokenAccountStateFrozen = 2
+)
+
+// SelectOrCreateSPLTokenAccount returns the signer's token account for tokenMint
+// with TokenAccountPreferATA
+func (t *Client) SelectOrCreateSPLTokenAccount(ctx context.Context, signer Signer, tokenMint solana.PublicKey) (solana.PublicKey, error) {
+	return t.SelectTokenAccount(ctx, signer, tokenMint, TokenAccountPreferATA)
+}
+
+// SelectTokenAccount returns the signer's token account for tokenMint picked by
+// strategy. When the associated token account is picked but missing, it is created
+// in a separate transaction
+func (t *Client) SelectTokenAccount(ctx context.Context, signer Signer, tokenMint solana.PublicKey, strategy TokenAccountStrategy) (solana.PublicKey, error) {
+	user := signer.Pubkey()
+	ata, createInst, err := ResolveTokenAccount(ctx, t.RpcClient, user, tokenMint, solana.PublicKey{})
+	if err != nil {
+		return solana.PublicKey{}, err
+	}
+
+	if strategy != TokenAccountATAOnly && (createInst != nil || strategy == TokenAccountLargestBalance) {
+		best, err := t.largestTokenAccount(ctx, user, tokenMint, ata)
+		if err != nil {
+			return solana.PublicKey{}, err
+		}
+		if !best.IsZero() {
+			return best, nil
+		}
+	}
+	if createInst == nil {
+		return ata, nil
+	}
+
+	_, err = t.SendTx(ctx, solana.Hash{}, []Signer{signer}, []solana.Instruction{createInst}, false)
+	if err != nil {
+		t.Logger().Error("failed to create token account", "mint", tokenMint.String(), "err", err)
+		return solana.PublicKey{}, err
+	}
+	return ata, nil
+}
+
+// largestTokenAccount returns the unfrozen token account of owner holding the
+// most of mint, preferring ata on ties, or the zero key when none holds any
+func (t *Client) largestTokenAccount(ctx context.Context, owner, mint, ata solana.PublicKey) (solana.PublicKey, error) {
+	accounts, err := t.RpcClient.GetTokenAccountsByOwner(ctx, owner,
+		&rpc.GetTokenAccountsConfig{Mint: mint.ToPointer()},
 		&rpc.GetTokenAccountsOpts{
-			Encoding: "jsonParsed",
+			Commitment: rpc.CommitmentConfirmed,
+			Encoding:   solana.EncodingBase64,
 		},
 	)
 	if err != nil {
-		log.Printf("GetTokenAccountsByOwner err: %v", err)
-		return solana.PublicKey{}, err
+		return solana.PublicKey{}, fmt.Errorf("failed to get token accounts: %w", ClassifyError(err))
 	}
-	if len(acc.Value) > 0 {
-		return acc.Value[0].Pubkey, nil
+
+	var best solana.PublicKey
+	var bestAmount uint64
+	for _, account := range accounts.Value {
+		if account.Account.Data == nil {
+			continue
+		}
+		data := account.Account.Data.GetBinary()
+		amount, err := ParseTokenAmount(data)
+		if err != nil || amount == 0 || len(data) <= tokenAccountStateOffset || data[tokenAccountStateOffset] == tokenAccountStateFrozen {
+			continue
+		}
+		if amount > bestAmount || (amount == bestAmount && account.Pubkey.Equals(ata)) {
+			best, bestAmount = account.Pubkey, amount
+		}
 	}
+	return best, nil
+}
 
-	// Find ATA address (this will always return a valid PDA)
-	ataAddress, _, err := solana.FindAssociatedTokenAddress(user, tokenMint)
+// FindAssociatedTokenAddress derives the associated token account of owner for mint
+// under the given token program (SPL Token or Token-2022)
+func FindAssociatedTokenAddress(owner, mint, tokenProgram solana.PublicKey) (solana.PublicKey, error) {
+	ata, _, err := solana.FindProgramAddress([][]byte{
+		owner.Bytes(),
+		tokenProgram.Bytes(),
+		mint.Bytes(),
+	}, solana.SPLAssociatedTokenAccountProgramID)
 	if err != nil {
-		log.Printf("FindAssociatedTokenAddress err: %v", err)
-		return solana.PublicKey{}, err
+		return solana.PublicKey{}, fmt.Errorf("failed to find associated token address: %w", err)
 	}
-	instructions := make([]solana.Instruction, 0)
-	createAtaInst, err := associatedtokenaccount.NewCreateInstruction(
-		user,
-		user,
-		tokenMint,
-	).ValidateAndBuild()
+	return ata, nil
+}
+
+// NewCreateAssociatedTokenAccountIdempotentInstruction builds the associated token account
+// program's CreateIdempotent instruction, which succeeds even if the account already exists
+func NewCreateAssociatedTokenAccountIdempotentInstruction(payer, owner, mint, tokenProgram solana.PublicKey) (solana.Instruction, error) {
+	ata, err := FindAssociatedTokenAddress(owner, mint, tokenProgram)
 	if err != nil {
-		return solana.PublicKey{}, err
+		return nil, err
 	}
-	instructions = append(instructions, createAtaInst)
+	accounts := solana.AccountMetaSlice{
+		solana.NewAccountMeta(payer, true, true),
+		solana.NewAccountMeta(ata, true, false),
+		solana.NewAccountMeta(owner, false, false),
+		solana.NewAccountMeta(mint, false, false),
+		solana.NewAccountMeta(solana.SystemProgramID, false, false),
+		solana.NewAccountMeta(tokenProgram, false, false),
+	}
+	// Instruction index 1 is CreateIdempotent, index 0 is the plain Create
+	return solana.NewInstruction(solana.SPLAssociatedTokenAccountProgramID, accounts, []byte{1}), nil
+}
 
-	if len(instructions) == 0 {
-		return ataAddress, nil
-	} else {
-		latestBlockhash, err := t.RpcClient.GetLatestBlockhash(ctx, rpc.CommitmentConfirmed)
-		if err != nil {
-			log.Printf("Failed to get latest blockhash: %v", err)
-			return solana.PublicKey{}, err
+// NewTransferCheckedInstruction returns an SPL Token or Token-2022
+// TransferChecked of amount of mint from source to destination, signed by owner
+func NewTransferCheckedInstruction(tokenProgram, source, mint, destination, owner solana.PublicKey, amount uint64, decimals uint8) solana.Instruction {
+	data := make([]byte, 10)
+	data[0] = 12 // TransferChecked
+	binary.LittleEndian.PutUint64(data[1:9], amount)
+	data[9] = decimals
+	accounts := solana.AccountMetaSlice{
+		solana.Meta(source).WRITE(),
+		solana.Meta(mint),
+		solana.Meta(destination).WRITE(),
+		solana.Meta(owner).SIGNER(),
+	}
+	return solana.NewInstruction(tokenProgram, accounts, data)
+}
+
+// ResolveTokenAccount returns account when the caller already provided one. Otherwise it
+// falls back to the owner's associated token account for mint and, if that account does
+// not exist on chain yet, also returns an idempotent instruction creating it
+func ResolveTokenAccount(ctx context.Context, client *rpc.Client, owner, mint, account solana.PublicKey) (solana.PublicKey, solana.Instruction, error) {
+	if !account.IsZero() {
+		return account, nil, nil
+	}
+
+	splAta, err := FindAssociatedTokenAddress(owner, mint, solana.TokenProgramID)
+	if err != nil {
+		return solana.PublicKey{}, nil, err
+	}
+	token2022Ata, err := FindAssociatedTokenAddress(owner, mint, solana.Token2022ProgramID)
+	if err != nil {
+		return solana.PublicKey{}, nil, err
+	}
+
+	// Fetch the mint together with both candidate ATAs so a single round trip tells us
+	// which token program owns the mint and whether its ATA already exists
+	results, err := client.GetMultipleAccounts(ctx, mint, splAta, token2022Ata)
+	if err != nil {
+		return solana.PublicKey{}, nil, fmt.Errorf("failed to get token accounts: %w", ClassifyError(err))
+	}
+	if len(results.Value) != 3 || results.Value[0] == nil {
+		return solana.PublicKey{}, nil, fmt.Errorf("mint account %s: %w", mint.String(), ErrAccountNotFound)
+	}
+
+	tokenProgram := results.Value[0].Owner
+	mintPrograms.Store(mint, tokenProgram)
+	ata, existing := splAta, results.Value[1]
+	if tokenProgram.Equals(solana.Token2022ProgramID) {
+		ata, existing = token2022Ata, results.Value[2]
+	}
+	if existing != nil {
+		return ata, nil, nil
+	}
+
+	createInst, err := NewCreateAssociatedTokenAccountIdempotentInstruction(owner, owner, mint, tokenProgram)
+	if err != nil {
+		return solana.PublicKey{}, nil, err
+	}
+	return ata, createInst, nil
+}
+
+// ResolveUserTokenAccount is ResolveTokenAccount for swap builders. With skipCreate,
+// set for users known to hold their accounts, it derives the associated token account
+// under tokenProgram without checking that it exists, and never returns an instruction.
+// A zero tokenProgram is looked up with MintTokenProgram
+func ResolveUserTokenAccount(ctx context.Context, client *rpc.Client, owner, mint, account, tokenProgram solana.PublicKey, skipCreate bool) (solana.PublicKey, solana.Instruction, error) {
+	if !skipCreate {
+		return ResolveTokenAccount(ctx, client, owner, mint, account)
+	}
+	if !account.IsZero() {
+		return account, nil, nil
+	}
+	if tokenProgram.IsZero() {
+		var err error
+		if tokenProgram, err = MintTokenProgram(ctx, client, mint); err != nil {
+			return solana.PublicKey{}, nil, err
 		}
-		signers := []solana.PrivateKey{privateKey}
-		_, err = t.SendTx(ctx, latestBlockhash.Value.Blockhash, signers, instructions, false)
-		if err != nil {
-			log.Printf("Failed to send transaction: %v", err)
-			return solana.PublicKey{}, err
+	}
+	ata, err := FindAssociatedTokenAddress(owner, mint, tokenProgram)
+	if err != nil {
+		return solana.PublicKey{}, nil, err
+	}
+	return ata, nil, nil
+}
+
+// mintPrograms caches the token program owning each mint, which never changes
+var mintPrograms sync.Map
+
+// MintTokenProgram returns the token program owning mint, fetching it only the first
+// time a mint is seen by it or ResolveTokenAccount
+func MintTokenProgram(ctx context.Context, client *rpc.Client, mint solana.PublicKey) (solana.PublicKey, error) {
+	if program, ok := mintPrograms.Load(mint); ok {
+		return program.(solana.PublicKey), nil
+	}
+	result, err := client.GetAccountInfo(ctx, mint)
+	if err != nil {
+		if errors.Is(err, rpc.ErrNotFound) {
+			return solana.PublicKey{}, fmt.Errorf("mint account %s: %w", mint.String(), ErrAccountNotFound)
 		}
-		return ataAddress, nil
+		return solana.PublicKey{}, fmt.Errorf("failed to get mint account: %w", ClassifyError(err))
+	}
+	if result.Value == nil {
+		return solana.PublicKey{}, fmt.Errorf("mint account %s: %w", mint.String(), ErrAccountNotFound)
+	}
+	mintPrograms.Store(mint, result.Value.Owner)
+	return result.Value.Owner, nil
+}
+
+// ParseTokenAmount returns the amount held by an SPL or Token-2022 token account
+func ParseTokenAmount(data []byte) (uint64, error) {
+	if len(data) < 72 {
+		return 0, fmt.Errorf("invalid token account data length: %d", len(data))
 	}
+	return binary.LittleEndian.Uint64(data[64:72]), nil
 }
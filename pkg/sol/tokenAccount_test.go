@@ -0,0 +1,44 @@
+package sol_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/yimingWOW/solroute/pkg/sol"
+	"github.com/yimingWOW/solroute/pkg/sol/soltest"
+)
+
+func TestResolveUserTokenAccountSkipCreate(t *testing.T) {
+	node := soltest.NewRPC()
+	owner, mint := solana.NewWallet().PublicKey(), solana.NewWallet().PublicKey()
+	node.SetAccount(mint, soltest.Account{Owner: solana.Token2022ProgramID, Data: make([]byte, 82)})
+	want, err := sol.FindAssociatedTokenAddress(owner, mint, solana.Token2022ProgramID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An unknown token program is looked up once, then served from the cache
+	for i := 0; i < 2; i++ {
+		got, inst, err := sol.ResolveUserTokenAccount(context.Background(), node.Client(), owner, mint, solana.PublicKey{}, solana.PublicKey{}, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equals(want) || inst != nil {
+			t.Fatalf("got %s and instruction %v, want %s and none", got, inst, want)
+		}
+	}
+	if calls := len(node.Calls()); calls != 1 {
+		t.Fatalf("got %d RPC calls, want 1 for the mint", calls)
+	}
+
+	// A known token program needs no call
+	node.Reset()
+	got, _, err := sol.ResolveUserTokenAccount(context.Background(), node.Client(), owner, solana.NewWallet().PublicKey(), solana.PublicKey{}, solana.TokenProgramID, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.IsZero() || len(node.Calls()) != 0 {
+		t.Fatalf("got %s after %d RPC calls, want an account and none", got, len(node.Calls()))
+	}
+}
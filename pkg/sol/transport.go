@@ -0,0 +1,132 @@
+package sol
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/gagliardetto/solana-go/rpc/jsonrpc"
+	"github.com/klauspost/compress/gzhttp"
+)
+
+// Transport defaults, matching the ones rpc.New uses
+const (
+	DefaultMaxConnsPerHost     = 9
+	DefaultIdleConnTimeout     = 5 * time.Minute
+	DefaultKeepAlive           = 180 * time.Second
+	DefaultDialTimeout         = 5 * time.Minute
+	DefaultTLSHandshakeTimeout = 10 * time.Second
+	DefaultRequestTimeout      = 5 * time.Minute
+)
+
+// HTTPOptions tunes the HTTP transport of the RPC client. Zero fields keep the defaults
+type HTTPOptions struct {
+	// MaxConnsPerHost caps the connections to the RPC node, in use or idle.
+	// Defaults to DefaultMaxConnsPerHost; negative means unlimited
+	MaxConnsPerHost int
+	// MaxIdleConnsPerHost caps the idle connections kept for reuse. Defaults to MaxConnsPerHost
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout closes connections idle for longer. Defaults to DefaultIdleConnTimeout
+	IdleConnTimeout time.Duration
+	// KeepAlive is the TCP keep-alive period. Defaults to DefaultKeepAlive
+	KeepAlive time.Duration
+	// DialTimeout bounds connection establishment. Defaults to DefaultDialTimeout
+	DialTimeout time.Duration
+	// TLSHandshakeTimeout defaults to DefaultTLSHandshakeTimeout
+	TLSHandshakeTimeout time.Duration
+	// RequestTimeout bounds a whole request, reading the response included.
+	// Defaults to DefaultRequestTimeout
+	RequestTimeout time.Duration
+	// ProxyURL routes requests through a proxy, e.g. "http://proxy:3128".
+	// The HTTP_PROXY and HTTPS_PROXY environment variables are used when empty
+	ProxyURL string
+	// DisableHTTP2 sticks to HTTP/1.1, one request per connection at a time
+	DisableHTTP2 bool
+	// HTTP2PingInterval sends a ping on HTTP/2 connections idle for that long
+	// and drops them when unanswered, so a dead connection isn't kept in the
+	// pool. Disabled when zero
+	HTTP2PingInterval time.Duration
+	// Headers are added to every request, e.g. provider API keys
+	Headers map[string]string
+}
+
+// ClientOptions configures NewClientWithOptions
+type ClientOptions struct {
+	HTTP HTTPOptions
+}
+
+// NewClientWithOptions creates a Client whose RPC connections are tuned by opts
+func NewClientWithOptions(ctx context.Context, endpoint, wsEndpoint string, opts ClientOptions) (*Client, error) {
+	httpClient, err := NewHTTPClient(opts.HTTP)
+	if err != nil {
+		return nil, err
+	}
+	rpcClient := rpc.NewWithCustomRPCClient(jsonrpc.NewClientWithOpts(endpoint, &jsonrpc.RPCClientOpts{
+		HTTPClient:    httpClient,
+		CustomHeaders: opts.HTTP.Headers,
+	}))
+	return newClient(ctx, rpcClient, wsEndpoint)
+}
+
+// NewHTTPClient returns an HTTP client for RPC requests configured by opts,
+// with gzip responses enabled like rpc.New
+func NewHTTPClient(opts HTTPOptions) (*http.Client, error) {
+	maxConns := opts.MaxConnsPerHost
+	switch {
+	case maxConns == 0:
+		maxConns = DefaultMaxConnsPerHost
+	case maxConns < 0:
+		maxConns = 0
+	}
+	maxIdle := opts.MaxIdleConnsPerHost
+	if maxIdle == 0 {
+		maxIdle = maxConns
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if opts.ProxyURL != "" {
+		proxyURL, err := url.Parse(opts.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy url: %w", err)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	transport := &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   withDefault(opts.DialTimeout, DefaultDialTimeout),
+			KeepAlive: withDefault(opts.KeepAlive, DefaultKeepAlive),
+		}).DialContext,
+		MaxConnsPerHost:     maxConns,
+		MaxIdleConnsPerHost: maxIdle,
+		IdleConnTimeout:     withDefault(opts.IdleConnTimeout, DefaultIdleConnTimeout),
+		TLSHandshakeTimeout: withDefault(opts.TLSHandshakeTimeout, DefaultTLSHandshakeTimeout),
+		ForceAttemptHTTP2:   !opts.DisableHTTP2,
+	}
+	if opts.DisableHTTP2 {
+		// A non-nil empty map turns off the automatic HTTP/2 upgrade
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	} else if opts.HTTP2PingInterval > 0 {
+		transport.HTTP2 = &http.HTTP2Config{
+			SendPingTimeout: opts.HTTP2PingInterval,
+		}
+	}
+
+	return &http.Client{
+		Timeout:   withDefault(opts.RequestTimeout, DefaultRequestTimeout),
+		Transport: gzhttp.Transport(transport),
+	}, nil
+}
+
+func withDefault(d, fallback time.Duration) time.Duration {
+	if d <= 0 {
+		return fallback
+	}
+	return d
+}
@@ -2,114 +2,82 @@ package sol
 
 import (
 	"context"
-	"log"
+	"fmt"
 
 	"github.com/gagliardetto/solana-go"
-	associatedtokenaccount "github.com/gagliardetto/solana-go/programs/associated-token-account"
 	"github.com/gagliardetto/solana-go/programs/system"
 	"github.com/gagliardetto/solana-go/programs/token"
-	"github.com/gagliardetto/solana-go/rpc"
 )
 
-func (t *Client) CoverWsol(ctx context.Context, privateKey solana.PrivateKey, amount int64) error {
-	var signers []solana.PrivateKey
-	signers = append(signers, privateKey)
-
-	allInstrs := make([]solana.Instruction, 0)
-	user := privateKey.PublicKey()
-
-	acc, err := t.RpcClient.GetTokenAccountsByOwner(ctx, user,
-		&rpc.GetTokenAccountsConfig{Mint: WSOL.ToPointer()},
-		&rpc.GetTokenAccountsOpts{
-			Encoding: "jsonParsed",
-		},
-	)
+// WrapSolInstructions returns the instructions moving amount lamports into the
+// owner's WSOL associated token account, creating the account if needed
+func WrapSolInstructions(owner solana.PublicKey, amount uint64) ([]solana.Instruction, error) {
+	wsolAccount, err := FindAssociatedTokenAddress(owner, WSOL, solana.TokenProgramID)
 	if err != nil {
-		log.Printf("GetTokenAccountsByOwner err: %v", err)
-		return err
+		return nil, err
 	}
-	if len(acc.Value) == 0 {
-		createAtaInst, err := associatedtokenaccount.NewCreateInstruction(
-			user,
-			user,
-			WSOL,
-		).ValidateAndBuild()
-		if err != nil {
-			return err
-		}
-		allInstrs = append(allInstrs, createAtaInst)
+	createInst, err := NewCreateAssociatedTokenAccountIdempotentInstruction(owner, owner, WSOL, solana.TokenProgramID)
+	if err != nil {
+		return nil, err
 	}
-
-	wsolAccount, _, err := solana.FindAssociatedTokenAddress(user, WSOL)
+	transferInst, err := system.NewTransferInstruction(amount, owner, wsolAccount).ValidateAndBuild()
 	if err != nil {
-		log.Printf("FindAssociatedTokenAddress err: %v", err)
-		return err
+		return nil, fmt.Errorf("failed to build transfer instruction: %w", err)
 	}
-
-	transferInst, err := system.NewTransferInstruction(
-		uint64(amount),
-		user,
-		wsolAccount,
-	).ValidateAndBuild()
+	// SyncNative credits the transferred lamports to the token balance
+	syncNativeInst, err := token.NewSyncNativeInstruction(wsolAccount).ValidateAndBuild()
 	if err != nil {
-		log.Printf("NewTransferInstruction err: %v", err)
-		return err
+		return nil, fmt.Errorf("failed to build sync native instruction: %w", err)
 	}
-	allInstrs = append(allInstrs, transferInst)
+	return []solana.Instruction{createInst, transferInst, syncNativeInst}, nil
+}
 
-	// Add SyncNative instruction for WSOL
-	syncNativeInst, err := token.NewSyncNativeInstruction(
+// UnwrapSolInstruction returns the instruction closing the owner's WSOL associated
+// token account, which pays its whole WSOL balance and rent back to owner as SOL
+func UnwrapSolInstruction(owner solana.PublicKey) (solana.Instruction, error) {
+	wsolAccount, err := FindAssociatedTokenAddress(owner, WSOL, solana.TokenProgramID)
+	if err != nil {
+		return nil, err
+	}
+	closeInst, err := token.NewCloseAccountInstruction(
 		wsolAccount,
+		owner,
+		owner,
+		[]solana.PublicKey{},
 	).ValidateAndBuild()
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to build close account instruction: %w", err)
 	}
-	allInstrs = append(allInstrs, syncNativeInst)
+	return closeInst, nil
+}
 
-	recent, err := t.RpcClient.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
+// CoverWsol wraps amount lamports into the signer's WSOL account in a separate transaction
+func (t *Client) CoverWsol(ctx context.Context, signer Signer, amount int64) error {
+	insts, err := WrapSolInstructions(signer.Pubkey(), uint64(amount))
 	if err != nil {
-		log.Printf("GetLatestBlockhash err: %v\n", err)
+		t.Logger().Error("failed to build wrap instructions", "err", err)
 		return err
 	}
-	_, err = t.SendTx(ctx, recent.Value.Blockhash, signers, allInstrs, false)
+
+	_, err = t.SendTx(ctx, solana.Hash{}, []Signer{signer}, insts, false)
 	if err != nil {
-		log.Printf("Failed to send transaction: %v\n", err)
+		t.Logger().Error("failed to wrap sol", "amount", amount, "err", err)
 		return err
 	}
 	return nil
 }
 
-func (t *Client) CloseWsol(ctx context.Context, privateKey solana.PrivateKey) error {
-	var signers []solana.PrivateKey
-	signers = append(signers, privateKey)
-	user := privateKey.PublicKey()
-	insts := make([]solana.Instruction, 0)
-
-	wsolAccount, _, err := solana.FindAssociatedTokenAddress(user, WSOL)
-	if err != nil {
-		log.Printf("FindAssociatedTokenAddress err: %v", err)
-		return err
-	}
-	closeInst, err := token.NewCloseAccountInstruction(
-		wsolAccount,
-		user,
-		user,
-		[]solana.PublicKey{},
-	).ValidateAndBuild()
+// CloseWsol closes the signer's WSOL account in a separate transaction, unwrapping its balance
+func (t *Client) CloseWsol(ctx context.Context, signer Signer) error {
+	closeInst, err := UnwrapSolInstruction(signer.Pubkey())
 	if err != nil {
-		log.Printf("CloseAccountInstruction err: %v\n", err)
+		t.Logger().Error("failed to build unwrap instruction", "err", err)
 		return err
 	}
-	insts = append(insts, closeInst)
 
-	recent, err := t.RpcClient.GetLatestBlockhash(ctx, rpc.CommitmentFinalized)
-	if err != nil {
-		log.Printf("GetLatestBlockhash err: %v\n", err)
-		return err
-	}
-	_, err = t.SendTx(ctx, recent.Value.Blockhash, signers, insts, false)
+	_, err = t.SendTx(ctx, solana.Hash{}, []Signer{signer}, []solana.Instruction{closeInst}, false)
 	if err != nil {
-		log.Printf("Failed to send transaction: %v\n", err)
+		t.Logger().Error("failed to close wsol account", "err", err)
 		return err
 	}
 	return nil
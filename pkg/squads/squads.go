@@ -0,0 +1,251 @@
+// Package squads wraps swap instructions into Squads v4 vault transaction
+// proposals, so a multisig vault can execute a route once its members approve.
+//
+// Build the swap for the vault, e.g. with router.BuildSwapInstructions and the
+// vault as user, then send the proposal instructions signed by a member:
+//
+//	vault, _ := squads.VaultPDA(multisig, 0)
+//	insts, _ := router.BuildSwapInstructions(ctx, client.RpcClient, pool, vault, ...)
+//	proposal, _ := squads.NewVaultTransactionProposal(ctx, client.RpcClient, multisig, member.Pubkey(), insts, squads.ProposalOptions{Approve: true})
+//	client.SendTx(ctx, solana.Hash{}, []sol.Signer{member}, proposal.Instructions, false)
+//
+// Members then approve the proposal, and once the threshold is met anyone
+// executes it, e.g. from the Squads app
+package squads
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/yimingWOW/solroute/pkg/sol"
+	"github.com/yimingWOW/solroute/utils"
+)
+
+// ProgramID is the Squads v4 multisig program
+var ProgramID = solana.MustPublicKeyFromBase58("SQDS4ep65T869zMMBKyuUq6aD6EgTu8psMjkvj52pCf")
+
+// transactionIndexOffset is the position of transaction_index in a Multisig
+// account: discriminator, create_key, config_authority, threshold, time_lock
+const transactionIndexOffset = 8 + 32 + 32 + 2 + 4
+
+var (
+	vaultTransactionCreateDiscriminator = utils.GetDiscriminator("global", "vault_transaction_create")
+	proposalCreateDiscriminator         = utils.GetDiscriminator("global", "proposal_create")
+	proposalApproveDiscriminator        = utils.GetDiscriminator("global", "proposal_approve")
+)
+
+// VaultPDA returns the vault at index of multisig, the account swaps run as
+func VaultPDA(multisig solana.PublicKey, index uint8) (solana.PublicKey, error) {
+	pda, _, err := solana.FindProgramAddress([][]byte{
+		[]byte("multisig"), multisig.Bytes(), []byte("vault"), {index},
+	}, ProgramID)
+	return pda, err
+}
+
+// TransactionPDA returns the vault transaction account at transactionIndex
+func TransactionPDA(multisig solana.PublicKey, transactionIndex uint64) (solana.PublicKey, error) {
+	pda, _, err := solana.FindProgramAddress([][]byte{
+		[]byte("multisig"), multisig.Bytes(), []byte("transaction"), binary.LittleEndian.AppendUint64(nil, transactionIndex),
+	}, ProgramID)
+	return pda, err
+}
+
+// ProposalPDA returns the proposal account of the transaction at transactionIndex
+func ProposalPDA(multisig solana.PublicKey, transactionIndex uint64) (solana.PublicKey, error) {
+	pda, _, err := solana.FindProgramAddress([][]byte{
+		[]byte("multisig"), multisig.Bytes(), []byte("transaction"), binary.LittleEndian.AppendUint64(nil, transactionIndex), []byte("proposal"),
+	}, ProgramID)
+	return pda, err
+}
+
+// TransactionIndex returns the index of the last transaction created on multisig
+func TransactionIndex(ctx context.Context, client *rpc.Client, multisig solana.PublicKey) (uint64, error) {
+	account, err := client.GetAccountInfoWithOpts(ctx, multisig, &rpc.GetAccountInfoOpts{
+		Encoding:   solana.EncodingBase64,
+		Commitment: rpc.CommitmentConfirmed,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get multisig %s: %w", multisig.String(), sol.ClassifyError(err))
+	}
+	if !account.Value.Owner.Equals(ProgramID) {
+		return 0, fmt.Errorf("account %s is not a squads multisig", multisig.String())
+	}
+	data := account.Value.Data.GetBinary()
+	if len(data) < transactionIndexOffset+8 {
+		return 0, fmt.Errorf("multisig %s: account too short", multisig.String())
+	}
+	return binary.LittleEndian.Uint64(data[transactionIndexOffset:]), nil
+}
+
+// ProposalOptions configures NewVaultTransactionProposal
+type ProposalOptions struct {
+	// VaultIndex of the vault executing the instructions
+	VaultIndex uint8
+	// RentPayer funds the transaction and proposal accounts. Defaults to the creator
+	RentPayer solana.PublicKey
+	// Memo is recorded on the vault transaction
+	Memo string
+	// Draft creates the proposal as a draft, members can't vote until it is activated
+	Draft bool
+	// Approve adds the creator's approval
+	Approve bool
+}
+
+// Proposal is a vault transaction proposal ready to be sent
+type Proposal struct {
+	TransactionIndex uint64
+	Vault            solana.PublicKey
+	Transaction      solana.PublicKey
+	Proposal         solana.PublicKey
+	// Instructions create the vault transaction and its proposal, and approve
+	// it when requested. They must be signed by the creator and rent payer
+	Instructions []solana.Instruction
+}
+
+// NewVaultTransactionProposal builds the instructions proposing insts for
+// execution by a vault of multisig, created by creator, a member allowed to
+// initiate transactions. insts run with the vault as signer and must not
+// carry compute budget instructions of their own
+func NewVaultTransactionProposal(ctx context.Context, client *rpc.Client, multisig, creator solana.PublicKey, insts []solana.Instruction, opts ProposalOptions) (*Proposal, error) {
+	if len(insts) == 0 {
+		return nil, errors.New("no instruction to propose")
+	}
+	rentPayer := opts.RentPayer
+	if rentPayer.IsZero() {
+		rentPayer = creator
+	}
+	vault, err := VaultPDA(multisig, opts.VaultIndex)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive vault: %w", err)
+	}
+	lastIndex, err := TransactionIndex(ctx, client, multisig)
+	if err != nil {
+		return nil, err
+	}
+	index := lastIndex + 1
+	transaction, err := TransactionPDA(multisig, index)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive transaction: %w", err)
+	}
+	proposal, err := ProposalPDA(multisig, index)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive proposal: %w", err)
+	}
+	message, err := compileMessage(vault, insts)
+	if err != nil {
+		return nil, err
+	}
+
+	// VaultTransactionCreateArgs: vault_index, ephemeral_signers, transaction_message, memo
+	data := bytes.NewBuffer(append([]byte{}, vaultTransactionCreateDiscriminator...))
+	data.WriteByte(opts.VaultIndex)
+	data.WriteByte(0)
+	data.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(message))))
+	data.Write(message)
+	writeOptionalString(data, opts.Memo)
+	createTx := solana.NewInstruction(ProgramID, solana.AccountMetaSlice{
+		solana.Meta(multisig).WRITE(),
+		solana.Meta(transaction).WRITE(),
+		solana.Meta(creator).SIGNER(),
+		solana.Meta(rentPayer).WRITE().SIGNER(),
+		solana.Meta(solana.SystemProgramID),
+	}, data.Bytes())
+
+	// ProposalCreateArgs: transaction_index, draft
+	proposalData := append(append([]byte{}, proposalCreateDiscriminator...), binary.LittleEndian.AppendUint64(nil, index)...)
+	if opts.Draft {
+		proposalData = append(proposalData, 1)
+	} else {
+		proposalData = append(proposalData, 0)
+	}
+	createProposal := solana.NewInstruction(ProgramID, solana.AccountMetaSlice{
+		solana.Meta(multisig),
+		solana.Meta(proposal).WRITE(),
+		solana.Meta(creator).SIGNER(),
+		solana.Meta(rentPayer).WRITE().SIGNER(),
+		solana.Meta(solana.SystemProgramID),
+	}, proposalData)
+
+	result := &Proposal{
+		TransactionIndex: index,
+		Vault:            vault,
+		Transaction:      transaction,
+		Proposal:         proposal,
+		Instructions:     []solana.Instruction{createTx, createProposal},
+	}
+	if opts.Approve {
+		if opts.Draft {
+			return nil, errors.New("a draft proposal can't be approved")
+		}
+		result.Instructions = append(result.Instructions, ApproveInstruction(multisig, proposal, creator))
+	}
+	return result, nil
+}
+
+// ApproveInstruction returns the vote of member approving proposal
+func ApproveInstruction(multisig, proposal, member solana.PublicKey) solana.Instruction {
+	// ProposalVoteArgs: memo, left out
+	data := append(append([]byte{}, proposalApproveDiscriminator...), 0)
+	return solana.NewInstruction(ProgramID, solana.AccountMetaSlice{
+		solana.Meta(multisig),
+		solana.Meta(member).WRITE().SIGNER(),
+		solana.Meta(proposal).WRITE(),
+	}, data)
+}
+
+// compileMessage serializes insts as the Squads TransactionMessage executed by
+// vault: signer and writable counts, account keys and compiled instructions,
+// the lists prefixed by u8 lengths and instruction data by a u16 length
+func compileMessage(vault solana.PublicKey, insts []solana.Instruction) ([]byte, error) {
+	tx, err := solana.NewTransaction(insts, solana.Hash{}, solana.TransactionPayer(vault))
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile instructions: %w", err)
+	}
+	msg := tx.Message
+	keys := msg.AccountKeys
+	if len(keys) > 255 || len(msg.Instructions) > 255 {
+		return nil, errors.New("too many accounts or instructions for a vault transaction")
+	}
+	signers := int(msg.Header.NumRequiredSignatures)
+
+	buf := &bytes.Buffer{}
+	buf.WriteByte(byte(signers))
+	buf.WriteByte(byte(signers - int(msg.Header.NumReadonlySignedAccounts)))
+	buf.WriteByte(byte(len(keys) - signers - int(msg.Header.NumReadonlyUnsignedAccounts)))
+	buf.WriteByte(byte(len(keys)))
+	for _, key := range keys {
+		buf.Write(key.Bytes())
+	}
+	buf.WriteByte(byte(len(msg.Instructions)))
+	for _, inst := range msg.Instructions {
+		if len(inst.Accounts) > 255 || len(inst.Data) > 65535 {
+			return nil, errors.New("instruction too large for a vault transaction")
+		}
+		buf.WriteByte(byte(inst.ProgramIDIndex))
+		buf.WriteByte(byte(len(inst.Accounts)))
+		for _, account := range inst.Accounts {
+			buf.WriteByte(byte(account))
+		}
+		buf.Write(binary.LittleEndian.AppendUint16(nil, uint16(len(inst.Data))))
+		buf.Write(inst.Data)
+	}
+	// No address table lookups
+	buf.WriteByte(0)
+	return buf.Bytes(), nil
+}
+
+// writeOptionalString writes a Borsh Option<String>
+func writeOptionalString(buf *bytes.Buffer, s string) {
+	if s == "" {
+		buf.WriteByte(0)
+		return
+	}
+	buf.WriteByte(1)
+	buf.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(s))))
+	buf.WriteString(s)
+}
@@ -0,0 +1,128 @@
+package pkg
+
+import (
+	"fmt"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+)
+
+// SwapBuildOptions tunes the instructions of a swap. Pools act on the
+// account, price limit and referral options; router.BuildSwapInstructions
+// applies the WSOL, fee payer, platform fee and compute budget options around
+// the pool's instructions, the same way for every protocol. Pools built from
+// directly reject those with ErrRouterOption. Zero fields keep the defaults
+type SwapBuildOptions struct {
+	// SkipCreateAccounts leaves out the instructions creating the user's missing
+	// associated token accounts, for users known to hold them. Pools prepend
+	// them by default
+	SkipCreateAccounts bool
+	// WrapInput funds the WSOL account with exactly the input amount before the
+	// swap when the input mint is WSOL, so the user only needs native SOL
+	WrapInput bool
+	// CloseInputWsol closes the WSOL account after the swap when the input mint
+	// is WSOL, reclaiming its rent along with any WSOL left in it as SOL
+	CloseInputWsol bool
+	// UnwrapOutput closes the WSOL account after the swap when the output mint is
+	// WSOL, so the user receives native SOL. Any WSOL already held there is
+	// unwrapped too
+	UnwrapOutput bool
+	// SqrtPriceLimitX64 stops the swap at this Q64.64 square root price on
+	// concentrated liquidity pools, e.g. Raydium CLMM. Nil or zero swaps
+	// through any price; other pools ignore it
+	SqrtPriceLimitX64 math.Int
+	// FeePayer, when set and different from the user, funds the rent of the
+	// associated token accounts the swap creates instead of the user. Build the
+	// transaction with sol.Client.BuildUnsignedTx paid by FeePayer so it also
+	// pays the fees; the user then only signs as the token authority
+	FeePayer solana.PublicKey
+	// ReferralAccount receives the referral share of the swap fee on pools
+	// with one: a token account of the input mint for the Meteora DLMM host
+	// fee. Other pools ignore it
+	ReferralAccount solana.PublicKey
+	// InputAccount and OutputAccount are the user's token accounts of the input
+	// and output mint the swap debits and credits. Zero uses the user's
+	// associated token accounts, created when missing. The WSOL options and
+	// the balance check act on the associated token accounts regardless
+	InputAccount  solana.PublicKey
+	OutputAccount solana.PublicKey
+	// PlatformFee takes an integrator fee out of the output with a token
+	// transfer after the swap, on any pool
+	PlatformFee PlatformFee
+	// ComputeUnitLimit and ComputeUnitPrice, in micro-lamports, prepend compute
+	// budget instructions when either is set. sol.Client.SendTx then leaves the
+	// priority fee as set
+	ComputeUnitLimit uint32
+	ComputeUnitPrice uint64
+}
+
+// UserAccounts returns InputAccount and OutputAccount as the user's accounts
+// of the first and second mint of a pool swapping pair
+func (o SwapBuildOptions) UserAccounts(pair Pair) (base, quote solana.PublicKey) {
+	if pair.BaseIn {
+		return o.InputAccount, o.OutputAccount
+	}
+	return o.OutputAccount, o.InputAccount
+}
+
+// CheckPoolOptions returns an error matching ErrRouterOption if o sets an
+// option only router.BuildSwapInstructions applies, so a pool never builds a
+// swap without the wrap, fee or budget its caller asked for
+func (o SwapBuildOptions) CheckPoolOptions() error {
+	for _, option := range []struct {
+		name string
+		set  bool
+	}{
+		{"WrapInput", o.WrapInput},
+		{"CloseInputWsol", o.CloseInputWsol},
+		{"UnwrapOutput", o.UnwrapOutput},
+		{"FeePayer", !o.FeePayer.IsZero()},
+		{"PlatformFee", o.PlatformFee.Bps > 0},
+		{"ComputeUnitLimit", o.ComputeUnitLimit > 0},
+		{"ComputeUnitPrice", o.ComputeUnitPrice > 0},
+	} {
+		if option.set {
+			return fmt.Errorf("%w: %s", ErrRouterOption, option.name)
+		}
+	}
+	return nil
+}
+
+// PoolOptions returns o without the options router.BuildSwapInstructions
+// applies around the pool's instructions
+func (o SwapBuildOptions) PoolOptions() SwapBuildOptions {
+	o.WrapInput, o.CloseInputWsol, o.UnwrapOutput = false, false, false
+	o.FeePayer = solana.PublicKey{}
+	o.PlatformFee = PlatformFee{}
+	o.ComputeUnitLimit, o.ComputeUnitPrice = 0, 0
+	return o
+}
+
+// CheckSwapAmounts returns an error unless amountIn and minOut fit the u64
+// amounts of swap instructions. Pools check them before building any
+func CheckSwapAmounts(amountIn, minOut math.Int) error {
+	if amountIn.IsNil() || !amountIn.IsUint64() {
+		return fmt.Errorf("amount in %v is not a uint64", amountIn)
+	}
+	if minOut.IsNil() || !minOut.IsUint64() {
+		return fmt.Errorf("min out %v is not a uint64", minOut)
+	}
+	return nil
+}
+
+// PlatformFee is an integrator fee taken from the output of a swap
+type PlatformFee struct {
+	// Bps of the minimum output is taken: the amount the swap is guaranteed to
+	// return, so the transfer never fails for lack of output
+	Bps uint16
+	// Account is a token account of the output mint receiving the fee
+	Account solana.PublicKey
+}
+
+// Amount returns the fee taken from a swap returning at least minOut
+func (f PlatformFee) Amount(minOut math.Int) math.Int {
+	if f.Bps == 0 || minOut.IsNil() {
+		return math.ZeroInt()
+	}
+	return minOut.MulRaw(int64(f.Bps)).QuoRaw(10000)
+}
@@ -0,0 +1,65 @@
+package pkg_test
+
+import (
+	"errors"
+	"testing"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/yimingWOW/solroute/pkg"
+)
+
+func TestCheckSwapAmounts(t *testing.T) {
+	overflow := math.NewIntFromUint64(^uint64(0)).AddRaw(1)
+	tests := []struct {
+		name             string
+		amountIn, minOut math.Int
+		wantErr          bool
+	}{
+		{name: "u64 amounts", amountIn: math.NewIntFromUint64(^uint64(0)), minOut: math.ZeroInt()},
+		{name: "amount in over u64", amountIn: overflow, minOut: math.ZeroInt(), wantErr: true},
+		{name: "min out over u64", amountIn: math.NewInt(1), minOut: overflow, wantErr: true},
+		{name: "negative min out", amountIn: math.NewInt(1), minOut: math.NewInt(-1), wantErr: true},
+		{name: "nil amount in", amountIn: math.Int{}, minOut: math.ZeroInt(), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := pkg.CheckSwapAmounts(tt.amountIn, tt.minOut); (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %t", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckPoolOptions(t *testing.T) {
+	account := solana.NewWallet().PublicKey()
+	tests := []struct {
+		name    string
+		opts    pkg.SwapBuildOptions
+		wantErr error
+	}{
+		{name: "pool options", opts: pkg.SwapBuildOptions{SkipCreateAccounts: true, InputAccount: account, ReferralAccount: account}},
+		{name: "wrap input", opts: pkg.SwapBuildOptions{WrapInput: true}, wantErr: pkg.ErrRouterOption},
+		{name: "close input wsol", opts: pkg.SwapBuildOptions{CloseInputWsol: true}, wantErr: pkg.ErrRouterOption},
+		{name: "unwrap output", opts: pkg.SwapBuildOptions{UnwrapOutput: true}, wantErr: pkg.ErrRouterOption},
+		{name: "fee payer", opts: pkg.SwapBuildOptions{FeePayer: account}, wantErr: pkg.ErrRouterOption},
+		{name: "platform fee", opts: pkg.SwapBuildOptions{PlatformFee: pkg.PlatformFee{Bps: 50, Account: account}}, wantErr: pkg.ErrRouterOption},
+		{name: "compute unit limit", opts: pkg.SwapBuildOptions{ComputeUnitLimit: 200_000}, wantErr: pkg.ErrRouterOption},
+		{name: "compute unit price", opts: pkg.SwapBuildOptions{ComputeUnitPrice: 1000}, wantErr: pkg.ErrRouterOption},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.opts.CheckPoolOptions(); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			poolOpts := tt.opts.PoolOptions()
+			if err := poolOpts.CheckPoolOptions(); err != nil {
+				t.Fatalf("PoolOptions kept a router option: %v", err)
+			}
+			if poolOpts.SkipCreateAccounts != tt.opts.SkipCreateAccounts || poolOpts.InputAccount != tt.opts.InputAccount ||
+				poolOpts.ReferralAccount != tt.opts.ReferralAccount {
+				t.Fatalf("PoolOptions dropped a pool option: %+v", poolOpts)
+			}
+		})
+	}
+}
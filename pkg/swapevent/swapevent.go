@@ -0,0 +1,389 @@
+// Package swapevent decodes the swaps a landed transaction executed on the
+// supported DEX programs, whether called directly or through an aggregator,
+// from the token transfers each swap made. The realized amounts can then be
+// reconciled with the quotes the swaps were built from
+package swapevent
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/pool/meteora"
+	"github.com/yimingWOW/solroute/pkg/pool/pump"
+	"github.com/yimingWOW/solroute/pkg/pool/raydium"
+	"github.com/yimingWOW/solroute/pkg/sol"
+	"github.com/yimingWOW/solroute/utils"
+)
+
+// lamportsPerSignature is the base fee of each transaction signature
+const lamportsPerSignature = 5000
+
+// Swap is a swap executed by one instruction
+type Swap struct {
+	Protocol pkg.ProtocolName
+	Program  solana.PublicKey
+	Pool     solana.PublicKey
+	// Instruction is the index of the top-level instruction the swap ran in;
+	// Inner is its index among that instruction's inner instructions, -1 when
+	// the swap is the top-level instruction itself
+	Instruction int
+	Inner       int
+	// UserIn and UserOut are the token accounts the swap paid from and into
+	UserIn     solana.PublicKey
+	UserOut    solana.PublicKey
+	InputMint  solana.PublicKey
+	OutputMint solana.PublicKey
+	// AmountIn is everything that left UserIn, fees charged to the input
+	// included, and AmountOut everything UserOut received, in base units
+	AmountIn       math.Int
+	AmountOut      math.Int
+	InputDecimals  uint8
+	OutputDecimals uint8
+}
+
+// Price returns the realized price: whole output tokens per whole input token
+func (s Swap) Price() float64 {
+	if !s.AmountIn.IsPositive() {
+		return 0
+	}
+	in := new(big.Float).Quo(new(big.Float).SetInt(s.AmountIn.BigInt()), pow10(s.InputDecimals))
+	out := new(big.Float).Quo(new(big.Float).SetInt(s.AmountOut.BigInt()), pow10(s.OutputDecimals))
+	price, _ := new(big.Float).Quo(out, in).Float64()
+	return price
+}
+
+// DeviationBps returns how far AmountOut fell short of expected, in basis
+// points of expected. Negative when the swap returned more
+func (s Swap) DeviationBps(expected math.Int) int64 {
+	if !expected.IsPositive() {
+		return 0
+	}
+	return expected.Sub(s.AmountOut).MulRaw(10000).Quo(expected).Int64()
+}
+
+// Execution is the outcome of a landed transaction
+type Execution struct {
+	Signature solana.Signature
+	Slot      uint64
+	BlockTime time.Time
+	// Fee is the lamports the transaction paid, PriorityFee the part above
+	// the signature fees
+	Fee          uint64
+	PriorityFee  uint64
+	ComputeUnits uint64
+	// Err is the error of a failed transaction, which executed no swaps
+	Err   error
+	Swaps []Swap
+}
+
+// Parse fetches the transaction of signature at the confirmed commitment and
+// decodes its swaps
+func Parse(ctx context.Context, client *rpc.Client, signature solana.Signature) (*Execution, error) {
+	result, err := client.GetTransaction(ctx, signature, &rpc.GetTransactionOpts{
+		Commitment:                     rpc.CommitmentConfirmed,
+		MaxSupportedTransactionVersion: &rpc.MaxSupportedTransactionVersion0,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction %s: %w", signature.String(), sol.ClassifyError(err))
+	}
+	return ParseTransaction(result)
+}
+
+// ParseTransaction decodes the swaps of a getTransaction result, which must
+// be binary encoded and carry its meta
+func ParseTransaction(result *rpc.GetTransactionResult) (*Execution, error) {
+	if result.Meta == nil || result.Transaction == nil {
+		return nil, fmt.Errorf("transaction has no meta")
+	}
+	tx, err := result.Transaction.GetTransaction()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode transaction: %w", err)
+	}
+	meta := result.Meta
+	if len(tx.Signatures) == 0 {
+		return nil, fmt.Errorf("transaction has no signatures")
+	}
+
+	exec := &Execution{
+		Signature: tx.Signatures[0],
+		Slot:      result.Slot,
+		Fee:       meta.Fee,
+	}
+	if result.BlockTime != nil {
+		exec.BlockTime = result.BlockTime.Time()
+	}
+	if baseFee := lamportsPerSignature * uint64(len(tx.Signatures)); meta.Fee > baseFee {
+		exec.PriorityFee = meta.Fee - baseFee
+	}
+	if meta.ComputeUnitsConsumed != nil {
+		exec.ComputeUnits = *meta.ComputeUnitsConsumed
+	}
+	if meta.Err != nil {
+		exec.Err = fmt.Errorf("transaction failed: %v", meta.Err)
+		return exec, nil
+	}
+
+	// Loaded addresses follow the static keys, writable ones first
+	keys := append(solana.PublicKeySlice{}, tx.Message.AccountKeys...)
+	keys = append(keys, meta.LoadedAddresses.Writable...)
+	keys = append(keys, meta.LoadedAddresses.ReadOnly...)
+	p := &parser{keys: keys, balances: tokenBalances(meta)}
+
+	inner := make(map[int][]solana.CompiledInstruction, len(meta.InnerInstructions))
+	for _, set := range meta.InnerInstructions {
+		inner[int(set.Index)] = set.Instructions
+	}
+	for i, inst := range tx.Message.Instructions {
+		p.current = nil
+		p.instruction(inst, i, -1)
+		for j, innerInst := range inner[i] {
+			p.instruction(innerInst, i, j)
+		}
+		exec.Swaps = append(exec.Swaps, p.done()...)
+	}
+	return exec, nil
+}
+
+// tokenBalance is the mint and decimals of a token account in the transaction
+type tokenBalance struct {
+	mint     solana.PublicKey
+	decimals uint8
+}
+
+func tokenBalances(meta *rpc.TransactionMeta) map[uint16]tokenBalance {
+	balances := make(map[uint16]tokenBalance)
+	for _, list := range [][]rpc.TokenBalance{meta.PreTokenBalances, meta.PostTokenBalances} {
+		for _, balance := range list {
+			entry := tokenBalance{mint: balance.Mint}
+			if balance.UiTokenAmount != nil {
+				entry.decimals = balance.UiTokenAmount.Decimals
+			}
+			balances[balance.AccountIndex] = entry
+		}
+	}
+	return balances
+}
+
+// transfer is a token program transfer made during a swap
+type transfer struct {
+	source, destination uint16
+	amount              uint64
+}
+
+// pendingSwap collects the transfers following a swap instruction
+type pendingSwap struct {
+	swap      Swap
+	accounts  [2]uint16 // the user's two token accounts
+	transfers []transfer
+}
+
+type parser struct {
+	keys     solana.PublicKeySlice
+	balances map[uint16]tokenBalance
+	current  *pendingSwap
+	pending  []*pendingSwap
+}
+
+// instruction handles one instruction: a swap starts collecting transfers, and
+// token transfers are attributed to the latest swap. Without stack heights a
+// transfer an aggregator makes after a swap returns is attributed to it too,
+// which only matters if it moves tokens through the swap's user accounts
+func (p *parser) instruction(inst solana.CompiledInstruction, index, inner int) {
+	if int(inst.ProgramIDIndex) >= len(p.keys) {
+		return
+	}
+	program := p.keys[inst.ProgramIDIndex]
+	if layout, ok := swapLayouts[program]; ok {
+		if swap := layout.decode(p.keys, inst); swap != nil {
+			swap.swap.Program = program
+			swap.swap.Instruction = index
+			swap.swap.Inner = inner
+			p.current = swap
+			p.pending = append(p.pending, swap)
+		}
+		return
+	}
+	if p.current == nil || !program.Equals(solana.TokenProgramID) && !program.Equals(solana.Token2022ProgramID) {
+		return
+	}
+	if t, ok := decodeTransfer(inst); ok {
+		p.current.transfers = append(p.current.transfers, t)
+		if mint, ok := transferMint(inst); ok && int(mint) < len(p.keys) {
+			for _, account := range []uint16{t.source, t.destination} {
+				if _, known := p.balances[account]; !known {
+					p.balances[account] = tokenBalance{mint: p.keys[mint], decimals: inst.Data[9]}
+				}
+			}
+		}
+	}
+}
+
+// done returns the swaps collected since the last call that moved tokens both
+// out of and into the user's accounts
+func (p *parser) done() []Swap {
+	var swaps []Swap
+	for _, pending := range p.pending {
+		in, out := math.ZeroInt(), math.ZeroInt()
+		var from, to uint16
+		var paid, received bool
+		for _, t := range pending.transfers {
+			for _, account := range pending.accounts {
+				if t.source == account {
+					from, paid = account, true
+				}
+				if t.destination == account {
+					to, received = account, true
+				}
+			}
+		}
+		if !paid || !received || from == to {
+			continue
+		}
+		for _, t := range pending.transfers {
+			if t.source == from {
+				in = in.Add(math.NewIntFromUint64(t.amount))
+			}
+			if t.destination == to {
+				out = out.Add(math.NewIntFromUint64(t.amount))
+			}
+		}
+		swap := pending.swap
+		swap.UserIn, swap.UserOut = p.keys[from], p.keys[to]
+		swap.AmountIn, swap.AmountOut = in, out
+		if balance, ok := p.balances[from]; ok {
+			swap.InputMint, swap.InputDecimals = balance.mint, balance.decimals
+		}
+		if balance, ok := p.balances[to]; ok {
+			swap.OutputMint, swap.OutputDecimals = balance.mint, balance.decimals
+		}
+		swaps = append(swaps, swap)
+	}
+	p.pending = nil
+	return swaps
+}
+
+// Token program instructions moving tokens
+const (
+	tokenTransfer        = 3
+	tokenTransferChecked = 12
+)
+
+func decodeTransfer(inst solana.CompiledInstruction) (transfer, bool) {
+	if len(inst.Data) < 9 {
+		return transfer{}, false
+	}
+	amount := binary.LittleEndian.Uint64(inst.Data[1:9])
+	switch {
+	case inst.Data[0] == tokenTransfer && len(inst.Accounts) >= 3:
+		return transfer{source: inst.Accounts[0], destination: inst.Accounts[1], amount: amount}, true
+	case inst.Data[0] == tokenTransferChecked && len(inst.Data) >= 10 && len(inst.Accounts) >= 4:
+		return transfer{source: inst.Accounts[0], destination: inst.Accounts[2], amount: amount}, true
+	}
+	return transfer{}, false
+}
+
+// transferMint returns the mint account index of a TransferChecked
+func transferMint(inst solana.CompiledInstruction) (uint16, bool) {
+	if len(inst.Data) >= 10 && inst.Data[0] == tokenTransferChecked && len(inst.Accounts) >= 4 {
+		return inst.Accounts[1], true
+	}
+	return 0, false
+}
+
+// swapLayout locates the pool and the user's token accounts in the swap
+// instructions of a program. Negative indexes count from the last account
+type swapLayout struct {
+	protocol      pkg.ProtocolName
+	discriminator func(data []byte) bool
+	pool          int
+	user          [2]int
+}
+
+func (l swapLayout) decode(keys solana.PublicKeySlice, inst solana.CompiledInstruction) *pendingSwap {
+	if !l.discriminator(inst.Data) {
+		return nil
+	}
+	index := func(i int) (uint16, bool) {
+		if i < 0 {
+			i += len(inst.Accounts)
+		}
+		if i < 0 || i >= len(inst.Accounts) || int(inst.Accounts[i]) >= len(keys) {
+			return 0, false
+		}
+		return inst.Accounts[i], true
+	}
+	pool, ok := index(l.pool)
+	if !ok {
+		return nil
+	}
+	swap := &pendingSwap{swap: Swap{Protocol: l.protocol, Pool: keys[pool]}}
+	for i, position := range l.user {
+		if swap.accounts[i], ok = index(position); !ok {
+			return nil
+		}
+	}
+	return swap
+}
+
+// anchor matches any of the Anchor instructions named
+func anchor(names ...string) func(data []byte) bool {
+	discriminators := make([][]byte, len(names))
+	for i, name := range names {
+		discriminators[i] = utils.GetDiscriminator("global", name)
+	}
+	return func(data []byte) bool {
+		for _, discriminator := range discriminators {
+			if bytes.HasPrefix(data, discriminator) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// swapLayouts covers the swap instructions of the supported programs
+var swapLayouts = map[solana.PublicKey]swapLayout{
+	raydium.RAYDIUM_AMM_PROGRAM_ID: {
+		protocol: pkg.ProtocolNameRaydiumAmm,
+		// swap_base_in and swap_base_out, with or without the target orders account
+		discriminator: func(data []byte) bool { return len(data) > 0 && (data[0] == 9 || data[0] == 11) },
+		pool:          1,
+		user:          [2]int{-3, -2},
+	},
+	raydium.RAYDIUM_CPMM_PROGRAM_ID: {
+		protocol:      pkg.ProtocolNameRaydiumCpmm,
+		discriminator: anchor("swap_base_input", "swap_base_output"),
+		pool:          3,
+		user:          [2]int{4, 5},
+	},
+	raydium.RAYDIUM_CLMM_PROGRAM_ID: {
+		protocol:      pkg.ProtocolNameRaydiumClmm,
+		discriminator: anchor("swap", "swap_v2"),
+		pool:          2,
+		user:          [2]int{3, 4},
+	},
+	meteora.MeteoraProgramID: {
+		protocol:      pkg.ProtocolNameMeteoraDlmm,
+		discriminator: anchor("swap", "swap_exact_out", "swap_with_price_impact", "swap2", "swap_exact_out2", "swap_with_price_impact2"),
+		pool:          0,
+		user:          [2]int{4, 5},
+	},
+	pump.PumpSwapProgramID: {
+		protocol:      pkg.ProtocolNamePumpAmm,
+		discriminator: anchor("buy", "sell", "buy_exact_quote_in"),
+		pool:          0,
+		user:          [2]int{5, 6},
+	},
+}
+
+func pow10(decimals uint8) *big.Float {
+	return new(big.Float).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil))
+}
@@ -0,0 +1,180 @@
+package tokens
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// ExtensionType identifies a Token-2022 mint or account extension
+type ExtensionType uint16
+
+const (
+	ExtensionUninitialized ExtensionType = iota
+	ExtensionTransferFeeConfig
+	ExtensionTransferFeeAmount
+	ExtensionMintCloseAuthority
+	ExtensionConfidentialTransferMint
+	ExtensionConfidentialTransferAccount
+	ExtensionDefaultAccountState
+	ExtensionImmutableOwner
+	ExtensionMemoTransfer
+	ExtensionNonTransferable
+	ExtensionInterestBearingConfig
+	ExtensionCpiGuard
+	ExtensionPermanentDelegate
+	ExtensionNonTransferableAccount
+	ExtensionTransferHook
+	ExtensionTransferHookAccount
+	ExtensionConfidentialTransferFeeConfig
+	ExtensionConfidentialTransferFeeAmount
+	ExtensionMetadataPointer
+	ExtensionTokenMetadata
+	ExtensionGroupPointer
+	ExtensionTokenGroup
+	ExtensionGroupMemberPointer
+	ExtensionTokenGroupMember
+	ExtensionConfidentialMintBurn
+	ExtensionScaledUiAmount
+	ExtensionPausable
+	ExtensionPausableAccount
+)
+
+var extensionNames = map[ExtensionType]string{
+	ExtensionUninitialized:                 "Uninitialized",
+	ExtensionTransferFeeConfig:             "TransferFeeConfig",
+	ExtensionTransferFeeAmount:             "TransferFeeAmount",
+	ExtensionMintCloseAuthority:            "MintCloseAuthority",
+	ExtensionConfidentialTransferMint:      "ConfidentialTransferMint",
+	ExtensionConfidentialTransferAccount:   "ConfidentialTransferAccount",
+	ExtensionDefaultAccountState:           "DefaultAccountState",
+	ExtensionImmutableOwner:                "ImmutableOwner",
+	ExtensionMemoTransfer:                  "MemoTransfer",
+	ExtensionNonTransferable:               "NonTransferable",
+	ExtensionInterestBearingConfig:         "InterestBearingConfig",
+	ExtensionCpiGuard:                      "CpiGuard",
+	ExtensionPermanentDelegate:             "PermanentDelegate",
+	ExtensionNonTransferableAccount:        "NonTransferableAccount",
+	ExtensionTransferHook:                  "TransferHook",
+	ExtensionTransferHookAccount:           "TransferHookAccount",
+	ExtensionConfidentialTransferFeeConfig: "ConfidentialTransferFeeConfig",
+	ExtensionConfidentialTransferFeeAmount: "ConfidentialTransferFeeAmount",
+	ExtensionMetadataPointer:               "MetadataPointer",
+	ExtensionTokenMetadata:                 "TokenMetadata",
+	ExtensionGroupPointer:                  "GroupPointer",
+	ExtensionTokenGroup:                    "TokenGroup",
+	ExtensionGroupMemberPointer:            "GroupMemberPointer",
+	ExtensionTokenGroupMember:              "TokenGroupMember",
+	ExtensionConfidentialMintBurn:          "ConfidentialMintBurn",
+	ExtensionScaledUiAmount:                "ScaledUiAmount",
+	ExtensionPausable:                      "Pausable",
+	ExtensionPausableAccount:               "PausableAccount",
+}
+
+func (t ExtensionType) String() string {
+	if name, ok := extensionNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("ExtensionType(%d)", uint16(t))
+}
+
+// Extension is a raw Token-2022 extension entry
+type Extension struct {
+	Type ExtensionType
+	Data []byte
+}
+
+// TransferFee is a Token-2022 transfer fee schedule entry
+type TransferFee struct {
+	Epoch       uint64 // first epoch the fee applies to
+	MaximumFee  uint64
+	BasisPoints uint16
+}
+
+// Fee returns the fee withheld on a transfer of amount: the basis points of
+// amount rounded up, capped at MaximumFee
+func (f TransferFee) Fee(amount uint64) uint64 {
+	if f.BasisPoints == 0 || amount == 0 {
+		return 0
+	}
+	hi, lo := bits.Mul64(amount, uint64(f.BasisPoints))
+	lo, carry := bits.Add64(lo, 9999, 0)
+	hi += carry
+	if hi >= 10000 {
+		return f.MaximumFee
+	}
+	fee, _ := bits.Div64(hi, lo, 10000)
+	return min(fee, f.MaximumFee)
+}
+
+// TransferFeeConfig is the ExtensionTransferFeeConfig of a mint
+type TransferFeeConfig struct {
+	ConfigAuthority   solana.PublicKey
+	WithdrawAuthority solana.PublicKey
+	WithheldAmount    uint64
+	Older             TransferFee
+	Newer             TransferFee
+}
+
+// At returns the fee in effect at epoch
+func (c TransferFeeConfig) At(epoch uint64) TransferFee {
+	if epoch >= c.Newer.Epoch {
+		return c.Newer
+	}
+	return c.Older
+}
+
+// transferFeeConfigSize is the length of an ExtensionTransferFeeConfig entry
+const transferFeeConfigSize = 32 + 32 + 8 + 2*18
+
+func parseTransferFeeConfig(data []byte) (TransferFeeConfig, error) {
+	if len(data) < transferFeeConfigSize {
+		return TransferFeeConfig{}, fmt.Errorf("transfer fee config too short: %d bytes", len(data))
+	}
+	fee := func(b []byte) TransferFee {
+		return TransferFee{
+			Epoch:       binary.LittleEndian.Uint64(b[0:8]),
+			MaximumFee:  binary.LittleEndian.Uint64(b[8:16]),
+			BasisPoints: binary.LittleEndian.Uint16(b[16:18]),
+		}
+	}
+	return TransferFeeConfig{
+		ConfigAuthority:   solana.PublicKeyFromBytes(data[0:32]),
+		WithdrawAuthority: solana.PublicKeyFromBytes(data[32:64]),
+		WithheldAmount:    binary.LittleEndian.Uint64(data[64:72]),
+		Older:             fee(data[72:90]),
+		Newer:             fee(data[90:108]),
+	}, nil
+}
+
+// parseExtensions reads the TLV entries following the base mint of a Token-2022
+// mint, which is padded to the size of a token account and tagged with its type
+func parseExtensions(data []byte) ([]Extension, error) {
+	const (
+		extensionsStart = 165 + 1 // padded base, account type
+		accountTypeMint = 1
+	)
+	if len(data) <= mintSize {
+		return nil, nil
+	}
+	if len(data) < extensionsStart || data[165] != accountTypeMint {
+		return nil, fmt.Errorf("invalid token-2022 mint of %d bytes", len(data))
+	}
+	var extensions []Extension
+	for offset := extensionsStart; offset+4 <= len(data); {
+		typ := ExtensionType(binary.LittleEndian.Uint16(data[offset:]))
+		length := int(binary.LittleEndian.Uint16(data[offset+2:]))
+		offset += 4
+		if typ == ExtensionUninitialized {
+			break
+		}
+		if offset+length > len(data) {
+			return nil, fmt.Errorf("extension %s overruns the account", typ)
+		}
+		extensions = append(extensions, Extension{Type: typ, Data: data[offset : offset+length]})
+		offset += length
+	}
+	return extensions, nil
+}
@@ -0,0 +1,137 @@
+package tokens
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/gagliardetto/solana-go/rpc"
+	"github.com/yimingWOW/solroute/pkg/sol"
+)
+
+// maxMultipleAccounts is the getMultipleAccounts limit of a single request
+const maxMultipleAccounts = 100
+
+// Resolver fetches mints and caches them. Mint decimals and programs don't
+// change, so entries are kept for the resolver's lifetime; use Forget to drop
+// one whose metadata or extensions were updated
+type Resolver struct {
+	client *rpc.Client
+
+	mu     sync.RWMutex
+	tokens map[solana.PublicKey]*Token
+}
+
+// NewResolver creates a Resolver reading mints through client
+func NewResolver(client *rpc.Client) *Resolver {
+	return &Resolver{
+		client: client,
+		tokens: make(map[solana.PublicKey]*Token),
+	}
+}
+
+// Resolve returns the token of mint, fetching it on first use
+func (r *Resolver) Resolve(ctx context.Context, mint solana.PublicKey) (*Token, error) {
+	tokens, err := r.ResolveMany(ctx, mint)
+	if err != nil {
+		return nil, err
+	}
+	return tokens[0], nil
+}
+
+// ResolveMany returns the tokens of mints in order, fetching the ones that
+// aren't cached together with their Metaplex metadata
+func (r *Resolver) ResolveMany(ctx context.Context, mints ...solana.PublicKey) ([]*Token, error) {
+	var missing []solana.PublicKey
+	r.mu.RLock()
+	for _, mint := range mints {
+		if _, ok := r.tokens[mint]; !ok {
+			missing = append(missing, mint)
+		}
+	}
+	r.mu.RUnlock()
+
+	// Each mint is fetched along with its metadata account
+	for start := 0; start < len(missing); start += maxMultipleAccounts / 2 {
+		batch := missing[start:min(start+maxMultipleAccounts/2, len(missing))]
+		if err := r.fetch(ctx, batch); err != nil {
+			return nil, err
+		}
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tokens := make([]*Token, len(mints))
+	for i, mint := range mints {
+		tokens[i] = r.tokens[mint]
+	}
+	return tokens, nil
+}
+
+// Decimals returns the decimals of mint
+func (r *Resolver) Decimals(ctx context.Context, mint solana.PublicKey) (uint8, error) {
+	token, err := r.Resolve(ctx, mint)
+	if err != nil {
+		return 0, err
+	}
+	return token.Decimals, nil
+}
+
+// Put caches token, e.g. a well-known mint, without fetching it
+func (r *Resolver) Put(token *Token) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tokens[token.Mint] = token
+}
+
+// Forget drops mint from the cache
+func (r *Resolver) Forget(mint solana.PublicKey) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.tokens, mint)
+}
+
+func (r *Resolver) fetch(ctx context.Context, mints []solana.PublicKey) error {
+	accounts := make([]solana.PublicKey, 0, 2*len(mints))
+	for _, mint := range mints {
+		metadata, err := MetadataPDA(mint)
+		if err != nil {
+			return fmt.Errorf("failed to derive metadata address of %s: %w", mint.String(), err)
+		}
+		accounts = append(accounts, mint, metadata)
+	}
+	results, err := r.client.GetMultipleAccounts(ctx, accounts...)
+	if err != nil {
+		return fmt.Errorf("failed to get mints: %w", sol.ClassifyError(err))
+	}
+	if len(results.Value) != len(accounts) {
+		return fmt.Errorf("got %d accounts, expected %d", len(results.Value), len(accounts))
+	}
+
+	tokens := make([]*Token, 0, len(mints))
+	for i, mint := range mints {
+		account := results.Value[2*i]
+		if account == nil {
+			return fmt.Errorf("mint %s not found", mint.String())
+		}
+		token, err := ParseMint(mint, account.Owner, account.Data.GetBinary())
+		if err != nil {
+			return err
+		}
+		// The Token-2022 metadata extension takes precedence over Metaplex
+		if metadata := results.Value[2*i+1]; token.Symbol == "" && metadata != nil && metadata.Owner.Equals(MetaplexProgramID) {
+			if name, symbol, uri, err := parseMetaplexMetadata(metadata.Data.GetBinary()); err == nil {
+				token.Name, token.Symbol, token.URI = name, symbol, uri
+			}
+		}
+		tokens = append(tokens, token)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, token := range tokens {
+		r.tokens[token.Mint] = token
+	}
+	return nil
+}
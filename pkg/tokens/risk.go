@@ -0,0 +1,149 @@
+package tokens
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gagliardetto/solana-go"
+)
+
+// Risk is a property of a mint that lets someone other than the holder
+// inflate, seize, freeze or block transfers of the token
+type Risk string
+
+const (
+	// RiskMintAuthority can mint more supply
+	RiskMintAuthority Risk = "mint_authority"
+	// RiskFreezeAuthority can freeze token accounts, blocking sells
+	RiskFreezeAuthority Risk = "freeze_authority"
+	// RiskPermanentDelegate can transfer or burn from every token account
+	RiskPermanentDelegate Risk = "permanent_delegate"
+	// RiskTransferHook runs a program on every transfer, which may reject it
+	RiskTransferHook Risk = "transfer_hook"
+	// RiskTransferFee withholds a fee on every transfer
+	RiskTransferFee Risk = "transfer_fee"
+	// RiskDefaultFrozen creates token accounts frozen
+	RiskDefaultFrozen Risk = "default_frozen"
+	// RiskNonTransferable can't be transferred at all
+	RiskNonTransferable Risk = "non_transferable"
+	// RiskPausable can have all transfers paused
+	RiskPausable Risk = "pausable"
+	// RiskMintCloseAuthority can close the mint once its supply is zero
+	RiskMintCloseAuthority Risk = "mint_close_authority"
+)
+
+// accountStateFrozen is the frozen AccountState of the DefaultAccountState extension
+const accountStateFrozen = 2
+
+// TokenRisk is what the mint account of a token lets its authorities do
+type TokenRisk struct {
+	Mint            solana.PublicKey
+	MintAuthority   *solana.PublicKey
+	FreezeAuthority *solana.PublicKey
+	// PermanentDelegate, TransferHookProgram and MintCloseAuthority come from
+	// the Token-2022 extensions of the same name
+	PermanentDelegate   *solana.PublicKey
+	TransferHookProgram *solana.PublicKey
+	MintCloseAuthority  *solana.PublicKey
+	// TransferFeeBps is the newest transfer fee, MaxTransferFee its cap
+	TransferFeeBps uint16
+	MaxTransferFee uint64
+	DefaultFrozen  bool
+	Paused         bool
+	// Risks lists the risks found, in the order of the constants
+	Risks []Risk
+}
+
+// Has reports whether the token carries risk
+func (r *TokenRisk) Has(risk Risk) bool {
+	for _, found := range r.Risks {
+		if found == risk {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *TokenRisk) String() string {
+	if len(r.Risks) == 0 {
+		return r.Mint.String() + ": no risk found"
+	}
+	risks := make([]string, len(r.Risks))
+	for i, risk := range r.Risks {
+		risks[i] = string(risk)
+	}
+	return r.Mint.String() + ": " + strings.Join(risks, ", ")
+}
+
+// Risk analyzes the authorities and Token-2022 extensions of the mint
+func (t *Token) Risk() *TokenRisk {
+	risk := &TokenRisk{Mint: t.Mint, MintAuthority: t.MintAuthority, FreezeAuthority: t.FreezeAuthority}
+	for _, ext := range t.Extensions {
+		switch ext.Type {
+		case ExtensionPermanentDelegate:
+			risk.PermanentDelegate = optionalNonZeroKey(ext.Data, 0)
+		case ExtensionTransferHook:
+			// authority, then the hook program
+			risk.TransferHookProgram = optionalNonZeroKey(ext.Data, 32)
+		case ExtensionMintCloseAuthority:
+			risk.MintCloseAuthority = optionalNonZeroKey(ext.Data, 0)
+		case ExtensionTransferFeeConfig:
+			if config, err := parseTransferFeeConfig(ext.Data); err == nil {
+				risk.TransferFeeBps, risk.MaxTransferFee = config.Newer.BasisPoints, config.Newer.MaximumFee
+			}
+		case ExtensionDefaultAccountState:
+			risk.DefaultFrozen = len(ext.Data) > 0 && ext.Data[0] == accountStateFrozen
+		case ExtensionPausable:
+			// authority, then the paused flag
+			risk.Paused = len(ext.Data) > 32 && ext.Data[32] != 0
+		}
+	}
+
+	add := func(found bool, r Risk) {
+		if found {
+			risk.Risks = append(risk.Risks, r)
+		}
+	}
+	_, nonTransferable := t.Extension(ExtensionNonTransferable)
+	_, pausable := t.Extension(ExtensionPausable)
+	add(risk.MintAuthority != nil, RiskMintAuthority)
+	add(risk.FreezeAuthority != nil, RiskFreezeAuthority)
+	add(risk.PermanentDelegate != nil, RiskPermanentDelegate)
+	add(risk.TransferHookProgram != nil, RiskTransferHook)
+	add(risk.TransferFeeBps > 0, RiskTransferFee)
+	add(risk.DefaultFrozen, RiskDefaultFrozen)
+	add(nonTransferable, RiskNonTransferable)
+	add(pausable, RiskPausable)
+	add(risk.MintCloseAuthority != nil, RiskMintCloseAuthority)
+	return risk
+}
+
+// DenyRisks returns a filter rejecting tokens carrying any of risks, for
+// router.SimpleRouter.SetTokenFilter
+func DenyRisks(risks ...Risk) func(*TokenRisk) error {
+	return func(r *TokenRisk) error {
+		var denied []string
+		for _, risk := range risks {
+			if r.Has(risk) {
+				denied = append(denied, string(risk))
+			}
+		}
+		if len(denied) > 0 {
+			return fmt.Errorf("mint %s has %s", r.Mint.String(), strings.Join(denied, ", "))
+		}
+		return nil
+	}
+}
+
+// optionalNonZeroKey decodes an OptionalNonZeroPubkey at offset, where all
+// zeros means none
+func optionalNonZeroKey(data []byte, offset int) *solana.PublicKey {
+	if len(data) < offset+32 {
+		return nil
+	}
+	key := solana.PublicKeyFromBytes(data[offset : offset+32])
+	if key.IsZero() {
+		return nil
+	}
+	return &key
+}
@@ -0,0 +1,201 @@
+// Package tokens resolves and caches mint information: decimals, the owning
+// token program, supply and authorities, the symbol and name from Metaplex
+// metadata or the Token-2022 metadata extension, and Token-2022 extensions
+// such as transfer fees. Amounts convert between base units and decimal
+// strings with FormatAmount and ParseAmount, so callers don't assume decimals
+package tokens
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"cosmossdk.io/math"
+	"github.com/gagliardetto/solana-go"
+)
+
+// mintSize is the length of a base SPL mint
+const mintSize = 82
+
+// MetaplexProgramID is the Metaplex token metadata program
+var MetaplexProgramID = solana.MustPublicKeyFromBase58("metaqbxxUerdq28cj1RbAWkYQm3ybzjb6a8bt518x1s")
+
+// Token describes a mint
+type Token struct {
+	Mint            solana.PublicKey
+	Program         solana.PublicKey // solana.TokenProgramID or solana.Token2022ProgramID
+	Decimals        uint8
+	Supply          uint64
+	MintAuthority   *solana.PublicKey // nil when minting is disabled
+	FreezeAuthority *solana.PublicKey
+	// Symbol, Name and URI come from the Token-2022 metadata extension, or
+	// else Metaplex metadata. Empty when the mint has neither
+	Symbol string
+	Name   string
+	URI    string
+	// Extensions of a Token-2022 mint
+	Extensions []Extension
+}
+
+// IsToken2022 reports whether the mint belongs to the Token-2022 program
+func (t *Token) IsToken2022() bool {
+	return t.Program.Equals(solana.Token2022ProgramID)
+}
+
+// Extension returns the extension of type typ, if the mint has it
+func (t *Token) Extension(typ ExtensionType) (Extension, bool) {
+	for _, ext := range t.Extensions {
+		if ext.Type == typ {
+			return ext, true
+		}
+	}
+	return Extension{}, false
+}
+
+// TransferFeeConfig returns the mint's transfer fee schedule, if it charges one
+func (t *Token) TransferFeeConfig() (TransferFeeConfig, bool) {
+	ext, ok := t.Extension(ExtensionTransferFeeConfig)
+	if !ok {
+		return TransferFeeConfig{}, false
+	}
+	config, err := parseTransferFeeConfig(ext.Data)
+	if err != nil {
+		return TransferFeeConfig{}, false
+	}
+	return config, true
+}
+
+// ParseMint decodes a mint account owned by program
+func ParseMint(mint, program solana.PublicKey, data []byte) (*Token, error) {
+	if !program.Equals(solana.TokenProgramID) && !program.Equals(solana.Token2022ProgramID) {
+		return nil, fmt.Errorf("mint %s is owned by %s, not a token program", mint.String(), program.String())
+	}
+	if len(data) < mintSize {
+		return nil, fmt.Errorf("mint %s: invalid data length: %d", mint.String(), len(data))
+	}
+	if data[45] == 0 {
+		return nil, fmt.Errorf("mint %s is not initialized", mint.String())
+	}
+	token := &Token{
+		Mint:            mint,
+		Program:         program,
+		Supply:          binary.LittleEndian.Uint64(data[36:44]),
+		Decimals:        data[44],
+		MintAuthority:   optionalKey(data[0:36]),
+		FreezeAuthority: optionalKey(data[46:82]),
+	}
+	if token.IsToken2022() {
+		extensions, err := parseExtensions(data)
+		if err != nil {
+			return nil, fmt.Errorf("mint %s: %w", mint.String(), err)
+		}
+		token.Extensions = extensions
+		if ext, ok := token.Extension(ExtensionTokenMetadata); ok {
+			if name, symbol, uri, err := parseTokenMetadata(ext.Data); err == nil {
+				token.Name, token.Symbol, token.URI = name, symbol, uri
+			}
+		}
+	}
+	return token, nil
+}
+
+// optionalKey decodes a COption<Pubkey>
+func optionalKey(data []byte) *solana.PublicKey {
+	if binary.LittleEndian.Uint32(data[0:4]) == 0 {
+		return nil
+	}
+	key := solana.PublicKeyFromBytes(data[4:36])
+	return &key
+}
+
+// MetadataPDA returns the Metaplex metadata account of mint
+func MetadataPDA(mint solana.PublicKey) (solana.PublicKey, error) {
+	pda, _, err := solana.FindProgramAddress([][]byte{
+		[]byte("metadata"), MetaplexProgramID.Bytes(), mint.Bytes(),
+	}, MetaplexProgramID)
+	return pda, err
+}
+
+// parseMetaplexMetadata reads name, symbol and uri of a Metaplex metadata
+// account: key, update authority, mint, then the three strings, NUL padded
+func parseMetaplexMetadata(data []byte) (name, symbol, uri string, err error) {
+	r := &borshReader{data: data, offset: 1 + 32 + 32}
+	name, symbol, uri = r.string(), r.string(), r.string()
+	if r.err != nil {
+		return "", "", "", fmt.Errorf("invalid metaplex metadata: %w", r.err)
+	}
+	return name, symbol, uri, nil
+}
+
+// parseTokenMetadata reads the ExtensionTokenMetadata entry: update authority,
+// mint, then name, symbol and uri
+func parseTokenMetadata(data []byte) (name, symbol, uri string, err error) {
+	r := &borshReader{data: data, offset: 32 + 32}
+	name, symbol, uri = r.string(), r.string(), r.string()
+	if r.err != nil {
+		return "", "", "", fmt.Errorf("invalid token metadata: %w", r.err)
+	}
+	return name, symbol, uri, nil
+}
+
+// borshReader reads u32-length-prefixed strings, keeping the first error
+type borshReader struct {
+	data   []byte
+	offset int
+	err    error
+}
+
+func (r *borshReader) string() string {
+	if r.err != nil {
+		return ""
+	}
+	if r.offset+4 > len(r.data) {
+		r.err = errors.New("unexpected end of data")
+		return ""
+	}
+	length := int(binary.LittleEndian.Uint32(r.data[r.offset:]))
+	r.offset += 4
+	if length > len(r.data)-r.offset {
+		r.err = errors.New("string overruns the account")
+		return ""
+	}
+	s := string(r.data[r.offset : r.offset+length])
+	r.offset += length
+	return strings.TrimRight(s, "\x00")
+}
+
+// FormatAmount renders amount base units with decimals, e.g. 1500000 with 6
+// decimals as "1.5"
+func FormatAmount(amount math.Int, decimals uint8) string {
+	if decimals == 0 {
+		return amount.String()
+	}
+	s := amount.Abs().String()
+	if len(s) <= int(decimals) {
+		s = strings.Repeat("0", int(decimals)-len(s)+1) + s
+	}
+	whole, frac := s[:len(s)-int(decimals)], strings.TrimRight(s[len(s)-int(decimals):], "0")
+	if amount.IsNegative() {
+		whole = "-" + whole
+	}
+	if frac == "" {
+		return whole
+	}
+	return whole + "." + frac
+}
+
+// ParseAmount converts a decimal string such as "1.5" into base units of a mint
+// with decimals, failing on more fractional digits than decimals
+func ParseAmount(s string, decimals uint8) (math.Int, error) {
+	whole, frac, _ := strings.Cut(strings.TrimSpace(s), ".")
+	if len(frac) > int(decimals) {
+		return math.Int{}, fmt.Errorf("amount %q has more than %d decimals", s, decimals)
+	}
+	digits := whole + frac + strings.Repeat("0", int(decimals)-len(frac))
+	amount, ok := math.NewIntFromString(digits)
+	if !ok || whole == "" && frac == "" {
+		return math.Int{}, fmt.Errorf("invalid amount %q", s)
+	}
+	return amount, nil
+}
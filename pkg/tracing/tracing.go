@@ -0,0 +1,58 @@
+// Package tracing holds the OpenTelemetry helpers the router and sol.Client
+// trace the route lifecycle with: discovery, quoting, instruction building,
+// simulation, sending and confirmation.
+//
+// Spans go to the TracerProvider set with SetTracerProvider on the component,
+// or to the global one, which discards them until the application registers a
+// provider with otel.SetTracerProvider
+package tracing
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope of the spans
+const ScopeName = "github.com/yimingWOW/solroute"
+
+// Span names, one per stage
+const (
+	SpanDiscovery = "solroute.discovery"
+	SpanQuote     = "solroute.quote"
+	SpanBuild     = "solroute.build_instructions"
+	SpanSimulate  = "solroute.simulate"
+	SpanSend      = "solroute.send"
+	SpanConfirm   = "solroute.confirm"
+)
+
+// Attribute keys set on the spans
+const (
+	KeyProgram   = attribute.Key("solroute.program")
+	KeyProtocol  = attribute.Key("solroute.protocol")
+	KeyPool      = attribute.Key("solroute.pool")
+	KeyBaseMint  = attribute.Key("solroute.base_mint")
+	KeyQuoteMint = attribute.Key("solroute.quote_mint")
+	KeyInputMint = attribute.Key("solroute.input_mint")
+	KeySignature = attribute.Key("solroute.signature")
+	KeyStatus    = attribute.Key("solroute.status")
+	KeyCount     = attribute.Key("solroute.count") // pools found, instructions built
+)
+
+// Tracer returns the solroute tracer of tp, or of the global provider when tp is nil
+func Tracer(tp trace.TracerProvider) trace.Tracer {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return tp.Tracer(ScopeName)
+}
+
+// End records err on span, when there is one, and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
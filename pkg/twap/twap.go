@@ -0,0 +1,176 @@
+// Package twap executes large orders as time-weighted child orders: the
+// amount is split into slices sent at regular, optionally jittered, intervals,
+// each routed on its own with router.SimpleRouter.ExecuteRoute, and skipped
+// when its quote is below the order's price limit
+package twap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/yimingWOW/solroute/pkg/logger"
+	"github.com/yimingWOW/solroute/pkg/router"
+	"github.com/yimingWOW/solroute/pkg/sol"
+)
+
+// Order is a TWAP order
+type Order struct {
+	InputMint  string
+	OutputMint string
+	// Amount of InputMint to sell, in base units
+	Amount math.Int
+	// Slices is the number of child orders, each Amount/Slices with the
+	// remainder on the last one
+	Slices int
+	// Duration spreads the slices over this long: slice i is due at
+	// i*Duration/Slices after the start
+	Duration time.Duration
+	// Jitter moves each due time by up to half this fraction of the interval
+	// between slices either way, 0-1, so the order is harder to anticipate
+	Jitter float64
+	// LimitPrice is the least OutputMint per InputMint, in base units, a slice
+	// executes at. Slices quoted below it are skipped. Zero disables the limit
+	LimitPrice math.LegacyDec
+	// CarryOver adds the amount of skipped and failed slices to the next one
+	// instead of leaving it unfilled
+	CarryOver bool
+	// Execute configures each slice's execution; its MinAmountOut is set
+	// from LimitPrice
+	Execute router.ExecuteOptions
+}
+
+// Slice is the outcome of one child order
+type Slice struct {
+	Index    int
+	At       time.Time
+	AmountIn math.Int
+	// Executed is set when the slice was sent
+	Executed *router.Executed
+	// Skipped is set when the quote was below the limit price
+	Skipped bool
+	Err     error
+}
+
+// Report is the outcome of an order
+type Report struct {
+	Slices []Slice
+	// Filled is the InputMint sold by the slices that landed, and Quoted the
+	// OutputMint they were quoted for
+	Filled math.Int
+	Quoted math.Int
+}
+
+// Executor runs TWAP orders through a router
+type Executor struct {
+	router  *router.SimpleRouter
+	client  *sol.Client
+	signers []sol.Signer
+	logger  logger.Logger
+	// OnSlice, when set, is called after each slice
+	OnSlice func(Slice)
+}
+
+// NewExecutor creates an Executor sending with client, paid and signed by the
+// first of signers. The router must have loaded the pair's pools
+func NewExecutor(r *router.SimpleRouter, client *sol.Client, signers ...sol.Signer) *Executor {
+	return &Executor{router: r, client: client, signers: signers}
+}
+
+// SetLogger sets where failed slices are reported. nil restores logger.Default
+func (e *Executor) SetLogger(l logger.Logger) {
+	e.logger = l
+}
+
+// Run executes order until every slice ran or ctx is done, returning the
+// slices run so far in both cases
+func (e *Executor) Run(ctx context.Context, order Order) (*Report, error) {
+	if order.Slices <= 0 {
+		return nil, errors.New("order needs at least one slice")
+	}
+	if order.Amount.IsNil() || !order.Amount.IsPositive() {
+		return nil, errors.New("order amount must be positive")
+	}
+	sliceAmount := order.Amount.QuoRaw(int64(order.Slices))
+	if !sliceAmount.IsPositive() {
+		return nil, fmt.Errorf("order amount %v is smaller than its %d slices", order.Amount, order.Slices)
+	}
+	interval := order.Duration / time.Duration(order.Slices)
+
+	report := &Report{Filled: math.ZeroInt(), Quoted: math.ZeroInt()}
+	start := time.Now()
+	remaining := order.Amount
+	carried := math.ZeroInt()
+	for i := 0; i < order.Slices; i++ {
+		due := start.Add(time.Duration(i) * interval)
+		if i > 0 {
+			due = due.Add(jitter(interval, order.Jitter))
+		}
+		if err := sleepUntil(ctx, due); err != nil {
+			return report, err
+		}
+
+		amount := sliceAmount
+		if i == order.Slices-1 {
+			amount = remaining
+		}
+		remaining = remaining.Sub(amount)
+		amount = amount.Add(carried)
+		carried = math.ZeroInt()
+
+		slice := e.runSlice(ctx, order, i, amount)
+		report.Slices = append(report.Slices, slice)
+		if slice.Err == nil && !slice.Skipped {
+			report.Filled = report.Filled.Add(amount)
+			report.Quoted = report.Quoted.Add(slice.Executed.Quote.AmountOut)
+		} else if order.CarryOver {
+			carried = amount
+		}
+		if e.OnSlice != nil {
+			e.OnSlice(slice)
+		}
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+	}
+	return report, nil
+}
+
+func (e *Executor) runSlice(ctx context.Context, order Order, index int, amount math.Int) Slice {
+	slice := Slice{Index: index, At: time.Now(), AmountIn: amount}
+	opts := order.Execute
+	if !order.LimitPrice.IsNil() && order.LimitPrice.IsPositive() {
+		opts.MinAmountOut = order.LimitPrice.MulInt(amount).Ceil().TruncateInt()
+	}
+	slice.Executed, slice.Err = e.router.ExecuteRoute(ctx, e.client, e.signers, order.InputMint, order.OutputMint, amount, opts)
+	if errors.Is(slice.Err, router.ErrPriceLimit) {
+		slice.Skipped = true
+	} else if slice.Err != nil {
+		logger.Or(e.logger).Warn("twap slice failed",
+			"slice", index, "amountIn", amount.String(), "err", slice.Err)
+	}
+	return slice
+}
+
+// jitter returns a random offset within ±fraction/2 of interval
+func jitter(interval time.Duration, fraction float64) time.Duration {
+	fraction = min(max(fraction, 0), 1)
+	if fraction == 0 || interval <= 0 {
+		return 0
+	}
+	return time.Duration((rand.Float64() - 0.5) * fraction * float64(interval))
+}
+
+func sleepUntil(ctx context.Context, t time.Time) error {
+	timer := time.NewTimer(time.Until(t))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
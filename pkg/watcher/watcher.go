@@ -0,0 +1,240 @@
+// Package watcher keeps pool state current from WebSocket account subscriptions,
+// so quotes run against live state instead of refetching accounts every time
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/gagliardetto/solana-go"
+	"github.com/yimingWOW/solroute/pkg"
+	"github.com/yimingWOW/solroute/pkg/sol"
+)
+
+// PoolWatcher applies account updates to watched pools in place
+type PoolWatcher struct {
+	client *sol.Client
+	source sol.AccountSource
+
+	mu    sync.Mutex
+	pools map[string]*watchedPool
+}
+
+type watchedPool struct {
+	pool    pkg.Pool
+	updater pkg.AccountUpdater
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	// mu guards the pool state against concurrent updates and quotes
+	mu   sync.Mutex
+	live bool
+
+	subsMu sync.Mutex
+	subs   map[solana.PublicKey]sol.Subscription
+}
+
+// NewPoolWatcher creates a watcher subscribing through client's WebSocket connection
+func NewPoolWatcher(client *sol.Client) *PoolWatcher {
+	return &PoolWatcher{
+		client: client,
+		source: client,
+		pools:  make(map[string]*watchedPool),
+	}
+}
+
+// NewPoolWatcherWithSource creates a watcher receiving updates from source, e.g. a
+// Geyser gRPC client. client is still used for the initial refresh
+func NewPoolWatcherWithSource(client *sol.Client, source sol.AccountSource) *PoolWatcher {
+	return &PoolWatcher{
+		client: client,
+		source: source,
+		pools:  make(map[string]*watchedPool),
+	}
+}
+
+// Watch refreshes pool once and then keeps it updated until ctx is done or
+// Unwatch is called. The pool must implement pkg.AccountUpdater
+func (w *PoolWatcher) Watch(ctx context.Context, pool pkg.Pool) error {
+	updater, ok := pool.(pkg.AccountUpdater)
+	if !ok {
+		return fmt.Errorf("pool %s (%s) does not support account updates", pool.GetID(), pool.ProtocolName())
+	}
+	w.Unwatch(pool.GetID())
+
+	if err := pool.Refresh(ctx, w.client.RpcClient); err != nil {
+		return fmt.Errorf("failed to refresh pool: %w", err)
+	}
+
+	wp := &watchedPool{
+		pool:    pool,
+		updater: updater,
+		subs:    make(map[solana.PublicKey]sol.Subscription),
+	}
+	wp.ctx, wp.cancel = context.WithCancel(ctx)
+	if err := w.sync(wp); err != nil {
+		wp.stop()
+		return err
+	}
+	wp.live = true
+
+	w.mu.Lock()
+	w.pools[pool.GetID()] = wp
+	w.mu.Unlock()
+	return nil
+}
+
+// Unwatch stops updating the pool with the given ID
+func (w *PoolWatcher) Unwatch(poolID string) {
+	w.mu.Lock()
+	wp, ok := w.pools[poolID]
+	delete(w.pools, poolID)
+	w.mu.Unlock()
+	if ok {
+		wp.stop()
+	}
+}
+
+// Close stops updating all pools
+func (w *PoolWatcher) Close() {
+	w.mu.Lock()
+	pools := w.pools
+	w.pools = make(map[string]*watchedPool)
+	w.mu.Unlock()
+	for _, wp := range pools {
+		wp.stop()
+	}
+}
+
+// Do runs fn while no update is applied to pool and reports whether it ran. It
+// returns false when pool is not watched or a subscription failed, in which case
+// the caller should Refresh the pool itself
+func (w *PoolWatcher) Do(pool pkg.Pool, fn func()) bool {
+	w.mu.Lock()
+	wp, ok := w.pools[pool.GetID()]
+	w.mu.Unlock()
+	if !ok {
+		return false
+	}
+
+	wp.mu.Lock()
+	defer wp.mu.Unlock()
+	if !wp.live {
+		return false
+	}
+	fn()
+	return true
+}
+
+// sync subscribes to accounts the pool started watching and drops the ones it no
+// longer needs, e.g. bin arrays left behind by a moved active bin
+func (w *PoolWatcher) sync(wp *watchedPool) error {
+	wp.mu.Lock()
+	want := make(map[solana.PublicKey]bool)
+	for _, pubkey := range wp.updater.WatchedAccounts() {
+		want[pubkey] = true
+	}
+	wp.mu.Unlock()
+
+	wp.subsMu.Lock()
+	var dropped []sol.Subscription
+	defer func() {
+		wp.subsMu.Unlock()
+		// Unsubscribe waits for running handlers, which take subsMu themselves
+		for _, sub := range dropped {
+			sub.Unsubscribe()
+		}
+	}()
+	if wp.ctx.Err() != nil {
+		return nil
+	}
+	for pubkey, sub := range wp.subs {
+		if !want[pubkey] {
+			dropped = append(dropped, sub)
+			delete(wp.subs, pubkey)
+		}
+	}
+	for pubkey := range want {
+		if _, ok := wp.subs[pubkey]; ok {
+			continue
+		}
+		sub, err := w.source.SubscribeAccounts(wp.ctx, []solana.PublicKey{pubkey}, func(pubkey solana.PublicKey, slot uint64, data []byte) {
+			w.apply(wp, pubkey, data)
+		})
+		if err != nil {
+			return err
+		}
+		wp.subs[pubkey] = sub
+		go w.monitor(wp, sub)
+	}
+	return nil
+}
+
+func (w *PoolWatcher) apply(wp *watchedPool, pubkey solana.PublicKey, data []byte) {
+	wp.mu.Lock()
+	err := wp.updater.ApplyAccount(pubkey, data)
+	after := wp.updater.WatchedAccounts()
+	wp.mu.Unlock()
+	if err != nil {
+		w.client.Logger().Warn("failed to apply account update",
+			"pool", wp.pool.GetID(), "account", pubkey.String(), "err", err)
+		return
+	}
+
+	// Resubscribe outside the handler, which must not unsubscribe itself
+	if !wp.subscribed(after) {
+		go func() {
+			if err := w.sync(wp); err != nil {
+				w.client.Logger().Error("failed to update subscriptions", "pool", wp.pool.GetID(), "err", err)
+				wp.setLive(false)
+			}
+		}()
+	}
+}
+
+// monitor marks the pool stale when sub fails
+func (w *PoolWatcher) monitor(wp *watchedPool, sub sol.Subscription) {
+	select {
+	case err := <-sub.Err():
+		w.client.Logger().Warn("subscription failed, pool is stale", "pool", wp.pool.GetID(), "err", err)
+		wp.setLive(false)
+	case <-wp.ctx.Done():
+	}
+}
+
+// subscribed reports whether the current subscriptions match pubkeys exactly
+func (wp *watchedPool) subscribed(pubkeys []solana.PublicKey) bool {
+	want := make(map[solana.PublicKey]bool, len(pubkeys))
+	for _, pubkey := range pubkeys {
+		want[pubkey] = true
+	}
+	wp.subsMu.Lock()
+	defer wp.subsMu.Unlock()
+	if len(want) != len(wp.subs) {
+		return false
+	}
+	for pubkey := range want {
+		if _, ok := wp.subs[pubkey]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (wp *watchedPool) setLive(live bool) {
+	wp.mu.Lock()
+	wp.live = live
+	wp.mu.Unlock()
+}
+
+func (wp *watchedPool) stop() {
+	wp.cancel()
+	wp.subsMu.Lock()
+	subs := wp.subs
+	wp.subs = make(map[solana.PublicKey]sol.Subscription)
+	wp.subsMu.Unlock()
+	for _, sub := range subs {
+		sub.Unsubscribe()
+	}
+}